@@ -1,16 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"wht-order-api/internal/config"
+	"wht-order-api/internal/connector"
 	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dao"
 	"wht-order-api/internal/handler"
 	"wht-order-api/internal/idgen"
+	"wht-order-api/internal/lifecycle"
 	"wht-order-api/internal/logger"
+	"wht-order-api/internal/metrics"
 	"wht-order-api/internal/middleware"
 	"wht-order-api/internal/mq"
+	"wht-order-api/internal/outbox"
+	"wht-order-api/internal/risk"
+	"wht-order-api/internal/service"
 	"wht-order-api/internal/shard"
 	"wht-order-api/internal/system"
 )
@@ -30,21 +43,37 @@ func main() {
 		log.Fatalf("启动消息队列失败,错误信息: %v", mqErr)
 		return
 	}
+	// 连接池指标
+	metrics.RegisterPools()
 
 	// idgen
 	// 初始化 ID 生成器
 	idgen.InitFromEnv()
 	// 启动时间回拨检测
-	go idgen.CheckSystemClock()
+	lifecycle.Go("idgen-clock-check", idgen.CheckSystemClock)
 	// 初始化分片引擎
 	shard.InitShardEngines()
 	logger.InitLogger()
 	// 初始化一些系统配置参数
 	system.Config()
 	// start MQ receive consumer
-	go mq.StartReceiveConsumer()
+	lifecycle.Go("receive-consumer", mq.StartReceiveConsumer)
 	// start MQ payout consumer
-	go mq.StartPayoutConsumer()
+	lifecycle.Go("payout-consumer", mq.StartPayoutConsumer)
+	// 代付复核超时自动驳回
+	lifecycle.Go("payout-approval-expiry", service.NewPayoutApprovalService(mq.NewPublisher()).RunExpiry)
+	// 上游余额不足代付排队出队
+	lifecycle.Go("payout-holding", service.NewPayoutHoldingService(mq.NewPublisher()).Run)
+	// 代收未支付超时回滚累计限额预占
+	lifecycle.Go("limit-expiry", service.NewLimitService(dao.NewMainDao()).RunExpiry)
+	// 风控规则引擎（加载规则后定时热加载）
+	lifecycle.Go("risk-engine", risk.Init(mq.NewPublisher()).Run)
+	// 批量代付恢复（重启后接管处理中的批次，逐行风控）
+	lifecycle.Go("payout-batch-resume", service.NewPayoutBatchService(mq.NewPublisher()).RunResume)
+	// 发件箱投递（订单统计等事件，发布确认 + 失败重试）
+	lifecycle.Go("outbox-relay", outbox.NewRelay(mq.NewConfirmPublisher()).Run)
+	// 原生上游连接器（未注册的接口继续走 PHP 网关）
+	log.Printf("🔌 已注册原生上游连接器: %v", connector.Codes())
 	// 2. 初始化全局 Publisher
 
 	// http server
@@ -54,6 +83,12 @@ func main() {
 
 	r := gin.New()
 
+	// Prometheus 指标（在全局中间件之前注册：抓取请求不写审计日志、不计入请求指标）
+	r.GET(config.C.Metrics.Path, middleware.MetricsAuth(), gin.WrapH(metrics.Handler()))
+
+	// 0. 请求数、耗时指标
+	r.Use(middleware.Metrics())
+
 	// 1. 初始化 trace_id、请求体、IP、UA、开始时间
 	r.Use(middleware.TraceAuditMiddleware())
 
@@ -80,16 +115,31 @@ func main() {
 		payout := handler.NewPayoutOrderHandler()
 		account := handler.NewAccountHandler()
 		reassign := handler.NewReassignOrderHandler()
+		batch := handler.NewPayoutBatchHandler()
+		withdraw := handler.NewWithdrawHandler()
 		// 代收网关
 		v1.POST("/order/receive/create", middleware.ReceiveCreateAuth(), receive.ReceiveOrderCreate)
 		v1.POST("/order/receive/query", middleware.ReceiveQueryAuth(), receive.ReceiveOrderQuery)
 		// 代付网关
 		v1.POST("/order/payout/create", middleware.PayoutCreateAuth(), payout.PayoutOrderCreate)
 		v1.POST("/order/payout/query", middleware.PayoutQueryAuth(), payout.PayoutOrderQuery)
+		// 批量代付（文件上传）
+		v1.POST("/order/payout/batch/create", middleware.PayoutBatchCreateAuth(), batch.BatchCreate)
+		v1.POST("/order/payout/batch/query", middleware.PayoutBatchQueryAuth(), batch.BatchQuery)
 		// 查询商户账户信息
 		v1.POST("/query/account/balance", middleware.AccountAuth(), account.Query)
+		// 商户资金流水查询 / 对账单下载
+		v1.POST("/query/account/statement", middleware.AccountStatementAuth(), account.Statement)
 		// 代付失败改派功能
 		v1.POST("/order/reassign/submit", middleware.ReassignCreateAuth(), reassign.ReassignOrderCreate)
+		// 商户提现（结算到银行账户）
+		v1.POST("/withdraw/create", middleware.WithdrawCreateAuth(), withdraw.Create)
+		v1.POST("/withdraw/query", middleware.WithdrawQueryAuth(), withdraw.Query)
+		v1.POST("/withdraw/destination/save", middleware.WithdrawDestinationSaveAuth(), withdraw.SaveDestination)
+		v1.POST("/withdraw/destination/list", middleware.WithdrawDestinationListAuth(), withdraw.ListDestinations)
+		// 上游直连回调（验签 + 来源IP白名单，投递到回调队列）
+		upstreamCallback := handler.NewUpstreamCallbackHandler()
+		v1.POST("/callback/:interface_code/:mode", upstreamCallback.Notify)
 	}
 
 	// ------------------------------------------------------------------
@@ -102,11 +152,57 @@ func main() {
 
 		// 通过上游交易号查询上游供应商配置信息
 		internal.POST("/upstream/config", upstream.ConfigQuery)
+
+		// 大额代付复核
+		approval := handler.NewPayoutApprovalHandler()
+		internal.POST("/payout/approval/list", approval.List)
+		internal.POST("/payout/approval/decide", approval.Decide)
+
+		// 上游补款通知，触发余额不足排队订单出队
+		holding := handler.NewPayoutHoldingHandler()
+		internal.POST("/payout/holding/topped-up", holding.ToppedUp)
+
+		// 商户钱包换汇
+		internal.POST("/account/fx/convert", handler.NewAccountHandler().FxConvert)
 	}
 
 	addr := ":" + config.C.Server.Port
-	log.Printf("wht application listen port %s", addr)
-	if err := r.Run(addr); err != nil {
-		log.Fatal(err)
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		log.Printf("wht application listen port %s", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	log.Printf("🛑 收到退出信号 %v，开始优雅退出（时限 %s）", sig, config.C.Server.ShutdownTimeout)
+	shutdown(srv)
+}
+
+// shutdown 按依赖顺序退出：停止接收请求 → 停止消费并处理完在途消息 → 等待后台任务 → 关闭 MQ、Redis、数据库
+func shutdown(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.C.Server.ShutdownTimeout)
+	defer cancel()
+
+	// 1. HTTP：不再接收新连接，等待处理中的请求（下单等）返回
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("⚠️ HTTP 服务关闭超时: %v", err)
+	}
+	// 2. MQ 消费：取消订阅，worker 处理完在途消息后退出
+	mq.StopConsumers()
+	// 3. 常驻任务结束循环，等待请求内派生的后台协程（订单绑定、支付地址回写、商户通知等）
+	lifecycle.Stop()
+	if err := lifecycle.Wait(ctx); err != nil {
+		log.Printf("⚠️ %v", err)
 	}
+	// 4. 补发断线缓冲后按依赖顺序关闭基础设施
+	mq.FlushPending()
+	dal.CloseRabbitMQ()
+	dal.CloseRedis()
+	dal.CloseOrderDB()
+	dal.CloseMainDB()
+	log.Println("✅ 服务已退出")
 }
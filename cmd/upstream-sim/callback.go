@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/mq"
+
+	"github.com/shopspring/decimal"
+)
+
+// 上游回调状态（与回调消费者约定一致）
+const (
+	statusSuccess = "0000"
+	statusPending = "0001"
+	statusFail    = "0005"
+)
+
+// scheduleCallback 按场景投递回调
+func (s *simulator) scheduleCallback(order *simOrder) {
+	status, amount, times, delay := statusSuccess, order.Amount, 1, *callbackDelay
+	switch order.Scenario {
+	case ScenarioNoCallback:
+		return
+	case ScenarioFail:
+		status = statusFail
+	case ScenarioDuplicate:
+		times = 2
+	case ScenarioLate:
+		delay = *lateDelay
+	case ScenarioAmountMismatch:
+		amount = amount.Add(decimal.NewFromInt(1))
+	}
+
+	time.Sleep(delay)
+	s.setStatus(order, status)
+	for i := 0; i < times; i++ {
+		if err := deliverCallback(order, status, amount); err != nil {
+			log.Printf("❌ [SIM-%s] 回调投递失败, 交易订单号: %s: %v", order.Mode, order.MOrderID, err)
+			return
+		}
+		log.Printf("📤 [SIM-%s] 回调已投递(%d/%d), 交易订单号: %s, 状态: %s, 金额: %s",
+			order.Mode, i+1, times, order.MOrderID, status, amount.StringFixed(2))
+	}
+}
+
+func deliverCallback(order *simOrder, status string, amount decimal.Decimal) error {
+	var msg any
+	if order.Mode == "receive" {
+		msg = dto.ReceiveHyperfOrderMessage{
+			MOrderID:    order.MOrderID,
+			UpOrderID:   order.UpOrderNo,
+			Amount:      amount,
+			Status:      status,
+			UpIpAddress: *callbackIP,
+			Timestamp:   time.Now().Unix(),
+		}
+	} else {
+		msg = dto.PayoutHyperfOrderMessage{
+			MOrderID:    order.MOrderID,
+			UpOrderID:   order.UpOrderNo,
+			Amount:      amount,
+			Status:      status,
+			UpIpAddress: *callbackIP,
+			Timestamp:   time.Now().Unix(),
+		}
+	}
+
+	switch *callbackMode {
+	case "mq":
+		// 与 PHP 网关相同：投递到回调消费者监听的队列
+		return mq.NewPublisher().Publish(order.Mode+"_callback", msg)
+	case "http":
+		return postCallback(order, msg)
+	default:
+		return nil
+	}
+}
+
+// postCallback 直连回调入口，请求头 X-Sim-Sign 为请求体的 HMAC-SHA256
+func postCallback(order *simOrder, msg any) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("回调序列化失败: %w", err)
+	}
+	mac := hmac.New(sha256.New, []byte(*callbackSign))
+	mac.Write(body)
+
+	target := fmt.Sprintf(*callbackURL, order.ProviderKey, order.Mode)
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建回调请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sim-Sign", hex.EncodeToString(mac.Sum(nil)))
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("回调请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	ack, _ := io.ReadAll(resp.Body)
+	log.Printf("↩️ [SIM-%s] 回调响应: %d %s", order.Mode, resp.StatusCode, string(ack))
+	return nil
+}
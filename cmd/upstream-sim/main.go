@@ -0,0 +1,71 @@
+// upstream-sim 上游模拟器：按 PHP 网关的请求/响应约定（data.code / pay_url / up_order_no）
+// 提供代收、代付、余额、查单接口，并按脚本场景投递回调（RabbitMQ 回调队列或直连回调 HTTP 入口），
+// 用于本地联调 ReceiveOrderService.Create 与回调链路。
+//
+// 用法（配置 upstream.receiveApiUrl / payoutApiUrl / balanceApiUrl 指向模拟器）：
+//
+//	go run ./cmd/upstream-sim -env dev -addr :9501 -scenario success -callback mq
+//	curl -X POST localhost:9501/sim/scenario -d '{"scenario":"amount_mismatch"}'
+//
+// 单笔订单可用请求头 X-Sim-Scenario 覆盖场景。-callback http 投递到 /api/v1/callback/{接口编码}/{receive|payout}，
+// 该接口编码需已注册原生连接器（请求头 X-Sim-Sign 为请求体的 HMAC-SHA256）。
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/dal"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	addr          = flag.String("addr", ":9501", "模拟器监听地址")
+	scenarioFlag  = flag.String("scenario", ScenarioSuccess, "默认场景: "+scenarioNames())
+	callbackMode  = flag.String("callback", "mq", "回调投递方式: mq|http|none")
+	callbackURL   = flag.String("callback-url", "http://localhost:8080/api/v1/callback/%s/%s", "直连回调地址模板（接口编码, receive/payout）")
+	callbackSign  = flag.String("callback-secret", "upstream-sim", "直连回调 HMAC-SHA256 签名密钥（X-Sim-Sign）")
+	callbackIP    = flag.String("callback-ip", "127.0.0.1", "MQ 回调消息中的 upIpAddress（需在 w_upstream 白名单内）")
+	callbackDelay = flag.Duration("callback-delay", 2*time.Second, "下单成功后回调延迟")
+	lateDelay     = flag.Duration("late-delay", 2*time.Minute, "late 场景回调延迟")
+	slowDelay     = flag.Duration("slow-delay", 2*time.Second, "slow 场景响应延迟")
+	hangDelay     = flag.Duration("hang-delay", 60*time.Second, "timeout 场景挂起时长")
+	balance       = flag.String("balance", "1000000.00", "余额接口返回的可用余额")
+)
+
+func main() {
+	// config.Init 内部会 flag.Parse，模拟器参数需在此之前声明
+	config.Init()
+
+	if err := setScenario(*scenarioFlag); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if *callbackMode == "mq" {
+		if err := dal.InitRabbitMQ(); err != nil {
+			log.Fatalf("❌ 连接消息队列失败: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	sim := newSimulator()
+	// 下单前的健康检查（HEAD /）
+	r.HEAD("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/order/receive", sim.Receive)
+	r.POST("/order/payout", sim.Payout)
+	r.POST("/order/balance", sim.Balance)
+	r.POST("/order/query", sim.Query)
+	// 运行时切换场景
+	r.POST("/sim/scenario", sim.SwitchScenario)
+	r.GET("/sim/orders", sim.Orders)
+
+	log.Printf("🧪 upstream-sim 监听 %s, 场景: %s, 回调: %s", *addr, currentScenario(), *callbackMode)
+	if err := r.Run(*addr); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 脚本场景
+const (
+	ScenarioSuccess        = "success"         // 受理成功，回调成功
+	ScenarioFail           = "fail"            // 受理成功，回调失败
+	ScenarioDecline        = "decline"         // 下单即拒绝（data.code != 0）
+	ScenarioTimeout        = "timeout"         // 挂起直到调用方超时
+	ScenarioSlow           = "slow"            // 延迟响应后成功
+	ScenarioMalformed      = "malformed"       // 返回非法 JSON
+	ScenarioDuplicate      = "duplicate"       // 成功回调投递两次
+	ScenarioLate           = "late"            // 回调延迟到 late-delay 之后
+	ScenarioAmountMismatch = "amount_mismatch" // 回调金额与下单金额不符
+	ScenarioNoCallback     = "no_callback"     // 受理成功但不回调（走查单）
+)
+
+var scenarios = map[string]struct{}{
+	ScenarioSuccess:        {},
+	ScenarioFail:           {},
+	ScenarioDecline:        {},
+	ScenarioTimeout:        {},
+	ScenarioSlow:           {},
+	ScenarioMalformed:      {},
+	ScenarioDuplicate:      {},
+	ScenarioLate:           {},
+	ScenarioAmountMismatch: {},
+	ScenarioNoCallback:     {},
+}
+
+var (
+	scenarioMu sync.RWMutex
+	scenario   = ScenarioSuccess
+)
+
+func setScenario(name string) error {
+	name = strings.TrimSpace(name)
+	if _, ok := scenarios[name]; !ok {
+		return fmt.Errorf("未知场景 %q，可选: %s", name, scenarioNames())
+	}
+	scenarioMu.Lock()
+	scenario = name
+	scenarioMu.Unlock()
+	return nil
+}
+
+func currentScenario() string {
+	scenarioMu.RLock()
+	defer scenarioMu.RUnlock()
+	return scenario
+}
+
+// resolveScenario 请求头 X-Sim-Scenario 优先（单笔覆盖），否则用全局场景
+func resolveScenario(header string) string {
+	if _, ok := scenarios[strings.TrimSpace(header)]; ok {
+		return strings.TrimSpace(header)
+	}
+	return currentScenario()
+}
+
+func scenarioNames() string {
+	names := make([]string, 0, len(scenarios))
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// gatewayReq PHP 网关下单/查单请求中模拟器关心的字段
+type gatewayReq struct {
+	MchOrderId  string `json:"mchOrderId"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	ProviderKey string `json:"providerKey"`
+	Mode        string `json:"mode"`
+	UpOrderNo   string `json:"upOrderNo"`
+}
+
+// simOrder 模拟器内的上游订单
+type simOrder struct {
+	MOrderID    string          `json:"mOrderId"`
+	UpOrderNo   string          `json:"upOrderNo"`
+	Mode        string          `json:"mode"`
+	ProviderKey string          `json:"providerKey"`
+	Amount      decimal.Decimal `json:"amount"`
+	Scenario    string          `json:"scenario"`
+	Status      string          `json:"status"`
+	CreateTime  time.Time       `json:"createTime"`
+}
+
+type simulator struct {
+	mu     sync.RWMutex
+	orders map[string]*simOrder // key: mOrderId
+	seq    atomic.Uint64
+}
+
+func newSimulator() *simulator {
+	return &simulator{orders: make(map[string]*simOrder)}
+}
+
+func (s *simulator) Receive(c *gin.Context) { s.create(c, "receive") }
+
+func (s *simulator) Payout(c *gin.Context) { s.create(c, "payout") }
+
+func (s *simulator) create(c *gin.Context, mode string) {
+	var req gatewayReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gatewayResp("1", "invalid request: "+err.Error(), nil))
+		return
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil || req.MchOrderId == "" {
+		c.JSON(http.StatusOK, gatewayResp("1", "invalid mchOrderId or amount", nil))
+		return
+	}
+
+	sc := resolveScenario(c.GetHeader("X-Sim-Scenario"))
+	log.Printf("📥 [SIM-%s] 交易订单号: %s, 金额: %s, 接口: %s, 场景: %s", mode, req.MchOrderId, req.Amount, req.ProviderKey, sc)
+
+	switch sc {
+	case ScenarioTimeout:
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(*hangDelay):
+		}
+		c.Status(http.StatusGatewayTimeout)
+		return
+	case ScenarioSlow:
+		time.Sleep(*slowDelay)
+	case ScenarioMalformed:
+		c.Data(http.StatusOK, "application/json", []byte(`{"code":0,"msg":"ok","data":{"code":"0","pay_url":`))
+		return
+	case ScenarioDecline:
+		c.JSON(http.StatusOK, gatewayResp("1001", "transaction declined by upstream", gin.H{"m_order_id": req.MchOrderId}))
+		return
+	}
+
+	order := &simOrder{
+		MOrderID:    req.MchOrderId,
+		UpOrderNo:   fmt.Sprintf("SIM%s%06d", time.Now().Format("20060102150405"), s.seq.Add(1)),
+		Mode:        mode,
+		ProviderKey: req.ProviderKey,
+		Amount:      amount,
+		Scenario:    sc,
+		Status:      statusPending,
+		CreateTime:  time.Now(),
+	}
+	s.mu.Lock()
+	s.orders[order.MOrderID] = order
+	s.mu.Unlock()
+
+	go s.scheduleCallback(order)
+
+	data := gin.H{
+		"up_order_no": order.UpOrderNo,
+		"m_order_id":  order.MOrderID,
+	}
+	if mode == "receive" {
+		data["pay_url"] = "http://localhost" + *addr + "/pay/" + order.UpOrderNo
+	}
+	c.JSON(http.StatusOK, gatewayResp("0", "ok", data))
+}
+
+// Balance 余额查询
+func (s *simulator) Balance(c *gin.Context) {
+	c.JSON(http.StatusOK, gatewayResp("0", "ok", gin.H{
+		"amount":          *balance,
+		"frozenAmount":    "0.00",
+		"availableAmount": *balance,
+	}))
+}
+
+// Query 查单（按 mchOrderId 或 upOrderNo）
+func (s *simulator) Query(c *gin.Context) {
+	var req gatewayReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gatewayResp("1", "invalid request: "+err.Error(), nil))
+		return
+	}
+	order := s.find(req.MchOrderId, req.UpOrderNo)
+	if order == nil {
+		c.JSON(http.StatusOK, gatewayResp("1004", "order not found", nil))
+		return
+	}
+	c.JSON(http.StatusOK, gatewayResp("0", "ok", gin.H{
+		"up_order_no": order.UpOrderNo,
+		"m_order_id":  order.MOrderID,
+		"status":      order.Status,
+		"amount":      order.Amount.StringFixed(2),
+	}))
+}
+
+// SwitchScenario 运行时切换全局场景
+func (s *simulator) SwitchScenario(c *gin.Context) {
+	var req struct {
+		Scenario string `json:"scenario"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := setScenario(req.Scenario); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("🔀 [SIM] 场景切换为: %s", req.Scenario)
+	c.JSON(http.StatusOK, gin.H{"scenario": req.Scenario})
+}
+
+// Orders 查看模拟器内订单
+func (s *simulator) Orders(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*simOrder, 0, len(s.orders))
+	for _, o := range s.orders {
+		list = append(list, o)
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (s *simulator) find(mOrderId, upOrderNo string) *simOrder {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if o, ok := s.orders[mOrderId]; ok {
+		return o
+	}
+	for _, o := range s.orders {
+		if upOrderNo != "" && o.UpOrderNo == upOrderNo {
+			return o
+		}
+	}
+	return nil
+}
+
+func (s *simulator) setStatus(order *simOrder, status string) {
+	s.mu.Lock()
+	order.Status = status
+	s.mu.Unlock()
+}
+
+// gatewayResp PHP 网关响应格式：顶层 code 恒为 0，业务结果看 data.code
+func gatewayResp(dataCode, msg string, data gin.H) gin.H {
+	if data == nil {
+		data = gin.H{}
+	}
+	data["code"] = dataCode
+	data["msg"] = msg
+	return gin.H{"code": 0, "msg": "ok", "data": data}
+}
@@ -0,0 +1,207 @@
+package beneficiary
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/utils"
+)
+
+// Account 收款账户要素（代付/批量代付/提现账户共用）
+type Account struct {
+	Currency     string
+	PayMethod    string
+	AccNo        string
+	AccName      string
+	BankCode     string
+	CciNo        string
+	AccountType  string
+	IdentityType string
+	IdentityNum  string
+	Address      string // 虚拟币收款地址（为空时使用 AccNo）
+	Network      string // 区块链网络
+}
+
+// ValidationError 收款账户校验失败（对外返回 CodeCardInvalid）
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+func invalid(field, reason string) *ValidationError {
+	return &ValidationError{Field: field, Reason: reason}
+}
+
+// Validator 收款账户校验器
+type Validator func(a Account) error
+
+var (
+	mu       sync.RWMutex
+	registry = map[string][]Validator{}
+)
+
+// Register 按 币种/支付方式 注册校验器，"*" 表示任意；同一账户会依次执行 币种+支付方式、币种+*、*+支付方式 的校验器
+func Register(currency, payMethod string, v Validator) {
+	mu.Lock()
+	defer mu.Unlock()
+	key := registryKey(currency, payMethod)
+	registry[key] = append(registry[key], v)
+}
+
+func registryKey(currency, payMethod string) string {
+	return strings.ToUpper(strings.TrimSpace(currency)) + ":" + strings.ToUpper(strings.TrimSpace(payMethod))
+}
+
+// Validate 执行账户格式校验（不访问数据库）
+func Validate(a Account) error {
+	mu.RLock()
+	var validators []Validator
+	for _, key := range []string{
+		registryKey(a.Currency, a.PayMethod),
+		registryKey(a.Currency, "*"),
+		registryKey("*", a.PayMethod),
+	} {
+		validators = append(validators, registry[key]...)
+	}
+	mu.RUnlock()
+
+	for _, v := range validators {
+		if err := v(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check 格式校验 + 银行编码存在性校验（w_bank_code），在冻结资金前调用
+func Check(mainDao dao.MainRepository, a Account) error {
+	if err := Validate(a); err != nil {
+		return err
+	}
+	if code := bankLookupCode(a); code != "" {
+		if _, err := mainDao.QueryPlatformBankInfo(code, a.Currency); err != nil {
+			return invalid("bank_code", fmt.Sprintf("bank code does not exist: %s", a.BankCode))
+		}
+	}
+	return nil
+}
+
+// bankLookupCode 需要在 w_bank_code 中校验的银行编码：印度传 IFSC 时按前 4 位银行代码校验，否则按平台银行编码校验
+func bankLookupCode(a Account) string {
+	code := strings.TrimSpace(a.BankCode)
+	if strings.EqualFold(a.Currency, "INR") && ValidIFSC(code) {
+		return strings.ToUpper(code[:4])
+	}
+	return code
+}
+
+func init() {
+	// 秘鲁：提供 CCI 或账户类型为 CCI 时校验 CCI
+	Register("PEN", "*", func(a Account) error {
+		cci := a.CciNo
+		if cci == "" && strings.EqualFold(a.AccountType, "CCI") {
+			cci = a.AccNo
+		}
+		if cci != "" && !ValidCCI(cci) {
+			return invalid("cci_no", "CCI must be 20 digits with valid check digits")
+		}
+		return nil
+	})
+
+	// 墨西哥：18 位按 CLABE 校验，16 位按借记卡 Luhn 校验
+	Register("MXN", "*", func(a Account) error {
+		acc := stripSeparators(a.AccNo)
+		switch len(acc) {
+		case 18:
+			if !ValidCLABE(acc) {
+				return invalid("acc_no", "CLABE check digit mismatch")
+			}
+		case 16:
+			if !ValidLuhn(acc) {
+				return invalid("acc_no", "card number check digit mismatch")
+			}
+		default:
+			return invalid("acc_no", "must be an 18-digit CLABE or 16-digit card number")
+		}
+		return nil
+	})
+
+	// 巴西：PIX 校验收款键；证件类型为 CPF/CNPJ 时校验证件号
+	Register("BRL", "PIX", func(a Account) error {
+		if !ValidPixKey(a.AccNo) {
+			return invalid("acc_no", "PIX key must be a CPF, CNPJ, email, +55 phone or random key")
+		}
+		return nil
+	})
+	Register("BRL", "*", func(a Account) error {
+		if a.IdentityNum == "" {
+			return nil
+		}
+		switch strings.ToUpper(strings.TrimSpace(a.IdentityType)) {
+		case "CPF":
+			if !ValidCPF(a.IdentityNum) {
+				return invalid("identity_num", "CPF check digits mismatch")
+			}
+		case "CNPJ":
+			if !ValidCNPJ(a.IdentityNum) {
+				return invalid("identity_num", "CNPJ check digits mismatch")
+			}
+		}
+		return nil
+	})
+
+	// 印度：UPI 校验 VPA，其余按银行账号校验；bank_code 可传 IFSC 或平台银行编码（存在性由 Check 校验）
+	Register("INR", "UPI", func(a Account) error {
+		if !ValidUPI(a.AccNo) {
+			return invalid("acc_no", "UPI VPA must be in the form handle@psp")
+		}
+		return nil
+	})
+	Register("INR", "*", func(a Account) error {
+		if strings.EqualFold(a.PayMethod, "UPI") {
+			return nil
+		}
+		if !ValidIndianAccount(a.AccNo) {
+			return invalid("acc_no", "must be 9-18 digits")
+		}
+		if strings.TrimSpace(a.BankCode) == "" {
+			return invalid("bank_code", "IFSC or bank code is required")
+		}
+		return nil
+	})
+
+	// 欧元 / SEPA：IBAN mod-97
+	ibanValidator := func(a Account) error {
+		if !ValidIBAN(a.AccNo) {
+			return invalid("acc_no", "IBAN check digits mismatch")
+		}
+		return nil
+	}
+	Register("EUR", "*", ibanValidator)
+	Register("*", "SEPA", ibanValidator)
+	Register("*", "IBAN", ibanValidator)
+
+	// 虚拟币：按 pay_method 所在链离线校验地址，network 需与链/协议一致
+	for payMethod := range utils.PayMethodMap {
+		Register("*", payMethod, cryptoValidator)
+	}
+}
+
+func cryptoValidator(a Account) error {
+	field, addr := "address", strings.TrimSpace(a.Address)
+	if addr == "" {
+		field, addr = "acc_no", strings.TrimSpace(a.AccNo)
+	}
+	if err := utils.ValidCryptoAddress(a.PayMethod, addr); err != nil {
+		return invalid(field, err.Error())
+	}
+	if err := utils.CheckCryptoNetwork(a.PayMethod, a.Network); err != nil {
+		return invalid("network", err.Error())
+	}
+	return nil
+}
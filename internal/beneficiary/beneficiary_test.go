@@ -0,0 +1,106 @@
+package beneficiary
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(string) bool
+		value string
+		want  bool
+	}{
+		{"CCI-有效", ValidCCI, "00219300245667903714", true},
+		{"CCI-带分隔符", ValidCCI, "002-193-002456679037-14", true},
+		{"CCI-校验位错误", ValidCCI, "00219300245667903715", false},
+		{"CCI-长度错误", ValidCCI, "0021930024566790371", false},
+		{"CLABE-有效", ValidCLABE, "032180000118359719", true},
+		{"CLABE-校验位错误", ValidCLABE, "032180000118359718", false},
+		{"Luhn-有效", ValidLuhn, "4111111111111111", true},
+		{"Luhn-无效", ValidLuhn, "4111111111111112", false},
+		{"CPF-有效", ValidCPF, "529.982.247-25", true},
+		{"CPF-校验位错误", ValidCPF, "52998224726", false},
+		{"CPF-全相同", ValidCPF, "11111111111", false},
+		{"CNPJ-有效", ValidCNPJ, "11.222.333/0001-81", true},
+		{"CNPJ-校验位错误", ValidCNPJ, "11222333000182", false},
+		{"PIX-CPF", ValidPixKey, "52998224725", true},
+		{"PIX-邮箱", ValidPixKey, "user@example.com", true},
+		{"PIX-手机", ValidPixKey, "+5511987654321", true},
+		{"PIX-随机键", ValidPixKey, "123e4567-e89b-12d3-a456-426614174000", true},
+		{"PIX-无效", ValidPixKey, "12345", false},
+		{"IBAN-英国", ValidIBAN, "GB82 WEST 1234 5698 7654 32", true},
+		{"IBAN-德国", ValidIBAN, "DE89370400440532013000", true},
+		{"IBAN-校验位错误", ValidIBAN, "GB82WEST12345698765431", false},
+		{"IFSC-有效", ValidIFSC, "SBIN0000300", true},
+		{"IFSC-第五位非0", ValidIFSC, "SBIN1000300", false},
+		{"印度账号-有效", ValidIndianAccount, "123456789012", true},
+		{"印度账号-过短", ValidIndianAccount, "12345678", false},
+		{"UPI-有效", ValidUPI, "name.surname@okaxis", true},
+		{"UPI-无效", ValidUPI, "name.surname", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(tt.value); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRegistry(t *testing.T) {
+	tests := []struct {
+		name      string
+		account   Account
+		wantField string
+	}{
+		{"秘鲁CCI", Account{Currency: "PEN", CciNo: "00219300245667903714"}, ""},
+		{"秘鲁CCI错误", Account{Currency: "PEN", CciNo: "00219300245667903715"}, "cci_no"},
+		{"秘鲁无CCI", Account{Currency: "PEN", AccNo: "191-12345678-0-12"}, ""},
+		{"墨西哥CLABE", Account{Currency: "MXN", AccNo: "032180000118359719"}, ""},
+		{"墨西哥账号长度", Account{Currency: "MXN", AccNo: "12345"}, "acc_no"},
+		{"巴西PIX", Account{Currency: "BRL", PayMethod: "pix", AccNo: "user@example.com"}, ""},
+		{"巴西PIX错误", Account{Currency: "BRL", PayMethod: "PIX", AccNo: "abc"}, "acc_no"},
+		{"巴西CPF证件", Account{Currency: "BRL", PayMethod: "TED", IdentityType: "cpf", IdentityNum: "52998224726"}, "identity_num"},
+		{"印度银行", Account{Currency: "INR", PayMethod: "IMPS", AccNo: "123456789012", BankCode: "SBIN0000300"}, ""},
+		{"印度平台银行编码", Account{Currency: "INR", PayMethod: "IMPS", AccNo: "123456789012", BankCode: "SBIN"}, ""},
+		{"印度缺银行编码", Account{Currency: "INR", PayMethod: "IMPS", AccNo: "123456789012"}, "bank_code"},
+		{"印度UPI", Account{Currency: "INR", PayMethod: "UPI", AccNo: "name@okaxis"}, ""},
+		{"欧元IBAN", Account{Currency: "EUR", AccNo: "DE89370400440532013000"}, ""},
+		{"SEPA错误IBAN", Account{Currency: "GBP", PayMethod: "SEPA", AccNo: "GB82WEST12345698765431"}, "acc_no"},
+		{"未注册币种", Account{Currency: "VND", AccNo: "anything"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.account)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("err = %v, want ValidationError", err)
+			}
+			if ve.Field != tt.wantField {
+				t.Errorf("field = %s, want %s", ve.Field, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestBankLookupCode(t *testing.T) {
+	if got := bankLookupCode(Account{Currency: "INR", BankCode: "sbin0000300"}); got != "SBIN" {
+		t.Errorf("INR IFSC lookup = %s, want SBIN", got)
+	}
+	if got := bankLookupCode(Account{Currency: "INR", BankCode: "SBIN"}); got != "SBIN" {
+		t.Errorf("INR bank code lookup = %s, want SBIN", got)
+	}
+	if got := bankLookupCode(Account{Currency: "PEN", BankCode: "BCP"}); got != "BCP" {
+		t.Errorf("PEN lookup = %s, want BCP", got)
+	}
+}
@@ -0,0 +1,176 @@
+package beneficiary
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var (
+	digitsOnly   = regexp.MustCompile(`^[0-9]+$`)
+	ifscPattern  = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+	ibanPattern  = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+	emailPattern = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+	upiPattern   = regexp.MustCompile(`^[a-zA-Z0-9.\-_]{2,256}@[a-zA-Z]{2,64}$`)
+	// PIX 手机号：+55 + 区号(2) + 号码(8~9)
+	pixPhonePattern = regexp.MustCompile(`^\+55[0-9]{10,11}$`)
+	// PIX 随机键（EVP）：UUID
+	pixEVPPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// stripSeparators 去掉空格、横线、点、斜杠等常见分隔符
+func stripSeparators(v string) string {
+	return strings.NewReplacer(" ", "", "-", "", ".", "", "/", "").Replace(strings.TrimSpace(v))
+}
+
+// ValidCCI 秘鲁银行间账户 CCI：20 位 = 银行(3) + 分行(3) + 账号(12) + 校验位(2)
+// 第一校验位校验 银行+分行，第二校验位校验 账号；逐位乘以 1、2 交替权重，乘积两位数时各位相加
+func ValidCCI(cci string) bool {
+	cci = stripSeparators(cci)
+	if len(cci) != 20 || !digitsOnly.MatchString(cci) {
+		return false
+	}
+	return cciCheckDigit(cci[0:6]) == cci[18] && cciCheckDigit(cci[6:18]) == cci[19]
+}
+
+func cciCheckDigit(part string) byte {
+	sum := 0
+	for i := 0; i < len(part); i++ {
+		p := int(part[i]-'0') * (1 + i%2)
+		sum += p/10 + p%10
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// ValidCLABE 墨西哥 CLABE：18 位，前 17 位按 3、7、1 循环加权取模 10，校验位 = (10 - sum%10) % 10
+func ValidCLABE(clabe string) bool {
+	clabe = stripSeparators(clabe)
+	if len(clabe) != 18 || !digitsOnly.MatchString(clabe) {
+		return false
+	}
+	weights := [3]int{3, 7, 1}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		sum += (int(clabe[i]-'0') * weights[i%3]) % 10
+	}
+	return byte('0'+(10-sum%10)%10) == clabe[17]
+}
+
+// ValidLuhn 银行卡号 Luhn 校验
+func ValidLuhn(number string) bool {
+	number = stripSeparators(number)
+	if len(number) < 12 || len(number) > 19 || !digitsOnly.MatchString(number) {
+		return false
+	}
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if (len(number)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// ValidCPF 巴西个人税号 CPF：11 位，两位校验位
+func ValidCPF(cpf string) bool {
+	cpf = stripSeparators(cpf)
+	if len(cpf) != 11 || !digitsOnly.MatchString(cpf) || strings.Count(cpf, cpf[:1]) == 11 {
+		return false
+	}
+	for n := 9; n <= 10; n++ {
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += int(cpf[i]-'0') * (n + 1 - i)
+		}
+		d := sum * 10 % 11
+		if d == 10 {
+			d = 0
+		}
+		if byte('0'+d) != cpf[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidCNPJ 巴西企业税号 CNPJ：14 位，两位校验位
+func ValidCNPJ(cnpj string) bool {
+	cnpj = stripSeparators(cnpj)
+	if len(cnpj) != 14 || !digitsOnly.MatchString(cnpj) || strings.Count(cnpj, cnpj[:1]) == 14 {
+		return false
+	}
+	weights := []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	for n := 12; n <= 13; n++ {
+		sum := 0
+		w := weights[13-n:]
+		for i := 0; i < n; i++ {
+			sum += int(cnpj[i]-'0') * w[i]
+		}
+		d := sum % 11
+		if d < 2 {
+			d = 0
+		} else {
+			d = 11 - d
+		}
+		if byte('0'+d) != cnpj[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidPixKey 巴西 PIX 收款键：CPF、CNPJ、邮箱、手机号(+55) 或随机键(UUID)
+func ValidPixKey(key string) bool {
+	key = strings.TrimSpace(key)
+	switch {
+	case key == "":
+		return false
+	case strings.Contains(key, "@"):
+		return len(key) <= 77 && emailPattern.MatchString(key)
+	case strings.HasPrefix(key, "+"):
+		return pixPhonePattern.MatchString(key)
+	case pixEVPPattern.MatchString(key):
+		return true
+	}
+	return ValidCPF(key) || ValidCNPJ(key)
+}
+
+// ValidIBAN IBAN：国家码 + 2 位校验 + BBAN，前 4 位移至末尾、字母转数字后 mod 97 == 1
+func ValidIBAN(iban string) bool {
+	iban = strings.ToUpper(stripSeparators(iban))
+	if !ibanPattern.MatchString(iban) {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	var sb strings.Builder
+	for _, r := range rearranged {
+		if r >= 'A' && r <= 'Z' {
+			sb.WriteString(big.NewInt(int64(r - 'A' + 10)).String())
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(sb.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// ValidIFSC 印度 IFSC：4 位银行字母 + 0 + 6 位分行编码
+func ValidIFSC(ifsc string) bool {
+	return ifscPattern.MatchString(strings.ToUpper(strings.TrimSpace(ifsc)))
+}
+
+// ValidIndianAccount 印度银行账号：9~18 位数字
+func ValidIndianAccount(accNo string) bool {
+	accNo = strings.TrimSpace(accNo)
+	return len(accNo) >= 9 && len(accNo) <= 18 && digitsOnly.MatchString(accNo)
+}
+
+// ValidUPI 印度 UPI VPA：handle@psp
+func ValidUPI(vpa string) bool {
+	return upiPattern.MatchString(strings.TrimSpace(vpa))
+}
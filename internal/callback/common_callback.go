@@ -7,9 +7,7 @@ import (
 )
 
 // verifyUpstreamWhitelist 校验上游供应商IP白名单
-func verifyUpstreamWhitelist(upstreamId uint64, ipAddress string) bool {
-	var mainDao *dao.MainDao
-	mainDao = dao.NewMainDao()
+func verifyUpstreamWhitelist(mainDao dao.MainRepository, upstreamId uint64, ipAddress string) bool {
 	upstream, err := mainDao.GetUpstreamWhitelist(upstreamId)
 	if err != nil || upstream == nil || upstream.Status != 1 {
 		return false
@@ -0,0 +1,127 @@
+package callback
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/event"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/system"
+)
+
+// dedupStaleAfter 认领超过该时长仍未完成视为处理进程已退出，允许重新认领
+const dedupStaleAfter = 5 * time.Minute
+
+// CallbackDedup 上游回调去重：消费端处理前按 (类型, 交易订单号, 回调状态, 上游消息ID) 原子认领
+type CallbackDedup struct {
+	repo dao.CallbackDedupRepository
+}
+
+func NewCallbackDedup() *CallbackDedup {
+	return NewCallbackDedupWithRepo(dao.NewCallbackDedupDao())
+}
+
+// NewCallbackDedupWithRepo 注入仓储（单元测试使用内存实现）
+func NewCallbackDedupWithRepo(repo dao.CallbackDedupRepository) *CallbackDedup {
+	return &CallbackDedup{repo: repo}
+}
+
+// Process 认领成功后执行 fn：
+//   - 相同回调已处理：不执行，返回 nil（消费端确认为空操作）
+//   - 相同回调处理中：返回临时错误，稍后重试
+//   - 相同终态以不同上游消息ID重发：按相同回调处理
+//   - 交易已有其他终态回调（如先成功后失败）：告警并返回 event.ManualReview
+//
+// fn 成功标记已处理；失败释放认领，重试或后续相同回调可重新处理
+func (d *CallbackDedup) Process(mode string, upOrderID uint64, status, msgID string, fn func() error) error {
+	now := time.Now()
+	rec := &ordermodel.CallbackDedupM{
+		Mode:       mode,
+		UpOrderID:  upOrderID,
+		Status:     status,
+		MsgID:      msgID,
+		State:      ordermodel.CallbackDedupProcessing,
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	if isTerminalCallbackStatus(status) {
+		rec.TerminalUpOrderID = &upOrderID
+	}
+
+	ok, err := d.repo.Claim(rec)
+	if err != nil {
+		return fmt.Errorf("回调去重认领失败: %w", err)
+	}
+	if !ok {
+		claimed, err := d.resolveClaimConflict(rec, now)
+		if err != nil || claimed == nil {
+			return err
+		}
+		rec = claimed
+	}
+
+	if err := fn(); err != nil {
+		if rErr := d.repo.Release(rec.ID); rErr != nil {
+			log.Printf("⚠️ [CALLBACK-DEDUP] 释放认领失败 mode=%s 交易订单号=%v: %v", mode, upOrderID, rErr)
+		}
+		return err
+	}
+	if err := d.repo.MarkDone(rec.ID, time.Now()); err != nil {
+		// 已处理但未标记，重复回调会再次进入处理，由订单终态校验拦截
+		log.Printf("⚠️ [CALLBACK-DEDUP] 标记已处理失败 mode=%s 交易订单号=%v: %v", mode, upOrderID, err)
+	}
+	return nil
+}
+
+// resolveClaimConflict 认领冲突时区分重复、处理中（可接管超时认领）与终态冲突；返回非空记录表示接管成功
+func (d *CallbackDedup) resolveClaimConflict(rec *ordermodel.CallbackDedupM, now time.Time) (*ordermodel.CallbackDedupM, error) {
+	existing, err := d.repo.GetByKey(rec.Mode, rec.UpOrderID, rec.Status, rec.MsgID)
+	if err != nil {
+		return nil, fmt.Errorf("回调去重查询失败: %w", err)
+	}
+	if existing != nil {
+		return d.resumeSameCallback(rec, existing, now)
+	}
+
+	terminal, err := d.repo.GetTerminal(rec.Mode, rec.UpOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("回调去重查询失败: %w", err)
+	}
+	if terminal == nil {
+		// 冲突记录已被释放，重试时重新认领
+		return nil, fmt.Errorf("回调去重认领冲突, 交易订单号: %v, 状态: %s", rec.UpOrderID, rec.Status)
+	}
+	if terminal.Status == rec.Status {
+		// 同一终态的重发（上游消息ID不同）：按重复回调处理，不转人工
+		return d.resumeSameCallback(rec, terminal, now)
+	}
+	notifyMsg := fmt.Sprintf("上游回调终态冲突，转人工复核\n类型: %s\n交易订单号: %v\n已处理状态: %s (上游消息ID: %s)\n当前回调状态: %s (上游消息ID: %s)",
+		rec.Mode, rec.UpOrderID, terminal.Status, terminal.MsgID, rec.Status, rec.MsgID)
+	notify.Notify(system.BotChatID, "warn", "上游回调状态冲突", notifyMsg, true)
+	return nil, event.ManualReview(errors.New(notifyMsg))
+}
+
+// resumeSameCallback 相同回调已有记录：已处理则忽略，处理中则接管超时认领或稍后重试
+func (d *CallbackDedup) resumeSameCallback(rec, existing *ordermodel.CallbackDedupM, now time.Time) (*ordermodel.CallbackDedupM, error) {
+	if existing.State == ordermodel.CallbackDedupDone {
+		log.Printf("♻️ [CALLBACK-DEDUP] 重复回调已忽略 mode=%s 交易订单号=%v 状态=%s 上游消息ID=%s (已处理消息ID=%s)",
+			rec.Mode, rec.UpOrderID, rec.Status, rec.MsgID, existing.MsgID)
+		return nil, nil
+	}
+	taken, err := d.repo.TakeOver(existing.ID, now.Add(-dedupStaleAfter), now)
+	if err != nil {
+		return nil, fmt.Errorf("回调去重接管失败: %w", err)
+	}
+	if !taken {
+		return nil, fmt.Errorf("相同回调正在处理中, 交易订单号: %v, 状态: %s", rec.UpOrderID, rec.Status)
+	}
+	return existing, nil
+}
+
+// isTerminalCallbackStatus 成功/失败为终态，同一交易只允许一个终态回调
+func isTerminalCallbackStatus(status string) bool {
+	return status == "0000" || status == "0005"
+}
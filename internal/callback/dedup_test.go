@@ -0,0 +1,129 @@
+package callback
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/event"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+func TestCallbackDedupDuplicateIsNoop(t *testing.T) {
+	store := memdao.NewCallbackDedupStore()
+	d := NewCallbackDedupWithRepo(store)
+
+	calls := 0
+	fn := func() error { calls++; return nil }
+	for i := 0; i < 2; i++ {
+		if err := d.Process("receive", testUpOrderID, "0000", "UP-8001", fn); err != nil {
+			t.Fatalf("#%d process: %v", i, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler calls = %d, want 1", calls)
+	}
+	recs := store.Records()
+	if len(recs) != 1 || recs[0].State != ordermodel.CallbackDedupDone || recs[0].TerminalUpOrderID == nil {
+		t.Errorf("records = %+v", recs)
+	}
+}
+
+func TestCallbackDedupFailureReleases(t *testing.T) {
+	store := memdao.NewCallbackDedupStore()
+	d := NewCallbackDedupWithRepo(store)
+
+	if err := d.Process("payout", testUpOrderID, "0000", "UP-1", func() error { return errors.New("db down") }); err == nil {
+		t.Fatal("want handler error")
+	}
+	if recs := store.Records(); len(recs) != 0 {
+		t.Fatalf("records = %+v, want released", recs)
+	}
+	calls := 0
+	if err := d.Process("payout", testUpOrderID, "0000", "UP-1", func() error { calls++; return nil }); err != nil || calls != 1 {
+		t.Errorf("retry err = %v, calls = %d", err, calls)
+	}
+}
+
+func TestCallbackDedupInFlight(t *testing.T) {
+	store := memdao.NewCallbackDedupStore()
+	d := NewCallbackDedupWithRepo(store)
+
+	err := d.Process("receive", testUpOrderID, "0000", "UP-1", func() error {
+		// 处理过程中相同回调再次到达：临时错误，稍后重试
+		inner := d.Process("receive", testUpOrderID, "0000", "UP-1", func() error {
+			t.Error("duplicate in flight must not be processed")
+			return nil
+		})
+		if inner == nil || event.IsPermanent(inner) {
+			t.Errorf("in-flight err = %v, want transient", inner)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+}
+
+func TestCallbackDedupTakesOverStaleClaim(t *testing.T) {
+	store := memdao.NewCallbackDedupStore()
+	stale := time.Now().Add(-2 * dedupStaleAfter)
+	if ok, _ := store.Claim(&ordermodel.CallbackDedupM{Mode: "receive", UpOrderID: testUpOrderID, Status: "0001", MsgID: "UP-1", CreateTime: stale, UpdateTime: stale}); !ok {
+		t.Fatal("seed claim")
+	}
+
+	calls := 0
+	if err := NewCallbackDedupWithRepo(store).Process("receive", testUpOrderID, "0001", "UP-1", func() error { calls++; return nil }); err != nil || calls != 1 {
+		t.Fatalf("err = %v, calls = %d, want stale claim taken over", err, calls)
+	}
+	if recs := store.Records(); len(recs) != 1 || recs[0].State != ordermodel.CallbackDedupDone {
+		t.Errorf("records = %+v", recs)
+	}
+}
+
+func TestCallbackDedupTerminalConflict(t *testing.T) {
+	store := memdao.NewCallbackDedupStore()
+	d := NewCallbackDedupWithRepo(store)
+	ok := func() error { return nil }
+
+	// 处理中状态不占终态，后续成功回调正常处理
+	if err := d.Process("payout", testUpOrderID, "0001", "UP-1", ok); err != nil {
+		t.Fatalf("pending: %v", err)
+	}
+	if err := d.Process("payout", testUpOrderID, "0000", "UP-1", ok); err != nil {
+		t.Fatalf("success: %v", err)
+	}
+	// 先成功后失败：转人工复核，不执行处理
+	err := d.Process("payout", testUpOrderID, "0005", "UP-1", func() error {
+		t.Error("conflicting callback must not be processed")
+		return nil
+	})
+	if !event.IsManualReview(err) {
+		t.Errorf("err = %v, want manual review", err)
+	}
+	// 不同类型的交易互不影响
+	if err := d.Process("receive", testUpOrderID, "0005", "UP-1", ok); err != nil {
+		t.Errorf("other mode: %v", err)
+	}
+}
+
+func TestCallbackDedupSameTerminalResendIsNoop(t *testing.T) {
+	store := memdao.NewCallbackDedupStore()
+	d := NewCallbackDedupWithRepo(store)
+
+	calls := 0
+	fn := func() error { calls++; return nil }
+	if err := d.Process("payout", testUpOrderID, "0000", "UP-1", fn); err != nil {
+		t.Fatalf("first: %v", err)
+	}
+	// 上游以新的消息ID重发相同终态：确认为空操作，不转人工
+	if err := d.Process("payout", testUpOrderID, "0000", "UP-2", fn); err != nil {
+		t.Fatalf("resend: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("handler calls = %d, want 1", calls)
+	}
+	if recs := store.Records(); len(recs) != 1 {
+		t.Errorf("records = %+v", recs)
+	}
+}
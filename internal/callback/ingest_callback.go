@@ -0,0 +1,208 @@
+package callback
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	mainmodel "wht-order-api/internal/model/main"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/outbox"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/system"
+	"wht-order-api/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// 直连回调投递的生产者（与 PHP 网关投递的回调队列相同）
+const (
+	receiveCallbackProducer = "receive_callback"
+	payoutCallbackProducer  = "payout_callback"
+)
+
+// IngestError 直连回调拒绝原因（Code 为平台错误码）
+type IngestError struct {
+	Code      int
+	Msg       string
+	Transient bool // 临时故障（入队失败、查库失败），上游应稍后重发
+}
+
+func (e *IngestError) Error() string {
+	return e.Msg
+}
+
+func ingestErr(code int, format string, args ...interface{}) *IngestError {
+	return &IngestError{Code: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+func transientIngestErr(code int, format string, args ...interface{}) *IngestError {
+	return &IngestError{Code: code, Msg: fmt.Sprintf(format, args...), Transient: true}
+}
+
+// CallbackIngest 上游直连回调入口：验签、校验来源 IP、归一化后投递到回调消费者，并归档原始报文
+// 投递使用发布确认模式且不走本地缓冲，broker 确认后才回复上游成功
+type CallbackIngest struct {
+	pub      outbox.Publisher
+	mainDao  dao.MainRepository
+	orderDao dao.OrderRepository
+}
+
+func NewCallbackIngest(pub outbox.Publisher) *CallbackIngest {
+	return NewCallbackIngestWithRepos(pub, dao.NewMainDao(), dao.NewOrderDao())
+}
+
+// NewCallbackIngestWithRepos 注入仓储（单元测试使用内存实现）
+func NewCallbackIngestWithRepos(pub outbox.Publisher, mainDao dao.MainRepository, orderDao dao.OrderRepository) *CallbackIngest {
+	return &CallbackIngest{pub: pub, mainDao: mainDao, orderDao: orderDao}
+}
+
+// Ingest 处理一次上游回调，成功返回回复上游的响应体
+func (s *CallbackIngest) Ingest(interfaceCode string, cb *connector.Callback) (string, error) {
+	archive := &mainmodel.UpstreamCallbackArchive{
+		InterfaceCode: interfaceCode,
+		Mode:          cb.Mode,
+		RemoteIP:      cb.RemoteIP,
+		Headers:       headersJSON(cb.Header),
+		Body:          string(cb.Body),
+		Result:        mainmodel.CallbackArchiveRejected,
+		CreateTime:    time.Now(),
+	}
+	defer s.archive(archive)
+
+	ack, err := s.ingest(interfaceCode, cb, archive)
+	if err != nil {
+		archive.Remark = utils.TruncateRunes(err.Error(), 255)
+		log.Printf("❌ [CALLBACK-INGEST] 接口: %s, 类型: %s, IP: %s, 处理失败: %v", interfaceCode, cb.Mode, cb.RemoteIP, err)
+		return "", err
+	}
+	archive.Result = mainmodel.CallbackArchiveAccepted
+	log.Printf("✅ [CALLBACK-INGEST] 接口: %s, 类型: %s, 交易订单号: %s, 状态: %s 已入队", interfaceCode, cb.Mode, archive.MOrderID, archive.UpStatus)
+	return ack, nil
+}
+
+func (s *CallbackIngest) ingest(interfaceCode string, cb *connector.Callback, archive *mainmodel.UpstreamCallbackArchive) (string, error) {
+	var producer string
+	switch cb.Mode {
+	case "receive":
+		producer = receiveCallbackProducer
+	case "payout":
+		producer = payoutCallbackProducer
+	default:
+		return "", ingestErr(constant.CodeInvalidParams, "不支持的回调类型: %s", cb.Mode)
+	}
+
+	conn, ok := connector.Get(interfaceCode)
+	if !ok {
+		return "", ingestErr(constant.CodeChannelNotFound, "接口 %s 未注册原生连接器", interfaceCode)
+	}
+
+	// 1. 验签
+	if err := conn.VerifyCallback(cb); err != nil {
+		s.alert(interfaceCode, cb, "回调验签失败", err.Error())
+		return "", ingestErr(constant.CodeNotifySignError, "回调验签失败: %v", err)
+	}
+
+	// 2. 解析报文
+	result, err := conn.ParseCallback(cb)
+	if err != nil {
+		return "", ingestErr(constant.CodeNotifyFormatError, "回调报文解析失败: %v", err)
+	}
+	archive.MOrderID = result.MOrderID
+	archive.UpOrderID = result.UpOrderID
+	archive.UpStatus = result.Status
+
+	// 3. 按交易订单定位上游供应商，校验实际来源 IP
+	mOrderIdNum, err := strconv.ParseUint(result.MOrderID, 10, 64)
+	if err != nil {
+		return "", ingestErr(constant.CodeNotifyFormatError, "交易订单号格式错误: %s", result.MOrderID)
+	}
+	txTable := shard.UpOrderShard.GetTable(mOrderIdNum, time.Now())
+	if cb.Mode == "payout" {
+		txTable = shard.UpOutOrderShard.GetTable(mOrderIdNum, time.Now())
+	}
+	upOrder, err := s.orderDao.GetTxByUpOrderId(txTable, mOrderIdNum)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ingestErr(constant.CodeOrderNotFound, "系统未找到交易订单号 %s: %v", result.MOrderID, err)
+		}
+		return "", transientIngestErr(constant.CodeServiceUnavailable, "查询交易订单 %s 失败: %v", result.MOrderID, err)
+	}
+	if !verifyUpstreamWhitelist(s.mainDao, upOrder.SupplierId, cb.RemoteIP) {
+		s.alert(interfaceCode, cb, "上游IP不在白名单内", fmt.Sprintf("供应商ID: %v, 交易订单号: %s", upOrder.SupplierId, result.MOrderID))
+		return "", ingestErr(constant.CodeIPNotWhitelisted, "回调IP %s 不在供应商 %v 白名单内", cb.RemoteIP, upOrder.SupplierId)
+	}
+
+	// 4. 归一化后投递到与 PHP 网关相同的回调队列（UpIpAddress 为实际观测到的来源 IP）
+	var msg any
+	if cb.Mode == "receive" {
+		msg = dto.ReceiveHyperfOrderMessage{
+			MOrderID:    result.MOrderID,
+			UpOrderID:   result.UpOrderID,
+			Amount:      result.Amount,
+			Status:      result.Status,
+			UpIpAddress: cb.RemoteIP,
+			Timestamp:   time.Now().Unix(),
+		}
+	} else {
+		msg = dto.PayoutHyperfOrderMessage{
+			MOrderID:    result.MOrderID,
+			UpOrderID:   result.UpOrderID,
+			Amount:      result.Amount,
+			Status:      result.Status,
+			UpIpAddress: cb.RemoteIP,
+			Timestamp:   time.Now().Unix(),
+		}
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return "", ingestErr(constant.CodeSystemError, "回调消息序列化失败: %v", err)
+	}
+	// 消息ID按 接口+交易订单号+状态 生成，上游重发时消费端可去重
+	msgID := fmt.Sprintf("%s:%s:%s", interfaceCode, result.MOrderID, result.Status)
+	if err := s.pub.PublishConfirm(producer, msgID, body); err != nil {
+		archive.Result = mainmodel.CallbackArchiveEnqueueFail
+		s.alert(interfaceCode, cb, "回调入队失败", err.Error())
+		return "", transientIngestErr(constant.CodeServiceUnavailable, "回调入队失败: %v", err)
+	}
+
+	if result.AckBody == "" {
+		return "success", nil
+	}
+	return result.AckBody, nil
+}
+
+// archive 归档失败只告警，不影响回调处理结果
+func (s *CallbackIngest) archive(archive *mainmodel.UpstreamCallbackArchive) {
+	if err := s.mainDao.CreateUpstreamCallbackArchive(archive); err != nil {
+		log.Printf("⚠️ [CALLBACK-INGEST] 回调报文归档失败: %v", err)
+		notify.Notify(system.BotChatID, "warn", "上游回调归档失败",
+			fmt.Sprintf("*接口:* `%s`\n*交易订单号:* `%s`\n*错误:* `%v`", archive.InterfaceCode, archive.MOrderID, err), true)
+	}
+}
+
+func (s *CallbackIngest) alert(interfaceCode string, cb *connector.Callback, title, detail string) {
+	notifyMsg := fmt.Sprintf(
+		"*接口:* `%s`\n"+
+			"*回调类型:* `%s`\n"+
+			"*回调IP:* `%s`\n"+
+			"*详情:* `%s`\n",
+		interfaceCode, cb.Mode, cb.RemoteIP, detail,
+	)
+	notify.Notify(system.BotChatID, "warn", "[直连回调] "+title, notifyMsg, true)
+}
+
+func headersJSON(h http.Header) string {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,115 @@
+package callback
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/shard"
+
+	"github.com/shopspring/decimal"
+)
+
+const testIngestInterface = "TEST_INGEST"
+
+// ingestConnector 直连回调连接器替身：验签通过，解析返回固定结果
+type ingestConnector struct {
+	connector.Connector
+	result connector.CallbackResult
+}
+
+func (c *ingestConnector) VerifyCallback(cb *connector.Callback) error { return nil }
+
+func (c *ingestConnector) ParseCallback(cb *connector.Callback) (*connector.CallbackResult, error) {
+	r := c.result
+	return &r, nil
+}
+
+type confirmCall struct {
+	topic, msgID string
+	body         []byte
+}
+
+type confirmPublisher struct {
+	err   error
+	calls []confirmCall
+}
+
+func (p *confirmPublisher) PublishConfirm(topic, messageID string, body []byte) error {
+	p.calls = append(p.calls, confirmCall{topic, messageID, body})
+	return p.err
+}
+
+func newIngestFixture(t *testing.T, pub *confirmPublisher, mOrderID string) (*CallbackIngest, *memdao.MainStore) {
+	t.Helper()
+	shard.InitShardEngines()
+	fakeredis.Use(t)
+
+	connector.Register(testIngestInterface, &ingestConnector{result: connector.CallbackResult{
+		MOrderID: mOrderID, UpOrderID: "UP-8001", Status: connector.StatusSuccess, Amount: decimal.NewFromInt(100),
+	}})
+
+	main := memdao.NewMainStore()
+	main.AddUpstream(testUpstreamID, testUpstreamIP, 1)
+	orders := memdao.NewOrderStore()
+	now := time.Now()
+	if err := orders.InsertTx(shard.UpOrderShard.GetTable(testUpOrderID, now), &ordermodel.UpstreamTx{
+		UpOrderId: testUpOrderID, OrderID: testOrderID, MerchantID: "1001", SupplierId: testUpstreamID,
+		Amount: decimal.NewFromInt(100), Currency: "BRL", CreateTime: &now,
+	}); err != nil {
+		t.Fatalf("seed tx: %v", err)
+	}
+	return NewCallbackIngestWithRepos(pub, main, orders), main
+}
+
+func ingestCallback() *connector.Callback {
+	return &connector.Callback{Mode: "receive", Body: []byte(`{}`), RemoteIP: testUpstreamIP}
+}
+
+func TestIngestPublishesWithConfirm(t *testing.T) {
+	pub := &confirmPublisher{}
+	svc, main := newIngestFixture(t, pub, "8001")
+
+	ack, err := svc.Ingest(testIngestInterface, ingestCallback())
+	if err != nil || ack != "success" {
+		t.Fatalf("ingest ack=%q err=%v", ack, err)
+	}
+	if len(pub.calls) != 1 || pub.calls[0].topic != receiveCallbackProducer ||
+		pub.calls[0].msgID != testIngestInterface+":8001:"+connector.StatusSuccess {
+		t.Fatalf("publish calls = %+v", pub.calls)
+	}
+	if a := main.Archives(); len(a) != 1 || a[0].Result != mainmodel.CallbackArchiveAccepted {
+		t.Fatalf("archive = %+v", a)
+	}
+}
+
+func TestIngestEnqueueFailureIsTransient(t *testing.T) {
+	svc, main := newIngestFixture(t, &confirmPublisher{err: errors.New("broker down")}, "8001")
+
+	_, err := svc.Ingest(testIngestInterface, ingestCallback())
+	var ie *IngestError
+	if !errors.As(err, &ie) || !ie.Transient {
+		t.Fatalf("err = %v, want transient IngestError", err)
+	}
+	if a := main.Archives(); len(a) != 1 || a[0].Result != mainmodel.CallbackArchiveEnqueueFail {
+		t.Fatalf("archive = %+v", a)
+	}
+}
+
+func TestIngestUnknownOrderIsPermanent(t *testing.T) {
+	pub := &confirmPublisher{}
+	svc, _ := newIngestFixture(t, pub, "9999")
+
+	_, err := svc.Ingest(testIngestInterface, ingestCallback())
+	var ie *IngestError
+	if !errors.As(err, &ie) || ie.Transient {
+		t.Fatalf("err = %v, want permanent IngestError", err)
+	}
+	if len(pub.calls) != 0 {
+		t.Fatalf("published %d messages for unknown order", len(pub.calls))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
 	"io"
@@ -12,12 +13,16 @@ import (
 	"strconv"
 	"strings"
 	"time"
-	"wht-order-api/internal/dal"
+	"wht-order-api/internal/config"
 	"wht-order-api/internal/dao"
 	"wht-order-api/internal/dto"
 	"wht-order-api/internal/event"
+	"wht-order-api/internal/lifecycle"
+	"wht-order-api/internal/metrics"
 	orderModel "wht-order-api/internal/model/order"
 	"wht-order-api/internal/notify"
+	"wht-order-api/internal/outbox"
+	"wht-order-api/internal/service"
 	"wht-order-api/internal/settlement"
 	"wht-order-api/internal/shard"
 	"wht-order-api/internal/system"
@@ -25,12 +30,33 @@ import (
 )
 
 type PayoutCallback struct {
-	pub event.Publisher
+	pub      event.Publisher
+	mainDao  dao.MainRepository
+	orderDao dao.PayoutOrderRepository
+	settle   *settlement.Settlement
+	limitSvc *service.LimitService
 }
 
 func NewPayoutCallback(pub event.Publisher) *PayoutCallback {
+	mainDao := dao.NewMainDao()
+	return &PayoutCallback{
+		pub:      pub,
+		mainDao:  mainDao,
+		orderDao: dao.NewPayoutOrderDao(),
+		settle:   settlement.NewSettlement(),
+		limitSvc: service.NewLimitService(mainDao),
+	}
+}
 
-	return &PayoutCallback{pub: pub}
+// NewPayoutCallbackWithRepos 注入仓储（单元测试使用内存实现）
+func NewPayoutCallbackWithRepos(pub event.Publisher, mainRepo dao.MainRepository, orderRepo dao.PayoutOrderRepository) *PayoutCallback {
+	return &PayoutCallback{
+		pub:      pub,
+		mainDao:  mainRepo,
+		orderDao: orderRepo,
+		settle:   settlement.NewSettlementWithRepos(mainRepo, nil), // 代付结算只操作主库资金
+		limitSvc: service.NewLimitService(mainRepo),
+	}
 }
 
 const (
@@ -45,22 +71,23 @@ func (s *PayoutCallback) HandleUpstreamCallback(msg *dto.PayoutHyperfOrderMessag
 		notifyMsg := fmt.Sprintf("交易订单号: %v,转换失败: %+v", mOrderIdNum, err)
 		notify.Notify(system.BotChatID, "warn", "代付回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.Permanent(errors.New(notifyMsg))
 	}
 
 	// 2) 获取上游订单
 	txTable := shard.UpOutOrderShard.GetTable(mOrderIdNum, time.Now())
-	var upOrder orderModel.UpstreamTx
-	if err := dal.OrderDB.Table(txTable).
-		Where("up_order_id = ?", mOrderIdNum).
-		First(&upOrder).Error; err != nil {
-		notifyMsg := fmt.Sprintf("系统未找到交易订单号，交易订单号: %v,平台订单号: %v,错误: %+v", mOrderIdNum, upOrder.OrderID, err)
+	upOrder, err := s.orderDao.GetTxByUpOrderId(txTable, mOrderIdNum)
+	if err == nil && upOrder == nil {
+		err = errors.New("record not found")
+	}
+	if err != nil {
+		notifyMsg := fmt.Sprintf("系统未找到交易订单号，交易订单号: %v,错误: %+v", mOrderIdNum, err)
 		notify.Notify(system.BotChatID, "warn", "代付回调商户", notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return errors.New(notifyMsg)
 	}
 
 	// 3) 验证上游IP
-	if !verifyUpstreamWhitelist(upOrder.SupplierId, msg.UpIpAddress) {
+	if !verifyUpstreamWhitelist(s.mainDao, upOrder.SupplierId, msg.UpIpAddress) {
 		title := "[代付回调] 上游IP不在白名单内"
 		notifyMsg := fmt.Sprintf(
 			"*供应商ID:* `%v`\n"+
@@ -72,129 +99,139 @@ func (s *PayoutCallback) HandleUpstreamCallback(msg *dto.PayoutHyperfOrderMessag
 		)
 		notify.Notify(system.BotChatID, "warn", title,
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.Permanent(errors.New(notifyMsg))
 	}
 	// 判断一下交易金额是否正确
 	if msg.Amount.Cmp(upOrder.Amount) != 0 {
 		notifyMsg := fmt.Sprintf("回调交易金额与订单金额不符,交易订单号: %v,平台订单号: %v,上游回调金额: %v,交易金额:%v", mOrderIdNum, upOrder.OrderID, msg.Amount, upOrder.Amount)
 		notify.Notify(system.BotChatID, "warn", "代付回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.Permanent(errors.New(notifyMsg))
 	}
 	// 4) 更新上游订单状态
 	newStatus := s.payoutGetUpStatusMessage(msg.Status)
 	upOrder.Status = newStatus
 	upOrder.UpOrderNo = msg.UpOrderID
 	upOrder.NotifyTime = utils.PtrTime(time.Now())
-	if err := dal.OrderDB.Table(txTable).
-		Where("up_order_id = ?", mOrderIdNum).
-		Updates(map[string]interface{}{
-			"status":      upOrder.Status,
-			"up_order_no": upOrder.UpOrderNo,
-			"notify_time": upOrder.NotifyTime,
-		}).Error; err != nil {
+	if err := s.orderDao.UpdateByWhere(txTable, map[string]interface{}{"up_order_id": mOrderIdNum}, map[string]interface{}{
+		"status":      upOrder.Status,
+		"up_order_no": upOrder.UpOrderNo,
+		"notify_time": upOrder.NotifyTime,
+	}); err != nil {
 		notifyMsg := fmt.Sprintf("更新订单交易信息失败,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, upOrder.OrderID, err)
 		notify.Notify(system.BotChatID, "warn", "代付回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return errors.New(notifyMsg)
 	}
 
 	// 5) 获取商户订单
 	orderTable := shard.OutOrderShard.GetTable(upOrder.OrderID, time.Now())
-	var order orderModel.MerchantOrder
-	if err := dal.OrderDB.Table(orderTable).
-		Where("order_id = ?", upOrder.OrderID).
-		First(&order).Error; err != nil {
+	order, err := s.orderDao.GetByOrderId(orderTable, upOrder.OrderID)
+	if err == nil && order == nil {
+		// 非代付订单时，尝试按商户提现单处理（提现复用代付上游交易表）
+		handled, wErr := service.NewWithdrawService(s.pub).HandleUpstreamCallback(upOrder.OrderID, mOrderIdNum, s.payoutConvertStatus(msg.Status), msg.UpOrderID)
+		if handled {
+			return wErr
+		}
+		err = errors.New("record not found")
+	}
+	if err != nil {
 		notifyMsg := fmt.Sprintf("平台订单号未找到,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, upOrder.OrderID, err)
 		notify.Notify(system.BotChatID, "warn", "代付回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return errors.New(notifyMsg)
 	}
 
-	// 更新商户订单状态
+	// 订单已改派到其他上游交易，旧交易回调仅更新交易记录
+	if order.UpOrderID != nil && *order.UpOrderID != mOrderIdNum {
+		log.Printf("[代付回调] 过期交易回调，订单已改派,交易订单号: %v,平台订单号: %v,当前交易订单号: %v,回调状态: %s",
+			mOrderIdNum, order.OrderID, *order.UpOrderID, s.payoutConvertStatus(msg.Status))
+		return nil
+	}
+
+	// 订单已是终态（成功/冲正退回/已驳回），重复或冲突回调进入人工核查，不重复结算
+	if order.Status == 2 || order.Status == 3 || order.Status == 4 {
+		notifyMsg := fmt.Sprintf("订单已是终态, 不能进行其他操作流程，进入人工核查阶段。交易订单号: %v,平台订单号: %v,订单状态: %v,回调状态: %s", mOrderIdNum, order.OrderID, s.payoutConvertStatus(utils.ConvertOrderStatus(order.Status)), s.payoutConvertStatus(msg.Status))
+		notify.Notify(system.BotChatID, "warn", "代付回调重复",
+			notifyMsg, true)
+		return event.ManualReview(errors.New(notifyMsg))
+	}
+
+	// 更新商户订单状态（成功时订单统计事件同事务写入发件箱）
+	statusText := s.payoutConvertStatus(msg.Status)
+	isSuccess := statusText == "SUCCESS"
+	var statEvt *orderModel.OutboxEventM
+	if isSuccess {
+		statEvt = s.payoutStatEvent(order, mOrderIdNum)
+	}
 	order.Status = newStatus
 	order.NotifyTime = utils.PtrTime(time.Now())
-	if err := dal.OrderDB.Table(orderTable).
-		Where("order_id = ?", upOrder.OrderID).
-		Updates(map[string]interface{}{
+	if err := s.orderDao.Transaction(func(orderDao dao.PayoutOrderRepository) error {
+		if err := orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": upOrder.OrderID}, map[string]interface{}{
 			"status":      order.Status,
 			"notify_time": order.NotifyTime,
-		}).Error; err != nil {
+		}); err != nil {
+			return err
+		}
+		if statEvt == nil {
+			return nil
+		}
+		return orderDao.InsertOutbox(statEvt)
+	}); err != nil {
 		notifyMsg := fmt.Sprintf("更新订单信息失败,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, upOrder.OrderID, err)
 		notify.Notify(system.BotChatID, "warn", "代付回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return errors.New(notifyMsg)
 	}
 
+	// 订单状态已提交，之后的失败重试也会被终态校验拦截，按永久错误转入死信人工处理
 	// 6) 校验商户
-	mainDao := dao.NewMainDao()
-	merchant, err := mainDao.GetMerchantId(upOrder.MerchantID)
+	merchant, err := s.mainDao.GetMerchantId(upOrder.MerchantID)
 	if err != nil || merchant == nil || merchant.Status != 1 {
 		notifyMsg := fmt.Sprintf("商户没有找到，商户不存在或者未启动,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, upOrder.OrderID, err)
 		notify.Notify(system.BotChatID, "warn", "代付回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.Permanent(errors.New(notifyMsg))
 	}
 
 	// 7) 结算逻辑: 成功时结算资金，失败时进入人工流程，不进行资金操作
-	statusText := s.payoutConvertStatus(msg.Status)
-	isSuccess := statusText == "SUCCESS"
-
-	// 成功时结算资金
 	if isSuccess {
-		settleService := settlement.NewSettlement()
 		settlementResult := dto.SettlementResult(order.SettleSnapshot)
-		if err := settleService.DoPayoutSettlement(settlementResult,
+		if err := s.settle.DoPayoutSettlement(settlementResult,
 			strconv.FormatUint(merchant.MerchantID, 10),
 			order.OrderID,
 			order.MOrderID,
 			isSuccess,
 			order.Amount,
 		); err != nil {
+			metrics.SettlementFailed("payout", order.Currency)
 			notifyMsg := fmt.Sprintf("结算失败\n交易订单号: %v\n平台订单号: %v\n商户订单号: %v\n错误: %v", mOrderIdNum, order.OrderID, order.MOrderID, err)
 			notify.Notify(system.BotChatID, "warn", "代付回调商户",
 				notifyMsg, true)
-			return fmt.Errorf(notifyMsg)
+			return event.Permanent(errors.New(notifyMsg))
 		}
 	}
 
-	// 8) 异步统计（仅成功时）
+	// 8) 记录成功出款的收款账户（统计事件已随状态变更写入发件箱），累计限额确认计入
 	if isSuccess {
-		go func() {
-			country, cErr := mainDao.GetCountry(order.Currency)
-			if cErr != nil {
-				notifyMsg := fmt.Sprintf("订单统计失败,交易订单号: %v,平台订单号:%v,商户订单号:%v,获取国家信息异常: %v", mOrderIdNum, order.OrderID, order.MOrderID, cErr)
-				notify.Notify(system.BotChatID, "warn", "代付回调商户",
-					notifyMsg, true)
-				log.Printf(notifyMsg)
-			}
-			if err := s.pub.Publish("order_stat", &dto.OrderMessageMQ{
-				OrderID:       strconv.FormatUint(order.OrderID, 10),
-				MerchantID:    order.MID,
-				CountryID:     country.ID,
-				ChannelID:     order.ChannelID,
-				SupplierID:    order.SupplierID,
-				Amount:        decimal.Zero,
-				SuccessAmount: order.Amount,
-				Profit:        *order.Profit,
-				Cost:          *order.Cost,
-				Fee:           order.Fees,
-				Status:        2,
-				OrderType:     "payout",
-				Currency:      order.Currency,
-				CreateTime:    time.Now(),
-			}); err != nil {
-				notifyMsg := fmt.Sprintf("订单统计失败,交易订单号: %v,平台订单号:%v,商户订单号:%v,推送到队列异常: %v", mOrderIdNum, order.OrderID, order.MOrderID, err)
-				notify.Notify(system.BotChatID, "warn", "代付回调商户",
-					notifyMsg, true)
-				return
-			}
-		}()
+		s.limitSvc.Settle(order.OrderID)
+		lifecycle.Go("payout-beneficiary", func() {
+			service.RecordPayoutBeneficiary(order.MID, order.Currency, order.AccountNo, order.OrderID)
+		})
 	}
 	//代付订单失败不直接给商户推送消息
 	if statusText == "FAIL" {
+		// 自动改派：选择下一个上游重新提交，改派用尽后进入人工流程
+		if config.C.Reassign.Enabled {
+			handled, rErr := service.NewAutoReassignServiceWithRepos(s.pub, s.mainDao, s.orderDao).HandlePayoutFailure(order.OrderID, mOrderIdNum, fmt.Sprintf("上游回调失败, 上游流水号: %s", msg.UpOrderID))
+			if rErr != nil {
+				log.Printf("[代付回调] 自动改派异常,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, order.OrderID, rErr)
+			} else if handled {
+				return nil
+			}
+		}
 		notifyMsg := fmt.Sprintf("[代付回调] 代付订单，上游支付失败，不自动进行下游商户通知推送，进入人工改派流程\n\n交易订单号: %v\n\n平台订单号: %v\n\n商户订单号:%v\n\n订单状态: %s\n", mOrderIdNum, order.OrderID, order.MOrderID, "上游支付失败")
-		log.Printf(notifyMsg)
+		log.Print(notifyMsg)
 		notify.Notify(system.BotChatID, "warn", "[代付回调-人工流程]",
 			notifyMsg, true)
 		return nil
@@ -213,6 +250,7 @@ func (s *PayoutCallback) HandleUpstreamCallback(msg *dto.PayoutHyperfOrderMessag
 	var lastErr error
 	for i := 1; i <= payoutMaxRetry; i++ {
 		lastErr = s.payoutNotifyMerchant(merchant.NickName, order.NotifyURL, payload)
+		metrics.MerchantNotified("payout", lastErr)
 		if lastErr == nil {
 			log.Printf("✅ [代付回调]成功通知商户信息,商户号: %v,商户名称: %v,交易订单号: %v,平台订单号: %v,商户订单号: %v, 通知次数: %d", merchant.AppId, merchant.NickName, mOrderIdNum, order.OrderID, order.MOrderID, i)
 			return nil
@@ -220,14 +258,46 @@ func (s *PayoutCallback) HandleUpstreamCallback(msg *dto.PayoutHyperfOrderMessag
 		notifyMsg := fmt.Sprintf("[代付回调]失败通知商户信息\n商户号: %v\n商户名称: %v\n交易订单号: %v\n平台订单号: %v\n商户订单号: %v\n(通知次数: %d/%d)\n错误: %v", merchant.AppId, merchant.NickName, mOrderIdNum, order.OrderID, order.MOrderID, i, receiveMaxRetry, lastErr)
 		notify.Notify(system.BotChatID, "warn", "代付回调",
 			notifyMsg, true)
-		log.Printf(notifyMsg)
+		log.Print(notifyMsg)
 		time.Sleep(time.Duration(i*2) * time.Second)
 	}
 
 	notifyMsg := fmt.Sprintf("[代付回调]失败通知商户信息\n商户号: %v\n商户名称: %v\n交易订单号: %v\n平台订单号: %v\n商户订单号: %v\n(通知次数: %d)\n错误: %v", merchant.AppId, merchant.NickName, mOrderIdNum, order.OrderID, order.MOrderID, receiveMaxRetry, lastErr)
 	notify.Notify(system.BotChatID, "warn", "代付回调",
 		notifyMsg, true)
-	return fmt.Errorf(notifyMsg)
+	return event.Permanent(errors.New(notifyMsg))
+}
+
+// payoutStatEvent 代付成功订单统计事件（获取国家信息失败时仍写入，国家为空）
+func (s *PayoutCallback) payoutStatEvent(order *orderModel.MerchantPayOutOrderM, mOrderIdNum uint64) *orderModel.OutboxEventM {
+	country, cErr := s.mainDao.GetCountry(order.Currency)
+	if cErr != nil {
+		notifyMsg := fmt.Sprintf("订单统计失败,交易订单号: %v,平台订单号:%v,商户订单号:%v,获取国家信息异常: %v", mOrderIdNum, order.OrderID, order.MOrderID, cErr)
+		notify.Notify(system.BotChatID, "warn", "代付回调商户",
+			notifyMsg, true)
+		log.Print(notifyMsg)
+	}
+	evt, err := outbox.NewOrderStat(&dto.OrderMessageMQ{
+		OrderID:       strconv.FormatUint(order.OrderID, 10),
+		MerchantID:    order.MID,
+		CountryID:     country.ID,
+		ChannelID:     order.ChannelID,
+		SupplierID:    order.SupplierID,
+		Amount:        decimal.Zero,
+		SuccessAmount: order.Amount,
+		Profit:        *order.Profit,
+		Cost:          *order.Cost,
+		Fee:           order.Fees,
+		Status:        2,
+		OrderType:     "payout",
+		Currency:      order.Currency,
+		CreateTime:    time.Now(),
+	})
+	if err != nil {
+		log.Printf("[代付回调] 构造订单统计事件失败,平台订单号: %v,错误: %v", order.OrderID, err)
+		return nil
+	}
+	return evt
 }
 
 // notifyPayoutCallback 通知 Telegram 封装
@@ -337,7 +407,7 @@ func (s *PayoutCallback) payoutUpdateMerchantOrder(orderId string, notifyStatus
 	}
 
 	// 更新数据库
-	if err := dal.OrderDB.Table(orderTable).Where("order_id = ?", id).Updates(updateData).Error; err != nil {
+	if err := s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": id}, updateData); err != nil {
 		return fmt.Errorf("[代付回调] notify update merchant order failed with MOrderID %v: %w", id, err)
 	}
 
@@ -0,0 +1,327 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
+	"wht-order-api/internal/idgen"
+	"wht-order-api/internal/lifecycle"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/service"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	testAgentID           = uint64(2001)
+	testPayoutChannelCode = "BR_PIX_OUT"
+)
+
+// payoutConnector 原生连接器替身：余额充足，代付下单受理（reject 时同步返回失败）
+type payoutConnector struct {
+	connector.Connector
+	calls  int
+	reject bool
+}
+
+func (c *payoutConnector) Balance(ctx context.Context, req dto.UpstreamRequest) (decimal.Decimal, error) {
+	return decimal.NewFromInt(100000), nil
+}
+
+func (c *payoutConnector) CreatePayout(ctx context.Context, req dto.UpstreamRequest) (*connector.OrderResult, error) {
+	c.calls++
+	if c.reject {
+		return &connector.OrderResult{Status: connector.StatusFail, Code: "ACCOUNT_INVALID", Msg: "invalid account"}, nil
+	}
+	return &connector.OrderResult{UpOrderNo: "UP-P-" + req.MchOrderId, Status: connector.StatusPending}, nil
+}
+
+type payoutFixture struct {
+	main      *memdao.MainStore
+	orders    *memdao.PayoutOrderStore
+	conn      *payoutConnector
+	svc       *service.PayoutOrderService
+	cb        *PayoutCallback
+	notifyUrl string
+	notified  chan dto.PayoutNotifyMerchantPayload
+}
+
+// newPayoutFixture 商户 1001 开通 BR_PIX_OUT（费率 2%，直属代理抽 0.4%），余额 balance BRL，上游走 fake 原生连接器
+func newPayoutFixture(t *testing.T, balance string) *payoutFixture {
+	t.Helper()
+	shard.InitShardEngines()
+	fakeredis.Use(t)
+	if err := idgen.InitNode("default", 1); err != nil {
+		t.Fatalf("idgen: %v", err)
+	}
+	prevTimeout, prevApproval, prevReassign := config.C.Upstream.Timeout.Payout, config.C.Approval.Enabled, config.C.Reassign.Enabled
+	config.C.Upstream.Timeout.Payout = 5 * time.Second
+	config.C.Approval.Enabled = false
+	config.C.Reassign.Enabled = false
+	t.Cleanup(func() {
+		config.C.Upstream.Timeout.Payout, config.C.Approval.Enabled, config.C.Reassign.Enabled = prevTimeout, prevApproval, prevReassign
+	})
+
+	f := &payoutFixture{
+		main:     memdao.NewMainStore(),
+		orders:   memdao.NewPayoutOrderStore(),
+		conn:     &payoutConnector{},
+		notified: make(chan dto.PayoutNotifyMerchantPayload, 4),
+	}
+	connector.Register("test_payout_flow", f.conn)
+	merchantSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.PayoutNotifyMerchantPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		f.notified <- payload
+		_, _ = w.Write([]byte("success"))
+	}))
+	t.Cleanup(merchantSrv.Close)
+	f.notifyUrl = merchantSrv.URL
+
+	f.main.AddMerchant(mainmodel.Merchant{MerchantID: testMerchantID, NickName: "m1", AppId: "APP1001", ApiKey: "secret", Status: 1, PId: testAgentID, Currency: "BRL"})
+	f.main.AddAgentMerchant(mainmodel.AgentMerchant{AID: int64(testAgentID), MID: int64(testMerchantID), SysChannelID: 8, Status: 1, DefaultRate: decimal.RequireFromString("0.4")})
+	f.main.AddUpstream(testUpstreamID, testUpstreamIP, 1)
+	f.main.AddCountry(dto.CurrencyCodeResponse{ID: 76, Code: "BRL", Country: "Brazil"})
+	f.main.AddSysChannel(dto.PayWayVo{Id: 8, Title: "PIX 代付", Currency: "BRL", Coding: testPayoutChannelCode, Type: 2, Status: 1})
+	f.main.AddMerchantChannel(dto.MerchantChannelDTO{
+		MId: testMerchantID, SysChannelId: 8, Status: 1, Type: 2, DispatchMode: 1, Currency: "BRL",
+		SysChannelCode: testPayoutChannelCode, DefaultRate: decimal.NewFromInt(2),
+	})
+	minAmount, maxAmount := decimal.NewFromInt(10), decimal.NewFromInt(5000)
+	f.main.AddPayProduct(testMerchantID, dto.PayProductVo{
+		ID: 21, Currency: "BRL", Type: 2, Status: 1,
+		UpstreamId: int64(testUpstreamID), UpstreamCode: "UP_PIX_OUT", UpstreamTitle: "up1", UpstreamWeight: 10, InterfaceCode: "test_payout_flow",
+		SysChannelID: 8, SysChannelCode: testPayoutChannelCode, SysChannelTitle: "PIX 代付",
+		MDefaultRate: decimal.NewFromInt(2), CostRate: decimal.NewFromInt(1),
+		MinAmount: &minAmount, MaxAmount: &maxAmount,
+	})
+	f.main.SetAccount(testMerchantID, "BRL", decimal.RequireFromString(balance), decimal.Zero)
+
+	f.svc = service.NewPayoutOrderServiceWithRepos(&recordPublisher{}, f.main, f.orders, f.orders.Index)
+	t.Cleanup(f.svc.Shutdown)
+	f.cb = NewPayoutCallbackWithRepos(&recordPublisher{}, f.main, f.orders)
+	return f
+}
+
+func (f *payoutFixture) create(t *testing.T, tranFlow, amount string) (ordermodel.MerchantPayOutOrderM, ordermodel.PayoutUpstreamTxM) {
+	t.Helper()
+	resp, err := f.svc.Create(dto.CreatePayoutOrderReq{
+		MerchantNo: "APP1001", TranFlow: tranFlow, Amount: amount, PayType: testPayoutChannelCode,
+		AccNo: "12345678", AccName: "Joao", PayMethod: "PIX", NotifyUrl: f.notifyUrl,
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	waitBackground(t)
+	orders, txs := f.orders.Orders(), f.orders.Txs()
+	if len(orders) != 1 || len(txs) != 1 {
+		t.Fatalf("orders/txs = %d/%d, want 1/1", len(orders), len(txs))
+	}
+	if resp.Code != "0" || resp.PaySerialNo != strconv.FormatUint(orders[0].OrderID, 10) {
+		t.Fatalf("resp = %+v", resp)
+	}
+	return orders[0], txs[0]
+}
+
+// waitBackground 等待下单后的异步绑定/缓存任务完成（依赖 fakeredis，需在用例结束前收尾）
+func waitBackground(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := lifecycle.Wait(ctx); err != nil {
+		t.Fatalf("background tasks: %v", err)
+	}
+}
+
+func (f *payoutFixture) order(t *testing.T, orderID uint64) *ordermodel.MerchantPayOutOrderM {
+	t.Helper()
+	o, err := f.orders.GetByOrderId(shard.OutOrderShard.GetTable(orderID, time.Now()), orderID)
+	if err != nil || o == nil {
+		t.Fatalf("load order: %v", err)
+	}
+	return o
+}
+
+func (f *payoutFixture) balance(t *testing.T, uid uint64) (money, freeze decimal.Decimal) {
+	t.Helper()
+	acc, ok := f.main.Account(uid, "BRL")
+	if !ok {
+		t.Fatalf("account %d not found", uid)
+	}
+	return acc.Money, acc.FreezeMoney
+}
+
+func payoutMsg(tx ordermodel.PayoutUpstreamTxM, status string) *dto.PayoutHyperfOrderMessage {
+	return &dto.PayoutHyperfOrderMessage{
+		MOrderID:    strconv.FormatUint(tx.UpOrderId, 10),
+		UpOrderID:   tx.UpOrderNo,
+		Amount:      tx.Amount,
+		Status:      status,
+		UpIpAddress: testUpstreamIP,
+		Timestamp:   time.Now().Unix(),
+	}
+}
+
+func logTypes(logs []mainmodel.MoneyLog) []int8 {
+	out := make([]int8, 0, len(logs))
+	for _, l := range logs {
+		out = append(out, l.Type)
+	}
+	return out
+}
+
+func TestPayoutCreateThenCallbackSuccess(t *testing.T) {
+	f := newPayoutFixture(t, "1200")
+
+	order, tx := f.create(t, "P-7001", "500")
+	// 下单冻结：订单金额 500 + 商户手续费 10（2%）+ 代理分润 2（0.4%）
+	if !order.FreezeAmount.Equal(decimal.NewFromInt(512)) || order.Status != 1 {
+		t.Fatalf("order = freeze %s, status %d", order.FreezeAmount, order.Status)
+	}
+	if tx.OrderID != order.OrderID || tx.UpOrderNo != "UP-P-"+strconv.FormatUint(tx.UpOrderId, 10) || f.conn.calls != 1 {
+		t.Fatalf("tx = %+v, upstream calls %d", tx, f.conn.calls)
+	}
+	if money, freeze := f.balance(t, testMerchantID); !money.Equal(decimal.NewFromInt(688)) || !freeze.Equal(decimal.NewFromInt(512)) {
+		t.Fatalf("after create = %s/%s, want 688/512", money, freeze)
+	}
+
+	if err := f.cb.HandleUpstreamCallback(payoutMsg(tx, "0000")); err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+
+	o := f.order(t, order.OrderID)
+	if o.Status != 2 || o.FinishTime == nil || o.NotifyStatus == nil || *o.NotifyStatus != 1 {
+		t.Errorf("order = status %d, finishTime %v, notifyStatus %v", o.Status, o.FinishTime, o.NotifyStatus)
+	}
+	// 成功从冻结扣除，余额不变；代理按快照分润
+	if money, freeze := f.balance(t, testMerchantID); !money.Equal(decimal.NewFromInt(688)) || !freeze.IsZero() {
+		t.Errorf("after callback = %s/%s, want 688/0", money, freeze)
+	}
+	if got := logTypes(f.main.MoneyLogs(testMerchantID)); len(got) != 2 || got[0] != dto.MoneyLogTypeFreeze || got[1] != dto.MoneyLogTypePayout {
+		t.Errorf("merchant money log types = %v, want [freeze payout]", got)
+	}
+	if money, _ := f.balance(t, testAgentID); !money.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("agent commission = %s, want 2", money)
+	}
+	// 下单与成功各一条订单统计事件
+	if evts := f.orders.Outbox.Events(); len(evts) != 2 {
+		t.Errorf("outbox events = %d, want 2", len(evts))
+	}
+	select {
+	case p := <-f.notified:
+		if p.TranFlow != "P-7001" || p.Status != "0000" || p.MerchantNo != "APP1001" || p.Sign == "" {
+			t.Errorf("merchant payload = %+v", p)
+		}
+	default:
+		t.Error("merchant was not notified")
+	}
+
+	// 重复回调：订单已终态，进入人工核查，不重复扣减冻结
+	err := f.cb.HandleUpstreamCallback(payoutMsg(tx, "0000"))
+	if err == nil || !event.IsManualReview(err) {
+		t.Fatalf("duplicate err = %v, want manual review", err)
+	}
+	if n := len(f.main.MoneyLogs(testMerchantID)); n != 2 {
+		t.Errorf("merchant money logs = %d after duplicate, want 2", n)
+	}
+}
+
+func TestPayoutCallbackFailKeepsFreeze(t *testing.T) {
+	f := newPayoutFixture(t, "1200")
+	order, tx := f.create(t, "P-7002", "500")
+
+	// 上游失败不直接解冻、不通知商户，资金保持冻结等待改派/人工处理
+	if err := f.cb.HandleUpstreamCallback(payoutMsg(tx, "0005")); err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if o := f.order(t, order.OrderID); o.Status != 3 {
+		t.Errorf("order status = %d, want 3", o.Status)
+	}
+	if money, freeze := f.balance(t, testMerchantID); !money.Equal(decimal.NewFromInt(688)) || !freeze.Equal(decimal.NewFromInt(512)) {
+		t.Errorf("balance = %s/%s, want 688/512", money, freeze)
+	}
+	if got := logTypes(f.main.MoneyLogs(testMerchantID)); len(got) != 1 || got[0] != dto.MoneyLogTypeFreeze {
+		t.Errorf("merchant money log types = %v, want [freeze]", got)
+	}
+	select {
+	case p := <-f.notified:
+		t.Errorf("merchant notified on failure: %+v", p)
+	default:
+	}
+}
+
+func TestPayoutCreateInsufficientBalance(t *testing.T) {
+	f := newPayoutFixture(t, "500")
+
+	// 需冻结 512，余额 500：拒绝下单，不落库、不冻结、不调用上游
+	_, err := f.svc.Create(dto.CreatePayoutOrderReq{
+		MerchantNo: "APP1001", TranFlow: "P-7003", Amount: "500", PayType: testPayoutChannelCode,
+		AccNo: "12345678", AccName: "Joao", PayMethod: "PIX",
+	})
+	if err == nil || !strings.Contains(err.Error(), "insufficient balance") {
+		t.Fatalf("create err = %v, want insufficient balance", err)
+	}
+	if n := len(f.orders.Orders()); n != 0 || f.conn.calls != 0 {
+		t.Errorf("orders = %d, upstream calls = %d, want 0/0", n, f.conn.calls)
+	}
+	if money, freeze := f.balance(t, testMerchantID); !money.Equal(decimal.NewFromInt(500)) || !freeze.IsZero() {
+		t.Errorf("balance = %s/%s, want 500/0", money, freeze)
+	}
+}
+
+func TestPayoutCreateUpstreamRejected(t *testing.T) {
+	f := newPayoutFixture(t, "1200")
+	f.conn.reject = true
+	// 错误码映射走缓存：账户无效不可切换通道
+	mapping := utils.MapToJSON([]mainmodel.UpstreamErrorMapping{{InterfaceCode: "test_payout_flow", UpstreamCode: "ACCOUNT_INVALID", Code: constant.CodeUpstreamInvalidAccount, Status: 1}})
+	dal.RedisClient.Set(dal.RedisCtx, "upstream_err_map:test_payout_flow", mapping, time.Minute)
+
+	// 上游同步明确拒绝：订单失败、冻结退回，返回平台流水号与失败状态
+	resp, err := f.svc.Create(dto.CreatePayoutOrderReq{
+		MerchantNo: "APP1001", TranFlow: "P-7004", Amount: "500", PayType: testPayoutChannelCode,
+		AccNo: "12345678", AccName: "Joao", PayMethod: "PIX", NotifyUrl: f.notifyUrl,
+	})
+	var ue *service.UpstreamError
+	if !errors.As(err, &ue) || ue.Code != constant.CodeUpstreamInvalidAccount {
+		t.Fatalf("create err = %v, want invalid account", err)
+	}
+	waitBackground(t)
+	orders := f.orders.Orders()
+	if len(orders) != 1 || f.conn.calls != 1 {
+		t.Fatalf("orders = %d, upstream calls = %d, want 1/1", len(orders), f.conn.calls)
+	}
+	if resp.PaySerialNo != strconv.FormatUint(orders[0].OrderID, 10) || resp.Status != "0005" {
+		t.Errorf("resp = %+v", resp)
+	}
+	if o := f.order(t, orders[0].OrderID); o.Status != 3 || o.FinishTime == nil {
+		t.Errorf("order = status %d, finishTime %v, want 3/set", o.Status, o.FinishTime)
+	}
+	if money, freeze := f.balance(t, testMerchantID); !money.Equal(decimal.NewFromInt(1200)) || !freeze.IsZero() {
+		t.Errorf("balance = %s/%s, want 1200/0", money, freeze)
+	}
+	select {
+	case p := <-f.notified:
+		if p.TranFlow != "P-7004" || p.Status != "0005" {
+			t.Errorf("merchant payload = %+v", p)
+		}
+	default:
+		t.Error("merchant was not notified")
+	}
+}
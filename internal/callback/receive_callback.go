@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
 	"io"
@@ -14,12 +15,14 @@ import (
 	"strconv"
 	"strings"
 	"time"
-	"wht-order-api/internal/dal"
 	"wht-order-api/internal/dao"
 	"wht-order-api/internal/dto"
 	"wht-order-api/internal/event"
-	orderModel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/metrics"
+	ordermodel "wht-order-api/internal/model/order"
 	"wht-order-api/internal/notify"
+	"wht-order-api/internal/outbox"
+	"wht-order-api/internal/service"
 	"wht-order-api/internal/settlement"
 	"wht-order-api/internal/shard"
 	"wht-order-api/internal/system"
@@ -27,11 +30,33 @@ import (
 )
 
 type ReceiveCallback struct {
-	pub event.Publisher
+	pub      event.Publisher
+	mainDao  dao.MainRepository
+	orderDao dao.OrderRepository
+	settle   *settlement.Settlement
+	limitSvc *service.LimitService
 }
 
 func NewReceiveCallback(pub event.Publisher) *ReceiveCallback {
-	return &ReceiveCallback{pub: pub}
+	mainDao := dao.NewMainDao()
+	return &ReceiveCallback{
+		pub:      pub,
+		mainDao:  mainDao,
+		orderDao: dao.NewOrderDao(),
+		settle:   settlement.NewSettlement(),
+		limitSvc: service.NewLimitService(mainDao),
+	}
+}
+
+// NewReceiveCallbackWithRepos 注入仓储（单元测试使用内存实现）
+func NewReceiveCallbackWithRepos(pub event.Publisher, mainRepo dao.MainRepository, orderRepo dao.OrderRepository) *ReceiveCallback {
+	return &ReceiveCallback{
+		pub:      pub,
+		mainDao:  mainRepo,
+		orderDao: orderRepo,
+		settle:   settlement.NewSettlementWithRepos(mainRepo, orderRepo),
+		limitSvc: service.NewLimitService(mainRepo),
+	}
 }
 
 const (
@@ -46,18 +71,18 @@ func (s *ReceiveCallback) HandleUpstreamCallback(msg *dto.ReceiveHyperfOrderMess
 		notifyMsg := fmt.Sprintf("交易订单号: %v,转换失败: %+v", mOrderIdNum, err)
 		notify.Notify(system.BotChatID, "warn", "代收回调异常",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.Permanent(errors.New(notifyMsg))
 	}
 	txTable := shard.UpOrderShard.GetTable(mOrderIdNum, time.Now())
 
-	var upOrder orderModel.UpstreamTx
-	if err := dal.OrderDB.Table(txTable).Where("up_order_id = ?", mOrderIdNum).First(&upOrder).Error; err != nil {
-		notifyMsg := fmt.Sprintf("系统未找到交易订单号，交易订单号: %v,平台订单号: %v,错误: %+v", mOrderIdNum, upOrder.OrderID, err)
+	upOrder, err := s.orderDao.GetTxByUpOrderId(txTable, mOrderIdNum)
+	if err != nil {
+		notifyMsg := fmt.Sprintf("系统未找到交易订单号，交易订单号: %v,错误: %+v", mOrderIdNum, err)
 		notify.Notify(system.BotChatID, "warn", "代收回调异常", notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return errors.New(notifyMsg)
 	}
 	// 验证上游供应商IP
-	if !verifyUpstreamWhitelist(upOrder.SupplierId, msg.UpIpAddress) {
+	if !verifyUpstreamWhitelist(s.mainDao, upOrder.SupplierId, msg.UpIpAddress) {
 		title := "[代收回调] 上游IP不在白名单内"
 		notifyMsg := fmt.Sprintf(
 			"*供应商ID:* `%v`\n"+
@@ -69,34 +94,27 @@ func (s *ReceiveCallback) HandleUpstreamCallback(msg *dto.ReceiveHyperfOrderMess
 		)
 		notify.Notify(system.BotChatID, "warn", title,
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.Permanent(errors.New(notifyMsg))
 	}
 	// 判断一下交易金额是否正确
 	if msg.Amount.Cmp(upOrder.Amount) != 0 {
 		notifyMsg := fmt.Sprintf("回调交易金额与订单金额不符,交易订单号: %v,平台订单号: %v,上游回调金额: %v,交易金额:%v", mOrderIdNum, upOrder.OrderID, msg.Amount, upOrder.Amount)
 		notify.Notify(system.BotChatID, "warn", "代收回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
-	}
-	// 更新上游订单状态
-	upOrder.Status = s.receiveGetUpStatusMessage(msg.Status)
-	upOrder.UpOrderNo = msg.UpOrderID
-	upOrder.NotifyTime = utils.PtrTime(time.Now())
-	if err := dal.OrderDB.Table(txTable).Where("up_order_id = ?", mOrderIdNum).Updates(&upOrder).Error; err != nil {
-		notifyMsg := fmt.Sprintf("更新订单交易信息失败,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, upOrder.OrderID, err)
-		notify.Notify(system.BotChatID, "warn", "代收回调异常",
-			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.Permanent(errors.New(notifyMsg))
 	}
 
 	// 根据商户订单号查找订单
-	var order orderModel.MerchantOrder
 	orderTable := shard.OrderShard.GetTable(upOrder.OrderID, time.Now())
-	if err := dal.OrderDB.Table(orderTable).Where("order_id = ?", upOrder.OrderID).First(&order).Error; err != nil {
+	order, err := s.orderDao.GetByOrderId(orderTable, upOrder.OrderID)
+	if err == nil && order == nil {
+		err = errors.New("record not found")
+	}
+	if err != nil {
 		notifyMsg := fmt.Sprintf("平台订单号未找到,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, upOrder.OrderID, err)
 		notify.Notify(system.BotChatID, "warn", "代收回调异常",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return errors.New(notifyMsg)
 	}
 
 	// 如果商户订单status状态>1,表示已经收到上游回调处理
@@ -104,80 +122,84 @@ func (s *ReceiveCallback) HandleUpstreamCallback(msg *dto.ReceiveHyperfOrderMess
 		notifyMsg := fmt.Sprintf("订单状态不是待处理或者未支付状态, 不能进行其他操作流程，进入人工核查阶段。交易订单号: %v,平台订单号: %v,订单状态: %v", mOrderIdNum, upOrder.OrderID, s.receiveConvertStatus(utils.ConvertOrderStatus(order.Status)))
 		notify.Notify(system.BotChatID, "warn", "代收回调重复",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.ManualReview(errors.New(notifyMsg))
 	}
 	// 判断一下交易金额是否正确
 	if msg.Amount.Cmp(order.Amount) != 0 {
 		notifyMsg := fmt.Sprintf("回调交易金额与订单金额不符,交易订单号: %v,平台订单号: %v,上游回调金额: %v,交易金额:%v", mOrderIdNum, upOrder.OrderID, msg.Amount, upOrder.Amount)
 		notify.Notify(system.BotChatID, "warn", "代收回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.Permanent(errors.New(notifyMsg))
+	}
+	// 更新上游订单状态（订单终态校验通过后再写，重复回调不改动交易记录）
+	upOrder.Status = s.receiveGetUpStatusMessage(msg.Status)
+	upOrder.UpOrderNo = msg.UpOrderID
+	upOrder.NotifyTime = utils.PtrTime(time.Now())
+	if err := s.orderDao.UpdateTxFields(txTable, mOrderIdNum, map[string]interface{}{
+		"status":      upOrder.Status,
+		"up_order_no": upOrder.UpOrderNo,
+		"notify_time": upOrder.NotifyTime,
+	}); err != nil {
+		notifyMsg := fmt.Sprintf("更新订单交易信息失败,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, upOrder.OrderID, err)
+		notify.Notify(system.BotChatID, "warn", "代收回调异常",
+			notifyMsg, true)
+		return errors.New(notifyMsg)
 	}
+
 	order.Status = s.receiveGetUpStatusMessage(msg.Status)
 	order.NotifyTime = utils.PtrTime(time.Now())
-	if err := dal.OrderDB.Table(orderTable).Where("order_id = ?", upOrder.OrderID).Updates(&order).Error; err != nil {
+	// 成功时订单统计事件与状态变更同事务写入发件箱
+	var statEvt *ordermodel.OutboxEventM
+	if s.receiveConvertStatus(msg.Status) == "SUCCESS" {
+		statEvt = s.receiveStatEvent(order, mOrderIdNum)
+	}
+	if err := s.orderDao.Transaction(func(orderDao dao.OrderRepository) error {
+		if err := orderDao.UpdateOrderFields(orderTable, upOrder.OrderID, map[string]interface{}{
+			"status":      order.Status,
+			"notify_time": order.NotifyTime,
+		}); err != nil {
+			return err
+		}
+		if statEvt == nil {
+			return nil
+		}
+		return orderDao.InsertOutbox(statEvt)
+	}); err != nil {
 		notifyMsg := fmt.Sprintf("更新订单信息失败,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, upOrder.OrderID, err)
 		notify.Notify(system.BotChatID, "warn", "代收回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return errors.New(notifyMsg)
+	}
+
+	// 累计限额：支付成功确认计入，失败回滚预占
+	switch s.receiveConvertStatus(msg.Status) {
+	case "SUCCESS":
+		s.limitSvc.Settle(order.OrderID)
+	case "FAIL":
+		s.limitSvc.ReleaseOrder(order.OrderID)
 	}
 
-	var mainDao *dao.MainDao
-	mainDao = dao.NewMainDao()
-	merchant, err := mainDao.GetMerchantId(upOrder.MerchantID)
+	// 订单状态已提交，之后的失败重试也会被终态校验拦截，按永久错误转入死信人工处理
+	merchant, err := s.mainDao.GetMerchantId(upOrder.MerchantID)
 	if err != nil || merchant == nil || merchant.Status != 1 {
 		notifyMsg := fmt.Sprintf("商户没有找到，商户不存在或者未启动,交易订单号: %v,平台订单号: %v,错误: %v", mOrderIdNum, upOrder.OrderID, err)
 		notify.Notify(system.BotChatID, "warn", "代收回调商户",
 			notifyMsg, true)
-		return fmt.Errorf(notifyMsg)
+		return event.Permanent(errors.New(notifyMsg))
 	}
 
 	// 如果订单成功就结算商户与代理分润
 	if s.receiveConvertStatus(msg.Status) == "SUCCESS" {
-		var settleService = settlement.NewSettlement()
 		var settlementResult dto.SettlementResult
 		settlementResult = dto.SettlementResult(order.SettleSnapshot)
-		err := settleService.DoPaySettlement(settlementResult, strconv.FormatUint(merchant.MerchantID, 10), order.OrderID, order.MOrderID)
+		err := s.settle.DoPaySettlement(settlementResult, strconv.FormatUint(merchant.MerchantID, 10), order.OrderID, order.MOrderID)
 		if err != nil {
+			metrics.SettlementFailed("receive", order.Currency)
 			notifyMsg := fmt.Sprintf("结算失败\n交易订单号: %v\n平台订单号: %v\n商户订单号: %v\n错误: %v", mOrderIdNum, order.OrderID, order.MOrderID, err)
 			notify.Notify(system.BotChatID, "warn", "代收回调商户",
 				notifyMsg, true)
-			return fmt.Errorf(notifyMsg)
+			return event.Permanent(errors.New(notifyMsg))
 		}
-
-		// 13) 异步处理统计数据
-		go func() {
-			country, cErr := mainDao.GetCountry(order.Currency)
-			if cErr != nil {
-				notifyMsg := fmt.Sprintf("订单统计失败,交易订单号: %v,平台订单号:%v,商户订单号:%v,获取国家信息异常: %v", mOrderIdNum, order.OrderID, order.MOrderID, cErr)
-				notify.Notify(system.BotChatID, "warn", "代收回调商户",
-					notifyMsg, true)
-				log.Printf(notifyMsg)
-			}
-			err := s.pub.Publish("order_stat", &dto.OrderMessageMQ{
-				OrderID:       strconv.FormatUint(order.OrderID, 10),
-				MerchantID:    order.MID,
-				CountryID:     country.ID,
-				ChannelID:     order.ChannelID,
-				SupplierID:    order.SupplierID,
-				Amount:        decimal.Zero,
-				SuccessAmount: order.Amount,
-				Profit:        *order.Profit,
-				Cost:          *order.Cost,
-				Fee:           order.Fees,
-				Status:        2,
-				OrderType:     "collect",
-				Currency:      order.Currency,
-				CreateTime:    time.Now(),
-			})
-			if err != nil {
-				notifyMsg := fmt.Sprintf("订单统计失败,交易订单号: %v,平台订单号:%v,商户订单号:%v,推送到队列异常: %v", mOrderIdNum, order.OrderID, order.MOrderID, err)
-				notify.Notify(system.BotChatID, "warn", "代收回调商户",
-					notifyMsg, true)
-				return
-			}
-		}()
-
 	}
 
 	// 构建回调通知负载
@@ -195,6 +217,7 @@ func (s *ReceiveCallback) HandleUpstreamCallback(msg *dto.ReceiveHyperfOrderMess
 	var lastErr error
 	for i := 1; i <= receiveMaxRetry; i++ {
 		lastErr = s.receiveNotifyMerchant(merchant.NickName, order.NotifyURL, payload)
+		metrics.MerchantNotified("receive", lastErr)
 		if lastErr == nil {
 			log.Printf("✅ [代收回调]成功通知商户信息,商户号: %v,商户名称: %v,交易订单号: %v,平台订单号: %v,商户订单号: %v, 通知次数: %d", merchant.AppId, merchant.NickName, mOrderIdNum, order.OrderID, order.MOrderID, i)
 			return nil
@@ -202,20 +225,50 @@ func (s *ReceiveCallback) HandleUpstreamCallback(msg *dto.ReceiveHyperfOrderMess
 		notifyMsg := fmt.Sprintf("[代收回调]失败通知商户信息\n商户号: %v\n商户名称: %v\n交易订单号: %v\n平台订单号: %v\n商户订单号: %v\n(通知次数: %d/%d)\n错误: %v", merchant.AppId, merchant.NickName, mOrderIdNum, order.OrderID, order.MOrderID, i, receiveMaxRetry, lastErr)
 		notify.Notify(system.BotChatID, "warn", "代收回调商户",
 			notifyMsg, true)
-		log.Printf(notifyMsg)
+		log.Print(notifyMsg)
 		time.Sleep(time.Duration(i*2) * time.Second)
 	}
 	notifyMsg := fmt.Sprintf("[代收回调]失败通知商户信息\n商户号: %v\n商户名称: %v\n交易订单号: %v\n平台订单号: %v\n商户订单号: %v\n(通知次数: %d)\n错误: %v", merchant.AppId, merchant.NickName, mOrderIdNum, order.OrderID, order.MOrderID, receiveMaxRetry, lastErr)
 	notify.Notify(system.BotChatID, "warn", "代收回调商户",
 		notifyMsg, true)
-	return fmt.Errorf(notifyMsg)
+	return event.Permanent(errors.New(notifyMsg))
+}
+
+// receiveStatEvent 代收成功订单统计事件（获取国家信息失败时仍写入，国家为空）
+func (s *ReceiveCallback) receiveStatEvent(order *ordermodel.MerchantOrder, mOrderIdNum uint64) *ordermodel.OutboxEventM {
+	country, cErr := s.mainDao.GetCountry(order.Currency)
+	if cErr != nil {
+		notifyMsg := fmt.Sprintf("订单统计失败,交易订单号: %v,平台订单号:%v,商户订单号:%v,获取国家信息异常: %v", mOrderIdNum, order.OrderID, order.MOrderID, cErr)
+		notify.Notify(system.BotChatID, "warn", "代收回调商户",
+			notifyMsg, true)
+		log.Print(notifyMsg)
+	}
+	evt, err := outbox.NewOrderStat(&dto.OrderMessageMQ{
+		OrderID:       strconv.FormatUint(order.OrderID, 10),
+		MerchantID:    order.MID,
+		CountryID:     country.ID,
+		ChannelID:     order.ChannelID,
+		SupplierID:    order.SupplierID,
+		Amount:        decimal.Zero,
+		SuccessAmount: order.Amount,
+		Profit:        *order.Profit,
+		Cost:          *order.Cost,
+		Fee:           order.Fees,
+		Status:        2,
+		OrderType:     "collect",
+		Currency:      order.Currency,
+		CreateTime:    time.Now(),
+	})
+	if err != nil {
+		log.Printf("[代收回调] 构造订单统计事件失败,平台订单号: %v,错误: %v", order.OrderID, err)
+		return nil
+	}
+	return evt
 }
 
 // verifyUpstreamWhitelist 校验上游供应商IP白名单
 func (s *ReceiveCallback) verifyUpstreamWhitelist(upstreamId uint64, ipAddress string) bool {
-	var mainDao *dao.MainDao
-	mainDao = dao.NewMainDao()
-	upstream, err := mainDao.GetUpstreamWhitelist(upstreamId)
+	upstream, err := s.mainDao.GetUpstreamWhitelist(upstreamId)
 	if err != nil || upstream == nil || upstream.Status != 1 {
 		return false
 	}
@@ -357,7 +410,7 @@ func (s *ReceiveCallback) receiveUpdateMerchantOrder(orderId string, notifyStatu
 	}
 
 	// 更新数据库
-	if err := dal.OrderDB.Table(orderTable).Where("order_id = ?", id).Updates(updateData).Error; err != nil {
+	if err := s.orderDao.UpdateOrderFields(orderTable, id, updateData); err != nil {
 		return fmt.Errorf("[代收回调] notify update merchant order failed with MOrderID %v: %w", id, err)
 	}
 
@@ -0,0 +1,254 @@
+package callback
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
+	"wht-order-api/internal/idgen"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/shard"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	testMerchantID = uint64(1001)
+	testUpstreamID = uint64(301)
+	testOrderID    = uint64(5001)
+	testUpOrderID  = uint64(8001)
+	testUpstreamIP = "10.0.0.1"
+)
+
+type recordPublisher struct {
+	mu   sync.Mutex
+	msgs map[string][]any
+}
+
+func (p *recordPublisher) Publish(topic string, msg any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.msgs == nil {
+		p.msgs = make(map[string][]any)
+	}
+	p.msgs[topic] = append(p.msgs[topic], msg)
+	return nil
+}
+
+type receiveFixture struct {
+	main     *memdao.MainStore
+	orders   *memdao.OrderStore
+	cb       *ReceiveCallback
+	notified chan dto.ReceiveNotifyMerchantPayload
+}
+
+// newReceiveFixture 一笔待支付的代收订单（金额 100 BRL，商户到账 97），商户回调地址为本地 httptest
+func newReceiveFixture(t *testing.T) *receiveFixture {
+	t.Helper()
+	shard.InitShardEngines()
+	fakeredis.Use(t)
+	if err := idgen.InitNode("default", 1); err != nil {
+		t.Fatalf("idgen: %v", err)
+	}
+
+	f := &receiveFixture{
+		main:     memdao.NewMainStore(),
+		orders:   memdao.NewOrderStore(),
+		notified: make(chan dto.ReceiveNotifyMerchantPayload, 4),
+	}
+	merchantSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload dto.ReceiveNotifyMerchantPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		f.notified <- payload
+		_, _ = w.Write([]byte("success"))
+	}))
+	t.Cleanup(merchantSrv.Close)
+
+	f.main.AddMerchant(mainmodel.Merchant{MerchantID: testMerchantID, NickName: "m1", AppId: "APP1001", ApiKey: "secret", Status: 1, Currency: "BRL"})
+	f.main.AddUpstream(testUpstreamID, "10.0.0.9, "+testUpstreamIP, 1)
+	f.main.AddCountry(dto.CurrencyCodeResponse{ID: 76, Code: "BRL", Country: "Brazil"})
+
+	amount := decimal.NewFromInt(100)
+	zero := decimal.Zero
+	now := time.Now()
+	if err := f.orders.Insert(shard.OrderShard.GetTable(testOrderID, now), &ordermodel.MerchantOrder{
+		OrderID: testOrderID, MID: testMerchantID, MOrderID: "M-5001", Amount: amount, Currency: "BRL",
+		Status: 1, NotifyURL: merchantSrv.URL, SupplierID: int64(testUpstreamID), Cost: &zero, Profit: &zero,
+		SettleSnapshot: ordermodel.SettleSnapshot(dto.SettlementResult{
+			OrderAmount: amount, Currency: "BRL", MerchantRecv: decimal.NewFromInt(97),
+		}),
+		CreateTime: &now,
+	}); err != nil {
+		t.Fatalf("seed order: %v", err)
+	}
+	if err := f.orders.InsertTx(shard.UpOrderShard.GetTable(testUpOrderID, now), &ordermodel.UpstreamTx{
+		UpOrderId: testUpOrderID, OrderID: testOrderID, MerchantID: "1001", SupplierId: testUpstreamID,
+		Amount: amount, Currency: "BRL", CreateTime: &now,
+	}); err != nil {
+		t.Fatalf("seed tx: %v", err)
+	}
+
+	f.cb = NewReceiveCallbackWithRepos(&recordPublisher{}, f.main, f.orders)
+	return f
+}
+
+func (f *receiveFixture) order(t *testing.T) *ordermodel.MerchantOrder {
+	t.Helper()
+	o, err := f.orders.GetByOrderId(shard.OrderShard.GetTable(testOrderID, time.Now()), testOrderID)
+	if err != nil || o == nil {
+		t.Fatalf("load order: %v", err)
+	}
+	return o
+}
+
+func (f *receiveFixture) tx(t *testing.T) *ordermodel.UpstreamTx {
+	t.Helper()
+	tx, err := f.orders.GetTxByUpOrderId(shard.UpOrderShard.GetTable(testUpOrderID, time.Now()), testUpOrderID)
+	if err != nil {
+		t.Fatalf("load tx: %v", err)
+	}
+	return tx
+}
+
+func callbackMsg(status, amount, ip string) *dto.ReceiveHyperfOrderMessage {
+	return &dto.ReceiveHyperfOrderMessage{
+		MOrderID:    "8001",
+		UpOrderID:   "UP-8001",
+		Amount:      decimal.RequireFromString(amount),
+		Status:      status,
+		UpIpAddress: ip,
+		Timestamp:   time.Now().Unix(),
+	}
+}
+
+func TestReceiveCallbackSuccess(t *testing.T) {
+	f := newReceiveFixture(t)
+
+	if err := f.cb.HandleUpstreamCallback(callbackMsg("0000", "100", testUpstreamIP)); err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+
+	tx := f.tx(t)
+	if tx.Status != 2 || tx.UpOrderNo != "UP-8001" || tx.NotifyTime == nil {
+		t.Errorf("tx = status %d, upOrderNo %q, notifyTime %v", tx.Status, tx.UpOrderNo, tx.NotifyTime)
+	}
+	o := f.order(t)
+	if o.Status != 2 || o.FinishTime == nil || o.NotifyStatus == nil || *o.NotifyStatus != 1 {
+		t.Errorf("order = status %d, finishTime %v, notifyStatus %v", o.Status, o.FinishTime, o.NotifyStatus)
+	}
+
+	acc, ok := f.main.Account(testMerchantID, "BRL")
+	if !ok || !acc.Money.Equal(decimal.NewFromInt(97)) {
+		t.Errorf("merchant balance = %s (exists %v), want 97", acc.Money, ok)
+	}
+
+	// 订单统计事件与状态变更同事务写入发件箱
+	evts := f.orders.Outbox.Events()
+	if len(evts) != 1 || evts[0].Topic != "order_stat" || evts[0].AggregateID != testOrderID || evts[0].EventID == "" {
+		t.Fatalf("outbox = %+v, want one order_stat event", evts)
+	}
+	var stat dto.OrderMessageMQ
+	if err := json.Unmarshal([]byte(evts[0].Payload), &stat); err != nil {
+		t.Fatalf("outbox payload: %v", err)
+	}
+	if stat.EventID != evts[0].EventID || stat.Status != 2 || stat.CountryID != 76 || !stat.SuccessAmount.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("order_stat payload = %+v", stat)
+	}
+
+	select {
+	case p := <-f.notified:
+		if p.TranFlow != "M-5001" || p.Status != "0000" || p.MerchantNo != "APP1001" || p.Sign == "" {
+			t.Errorf("merchant payload = %+v", p)
+		}
+	default:
+		t.Error("merchant was not notified")
+	}
+}
+
+func TestReceiveCallbackFailStatus(t *testing.T) {
+	f := newReceiveFixture(t)
+
+	if err := f.cb.HandleUpstreamCallback(callbackMsg("0005", "100", testUpstreamIP)); err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if o := f.order(t); o.Status != 3 || o.FinishTime != nil {
+		t.Errorf("order = status %d, finishTime %v, want 3/nil", o.Status, o.FinishTime)
+	}
+	if _, ok := f.main.Account(testMerchantID, "BRL"); ok {
+		t.Error("failed order must not be settled")
+	}
+	if evts := f.orders.Outbox.Events(); len(evts) != 0 {
+		t.Errorf("outbox = %+v, want no stat event for failed order", evts)
+	}
+}
+
+func TestReceiveCallbackRejected(t *testing.T) {
+	tests := []struct {
+		name          string
+		msg           *dto.ReceiveHyperfOrderMessage
+		wantErr       string
+		wantPermanent bool
+	}{
+		{"IP不在白名单", callbackMsg("0000", "100", "10.9.9.9"), "10.9.9.9", true},
+		{"金额不符", callbackMsg("0000", "100.01", testUpstreamIP), "金额不符", true},
+		// 可能是下单事务尚未提交，按临时错误延迟重试
+		{"交易订单不存在", &dto.ReceiveHyperfOrderMessage{MOrderID: "8999", Amount: decimal.NewFromInt(100), Status: "0000", UpIpAddress: testUpstreamIP}, "未找到交易订单号", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newReceiveFixture(t)
+
+			err := f.cb.HandleUpstreamCallback(tt.msg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+			if got := event.IsPermanent(err); got != tt.wantPermanent {
+				t.Errorf("permanent = %v, want %v", got, tt.wantPermanent)
+			}
+			if tx := f.tx(t); tx.Status != 0 {
+				t.Errorf("tx status = %d, want untouched 0", tx.Status)
+			}
+			if o := f.order(t); o.Status != 1 {
+				t.Errorf("order status = %d, want untouched 1", o.Status)
+			}
+			if _, ok := f.main.Account(testMerchantID, "BRL"); ok {
+				t.Error("rejected callback must not settle")
+			}
+			if evts := f.orders.Outbox.Events(); len(evts) != 0 {
+				t.Errorf("outbox = %+v, want empty", evts)
+			}
+		})
+	}
+}
+
+func TestReceiveCallbackDuplicate(t *testing.T) {
+	f := newReceiveFixture(t)
+
+	msg := callbackMsg("0000", "100", testUpstreamIP)
+	if err := f.cb.HandleUpstreamCallback(msg); err != nil {
+		t.Fatalf("first callback: %v", err)
+	}
+	<-f.notified
+
+	// 重复回调：订单已终态，进入人工核查，不重复结算、不重复通知
+	err := f.cb.HandleUpstreamCallback(msg)
+	if err == nil || !strings.Contains(err.Error(), "人工核查") || !event.IsManualReview(err) {
+		t.Fatalf("duplicate err = %v, want manual review", err)
+	}
+	if acc, _ := f.main.Account(testMerchantID, "BRL"); !acc.Money.Equal(decimal.NewFromInt(97)) {
+		t.Errorf("merchant balance = %s, want 97 (settled once)", acc.Money)
+	}
+	select {
+	case p := <-f.notified:
+		t.Errorf("merchant notified twice: %+v", p)
+	default:
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"time"
+	"wht-order-api/internal/metrics"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -37,7 +38,9 @@ func (m *ChannelHealthManager) Update(productID int64, success bool) error {
 func (m *ChannelHealthManager) IsDisabled(productID int64) bool {
 	ctx := context.Background()
 	val, err := m.Redis.Get(ctx, m.disabledKey(productID)).Int()
-	return err == nil && val == 1
+	disabled := err == nil && val == 1
+	metrics.SetProductCircuit(productID, disabled)
+	return disabled
 }
 
 func (m *ChannelHealthManager) disabledKey(productID int64) string {
@@ -10,8 +10,9 @@ import (
 )
 
 type ServerCfg struct {
-	Port string `mapstructure:"port"`
-	Mode string `mapstructure:"mode"`
+	Port            string        `mapstructure:"port"`
+	Mode            string        `mapstructure:"mode"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // 优雅退出总时限（HTTP 请求、在途消息、后台任务）
 }
 type MysqlCfg struct {
 	Host         string `mapstructure:"host"`
@@ -40,6 +41,16 @@ type RabbitConsumerCfg struct {
 	AutoDelete   bool   `mapstructure:"auto_delete"`
 	Exclusive    bool   `mapstructure:"exclusive"`
 	NoWait       bool   `mapstructure:"no_wait"`
+
+	Workers     int             `mapstructure:"workers"`      // 并发处理协程数
+	RetryDelays []time.Duration `mapstructure:"retry_delays"` // 临时错误的延迟重试梯度（TTL 重试队列），用尽后转死信队列
+}
+
+// RabbitPublisherCfg 发布端：独立通道池 + 发布确认，断线期间消息暂存本地有界缓冲
+type RabbitPublisherCfg struct {
+	PoolSize       int           `mapstructure:"pool_size"`       // 发布通道数（与消费通道隔离）
+	ConfirmTimeout time.Duration `mapstructure:"confirm_timeout"` // 等待 broker 确认超时
+	BufferSize     int           `mapstructure:"buffer_size"`     // 断线重连期间本地缓冲上限，满则拒绝发布
 }
 
 type RabbitCfg struct {
@@ -51,6 +62,7 @@ type RabbitCfg struct {
 	PrefetchCount int                 `mapstructure:"prefetch_count"`
 	Producers     []RabbitProducerCfg `mapstructure:"producers"`
 	Consumers     []RabbitConsumerCfg `mapstructure:"consumers"`
+	Publisher     RabbitPublisherCfg  `mapstructure:"publisher"`
 }
 
 type RedisCfg struct {
@@ -84,6 +96,74 @@ type UpstreamCfg struct {
 	Retry         RetryConfig   `mapstructure:"retry"`
 }
 
+// ReassignCfg 代付自动改派配置
+type ReassignCfg struct {
+	Enabled     bool          `mapstructure:"enabled"`     // 是否开启自动改派
+	MaxAttempts int           `mapstructure:"maxAttempts"` // 单笔订单最大改派次数（不含首次下单）
+	TimeBudget  time.Duration `mapstructure:"timeBudget"`  // 自订单创建起允许自动改派的时间窗口
+}
+
+// BatchCfg 批量代付配置
+type BatchCfg struct {
+	MaxRows        int           `mapstructure:"maxRows"`        // 单批次最大行数
+	MaxFileSize    int64         `mapstructure:"maxFileSize"`    // 上传文件大小上限（字节）
+	Workers        int           `mapstructure:"workers"`        // 批量代付并发处理协程数（全局）
+	ResumeInterval time.Duration `mapstructure:"resumeInterval"` // 处理中批次恢复扫描间隔（重启/崩溃后接管未完成的批次）
+}
+
+// ApprovalCfg 大额代付复核（maker-checker）配置
+type ApprovalCfg struct {
+	Enabled              bool              `mapstructure:"enabled"`              // 是否开启代付复核
+	Thresholds           map[string]string `mapstructure:"thresholds"`           // 币种默认复核阈值（商户级配置优先）
+	FirstTimeBeneficiary bool              `mapstructure:"firstTimeBeneficiary"` // 首次出款的收款账户是否需要复核
+	ExpireAfter          time.Duration     `mapstructure:"expireAfter"`          // 待复核订单超时自动驳回时间
+	ScanInterval         time.Duration     `mapstructure:"scanInterval"`         // 超时扫描间隔
+}
+
+// HoldingCfg 上游余额不足代付排队配置
+type HoldingCfg struct {
+	Enabled      bool          `mapstructure:"enabled"`      // 是否开启排队（关闭时余额不足直接转人工）
+	MaxWait      time.Duration `mapstructure:"maxWait"`      // 最长排队时间，超时自动失败解冻
+	PollInterval time.Duration `mapstructure:"pollInterval"` // 轮询上游余额、出队间隔
+	DrainBatch   int           `mapstructure:"drainBatch"`   // 每个队列单次最多出队数量
+}
+
+// WithdrawCfg 商户提现配置
+type WithdrawCfg struct {
+	Enabled  bool          `mapstructure:"enabled"`  // 是否开放商户提现接口
+	CoolDown time.Duration `mapstructure:"coolDown"` // 提现账户新增/修改后的冷却期，冷却期内不可提现
+}
+
+// LimitCfg 商户/通道/收款账户累计限额配置
+type LimitCfg struct {
+	Enabled             bool          `mapstructure:"enabled"`             // 是否开启累计限额校验
+	FailOpen            bool          `mapstructure:"failOpen"`            // Redis 异常时是否放行（false 则拒单）
+	ReceiveExpireAfter  time.Duration `mapstructure:"receiveExpireAfter"`  // 代收未支付超时后回滚预占额度
+	ReleaseScanInterval time.Duration `mapstructure:"releaseScanInterval"` // 超时预占扫描间隔
+}
+
+// RiskCfg 风控规则引擎配置（规则见 w_risk_rule / w_risk_blacklist）
+type RiskCfg struct {
+	Enabled        bool          `mapstructure:"enabled"`        // 是否开启风控规则引擎
+	ReloadInterval time.Duration `mapstructure:"reloadInterval"` // 规则热加载间隔
+	ReviewScore    int           `mapstructure:"reviewScore"`    // 累计分值达到该值转复核（0 不按分值）
+	DenyScore      int           `mapstructure:"denyScore"`      // 累计分值达到该值拒绝（0 不按分值）
+}
+
+// OutboxCfg 事务发件箱投递配置（事件写入 p_outbox，由 Relay 带发布确认投递）
+type OutboxCfg struct {
+	PollInterval time.Duration `mapstructure:"pollInterval"` // 扫描待投递事件间隔
+	BatchSize    int           `mapstructure:"batchSize"`    // 单次最多投递数量
+	MaxAttempts  int           `mapstructure:"maxAttempts"`  // 最大投递次数，超过转死信并告警
+	Retention    time.Duration `mapstructure:"retention"`    // 已投递事件保留时长
+}
+
+// MetricsCfg Prometheus 指标
+type MetricsCfg struct {
+	Path           string `mapstructure:"path"`           // 抓取路径，默认 /metrics（仅内网 IP 可访问）
+	MaxLabelValues int    `mapstructure:"maxLabelValues"` // 商户、通道、上游等标签各自最多保留的取值数，超出归入 other
+}
+
 type ProjectCfg struct {
 	Name      string `mapstructure:"name"`
 	Version   string `mapstructure:"version"`
@@ -104,6 +184,15 @@ type Root struct {
 	Security   SecurityCfg `mapstructure:"security"`
 	Order      OrderCfg    `mapstructure:"order"`
 	Upstream   UpstreamCfg `mapstructure:"upstream"`
+	Reassign   ReassignCfg `mapstructure:"reassign"`
+	Batch      BatchCfg    `mapstructure:"batch"`
+	Approval   ApprovalCfg `mapstructure:"approval"`
+	Holding    HoldingCfg  `mapstructure:"holding"`
+	Withdraw   WithdrawCfg `mapstructure:"withdraw"`
+	Limit      LimitCfg    `mapstructure:"limit"`
+	Risk       RiskCfg     `mapstructure:"risk"`
+	Outbox     OutboxCfg   `mapstructure:"outbox"`
+	Metrics    MetricsCfg  `mapstructure:"metrics"`
 	Project    ProjectCfg  `mapstructure:"project"`
 }
 
@@ -127,12 +216,95 @@ func Init() {
 	if strings.TrimSpace(C.Server.Port) == "" {
 		C.Server.Port = "8080"
 	}
+	if C.Server.ShutdownTimeout <= 0 {
+		C.Server.ShutdownTimeout = 30 * time.Second
+	}
 	if C.Order.ShardsPerMonth <= 0 {
 		C.Order.ShardsPerMonth = 4
 	}
 	if C.Order.CreateTimeoutSec <= 0 {
 		C.Order.CreateTimeoutSec = 3
 	}
+	if C.Reassign.MaxAttempts <= 0 {
+		C.Reassign.MaxAttempts = 3
+	}
+	if C.Reassign.TimeBudget <= 0 {
+		C.Reassign.TimeBudget = 30 * time.Minute
+	}
+	if C.Batch.MaxRows <= 0 {
+		C.Batch.MaxRows = 1000
+	}
+	if C.Batch.MaxFileSize <= 0 {
+		C.Batch.MaxFileSize = 5 << 20
+	}
+	if C.Batch.Workers <= 0 {
+		C.Batch.Workers = 8
+	}
+	if C.Batch.ResumeInterval <= 0 {
+		C.Batch.ResumeInterval = time.Minute
+	}
+	if C.Approval.ExpireAfter <= 0 {
+		C.Approval.ExpireAfter = 24 * time.Hour
+	}
+	if C.Approval.ScanInterval <= 0 {
+		C.Approval.ScanInterval = time.Minute
+	}
+	if C.Holding.MaxWait <= 0 {
+		C.Holding.MaxWait = 30 * time.Minute
+	}
+	if C.Holding.PollInterval <= 0 {
+		C.Holding.PollInterval = 30 * time.Second
+	}
+	if C.Holding.DrainBatch <= 0 {
+		C.Holding.DrainBatch = 20
+	}
+	if C.Withdraw.CoolDown <= 0 {
+		C.Withdraw.CoolDown = 24 * time.Hour
+	}
+	if C.Limit.ReceiveExpireAfter <= 0 {
+		C.Limit.ReceiveExpireAfter = 30 * time.Minute
+	}
+	if C.Limit.ReleaseScanInterval <= 0 {
+		C.Limit.ReleaseScanInterval = time.Minute
+	}
+	if C.Risk.ReloadInterval <= 0 {
+		C.Risk.ReloadInterval = 30 * time.Second
+	}
+	for i := range C.RabbitMQ.Consumers {
+		if C.RabbitMQ.Consumers[i].Workers <= 0 {
+			C.RabbitMQ.Consumers[i].Workers = 10
+		}
+		if len(C.RabbitMQ.Consumers[i].RetryDelays) == 0 {
+			C.RabbitMQ.Consumers[i].RetryDelays = []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute}
+		}
+	}
+	if C.RabbitMQ.Publisher.PoolSize <= 0 {
+		C.RabbitMQ.Publisher.PoolSize = 4
+	}
+	if C.RabbitMQ.Publisher.ConfirmTimeout <= 0 {
+		C.RabbitMQ.Publisher.ConfirmTimeout = 5 * time.Second
+	}
+	if C.RabbitMQ.Publisher.BufferSize <= 0 {
+		C.RabbitMQ.Publisher.BufferSize = 1000
+	}
+	if C.Outbox.PollInterval <= 0 {
+		C.Outbox.PollInterval = time.Second
+	}
+	if C.Outbox.BatchSize <= 0 {
+		C.Outbox.BatchSize = 200
+	}
+	if C.Outbox.MaxAttempts <= 0 {
+		C.Outbox.MaxAttempts = 30
+	}
+	if C.Outbox.Retention <= 0 {
+		C.Outbox.Retention = 7 * 24 * time.Hour
+	}
+	if strings.TrimSpace(C.Metrics.Path) == "" {
+		C.Metrics.Path = "/metrics"
+	}
+	if C.Metrics.MaxLabelValues <= 0 {
+		C.Metrics.MaxLabelValues = 200
+	}
 	log.Printf("全局白名单配置加载成功: %+v", C.Security.IPWhitelist.Global)
 
 }
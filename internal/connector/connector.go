@@ -0,0 +1,105 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"wht-order-api/internal/dto"
+
+	"github.com/shopspring/decimal"
+)
+
+// 上游订单状态（与 PHP 网关回调的 status 一致，回调消费者按此转换）
+const (
+	StatusSuccess = "0000"
+	StatusPending = "0001"
+	StatusFail    = "0005"
+)
+
+// OrderResult 上游下单结果
+type OrderResult struct {
+	UpOrderNo string // 上游流水号
+	PayUrl    string // 支付链接（代收）
+	Status    string // 上游受理状态，为空视为处理中；StatusFail 表示上游已明确失败
+	Code      string // 上游失败码（Status 为 StatusFail 时按接口映射表映射）
+	Msg       string // 上游失败描述
+}
+
+// QueryResult 上游查单结果
+type QueryResult struct {
+	UpOrderNo string
+	Status    string
+	Amount    decimal.Decimal
+	Msg       string
+}
+
+// Callback 上游回调原始请求
+type Callback struct {
+	Mode     string      // receive / payout
+	Header   http.Header // 原始请求头
+	Body     []byte      // 原始请求体
+	RemoteIP string      // 上游回调 IP
+}
+
+// CallbackResult 回调解析结果（MOrderID 为平台交易订单号，即下单时的 MchOrderId）
+type CallbackResult struct {
+	MOrderID  string
+	UpOrderID string // 上游流水号
+	Status    string
+	Amount    decimal.Decimal
+	AckBody   string // 回复上游的响应体，为空回复 "success"
+}
+
+// BusinessError 上游明确返回的业务失败（由调用方按接口编码映射为平台错误码）
+type BusinessError struct {
+	Code string
+	Msg  string
+}
+
+func (e *BusinessError) Error() string {
+	return fmt.Sprintf("upstream business error: code=%s, msg=%s", e.Code, e.Msg)
+}
+
+// Connector 上游原生对接（逐个替代 PHP 网关的对接实现）
+// 请求参数沿用 dto.UpstreamRequest；业务失败返回 *BusinessError，其余错误视为网络/传输异常
+type Connector interface {
+	CreateReceive(ctx context.Context, req dto.UpstreamRequest) (*OrderResult, error)
+	CreatePayout(ctx context.Context, req dto.UpstreamRequest) (*OrderResult, error)
+	Query(ctx context.Context, req dto.UpstreamRequest) (*QueryResult, error)
+	Balance(ctx context.Context, req dto.UpstreamRequest) (decimal.Decimal, error)
+	VerifyCallback(cb *Callback) error
+	ParseCallback(cb *Callback) (*CallbackResult, error)
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Connector{}
+)
+
+// Register 按接口编码（PayProductVo.InterfaceCode）注册原生连接器，一般在连接器包的 init 中调用
+func Register(interfaceCode string, c Connector) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[strings.TrimSpace(interfaceCode)] = c
+}
+
+// Get 获取接口编码对应的原生连接器，未注册时调用方回退到 PHP 网关
+func Get(interfaceCode string) (Connector, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := registry[strings.TrimSpace(interfaceCode)]
+	return c, ok
+}
+
+// Codes 已注册的接口编码
+func Codes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	codes := make([]string, 0, len(registry))
+	for code := range registry {
+		codes = append(codes, code)
+	}
+	return codes
+}
@@ -0,0 +1,250 @@
+// Package reference 原生连接器参考实现（通用 JSON + MD5 签名协议）
+//
+// 新上游接入时以本包为模板：按上游文档调整请求字段、状态映射与验签，
+// 在连接器包的 init 中调用 connector.Register(接口编码, New(...))，并在 cmd 中匿名导入。
+//
+// 协议约定：
+//   - 下单 POST SubmitUrl，查单/余额 POST QueryUrl（method=query/balance）
+//   - 请求与回调均按 utils.GenerateSign 签名，密钥为上游商户密钥
+//   - 响应 {"code":"0","msg":"","data":{...}}，code 非 0 为业务失败
+//   - 订单状态 SUCCESS / PENDING / FAIL
+package reference
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+const codeOK = "0"
+
+// KeyFunc 按上游商户号获取回调验签密钥
+type KeyFunc func(mchNo string) (string, error)
+
+// Connector 参考连接器
+type Connector struct {
+	keys KeyFunc
+}
+
+var _ connector.Connector = (*Connector)(nil)
+
+// New keys 用于回调验签（回调请求不携带下单时的密钥）
+func New(keys KeyFunc) *Connector {
+	return &Connector{keys: keys}
+}
+
+// response 上游统一响应
+type response struct {
+	Code string          `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// orderData 下单/查单响应数据
+type orderData struct {
+	UpOrderNo string `json:"upOrderNo"`
+	PayUrl    string `json:"payUrl"`
+	Status    string `json:"status"`
+	Amount    string `json:"amount"`
+	ErrCode   string `json:"errCode"`
+	ErrMsg    string `json:"errMsg"`
+}
+
+// callbackBody 上游回调报文
+type callbackBody struct {
+	MchNo      string `json:"mchNo"`
+	MchOrderId string `json:"mchOrderId"`
+	UpOrderNo  string `json:"upOrderNo"`
+	Status     string `json:"status"`
+	Amount     string `json:"amount"`
+	Sign       string `json:"sign"`
+}
+
+func (c *Connector) CreateReceive(ctx context.Context, req dto.UpstreamRequest) (*connector.OrderResult, error) {
+	return c.create(ctx, req, "receive")
+}
+
+func (c *Connector) CreatePayout(ctx context.Context, req dto.UpstreamRequest) (*connector.OrderResult, error) {
+	return c.create(ctx, req, "payout")
+}
+
+func (c *Connector) create(ctx context.Context, req dto.UpstreamRequest, mode string) (*connector.OrderResult, error) {
+	params := map[string]string{
+		"mchNo":       req.MchNo,
+		"mchOrderId":  req.MchOrderId,
+		"amount":      req.Amount,
+		"currency":    req.Currency,
+		"payType":     req.PayType,
+		"payMethod":   req.PayMethod,
+		"notifyUrl":   req.NotifyUrl,
+		"redirectUrl": req.RedirectUrl,
+		"productInfo": req.ProductInfo,
+		"clientIp":    req.ClientIp,
+		"mode":        mode,
+	}
+	if mode == "payout" {
+		params["accNo"] = req.AccNo
+		params["accName"] = req.AccName
+		params["bankCode"] = req.BankCode
+		params["identityType"] = req.IdentityType
+		params["identityNum"] = req.IdentityNum
+	}
+
+	var data orderData
+	if err := c.post(ctx, req.SubmitUrl, params, req.ApiKey, &data); err != nil {
+		return nil, err
+	}
+	status, err := convertStatus(data.Status)
+	if err != nil {
+		return nil, err
+	}
+	return &connector.OrderResult{
+		UpOrderNo: data.UpOrderNo,
+		PayUrl:    data.PayUrl,
+		Status:    status,
+		Code:      data.ErrCode,
+		Msg:       data.ErrMsg,
+	}, nil
+}
+
+func (c *Connector) Query(ctx context.Context, req dto.UpstreamRequest) (*connector.QueryResult, error) {
+	params := map[string]string{
+		"method":     "query",
+		"mchNo":      req.MchNo,
+		"mchOrderId": req.MchOrderId,
+	}
+	var data orderData
+	if err := c.post(ctx, req.QueryUrl, params, req.ApiKey, &data); err != nil {
+		return nil, err
+	}
+	status, err := convertStatus(data.Status)
+	if err != nil {
+		return nil, err
+	}
+	amount, _ := decimal.NewFromString(data.Amount)
+	return &connector.QueryResult{UpOrderNo: data.UpOrderNo, Status: status, Amount: amount, Msg: data.ErrMsg}, nil
+}
+
+func (c *Connector) Balance(ctx context.Context, req dto.UpstreamRequest) (decimal.Decimal, error) {
+	params := map[string]string{
+		"method":   "balance",
+		"mchNo":    req.MchNo,
+		"currency": req.Currency,
+	}
+	var data struct {
+		Balance string `json:"balance"`
+	}
+	if err := c.post(ctx, req.QueryUrl, params, req.ApiKey, &data); err != nil {
+		return decimal.Zero, err
+	}
+	balance, err := decimal.NewFromString(data.Balance)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("解析上游余额失败: %w", err)
+	}
+	return balance, nil
+}
+
+func (c *Connector) VerifyCallback(cb *connector.Callback) error {
+	body, err := parseCallbackBody(cb)
+	if err != nil {
+		return err
+	}
+	if c.keys == nil {
+		return errors.New("未配置回调验签密钥")
+	}
+	key, err := c.keys(body.MchNo)
+	if err != nil {
+		return fmt.Errorf("获取验签密钥失败: %w", err)
+	}
+	if !utils.VerifySign(body.signParams(), key) {
+		return errors.New("回调签名错误")
+	}
+	return nil
+}
+
+func (c *Connector) ParseCallback(cb *connector.Callback) (*connector.CallbackResult, error) {
+	body, err := parseCallbackBody(cb)
+	if err != nil {
+		return nil, err
+	}
+	status, err := convertStatus(body.Status)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := decimal.NewFromString(body.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("回调金额格式错误: %w", err)
+	}
+	return &connector.CallbackResult{
+		MOrderID:  body.MchOrderId,
+		UpOrderID: body.UpOrderNo,
+		Status:    status,
+		Amount:    amount,
+	}, nil
+}
+
+// post 签名后请求上游并解析统一响应；code 非 0 返回 *connector.BusinessError
+func (c *Connector) post(ctx context.Context, url string, params map[string]string, key string, out interface{}) error {
+	params["sign"] = utils.GenerateSign(params, key)
+	raw, err := utils.HttpPostJsonWithContext(ctx, url, params)
+	if err != nil {
+		return err
+	}
+	var resp response
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		return fmt.Errorf("解析上游响应失败: %w, body=%s", err, utils.TruncateRunes(raw, 200))
+	}
+	if resp.Code != codeOK {
+		return &connector.BusinessError{Code: resp.Code, Msg: resp.Msg}
+	}
+	if len(resp.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("解析上游响应数据失败: %w", err)
+	}
+	return nil
+}
+
+func parseCallbackBody(cb *connector.Callback) (*callbackBody, error) {
+	var body callbackBody
+	if err := json.Unmarshal(cb.Body, &body); err != nil {
+		return nil, fmt.Errorf("回调报文格式错误: %w", err)
+	}
+	if body.MchOrderId == "" {
+		return nil, errors.New("回调缺少平台交易订单号")
+	}
+	return &body, nil
+}
+
+func (b *callbackBody) signParams() map[string]string {
+	return map[string]string{
+		"mchNo":      b.MchNo,
+		"mchOrderId": b.MchOrderId,
+		"upOrderNo":  b.UpOrderNo,
+		"status":     b.Status,
+		"amount":     b.Amount,
+		"sign":       b.Sign,
+	}
+}
+
+// convertStatus 上游状态转平台状态（空状态视为处理中）
+func convertStatus(status string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(status)) {
+	case "SUCCESS":
+		return connector.StatusSuccess, nil
+	case "PENDING", "":
+		return connector.StatusPending, nil
+	case "FAIL":
+		return connector.StatusFail, nil
+	default:
+		return "", fmt.Errorf("未知的上游订单状态: %s", status)
+	}
+}
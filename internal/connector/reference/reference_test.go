@@ -0,0 +1,125 @@
+package reference
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/utils"
+)
+
+const testKey = "secret"
+
+// upstreamServer 模拟上游：校验签名后按 handle 返回 data
+func upstreamServer(t *testing.T, handle func(params map[string]string) (code, msg string, data any)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if !utils.VerifySign(params, testKey) {
+			t.Errorf("request sign mismatch: %v", params)
+		}
+		code, msg, data := handle(params)
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": code, "msg": msg, "data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testReq(url string) dto.UpstreamRequest {
+	return dto.UpstreamRequest{MchNo: "UPM1", ApiKey: testKey, MchOrderId: "9001", Amount: "100", Currency: "BRL", PayType: "BR_PIX", SubmitUrl: url, QueryUrl: url}
+}
+
+func TestCreateReceive(t *testing.T) {
+	srv := upstreamServer(t, func(p map[string]string) (string, string, any) {
+		if p["mode"] != "receive" || p["mchOrderId"] != "9001" {
+			t.Errorf("params = %v", p)
+		}
+		return codeOK, "", map[string]string{"upOrderNo": "UP-1", "payUrl": "https://pay.example/1", "status": "PENDING"}
+	})
+
+	r, err := New(nil).CreateReceive(context.Background(), testReq(srv.URL))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if r.UpOrderNo != "UP-1" || r.PayUrl != "https://pay.example/1" || r.Status != connector.StatusPending {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestCreatePayoutFailures(t *testing.T) {
+	// 业务失败：code 非 0
+	srv := upstreamServer(t, func(map[string]string) (string, string, any) { return "E1001", "invalid account", nil })
+	_, err := New(nil).CreatePayout(context.Background(), testReq(srv.URL))
+	var be *connector.BusinessError
+	if !errors.As(err, &be) || be.Code != "E1001" {
+		t.Errorf("err = %v, want business error E1001", err)
+	}
+
+	// 同步失败：受理响应但状态为 FAIL，携带失败码
+	srv = upstreamServer(t, func(map[string]string) (string, string, any) {
+		return codeOK, "", map[string]string{"upOrderNo": "UP-2", "status": "FAIL", "errCode": "R01", "errMsg": "risk rejected"}
+	})
+	r, err := New(nil).CreatePayout(context.Background(), testReq(srv.URL))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if r.Status != connector.StatusFail || r.Code != "R01" || r.Msg != "risk rejected" {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestQueryAndBalance(t *testing.T) {
+	srv := upstreamServer(t, func(p map[string]string) (string, string, any) {
+		if p["method"] == "balance" {
+			return codeOK, "", map[string]string{"balance": "2500.50"}
+		}
+		return codeOK, "", map[string]string{"upOrderNo": "UP-3", "status": "SUCCESS", "amount": "100"}
+	})
+	c := New(nil)
+
+	q, err := c.Query(context.Background(), testReq(srv.URL))
+	if err != nil || q.Status != connector.StatusSuccess || q.UpOrderNo != "UP-3" || q.Amount.String() != "100" {
+		t.Errorf("query = %+v, err = %v", q, err)
+	}
+	b, err := c.Balance(context.Background(), testReq(srv.URL))
+	if err != nil || b.String() != "2500.5" {
+		t.Errorf("balance = %s, err = %v", b, err)
+	}
+}
+
+func TestCallback(t *testing.T) {
+	c := New(func(mchNo string) (string, error) {
+		if mchNo != "UPM1" {
+			return "", errors.New("unknown merchant")
+		}
+		return testKey, nil
+	})
+	params := map[string]string{"mchNo": "UPM1", "mchOrderId": "9001", "upOrderNo": "UP-1", "status": "SUCCESS", "amount": "100.00"}
+	params["sign"] = utils.GenerateSign(params, testKey)
+	body, _ := json.Marshal(params)
+	cb := &connector.Callback{Mode: "receive", Body: body}
+
+	if err := c.VerifyCallback(cb); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	r, err := c.ParseCallback(cb)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if r.MOrderID != "9001" || r.UpOrderID != "UP-1" || r.Status != connector.StatusSuccess || r.Amount.String() != "100" {
+		t.Errorf("result = %+v", r)
+	}
+
+	params["amount"] = "1000.00"
+	tampered, _ := json.Marshal(params)
+	if err := c.VerifyCallback(&connector.Callback{Body: tampered}); err == nil {
+		t.Error("tampered callback must fail verification")
+	}
+}
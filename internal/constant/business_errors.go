@@ -23,6 +23,54 @@ const (
 	CodeOrderClosed        = 2107 // 订单已关闭，无法进行任何操作
 )
 
+// 批量代付相关错误码
+const (
+	CodeBatchFileInvalid  = 2110 // 批量文件格式错误或无法解析
+	CodeBatchRowInvalid   = 2111 // 批量文件存在无效行，请根据明细修正后重新提交
+	CodeBatchAlreadyExist = 2112 // 批次号已存在，请勿重复提交
+	CodeBatchTooLarge     = 2113 // 批量行数或文件大小超过限制
+	CodeBatchNotFound     = 2114 // 批次不存在
+)
+
+// 代付复核相关错误码
+const (
+	CodeApprovalNotFound       = 2120 // 复核记录不存在
+	CodeApprovalAlreadyDecided = 2121 // 复核记录已处理，请勿重复操作
+	CodeApprovalActionInvalid  = 2122 // 复核操作无效，仅支持 approve / reject
+)
+
+// 商户钱包换汇相关错误码
+const (
+	CodeFxCurrencyInvalid  = 2130 // 换汇币种无效，源币种与目标币种不能相同
+	CodeFxRateInvalid      = 2131 // 换汇汇率或金额无效
+	CodeFxBalanceLow       = 2132 // 源币种钱包余额不足
+	CodeFxConversionNoUsed = 2133 // 换汇单号已被其他商户使用
+)
+
+// 商户提现相关错误码
+const (
+	CodeWithdrawDisabled            = 2140 // 提现功能未开放
+	CodeWithdrawDestinationNotFound = 2141 // 提现账户不存在或已停用
+	CodeWithdrawDestinationCooling  = 2142 // 提现账户处于变更冷却期，暂不可提现
+	CodeWithdrawAmountInvalid       = 2143 // 提现金额不在允许范围内
+	CodeWithdrawFeeRuleMissing      = 2144 // 未配置提现手续费规则
+	CodeWithdrawAlreadyExist        = 2145 // 提现单号已存在，请勿重复提交
+	CodeWithdrawNotFound            = 2146 // 提现订单不存在
+	CodeWithdrawCurrencyMismatch    = 2147 // 提现账户币种与通道币种不一致
+)
+
+// 累计限额相关错误码
+const (
+	CodeLimitMerchantDailyAmount   = 2150 // 超出商户单日累计金额限额
+	CodeLimitMerchantDailyCount    = 2151 // 超出商户单日累计笔数限额
+	CodeLimitMerchantMonthlyAmount = 2152 // 超出商户单月累计金额限额
+	CodeLimitMerchantMonthlyCount  = 2153 // 超出商户单月累计笔数限额
+	CodeLimitChannelDailyAmount    = 2154 // 超出通道单日累计金额限额
+	CodeLimitChannelDailyCount     = 2155 // 超出通道单日累计笔数限额
+	CodeLimitPayerDailyAmount      = 2156 // 超出收款账户单日累计金额限额
+	CodeLimitPayerDailyCount       = 2157 // 超出收款账户单日累计笔数限额
+)
+
 // 支付通道相关错误码
 const (
 	CodeChannelNotFound     = 2200 // 支付通道不存在，请检查通道编码是否正确
@@ -34,16 +34,7 @@ func (e *CustomError) WithData(data interface{}) Error {
 	return e
 }
 
-// NewError 创建错误
-func NewError(code int) Error {
-	if info, exists := ErrorMessages[code]; exists {
-		return &CustomError{code: code, message: info.CN}
-	}
-	return &CustomError{code: code, message: "未知错误"}
-}
-
-// GetErrorInfo 获取错误信息
-func GetErrorInfo(code int) (ErrorInfo, bool) {
-	info, exists := ErrorMessages[code]
-	return info, exists
+// NewError 创建错误（对外文案由 i18n 按错误码渲染）
+func NewError(code int, message string) Error {
+	return &CustomError{code: code, message: message}
 }
@@ -0,0 +1,645 @@
+package fakeredis
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInt    = errors.New("ERR value is not an integer or out of range")
+	errNotFloat  = errors.New("ERR value is not a valid float")
+	errSyntax    = errors.New("ERR syntax error")
+)
+
+func errArgs(name string) error {
+	return fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(name))
+}
+
+// lookup 读取键，已过期的键惰性删除（调用方持有锁）
+func (s *Server) lookup(key string) *entry {
+	e, ok := s.data[key]
+	if !ok {
+		return nil
+	}
+	if !e.expireAt.IsZero() && !time.Now().Before(e.expireAt) {
+		delete(s.data, key)
+		return nil
+	}
+	return e
+}
+
+// typed 读取指定类型的键；不存在且 create 为 true 时创建（调用方持有锁）
+func (s *Server) typed(key string, kind int, create bool) (*entry, error) {
+	e := s.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = &entry{kind: kind}
+		switch kind {
+		case kindHash:
+			e.hash = make(map[string]string)
+		case kindSet:
+			e.set = make(map[string]struct{})
+		case kindZSet:
+			e.zset = make(map[string]float64)
+		}
+		s.data[key] = e
+		return e, nil
+	}
+	if e.kind != kind {
+		return nil, errWrongType
+	}
+	return e, nil
+}
+
+// exec 执行单条命令（调用方持有锁）
+func (s *Server) exec(args []string) interface{} {
+	name := strings.ToUpper(args[0])
+	argv := args[1:]
+	need := func(n int) bool { return len(argv) >= n }
+
+	switch name {
+	case "PING":
+		if len(argv) > 0 {
+			return argv[0]
+		}
+		return status("PONG")
+	case "SELECT", "CLIENT":
+		return status("OK")
+	case "FLUSHALL", "FLUSHDB":
+		s.data = make(map[string]*entry)
+		return status("OK")
+
+	// ---------- 键 ----------
+	case "DEL", "UNLINK":
+		if !need(1) {
+			return errArgs(name)
+		}
+		var n int64
+		for _, k := range argv {
+			if s.lookup(k) != nil {
+				delete(s.data, k)
+				n++
+			}
+		}
+		return n
+	case "EXISTS":
+		if !need(1) {
+			return errArgs(name)
+		}
+		var n int64
+		for _, k := range argv {
+			if s.lookup(k) != nil {
+				n++
+			}
+		}
+		return n
+	case "EXPIRE", "PEXPIRE":
+		if !need(2) {
+			return errArgs(name)
+		}
+		n, err := strconv.ParseInt(argv[1], 10, 64)
+		if err != nil {
+			return errNotInt
+		}
+		e := s.lookup(argv[0])
+		if e == nil {
+			return int64(0)
+		}
+		unit := time.Second
+		if name == "PEXPIRE" {
+			unit = time.Millisecond
+		}
+		e.expireAt = time.Now().Add(time.Duration(n) * unit)
+		return int64(1)
+	case "PERSIST":
+		if !need(1) {
+			return errArgs(name)
+		}
+		e := s.lookup(argv[0])
+		if e == nil || e.expireAt.IsZero() {
+			return int64(0)
+		}
+		e.expireAt = time.Time{}
+		return int64(1)
+	case "TTL", "PTTL":
+		if !need(1) {
+			return errArgs(name)
+		}
+		e := s.lookup(argv[0])
+		if e == nil {
+			return int64(-2)
+		}
+		if e.expireAt.IsZero() {
+			return int64(-1)
+		}
+		if name == "PTTL" {
+			return time.Until(e.expireAt).Milliseconds()
+		}
+		return int64(math.Ceil(time.Until(e.expireAt).Seconds()))
+	case "KEYS":
+		if !need(1) {
+			return errArgs(name)
+		}
+		var keys []string
+		for k := range s.data {
+			if s.lookup(k) != nil && matchPattern(argv[0], k) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		return keys
+
+	// ---------- 字符串 ----------
+	case "GET":
+		if !need(1) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindString, false)
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			return nil
+		}
+		return e.str
+	case "SET":
+		return s.set(argv)
+	case "SETNX":
+		if !need(2) {
+			return errArgs(name)
+		}
+		if s.lookup(argv[0]) != nil {
+			return int64(0)
+		}
+		s.data[argv[0]] = &entry{kind: kindString, str: argv[1]}
+		return int64(1)
+	case "SETEX":
+		if !need(3) {
+			return errArgs(name)
+		}
+		return s.set([]string{argv[0], argv[2], "EX", argv[1]})
+	case "INCR", "DECR", "INCRBY", "DECRBY":
+		if !need(1) {
+			return errArgs(name)
+		}
+		delta := int64(1)
+		if name == "INCRBY" || name == "DECRBY" {
+			if !need(2) {
+				return errArgs(name)
+			}
+			d, err := strconv.ParseInt(argv[1], 10, 64)
+			if err != nil {
+				return errNotInt
+			}
+			delta = d
+		}
+		if strings.HasPrefix(name, "DECR") {
+			delta = -delta
+		}
+		e, err := s.typed(argv[0], kindString, true)
+		if err != nil {
+			return err
+		}
+		cur := int64(0)
+		if e.str != "" {
+			if cur, err = strconv.ParseInt(e.str, 10, 64); err != nil {
+				return errNotInt
+			}
+		}
+		cur += delta
+		e.str = strconv.FormatInt(cur, 10)
+		return cur
+	case "INCRBYFLOAT":
+		if !need(2) {
+			return errArgs(name)
+		}
+		delta, err := strconv.ParseFloat(argv[1], 64)
+		if err != nil {
+			return errNotFloat
+		}
+		e, err := s.typed(argv[0], kindString, true)
+		if err != nil {
+			return err
+		}
+		cur := 0.0
+		if e.str != "" {
+			if cur, err = strconv.ParseFloat(e.str, 64); err != nil {
+				return errNotFloat
+			}
+		}
+		e.str = strconv.FormatFloat(cur+delta, 'f', -1, 64)
+		return e.str
+
+	// ---------- 哈希 ----------
+	case "HSET", "HMSET":
+		if !need(3) || len(argv[1:])%2 != 0 {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindHash, true)
+		if err != nil {
+			return err
+		}
+		var added int64
+		for i := 1; i < len(argv); i += 2 {
+			if _, ok := e.hash[argv[i]]; !ok {
+				added++
+			}
+			e.hash[argv[i]] = argv[i+1]
+		}
+		if name == "HMSET" {
+			return status("OK")
+		}
+		return added
+	case "HGET":
+		if !need(2) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindHash, false)
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			return nil
+		}
+		v, ok := e.hash[argv[1]]
+		if !ok {
+			return nil
+		}
+		return v
+	case "HGETALL":
+		if !need(1) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindHash, false)
+		if err != nil {
+			return err
+		}
+		out := []string{}
+		if e != nil {
+			fields := make([]string, 0, len(e.hash))
+			for f := range e.hash {
+				fields = append(fields, f)
+			}
+			sort.Strings(fields)
+			for _, f := range fields {
+				out = append(out, f, e.hash[f])
+			}
+		}
+		return out
+	case "HDEL":
+		if !need(2) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindHash, false)
+		if err != nil || e == nil {
+			return orZero(err)
+		}
+		var n int64
+		for _, f := range argv[1:] {
+			if _, ok := e.hash[f]; ok {
+				delete(e.hash, f)
+				n++
+			}
+		}
+		s.dropEmpty(argv[0], len(e.hash))
+		return n
+
+	// ---------- 集合 ----------
+	case "SADD":
+		if !need(2) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindSet, true)
+		if err != nil {
+			return err
+		}
+		var n int64
+		for _, m := range argv[1:] {
+			if _, ok := e.set[m]; !ok {
+				e.set[m] = struct{}{}
+				n++
+			}
+		}
+		return n
+	case "SREM":
+		if !need(2) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindSet, false)
+		if err != nil || e == nil {
+			return orZero(err)
+		}
+		var n int64
+		for _, m := range argv[1:] {
+			if _, ok := e.set[m]; ok {
+				delete(e.set, m)
+				n++
+			}
+		}
+		s.dropEmpty(argv[0], len(e.set))
+		return n
+	case "SMEMBERS":
+		if !need(1) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindSet, false)
+		if err != nil {
+			return err
+		}
+		out := []string{}
+		if e != nil {
+			for m := range e.set {
+				out = append(out, m)
+			}
+			sort.Strings(out)
+		}
+		return out
+	case "SISMEMBER":
+		if !need(2) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindSet, false)
+		if err != nil || e == nil {
+			return orZero(err)
+		}
+		if _, ok := e.set[argv[1]]; ok {
+			return int64(1)
+		}
+		return int64(0)
+
+	// ---------- 有序集合 ----------
+	case "ZADD":
+		if !need(3) || len(argv[1:])%2 != 0 {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindZSet, true)
+		if err != nil {
+			return err
+		}
+		var added int64
+		for i := 1; i < len(argv); i += 2 {
+			score, err := strconv.ParseFloat(argv[i], 64)
+			if err != nil {
+				return errNotFloat
+			}
+			if _, ok := e.zset[argv[i+1]]; !ok {
+				added++
+			}
+			e.zset[argv[i+1]] = score
+		}
+		return added
+	case "ZCARD":
+		if !need(1) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindZSet, false)
+		if err != nil || e == nil {
+			return orZero(err)
+		}
+		return int64(len(e.zset))
+	case "ZREM":
+		if !need(2) {
+			return errArgs(name)
+		}
+		e, err := s.typed(argv[0], kindZSet, false)
+		if err != nil || e == nil {
+			return orZero(err)
+		}
+		var n int64
+		for _, m := range argv[1:] {
+			if _, ok := e.zset[m]; ok {
+				delete(e.zset, m)
+				n++
+			}
+		}
+		s.dropEmpty(argv[0], len(e.zset))
+		return n
+	case "ZRANGE":
+		return s.zrange(argv)
+	case "ZRANGEBYSCORE":
+		return s.zrangeByScore(argv)
+
+	case "EVAL", "EVALSHA":
+		return s.eval(name, argv)
+	case "SCRIPT":
+		return errors.New("ERR fakeredis does not support SCRIPT")
+	}
+	return fmt.Errorf("ERR unknown command '%s'", strings.ToLower(name))
+}
+
+// set SET key value [EX s|PX ms] [NX|XX] [KEEPTTL]
+func (s *Server) set(argv []string) interface{} {
+	if len(argv) < 2 {
+		return errArgs("SET")
+	}
+	key, val := argv[0], argv[1]
+	var ttl time.Duration
+	var nx, xx, keepTTL bool
+	for i := 2; i < len(argv); i++ {
+		switch strings.ToUpper(argv[i]) {
+		case "EX", "PX":
+			if i+1 >= len(argv) {
+				return errSyntax
+			}
+			n, err := strconv.ParseInt(argv[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return errNotInt
+			}
+			unit := time.Second
+			if strings.ToUpper(argv[i]) == "PX" {
+				unit = time.Millisecond
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		default:
+			return errSyntax
+		}
+	}
+	old := s.lookup(key)
+	if (nx && old != nil) || (xx && old == nil) {
+		return nil
+	}
+	e := &entry{kind: kindString, str: val}
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	} else if keepTTL && old != nil {
+		e.expireAt = old.expireAt
+	}
+	s.data[key] = e
+	return status("OK")
+}
+
+// zrange ZRANGE key start stop [WITHSCORES]（按下标，分数升序、同分按成员字典序）
+func (s *Server) zrange(argv []string) interface{} {
+	if len(argv) < 3 {
+		return errArgs("ZRANGE")
+	}
+	start, err1 := strconv.Atoi(argv[1])
+	stop, err2 := strconv.Atoi(argv[2])
+	if err1 != nil || err2 != nil {
+		return errNotInt
+	}
+	withScores := len(argv) > 3 && strings.ToUpper(argv[3]) == "WITHSCORES"
+
+	e, err := s.typed(argv[0], kindZSet, false)
+	if err != nil {
+		return err
+	}
+	out := []string{}
+	if e == nil {
+		return out
+	}
+	members := make([]string, 0, len(e.zset))
+	for m := range e.zset {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		si, sj := e.zset[members[i]], e.zset[members[j]]
+		if si != sj {
+			return si < sj
+		}
+		return members[i] < members[j]
+	})
+	n := len(members)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	for i := start; i <= stop; i++ {
+		out = append(out, members[i])
+		if withScores {
+			out = append(out, strconv.FormatFloat(e.zset[members[i]], 'f', -1, 64))
+		}
+	}
+	return out
+}
+
+// zrangeByScore ZRANGEBYSCORE key min max（支持 -inf/+inf 与 "(" 开区间，不支持 WITHSCORES/LIMIT）
+func (s *Server) zrangeByScore(argv []string) interface{} {
+	if len(argv) != 3 {
+		return errArgs("ZRANGEBYSCORE")
+	}
+	min, minOpen, err1 := parseScoreBound(argv[1])
+	max, maxOpen, err2 := parseScoreBound(argv[2])
+	if err1 != nil || err2 != nil {
+		return errors.New("ERR min or max is not a float")
+	}
+	e, err := s.typed(argv[0], kindZSet, false)
+	if err != nil {
+		return err
+	}
+	out := []string{}
+	if e == nil {
+		return out
+	}
+	for m, score := range e.zset {
+		if score < min || (minOpen && score == min) || score > max || (maxOpen && score == max) {
+			continue
+		}
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		si, sj := e.zset[out[i]], e.zset[out[j]]
+		if si != sj {
+			return si < sj
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+func parseScoreBound(v string) (float64, bool, error) {
+	open := strings.HasPrefix(v, "(")
+	v = strings.TrimPrefix(v, "(")
+	switch strings.ToLower(v) {
+	case "-inf":
+		return math.Inf(-1), open, nil
+	case "+inf", "inf":
+		return math.Inf(1), open, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	return f, open, err
+}
+
+// eval EVAL script numkeys key... arg... / EVALSHA sha1 numkeys key... arg...
+// 只执行已注册的替身；EVALSHA 未命中返回 NOSCRIPT，go-redis 会回退到 EVAL
+func (s *Server) eval(name string, argv []string) interface{} {
+	if len(argv) < 2 {
+		return errArgs(name)
+	}
+	numKeys, err := strconv.Atoi(argv[1])
+	if err != nil || numKeys < 0 || numKeys > len(argv)-2 {
+		return errors.New("ERR Number of keys can't be greater than number of args")
+	}
+	sha := strings.ToLower(argv[0])
+	if name == "EVAL" {
+		sum := sha1.Sum([]byte(argv[0]))
+		sha = hex.EncodeToString(sum[:])
+	}
+	fn, ok := s.scripts[sha]
+	if !ok {
+		if name == "EVALSHA" {
+			return errors.New("NOSCRIPT No matching script. Please use EVAL.")
+		}
+		return errors.New("ERR fakeredis script not registered, use HandleScript")
+	}
+	call := func(args ...string) interface{} { return s.exec(args) }
+	return fn(call, argv[2:2+numKeys], argv[2+numKeys:])
+}
+
+func (s *Server) dropEmpty(key string, size int) {
+	if size == 0 {
+		delete(s.data, key)
+	}
+}
+
+func orZero(err error) interface{} {
+	if err != nil {
+		return err
+	}
+	return int64(0)
+}
+
+// matchPattern KEYS 的 glob 匹配（仅支持 * 与 ?）
+func matchPattern(pattern, key string) bool {
+	if pattern == "" {
+		return key == ""
+	}
+	switch pattern[0] {
+	case '*':
+		for i := 0; i <= len(key); i++ {
+			if matchPattern(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case '?':
+		return key != "" && matchPattern(pattern[1:], key[1:])
+	default:
+		return key != "" && key[0] == pattern[0] && matchPattern(pattern[1:], key[1:])
+	}
+}
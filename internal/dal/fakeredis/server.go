@@ -0,0 +1,284 @@
+// Package fakeredis 进程内的 Redis 替身（RESP 协议，监听本地随机端口），供单元测试替换 dal.RedisClient。
+//
+// 覆盖业务代码用到的命令：字符串（GET/SET/SETNX/INCR/INCRBYFLOAT…）、过期（EXPIRE/TTL）、
+// 哈希、集合、有序集合与 MULTI/EXEC 事务管道。不解释 Lua：EVAL/EVALSHA 仅执行经 HandleScript
+// 按脚本 SHA1 注册的 Go 替身，未注册的脚本返回错误（依赖脚本的累计限额在测试中需保持关闭）。
+package fakeredis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wht-order-api/internal/dal"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	kindString = iota + 1
+	kindHash
+	kindSet
+	kindZSet
+)
+
+type entry struct {
+	kind     int
+	str      string
+	hash     map[string]string
+	set      map[string]struct{}
+	zset     map[string]float64
+	expireAt time.Time
+}
+
+// Server 内存 Redis 服务
+type Server struct {
+	ln      net.Listener
+	mu      sync.Mutex
+	data    map[string]*entry
+	scripts map[string]ScriptFunc // 脚本 SHA1 -> Go 替身
+	wg      sync.WaitGroup
+	closed  chan struct{}
+}
+
+// ScriptFunc Lua 脚本的 Go 替身，call 在服务端锁内执行单条命令（与脚本一样原子）
+type ScriptFunc func(call func(args ...string) interface{}, keys, argv []string) interface{}
+
+// Run 在 127.0.0.1 随机端口启动
+func Run() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("fakeredis listen failed: %w", err)
+	}
+	s := &Server{ln: ln, data: make(map[string]*entry), scripts: make(map[string]ScriptFunc), closed: make(chan struct{})}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Use 启动服务并替换 dal.RedisClient，测试结束时恢复
+func Use(tb testing.TB) *Server {
+	tb.Helper()
+	s, err := Run()
+	if err != nil {
+		tb.Fatalf("start fakeredis: %v", err)
+	}
+	prevClient, prevCtx := dal.RedisClient, dal.RedisCtx
+	dal.RedisClient = redis.NewClient(&redis.Options{Addr: s.Addr()})
+	dal.RedisCtx = context.Background()
+	tb.Cleanup(func() {
+		_ = dal.RedisClient.Close()
+		dal.RedisClient, dal.RedisCtx = prevClient, prevCtx
+		s.Close()
+	})
+	return s
+}
+
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *Server) Close() {
+	select {
+	case <-s.closed:
+		return
+	default:
+	}
+	close(s.closed)
+	_ = s.ln.Close()
+	s.wg.Wait()
+}
+
+// HandleScript 注册脚本替身，sha 为 redis.Script.Hash()
+func (s *Server) HandleScript(sha string, fn ScriptFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[strings.ToLower(sha)] = fn
+}
+
+// FlushAll 清空数据
+func (s *Server) FlushAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = make(map[string]*entry)
+}
+
+// Get 读取字符串键（断言用）
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.lookup(key)
+	if e == nil || e.kind != kindString {
+		return "", false
+	}
+	return e.str, true
+}
+
+// Exists 键是否存在（断言用）
+func (s *Server) Exists(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookup(key) != nil
+}
+
+// TTL 剩余过期时间，无过期返回 0（断言用）
+func (s *Server) TTL(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.lookup(key)
+	if e == nil || e.expireAt.IsZero() {
+		return 0
+	}
+	return time.Until(e.expireAt)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+	go func() {
+		<-s.closed
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	var queue [][]string // MULTI 之后排队的命令
+	inMulti := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		name := strings.ToUpper(args[0])
+		switch {
+		case name == "MULTI":
+			inMulti, queue = true, nil
+			writeReply(w, status("OK"))
+		case name == "DISCARD":
+			inMulti, queue = false, nil
+			writeReply(w, status("OK"))
+		case name == "EXEC":
+			if !inMulti {
+				writeReply(w, errors.New("ERR EXEC without MULTI"))
+				break
+			}
+			s.mu.Lock()
+			replies := make([]interface{}, 0, len(queue))
+			for _, cmd := range queue {
+				replies = append(replies, s.exec(cmd))
+			}
+			s.mu.Unlock()
+			inMulti, queue = false, nil
+			writeReply(w, replies)
+		case inMulti:
+			queue = append(queue, args)
+			writeReply(w, status("QUEUED"))
+		default:
+			s.mu.Lock()
+			reply := s.exec(args)
+			s.mu.Unlock()
+			writeReply(w, reply)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// ================== RESP 编解码 ==================
+
+type status string
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		// inline 命令
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, fmt.Errorf("bad array header %q", line)
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		hdr, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(hdr, "$") {
+			return nil, fmt.Errorf("bad bulk header %q", hdr)
+		}
+		size, err := strconv.Atoi(hdr[1:])
+		if err != nil {
+			return nil, fmt.Errorf("bad bulk header %q", hdr)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeReply(w *bufio.Writer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case status:
+		fmt.Fprintf(w, "+%s\r\n", string(v))
+	case error:
+		fmt.Fprintf(w, "-%s\r\n", v.Error())
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case int:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []string:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, s := range v {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+		}
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	default:
+		fmt.Fprintf(w, "-ERR fakeredis unsupported reply %T\r\n", v)
+	}
+}
@@ -0,0 +1,109 @@
+package fakeredis
+
+import (
+	"testing"
+	"time"
+	"wht-order-api/internal/dal"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestStringsAndExpire(t *testing.T) {
+	srv := Use(t)
+	rdb, ctx := dal.RedisClient, dal.RedisCtx
+
+	if err := rdb.Set(ctx, "k", "v", time.Minute).Err(); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if v, _ := rdb.Get(ctx, "k").Result(); v != "v" {
+		t.Errorf("get = %q, want v", v)
+	}
+	if ttl := srv.TTL("k"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("ttl = %v, want (0, 1m]", ttl)
+	}
+	if _, err := rdb.Get(ctx, "missing").Result(); err != redis.Nil {
+		t.Errorf("get missing err = %v, want redis.Nil", err)
+	}
+
+	ok, _ := rdb.SetNX(ctx, "once", 1, time.Minute).Result()
+	again, _ := rdb.SetNX(ctx, "once", 1, time.Minute).Result()
+	if !ok || again {
+		t.Errorf("setnx = %v/%v, want true/false", ok, again)
+	}
+
+	if n, _ := rdb.Incr(ctx, "cnt").Result(); n != 1 {
+		t.Errorf("incr = %d, want 1", n)
+	}
+	rdb.PExpire(ctx, "cnt", 50*time.Millisecond)
+	time.Sleep(80 * time.Millisecond)
+	if srv.Exists("cnt") {
+		t.Error("cnt should have expired")
+	}
+}
+
+func TestTxPipelineAndCollections(t *testing.T) {
+	Use(t)
+	rdb, ctx := dal.RedisClient, dal.RedisCtx
+
+	pipe := rdb.TxPipeline()
+	incr := pipe.IncrByFloat(ctx, "vol", 100.5)
+	pipe.IncrByFloat(ctx, "vol", 0.25)
+	pipe.Expire(ctx, "vol", time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if incr.Val() != 100.5 {
+		t.Errorf("first incrbyfloat = %v, want 100.5", incr.Val())
+	}
+	if v, _ := rdb.Get(ctx, "vol").Result(); v != "100.75" {
+		t.Errorf("vol = %s, want 100.75", v)
+	}
+
+	rdb.HSet(ctx, "h", "a", "1", "b", "2")
+	if all, _ := rdb.HGetAll(ctx, "h").Result(); len(all) != 2 || all["b"] != "2" {
+		t.Errorf("hgetall = %v", all)
+	}
+
+	rdb.ZAdd(ctx, "z", &redis.Z{Score: 2, Member: "b"}, &redis.Z{Score: 1, Member: "a"})
+	zs, _ := rdb.ZRangeWithScores(ctx, "z", 0, -1).Result()
+	if len(zs) != 2 || zs[0].Member != "a" || zs[1].Score != 2 {
+		t.Errorf("zrange = %+v", zs)
+	}
+	if err := rdb.Get(ctx, "h").Err(); err == nil {
+		t.Error("get on hash should fail with WRONGTYPE")
+	}
+}
+
+func TestScriptHandlerAndZRangeByScore(t *testing.T) {
+	srv := Use(t)
+	rdb, ctx := dal.RedisClient, dal.RedisCtx
+
+	move := redis.NewScript(`if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1]) return 1 end return 0`)
+	if err := move.Run(ctx, rdb, []string{"q", "inflight"}, "a", 5).Err(); err == nil {
+		t.Fatal("unregistered script should fail")
+	}
+	srv.HandleScript(move.Hash(), func(call func(args ...string) interface{}, keys, argv []string) interface{} {
+		if n, _ := call("ZREM", keys[0], argv[0]).(int64); n == 1 {
+			call("ZADD", keys[1], argv[1], argv[0])
+			return int64(1)
+		}
+		return int64(0)
+	})
+
+	rdb.ZAdd(ctx, "q", &redis.Z{Score: 1, Member: "a"})
+	if n, err := move.Run(ctx, rdb, []string{"q", "inflight"}, "a", 5).Int(); err != nil || n != 1 {
+		t.Fatalf("first move = %d, %v", n, err)
+	}
+	if n, _ := move.Run(ctx, rdb, []string{"q", "inflight"}, "a", 5).Int(); n != 0 {
+		t.Errorf("second move = %d, want 0", n)
+	}
+
+	rdb.ZAdd(ctx, "inflight", &redis.Z{Score: 9, Member: "b"}, &redis.Z{Score: 3, Member: "c"})
+	got, err := rdb.ZRangeByScore(ctx, "inflight", &redis.ZRangeBy{Min: "-inf", Max: "5"}).Result()
+	if err != nil || len(got) != 2 || got[0] != "c" || got[1] != "a" {
+		t.Errorf("zrangebyscore = %v, %v", got, err)
+	}
+	if got, _ := rdb.ZRangeByScore(ctx, "inflight", &redis.ZRangeBy{Min: "(3", Max: "+inf"}).Result(); len(got) != 2 || got[0] != "a" {
+		t.Errorf("zrangebyscore open = %v", got)
+	}
+}
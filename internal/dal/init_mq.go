@@ -20,6 +20,7 @@ var (
 	chClosedCh   chan *amqp.Error
 
 	reconnecting bool
+	closing      bool // 进程退出中，连接关闭后不再重连
 )
 
 // InitRabbitMQ 初始化（首次连接）
@@ -103,7 +104,7 @@ func watchClose() {
 // 自愈重连（阻塞重试直至成功）
 func reconnect() {
 	mu.Lock()
-	if reconnecting {
+	if reconnecting || closing {
 		mu.Unlock()
 		return
 	}
@@ -126,6 +127,23 @@ func reconnect() {
 	}
 }
 
+// CloseRabbitMQ 进程退出时关闭通道与连接（之后不再自愈重连）
+func CloseRabbitMQ() {
+	mu.Lock()
+	defer mu.Unlock()
+	closing = true
+	if mqChannel != nil {
+		_ = mqChannel.Close()
+	}
+	if mqConn != nil {
+		if err := mqConn.Close(); err != nil {
+			log.Printf("[RabbitMQ] ⚠️ 关闭连接失败: %v", err)
+			return
+		}
+	}
+	log.Println("[RabbitMQ] 🔌 连接已关闭")
+}
+
 // -------- 状态判断（不用 IsClosed） --------
 
 func isConnAlive() bool {
@@ -154,6 +172,11 @@ func isChanAlive() bool {
 
 // -------- 对外获取 --------
 
+// IsConnected 连接是否可用（不触发重连，发布端据此决定直接投递还是暂存本地缓冲）
+func IsConnected() bool {
+	return isConnAlive()
+}
+
 func GetConnection() *amqp.Connection {
 	// 若已断开，尝试重连
 	if !isConnAlive() {
@@ -41,3 +41,19 @@ func InitMainDB() {
 	sqlDB.SetConnMaxLifetime(2 * time.Hour)
 	MainDB = db
 }
+
+// CloseMainDB 进程退出时关闭连接池
+func CloseMainDB() {
+	if MainDB == nil {
+		return
+	}
+	sqlDB, err := MainDB.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+	if err != nil {
+		log.Printf("[MySQL] ⚠️ 关闭 MainDB 失败: %v", err)
+		return
+	}
+	log.Println("[MySQL] 🔌 MainDB 已关闭")
+}
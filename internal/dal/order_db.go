@@ -41,3 +41,19 @@ func InitOrderDB() {
 	sqlDB.SetConnMaxLifetime(2 * time.Hour)
 	OrderDB = db
 }
+
+// CloseOrderDB 进程退出时关闭连接池
+func CloseOrderDB() {
+	if OrderDB == nil {
+		return
+	}
+	sqlDB, err := OrderDB.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+	if err != nil {
+		log.Printf("[MySQL] ⚠️ 关闭 OrderDB 失败: %v", err)
+		return
+	}
+	log.Println("[MySQL] 🔌 OrderDB 已关闭")
+}
@@ -26,3 +26,15 @@ func InitRedis() {
 		log.Fatalf("redis ping failed: %v", err)
 	}
 }
+
+// CloseRedis 进程退出时关闭连接池
+func CloseRedis() {
+	if RedisClient == nil {
+		return
+	}
+	if err := RedisClient.Close(); err != nil {
+		log.Printf("[Redis] ⚠️ 关闭连接失败: %v", err)
+		return
+	}
+	log.Println("[Redis] 🔌 连接已关闭")
+}
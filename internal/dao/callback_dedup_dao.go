@@ -0,0 +1,115 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+	"wht-order-api/internal/dal"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+type CallbackDedupDao struct {
+	DB *gorm.DB
+}
+
+// 工厂方法：默认使用 dal.OrderDB
+func NewCallbackDedupDao() *CallbackDedupDao {
+	if dal.OrderDB == nil {
+		log.Panic("[FATAL] dal.OrderDB is nil - database not initialized")
+	}
+	return &CallbackDedupDao{DB: dal.OrderDB}
+}
+
+// 安全检查方法
+func (r *CallbackDedupDao) checkDB() error {
+	if r == nil {
+		return errors.New("CallbackDedupDao is nil")
+	}
+	if r.DB == nil {
+		return errors.New("DB connection is nil")
+	}
+	return nil
+}
+
+// Claim 认领回调：插入成功返回 true；任一唯一键冲突（重复消息或终态已被占用）返回 false
+func (r *CallbackDedupDao) Claim(rec *ordermodel.CallbackDedupM) (bool, error) {
+	if err := r.checkDB(); err != nil {
+		return false, fmt.Errorf("claim callback failed: %w", err)
+	}
+	res := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+	if res.Error != nil {
+		return false, fmt.Errorf("claim callback failed: %w", res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// GetByKey 按去重键查询
+func (r *CallbackDedupDao) GetByKey(mode string, upOrderID uint64, status, msgID string) (*ordermodel.CallbackDedupM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("get callback dedup failed: %w", err)
+	}
+	var rec ordermodel.CallbackDedupM
+	err := r.DB.Where("mode = ? AND up_order_id = ? AND status = ? AND msg_id = ?", mode, upOrderID, status, msgID).
+		Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get callback dedup failed: %w", err)
+	}
+	return &rec, nil
+}
+
+// GetTerminal 查询交易已占用的终态回调
+func (r *CallbackDedupDao) GetTerminal(mode string, upOrderID uint64) (*ordermodel.CallbackDedupM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("get terminal callback failed: %w", err)
+	}
+	var rec ordermodel.CallbackDedupM
+	err := r.DB.Where("mode = ? AND terminal_up_order_id = ?", mode, upOrderID).Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get terminal callback failed: %w", err)
+	}
+	return &rec, nil
+}
+
+// TakeOver 接管超时未完成的认领（进程崩溃遗留），CAS 保证只有一个消费者接管成功
+func (r *CallbackDedupDao) TakeOver(id uint64, staleBefore, now time.Time) (bool, error) {
+	if err := r.checkDB(); err != nil {
+		return false, fmt.Errorf("take over callback failed: %w", err)
+	}
+	res := r.DB.Model(&ordermodel.CallbackDedupM{}).
+		Where("id = ? AND state = ? AND update_time < ?", id, ordermodel.CallbackDedupProcessing, staleBefore).
+		Update("update_time", now)
+	if res.Error != nil {
+		return false, fmt.Errorf("take over callback failed: %w", res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// MarkDone 标记处理完成
+func (r *CallbackDedupDao) MarkDone(id uint64, now time.Time) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("mark callback done failed: %w", err)
+	}
+	return r.DB.Model(&ordermodel.CallbackDedupM{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"state":       ordermodel.CallbackDedupDone,
+			"update_time": now,
+		}).Error
+}
+
+// Release 处理失败时删除认领，允许重试或后续相同回调重新处理
+func (r *CallbackDedupDao) Release(id uint64) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("release callback failed: %w", err)
+	}
+	return r.DB.Where("id = ? AND state = ?", id, ordermodel.CallbackDedupProcessing).
+		Delete(&ordermodel.CallbackDedupM{}).Error
+}
@@ -158,6 +158,78 @@ func (d *MainDao) GetAgentMerchant(param dto.QueryAgentMerchant) (*mainmodel.Age
 	return &ch, nil
 }
 
+// maxAgentDepth 代理层级上限，防止配置成环或过深
+const maxAgentDepth = 5
+
+// ListAgentChain 沿 w_agent_merchant 自下而上查询代理链：
+// 第 1 级为 (a_id=商户上级, m_id=商户)；之后每级为 (m_id=下级代理) 的启用记录
+// 任一级未配置或停用即停止向上查找
+func (d *MainDao) ListAgentChain(mId, pId uint64, sysChannelID int64) ([]mainmodel.AgentMerchant, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("list agent chain failed: %w", err)
+	}
+	if pId == 0 {
+		return nil, nil
+	}
+
+	var chain []mainmodel.AgentMerchant
+	seen := map[int64]bool{int64(mId): true}
+	child := int64(mId)
+	for level := 1; level <= maxAgentDepth; level++ {
+		var row mainmodel.AgentMerchant
+		query := d.DB.Where("m_id = ? AND sys_channel_id = ?", child, sysChannelID)
+		if level == 1 {
+			query = query.Where("a_id = ?", pId)
+		}
+		err := query.Order("id ASC").First(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return chain, fmt.Errorf("query agent chain failed: %w", err)
+		}
+		if row.Status != 1 || seen[row.AID] {
+			break
+		}
+		chain = append(chain, row)
+		seen[row.AID] = true
+		child = row.AID
+	}
+	return chain, nil
+}
+
+// ListFeeSchedules 查询系统通道+币种下启用的费率方案
+func (d *MainDao) ListFeeSchedules(sysChannelID int64, currency string) ([]dto.FeeSchedule, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("list fee schedules failed: %w", err)
+	}
+
+	var rows []mainmodel.FeeSchedule
+	if err := d.DB.Where("sys_channel_id = ? AND currency = ? AND status = 1", sysChannelID, currency).
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("query fee schedules failed: %w", err)
+	}
+
+	list := make([]dto.FeeSchedule, 0, len(rows))
+	for _, r := range rows {
+		tiers := make([]dto.FeeTier, 0, len(r.Tiers))
+		for _, t := range r.Tiers {
+			tiers = append(tiers, dto.FeeTier{From: t.From, Rate: t.Rate, Fixed: t.Fixed})
+		}
+		list = append(list, dto.FeeSchedule{
+			ID:        r.ID,
+			Party:     r.Party,
+			OwnerID:   r.OwnerID,
+			PayMethod: r.PayMethod,
+			TierMode:  r.TierMode,
+			Tiers:     tiers,
+			MinFee:    r.MinFee,
+			MaxFee:    r.MaxFee,
+		})
+	}
+	return list, nil
+}
+
 // GetSysChannel 查询通道编码
 func (d *MainDao) GetSysChannel(channelCode string) (*dto.PayWayVo, error) {
 	if err := d.checkDB(); err != nil {
@@ -171,77 +243,314 @@ func (d *MainDao) GetSysChannel(channelCode string) (*dto.PayWayVo, error) {
 	return &ch, nil
 }
 
-// GetMerchantAccount 获取商户账户信息
-func (d *MainDao) GetMerchantAccount(mId string) (dto.MerchantMoney, error) {
+// GetMerchantAccount 获取商户指定币种钱包
+func (d *MainDao) GetMerchantAccount(mId string, currency string) (dto.MerchantMoney, error) {
 	if err := d.checkDB(); err != nil {
 		return dto.MerchantMoney{}, fmt.Errorf("get merchant account failed: %w", err)
 	}
 
 	var ch dto.MerchantMoney
-	if err := d.DB.Table("w_merchant_money").Where("uid=?", mId).First(&ch).Error; err != nil {
+	if err := d.DB.Table("w_merchant_money").Where("uid=?", mId).Where("currency=?", currency).First(&ch).Error; err != nil {
 		return dto.MerchantMoney{}, fmt.Errorf("query failed: %w", err)
 	}
 	return ch, nil
 }
 
-// FreezeAdditionalAmount 增加商户冻结金额（补差额）
+// ListMerchantWallets 获取商户全部币种钱包
+func (d *MainDao) ListMerchantWallets(mId uint64) ([]dto.Account, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("list merchant wallets failed: %w", err)
+	}
+
+	var list []dto.Account
+	if err := d.DB.Table("w_merchant_money AS a").
+		Joins("inner join w_merchant AS b ON a.uid = b.m_id").
+		Select("b.nickname as acc_name,b.app_id as merchant_no,a.money as amount,a.freeze_money as frozen_amount,a.currency").
+		Where("a.uid=?", mId).
+		Order("a.currency ASC").
+		Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return list, nil
+}
+
+var (
+	// ErrFxInsufficientBalance 换汇源币种钱包余额不足
+	ErrFxInsufficientBalance = errors.New("insufficient balance for fx conversion")
+	// ErrFxConversionNoUsed 换汇单号已被其他商户使用
+	ErrFxConversionNoUsed = errors.New("conversion_no already used by another merchant")
+)
+
+// ConvertMerchantWallet 商户钱包换汇：源币种扣减、目标币种增加，双边记资金流水（幂等：同一换汇单号只处理一次）
+func (d *MainDao) ConvertMerchantWallet(conv *mainmodel.MerchantFxConversion) (existed bool, err error) {
+	if err := d.checkDB(); err != nil {
+		return false, fmt.Errorf("convert merchant wallet failed: %w", err)
+	}
+
+	err = d.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		conv.CreateTime = now
+
+		// 1) 写换汇记录（conversion_no 唯一约束）
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(conv)
+		if res.Error != nil {
+			return fmt.Errorf("create fx conversion failed: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			// 换汇单号全局唯一：只返回本商户的记录，其他商户的同号记录视为冲突
+			err := tx.Where("conversion_no = ? AND uid = ?", conv.ConversionNo, conv.UID).Take(conv).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFxConversionNoUsed
+			}
+			if err != nil {
+				return fmt.Errorf("query fx conversion failed: %w", err)
+			}
+			existed = true
+			return nil
+		}
+
+		// 2) 按币种顺序锁定两个钱包，避免并发换汇死锁
+		var wallets []mainmodel.MerchantMoney
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uid = ? AND currency IN ?", conv.UID, []string{conv.FromCurrency, conv.ToCurrency}).
+			Order("currency ASC").
+			Find(&wallets).Error; err != nil {
+			return fmt.Errorf("lock merchant wallets failed: %w", err)
+		}
+		var from, to *mainmodel.MerchantMoney
+		for i := range wallets {
+			switch wallets[i].Currency {
+			case conv.FromCurrency:
+				from = &wallets[i]
+			case conv.ToCurrency:
+				to = &wallets[i]
+			}
+		}
+		if from == nil || from.Money.LessThan(conv.FromAmount) {
+			return ErrFxInsufficientBalance
+		}
+
+		// 3) 源币种钱包扣减
+		if err := tx.Table("w_merchant_money").
+			Where("id = ?", from.ID).
+			Updates(map[string]interface{}{
+				"money":       gorm.Expr("money - ?", conv.FromAmount),
+				"update_time": now,
+			}).Error; err != nil {
+			return fmt.Errorf("debit %s wallet failed: %w", conv.FromCurrency, err)
+		}
+
+		// 4) 目标币种钱包增加（不存在则开户）
+		toOld := decimal.Zero
+		if to == nil {
+			newAccount := mainmodel.MerchantMoney{
+				UID:         conv.UID,
+				Status:      1,
+				Currency:    conv.ToCurrency,
+				Money:       conv.ToAmount,
+				FreezeMoney: decimal.Zero,
+				CreateTime:  now,
+				UpdateTime:  now,
+			}
+			if err := tx.Table("w_merchant_money").Create(&newAccount).Error; err != nil {
+				return fmt.Errorf("create %s wallet failed: %w", conv.ToCurrency, err)
+			}
+		} else {
+			toOld = to.Money
+			if err := tx.Table("w_merchant_money").
+				Where("id = ?", to.ID).
+				Updates(map[string]interface{}{
+					"money":       gorm.Expr("money + ?", conv.ToAmount),
+					"update_time": now,
+				}).Error; err != nil {
+				return fmt.Errorf("credit %s wallet failed: %w", conv.ToCurrency, err)
+			}
+		}
+
+		// 5) 双边资金流水
+		desc := fmt.Sprintf("换汇 %s->%s 汇率%s", conv.FromCurrency, conv.ToCurrency, conv.Rate.String())
+		logs := []mainmodel.MoneyLog{
+			{
+				UID:         conv.UID,
+				Money:       conv.FromAmount.Neg(),
+				OrderNo:     conv.ConversionNo,
+				MOrderNo:    conv.ConversionNo,
+				Type:        dto.MoneyLogTypeFxOut,
+				Operator:    conv.Operator,
+				Currency:    conv.FromCurrency,
+				Description: desc,
+				OldBalance:  from.Money,
+				Balance:     from.Money.Sub(conv.FromAmount),
+				CreateTime:  now,
+				CreateBy:    conv.Operator,
+			},
+			{
+				UID:         conv.UID,
+				Money:       conv.ToAmount,
+				OrderNo:     conv.ConversionNo,
+				MOrderNo:    conv.ConversionNo,
+				Type:        dto.MoneyLogTypeFxIn,
+				Operator:    conv.Operator,
+				Currency:    conv.ToCurrency,
+				Description: desc,
+				OldBalance:  toOld,
+				Balance:     toOld.Add(conv.ToAmount),
+				CreateTime:  now,
+				CreateBy:    conv.Operator,
+			},
+		}
+		if err := tx.Table("w_money_log").Create(&logs).Error; err != nil {
+			return fmt.Errorf("create fx money log failed: %w", err)
+		}
+		return nil
+	})
+	return existed, err
+}
+
+// FreezeAdditionalAmount 增加商户冻结金额（补差额），差额从可用余额中扣除
+// 资金流水按 订单号+改派次数 幂等（同一订单每次改派各记一次），重复调用不会重复冻结
 func (d *MainDao) FreezeAdditionalAmount(
 	uid uint64,
 	currency string,
 	orderNo string,
+	attempt int,
 	diff decimal.Decimal,
 	operator string,
 	mOrderNo string,
 ) error {
+	if err := d.checkDB(); err != nil {
+		return fmt.Errorf("freeze additional amount failed: %w", err)
+	}
 	if diff.LessThanOrEqual(decimal.Zero) {
 		return nil // 不需要补充冻结
 	}
 
-	// 事务保护
-	return dal.MainDB.Transaction(func(tx *gorm.DB) error {
-		var oldBalance, newFreeze decimal.Decimal
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		var account mainmodel.MerchantMoney
+		if err := tx.Table("w_merchant_money").
+			Where("uid = ? AND currency = ?", uid, currency).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&account).Error; err != nil {
+			return fmt.Errorf("get merchant account failed: %w", err)
+		}
 
-		// 查询当前余额与冻结金额
-		var row struct {
-			Money       decimal.Decimal
-			FreezeMoney decimal.Decimal
+		newBalance := account.Money.Sub(diff)
+		newFreeze := account.FreezeMoney.Add(diff)
+
+		// 先写资金日志（幂等保护），已存在说明该次补冻结已处理
+		logEntry := mainmodel.MoneyLog{
+			Currency:    currency,
+			UID:         uid,
+			Money:       diff.Neg(), // 从可用余额扣除
+			OrderNo:     orderNo,
+			Attempt:     attempt,
+			MOrderNo:    mOrderNo,
+			Type:        dto.MoneyLogTypeFreezeAdditional,
+			Description: fmt.Sprintf("改派补充冻结，冻结前=%s，冻结后=%s", account.FreezeMoney, newFreeze),
+			OldBalance:  account.Money,
+			Balance:     newBalance,
+			Operator:    operator,
+			CreateTime:  time.Now(),
+			CreateBy:    operator,
+		}
+		res := tx.Table("w_money_log").
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&logEntry)
+		if res.Error != nil {
+			return fmt.Errorf("create freeze additional log failed: %w", res.Error)
 		}
-		sqlSelect := "SELECT money, freeze_money FROM w_merchant_money WHERE uid = ? AND currency = ? FOR UPDATE"
-		if err := tx.Raw(sqlSelect, uid, currency).Scan(&row).Error; err != nil {
-			return fmt.Errorf("query merchant account failed: %w", err)
+		if res.RowsAffected == 0 {
+			return nil
 		}
 
-		oldBalance = row.FreezeMoney
-		newFreeze = oldBalance.Add(diff)
+		// 校验余额是否足够补冻结
+		if account.Money.LessThan(diff) {
+			return fmt.Errorf("insufficient balance: uid=%d, balance=%s, need=%s", uid, account.Money, diff)
+		}
 
-		// 更新冻结金额
-		sqlUpdate := `
-			UPDATE w_merchant_money 
-			SET freeze_money = freeze_money + ?, update_time = NOW()
-			WHERE uid = ? AND currency = ?`
-		if err := tx.Exec(sqlUpdate, diff, uid, currency).Error; err != nil {
+		if err := tx.Table("w_merchant_money").
+			Where("uid = ? AND currency = ?", uid, currency).
+			Updates(map[string]interface{}{
+				"money":        newBalance,
+				"freeze_money": newFreeze,
+				"update_time":  time.Now(),
+			}).Error; err != nil {
 			return fmt.Errorf("update freeze_money failed: %w", err)
 		}
 
-		// 插入资金日志
-		sqlLog := `
-			INSERT INTO w_money_log 
-			(uid, type, money, order_no, operator, currency, old_balance, balance, description, create_time, m_order_no)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-		if err := tx.Exec(sqlLog,
-			uid,
-			7, // type=7 => 后台冻结（冻结补差）
-			diff,
-			orderNo,
-			operator,
-			currency,
-			oldBalance,
-			newFreeze,
-			"改派补充冻结",
-			time.Now(),
-			mOrderNo,
-		).Error; err != nil {
-			return fmt.Errorf("insert money log failed: %w", err)
+		return nil
+	})
+}
+
+// ReleaseExcessFreeze 释放多余冻结金额（改派后新通道费用更低），差额退回可用余额
+// 资金流水按 订单号+改派次数 幂等（同一订单每次改派各记一次），重复调用不会重复释放
+func (d *MainDao) ReleaseExcessFreeze(
+	uid uint64,
+	currency string,
+	orderNo string,
+	attempt int,
+	diff decimal.Decimal,
+	operator string,
+	mOrderNo string,
+) error {
+	if err := d.checkDB(); err != nil {
+		return fmt.Errorf("release excess freeze failed: %w", err)
+	}
+	if diff.LessThanOrEqual(decimal.Zero) {
+		return nil // 无需释放
+	}
+
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		var account mainmodel.MerchantMoney
+		if err := tx.Table("w_merchant_money").
+			Where("uid = ? AND currency = ?", uid, currency).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&account).Error; err != nil {
+			return fmt.Errorf("get merchant account failed: %w", err)
+		}
+
+		newBalance := account.Money.Add(diff)
+		newFreeze := account.FreezeMoney.Sub(diff)
+
+		// 先写资金日志（幂等保护），已存在说明该次释放已处理
+		logEntry := mainmodel.MoneyLog{
+			Currency:    currency,
+			UID:         uid,
+			Money:       diff, // 退回可用余额
+			OrderNo:     orderNo,
+			Attempt:     attempt,
+			MOrderNo:    mOrderNo,
+			Type:        dto.MoneyLogTypeUnfreezeExcess,
+			Description: fmt.Sprintf("改派释放多余冻结，冻结前=%s，冻结后=%s", account.FreezeMoney, newFreeze),
+			OldBalance:  account.Money,
+			Balance:     newBalance,
+			Operator:    operator,
+			CreateTime:  time.Now(),
+			CreateBy:    operator,
+		}
+		res := tx.Table("w_money_log").
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&logEntry)
+		if res.Error != nil {
+			return fmt.Errorf("create release freeze log failed: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		if account.FreezeMoney.LessThan(diff) {
+			return fmt.Errorf("insufficient frozen funds: uid=%d, frozen=%s, need=%s",
+				uid, account.FreezeMoney.String(), diff.String())
+		}
+
+		if err := tx.Table("w_merchant_money").
+			Where("uid = ? AND currency = ?", uid, currency).
+			Updates(map[string]interface{}{
+				"money":        newBalance,
+				"freeze_money": newFreeze,
+				"update_time":  time.Now(),
+			}).Error; err != nil {
+			return fmt.Errorf("update merchant account failed: %w", err)
 		}
 
 		return nil
@@ -359,21 +668,24 @@ func (d *MainDao) HandlePayoutCallback(uid uint64, currency, orderNo string, mOr
 	orderAmount = orderAmount.Add(agentFees).Add(merchantFees)
 
 	return d.DB.Transaction(func(tx *gorm.DB) error {
-		// 获取商户账户
+		// 获取并锁定商户账户（并发回调串行结算）
 		var account mainmodel.MerchantMoney
 		if err := tx.Table("w_merchant_money").
 			Where("uid = ? AND currency = ?", uid, currency).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
 			First(&account).Error; err != nil {
 			return fmt.Errorf("get merchant account failed: %w", err)
 		}
 
 		oldBalance := account.Money
-		// oldFreeze := account.FreezeMoney // 未使用可删除
 
-		// 冻结足额校验（成功/失败两种路径都需要先从冻结减掉）
-		if account.FreezeMoney.LessThan(orderAmount) {
-			return fmt.Errorf("insufficient frozen funds: uid=%d, frozen=%s, need=%s",
-				uid, account.FreezeMoney.String(), orderAmount.String())
+		// 冻结足额校验（成功/失败两种路径都需要先从冻结减掉）；在资金日志去重之后执行，重复回调不会因冻结已扣减而报错
+		checkFreeze := func() error {
+			if account.FreezeMoney.LessThan(orderAmount) {
+				return fmt.Errorf("insufficient frozen funds: uid=%d, frozen=%s, need=%s",
+					uid, account.FreezeMoney.String(), orderAmount.String())
+			}
+			return nil
 		}
 
 		if status {
@@ -395,10 +707,18 @@ func (d *MainDao) HandlePayoutCallback(uid uint64, currency, orderNo string, mOr
 				CreateTime:  time.Now(),
 				CreateBy:    operator,
 			}
-			if err := tx.Table("w_money_log").
+			res := tx.Table("w_money_log").
 				Clauses(clause.OnConflict{DoNothing: true}).
-				Create(&payoutLog).Error; err != nil {
-				return fmt.Errorf("create payout log failed: %w", err)
+				Create(&payoutLog)
+			if res.Error != nil {
+				return fmt.Errorf("create payout log failed: %w", res.Error)
+			}
+			// 日志已存在说明该订单已结算（幂等）
+			if res.RowsAffected == 0 {
+				return nil
+			}
+			if err := checkFreeze(); err != nil {
+				return err
 			}
 
 			// 更新冻结
@@ -432,10 +752,18 @@ func (d *MainDao) HandlePayoutCallback(uid uint64, currency, orderNo string, mOr
 			Operator:    operator,
 			CreateBy:    operator,
 		}
-		if err := tx.Table("w_money_log").
+		res := tx.Table("w_money_log").
 			Clauses(clause.OnConflict{DoNothing: true}).
-			Create(&delFreezeLog).Error; err != nil {
-			return fmt.Errorf("create unfreezeDel log failed: %w", err)
+			Create(&delFreezeLog)
+		if res.Error != nil {
+			return fmt.Errorf("create unfreezeDel log failed: %w", res.Error)
+		}
+		// 日志已存在说明该订单已解冻（幂等）
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		if err := checkFreeze(); err != nil {
+			return err
 		}
 
 		// 解冻资金退回余额日志 (61)
@@ -1026,3 +1354,248 @@ func (d *MainDao) GetUpstreamSupplier(upstreamId uint64) (*dto.UpstreamSupplierD
 	}
 	return &upstream, nil
 }
+
+// GetPayoutApprovalRule 获取商户级代付复核规则（未配置返回 nil）
+func (d *MainDao) GetPayoutApprovalRule(mId uint64, currency string) (*mainmodel.MerchantPayoutApproval, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("get payout approval rule failed: %w", err)
+	}
+
+	var rule mainmodel.MerchantPayoutApproval
+	err := d.DB.Where("m_id = ? AND currency = ? AND status = 1", mId, currency).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query payout approval rule failed: %w", err)
+	}
+	return &rule, nil
+}
+
+// GetWithdrawFeeRule 获取提现手续费规则：商户专属规则优先，其次币种默认规则（不存在返回 nil）
+func (d *MainDao) GetWithdrawFeeRule(mId uint64, currency string) (*mainmodel.MerchantWithdrawFee, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("get withdraw fee rule failed: %w", err)
+	}
+
+	var rule mainmodel.MerchantWithdrawFee
+	err := d.DB.Where("m_id IN ? AND currency = ? AND status = 1", []uint64{mId, 0}, currency).
+		Order("m_id DESC").
+		First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query withdraw fee rule failed: %w", err)
+	}
+	return &rule, nil
+}
+
+// GetWithdrawDestination 获取商户提现账户（不存在返回 nil）
+func (d *MainDao) GetWithdrawDestination(mId, id uint64) (*mainmodel.MerchantWithdrawDestination, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("get withdraw destination failed: %w", err)
+	}
+
+	var dest mainmodel.MerchantWithdrawDestination
+	err := d.DB.Where("id = ? AND m_id = ?", id, mId).First(&dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query withdraw destination failed: %w", err)
+	}
+	return &dest, nil
+}
+
+// ListWithdrawDestinations 获取商户全部提现账户
+func (d *MainDao) ListWithdrawDestinations(mId uint64) ([]mainmodel.MerchantWithdrawDestination, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("list withdraw destinations failed: %w", err)
+	}
+
+	var list []mainmodel.MerchantWithdrawDestination
+	if err := d.DB.Where("m_id = ?", mId).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("query withdraw destinations failed: %w", err)
+	}
+	return list, nil
+}
+
+// SaveWithdrawDestination 新增或修改提现账户（ID 为 0 时新增）
+func (d *MainDao) SaveWithdrawDestination(dest *mainmodel.MerchantWithdrawDestination) error {
+	if err := d.checkDB(); err != nil {
+		return fmt.Errorf("save withdraw destination failed: %w", err)
+	}
+	if dest.ID == 0 {
+		return d.DB.Create(dest).Error
+	}
+	return d.DB.Where("id = ? AND m_id = ?", dest.ID, dest.MID).Save(dest).Error
+}
+
+// MoneyLogFilter 资金流水查询条件（时间区间左闭右开）
+type MoneyLogFilter struct {
+	UID      uint64
+	Currency string
+	Types    []int8
+	Start    time.Time
+	End      time.Time
+	OrderNo  string
+}
+
+func (d *MainDao) moneyLogQuery(f MoneyLogFilter) *gorm.DB {
+	q := d.DB.Table("w_money_log").
+		Where("uid = ? AND currency = ?", f.UID, f.Currency).
+		Where("create_time >= ? AND create_time < ?", f.Start, f.End)
+	if len(f.Types) > 0 {
+		q = q.Where("type IN ?", f.Types)
+	}
+	if f.OrderNo != "" {
+		q = q.Where("order_no = ? OR m_order_no = ?", f.OrderNo, f.OrderNo)
+	}
+	return q
+}
+
+// ListMoneyLogs 分页查询资金流水
+func (d *MainDao) ListMoneyLogs(f MoneyLogFilter, limit, offset int) ([]mainmodel.MoneyLog, int64, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, 0, fmt.Errorf("list money logs failed: %w", err)
+	}
+	var total int64
+	if err := d.moneyLogQuery(f).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count money logs failed: %w", err)
+	}
+	var list []mainmodel.MoneyLog
+	if err := d.moneyLogQuery(f).Order("id ASC").Limit(limit).Offset(offset).Find(&list).Error; err != nil {
+		return nil, 0, fmt.Errorf("query money logs failed: %w", err)
+	}
+	return list, total, nil
+}
+
+// EachMoneyLogs 分批遍历资金流水（用于导出）
+func (d *MainDao) EachMoneyLogs(f MoneyLogFilter, batch int, fn func([]mainmodel.MoneyLog) error) error {
+	if err := d.checkDB(); err != nil {
+		return fmt.Errorf("iterate money logs failed: %w", err)
+	}
+	var list []mainmodel.MoneyLog
+	return d.moneyLogQuery(f).Order("id ASC").FindInBatches(&list, batch, func(tx *gorm.DB, _ int) error {
+		return fn(list)
+	}).Error
+}
+
+// GetBalanceAt 获取某一时刻的商户可用余额（按资金流水推算，不存在流水返回 ok=false）
+func (d *MainDao) GetBalanceAt(uid uint64, currency string, t time.Time) (balance decimal.Decimal, ok bool, err error) {
+	if err := d.checkDB(); err != nil {
+		return decimal.Zero, false, fmt.Errorf("get balance at failed: %w", err)
+	}
+	// 该时刻之前最后一条流水的变动后余额
+	var prev mainmodel.MoneyLog
+	err = d.DB.Table("w_money_log").
+		Where("uid = ? AND currency = ? AND create_time < ?", uid, currency, t).
+		Order("create_time DESC, id DESC").Take(&prev).Error
+	if err == nil {
+		return prev.Balance, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return decimal.Zero, false, err
+	}
+	// 无历史流水：取该时刻之后第一条流水的变动前余额
+	var next mainmodel.MoneyLog
+	err = d.DB.Table("w_money_log").
+		Where("uid = ? AND currency = ? AND create_time >= ?", uid, currency, t).
+		Order("create_time ASC, id ASC").Take(&next).Error
+	if err == nil {
+		return next.OldBalance, true, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return decimal.Zero, false, nil
+	}
+	return decimal.Zero, false, err
+}
+
+// GetMerchantLimit 获取商户累计限额规则：商户专属规则优先，其次币种默认规则（不存在返回 nil）
+func (d *MainDao) GetMerchantLimit(mId uint64, currency string, orderType int8) (*mainmodel.MerchantLimit, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("get merchant limit failed: %w", err)
+	}
+
+	var rule mainmodel.MerchantLimit
+	err := d.DB.Where("m_id IN ? AND currency = ? AND order_type = ? AND status = 1", []uint64{mId, 0}, currency, orderType).
+		Order("m_id DESC").
+		First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query merchant limit failed: %w", err)
+	}
+	return &rule, nil
+}
+
+// GetChannelLimit 获取系统通道单日累计限额（不存在返回 nil）
+func (d *MainDao) GetChannelLimit(sysChannelID uint64, currency string) (*mainmodel.ChannelLimit, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("get channel limit failed: %w", err)
+	}
+
+	var rule mainmodel.ChannelLimit
+	err := d.DB.Where("sys_channel_id = ? AND currency = ? AND status = 1", sysChannelID, currency).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query channel limit failed: %w", err)
+	}
+	return &rule, nil
+}
+
+// ListRiskRules 获取启用的风控规则（按优先级排序）
+func (d *MainDao) ListRiskRules() ([]mainmodel.RiskRule, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("list risk rules failed: %w", err)
+	}
+
+	var list []mainmodel.RiskRule
+	if err := d.DB.Where("status = 1").Order("priority ASC, id ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("query risk rules failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListRiskBlacklist 获取启用的风控黑名单
+func (d *MainDao) ListRiskBlacklist() ([]mainmodel.RiskBlacklist, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("list risk blacklist failed: %w", err)
+	}
+
+	var list []mainmodel.RiskBlacklist
+	if err := d.DB.Where("status = 1").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("query risk blacklist failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListUpstreamErrorMappings 获取接口的上游错误码映射（含通用规则 *，接口专属规则在前）
+func (d *MainDao) ListUpstreamErrorMappings(interfaceCode string) ([]mainmodel.UpstreamErrorMapping, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, fmt.Errorf("list upstream error mappings failed: %w", err)
+	}
+
+	var list []mainmodel.UpstreamErrorMapping
+	if err := d.DB.Where("interface_code IN ? AND status = 1", []string{interfaceCode, "*"}).
+		Order("interface_code = '*' ASC, priority ASC, id ASC").
+		Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("query upstream error mappings failed: %w", err)
+	}
+	return list, nil
+}
+
+// CreateUpstreamCallbackArchive 归档上游回调原始报文
+func (d *MainDao) CreateUpstreamCallbackArchive(archive *mainmodel.UpstreamCallbackArchive) error {
+	if err := d.checkDB(); err != nil {
+		return fmt.Errorf("create upstream callback archive failed: %w", err)
+	}
+	if err := d.DB.Create(archive).Error; err != nil {
+		return fmt.Errorf("insert upstream callback archive failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package memdao
+
+import (
+	"sync"
+	"time"
+	"wht-order-api/internal/dao"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+// CallbackDedupStore 回调去重内存实现（按两个唯一键模拟 INSERT 冲突）
+type CallbackDedupStore struct {
+	mu     sync.Mutex
+	nextID uint64
+	rows   []*ordermodel.CallbackDedupM
+}
+
+func NewCallbackDedupStore() *CallbackDedupStore {
+	return &CallbackDedupStore{}
+}
+
+var _ dao.CallbackDedupRepository = (*CallbackDedupStore)(nil)
+
+func (s *CallbackDedupStore) Claim(rec *ordermodel.CallbackDedupM) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.Mode != rec.Mode {
+			continue
+		}
+		if r.UpOrderID == rec.UpOrderID && r.Status == rec.Status && r.MsgID == rec.MsgID {
+			return false, nil
+		}
+		if r.TerminalUpOrderID != nil && rec.TerminalUpOrderID != nil && *r.TerminalUpOrderID == *rec.TerminalUpOrderID {
+			return false, nil
+		}
+	}
+	s.nextID++
+	rec.ID = s.nextID
+	cp := *rec
+	s.rows = append(s.rows, &cp)
+	return true, nil
+}
+
+func (s *CallbackDedupStore) GetByKey(mode string, upOrderID uint64, status, msgID string) (*ordermodel.CallbackDedupM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.Mode == mode && r.UpOrderID == upOrderID && r.Status == status && r.MsgID == msgID {
+			cp := *r
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *CallbackDedupStore) GetTerminal(mode string, upOrderID uint64) (*ordermodel.CallbackDedupM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.Mode == mode && r.TerminalUpOrderID != nil && *r.TerminalUpOrderID == upOrderID {
+			cp := *r
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *CallbackDedupStore) TakeOver(id uint64, staleBefore, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.ID == id && r.State == ordermodel.CallbackDedupProcessing && r.UpdateTime.Before(staleBefore) {
+			r.UpdateTime = now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *CallbackDedupStore) MarkDone(id uint64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.ID == id {
+			r.State = ordermodel.CallbackDedupDone
+			r.UpdateTime = now
+		}
+	}
+	return nil
+}
+
+func (s *CallbackDedupStore) Release(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.rows[:0]
+	for _, r := range s.rows {
+		if r.ID == id && r.State == ordermodel.CallbackDedupProcessing {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	s.rows = kept
+	return nil
+}
+
+// Records 全部去重记录（断言用）
+func (s *CallbackDedupStore) Records() []ordermodel.CallbackDedupM {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ordermodel.CallbackDedupM, 0, len(s.rows))
+	for _, r := range s.rows {
+		out = append(out, *r)
+	}
+	return out
+}
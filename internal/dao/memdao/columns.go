@@ -0,0 +1,102 @@
+package memdao
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// applyColumns 按 gorm column 标签把 map 更新应用到模型上（模拟 Updates(map)）
+// 模型上没有的列忽略（与真实库一样，表里可能有模型未映射的列）
+func applyColumns(dst interface{}, data map[string]interface{}) {
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		col := columnName(t.Field(i).Tag.Get("gorm"))
+		val, ok := data[col]
+		if col == "" || !ok {
+			continue
+		}
+		setField(v.Field(i), val)
+	}
+}
+
+func columnName(tag string) string {
+	for _, part := range strings.Split(tag, ";") {
+		if strings.HasPrefix(part, "column:") {
+			return strings.TrimPrefix(part, "column:")
+		}
+	}
+	return ""
+}
+
+func setField(f reflect.Value, val interface{}) {
+	if val == nil {
+		f.Set(reflect.Zero(f.Type()))
+		return
+	}
+	rv := reflect.ValueOf(val)
+	// 指针值先解引用，再按目标字段类型赋值
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			f.Set(reflect.Zero(f.Type()))
+			return
+		}
+		if rv.Type().AssignableTo(f.Type()) {
+			f.Set(rv)
+			return
+		}
+		rv = rv.Elem()
+	}
+	target := f.Type()
+	if target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+	// 数值转字符串在 reflect 中合法（按 rune 转换），这里视为类型不匹配
+	if (rv.Kind() == reflect.String) != (target.Kind() == reflect.String) {
+		return
+	}
+	var out reflect.Value
+	switch {
+	case rv.Type().AssignableTo(target):
+		out = rv
+	case rv.Type().ConvertibleTo(target):
+		out = rv.Convert(target)
+	default:
+		return
+	}
+	if f.Kind() == reflect.Ptr {
+		p := reflect.New(target)
+		p.Elem().Set(out)
+		f.Set(p)
+		return
+	}
+	f.Set(out)
+}
+
+// matchColumns 模型是否满足 map 条件（模拟 Where(map)，按 gorm column 标签比较，指针字段取值比较）
+func matchColumns(src interface{}, where map[string]interface{}) bool {
+	v := reflect.ValueOf(src).Elem()
+	t := v.Type()
+	matched := 0
+	for i := 0; i < t.NumField(); i++ {
+		col := columnName(t.Field(i).Tag.Get("gorm"))
+		want, ok := where[col]
+		if col == "" || !ok {
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				return false
+			}
+			f = f.Elem()
+		}
+		if fmt.Sprint(f.Interface()) != fmt.Sprint(want) {
+			return false
+		}
+		matched++
+	}
+	// 条件列模型上不存在时视为不匹配（真实库会报未知列）
+	return matched == len(where)
+}
@@ -0,0 +1,128 @@
+package memdao
+
+import (
+	"fmt"
+	"sync"
+	"wht-order-api/internal/dao"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+// IndexStore 商户订单号索引表内存实现（按表名隔离，商户+商户订单号唯一）
+type IndexStore struct {
+	mu      sync.Mutex
+	receive map[string][]ordermodel.ReceiveOrderIndexM
+	payout  map[string][]ordermodel.PayoutOrderIndexM
+	seq     uint64
+}
+
+func NewIndexStore() *IndexStore {
+	return &IndexStore{
+		receive: make(map[string][]ordermodel.ReceiveOrderIndexM),
+		payout:  make(map[string][]ordermodel.PayoutOrderIndexM),
+	}
+}
+
+var _ dao.IndexTableRepository = (*IndexStore)(nil)
+
+func (s *IndexStore) GetByOutIndexTable(table, mOrderId string, mId uint64) (*ordermodel.PayoutOrderIndexM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, idx := range s.payout[table] {
+		if idx.MID == mId && idx.MOrderID == mOrderId {
+			cp := idx
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *IndexStore) GetByIndexTable(table, mOrderId string, mId uint64) (*ordermodel.ReceiveOrderIndexM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, idx := range s.receive[table] {
+		if idx.MID == mId && idx.MOrderID == mOrderId {
+			cp := idx
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *IndexStore) InsertPayoutIndex(table string, index *ordermodel.PayoutOrderIndexM) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, idx := range s.payout[table] {
+		if idx.MID == index.MID && idx.MOrderID == index.MOrderID {
+			return fmt.Errorf("duplicate entry: %s m_id=%d m_order_id=%s", table, index.MID, index.MOrderID)
+		}
+	}
+	s.seq++
+	index.ID = s.seq
+	s.payout[table] = append(s.payout[table], *index)
+	return nil
+}
+
+func (s *IndexStore) InsertReceiveIndex(table string, index *ordermodel.ReceiveOrderIndexM) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, idx := range s.receive[table] {
+		if idx.MID == index.MID && idx.MOrderID == index.MOrderID {
+			return fmt.Errorf("duplicate entry: %s m_id=%d m_order_id=%s", table, index.MID, index.MOrderID)
+		}
+	}
+	s.seq++
+	index.ID = s.seq
+	s.receive[table] = append(s.receive[table], *index)
+	return nil
+}
+
+func (s *IndexStore) GetPayoutIndexByOrderId(table string, orderId uint64) (*ordermodel.PayoutOrderIndexM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, idx := range s.payout[table] {
+		if idx.OrderID == orderId {
+			cp := idx
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *IndexStore) GetReceiveIndexByOrderId(table string, orderId uint64) (*ordermodel.ReceiveOrderIndexM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, idx := range s.receive[table] {
+		if idx.OrderID == orderId {
+			cp := idx
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+type indexSnapshot struct {
+	receive map[string][]ordermodel.ReceiveOrderIndexM
+	payout  map[string][]ordermodel.PayoutOrderIndexM
+}
+
+func (s *IndexStore) snapshot() indexSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := indexSnapshot{
+		receive: make(map[string][]ordermodel.ReceiveOrderIndexM, len(s.receive)),
+		payout:  make(map[string][]ordermodel.PayoutOrderIndexM, len(s.payout)),
+	}
+	for t, rows := range s.receive {
+		snap.receive[t] = append([]ordermodel.ReceiveOrderIndexM(nil), rows...)
+	}
+	for t, rows := range s.payout {
+		snap.payout[t] = append([]ordermodel.PayoutOrderIndexM(nil), rows...)
+	}
+	return snap
+}
+
+func (s *IndexStore) restore(snap indexSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receive, s.payout = snap.receive, snap.payout
+}
@@ -0,0 +1,671 @@
+// Package memdao 仓储接口的内存实现，供单元测试注入，不依赖 MySQL。
+//
+// MainStore 只实现下单、回调、结算、冻结链路用到的方法；其余方法落到嵌入的 nil
+// dao.MainRepository 上，调用即 panic，测试覆盖到新的依赖时能第一时间发现。
+// 资金相关方法与 MainDao 的语义保持一致（资金日志按 用户+币种+订单号+类型+改派次数 幂等）。
+package memdao
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	mainmodel "wht-order-api/internal/model/main"
+
+	"github.com/shopspring/decimal"
+)
+
+var errNotFound = errors.New("record not found")
+
+// MainStore 主库内存实现
+type MainStore struct {
+	dao.MainRepository // 未实现的方法调用时 panic
+
+	mu               sync.Mutex
+	merchants        map[uint64]*mainmodel.Merchant
+	upstreams        map[uint64]*dto.VerifyUpstream
+	sysChannels      map[string]*dto.PayWayVo
+	merchantChannels map[string]*dto.MerchantChannelDTO // m_id:通道编码
+	products         map[uint64][]dto.PayProductVo      // m_id -> 商户可用通道产品
+	agentMerchants   []mainmodel.AgentMerchant
+	countries        map[string]dto.CurrencyCodeResponse
+	accounts         map[string]*mainmodel.MerchantMoney // uid:币种
+	moneyLogs        []mainmodel.MoneyLog
+	agentMoney       []mainmodel.AgentMoney
+	archives         []mainmodel.UpstreamCallbackArchive
+	successRate      map[int64][2]int // 通道产品ID -> [成功次数, 失败次数]
+	withdrawFees     []mainmodel.MerchantWithdrawFee
+	withdrawDests    []mainmodel.MerchantWithdrawDestination
+	approvalRules    []mainmodel.MerchantPayoutApproval
+}
+
+func NewMainStore() *MainStore {
+	return &MainStore{
+		merchants:        make(map[uint64]*mainmodel.Merchant),
+		upstreams:        make(map[uint64]*dto.VerifyUpstream),
+		sysChannels:      make(map[string]*dto.PayWayVo),
+		merchantChannels: make(map[string]*dto.MerchantChannelDTO),
+		products:         make(map[uint64][]dto.PayProductVo),
+		countries:        make(map[string]dto.CurrencyCodeResponse),
+		accounts:         make(map[string]*mainmodel.MerchantMoney),
+		successRate:      make(map[int64][2]int),
+	}
+}
+
+// ================== 测试数据 ==================
+
+func (s *MainStore) AddMerchant(m mainmodel.Merchant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.merchants[m.MerchantID] = &m
+}
+
+// AddUpstream 上游供应商 IP 白名单（逗号分隔）
+func (s *MainStore) AddUpstream(upstreamId uint64, ipWhitelist string, status int8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.upstreams[upstreamId] = &dto.VerifyUpstream{IpWhitelist: ipWhitelist, Status: status}
+}
+
+func (s *MainStore) AddSysChannel(ch dto.PayWayVo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sysChannels[ch.Coding] = &ch
+}
+
+func (s *MainStore) AddMerchantChannel(ch dto.MerchantChannelDTO) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.merchantChannels[merchantChannelKey(ch.MId, ch.SysChannelCode)] = &ch
+}
+
+// AddPayProduct 商户可用的上游通道产品
+func (s *MainStore) AddPayProduct(mId uint64, p dto.PayProductVo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.products[mId] = append(s.products[mId], p)
+}
+
+// AddAgentMerchant 代理-商户（或下级代理）关系，ListAgentChain 按 MainDao 的规则逐级查找
+func (s *MainStore) AddAgentMerchant(am mainmodel.AgentMerchant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	am.ID = len(s.agentMerchants) + 1
+	s.agentMerchants = append(s.agentMerchants, am)
+}
+
+func (s *MainStore) AddCountry(c dto.CurrencyCodeResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.countries[c.Code] = c
+}
+
+// SetAccount 设置钱包余额与冻结金额
+func (s *MainStore) SetAccount(uid uint64, currency string, money, freeze decimal.Decimal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[accountKey(uid, currency)] = &mainmodel.MerchantMoney{
+		UID: uid, Currency: currency, Status: 1, Money: money, FreezeMoney: freeze,
+		CreateTime: time.Now(), UpdateTime: time.Now(),
+	}
+}
+
+// AddWithdrawFeeRule 提现手续费规则（MID 为 0 时为币种默认规则）
+func (s *MainStore) AddWithdrawFeeRule(rule mainmodel.MerchantWithdrawFee) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rule.ID = uint64(len(s.withdrawFees) + 1)
+	s.withdrawFees = append(s.withdrawFees, rule)
+}
+
+// AddWithdrawDestination 提现账户（原样写入，ActiveAt 由调用方指定），返回账户ID
+func (s *MainStore) AddWithdrawDestination(dest mainmodel.MerchantWithdrawDestination) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dest.ID = uint64(len(s.withdrawDests) + 1)
+	s.withdrawDests = append(s.withdrawDests, dest)
+	return dest.ID
+}
+
+// AddPayoutApprovalRule 商户级代付复核规则
+func (s *MainStore) AddPayoutApprovalRule(rule mainmodel.MerchantPayoutApproval) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rule.ID = uint64(len(s.approvalRules) + 1)
+	s.approvalRules = append(s.approvalRules, rule)
+}
+
+// ================== 断言 ==================
+
+// Account 钱包（不存在时 ok=false）
+func (s *MainStore) Account(uid uint64, currency string) (mainmodel.MerchantMoney, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.accounts[accountKey(uid, currency)]
+	if !ok {
+		return mainmodel.MerchantMoney{}, false
+	}
+	return *acc, true
+}
+
+// MoneyLogs 指定用户的资金日志
+func (s *MainStore) MoneyLogs(uid uint64) []mainmodel.MoneyLog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []mainmodel.MoneyLog
+	for _, l := range s.moneyLogs {
+		if l.UID == uid {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// AgentMoneyLogs 代理佣金记录
+func (s *MainStore) AgentMoneyLogs() []mainmodel.AgentMoney {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]mainmodel.AgentMoney(nil), s.agentMoney...)
+}
+
+// Archives 上游回调归档
+func (s *MainStore) Archives() []mainmodel.UpstreamCallbackArchive {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]mainmodel.UpstreamCallbackArchive(nil), s.archives...)
+}
+
+// SuccessRate 通道产品成功/失败次数
+func (s *MainStore) SuccessRate(productID int64) (success, fail int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.successRate[productID]
+	return r[0], r[1]
+}
+
+// ================== 商户、通道 ==================
+
+func (s *MainStore) GetMerchant(mid string) (*mainmodel.Merchant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.merchants {
+		if m.AppId == mid {
+			cp := *m
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("query merchant failed: %w", errNotFound)
+}
+
+func (s *MainStore) GetMerchantId(mid string) (*mainmodel.Merchant, error) {
+	id, err := strconv.ParseUint(mid, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.merchants[id]
+	if !ok {
+		return nil, fmt.Errorf("query failed: %w", errNotFound)
+	}
+	cp := *m
+	return &cp, nil
+}
+
+func (s *MainStore) GetUpstreamWhitelist(upstreamId uint64) (*dto.VerifyUpstream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	up, ok := s.upstreams[upstreamId]
+	if !ok {
+		return nil, fmt.Errorf("query failed: %w", errNotFound)
+	}
+	cp := *up
+	return &cp, nil
+}
+
+func (s *MainStore) GetSysChannel(channelCode string) (*dto.PayWayVo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch, ok := s.sysChannels[channelCode]
+	if !ok || ch.Status != 1 {
+		return nil, fmt.Errorf("query failed: %w", errNotFound)
+	}
+	cp := *ch
+	return &cp, nil
+}
+
+func (s *MainStore) DetailChannel(mid uint64, channelCode string) (*dto.MerchantChannelDTO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch, ok := s.merchantChannels[merchantChannelKey(mid, channelCode)]
+	if !ok || ch.Status != 1 {
+		return nil, errNotFound
+	}
+	if ch.DefaultRate.Cmp(decimal.Zero) <= 0 && ch.Type == 1 {
+		return nil, errors.New("通道未设置有效费率")
+	}
+	cp := *ch
+	return &cp, nil
+}
+
+func (s *MainStore) GetAvailablePollingPayProducts(mId uint, payType string, currency string, channelType int8) ([]dto.PayProductVo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []dto.PayProductVo
+	for _, p := range s.products[uint64(mId)] {
+		if p.SysChannelCode == payType && p.Currency == currency && p.Type == channelType && p.Status == 1 {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (s *MainStore) GetSinglePayChannel(mId uint, sysChannelCode string, channelType int8, currency string) (dto.PayProductVo, error) {
+	products, _ := s.GetAvailablePollingPayProducts(mId, sysChannelCode, currency, channelType)
+	if len(products) == 0 {
+		return dto.PayProductVo{}, nil
+	}
+	return products[0], nil
+}
+
+func (s *MainStore) UpdateSuccessRate(productID int64, success bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.successRate[productID]
+	if success {
+		r[0]++
+	} else {
+		r[1]++
+	}
+	s.successRate[productID] = r
+	return nil
+}
+
+// ListAgentChain 与 MainDao 相同：第 1 级为 (a_id=商户上级, m_id=商户)，之后按下级代理逐级向上，停用或成环即停止
+func (s *MainStore) ListAgentChain(mId, pId uint64, sysChannelID int64) ([]mainmodel.AgentMerchant, error) {
+	if pId == 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var chain []mainmodel.AgentMerchant
+	seen := map[int64]bool{int64(mId): true}
+	child := int64(mId)
+	for level := 1; level <= 5; level++ {
+		var row *mainmodel.AgentMerchant
+		for i := range s.agentMerchants {
+			am := &s.agentMerchants[i]
+			if am.MID != child || am.SysChannelID != sysChannelID {
+				continue
+			}
+			if level == 1 && am.AID != int64(pId) {
+				continue
+			}
+			row = am
+			break
+		}
+		if row == nil || row.Status != 1 || seen[row.AID] {
+			break
+		}
+		chain = append(chain, *row)
+		seen[row.AID] = true
+		child = row.AID
+	}
+	return chain, nil
+}
+
+func (s *MainStore) ListFeeSchedules(sysChannelID int64, currency string) ([]dto.FeeSchedule, error) {
+	return nil, nil
+}
+
+func (s *MainStore) GetCountry(currency string) (dto.CurrencyCodeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.countries[currency]
+	if !ok {
+		return dto.CurrencyCodeResponse{}, fmt.Errorf("query failed: %w", errNotFound)
+	}
+	return c, nil
+}
+
+func (s *MainStore) GetMerchantLimit(mId uint64, currency string, orderType int8) (*mainmodel.MerchantLimit, error) {
+	return nil, nil
+}
+
+func (s *MainStore) GetChannelLimit(sysChannelID uint64, currency string) (*mainmodel.ChannelLimit, error) {
+	return nil, nil
+}
+
+func (s *MainStore) GetPayoutApprovalRule(mId uint64, currency string) (*mainmodel.MerchantPayoutApproval, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.approvalRules {
+		if r.MID == mId && r.Currency == currency && r.Status == 1 {
+			cp := r
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetWithdrawFeeRule 与 MainDao 一致：商户专属规则优先，其次币种默认规则
+func (s *MainStore) GetWithdrawFeeRule(mId uint64, currency string) (*mainmodel.MerchantWithdrawFee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found *mainmodel.MerchantWithdrawFee
+	for i := range s.withdrawFees {
+		r := &s.withdrawFees[i]
+		if r.Currency != currency || r.Status != 1 || (r.MID != mId && r.MID != 0) {
+			continue
+		}
+		if found == nil || r.MID > found.MID {
+			found = r
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	cp := *found
+	return &cp, nil
+}
+
+func (s *MainStore) GetWithdrawDestination(mId, id uint64) (*mainmodel.MerchantWithdrawDestination, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.withdrawDests {
+		if d.ID == id && d.MID == mId {
+			cp := d
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *MainStore) ListWithdrawDestinations(mId uint64) ([]mainmodel.MerchantWithdrawDestination, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []mainmodel.MerchantWithdrawDestination
+	for _, d := range s.withdrawDests {
+		if d.MID == mId {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// SaveWithdrawDestination ID 为 0 时新增，否则按 id + m_id 覆盖
+func (s *MainStore) SaveWithdrawDestination(dest *mainmodel.MerchantWithdrawDestination) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if dest.ID == 0 {
+		dest.ID = uint64(len(s.withdrawDests) + 1)
+		s.withdrawDests = append(s.withdrawDests, *dest)
+		return nil
+	}
+	for i := range s.withdrawDests {
+		if s.withdrawDests[i].ID == dest.ID && s.withdrawDests[i].MID == dest.MID {
+			s.withdrawDests[i] = *dest
+			return nil
+		}
+	}
+	return fmt.Errorf("save withdraw destination failed: %w", errNotFound)
+}
+
+func (s *MainStore) ListUpstreamErrorMappings(interfaceCode string) ([]mainmodel.UpstreamErrorMapping, error) {
+	return nil, nil
+}
+
+func (s *MainStore) CreateUpstreamCallbackArchive(archive *mainmodel.UpstreamCallbackArchive) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archive.ID = uint64(len(s.archives) + 1)
+	s.archives = append(s.archives, *archive)
+	return nil
+}
+
+// ================== 资金 ==================
+
+func (s *MainStore) GetMerchantAccount(mId string, currency string) (dto.MerchantMoney, error) {
+	uid, err := strconv.ParseUint(mId, 10, 64)
+	if err != nil {
+		return dto.MerchantMoney{}, fmt.Errorf("query failed: %w", err)
+	}
+	acc, ok := s.Account(uid, currency)
+	if !ok {
+		return dto.MerchantMoney{}, fmt.Errorf("query failed: %w", errNotFound)
+	}
+	return dto.MerchantMoney{UID: acc.UID, Currency: acc.Currency, Money: acc.Money, FreezeMoney: acc.FreezeMoney}, nil
+}
+
+// FreezePayout 余额转入冻结，写冻结日志
+func (s *MainStore) FreezePayout(uid uint64, currency, orderNo string, mOrderNo string, amount decimal.Decimal, operator string) error {
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return fmt.Errorf("invalid freeze amount: %s", amount.String())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[accountKey(uid, currency)]
+	if !ok {
+		return fmt.Errorf("get merchant account failed: %w", errNotFound)
+	}
+	if acc.Money.LessThan(amount) {
+		return fmt.Errorf("insufficient balance: uid=%d, balance=%s, need=%s", uid, acc.Money, amount)
+	}
+	newBalance := acc.Money.Sub(amount)
+	if !s.appendMoneyLog(mainmodel.MoneyLog{
+		Currency: currency, UID: uid, Money: amount.Neg(), OrderNo: orderNo, MOrderNo: mOrderNo,
+		Type: dto.MoneyLogTypeFreeze, Description: "代付下单冻结资金",
+		OldBalance: acc.Money, Balance: newBalance, Operator: operator, CreateBy: operator,
+	}) {
+		return nil
+	}
+	acc.Money = newBalance
+	acc.FreezeMoney = acc.FreezeMoney.Add(amount)
+	acc.UpdateTime = time.Now()
+	return nil
+}
+
+// FreezeAdditionalAmount 改派补冻结：差额从余额转入冻结（按 订单号+改派次数 幂等）
+func (s *MainStore) FreezeAdditionalAmount(uid uint64, currency string, orderNo string, attempt int, diff decimal.Decimal, operator string, mOrderNo string) error {
+	if diff.LessThanOrEqual(decimal.Zero) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[accountKey(uid, currency)]
+	if !ok {
+		return fmt.Errorf("get merchant account failed: %w", errNotFound)
+	}
+	if s.hasMoneyLogAttempt(uid, currency, orderNo, dto.MoneyLogTypeFreezeAdditional, attempt) {
+		return nil
+	}
+	if acc.Money.LessThan(diff) {
+		return fmt.Errorf("insufficient balance: uid=%d, balance=%s, need=%s", uid, acc.Money, diff)
+	}
+	newBalance := acc.Money.Sub(diff)
+	s.appendMoneyLog(mainmodel.MoneyLog{
+		Currency: currency, UID: uid, Money: diff.Neg(), OrderNo: orderNo, MOrderNo: mOrderNo, Attempt: attempt,
+		Type: dto.MoneyLogTypeFreezeAdditional, Description: "改派补充冻结",
+		OldBalance: acc.Money, Balance: newBalance, Operator: operator, CreateBy: operator,
+	})
+	acc.Money = newBalance
+	acc.FreezeMoney = acc.FreezeMoney.Add(diff)
+	acc.UpdateTime = time.Now()
+	return nil
+}
+
+// ReleaseExcessFreeze 改派释放多余冻结：差额从冻结退回余额（按 订单号+改派次数 幂等）
+func (s *MainStore) ReleaseExcessFreeze(uid uint64, currency string, orderNo string, attempt int, diff decimal.Decimal, operator string, mOrderNo string) error {
+	if diff.LessThanOrEqual(decimal.Zero) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[accountKey(uid, currency)]
+	if !ok {
+		return fmt.Errorf("get merchant account failed: %w", errNotFound)
+	}
+	if s.hasMoneyLogAttempt(uid, currency, orderNo, dto.MoneyLogTypeUnfreezeExcess, attempt) {
+		return nil
+	}
+	if acc.FreezeMoney.LessThan(diff) {
+		return fmt.Errorf("insufficient frozen funds: uid=%d, frozen=%s, need=%s", uid, acc.FreezeMoney, diff)
+	}
+	newBalance := acc.Money.Add(diff)
+	s.appendMoneyLog(mainmodel.MoneyLog{
+		Currency: currency, UID: uid, Money: diff, OrderNo: orderNo, MOrderNo: mOrderNo, Attempt: attempt,
+		Type: dto.MoneyLogTypeUnfreezeExcess, Description: "改派释放多余冻结",
+		OldBalance: acc.Money, Balance: newBalance, Operator: operator, CreateBy: operator,
+	})
+	acc.Money = newBalance
+	acc.FreezeMoney = acc.FreezeMoney.Sub(diff)
+	acc.UpdateTime = time.Now()
+	return nil
+}
+
+// HandlePayoutCallback 成功从冻结扣除；失败从冻结退回余额
+func (s *MainStore) HandlePayoutCallback(uid uint64, currency, orderNo string, mOrderNo string, merchantFees decimal.Decimal, agentFees decimal.Decimal, status bool, orderAmount decimal.Decimal, operator string) error {
+	total := orderAmount.Add(agentFees).Add(merchantFees)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[accountKey(uid, currency)]
+	if !ok {
+		return fmt.Errorf("get merchant account failed: %w", errNotFound)
+	}
+	// 与 MainDao 一致：先按资金日志去重（重复回调直接成功），再校验冻结足额
+	var logType int8 = dto.MoneyLogTypeUnfreezeDel
+	if status {
+		logType = dto.MoneyLogTypePayout
+	}
+	if s.hasMoneyLog(uid, currency, orderNo, logType) {
+		return nil
+	}
+	if acc.FreezeMoney.LessThan(total) {
+		return fmt.Errorf("insufficient frozen funds: uid=%d, frozen=%s, need=%s", uid, acc.FreezeMoney, total)
+	}
+
+	oldBalance := acc.Money
+	if status {
+		s.appendMoneyLog(mainmodel.MoneyLog{
+			Currency: currency, UID: uid, Money: total.Neg(), OrderNo: orderNo, MOrderNo: mOrderNo,
+			Type: dto.MoneyLogTypePayout, Description: "代付成功，扣除冻结资金",
+			OldBalance: oldBalance, Balance: oldBalance, Operator: operator, CreateBy: operator,
+		})
+		acc.FreezeMoney = acc.FreezeMoney.Sub(total)
+		acc.UpdateTime = time.Now()
+		return nil
+	}
+
+	newBalance := oldBalance.Add(total)
+	s.appendMoneyLog(mainmodel.MoneyLog{
+		Currency: currency, UID: uid, Money: total.Neg(), OrderNo: orderNo, MOrderNo: mOrderNo,
+		Type: dto.MoneyLogTypeUnfreezeDel, Description: "代付失败，取消冻结资金",
+		OldBalance: oldBalance, Balance: oldBalance, Operator: operator, CreateBy: operator,
+	})
+	s.appendMoneyLog(mainmodel.MoneyLog{
+		Currency: currency, UID: uid, Money: total, OrderNo: orderNo, MOrderNo: mOrderNo,
+		Type: dto.MoneyLogTypeUnfreeze, Description: "代付失败，解冻资金退回余额",
+		OldBalance: oldBalance, Balance: newBalance, Operator: operator, CreateBy: operator,
+	})
+	acc.Money = newBalance
+	acc.FreezeMoney = acc.FreezeMoney.Sub(total)
+	acc.UpdateTime = time.Now()
+	return nil
+}
+
+// CreateMoneyLog 写资金日志并入账（账户不存在时创建）
+func (s *MainStore) CreateMoneyLog(moneyLog dto.MoneyLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credit(moneyLog.UID, moneyLog.Currency, mainmodel.MoneyLog{
+		Money: moneyLog.Money, OrderNo: moneyLog.OrderNo, MOrderNo: moneyLog.MOrderNo, Type: moneyLog.Type,
+		Operator: moneyLog.Operator, Description: moneyLog.Description, CreateBy: moneyLog.CreateBy,
+	})
+	return nil
+}
+
+// CreateAgentMoneyLog 代理佣金记录（按 代理+订单+类型 幂等）并入账到代理钱包
+func (s *MainStore) CreateAgentMoneyLog(agentMoney dto.AgentMoney, moneyLogType int8, remark string) error {
+	if agentMoney.AID <= 0 || agentMoney.Money.LessThanOrEqual(decimal.Zero) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, am := range s.agentMoney {
+		if am.AID == agentMoney.AID && am.OrderNo == agentMoney.OrderNo && am.Type == agentMoney.Type {
+			return nil
+		}
+	}
+	s.agentMoney = append(s.agentMoney, mainmodel.AgentMoney{
+		ID: uint64(len(s.agentMoney) + 1), Currency: agentMoney.Currency, AID: agentMoney.AID, MID: agentMoney.MID,
+		OrderNo: agentMoney.OrderNo, MOrderNo: agentMoney.MOrderNo, Type: agentMoney.Type,
+		OrderMoney: agentMoney.OrderMoney, Remark: agentMoney.Remark, Money: agentMoney.Money, CreateTime: time.Now(),
+	})
+	s.credit(agentMoney.AID, agentMoney.Currency, mainmodel.MoneyLog{
+		Money: agentMoney.Money, OrderNo: agentMoney.OrderNo, MOrderNo: agentMoney.MOrderNo,
+		Type: moneyLogType, Description: remark,
+	})
+	return nil
+}
+
+// credit 入账：资金日志写入成功才更新余额（调用方持有锁）
+func (s *MainStore) credit(uid uint64, currency string, entry mainmodel.MoneyLog) {
+	acc, ok := s.accounts[accountKey(uid, currency)]
+	oldBalance := decimal.Zero
+	if ok {
+		oldBalance = acc.Money
+	}
+	entry.UID, entry.Currency = uid, currency
+	entry.OldBalance, entry.Balance = oldBalance, oldBalance.Add(entry.Money)
+	if !s.appendMoneyLog(entry) {
+		return
+	}
+	if !ok {
+		acc = &mainmodel.MerchantMoney{UID: uid, Currency: currency, Status: 1, FreezeMoney: decimal.Zero, CreateTime: time.Now()}
+		s.accounts[accountKey(uid, currency)] = acc
+	}
+	acc.Money = entry.Balance
+	acc.UpdateTime = time.Now()
+}
+
+// appendMoneyLog 模拟 w_money_log 唯一约束：同一 用户+币种+订单号+类型+改派次数 只写一次（调用方持有锁）
+func (s *MainStore) appendMoneyLog(entry mainmodel.MoneyLog) bool {
+	if s.hasMoneyLogAttempt(entry.UID, entry.Currency, entry.OrderNo, entry.Type, entry.Attempt) {
+		return false
+	}
+	entry.ID = uint64(len(s.moneyLogs) + 1)
+	entry.CreateTime = time.Now()
+	s.moneyLogs = append(s.moneyLogs, entry)
+	return true
+}
+
+// hasMoneyLog 资金日志是否已存在（首次下单，改派次数 0；调用方持有锁）
+func (s *MainStore) hasMoneyLog(uid uint64, currency, orderNo string, logType int8) bool {
+	return s.hasMoneyLogAttempt(uid, currency, orderNo, logType, 0)
+}
+
+func (s *MainStore) hasMoneyLogAttempt(uid uint64, currency, orderNo string, logType int8, attempt int) bool {
+	for _, l := range s.moneyLogs {
+		if l.UID == uid && l.Currency == currency && l.OrderNo == orderNo && l.Type == logType && l.Attempt == attempt {
+			return true
+		}
+	}
+	return false
+}
+
+func accountKey(uid uint64, currency string) string {
+	return fmt.Sprintf("%d:%s", uid, currency)
+}
+
+func merchantChannelKey(mid uint64, channelCode string) string {
+	return fmt.Sprintf("%d:%s", mid, channelCode)
+}
@@ -0,0 +1,292 @@
+package memdao
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	ordermodel "wht-order-api/internal/model/order"
+
+	"gorm.io/gorm"
+)
+
+// OrderStore 代收订单库内存实现（按分表名隔离，可发现分表计算不一致的问题）
+// 代收索引写入共享给 Index，测试中可把 Index 作为 IndexTableRepository 注入；发件箱事件写入 Outbox
+type OrderStore struct {
+	mu     sync.Mutex
+	orders map[string]map[uint64]*ordermodel.MerchantOrder // 表名 -> order_id -> 订单
+	txs    map[string]map[uint64]*ordermodel.UpstreamTx    // 表名 -> up_order_id -> 上游交易
+	Index  *IndexStore
+	Outbox *OutboxStore
+}
+
+func NewOrderStore() *OrderStore {
+	return &OrderStore{
+		orders: make(map[string]map[uint64]*ordermodel.MerchantOrder),
+		txs:    make(map[string]map[uint64]*ordermodel.UpstreamTx),
+		Index:  NewIndexStore(),
+		Outbox: NewOutboxStore(),
+	}
+}
+
+var _ dao.OrderRepository = (*OrderStore)(nil)
+
+func (s *OrderStore) Insert(table string, o *ordermodel.MerchantOrder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := s.orders[table]
+	if rows == nil {
+		rows = make(map[uint64]*ordermodel.MerchantOrder)
+		s.orders[table] = rows
+	}
+	if _, ok := rows[o.OrderID]; ok {
+		return fmt.Errorf("duplicate order_id %d in %s", o.OrderID, table)
+	}
+	cp := *o
+	rows[o.OrderID] = &cp
+	return nil
+}
+
+func (s *OrderStore) GetByID(table string, id uint64) (*ordermodel.MerchantOrder, error) {
+	return s.GetByOrderId(table, id)
+}
+
+func (s *OrderStore) GetByMerchantNo(table string, mid uint64, mNo string) (*ordermodel.MerchantOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.orders[table] {
+		if o.MID == mid && o.MOrderID == mNo {
+			cp := *o
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *OrderStore) ListInTables(tables []string, kw string, status *int8, limit, offset int) ([]ordermodel.MerchantOrder, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.MerchantOrder
+	for _, t := range tables {
+		for _, o := range s.orders[t] {
+			if kw != "" && !strings.Contains(o.MOrderID, kw) {
+				continue
+			}
+			if status != nil && o.Status != *status {
+				continue
+			}
+			out = append(out, *o)
+		}
+	}
+	total := int64(len(out))
+	if offset >= len(out) {
+		return nil, total, nil
+	}
+	out = out[offset:]
+	if limit > 0 && limit < len(out) {
+		out = out[:limit]
+	}
+	return out, total, nil
+}
+
+func (s *OrderStore) InsertTx(table string, o *ordermodel.UpstreamTx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := s.txs[table]
+	if rows == nil {
+		rows = make(map[uint64]*ordermodel.UpstreamTx)
+		s.txs[table] = rows
+	}
+	if _, ok := rows[o.UpOrderId]; ok {
+		return fmt.Errorf("duplicate up_order_id %d in %s", o.UpOrderId, table)
+	}
+	cp := *o
+	rows[o.UpOrderId] = &cp
+	return nil
+}
+
+func (s *OrderStore) UpdateUpTx(table string, o dto.UpdateUpTxVo) error {
+	data := map[string]interface{}{}
+	if o.UpOrderNo != "" {
+		data["up_order_no"] = o.UpOrderNo
+	}
+	if !o.UpdateTime.IsZero() {
+		data["update_time"] = o.UpdateTime
+	}
+	return s.UpdateTxFields(table, o.UpOrderId, data)
+}
+
+func (s *OrderStore) UpdateOrder(table string, o dto.UpdateOrderVo) error {
+	// 与 gorm Updates(struct) 一致：零值字段不更新
+	data := map[string]interface{}{}
+	if o.UpOrderId != 0 {
+		data["up_order_id"] = o.UpOrderId
+	}
+	if o.SupplierId != 0 {
+		data["supplier_id"] = o.SupplierId
+	}
+	if !o.UpdateTime.IsZero() {
+		data["update_time"] = o.UpdateTime
+	}
+	return s.UpdateOrderFields(table, o.OrderId, data)
+}
+
+func (s *OrderStore) UpdateOrderFields(table string, orderId uint64, data map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o, ok := s.orders[table][orderId]; ok {
+		applyColumns(o, data)
+	}
+	return nil
+}
+
+func (s *OrderStore) UpdateTxFields(table string, upOrderId uint64, data map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tx, ok := s.txs[table][upOrderId]; ok {
+		applyColumns(tx, data)
+	}
+	return nil
+}
+
+func (s *OrderStore) InsertReceiveOrderIndexTable(table string, o *ordermodel.ReceiveOrderIndexM) error {
+	return s.Index.InsertReceiveIndex(table, o)
+}
+
+func (s *OrderStore) GetByOrderId(table string, orderId uint64) (*ordermodel.MerchantOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[table][orderId]
+	if !ok {
+		return nil, nil
+	}
+	cp := *o
+	return &cp, nil
+}
+
+func (s *OrderStore) GetTxByOrderId(table string, orderId uint64) (*ordermodel.UpstreamTx, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tx := range s.txs[table] {
+		if tx.OrderID == orderId {
+			cp := *tx
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *OrderStore) GetTxByUpOrderId(table string, upOrderId uint64) (*ordermodel.UpstreamTx, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, ok := s.txs[table][upOrderId]
+	if !ok {
+		return nil, fmt.Errorf("query not found: up_order_id=%d: %w", upOrderId, gorm.ErrRecordNotFound)
+	}
+	cp := *tx
+	return &cp, nil
+}
+
+func (s *OrderStore) GetByStatus(table string, status int8) ([]ordermodel.MerchantOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.MerchantOrder
+	for _, o := range s.orders[table] {
+		if o.Status == status {
+			out = append(out, *o)
+		}
+	}
+	return out, nil
+}
+
+func (s *OrderStore) BatchUpdateStatus(table string, orderIds []uint64, status int8) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range orderIds {
+		if o, ok := s.orders[table][id]; ok {
+			o.Status = status
+		}
+	}
+	return nil
+}
+
+func (s *OrderStore) GetOrderCount(table string, mid uint64, status *int8) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, o := range s.orders[table] {
+		if o.MID == mid && (status == nil || o.Status == *status) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Transaction fn 返回错误时恢复到执行前的快照（不做并发隔离，仅用于单测）
+func (s *OrderStore) Transaction(fn func(repo dao.OrderRepository) error) error {
+	orders, txs := s.snapshot()
+	index := s.Index.snapshot()
+	outbox := s.Outbox.snapshot()
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.orders, s.txs = orders, txs
+		s.mu.Unlock()
+		s.Index.restore(index)
+		s.Outbox.restore(outbox)
+		return err
+	}
+	return nil
+}
+
+func (s *OrderStore) InsertOutbox(e *ordermodel.OutboxEventM) error {
+	return s.Outbox.Insert(e)
+}
+
+func (s *OrderStore) snapshot() (map[string]map[uint64]*ordermodel.MerchantOrder, map[string]map[uint64]*ordermodel.UpstreamTx) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make(map[string]map[uint64]*ordermodel.MerchantOrder, len(s.orders))
+	for t, rows := range s.orders {
+		orders[t] = make(map[uint64]*ordermodel.MerchantOrder, len(rows))
+		for id, o := range rows {
+			cp := *o
+			orders[t][id] = &cp
+		}
+	}
+	txs := make(map[string]map[uint64]*ordermodel.UpstreamTx, len(s.txs))
+	for t, rows := range s.txs {
+		txs[t] = make(map[uint64]*ordermodel.UpstreamTx, len(rows))
+		for id, tx := range rows {
+			cp := *tx
+			txs[t][id] = &cp
+		}
+	}
+	return orders, txs
+}
+
+// Orders 全部订单（断言用）
+func (s *OrderStore) Orders() []ordermodel.MerchantOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.MerchantOrder
+	for _, rows := range s.orders {
+		for _, o := range rows {
+			out = append(out, *o)
+		}
+	}
+	return out
+}
+
+// Txs 全部上游交易（断言用）
+func (s *OrderStore) Txs() []ordermodel.UpstreamTx {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.UpstreamTx
+	for _, rows := range s.txs {
+		for _, tx := range rows {
+			out = append(out, *tx)
+		}
+	}
+	return out
+}
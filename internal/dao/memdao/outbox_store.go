@@ -0,0 +1,130 @@
+package memdao
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"wht-order-api/internal/dao"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+// OutboxStore 发件箱内存实现，OrderStore.InsertOutbox 写入这里，测试中可直接作为 OutboxRepository 注入 Relay
+type OutboxStore struct {
+	mu     sync.Mutex
+	nextID uint64
+	rows   []*ordermodel.OutboxEventM
+}
+
+func NewOutboxStore() *OutboxStore {
+	return &OutboxStore{}
+}
+
+var _ dao.OutboxRepository = (*OutboxStore)(nil)
+
+func (s *OutboxStore) Insert(e *ordermodel.OutboxEventM) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.EventID == e.EventID {
+			return fmt.Errorf("duplicate event_id %s", e.EventID)
+		}
+	}
+	s.nextID++
+	e.ID = s.nextID
+	cp := *e
+	s.rows = append(s.rows, &cp)
+	return nil
+}
+
+func (s *OutboxStore) ListDue(now time.Time, limit int) ([]ordermodel.OutboxEventM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.OutboxEventM
+	for _, r := range s.rows {
+		if r.Status == ordermodel.OutboxPending && !r.NextRetryAt.After(now) {
+			out = append(out, *r)
+			if len(out) == limit {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *OutboxStore) MarkSent(id uint64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r := s.find(id); r != nil && r.Status == ordermodel.OutboxPending {
+		r.Status = ordermodel.OutboxSent
+		r.SentTime = &now
+		r.UpdateTime = &now
+	}
+	return nil
+}
+
+func (s *OutboxStore) MarkRetry(id uint64, attempts int, nextRetryAt time.Time, lastErr string, dead bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r := s.find(id); r != nil && r.Status == ordermodel.OutboxPending {
+		if dead {
+			r.Status = ordermodel.OutboxDead
+		}
+		r.Attempts = attempts
+		r.NextRetryAt = nextRetryAt
+		r.LastError = lastErr
+	}
+	return nil
+}
+
+func (s *OutboxStore) PurgeSent(before time.Time, limit int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	kept := s.rows[:0]
+	for _, r := range s.rows {
+		if r.Status == ordermodel.OutboxSent && r.SentTime != nil && r.SentTime.Before(before) && n < int64(limit) {
+			n++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	s.rows = kept
+	return n, nil
+}
+
+// Events 全部事件（断言用）
+func (s *OutboxStore) Events() []ordermodel.OutboxEventM {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ordermodel.OutboxEventM, 0, len(s.rows))
+	for _, r := range s.rows {
+		out = append(out, *r)
+	}
+	return out
+}
+
+func (s *OutboxStore) find(id uint64) *ordermodel.OutboxEventM {
+	for _, r := range s.rows {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *OutboxStore) snapshot() []*ordermodel.OutboxEventM {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*ordermodel.OutboxEventM, 0, len(s.rows))
+	for _, r := range s.rows {
+		cp := *r
+		out = append(out, &cp)
+	}
+	return out
+}
+
+func (s *OutboxStore) restore(rows []*ordermodel.OutboxEventM) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = rows
+}
@@ -0,0 +1,167 @@
+package memdao
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"wht-order-api/internal/dao"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+// PayoutApprovalStore 代付复核记录内存实现（order_id 唯一；收款账户按 商户+币种+账号 唯一）
+// 复核事务内的订单更新写入 Orders
+type PayoutApprovalStore struct {
+	mu            sync.Mutex
+	approvals     []ordermodel.PayoutApprovalM
+	beneficiaries []ordermodel.PayoutBeneficiaryM
+	Orders        *PayoutOrderStore
+}
+
+func NewPayoutApprovalStore(orders *PayoutOrderStore) *PayoutApprovalStore {
+	return &PayoutApprovalStore{Orders: orders}
+}
+
+var _ dao.PayoutApprovalRepository = (*PayoutApprovalStore)(nil)
+
+func (s *PayoutApprovalStore) Insert(o *ordermodel.PayoutApprovalM) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.approvals {
+		if a.OrderID == o.OrderID {
+			return fmt.Errorf("duplicate entry: order_id=%d", o.OrderID)
+		}
+	}
+	o.ID = uint64(len(s.approvals) + 1)
+	s.approvals = append(s.approvals, *o)
+	return nil
+}
+
+// GetByOrderId 未找到返回 nil, nil（与 PayoutApprovalDao 一致）
+func (s *PayoutApprovalStore) GetByOrderId(orderId uint64) (*ordermodel.PayoutApprovalM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.approvals {
+		if a.OrderID == orderId {
+			cp := a
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+// Decide 仅待复核记录可置为终态（与 PayoutApprovalDao 的条件更新一致）
+func (s *PayoutApprovalStore) Decide(orderId uint64, status int8, operator, operatorIP, remark string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for i := range s.approvals {
+		a := &s.approvals[i]
+		if a.OrderID != orderId || a.Status != ordermodel.PayoutApprovalPending {
+			continue
+		}
+		a.Status, a.Operator, a.OperatorIP, a.Remark = status, operator, operatorIP, remark
+		a.DecideTime, a.UpdateTime = &now, &now
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *PayoutApprovalStore) ListPending(mId uint64, limit, offset int) ([]ordermodel.PayoutApprovalM, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.PayoutApprovalM
+	for _, a := range s.approvals {
+		if a.Status == ordermodel.PayoutApprovalPending && (mId == 0 || a.MID == mId) {
+			out = append(out, a)
+		}
+	}
+	total := int64(len(out))
+	if offset >= len(out) {
+		return nil, total, nil
+	}
+	out = out[offset:]
+	if limit > 0 && limit < len(out) {
+		out = out[:limit]
+	}
+	return out, total, nil
+}
+
+func (s *PayoutApprovalStore) ListExpired(now time.Time, limit int) ([]ordermodel.PayoutApprovalM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.PayoutApprovalM
+	for _, a := range s.approvals {
+		if a.Status == ordermodel.PayoutApprovalPending && !a.ExpireAt.After(now) {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	if limit > 0 && limit < len(out) {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *PayoutApprovalStore) BeneficiaryExists(mId uint64, currency, accNo string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.beneficiaries {
+		if b.MID == mId && b.Currency == currency && b.AccNo == accNo {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RecordBeneficiary 已存在则忽略
+func (s *PayoutApprovalStore) RecordBeneficiary(mId uint64, currency, accNo string, orderId uint64) error {
+	if ok, _ := s.BeneficiaryExists(mId, currency, accNo); ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.beneficiaries = append(s.beneficiaries, ordermodel.PayoutBeneficiaryM{
+		ID: uint64(len(s.beneficiaries) + 1), MID: mId, Currency: currency, AccNo: accNo,
+		FirstOrderID: orderId, CreateTime: time.Now(),
+	})
+	return nil
+}
+
+func (s *PayoutApprovalStore) Reopen(orderId uint64, fromStatus int8) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for i := range s.approvals {
+		if s.approvals[i].OrderID == orderId && s.approvals[i].Status == fromStatus {
+			s.approvals[i].Status = ordermodel.PayoutApprovalPending
+			s.approvals[i].UpdateTime = &now
+		}
+	}
+	return nil
+}
+
+// Transaction fn 返回错误时恢复复核记录与代付订单库快照（不做并发隔离，仅用于单测）
+func (s *PayoutApprovalStore) Transaction(fn func(repo dao.PayoutApprovalRepository, orderRepo dao.PayoutOrderRepository) error) error {
+	s.mu.Lock()
+	approvals := append([]ordermodel.PayoutApprovalM(nil), s.approvals...)
+	s.mu.Unlock()
+	orders, txs, reassign := s.Orders.snapshot()
+	if err := fn(s, s.Orders); err != nil {
+		s.mu.Lock()
+		s.approvals = approvals
+		s.mu.Unlock()
+		s.Orders.mu.Lock()
+		s.Orders.orders, s.Orders.txs, s.Orders.reassign = orders, txs, reassign
+		s.Orders.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Approvals 全部复核记录（断言用）
+func (s *PayoutApprovalStore) Approvals() []ordermodel.PayoutApprovalM {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ordermodel.PayoutApprovalM(nil), s.approvals...)
+}
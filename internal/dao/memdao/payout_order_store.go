@@ -0,0 +1,303 @@
+package memdao
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+// PayoutOrderStore 代付订单库内存实现（按分表名隔离，与 OrderStore 一致）
+// 代付索引写入共享给 Index，发件箱事件写入 Outbox
+type PayoutOrderStore struct {
+	mu       sync.Mutex
+	orders   map[string]map[uint64]*ordermodel.MerchantPayOutOrderM // 表名 -> order_id -> 订单
+	txs      map[string]map[uint64]*ordermodel.PayoutUpstreamTxM    // 表名 -> up_order_id -> 上游交易
+	reassign []ordermodel.PayoutReassignM
+	Index    *IndexStore
+	Outbox   *OutboxStore
+}
+
+func NewPayoutOrderStore() *PayoutOrderStore {
+	return &PayoutOrderStore{
+		orders: make(map[string]map[uint64]*ordermodel.MerchantPayOutOrderM),
+		txs:    make(map[string]map[uint64]*ordermodel.PayoutUpstreamTxM),
+		Index:  NewIndexStore(),
+		Outbox: NewOutboxStore(),
+	}
+}
+
+var _ dao.PayoutOrderRepository = (*PayoutOrderStore)(nil)
+
+func (s *PayoutOrderStore) Insert(table string, o *ordermodel.MerchantPayOutOrderM) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := s.orders[table]
+	if rows == nil {
+		rows = make(map[uint64]*ordermodel.MerchantPayOutOrderM)
+		s.orders[table] = rows
+	}
+	if _, ok := rows[o.OrderID]; ok {
+		return fmt.Errorf("duplicate order_id %d in %s", o.OrderID, table)
+	}
+	cp := *o
+	rows[o.OrderID] = &cp
+	return nil
+}
+
+// UpdateByWhere 条件同时作用于该表的订单与上游交易（分表名不会重叠）
+func (s *PayoutOrderStore) UpdateByWhere(table string, where map[string]interface{}, data map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.orders[table] {
+		if matchColumns(o, where) {
+			applyColumns(o, data)
+		}
+	}
+	for _, tx := range s.txs[table] {
+		if matchColumns(tx, where) {
+			applyColumns(tx, data)
+		}
+	}
+	return nil
+}
+
+func (s *PayoutOrderStore) GetByID(table string, id uint64) (*ordermodel.MerchantPayOutOrderM, error) {
+	return s.GetByOrderId(table, id)
+}
+
+func (s *PayoutOrderStore) GetByMerchantNo(table string, mid uint64, mNo string) (*ordermodel.MerchantPayOutOrderM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.orders[table] {
+		if o.MID == mid && o.MOrderID == mNo {
+			cp := *o
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *PayoutOrderStore) ListInTables(tables []string, kw string, status *int8, limit, offset int) ([]ordermodel.MerchantPayOutOrderM, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.MerchantPayOutOrderM
+	for _, t := range tables {
+		for _, o := range s.orders[t] {
+			if kw != "" && !strings.Contains(o.MOrderID, kw) {
+				continue
+			}
+			if status != nil && o.Status != *status {
+				continue
+			}
+			out = append(out, *o)
+		}
+	}
+	total := int64(len(out))
+	if offset >= len(out) {
+		return nil, total, nil
+	}
+	out = out[offset:]
+	if limit > 0 && limit < len(out) {
+		out = out[:limit]
+	}
+	return out, total, nil
+}
+
+func (s *PayoutOrderStore) InsertTx(table string, o *ordermodel.PayoutUpstreamTxM) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := s.txs[table]
+	if rows == nil {
+		rows = make(map[uint64]*ordermodel.PayoutUpstreamTxM)
+		s.txs[table] = rows
+	}
+	if _, ok := rows[o.UpOrderId]; ok {
+		return fmt.Errorf("duplicate up_order_id %d in %s", o.UpOrderId, table)
+	}
+	cp := *o
+	rows[o.UpOrderId] = &cp
+	return nil
+}
+
+func (s *PayoutOrderStore) UpdateUpTx(table string, o dto.UpdateUpTxVo) error {
+	data := map[string]interface{}{}
+	if o.UpOrderNo != "" {
+		data["up_order_no"] = o.UpOrderNo
+	}
+	if !o.UpdateTime.IsZero() {
+		data["update_time"] = o.UpdateTime
+	}
+	return s.UpdateByWhere(table, map[string]interface{}{"up_order_id": o.UpOrderId}, data)
+}
+
+func (s *PayoutOrderStore) UpdateOrder(table string, o dto.UpdateOrderVo) error {
+	// 与 gorm Updates(struct) 一致：零值字段不更新
+	data := map[string]interface{}{}
+	if o.UpOrderId != 0 {
+		data["up_order_id"] = o.UpOrderId
+	}
+	if o.SupplierId != 0 {
+		data["supplier_id"] = o.SupplierId
+	}
+	if !o.UpdateTime.IsZero() {
+		data["update_time"] = o.UpdateTime
+	}
+	return s.UpdateByWhere(table, map[string]interface{}{"order_id": o.OrderId}, data)
+}
+
+func (s *PayoutOrderStore) InsertPayoutOrderIndexTable(table string, o *ordermodel.PayoutOrderIndexM) error {
+	return s.Index.InsertPayoutIndex(table, o)
+}
+
+func (s *PayoutOrderStore) GetByOrderId(table string, orderId uint64) (*ordermodel.MerchantPayOutOrderM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[table][orderId]
+	if !ok {
+		return nil, nil
+	}
+	cp := *o
+	return &cp, nil
+}
+
+func (s *PayoutOrderStore) GetTxByOrderId(table string, orderId uint64) (*ordermodel.PayoutUpstreamTxM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tx := range s.txs[table] {
+		if tx.OrderID == orderId {
+			cp := *tx
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetTxByUpOrderId 未找到返回 nil, nil（与 PayoutOrderDao 一致）
+func (s *PayoutOrderStore) GetTxByUpOrderId(table string, upOrderId uint64) (*ordermodel.PayoutUpstreamTxM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, ok := s.txs[table][upOrderId]
+	if !ok {
+		return nil, nil
+	}
+	cp := *tx
+	return &cp, nil
+}
+
+func (s *PayoutOrderStore) InsertReassign(o *ordermodel.PayoutReassignM) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.reassign {
+		if r.OrderID == o.OrderID && r.Attempt == o.Attempt {
+			return fmt.Errorf("duplicate entry: order_id=%d attempt=%d", o.OrderID, o.Attempt)
+		}
+	}
+	o.ID = uint64(len(s.reassign) + 1)
+	s.reassign = append(s.reassign, *o)
+	return nil
+}
+
+func (s *PayoutOrderStore) UpdateReassignResult(orderId, upOrderId uint64, result int8, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for i := range s.reassign {
+		if s.reassign[i].OrderID == orderId && s.reassign[i].UpOrderID == upOrderId {
+			s.reassign[i].Result = result
+			s.reassign[i].Reason = reason
+			s.reassign[i].UpdateTime = &now
+		}
+	}
+	return nil
+}
+
+func (s *PayoutOrderStore) ListReassignByOrderId(orderId uint64) ([]ordermodel.PayoutReassignM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.PayoutReassignM
+	for _, r := range s.reassign {
+		if r.OrderID == orderId {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Attempt < out[j].Attempt })
+	return out, nil
+}
+
+func (s *PayoutOrderStore) InsertOutbox(e *ordermodel.OutboxEventM) error {
+	return s.Outbox.Insert(e)
+}
+
+// Transaction fn 返回错误时恢复到执行前的快照（不做并发隔离，仅用于单测）
+func (s *PayoutOrderStore) Transaction(fn func(repo dao.PayoutOrderRepository) error) error {
+	orders, txs, reassign := s.snapshot()
+	index := s.Index.snapshot()
+	outbox := s.Outbox.snapshot()
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.orders, s.txs, s.reassign = orders, txs, reassign
+		s.mu.Unlock()
+		s.Index.restore(index)
+		s.Outbox.restore(outbox)
+		return err
+	}
+	return nil
+}
+
+func (s *PayoutOrderStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *PayoutOrderStore) snapshot() (map[string]map[uint64]*ordermodel.MerchantPayOutOrderM, map[string]map[uint64]*ordermodel.PayoutUpstreamTxM, []ordermodel.PayoutReassignM) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make(map[string]map[uint64]*ordermodel.MerchantPayOutOrderM, len(s.orders))
+	for t, rows := range s.orders {
+		orders[t] = make(map[uint64]*ordermodel.MerchantPayOutOrderM, len(rows))
+		for id, o := range rows {
+			cp := *o
+			orders[t][id] = &cp
+		}
+	}
+	txs := make(map[string]map[uint64]*ordermodel.PayoutUpstreamTxM, len(s.txs))
+	for t, rows := range s.txs {
+		txs[t] = make(map[uint64]*ordermodel.PayoutUpstreamTxM, len(rows))
+		for id, tx := range rows {
+			cp := *tx
+			txs[t][id] = &cp
+		}
+	}
+	return orders, txs, append([]ordermodel.PayoutReassignM(nil), s.reassign...)
+}
+
+// Orders 全部订单（断言用）
+func (s *PayoutOrderStore) Orders() []ordermodel.MerchantPayOutOrderM {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.MerchantPayOutOrderM
+	for _, rows := range s.orders {
+		for _, o := range rows {
+			out = append(out, *o)
+		}
+	}
+	return out
+}
+
+// Txs 全部上游交易（断言用）
+func (s *PayoutOrderStore) Txs() []ordermodel.PayoutUpstreamTxM {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.PayoutUpstreamTxM
+	for _, rows := range s.txs {
+		for _, tx := range rows {
+			out = append(out, *tx)
+		}
+	}
+	return out
+}
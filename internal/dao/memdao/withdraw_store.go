@@ -0,0 +1,140 @@
+package memdao
+
+import (
+	"fmt"
+	"sync"
+	"wht-order-api/internal/dao"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+// WithdrawStore 提现订单库内存实现（按分表名隔离，商户+商户提现单号唯一）
+// 上游交易与代付共表，写入 Payout
+type WithdrawStore struct {
+	mu     sync.Mutex
+	orders map[string]map[uint64]*ordermodel.WithdrawOrderM // 表名 -> order_id -> 订单
+	index  []ordermodel.WithdrawOrderIndexM
+	Payout *PayoutOrderStore
+}
+
+func NewWithdrawStore(payout *PayoutOrderStore) *WithdrawStore {
+	return &WithdrawStore{
+		orders: make(map[string]map[uint64]*ordermodel.WithdrawOrderM),
+		Payout: payout,
+	}
+}
+
+var _ dao.WithdrawRepository = (*WithdrawStore)(nil)
+
+func (s *WithdrawStore) Insert(table string, o *ordermodel.WithdrawOrderM) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := s.orders[table]
+	if rows == nil {
+		rows = make(map[uint64]*ordermodel.WithdrawOrderM)
+		s.orders[table] = rows
+	}
+	if _, ok := rows[o.OrderID]; ok {
+		return fmt.Errorf("duplicate order_id %d in %s", o.OrderID, table)
+	}
+	cp := *o
+	rows[o.OrderID] = &cp
+	return nil
+}
+
+func (s *WithdrawStore) InsertIndex(o *ordermodel.WithdrawOrderIndexM) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, idx := range s.index {
+		if idx.MID == o.MID && idx.WithdrawNo == o.WithdrawNo {
+			return fmt.Errorf("duplicate entry: m_id=%d withdraw_no=%s", o.MID, o.WithdrawNo)
+		}
+	}
+	o.ID = uint64(len(s.index) + 1)
+	s.index = append(s.index, *o)
+	return nil
+}
+
+// GetIndex 未找到返回 nil, nil（与 WithdrawDao 一致）
+func (s *WithdrawStore) GetIndex(mId uint64, withdrawNo string) (*ordermodel.WithdrawOrderIndexM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, idx := range s.index {
+		if idx.MID == mId && idx.WithdrawNo == withdrawNo {
+			cp := idx
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *WithdrawStore) GetByOrderId(table string, orderId uint64) (*ordermodel.WithdrawOrderM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[table][orderId]
+	if !ok {
+		return nil, nil
+	}
+	cp := *o
+	return &cp, nil
+}
+
+// UpdateByWhere 返回命中行数（提现回调按原状态 CAS）
+func (s *WithdrawStore) UpdateByWhere(table string, where map[string]interface{}, data map[string]interface{}) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows int64
+	for _, o := range s.orders[table] {
+		if matchColumns(o, where) {
+			applyColumns(o, data)
+			rows++
+		}
+	}
+	return rows, nil
+}
+
+func (s *WithdrawStore) InsertTx(table string, o *ordermodel.PayoutUpstreamTxM) error {
+	return s.Payout.InsertTx(table, o)
+}
+
+// Transaction fn 返回错误时恢复到执行前的快照（含代付上游交易表；不做并发隔离，仅用于单测）
+func (s *WithdrawStore) Transaction(fn func(repo dao.WithdrawRepository) error) error {
+	orders, index := s.snapshot()
+	payoutOrders, txs, reassign := s.Payout.snapshot()
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.orders, s.index = orders, index
+		s.mu.Unlock()
+		s.Payout.mu.Lock()
+		s.Payout.orders, s.Payout.txs, s.Payout.reassign = payoutOrders, txs, reassign
+		s.Payout.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *WithdrawStore) snapshot() (map[string]map[uint64]*ordermodel.WithdrawOrderM, []ordermodel.WithdrawOrderIndexM) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make(map[string]map[uint64]*ordermodel.WithdrawOrderM, len(s.orders))
+	for t, rows := range s.orders {
+		orders[t] = make(map[uint64]*ordermodel.WithdrawOrderM, len(rows))
+		for id, o := range rows {
+			cp := *o
+			orders[t][id] = &cp
+		}
+	}
+	return orders, append([]ordermodel.WithdrawOrderIndexM(nil), s.index...)
+}
+
+// Orders 全部提现订单（断言用）
+func (s *WithdrawStore) Orders() []ordermodel.WithdrawOrderM {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ordermodel.WithdrawOrderM
+	for _, rows := range s.orders {
+		for _, o := range rows {
+			out = append(out, *o)
+		}
+	}
+	return out
+}
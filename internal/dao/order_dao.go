@@ -142,6 +142,40 @@ func (r *OrderDao) UpdateOrder(table string, o dto.UpdateOrderVo) error {
 	return r.DB.Table(table).Where("order_id = ?", o.OrderId).Updates(o).Error
 }
 
+// 按 order_id 更新订单指定字段
+func (r *OrderDao) UpdateOrderFields(table string, orderId uint64, data map[string]interface{}) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("update order fields failed: %w", err)
+	}
+	return r.DB.Table(table).Where("order_id = ?", orderId).Updates(data).Error
+}
+
+// 按 up_order_id 更新上游交易指定字段
+func (r *OrderDao) UpdateTxFields(table string, upOrderId uint64, data map[string]interface{}) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("update tx fields failed: %w", err)
+	}
+	return r.DB.Table(table).Where("up_order_id = ?", upOrderId).Updates(data).Error
+}
+
+// 写入发件箱事件（与订单变更同一事务时使用事务 Dao 调用）
+func (r *OrderDao) InsertOutbox(e *ordermodel.OutboxEventM) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("insert outbox event failed: %w", err)
+	}
+	return NewOutboxDaoWithDB(r.DB).Insert(e)
+}
+
+// 事务内执行（fn 内使用传入的事务 Dao）
+func (r *OrderDao) Transaction(fn func(repo OrderRepository) error) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(NewOrderDaoWithDB(tx))
+	})
+}
+
 // 插入代收订单索引表
 func (r *OrderDao) InsertReceiveOrderIndexTable(table string, o *ordermodel.ReceiveOrderIndexM) error {
 	if err := r.checkDB(); err != nil {
@@ -0,0 +1,105 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+	"wht-order-api/internal/dal"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+type OutboxDao struct {
+	DB *gorm.DB
+}
+
+// 工厂方法：默认使用 dal.OrderDB
+func NewOutboxDao() *OutboxDao {
+	if dal.OrderDB == nil {
+		log.Panic("[FATAL] dal.OrderDB is nil - database not initialized")
+	}
+	return &OutboxDao{DB: dal.OrderDB}
+}
+
+// 支持传入自定义 DB（订单事务内写入发件箱）
+func NewOutboxDaoWithDB(db *gorm.DB) *OutboxDao {
+	if db == nil {
+		log.Panic("[FATAL] db cannot be nil")
+	}
+	return &OutboxDao{DB: db}
+}
+
+// 安全检查方法
+func (r *OutboxDao) checkDB() error {
+	if r == nil {
+		return errors.New("OutboxDao is nil")
+	}
+	if r.DB == nil {
+		return errors.New("DB connection is nil")
+	}
+	return nil
+}
+
+// Insert 写入待投递事件
+func (r *OutboxDao) Insert(e *ordermodel.OutboxEventM) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("insert outbox event failed: %w", err)
+	}
+	return r.DB.Create(e).Error
+}
+
+// ListDue 查询到期待投递的事件（按写入顺序）
+func (r *OutboxDao) ListDue(now time.Time, limit int) ([]ordermodel.OutboxEventM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("list outbox events failed: %w", err)
+	}
+	var list []ordermodel.OutboxEventM
+	err := r.DB.Where("status = ? AND next_retry_at <= ?", ordermodel.OutboxPending, now).
+		Order("id ASC").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// MarkSent 标记投递成功
+func (r *OutboxDao) MarkSent(id uint64, now time.Time) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("mark outbox sent failed: %w", err)
+	}
+	return r.DB.Model(&ordermodel.OutboxEventM{}).
+		Where("id = ? AND status = ?", id, ordermodel.OutboxPending).
+		Updates(map[string]interface{}{
+			"status":      ordermodel.OutboxSent,
+			"sent_time":   now,
+			"update_time": now,
+		}).Error
+}
+
+// MarkRetry 记录投递失败：dead 为 true 时转死信，否则在 nextRetryAt 后重试
+func (r *OutboxDao) MarkRetry(id uint64, attempts int, nextRetryAt time.Time, lastErr string, dead bool) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("mark outbox retry failed: %w", err)
+	}
+	status := ordermodel.OutboxPending
+	if dead {
+		status = ordermodel.OutboxDead
+	}
+	return r.DB.Model(&ordermodel.OutboxEventM{}).
+		Where("id = ? AND status = ?", id, ordermodel.OutboxPending).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"attempts":      attempts,
+			"next_retry_at": nextRetryAt,
+			"last_error":    lastErr,
+			"update_time":   time.Now(),
+		}).Error
+}
+
+// PurgeSent 删除 before 之前已投递的事件，返回删除数量
+func (r *OutboxDao) PurgeSent(before time.Time, limit int) (int64, error) {
+	if err := r.checkDB(); err != nil {
+		return 0, fmt.Errorf("purge outbox events failed: %w", err)
+	}
+	res := r.DB.Where("status = ? AND sent_time < ?", ordermodel.OutboxSent, before).
+		Limit(limit).Delete(&ordermodel.OutboxEventM{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,171 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+	"wht-order-api/internal/dal"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+type PayoutApprovalDao struct {
+	DB *gorm.DB
+}
+
+// 工厂方法：默认使用 dal.OrderDB
+func NewPayoutApprovalDao() *PayoutApprovalDao {
+	if dal.OrderDB == nil {
+		log.Panic("[FATAL] dal.OrderDB is nil - database not initialized")
+	}
+	return &PayoutApprovalDao{DB: dal.OrderDB}
+}
+
+// 支持传入自定义 DB（比如 txDB）
+func NewPayoutApprovalDaoWithDB(db *gorm.DB) *PayoutApprovalDao {
+	if db == nil {
+		log.Panic("[FATAL] db cannot be nil")
+	}
+	return &PayoutApprovalDao{DB: db}
+}
+
+// 安全检查方法
+func (r *PayoutApprovalDao) checkDB() error {
+	if r == nil {
+		return errors.New("PayoutApprovalDao is nil")
+	}
+	if r.DB == nil {
+		return errors.New("DB connection is nil")
+	}
+	return nil
+}
+
+// Insert 写入待复核记录
+func (r *PayoutApprovalDao) Insert(o *ordermodel.PayoutApprovalM) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("insert payout approval failed: %w", err)
+	}
+	return r.DB.Create(o).Error
+}
+
+// GetByOrderId 按平台订单号查询复核记录（不存在返回 nil）
+func (r *PayoutApprovalDao) GetByOrderId(orderId uint64) (*ordermodel.PayoutApprovalM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("get payout approval failed: %w", err)
+	}
+	var o ordermodel.PayoutApprovalM
+	err := r.DB.Where("order_id = ?", orderId).First(&o).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// Decide 将待复核记录置为终态（仅当仍处于待复核时生效，返回是否抢占成功）
+func (r *PayoutApprovalDao) Decide(orderId uint64, status int8, operator, operatorIP, remark string) (bool, error) {
+	if err := r.checkDB(); err != nil {
+		return false, fmt.Errorf("decide payout approval failed: %w", err)
+	}
+	now := time.Now()
+	res := r.DB.Model(&ordermodel.PayoutApprovalM{}).
+		Where("order_id = ? AND status = ?", orderId, ordermodel.PayoutApprovalPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"operator":    operator,
+			"operator_ip": operatorIP,
+			"remark":      remark,
+			"decide_time": now,
+			"update_time": now,
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// ListPending 分页查询待复核记录
+func (r *PayoutApprovalDao) ListPending(mId uint64, limit, offset int) ([]ordermodel.PayoutApprovalM, int64, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, 0, fmt.Errorf("list payout approval failed: %w", err)
+	}
+	q := r.DB.Model(&ordermodel.PayoutApprovalM{}).Where("status = ?", ordermodel.PayoutApprovalPending)
+	if mId > 0 {
+		q = q.Where("m_id = ?", mId)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []ordermodel.PayoutApprovalM
+	if err := q.Order("id ASC").Limit(limit).Offset(offset).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ListExpired 查询已超时仍待复核的记录
+func (r *PayoutApprovalDao) ListExpired(now time.Time, limit int) ([]ordermodel.PayoutApprovalM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("list expired payout approval failed: %w", err)
+	}
+	var list []ordermodel.PayoutApprovalM
+	err := r.DB.Where("status = ? AND expire_at <= ?", ordermodel.PayoutApprovalPending, now).
+		Order("id ASC").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// BeneficiaryExists 收款账户是否已成功出款过
+func (r *PayoutApprovalDao) BeneficiaryExists(mId uint64, currency, accNo string) (bool, error) {
+	if err := r.checkDB(); err != nil {
+		return false, fmt.Errorf("check beneficiary failed: %w", err)
+	}
+	var cnt int64
+	if err := r.DB.Model(&ordermodel.PayoutBeneficiaryM{}).
+		Where("m_id = ? AND currency = ? AND acc_no = ?", mId, currency, accNo).
+		Count(&cnt).Error; err != nil {
+		return false, err
+	}
+	return cnt > 0, nil
+}
+
+// RecordBeneficiary 记录成功出款的收款账户（已存在则忽略）
+func (r *PayoutApprovalDao) RecordBeneficiary(mId uint64, currency, accNo string, orderId uint64) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("record beneficiary failed: %w", err)
+	}
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&ordermodel.PayoutBeneficiaryM{
+		MID:          mId,
+		Currency:     currency,
+		AccNo:        accNo,
+		FirstOrderID: orderId,
+		CreateTime:   time.Now(),
+	}).Error
+}
+
+// Reopen 资金处理失败时将复核记录恢复为待复核，便于重试
+func (r *PayoutApprovalDao) Reopen(orderId uint64, fromStatus int8) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("reopen payout approval failed: %w", err)
+	}
+	return r.DB.Model(&ordermodel.PayoutApprovalM{}).
+		Where("order_id = ? AND status = ?", orderId, fromStatus).
+		Updates(map[string]interface{}{
+			"status":      ordermodel.PayoutApprovalPending,
+			"update_time": time.Now(),
+		}).Error
+}
+
+// Transaction 复核记录与代付订单在同一事务内执行，fn 返回错误时回滚
+func (r *PayoutApprovalDao) Transaction(fn func(repo PayoutApprovalRepository, orderRepo PayoutOrderRepository) error) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(NewPayoutApprovalDaoWithDB(tx), NewPayoutOrderDaoWithDB(tx))
+	})
+}
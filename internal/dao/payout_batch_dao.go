@@ -0,0 +1,122 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+	"wht-order-api/internal/dal"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+type PayoutBatchDao struct {
+	DB *gorm.DB
+}
+
+// 工厂方法：默认使用 dal.OrderDB
+func NewPayoutBatchDao() *PayoutBatchDao {
+	if dal.OrderDB == nil {
+		log.Panic("[FATAL] dal.OrderDB is nil - database not initialized")
+	}
+	return &PayoutBatchDao{DB: dal.OrderDB}
+}
+
+// 支持传入自定义 DB（比如 txDB）
+func NewPayoutBatchDaoWithDB(db *gorm.DB) *PayoutBatchDao {
+	if db == nil {
+		log.Panic("[FATAL] db cannot be nil")
+	}
+	return &PayoutBatchDao{DB: db}
+}
+
+// 安全检查方法
+func (r *PayoutBatchDao) checkDB() error {
+	if r == nil {
+		return errors.New("PayoutBatchDao is nil")
+	}
+	if r.DB == nil {
+		return errors.New("DB connection is nil")
+	}
+	return nil
+}
+
+// InsertBatch 写入批次及批次行
+func (r *PayoutBatchDao) InsertBatch(batch *ordermodel.PayoutBatchM, items []ordermodel.PayoutBatchItemM) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("insert batch failed: %w", err)
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return fmt.Errorf("insert batch failed: %w", err)
+		}
+		if err := tx.CreateInBatches(items, 200).Error; err != nil {
+			return fmt.Errorf("insert batch items failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetByBatchNo 根据商户ID+商户批次号查询批次
+func (r *PayoutBatchDao) GetByBatchNo(mId uint64, batchNo string) (*ordermodel.PayoutBatchM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("get batch failed: %w", err)
+	}
+	var m ordermodel.PayoutBatchM
+	err := r.DB.Where("m_id = ? AND batch_no = ?", mId, batchNo).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return &m, nil
+}
+
+// ListItems 查询批次全部行
+func (r *PayoutBatchDao) ListItems(batchId uint64) ([]ordermodel.PayoutBatchItemM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("list batch items failed: %w", err)
+	}
+	var list []ordermodel.PayoutBatchItemM
+	if err := r.DB.Where("batch_id = ?", batchId).Order("line_no ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return list, nil
+}
+
+// UpdateItem 更新批次行处理结果
+func (r *PayoutBatchDao) UpdateItem(id uint64, orderId uint64, status int8, errMsg string) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("update batch item failed: %w", err)
+	}
+	return r.DB.Model(&ordermodel.PayoutBatchItemM{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"order_id":    orderId,
+			"status":      status,
+			"error_msg":   errMsg,
+			"update_time": time.Now(),
+		}).Error
+}
+
+// UpdateBatch 更新批次信息
+func (r *PayoutBatchDao) UpdateBatch(batchId uint64, data map[string]interface{}) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("update batch failed: %w", err)
+	}
+	return r.DB.Model(&ordermodel.PayoutBatchM{}).Where("batch_id = ?", batchId).Updates(data).Error
+}
+
+// ListProcessingBatches 查询 before 之前创建、仍处于处理中的批次（用于重启后恢复）
+func (r *PayoutBatchDao) ListProcessingBatches(before time.Time, limit int) ([]ordermodel.PayoutBatchM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("list processing batches failed: %w", err)
+	}
+	var list []ordermodel.PayoutBatchM
+	if err := r.DB.Where("status = ? AND create_time < ?", ordermodel.PayoutBatchStatusProcessing, before).
+		Order("create_time ASC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return list, nil
+}
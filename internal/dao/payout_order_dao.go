@@ -1,9 +1,11 @@
 package dao
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"time"
 	"wht-order-api/internal/dto"
 
 	"gorm.io/gorm"
@@ -204,3 +206,63 @@ func (r *PayoutOrderDao) GetTxByUpOrderId(table string, upOrderId uint64) (*orde
 	}
 	return &m, nil
 }
+
+// InsertReassign 写入改派链路记录
+func (r *PayoutOrderDao) InsertReassign(o *ordermodel.PayoutReassignM) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("insert reassign failed: %w", err)
+	}
+	return r.DB.Create(o).Error
+}
+
+// UpdateReassignResult 根据上游交易ID更新改派链路结果
+func (r *PayoutOrderDao) UpdateReassignResult(orderId, upOrderId uint64, result int8, reason string) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("update reassign failed: %w", err)
+	}
+	return r.DB.Model(&ordermodel.PayoutReassignM{}).
+		Where("order_id = ? AND up_order_id = ?", orderId, upOrderId).
+		Updates(map[string]interface{}{
+			"result":      result,
+			"reason":      reason,
+			"update_time": time.Now(),
+		}).Error
+}
+
+// ListReassignByOrderId 按尝试序号查询订单改派链路
+func (r *PayoutOrderDao) ListReassignByOrderId(orderId uint64) ([]ordermodel.PayoutReassignM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("list reassign failed: %w", err)
+	}
+	var list []ordermodel.PayoutReassignM
+	if err := r.DB.Where("order_id = ?", orderId).Order("attempt ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return list, nil
+}
+
+// 写入发件箱事件（与订单变更同一事务时使用事务 Dao 调用）
+func (r *PayoutOrderDao) InsertOutbox(e *ordermodel.OutboxEventM) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("insert outbox event failed: %w", err)
+	}
+	return NewOutboxDaoWithDB(r.DB).Insert(e)
+}
+
+// 事务内执行（fn 内使用传入的事务 Dao）
+func (r *PayoutOrderDao) Transaction(fn func(repo PayoutOrderRepository) error) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(NewPayoutOrderDaoWithDB(tx))
+	})
+}
+
+// Ping 订单库连通性检查
+func (r *PayoutOrderDao) Ping(ctx context.Context) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return r.DB.WithContext(ctx).Exec("SELECT 1").Error
+}
@@ -0,0 +1,177 @@
+package dao
+
+import (
+	"context"
+	"time"
+	"wht-order-api/internal/dto"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+
+	"github.com/shopspring/decimal"
+)
+
+// 仓储接口：服务、回调、结算依赖接口而不是具体 Dao，单元测试可注入内存实现（见 dao/memdao）
+
+// MainRepository 主库（商户、通道、资金）访问
+type MainRepository interface {
+	GetMerchant(mid string) (*mainmodel.Merchant, error)
+	GetMerchantWhitelist(mid uint64, mode int8) ([]mainmodel.MerchantWhitelist, error)
+	GetMerchantId(mid string) (*mainmodel.Merchant, error)
+	GetUpstreamWhitelist(upstreamId uint64) (*dto.VerifyUpstream, error)
+	GetChannel(cid uint64) (*mainmodel.Channel, error)
+	SelectPaymentChannel(merchantID uint, channelCode string, currency string) ([]dto.PaymentChannelVo, error)
+	GetAgentMerchant(param dto.QueryAgentMerchant) (*mainmodel.AgentMerchant, error)
+	ListAgentChain(mId, pId uint64, sysChannelID int64) ([]mainmodel.AgentMerchant, error)
+	ListFeeSchedules(sysChannelID int64, currency string) ([]dto.FeeSchedule, error)
+	GetSysChannel(channelCode string) (*dto.PayWayVo, error)
+	GetMerchantAccount(mId string, currency string) (dto.MerchantMoney, error)
+	ListMerchantWallets(mId uint64) ([]dto.Account, error)
+	ConvertMerchantWallet(conv *mainmodel.MerchantFxConversion) (existed bool, err error)
+	FreezeAdditionalAmount(uid uint64, currency string, orderNo string, attempt int, diff decimal.Decimal, operator string, mOrderNo string) error
+	ReleaseExcessFreeze(uid uint64, currency string, orderNo string, attempt int, diff decimal.Decimal, operator string, mOrderNo string) error
+	QueryUpstreamBankInfo(interfaceId int, internalBankCode string, currency string) (dto.BankCodeMappingDto, error)
+	QueryPlatformBankInfo(internalBankCode string, currency string) (dto.BankCodeDto, error)
+	FreezePayout(uid uint64, currency, orderNo string, mOrderNo string, amount decimal.Decimal, operator string) error
+	HandlePayoutCallback(uid uint64, currency, orderNo string, mOrderNo string, merchantFees decimal.Decimal, agentFees decimal.Decimal, status bool, orderAmount decimal.Decimal, operator string) error
+	CreateMoneyLog(moneyLog dto.MoneyLog) error
+	UpsertMerchantMoney(merchantMoney dto.MerchantMoney) error
+	CreateAgentMoneyLog(agentMoney dto.AgentMoney, moneyLogType int8, remark string) error
+	GetAccountDetail(mId uint64, currency string) (dto.AccountResp, error)
+	CheckChannelValid(mid uint64, channelCode string) error
+	DetailChannel(mid uint64, channelCode string) (*dto.MerchantChannelDTO, error)
+	UpdateSuccessRate(productID int64, success bool) error
+	GetAvailablePollingPayProducts(mId uint, payType string, currency string, channelType int8) ([]dto.PayProductVo, error)
+	GetTestSinglePayChannel(mId uint, sysChannelCode string, channelType int8, currency string, payProductId uint64) (dto.PayProductVo, error)
+	GetSinglePayChannel(mId uint, sysChannelCode string, channelType int8, currency string) (dto.PayProductVo, error)
+	GetCountry(currency string) (dto.CurrencyCodeResponse, error)
+	GetUpstreamSupplier(upstreamId uint64) (*dto.UpstreamSupplierDto, error)
+	GetPayoutApprovalRule(mId uint64, currency string) (*mainmodel.MerchantPayoutApproval, error)
+	GetWithdrawFeeRule(mId uint64, currency string) (*mainmodel.MerchantWithdrawFee, error)
+	GetWithdrawDestination(mId, id uint64) (*mainmodel.MerchantWithdrawDestination, error)
+	ListWithdrawDestinations(mId uint64) ([]mainmodel.MerchantWithdrawDestination, error)
+	SaveWithdrawDestination(dest *mainmodel.MerchantWithdrawDestination) error
+	ListMoneyLogs(f MoneyLogFilter, limit, offset int) ([]mainmodel.MoneyLog, int64, error)
+	EachMoneyLogs(f MoneyLogFilter, batch int, fn func([]mainmodel.MoneyLog) error) error
+	GetBalanceAt(uid uint64, currency string, t time.Time) (balance decimal.Decimal, ok bool, err error)
+	GetMerchantLimit(mId uint64, currency string, orderType int8) (*mainmodel.MerchantLimit, error)
+	GetChannelLimit(sysChannelID uint64, currency string) (*mainmodel.ChannelLimit, error)
+	ListRiskRules() ([]mainmodel.RiskRule, error)
+	ListRiskBlacklist() ([]mainmodel.RiskBlacklist, error)
+	ListUpstreamErrorMappings(interfaceCode string) ([]mainmodel.UpstreamErrorMapping, error)
+	CreateUpstreamCallbackArchive(archive *mainmodel.UpstreamCallbackArchive) error
+}
+
+// OrderRepository 代收订单库（订单、上游交易、索引）访问
+type OrderRepository interface {
+	Insert(table string, o *ordermodel.MerchantOrder) error
+	GetByID(table string, id uint64) (*ordermodel.MerchantOrder, error)
+	GetByMerchantNo(table string, mid uint64, mNo string) (*ordermodel.MerchantOrder, error)
+	ListInTables(tables []string, kw string, status *int8, limit, offset int) ([]ordermodel.MerchantOrder, int64, error)
+	InsertTx(table string, o *ordermodel.UpstreamTx) error
+	UpdateUpTx(table string, o dto.UpdateUpTxVo) error
+	UpdateOrder(table string, o dto.UpdateOrderVo) error
+	UpdateOrderFields(table string, orderId uint64, data map[string]interface{}) error
+	UpdateTxFields(table string, upOrderId uint64, data map[string]interface{}) error
+	InsertReceiveOrderIndexTable(table string, o *ordermodel.ReceiveOrderIndexM) error
+	GetByOrderId(table string, orderId uint64) (*ordermodel.MerchantOrder, error)
+	GetTxByOrderId(table string, orderId uint64) (*ordermodel.UpstreamTx, error)
+	GetTxByUpOrderId(table string, upOrderId uint64) (*ordermodel.UpstreamTx, error)
+	GetByStatus(table string, status int8) ([]ordermodel.MerchantOrder, error)
+	BatchUpdateStatus(table string, orderIds []uint64, status int8) error
+	GetOrderCount(table string, mid uint64, status *int8) (int64, error)
+	// InsertOutbox 写入发件箱事件（需在订单变更的同一事务内调用）
+	InsertOutbox(e *ordermodel.OutboxEventM) error
+	// Transaction 在同一事务内执行 fn，fn 返回错误时回滚
+	Transaction(fn func(repo OrderRepository) error) error
+}
+
+// PayoutOrderRepository 代付订单库访问
+type PayoutOrderRepository interface {
+	Insert(table string, o *ordermodel.MerchantPayOutOrderM) error
+	UpdateByWhere(table string, where map[string]interface{}, data map[string]interface{}) error
+	GetByID(table string, id uint64) (*ordermodel.MerchantPayOutOrderM, error)
+	GetByMerchantNo(table string, mid uint64, mNo string) (*ordermodel.MerchantPayOutOrderM, error)
+	ListInTables(tables []string, kw string, status *int8, limit, offset int) ([]ordermodel.MerchantPayOutOrderM, int64, error)
+	InsertTx(table string, o *ordermodel.PayoutUpstreamTxM) error
+	UpdateUpTx(table string, o dto.UpdateUpTxVo) error
+	UpdateOrder(table string, o dto.UpdateOrderVo) error
+	InsertPayoutOrderIndexTable(table string, o *ordermodel.PayoutOrderIndexM) error
+	GetByOrderId(table string, orderId uint64) (*ordermodel.MerchantPayOutOrderM, error)
+	GetTxByOrderId(table string, orderId uint64) (*ordermodel.PayoutUpstreamTxM, error)
+	GetTxByUpOrderId(table string, upOrderId uint64) (*ordermodel.PayoutUpstreamTxM, error)
+	InsertReassign(o *ordermodel.PayoutReassignM) error
+	UpdateReassignResult(orderId, upOrderId uint64, result int8, reason string) error
+	ListReassignByOrderId(orderId uint64) ([]ordermodel.PayoutReassignM, error)
+	// InsertOutbox 写入发件箱事件（需在订单变更的同一事务内调用）
+	InsertOutbox(e *ordermodel.OutboxEventM) error
+	// Transaction 在同一事务内执行 fn，fn 返回错误时回滚
+	Transaction(fn func(repo PayoutOrderRepository) error) error
+	// Ping 订单库连通性检查（服务健康检查使用）
+	Ping(ctx context.Context) error
+}
+
+// WithdrawRepository 商户提现订单库访问
+type WithdrawRepository interface {
+	Insert(table string, o *ordermodel.WithdrawOrderM) error
+	InsertIndex(o *ordermodel.WithdrawOrderIndexM) error
+	GetIndex(mId uint64, withdrawNo string) (*ordermodel.WithdrawOrderIndexM, error)
+	GetByOrderId(table string, orderId uint64) (*ordermodel.WithdrawOrderM, error)
+	UpdateByWhere(table string, where map[string]interface{}, data map[string]interface{}) (int64, error)
+	// InsertTx 写入提现对应的上游交易（与代付共用上游交易表，同在订单库）
+	InsertTx(table string, o *ordermodel.PayoutUpstreamTxM) error
+	// Transaction 在同一事务内执行 fn，fn 返回错误时回滚
+	Transaction(fn func(repo WithdrawRepository) error) error
+}
+
+// PayoutApprovalRepository 代付复核记录与已出款收款账户访问
+type PayoutApprovalRepository interface {
+	Insert(o *ordermodel.PayoutApprovalM) error
+	GetByOrderId(orderId uint64) (*ordermodel.PayoutApprovalM, error)
+	Decide(orderId uint64, status int8, operator, operatorIP, remark string) (bool, error)
+	ListPending(mId uint64, limit, offset int) ([]ordermodel.PayoutApprovalM, int64, error)
+	ListExpired(now time.Time, limit int) ([]ordermodel.PayoutApprovalM, error)
+	BeneficiaryExists(mId uint64, currency, accNo string) (bool, error)
+	RecordBeneficiary(mId uint64, currency, accNo string, orderId uint64) error
+	Reopen(orderId uint64, fromStatus int8) error
+	// Transaction 复核记录与代付订单在同一事务内写入（同在订单库），fn 返回错误时回滚
+	Transaction(fn func(repo PayoutApprovalRepository, orderRepo PayoutOrderRepository) error) error
+}
+
+// IndexTableRepository 商户订单号索引表访问
+type IndexTableRepository interface {
+	GetByOutIndexTable(table, mOrderId string, mId uint64) (*ordermodel.PayoutOrderIndexM, error)
+	GetByIndexTable(table, mOrderId string, mId uint64) (*ordermodel.ReceiveOrderIndexM, error)
+	InsertPayoutIndex(table string, index *ordermodel.PayoutOrderIndexM) error
+	InsertReceiveIndex(table string, index *ordermodel.ReceiveOrderIndexM) error
+	GetPayoutIndexByOrderId(table string, orderId uint64) (*ordermodel.PayoutOrderIndexM, error)
+	GetReceiveIndexByOrderId(table string, orderId uint64) (*ordermodel.ReceiveOrderIndexM, error)
+}
+
+// OutboxRepository 发件箱投递端访问
+type OutboxRepository interface {
+	ListDue(now time.Time, limit int) ([]ordermodel.OutboxEventM, error)
+	MarkSent(id uint64, now time.Time) error
+	MarkRetry(id uint64, attempts int, nextRetryAt time.Time, lastErr string, dead bool) error
+	PurgeSent(before time.Time, limit int) (int64, error)
+}
+
+// CallbackDedupRepository 上游回调去重记录访问
+type CallbackDedupRepository interface {
+	Claim(rec *ordermodel.CallbackDedupM) (bool, error)
+	GetByKey(mode string, upOrderID uint64, status, msgID string) (*ordermodel.CallbackDedupM, error)
+	GetTerminal(mode string, upOrderID uint64) (*ordermodel.CallbackDedupM, error)
+	TakeOver(id uint64, staleBefore, now time.Time) (bool, error)
+	MarkDone(id uint64, now time.Time) error
+	Release(id uint64) error
+}
+
+var (
+	_ MainRepository           = (*MainDao)(nil)
+	_ OrderRepository          = (*OrderDao)(nil)
+	_ PayoutOrderRepository    = (*PayoutOrderDao)(nil)
+	_ WithdrawRepository       = (*WithdrawDao)(nil)
+	_ PayoutApprovalRepository = (*PayoutApprovalDao)(nil)
+	_ IndexTableRepository     = (*IndexTableDao)(nil)
+	_ OutboxRepository         = (*OutboxDao)(nil)
+	_ CallbackDedupRepository  = (*CallbackDedupDao)(nil)
+)
@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"wht-order-api/internal/dal"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+type WithdrawDao struct {
+	DB *gorm.DB
+}
+
+// 工厂方法：默认使用 dal.OrderDB
+func NewWithdrawDao() *WithdrawDao {
+	if dal.OrderDB == nil {
+		log.Panic("[FATAL] dal.OrderDB is nil - database not initialized")
+	}
+	return &WithdrawDao{DB: dal.OrderDB}
+}
+
+// 支持传入自定义 DB（比如 txDB）
+func NewWithdrawDaoWithDB(db *gorm.DB) *WithdrawDao {
+	if db == nil {
+		log.Panic("[FATAL] db cannot be nil")
+	}
+	return &WithdrawDao{DB: db}
+}
+
+// 安全检查方法
+func (r *WithdrawDao) checkDB() error {
+	if r == nil {
+		return errors.New("WithdrawDao is nil")
+	}
+	if r.DB == nil {
+		return errors.New("DB connection is nil")
+	}
+	return nil
+}
+
+// Insert 写入提现订单
+func (r *WithdrawDao) Insert(table string, o *ordermodel.WithdrawOrderM) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("insert withdraw order failed: %w", err)
+	}
+	return r.DB.Table(table).Create(o).Error
+}
+
+// InsertIndex 写入商户提现单号索引（唯一约束 m_id + withdraw_no 保证幂等）
+func (r *WithdrawDao) InsertIndex(o *ordermodel.WithdrawOrderIndexM) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("insert withdraw index failed: %w", err)
+	}
+	return r.DB.Create(o).Error
+}
+
+// GetIndex 按商户提现单号查询索引（不存在返回 nil）
+func (r *WithdrawDao) GetIndex(mId uint64, withdrawNo string) (*ordermodel.WithdrawOrderIndexM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("get withdraw index failed: %w", err)
+	}
+	var m ordermodel.WithdrawOrderIndexM
+	err := r.DB.Where("m_id = ? AND withdraw_no = ?", mId, withdrawNo).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetByOrderId 按平台提现单号查询（不存在返回 nil）
+func (r *WithdrawDao) GetByOrderId(table string, orderId uint64) (*ordermodel.WithdrawOrderM, error) {
+	if err := r.checkDB(); err != nil {
+		return nil, fmt.Errorf("get withdraw order failed: %w", err)
+	}
+	var m ordermodel.WithdrawOrderM
+	err := r.DB.Table(table).Where("order_id = ?", orderId).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// UpdateByWhere 条件更新，返回影响行数（用于状态 CAS）
+func (r *WithdrawDao) UpdateByWhere(table string, where map[string]interface{}, data map[string]interface{}) (int64, error) {
+	if err := r.checkDB(); err != nil {
+		return 0, fmt.Errorf("update withdraw order failed: %w", err)
+	}
+	res := r.DB.Table(table).Where(where).Updates(data)
+	return res.RowsAffected, res.Error
+}
+
+// InsertTx 写入提现对应的上游交易（代付上游交易表）
+func (r *WithdrawDao) InsertTx(table string, o *ordermodel.PayoutUpstreamTxM) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("insert withdraw transaction failed: %w", err)
+	}
+	return r.DB.Table(table).Create(o).Error
+}
+
+// Transaction 在同一事务内执行 fn，fn 返回错误时回滚
+func (r *WithdrawDao) Transaction(fn func(repo WithdrawRepository) error) error {
+	if err := r.checkDB(); err != nil {
+		return fmt.Errorf("transaction failed: %w", err)
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(NewWithdrawDaoWithDB(tx))
+	})
+}
@@ -19,11 +19,61 @@ type Account struct {
 
 // AccountResp 账户返回数据
 type AccountResp struct {
-	Code         string `json:"code"`
-	Msg          string `json:"msg"`
-	AccName      string `json:"acc_name"`      //  商户名称
-	MerchantNo   string `json:"merchant_no"`   // 商户号
-	Currency     string `json:"currency"`      // 货币符号
-	FrozenAmount string `json:"frozen_amount"` //冻结金额
-	Amount       string `json:"amount"`        // 可用余额
+	Code         string    `json:"code"`
+	Msg          string    `json:"msg"`
+	AccName      string    `json:"acc_name"`      //  商户名称
+	MerchantNo   string    `json:"merchant_no"`   // 商户号
+	Currency     string    `json:"currency"`      // 货币符号
+	FrozenAmount string    `json:"frozen_amount"` //冻结金额
+	Amount       string    `json:"amount"`        // 可用余额
+	Wallets      []Account `json:"wallets"`       // 全部币种钱包
+}
+
+// AccountStatementReq 商户资金流水查询参数
+type AccountStatementReq struct {
+	Version      string `json:"version" binding:"required"`       //接口版本
+	MerchantNo   string `json:"merchant_no" binding:"required"`   //商户号
+	Currency     string `json:"currency" binding:"required"`      //货币符号
+	Types        string `json:"types"`                            //流水类型，逗号分隔：deposit,payout,freeze,unfreeze,commission,fx
+	StartTime    string `json:"start_time"`                       //开始时间 yyyy-MM-dd HH:mm:ss（含）
+	EndTime      string `json:"end_time"`                         //结束时间 yyyy-MM-dd HH:mm:ss（不含）
+	OrderNo      string `json:"order_no"`                         //平台订单号或商户订单号
+	Page         string `json:"page"`                             //页码，默认1
+	PageSize     string `json:"page_size"`                        //每页条数，默认20，最大100
+	Format       string `json:"format"`                           //json（默认）| csv
+	Period       string `json:"period"`                           //csv 对账周期 day | month
+	Date         string `json:"date"`                             //csv 对账日期 yyyy-MM-dd | yyyy-MM
+	TranDatetime string `json:"tran_datetime" binding:"required"` //13位时间戳
+	Sign         string `json:"sign" binding:"required"`          //MD5 签名 32大写
+}
+
+// AccountStatementItem 资金流水明细
+type AccountStatementItem struct {
+	Id          uint64 `json:"id"`
+	Type        int8   `json:"type"`        // 流水类型编码
+	TypeName    string `json:"type_name"`   // 流水类型
+	Money       string `json:"money"`       // 变动金额
+	OldBalance  string `json:"old_balance"` // 变动前余额
+	Balance     string `json:"balance"`     // 变动后余额
+	OrderNo     string `json:"order_no"`    // 平台订单号
+	MOrderNo    string `json:"m_order_no"`  // 商户订单号
+	Description string `json:"description"` // 备注
+	CreateTime  string `json:"create_time"` // 时间
+}
+
+// AccountStatementResp 资金流水返回数据
+type AccountStatementResp struct {
+	Code           string                 `json:"code"`
+	Msg            string                 `json:"msg"`
+	MerchantNo     string                 `json:"merchant_no"`
+	Currency       string                 `json:"currency"`
+	StartTime      string                 `json:"start_time"`
+	EndTime        string                 `json:"end_time"`
+	OpeningBalance string                 `json:"opening_balance"` // 期初余额
+	ClosingBalance string                 `json:"closing_balance"` // 期末余额
+	Total          int64                  `json:"total"`
+	Page           int                    `json:"page"`
+	PageSize       int                    `json:"page_size"`
+	List           []AccountStatementItem `json:"list"`
+	TraceID        string                 `json:"trace_id,omitempty"`
 }
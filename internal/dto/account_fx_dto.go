@@ -0,0 +1,25 @@
+package dto
+
+// AccountFxConvertReq 商户钱包换汇（内部接口）
+type AccountFxConvertReq struct {
+	ConversionNo string `json:"conversion_no" binding:"required"` // 换汇单号（幂等键）
+	MerchantNo   string `json:"merchant_no" binding:"required"`   // 商户号
+	FromCurrency string `json:"from_currency" binding:"required"` // 源币种
+	ToCurrency   string `json:"to_currency" binding:"required"`   // 目标币种
+	Amount       string `json:"amount" binding:"required"`        // 转出金额（源币种）
+	Rate         string `json:"rate" binding:"required"`          // 汇率：1 源币种 = rate 目标币种
+	Operator     string `json:"operator" binding:"required"`      // 操作人
+	Remark       string `json:"remark"`                           // 备注
+}
+
+// AccountFxConvertResp 换汇结果
+type AccountFxConvertResp struct {
+	ConversionNo string `json:"conversion_no"`
+	MerchantNo   string `json:"merchant_no"`
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	FromAmount   string `json:"from_amount"`
+	ToAmount     string `json:"to_amount"`
+	Rate         string `json:"rate"`
+	CreateTime   string `json:"create_time"`
+}
@@ -18,3 +18,21 @@ type AgentMoney struct {
 	Remark     string          `json:"remark"`      // 备注
 	CreateTime time.Time       `json:"createTime"`  // 创建时间戳
 }
+
+// AgentQuote 某一级代理适用的费率（自下而上，Level=1 为商户直属代理）
+type AgentQuote struct {
+	AgentID uint64
+	Level   int
+	Quote   FeeQuote
+}
+
+// AgentLeg 多级代理分润明细：每级获得本级费用与下级费用之间的差额
+type AgentLeg struct {
+	AgentID    uint64          `json:"agentId"`        // 代理ID
+	Level      int             `json:"level"`          // 层级，1 为商户直属代理
+	Rate       decimal.Decimal `json:"rate"`           // 本级费率（%）
+	Fixed      decimal.Decimal `json:"fixed"`          // 本级单笔费用
+	Fee        decimal.Decimal `json:"fee"`            // 本级费用（按本级费率计算的总额）
+	Commission decimal.Decimal `json:"commission"`     // 本级分润 = 本级费用 - 下级已分配
+	Tier       string          `json:"tier,omitempty"` // 命中的费率方案档位
+}
@@ -0,0 +1,44 @@
+package dto
+
+import "github.com/shopspring/decimal"
+
+// 费率方案适用方
+const (
+	FeePartyMerchant int8 = 1 // 商户手续费
+	FeePartyAgent    int8 = 2 // 代理佣金
+	FeePartyUpstream int8 = 3 // 上游成本
+)
+
+// 阶梯计价方式
+const (
+	FeeTierModeAmount int8 = 1 // 按单笔金额分档
+	FeeTierModeVolume int8 = 2 // 按商户当月累计交易量分档
+)
+
+// FeeTier 阶梯档位：计价基数 >= From 时适用该档
+type FeeTier struct {
+	From  decimal.Decimal `json:"from"`  // 档位起点（含）
+	Rate  decimal.Decimal `json:"rate"`  // 费率（%）
+	Fixed decimal.Decimal `json:"fixed"` // 单笔固定费用
+}
+
+// FeeSchedule 费率方案
+type FeeSchedule struct {
+	ID        uint64
+	Party     int8            // 适用方
+	OwnerID   uint64          // 商户ID / 代理ID / 上游通道产品ID，0 表示该系统通道下通用
+	PayMethod string          // 支付方式，空表示通用
+	TierMode  int8            // 阶梯计价方式
+	Tiers     []FeeTier       // 阶梯档位
+	MinFee    decimal.Decimal // 保底手续费，0 不限
+	MaxFee    decimal.Decimal // 封顶手续费，0 不限
+}
+
+// FeeQuote 某一方最终适用的费率
+type FeeQuote struct {
+	Rate   decimal.Decimal // 费率（%）
+	Fixed  decimal.Decimal // 单笔固定费用
+	MinFee decimal.Decimal // 保底手续费，0 不限
+	MaxFee decimal.Decimal // 封顶手续费，0 不限
+	Tier   string          // 命中的方案档位，平铺费率为空
+}
@@ -14,4 +14,11 @@ const (
 	MoneyLogTypeUnfreeze    = 61 // 解冻资金（失败退回）
 	MoneyLogTypeUnfreezeDel = 62 // 删除冻结（失败扣掉冻结资金）
 
+	// 改派冻结差额调整
+	MoneyLogTypeFreezeAdditional = 7  // 改派补充冻结（新通道费用更高）
+	MoneyLogTypeUnfreezeExcess   = 63 // 改派释放多余冻结（新通道费用更低）
+
+	// 商户钱包换汇
+	MoneyLogTypeFxOut = 81 // 换汇转出（源币种钱包扣减）
+	MoneyLogTypeFxIn  = 82 // 换汇转入（目标币种钱包增加）
 )
@@ -6,6 +6,7 @@ import (
 )
 
 type OrderMessageMQ struct {
+	EventID         string // 发件箱事件ID，同一事件重复投递时不变，消费端按此去重
 	OrderID         string
 	MerchantID      uint64
 	CountryID       int64
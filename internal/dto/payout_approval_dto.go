@@ -0,0 +1,34 @@
+package dto
+
+// PayoutApprovalDecideReq 代付复核操作（内部接口）
+type PayoutApprovalDecideReq struct {
+	OrderId  string `json:"order_id" binding:"required"`                    // 平台订单号
+	Action   string `json:"action" binding:"required,oneof=approve reject"` // approve 通过 / reject 驳回
+	Operator string `json:"operator" binding:"required"`                    // 复核人
+	Remark   string `json:"remark"`                                         // 复核备注
+}
+
+// PayoutApprovalListReq 待复核列表查询（内部接口）
+type PayoutApprovalListReq struct {
+	MerchantId uint64 `json:"merchant_id"` // 商户ID，0 表示全部
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+}
+
+// PayoutApprovalVo 待复核记录
+type PayoutApprovalVo struct {
+	OrderId   string `json:"order_id"`
+	MId       uint64 `json:"m_id"`
+	TranFlow  string `json:"tran_flow"`
+	Currency  string `json:"currency"`
+	Amount    string `json:"amount"`
+	Reason    string `json:"reason"`
+	ExpireAt  string `json:"expire_at"`
+	CreatedAt string `json:"created_at"`
+}
+
+// PayoutApprovalListResp 待复核列表
+type PayoutApprovalListResp struct {
+	Total int64              `json:"total"`
+	List  []PayoutApprovalVo `json:"list"`
+}
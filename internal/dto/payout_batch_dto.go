@@ -0,0 +1,103 @@
+package dto
+
+// CreatePayoutBatchReq 批量代付提交参数（multipart/form-data，file 为批量文件）
+type CreatePayoutBatchReq struct {
+	Version        string `form:"version" binding:"required"`           //接口版本
+	MerchantNo     string `form:"merchant_no" binding:"required"`       //商户号
+	BatchNo        string `form:"batch_no" binding:"required"`          //商户批次号
+	TranDatetime   string `form:"tran_datetime" binding:"required"`     //13位时间戳
+	PayType        string `form:"pay_type" binding:"required"`          //通道编码
+	FileType       string `form:"file_type" binding:"required"`         //文件类型 csv|json
+	FileHash       string `form:"file_hash" binding:"required"`         //文件内容MD5(32位小写)，参与签名
+	NotifyUrl      string `form:"notify_url" binding:"omitempty,url"`   //批次完成回调地址
+	OrderNotifyUrl string `form:"order_notify_url" binding:"omitempty"` //单笔订单回调地址
+	Sign           string `form:"sign" binding:"required"`              //MD5 签名 32大写
+	ClientId       string `form:"-"`                                    //客户端IP
+}
+
+// PayoutBatchRow 批量文件中的单行代付（CSV 表头与 JSON 字段同名）
+type PayoutBatchRow struct {
+	LineNo       int    `json:"-"`             //行号（从1开始）
+	TranFlow     string `json:"tran_flow"`     //订单号
+	Amount       string `json:"amount"`        //订单金额
+	AccNo        string `json:"acc_no"`        //账号
+	AccName      string `json:"acc_name"`      //姓名
+	PayMethod    string `json:"pay_method"`    //支付方式
+	BankCode     string `json:"bank_code"`     //银行编码
+	BankName     string `json:"bank_name"`     //银行名
+	BranchBank   string `json:"branch_bank"`   //支行银行名
+	PayEmail     string `json:"pay_email"`     //邮箱
+	PayPhone     string `json:"pay_phone"`     //手机号
+	IdentityType string `json:"identity_type"` //证件类型
+	IdentityNum  string `json:"identity_num"`  //证件号码
+	AccountType  string `json:"account_type"`  //账户类型
+	CciNo        string `json:"cci_no"`        //银行间账户
+	Address      string `json:"address"`       //客户地址
+	Network      string `json:"network"`       //区块链网络
+}
+
+// PayoutBatchRowError 批量文件行校验错误
+type PayoutBatchRowError struct {
+	LineNo   int    `json:"line_no"`
+	TranFlow string `json:"tran_flow"`
+	Error    string `json:"error"`
+}
+
+// CreatePayoutBatchResp 批量代付提交返回数据
+type CreatePayoutBatchResp struct {
+	Code         string `json:"code"`          //响应码
+	Msg          string `json:"msg"`           //响应说明
+	BatchNo      string `json:"batch_no"`      //商户批次号
+	BatchSerial  string `json:"batch_serial"`  //平台批次号
+	TotalCount   int    `json:"total_count"`   //总笔数
+	TotalAmount  string `json:"total_amount"`  //总金额
+	FreezeAmount string `json:"freeze_amount"` //冻结金额（含手续费预估）
+	SysTime      string `json:"sys_time"`      //系统当前时间
+	TraceID      string `json:"trace_id"`
+}
+
+// QueryPayoutBatchReq 批量代付查询参数
+type QueryPayoutBatchReq struct {
+	Version      string `json:"version" binding:"required"`       //接口版本
+	MerchantNo   string `json:"merchant_no" binding:"required"`   //商户号
+	BatchNo      string `json:"batch_no" binding:"required"`      //商户批次号
+	TranDatetime string `json:"tran_datetime" binding:"required"` //13位时间戳
+	Sign         string `json:"sign" binding:"required"`          //MD5 签名 32大写
+}
+
+// PayoutBatchItemResp 批量代付单行结果
+type PayoutBatchItemResp struct {
+	LineNo      int    `json:"line_no"`       //行号
+	TranFlow    string `json:"tran_flow"`     //商户订单号
+	PaySerialNo string `json:"pay_serial_no"` //平台流水号
+	Amount      string `json:"amount"`        //订单金额
+	Status      string `json:"status"`        //订单状态 0000成功 0001处理中 0005失败
+	Msg         string `json:"msg"`           //说明
+}
+
+// QueryPayoutBatchResp 批量代付查询返回数据
+type QueryPayoutBatchResp struct {
+	Code           string                `json:"code"`
+	Msg            string                `json:"msg"`
+	BatchNo        string                `json:"batch_no"`        //商户批次号
+	BatchSerial    string                `json:"batch_serial"`    //平台批次号
+	Status         string                `json:"status"`          //批次状态 PROCESSING|COMPLETED
+	TotalCount     int                   `json:"total_count"`     //总笔数
+	SubmittedCount int                   `json:"submitted_count"` //已提交上游笔数
+	FailCount      int                   `json:"fail_count"`      //失败笔数
+	TotalAmount    string                `json:"total_amount"`    //总金额
+	Items          []PayoutBatchItemResp `json:"items"`           //单行结果
+}
+
+// PayoutBatchNotifyPayload 批次完成回调商户数据
+type PayoutBatchNotifyPayload struct {
+	MerchantNo     string `json:"merchant_no"`
+	BatchNo        string `json:"batch_no"`
+	BatchSerial    string `json:"batch_serial"`
+	Status         string `json:"status"`
+	TotalCount     int    `json:"total_count"`
+	SubmittedCount int    `json:"submitted_count"`
+	FailCount      int    `json:"fail_count"`
+	TotalAmount    string `json:"total_amount"`
+	Sign           string `json:"sign"`
+}
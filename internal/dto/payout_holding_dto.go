@@ -0,0 +1,7 @@
+package dto
+
+// PayoutHoldingDrainReq 上游补款通知（内部接口），为空表示出队全部队列
+type PayoutHoldingDrainReq struct {
+	PayType  string `json:"pay_type"` // 系统通道编码
+	Currency string `json:"currency"` // 币种
+}
@@ -28,6 +28,7 @@ type CreatePayoutOrderReq struct {
 	CciNo        string `json:"cci_no"`                             //银行间账户
 	Address      string `json:"address"`                            //客户地址
 	Network      string `json:"network"`                            //区块链网络
+	RiskReview   string `json:"-"`                                  //风控复核原因（中间件风控评估结果，非空则进入复核）
 }
 
 // CreatePayoutOrderResp 创建代付订单返回数据
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// RiskHit 单条风控规则命中明细
+type RiskHit struct {
+	RuleID   uint64 `json:"ruleId"`
+	RuleName string `json:"ruleName"`
+	RuleType string `json:"ruleType"`
+	Action   string `json:"action"` // allow/review/deny
+	Score    int    `json:"score"`
+	Reason   string `json:"reason"`
+}
+
+// RiskNotifyMessage 风控事件（发布到 risk_notify）
+type RiskNotifyMessage struct {
+	OrderType  int8      `json:"orderType"` // 1代收 2代付
+	MerchantID uint64    `json:"merchantId"`
+	MerchantNo string    `json:"merchantNo"`
+	TranFlow   string    `json:"tranFlow"`
+	PayType    string    `json:"payType"`
+	Currency   string    `json:"currency"`
+	Amount     string    `json:"amount"`
+	ClientIP   string    `json:"clientIp"`
+	AccNo      string    `json:"accNo"`
+	Action     string    `json:"action"` // review/deny
+	Score      int       `json:"score"`
+	Hits       []RiskHit `json:"hits"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -22,4 +22,11 @@ type SettlementResult struct {
 	AgentIncome    decimal.Decimal `json:"agentIncome"`    // 代理收入
 	PlatformProfit decimal.Decimal `json:"platformProfit"` // 平台净利润
 
+	// 费率方案命中档位（平铺费率时为空）
+	MerchantFeeTier string `json:"merchantFeeTier,omitempty"`
+	AgentFeeTier    string `json:"agentFeeTier,omitempty"`
+	UpFeeTier       string `json:"upFeeTier,omitempty"`
+
+	// 多级代理分润明细（AgentIncome 为各级分润合计）
+	AgentLegs []AgentLeg `json:"agentLegs,omitempty"`
 }
@@ -0,0 +1,93 @@
+package dto
+
+// WithdrawDestinationSaveReq 新增/修改提现账户
+type WithdrawDestinationSaveReq struct {
+	Version       string `json:"version" binding:"required"`       //接口版本
+	MerchantNo    string `json:"merchant_no" binding:"required"`   //商户号
+	DestinationId string `json:"destination_id"`                   //提现账户ID，为空时新增
+	Currency      string `json:"currency" binding:"required"`      //币种
+	Label         string `json:"label"`                            //账户别名
+	AccNo         string `json:"acc_no" binding:"required"`        //收款账号/虚拟币地址
+	AccName       string `json:"acc_name" binding:"required"`      //收款人
+	BankCode      string `json:"bank_code"`                        //银行编码
+	BankName      string `json:"bank_name"`                        //银行名
+	PayMethod     string `json:"pay_method"`                       //支付方式（虚拟币为链）
+	AccountType   string `json:"account_type"`                     //账户类型
+	CciNo         string `json:"cci_no"`                           //银行间账户
+	IdentityNum   string `json:"identity_num"`                     //证件号码
+	Status        string `json:"status"`                           //1启用 0停用，默认1
+	TranDatetime  string `json:"tran_datetime" binding:"required"` //13位时间戳
+	Sign          string `json:"sign" binding:"required"`          //MD5 签名 32大写
+}
+
+// WithdrawDestinationListReq 提现账户列表
+type WithdrawDestinationListReq struct {
+	Version      string `json:"version" binding:"required"`       //接口版本
+	MerchantNo   string `json:"merchant_no" binding:"required"`   //商户号
+	TranDatetime string `json:"tran_datetime" binding:"required"` //13位时间戳
+	Sign         string `json:"sign" binding:"required"`          //MD5 签名 32大写
+}
+
+// WithdrawDestinationVo 提现账户
+type WithdrawDestinationVo struct {
+	DestinationId string `json:"destination_id"`
+	Currency      string `json:"currency"`
+	Label         string `json:"label"`
+	AccNo         string `json:"acc_no"`
+	AccName       string `json:"acc_name"`
+	BankCode      string `json:"bank_code"`
+	BankName      string `json:"bank_name"`
+	PayMethod     string `json:"pay_method"`
+	Status        string `json:"status"`
+	ActiveAt      string `json:"active_at"` // 冷却期结束时间，之后方可提现
+}
+
+// CreateWithdrawReq 商户提现下单
+type CreateWithdrawReq struct {
+	Version       string `json:"version" binding:"required"`         //接口版本
+	MerchantNo    string `json:"merchant_no" binding:"required"`     //商户号
+	WithdrawNo    string `json:"withdraw_no" binding:"required"`     //商户提现单号
+	DestinationId string `json:"destination_id" binding:"required"`  //提现账户ID
+	PayType       string `json:"pay_type" binding:"required"`        //代付通道编码
+	Amount        string `json:"amount" binding:"required"`          //到账金额（手续费另行从余额扣除）
+	NotifyUrl     string `json:"notify_url" binding:"omitempty,url"` //回调地址
+	TranDatetime  string `json:"tran_datetime" binding:"required"`   //13位时间戳
+	Sign          string `json:"sign" binding:"required"`            //MD5 签名 32大写
+	ClientId      string `json:"client_id"`                          //客户端IP
+}
+
+// CreateWithdrawResp 提现下单返回数据
+type CreateWithdrawResp struct {
+	Code             string `json:"code"`               //响应码
+	Msg              string `json:"msg"`                //响应说明
+	Status           string `json:"status"`             //提现状态
+	WithdrawNo       string `json:"withdraw_no"`        //商户提现单号
+	WithdrawSerialNo string `json:"withdraw_serial_no"` //平台提现单号
+	Currency         string `json:"currency"`           //币种
+	Amount           string `json:"amount"`             //到账金额
+	Fee              string `json:"fee"`                //手续费
+	SysTime          string `json:"sys_time"`           //系统当前时间
+	TraceID          string `json:"trace_id,omitempty"`
+}
+
+// QueryWithdrawReq 提现查询
+type QueryWithdrawReq struct {
+	Version      string `json:"version" binding:"required"`       //接口版本
+	MerchantNo   string `json:"merchant_no" binding:"required"`   //商户号
+	WithdrawNo   string `json:"withdraw_no" binding:"required"`   //商户提现单号
+	TranDatetime string `json:"tran_datetime" binding:"required"` //13位时间戳
+	Sign         string `json:"sign" binding:"required"`          //MD5 签名 32大写
+}
+
+// WithdrawNotifyMerchantPayload 提现结果回调商户
+type WithdrawNotifyMerchantPayload struct {
+	WithdrawNo       string `json:"withdraw_no"`
+	WithdrawSerialNo string `json:"withdraw_serial_no"`
+	Status           string `json:"status"`
+	Msg              string `json:"msg"`
+	MerchantNo       string `json:"merchant_no"`
+	Currency         string `json:"currency"`
+	Amount           string `json:"amount"`
+	Fee              string `json:"fee"`
+	Sign             string `json:"sign"`
+}
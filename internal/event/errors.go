@@ -0,0 +1,40 @@
+package event
+
+import "errors"
+
+// permanentError 重试无意义的消息处理错误（消息格式错误、IP 不在白名单、金额不符、订单已终态等）
+type permanentError struct {
+	err    error
+	review bool // 需人工复核（状态冲突），转入人工复核队列而非死信队列
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记为永久错误：消费端不再重试，直接转入死信队列
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// ManualReview 标记为需人工复核的永久错误（如同一交易先成功后失败），消费端转入人工复核队列
+func ManualReview(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err, review: true}
+}
+
+// IsPermanent 是否为永久错误（未标记的错误按临时错误重试，如数据库不可用）
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// IsManualReview 是否需转入人工复核队列
+func IsManualReview(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p) && p.review
+}
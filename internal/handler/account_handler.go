@@ -1,11 +1,17 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
+	"strings"
+	"wht-order-api/internal/constant"
 	"wht-order-api/internal/dto"
 	"wht-order-api/internal/service"
+	"wht-order-api/internal/utils"
 )
 
 // AccountHandler 账户处理器
@@ -42,3 +48,82 @@ func (h *AccountHandler) Query(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// Statement 资金流水查询 / 对账单下载（format=csv）
+func (h *AccountHandler) Statement(c *gin.Context) {
+	val, exists := c.Get("account_statement_request")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "account_statement_request not found"})
+		return
+	}
+	req, ok := val.(dto.AccountStatementReq)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid account_statement_request type"})
+		return
+	}
+	log.Printf("资金流水查询收到数据: %+v\n", req)
+
+	if strings.EqualFold(req.Format, "csv") {
+		fileName, err := h.svc.StatementFileName(req)
+		if err != nil {
+			c.JSON(http.StatusOK, utils.CustomError(c, constant.CodeInvalidParams, err.Error()))
+			return
+		}
+		// 先写入缓冲区，导出失败时仍可返回 JSON 错误
+		var buf bytes.Buffer
+		if err := h.svc.WriteStatementCSV(&buf, req); err != nil {
+			log.Printf("资金流水导出失败: %v", err)
+			c.JSON(http.StatusOK, statementError(c, err))
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+		return
+	}
+
+	response, err := h.svc.Statement(req)
+	if err != nil {
+		log.Printf("资金流水查询失败: %v", err)
+		c.JSON(http.StatusOK, statementError(c, err))
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func statementError(c *gin.Context, err error) utils.Response {
+	if errors.Is(err, service.ErrStatementParams) {
+		return utils.CustomError(c, constant.CodeInvalidParams, err.Error())
+	}
+	return utils.CustomError(c, constant.CodeSystemError, err.Error())
+}
+
+// FxConvert 商户钱包换汇（内部接口）
+func (h *AccountHandler) FxConvert(c *gin.Context) {
+	var req dto.AccountFxConvertReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeInvalidParams))
+		return
+	}
+	response, err := h.svc.ConvertCurrency(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrFxCurrencyInvalid):
+			c.JSON(http.StatusOK, utils.Error(c, constant.CodeFxCurrencyInvalid))
+		case errors.Is(err, service.ErrFxRateInvalid):
+			c.JSON(http.StatusOK, utils.Error(c, constant.CodeFxRateInvalid))
+		case errors.Is(err, service.ErrFxBalanceLow):
+			c.JSON(http.StatusOK, utils.Error(c, constant.CodeFxBalanceLow))
+		case errors.Is(err, service.ErrFxConversionNoUsed):
+			c.JSON(http.StatusOK, utils.Error(c, constant.CodeFxConversionNoUsed))
+		default:
+			log.Printf("[FX] 换汇失败 conversion_no=%s err=%v", req.ConversionNo, err)
+			c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeSystemError, err.Error(), ""))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, &dto.GenericResp[dto.AccountFxConvertResp]{
+		Code: "0",
+		Msg:  "ok",
+		Data: response,
+	})
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"time"
 	"wht-order-api/internal/constant"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/i18n"
 	"wht-order-api/internal/mq"
 	"wht-order-api/internal/service"
 	"wht-order-api/internal/utils"
@@ -58,14 +60,31 @@ func (h *PayoutOrderPayoutHandler) PayoutOrderCreate(c *gin.Context) {
 		auditCtx.ErrorMsg = err.Error()
 		auditCtx.ResponseBody = `{"code":400,"msg":"` + err.Error() + `"}`
 		log.Printf("[TraceId]: %+v,响应信息: %+v", auditCtx.TraceID, err.Error())
-		//c.JSON(http.StatusOK, utils.ErrorWithTrace(constant.CodeTransactionFailed, auditCtx.TraceID))
-		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(constant.CodeTransactionFailed, err.Error(), auditCtx.TraceID))
+		//c.JSON(http.StatusOK, utils.ErrorWithTrace(c, constant.CodeTransactionFailed, auditCtx.TraceID))
+		var le *service.LimitError
+		if errors.As(err, &le) {
+			c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, le.Code, le.Message(i18n.FromContext(c)), auditCtx.TraceID))
+			return
+		}
+		// 上游错误按映射后的平台错误码返回，不透传上游原始信息
+		var ue *service.UpstreamError
+		if errors.As(err, &ue) {
+			errResp := utils.ErrorWithTrace(c, ue.Code, auditCtx.TraceID)
+			if response.PaySerialNo != "" {
+				// 订单已创建（上游拒绝后已失败退款），返回平台流水号便于商户对账
+				response.TraceID = auditCtx.TraceID
+				errResp.Data = response
+			}
+			c.JSON(http.StatusOK, errResp)
+			return
+		}
+		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeTransactionFailed, err.Error(), auditCtx.TraceID))
 		return
 	}
 	paySerialNo, parseErr := strconv.ParseUint(response.PaySerialNo, 10, 64)
 	if parseErr != nil {
-		log.Printf("[TraceId]: %+v,响应信息: %+v", auditCtx.TraceID, err.Error())
-		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(constant.CodeSystemError, err.Error(), auditCtx.TraceID))
+		log.Printf("[TraceId]: %+v,响应信息: %+v", auditCtx.TraceID, parseErr.Error())
+		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeSystemError, parseErr.Error(), auditCtx.TraceID))
 		return
 	}
 	auditCtx.PlatformOrderID = paySerialNo
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"time"
 	"wht-order-api/internal/constant"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/i18n"
 	"wht-order-api/internal/mq"
 	"wht-order-api/internal/service"
 	"wht-order-api/internal/utils"
@@ -27,12 +29,12 @@ func NewReceiveOrderHandler() *ReceiveOrderHandler {
 func (h *ReceiveOrderHandler) ReceiveOrderCreate(c *gin.Context) {
 	val, exists := c.Get("pay_request")
 	if !exists {
-		c.JSON(http.StatusOK, utils.Error(constant.CodeMissingParams))
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeMissingParams))
 		return
 	}
 	req, ok := val.(dto.CreateOrderReq)
 	if !ok {
-		c.JSON(http.StatusOK, utils.Error(constant.CodeParamsTypeError))
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeParamsTypeError))
 		return
 	}
 	log.Printf("收到数据: %+v\n", req)
@@ -55,14 +57,25 @@ func (h *ReceiveOrderHandler) ReceiveOrderCreate(c *gin.Context) {
 		auditCtx.ErrorMsg = err.Error()
 		auditCtx.ResponseBody = `{"code":400,"msg":"` + err.Error() + `"}`
 		log.Printf("[TraceId]: %+v,响应信息: %+v", auditCtx.TraceID, err.Error())
-		//c.JSON(http.StatusOK, utils.ErrorWithTrace(constant.CodeTransactionFailed, auditCtx.TraceID))
-		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(constant.CodeTransactionFailed, err.Error(), auditCtx.TraceID))
+		//c.JSON(http.StatusOK, utils.ErrorWithTrace(c, constant.CodeTransactionFailed, auditCtx.TraceID))
+		var le *service.LimitError
+		if errors.As(err, &le) {
+			c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, le.Code, le.Message(i18n.FromContext(c)), auditCtx.TraceID))
+			return
+		}
+		// 上游错误按映射后的平台错误码返回，不透传上游原始信息
+		var ue *service.UpstreamError
+		if errors.As(err, &ue) {
+			c.JSON(http.StatusOK, utils.ErrorWithTrace(c, ue.Code, auditCtx.TraceID))
+			return
+		}
+		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeTransactionFailed, err.Error(), auditCtx.TraceID))
 		return
 	}
 	paySerialNo, parseErr := strconv.ParseUint(response.PaySerialNo, 10, 64)
 	if parseErr != nil {
 		log.Printf("[TraceId]: %+v,响应信息: %+v", auditCtx.TraceID, err.Error())
-		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(constant.CodeSystemError, err.Error(), auditCtx.TraceID))
+		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeSystemError, err.Error(), auditCtx.TraceID))
 		return
 	}
 	auditCtx.PlatformOrderID = paySerialNo
@@ -71,7 +84,7 @@ func (h *ReceiveOrderHandler) ReceiveOrderCreate(c *gin.Context) {
 		auditCtx.ErrorMsg = err.Error()
 		auditCtx.ResponseBody = `{"code":400,"msg":"` + err.Error() + `"}`
 		log.Printf("[TraceId]: %+v,响应信息: %+v", auditCtx.TraceID, err.Error())
-		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(constant.CodeSystemError, err.Error(), auditCtx.TraceID))
+		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeSystemError, err.Error(), auditCtx.TraceID))
 		return
 	}
 	if err != nil {
@@ -91,12 +104,12 @@ func (h *ReceiveOrderHandler) ReceiveOrderCreate(c *gin.Context) {
 func (h *ReceiveOrderHandler) ReceiveOrderQuery(c *gin.Context) {
 	val, exists := c.Get("receive_query_request")
 	if !exists {
-		c.JSON(http.StatusOK, utils.Error(constant.CodeMissingParams))
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeMissingParams))
 		return
 	}
 	req, ok := val.(dto.QueryReceiveOrderReq)
 	if !ok {
-		c.JSON(http.StatusOK, utils.Error(constant.CodeParamsTypeError))
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeParamsTypeError))
 		return
 	}
 
@@ -116,7 +129,7 @@ func (h *ReceiveOrderHandler) ReceiveOrderQuery(c *gin.Context) {
 		auditCtx.Status = "failed"
 		auditCtx.ErrorMsg = err.Error()
 		auditCtx.ResponseBody = `{"code":400,"msg":"` + err.Error() + `"}`
-		c.JSON(http.StatusOK, utils.ErrorWithTrace(constant.CodeSystemError, auditCtx.TraceID))
+		c.JSON(http.StatusOK, utils.ErrorWithTrace(c, constant.CodeSystemError, auditCtx.TraceID))
 		return
 	}
 
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/mq"
+	"wht-order-api/internal/service"
+	"wht-order-api/internal/utils"
+)
+
+// PayoutApprovalHandler 大额代付复核处理器（内部接口）
+type PayoutApprovalHandler struct {
+	svc *service.PayoutApprovalService
+}
+
+func NewPayoutApprovalHandler() *PayoutApprovalHandler {
+	pub := mq.NewPublisher()
+	return &PayoutApprovalHandler{svc: service.NewPayoutApprovalService(pub)}
+}
+
+// List 待复核列表
+func (h *PayoutApprovalHandler) List(c *gin.Context) {
+	var req dto.PayoutApprovalListReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeInvalidParams))
+		return
+	}
+	response, err := h.svc.List(req)
+	if err != nil {
+		log.Printf("[PAYOUT-APPROVAL] 查询待复核列表失败: %v", err)
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeSystemError))
+		return
+	}
+	c.JSON(http.StatusOK, &dto.GenericResp[dto.PayoutApprovalListResp]{
+		Code: "0",
+		Msg:  "ok",
+		Data: response,
+	})
+}
+
+// Decide 复核通过 / 驳回
+func (h *PayoutApprovalHandler) Decide(c *gin.Context) {
+	var req dto.PayoutApprovalDecideReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeApprovalActionInvalid))
+		return
+	}
+	if err := h.svc.Decide(req, c.ClientIP()); err != nil {
+		switch {
+		case errors.Is(err, service.ErrApprovalNotFound):
+			c.JSON(http.StatusOK, utils.Error(c, constant.CodeApprovalNotFound))
+		case errors.Is(err, service.ErrApprovalAlreadyDecided):
+			c.JSON(http.StatusOK, utils.Error(c, constant.CodeApprovalAlreadyDecided))
+		default:
+			log.Printf("[PAYOUT-APPROVAL] 复核操作失败 order=%s action=%s err=%v", req.OrderId, req.Action, err)
+			c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeSystemError, err.Error(), ""))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, &dto.GenericResp[any]{
+		Code: "0",
+		Msg:  "ok",
+	})
+}
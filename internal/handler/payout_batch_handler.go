@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/mq"
+	"wht-order-api/internal/service"
+	"wht-order-api/internal/utils"
+)
+
+// 批量代付处理器
+type PayoutBatchHandler struct{ svc *service.PayoutBatchService }
+
+func NewPayoutBatchHandler() *PayoutBatchHandler {
+	pub := mq.NewPublisher()
+	return &PayoutBatchHandler{svc: service.NewPayoutBatchService(pub)}
+}
+
+// BatchCreate 批量代付提交
+func (h *PayoutBatchHandler) BatchCreate(c *gin.Context) {
+	val, exists := c.Get("payout_batch_request")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "payout_batch_request not found"})
+		return
+	}
+	req, ok := val.(dto.CreatePayoutBatchReq)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid payout_batch_request type"})
+		return
+	}
+	fileVal, _ := c.Get("payout_batch_file")
+	content, _ := fileVal.([]byte)
+
+	requestType, _ := c.Get("request_type")
+	ctxVal, _ := c.Get("audit_ctx")
+	auditCtx := ctxVal.(*dto.AuditContextPayload)
+	// 审计日志只记录表单参数，不落整份文件
+	reqJson, _ := json.Marshal(req)
+	auditCtx.RequestBody = string(reqJson)
+	auditCtx.MerchantNo = req.MerchantNo
+	auditCtx.TranFlow = req.BatchNo
+	auditCtx.ChannelCode = req.PayType
+	auditCtx.CreatedAt = time.Now()
+	auditCtx.RequestType = requestType.(string)
+	auditCtx.IP = utils.GetRealClientIP(c)
+
+	response, err := h.svc.Create(req, content)
+	if err != nil {
+		auditCtx.Status = "failed"
+		auditCtx.ErrorMsg = err.Error()
+		auditCtx.ResponseBody = `{"code":400,"msg":"` + err.Error() + `"}`
+		log.Printf("[TraceId]: %+v,批量代付失败: %+v", auditCtx.TraceID, err.Error())
+
+		var be *service.BatchError
+		if errors.As(err, &be) {
+			if len(be.Rows) > 0 {
+				c.JSON(http.StatusOK, utils.ErrorWithData(c, be.Code, gin.H{"errors": be.Rows}))
+				return
+			}
+			c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, be.Code, be.Msg, auditCtx.TraceID))
+			return
+		}
+		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeTransactionFailed, err.Error(), auditCtx.TraceID))
+		return
+	}
+	if batchSerial, pErr := strconv.ParseUint(response.BatchSerial, 10, 64); pErr == nil {
+		auditCtx.PlatformOrderID = batchSerial
+	}
+
+	response.TraceID = auditCtx.TraceID
+	respJson, _ := json.Marshal(response)
+	auditCtx.Status = "success"
+	auditCtx.ResponseBody = string(respJson)
+	c.JSON(http.StatusOK, response)
+}
+
+// BatchQuery 批量代付查询
+func (h *PayoutBatchHandler) BatchQuery(c *gin.Context) {
+	val, exists := c.Get("payout_batch_query_request")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "payout_batch_query_request not found"})
+		return
+	}
+	req, ok := val.(dto.QueryPayoutBatchReq)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid payout_batch_query_request type"})
+		return
+	}
+
+	requestType, _ := c.Get("request_type")
+	ctxVal, _ := c.Get("audit_ctx")
+	auditCtx := ctxVal.(*dto.AuditContextPayload)
+	auditCtx.MerchantNo = req.MerchantNo
+	auditCtx.TranFlow = req.BatchNo
+	auditCtx.Status = "success"
+	auditCtx.RequestType = requestType.(string)
+	auditCtx.IP = utils.GetRealClientIP(c)
+
+	response, err := h.svc.Get(req)
+	if err != nil {
+		auditCtx.Status = "failed"
+		auditCtx.ErrorMsg = err.Error()
+		auditCtx.ResponseBody = `{"code":400,"msg":"` + err.Error() + `"}`
+		var be *service.BatchError
+		if errors.As(err, &be) {
+			c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, be.Code, be.Msg, auditCtx.TraceID))
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	respJson, _ := json.Marshal(response)
+	auditCtx.ResponseBody = string(respJson)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/lifecycle"
+	"wht-order-api/internal/mq"
+	"wht-order-api/internal/service"
+	"wht-order-api/internal/utils"
+)
+
+// PayoutHoldingHandler 上游余额不足代付排队处理器（内部接口）
+type PayoutHoldingHandler struct {
+	svc *service.PayoutHoldingService
+}
+
+func NewPayoutHoldingHandler() *PayoutHoldingHandler {
+	pub := mq.NewPublisher()
+	return &PayoutHoldingHandler{svc: service.NewPayoutHoldingService(pub)}
+}
+
+// ToppedUp 上游已补款，立即触发排队订单出队
+func (h *PayoutHoldingHandler) ToppedUp(c *gin.Context) {
+	var req dto.PayoutHoldingDrainReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeInvalidParams))
+		return
+	}
+	log.Printf("[PAYOUT-HOLD] 收到上游补款通知 通道=%s 币种=%s IP=%s", req.PayType, req.Currency, c.ClientIP())
+	lifecycle.Go("payout-holding-drain", func() { h.svc.Drain(req.PayType, req.Currency) })
+	c.JSON(http.StatusOK, &dto.GenericResp[any]{
+		Code: "0",
+		Msg:  "ok",
+	})
+}
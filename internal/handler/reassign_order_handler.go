@@ -24,12 +24,12 @@ func NewReassignOrderHandler() *ReassignOrderHandler {
 func (h *ReassignOrderHandler) ReassignOrderCreate(c *gin.Context) {
 	val, exists := c.Get("payout_request")
 	if !exists {
-		c.JSON(http.StatusOK, utils.Error(constant.CodeMissingParams))
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeMissingParams))
 		return
 	}
 	req, ok := val.(dto.CreateReassignOrderReq)
 	if !ok {
-		c.JSON(http.StatusOK, utils.Error(constant.CodeParamsTypeError))
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeParamsTypeError))
 		return
 	}
 	log.Printf("收到数据: %+v\n", req)
@@ -50,8 +50,8 @@ func (h *ReassignOrderHandler) ReassignOrderCreate(c *gin.Context) {
 	paySerialNo, parseErr := strconv.ParseUint(response.PaySerialNo, 10, 64)
 	if parseErr != nil {
 		log.Printf("[TraceId]: %+v,响应信息: %+v", auditCtx.TraceID, err.Error())
-		//c.JSON(http.StatusOK, utils.CustomErrorWithTrace(constant.CodeSystemError, err.Error(), auditCtx.TraceID))
-		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(constant.CodeTransactionFailed, err.Error(), auditCtx.TraceID))
+		//c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeSystemError, err.Error(), auditCtx.TraceID))
+		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeTransactionFailed, err.Error(), auditCtx.TraceID))
 		return
 	}
 	auditCtx.PlatformOrderID = paySerialNo
@@ -60,7 +60,7 @@ func (h *ReassignOrderHandler) ReassignOrderCreate(c *gin.Context) {
 		auditCtx.ErrorMsg = err.Error()
 		auditCtx.ResponseBody = `{"code":400,"msg":"` + err.Error() + `"}`
 		log.Printf("[TraceId]: %+v,响应信息: %+v", auditCtx.TraceID, err.Error())
-		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(constant.CodeSystemError, err.Error(), auditCtx.TraceID))
+		c.JSON(http.StatusOK, utils.CustomErrorWithTrace(c, constant.CodeSystemError, err.Error(), auditCtx.TraceID))
 		return
 	}
 	if err != nil {
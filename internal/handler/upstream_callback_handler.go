@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"wht-order-api/internal/callback"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/mq"
+	"wht-order-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 回调报文大小上限
+const maxCallbackBodySize = 1 << 20
+
+// UpstreamCallbackHandler 上游直连回调（替代 PHP 网关解析后投递 MQ）
+type UpstreamCallbackHandler struct {
+	svc *callback.CallbackIngest
+}
+
+func NewUpstreamCallbackHandler() *UpstreamCallbackHandler {
+	return &UpstreamCallbackHandler{svc: callback.NewCallbackIngest(mq.NewConfirmPublisher())}
+}
+
+// Notify /api/v1/callback/:interface_code/:mode，成功时按连接器要求的响应体回复上游
+func (h *UpstreamCallbackHandler) Notify(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCallbackBodySize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.Error(c, constant.CodeInternalError))
+		return
+	}
+
+	cb := &connector.Callback{
+		Mode:     c.Param("mode"),
+		Header:   c.Request.Header.Clone(),
+		Body:     body,
+		RemoteIP: c.ClientIP(), // 仅信任已配置的代理转发头，避免伪造来源 IP
+	}
+	ack, err := h.svc.Ingest(c.Param("interface_code"), cb)
+	if err != nil {
+		// 临时故障返回 5xx，上游按其重试策略重发；验签、报文等永久错误返回 200 避免无效重发
+		var ie *callback.IngestError
+		if errors.As(err, &ie) {
+			status := http.StatusOK
+			if ie.Transient {
+				status = http.StatusServiceUnavailable
+			}
+			c.JSON(status, utils.Error(c, ie.Code))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.Error(c, constant.CodeSystemError))
+		return
+	}
+	c.String(http.StatusOK, ack)
+}
@@ -23,13 +23,13 @@ func (h *UpstreamHandler) ConfigQuery(c *gin.Context) {
 
 	var req dto.QueryUpstreamSupplierReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusOK, utils.Error(constant.CodeInvalidParams))
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeInvalidParams))
 		return
 	}
 	// 调用服务层处理
 	response, err := h.svc.Get(req.TradeOrderId, req.TradeType)
 	if err != nil {
-		c.JSON(http.StatusOK, utils.Error(constant.CodeSystemError))
+		c.JSON(http.StatusOK, utils.Error(c, constant.CodeSystemError))
 		return
 	}
 	genericResp := &dto.GenericResp[*dto.UpstreamSupplierDto]{
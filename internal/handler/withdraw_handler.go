@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/mq"
+	"wht-order-api/internal/service"
+	"wht-order-api/internal/utils"
+)
+
+// WithdrawHandler 商户提现处理器
+type WithdrawHandler struct{ svc *service.WithdrawService }
+
+func NewWithdrawHandler() *WithdrawHandler {
+	pub := mq.NewPublisher()
+	return &WithdrawHandler{svc: service.NewWithdrawService(pub)}
+}
+
+// withdrawErrorResp 业务错误按错误码返回；其他错误（数据库等内部异常）只记录日志，对外返回系统错误文案
+func withdrawErrorResp(c *gin.Context, err error, traceId string) utils.Response {
+	var we *service.WithdrawError
+	if errors.As(err, &we) {
+		return utils.CustomErrorWithTrace(c, we.Code, we.Msg, traceId)
+	}
+	log.Printf("[WITHDRAW] 内部错误 traceId=%s err=%v", traceId, err)
+	return utils.ErrorWithTrace(c, constant.CodeSystemError, traceId)
+}
+
+// Create 商户提现下单
+func (h *WithdrawHandler) Create(c *gin.Context) {
+	val, exists := c.Get("withdraw_request")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "withdraw_request not found"})
+		return
+	}
+	req, ok := val.(dto.CreateWithdrawReq)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid withdraw_request type"})
+		return
+	}
+
+	requestType, _ := c.Get("request_type")
+	ctxVal, _ := c.Get("audit_ctx")
+	auditCtx := ctxVal.(*dto.AuditContextPayload)
+	reqJson, _ := json.Marshal(req)
+	auditCtx.RequestBody = string(reqJson)
+	auditCtx.MerchantNo = req.MerchantNo
+	auditCtx.TranFlow = req.WithdrawNo
+	auditCtx.ChannelCode = req.PayType
+	auditCtx.CreatedAt = time.Now()
+	auditCtx.RequestType = requestType.(string)
+	auditCtx.IP = utils.GetRealClientIP(c)
+
+	response, err := h.svc.Create(req)
+	if err != nil {
+		auditCtx.Status = "failed"
+		auditCtx.ErrorMsg = err.Error()
+		auditCtx.ResponseBody = `{"code":400,"msg":"` + err.Error() + `"}`
+		log.Printf("[TraceId]: %+v,商户提现失败: %+v", auditCtx.TraceID, err.Error())
+		c.JSON(http.StatusOK, withdrawErrorResp(c, err, auditCtx.TraceID))
+		return
+	}
+	if serialNo, pErr := strconv.ParseUint(response.WithdrawSerialNo, 10, 64); pErr == nil {
+		auditCtx.PlatformOrderID = serialNo
+	}
+
+	response.TraceID = auditCtx.TraceID
+	respJson, _ := json.Marshal(response)
+	auditCtx.Status = "success"
+	auditCtx.ResponseBody = string(respJson)
+	c.JSON(http.StatusOK, response)
+}
+
+// Query 商户提现查询
+func (h *WithdrawHandler) Query(c *gin.Context) {
+	val, exists := c.Get("withdraw_query_request")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "withdraw_query_request not found"})
+		return
+	}
+	req, ok := val.(dto.QueryWithdrawReq)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid withdraw_query_request type"})
+		return
+	}
+
+	requestType, _ := c.Get("request_type")
+	ctxVal, _ := c.Get("audit_ctx")
+	auditCtx := ctxVal.(*dto.AuditContextPayload)
+	auditCtx.MerchantNo = req.MerchantNo
+	auditCtx.TranFlow = req.WithdrawNo
+	auditCtx.Status = "success"
+	auditCtx.RequestType = requestType.(string)
+	auditCtx.IP = utils.GetRealClientIP(c)
+
+	response, err := h.svc.Get(req)
+	if err != nil {
+		auditCtx.Status = "failed"
+		auditCtx.ErrorMsg = err.Error()
+		auditCtx.ResponseBody = `{"code":400,"msg":"` + err.Error() + `"}`
+		c.JSON(http.StatusOK, withdrawErrorResp(c, err, auditCtx.TraceID))
+		return
+	}
+	respJson, _ := json.Marshal(response)
+	auditCtx.ResponseBody = string(respJson)
+	c.JSON(http.StatusOK, response)
+}
+
+// SaveDestination 新增/修改提现账户
+func (h *WithdrawHandler) SaveDestination(c *gin.Context) {
+	val, exists := c.Get("withdraw_destination_request")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "withdraw_destination_request not found"})
+		return
+	}
+	req, ok := val.(dto.WithdrawDestinationSaveReq)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid withdraw_destination_request type"})
+		return
+	}
+	response, err := h.svc.SaveDestination(req)
+	if err != nil {
+		log.Printf("[WITHDRAW] 保存提现账户失败 merchant=%s err=%v", req.MerchantNo, err)
+		c.JSON(http.StatusOK, withdrawErrorResp(c, err, ""))
+		return
+	}
+	c.JSON(http.StatusOK, &dto.GenericResp[dto.WithdrawDestinationVo]{
+		Code: "0",
+		Msg:  "ok",
+		Data: response,
+	})
+}
+
+// ListDestinations 提现账户列表
+func (h *WithdrawHandler) ListDestinations(c *gin.Context) {
+	val, exists := c.Get("withdraw_destination_list_request")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "withdraw_destination_list_request not found"})
+		return
+	}
+	req, ok := val.(dto.WithdrawDestinationListReq)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid withdraw_destination_list_request type"})
+		return
+	}
+	response, err := h.svc.ListDestinations(req)
+	if err != nil {
+		c.JSON(http.StatusOK, withdrawErrorResp(c, err, ""))
+		return
+	}
+	c.JSON(http.StatusOK, &dto.GenericResp[[]dto.WithdrawDestinationVo]{
+		Code: "0",
+		Msg:  "ok",
+		Data: response,
+	})
+}
@@ -0,0 +1,170 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的语言
+const (
+	LangZH = "zh" // 简体中文（默认）
+	LangEN = "en" // 英语
+	LangES = "es" // 西班牙语
+	LangPT = "pt" // 葡萄牙语
+
+	DefaultLang = LangZH
+
+	// ContextKey gin 上下文中保存请求语言的 key
+	ContextKey = "lang"
+)
+
+// 非错误码类提示文案的 key
+const (
+	TextSuccess           = "success"
+	TextUnknownError      = "unknown_error"
+	TextValidationFailed  = "validation_failed"
+	TextJSONFormatError   = "json_format_error"
+	TextParamsTypeError   = "params_type_error"
+	TextFieldTypeMismatch = "field_type_mismatch"
+	TextRequestFormat     = "request_format_error"
+	TextFieldRequired     = "validation.required"
+	TextFieldURL          = "validation.url"
+	TextFieldEmail        = "validation.email"
+	TextFieldInvalid      = "validation.invalid"
+	TextInvalidField      = "validation.field"        // 参数：字段名
+	TextIPNotWhitelisted  = "auth.ip_not_whitelisted" // 参数：客户端 IP
+	TextLimitValue        = "limit.value"             // 参数：限额值
+	TextBatchFileRequired = "batch.file_required"
+	TextBatchFileUnread   = "batch.file_unreadable"
+	TextBatchFileHash     = "batch.file_hash_mismatch"
+)
+
+// catalog 单一语言的文案（错误码 + 文案 key）
+type catalog struct {
+	codes map[int]string
+	texts map[string]string
+}
+
+var catalogs = map[string]*catalog{
+	LangZH: &zhCatalog,
+	LangEN: &enCatalog,
+	LangES: &esCatalog,
+	LangPT: &ptCatalog,
+}
+
+// Languages 全部支持的语言
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Normalize 将 zh-CN / pt_BR / es-419 等语言标签归一为支持的语言，不支持返回 false
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if _, ok := catalogs[tag]; ok {
+		return tag, true
+	}
+	return "", false
+}
+
+// ParseAcceptLanguage 按 q 值选择 Accept-Language 中第一个支持的语言，均不支持返回 false
+func ParseAcceptLanguage(header string) (string, bool) {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		lang, ok := Normalize(fields[0])
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v, found := strings.CutPrefix(strings.TrimSpace(f), "q="); found {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang: lang, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang, true
+}
+
+// FromContext 当前请求语言：商户偏好/Accept-Language 已解析则直接使用，否则按 Accept-Language，默认中文
+func FromContext(c *gin.Context) string {
+	if c == nil {
+		return DefaultLang
+	}
+	if lang := c.GetString(ContextKey); lang != "" {
+		return lang
+	}
+	if c.Request != nil {
+		if lang, ok := ParseAcceptLanguage(c.GetHeader("Accept-Language")); ok {
+			return lang
+		}
+	}
+	return DefaultLang
+}
+
+// UseMerchantLanguage 商户偏好语言（w_merchant.language）；请求显式携带受支持的 Accept-Language 时以请求为准
+func UseMerchantLanguage(c *gin.Context, preference string) {
+	if c == nil {
+		return
+	}
+	if c.Request != nil {
+		if lang, ok := ParseAcceptLanguage(c.GetHeader("Accept-Language")); ok {
+			c.Set(ContextKey, lang)
+			return
+		}
+	}
+	if lang, ok := Normalize(preference); ok {
+		c.Set(ContextKey, lang)
+	}
+}
+
+// Message 错误码文案，当前语言缺失时依次回退英文、中文；均未定义返回 false
+func Message(lang string, code int) (string, bool) {
+	for _, l := range []string{lang, LangEN, LangZH} {
+		if cat, ok := catalogs[l]; ok {
+			if msg, ok := cat.codes[code]; ok {
+				return msg, true
+			}
+		}
+	}
+	return Text(lang, TextUnknownError), false
+}
+
+// Text 非错误码类文案（支持 fmt 参数），缺失时依次回退英文、中文，均未定义返回 key
+func Text(lang, key string, args ...interface{}) string {
+	for _, l := range []string{lang, LangEN, LangZH} {
+		if cat, ok := catalogs[l]; ok {
+			if msg, ok := cat.texts[key]; ok {
+				if len(args) > 0 {
+					return fmt.Sprintf(msg, args...)
+				}
+				return msg
+			}
+		}
+	}
+	return key
+}
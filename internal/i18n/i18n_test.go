@@ -0,0 +1,115 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// constantCodes 解析 internal/constant 下全部 Code* 错误码常量
+func constantCodes(t *testing.T) map[string]int {
+	t.Helper()
+	files, err := filepath.Glob("../constant/*.go")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("glob constant files: %v", err)
+	}
+
+	codes := map[string]int{}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", file, err)
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if !strings.HasPrefix(name.Name, "Code") || i >= len(vs.Values) {
+						continue
+					}
+					lit, ok := vs.Values[i].(*ast.BasicLit)
+					if !ok || lit.Kind != token.INT {
+						continue
+					}
+					v, _ := strconv.Atoi(lit.Value)
+					codes[name.Name] = v
+				}
+			}
+		}
+	}
+	return codes
+}
+
+func TestEveryCodeTranslated(t *testing.T) {
+	codes := constantCodes(t)
+	if len(codes) < 100 {
+		t.Fatalf("only %d codes parsed from constant package", len(codes))
+	}
+	for _, lang := range Languages() {
+		cat := catalogs[lang]
+		for name, code := range codes {
+			if msg := cat.codes[code]; strings.TrimSpace(msg) == "" {
+				t.Errorf("[%s] missing translation for %s (%d)", lang, name, code)
+			}
+		}
+	}
+}
+
+func TestEveryTextTranslated(t *testing.T) {
+	keys := map[string]bool{}
+	for _, cat := range catalogs {
+		for key := range cat.texts {
+			keys[key] = true
+		}
+	}
+	for _, lang := range Languages() {
+		for key := range keys {
+			if msg := catalogs[lang].texts[key]; strings.TrimSpace(msg) == "" {
+				t.Errorf("[%s] missing text %q", lang, key)
+			}
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		wantOK bool
+	}{
+		{"pt-BR,pt;q=0.9,en;q=0.8", LangPT, true},
+		{"es-419", LangES, true},
+		{"fr-FR,en;q=0.5", LangEN, true},
+		{"en;q=0.3, zh-CN", LangZH, true},
+		{"es;q=0, en;q=0.1", LangEN, true},
+		{"fr-FR,de", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseAcceptLanguage(tt.header)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseAcceptLanguage(%q) = %q, %v; want %q, %v", tt.header, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestMessageFallback(t *testing.T) {
+	if msg, ok := Message("fr", 0); !ok || msg != enCatalog.codes[0] {
+		t.Errorf("unsupported language should fall back to English, got %q", msg)
+	}
+	if msg, ok := Message(LangES, -1); ok || msg != esCatalog.texts[TextUnknownError] {
+		t.Errorf("unknown code = %q, %v", msg, ok)
+	}
+	if got := Text(LangPT, TextRequestFormat, "x"); got != "Formato de requisição inválido: x" {
+		t.Errorf("Text with args = %q", got)
+	}
+}
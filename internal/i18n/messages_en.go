@@ -0,0 +1,210 @@
+package i18n
+
+import "wht-order-api/internal/constant"
+
+// enCatalog 英语
+var enCatalog = catalog{
+	codes: map[int]string{
+		// 系统错误
+		constant.CodeSuccess:            "Success",
+		constant.CodeSystemError:        "System error",
+		constant.CodeDatabaseError:      "Database error",
+		constant.CodeRedisError:         "Cache service error",
+		constant.CodeInternalError:      "Failed to read request body",
+		constant.CodeServiceUnavailable: "Service temporarily unavailable",
+		constant.CodeTimeout:            "Request expired, resubmit request",
+		constant.CodeRateLimit:          "Rate limit exceeded, please retry later",
+		constant.CodeCircuitBreak:       "Service busy, please retry later",
+
+		// 参数错误
+		constant.CodeInvalidParams:     "The parameter format is incorrect; the request parameters do not conform to the expected format or specification.",
+		constant.CodeMissingParams:     "Missing required parameters; the request is missing a required parameter field.",
+		constant.CodeParamsFormatError: "Parameter format error, parameter value format incorrect",
+		constant.CodeParamsTypeError:   "Parameter type error; parameter value type does not match the expected type.",
+		constant.CodeParamsRangeError:  "Parameter out of allowed range",
+		constant.CodeDuplicateRequest:  "Duplicate request, do not resubmit",
+
+		// 认证授权错误
+		constant.CodeUnauthorized:     "Unable to recognize IP address",
+		constant.CodeTokenExpired:     "Token expired",
+		constant.CodeTokenInvalid:     "Token invalid",
+		constant.CodeSignatureError:   "Signature verification failed",
+		constant.CodeAccessDenied:     "Access denied",
+		constant.CodeIPNotWhitelisted: "Ip not in the whitelist error",
+		constant.CodeMerchantDisabled: "Merchant not activated",
+		constant.CodeMerchantAbnormal: "Merchant Abnormal",
+
+		constant.CodeTransactionFailed: "Transaction failed",
+
+		// 商户相关错误
+		constant.CodeMerchantNotFound:     "Merchant not found",
+		constant.CodeMerchantBalanceLow:   "Merchant balance insufficient",
+		constant.CodeMerchantRateInvalid:  "Merchant rate invalid",
+		constant.CodeMerchantLimitReached: "Merchant limit reached",
+		constant.CodeMerchantKeyInvalid:   "Merchant key invalid",
+
+		// 订单相关错误
+		constant.CodeOrderNotFound:      "Order not found",
+		constant.CodeOrderAlreadyExist:  "Order already exists",
+		constant.CodeOrderStatusInvalid: "Order status invalid",
+		constant.CodeOrderAmountInvalid: "Order amount invalid",
+		constant.CodeOrderExpired:       "Order expired",
+		constant.CodeOrderPaid:          "Order already paid",
+		constant.CodeOrderRefunded:      "Order already refunded",
+		constant.CodeOrderClosed:        "Order closed",
+
+		// 批量代付相关错误
+		constant.CodeBatchFileInvalid:  "Batch file invalid",
+		constant.CodeBatchRowInvalid:   "Batch contains invalid rows",
+		constant.CodeBatchAlreadyExist: "Batch already exists",
+		constant.CodeBatchTooLarge:     "Batch too large",
+		constant.CodeBatchNotFound:     "Batch not found",
+
+		// 代付复核相关错误
+		constant.CodeApprovalNotFound:       "Approval not found",
+		constant.CodeApprovalAlreadyDecided: "Approval already decided",
+		constant.CodeApprovalActionInvalid:  "Approval action invalid",
+
+		// 商户钱包换汇相关错误
+		constant.CodeFxCurrencyInvalid:  "FX currency invalid",
+		constant.CodeFxRateInvalid:      "FX rate or amount invalid",
+		constant.CodeFxBalanceLow:       "Source wallet balance insufficient",
+		constant.CodeFxConversionNoUsed: "Conversion number already used by another merchant",
+
+		// 商户提现相关错误
+		constant.CodeWithdrawDisabled:            "Withdrawal is not enabled",
+		constant.CodeWithdrawDestinationNotFound: "Withdrawal destination not found or disabled",
+		constant.CodeWithdrawDestinationCooling:  "Withdrawal destination is in cool-down period",
+		constant.CodeWithdrawAmountInvalid:       "Withdrawal amount out of range",
+		constant.CodeWithdrawFeeRuleMissing:      "Withdrawal fee rule not configured",
+		constant.CodeWithdrawAlreadyExist:        "Withdrawal number already exists",
+		constant.CodeWithdrawNotFound:            "Withdrawal not found",
+		constant.CodeWithdrawCurrencyMismatch:    "Destination currency does not match channel currency",
+
+		// 累计限额相关错误
+		constant.CodeLimitMerchantDailyAmount:   "Merchant daily amount limit exceeded",
+		constant.CodeLimitMerchantDailyCount:    "Merchant daily count limit exceeded",
+		constant.CodeLimitMerchantMonthlyAmount: "Merchant monthly amount limit exceeded",
+		constant.CodeLimitMerchantMonthlyCount:  "Merchant monthly count limit exceeded",
+		constant.CodeLimitChannelDailyAmount:    "Channel daily amount limit exceeded",
+		constant.CodeLimitChannelDailyCount:     "Channel daily count limit exceeded",
+		constant.CodeLimitPayerDailyAmount:      "Payee account daily amount limit exceeded",
+		constant.CodeLimitPayerDailyCount:       "Payee account daily count limit exceeded",
+
+		// 支付通道相关错误
+		constant.CodeChannelNotFound:     "Channel not found",
+		constant.CodeChannelDisabled:     "Channel disabled",
+		constant.CodeChannelBusy:         "Channel busy",
+		constant.CodeChannelMaintenance:  "Channel under maintenance",
+		constant.CodeChannelRateInvalid:  "Channel rate invalid",
+		constant.CodeChannelLimitReached: "Channel limit reached",
+		constant.CodeChannelUnavailable:  "Channel unavailable",
+
+		// 支付相关错误
+		constant.CodePaymentFailed:        "Payment failed",
+		constant.CodePaymentProcessing:    "Payment processing",
+		constant.CodePaymentTimeout:       "Payment timeout",
+		constant.CodePaymentAmountError:   "Payment amount error",
+		constant.CodePaymentCurrencyError: "Payment currency error",
+		constant.CodePaymentMethodError:   "Payment method error",
+
+		// 风控相关错误
+		constant.CodeRiskRejected:      "Risk control rejected",
+		constant.CodeRiskSuspicious:    "Suspicious transaction",
+		constant.CodeRiskBlacklist:     "Blacklisted user",
+		constant.CodeRiskHighFrequency: "High frequency transaction",
+		constant.CodeRiskAmountLimit:   "Amount limit exceeded",
+		constant.CodeRiskGeoBlocked:    "Geographic restriction",
+
+		// 结算相关错误
+		constant.CodeSettlementFailed:     "Settlement failed",
+		constant.CodeSettlementProcessing: "Settlement processing, do not resubmit",
+		constant.CodeSettlementBalanceLow: "Settlement balance insufficient",
+		constant.CodeSettlementLimit:      "Settlement amount exceeds single limit",
+		constant.CodeSettlementTimeLimit:  "Outside settlement hours",
+		constant.CodeSettlementBankError:  "Bank settlement system error",
+
+		// 退款相关错误
+		constant.CodeRefundFailed:       "Refund failed",
+		constant.CodeRefundProcessing:   "Refund processing, do not resubmit",
+		constant.CodeRefundAmountError:  "Refund amount exceeds refundable amount",
+		constant.CodeRefundTimeLimit:    "Refund period has expired",
+		constant.CodeRefundOrderInvalid: "Order status does not allow refund",
+		constant.CodeRefundChannelError: "Refund channel error",
+
+		// 通知相关错误
+		constant.CodeNotifyFailed:      "Notification failed",
+		constant.CodeNotifyTimeout:     "Notification timeout",
+		constant.CodeNotifySignError:   "Notification signature verification failed",
+		constant.CodeNotifyFormatError: "Notification format error",
+		constant.CodeNotifyRepeat:      "Duplicate notification",
+
+		// 对账相关错误
+		constant.CodeReconFileError:    "Reconciliation file invalid",
+		constant.CodeReconDataMismatch: "Reconciliation data mismatch",
+		constant.CodeReconDownloadFail: "Reconciliation file download failed",
+		constant.CodeReconProcessFail:  "Reconciliation processing failed",
+		constant.CodeReconNoData:       "No reconciliation data",
+
+		// 配置相关错误
+		constant.CodeConfigNotFound:   "Configuration not found",
+		constant.CodeConfigInvalid:    "Configuration invalid",
+		constant.CodeConfigUpdateFail: "Configuration update failed",
+		constant.CodeConfigReadOnly:   "Configuration is read-only",
+
+		// 汇率相关错误
+		constant.CodeRateNotFound:   "Exchange rate not found",
+		constant.CodeRateExpired:    "Exchange rate expired",
+		constant.CodeRateInvalid:    "Exchange rate invalid",
+		constant.CodeRateUpdateFail: "Exchange rate update failed",
+
+		// 账户相关错误
+		constant.CodeAccountNotFound:         "Account not found",
+		constant.CodeAccountFrozen:           "Account frozen",
+		constant.CodeAccountBalanceLow:       "Account balance insufficient",
+		constant.CodeAccountPasswordError:    "Account password incorrect",
+		constant.CodeAccountPermissionDenied: "Account permission denied",
+
+		// 上游错误
+		constant.CodeUpstreamError:               "Upstream channel error",
+		constant.CodeUpstreamTimeout:             "Upstream channel timeout",
+		constant.CodeUpstreamRejected:            "Upstream channel rejected",
+		constant.CodeUpstreamBalanceInsufficient: "Upstream channel balance insufficient",
+		constant.CodeUpstreamInvalidAccount:      "Upstream channel account invalid",
+		constant.CodeUpstreamNetworkError:        "Upstream channel network error",
+		constant.CodeUpstreamDataFormatError:     "Upstream channel data format error",
+		constant.CodeUpstreamSignError:           "Upstream channel signature error",
+		constant.CodeUpstreamRateLimit:           "Upstream channel rate limited",
+		constant.CodeUpstreamMaintenance:         "Upstream channel under maintenance",
+		constant.CodeUpstreamRiskControl:         "Upstream channel risk control",
+		constant.CodeUpstreamCurrencyUnsupported: "Upstream channel currency unsupported",
+		constant.CodeUpstreamAmountLimit:         "Upstream channel amount limit exceeded",
+		constant.CodeUpstreamBankProcessing:      "Upstream channel bank processing",
+		constant.CodeUpstreamDuplicateOrder:      "Upstream channel duplicate order",
+		constant.CodeUpstreamChannelClosed:       "Upstream channel closed",
+
+		// 银行错误
+		constant.CodeBankRejected: "Bank rejected",
+		constant.CodeBankTimeout:  "Bank timeout",
+		constant.CodeCardInvalid:  "Beneficiary account invalid",
+	},
+	texts: map[string]string{
+		TextSuccess:           "Success",
+		TextUnknownError:      "Unknown error",
+		TextValidationFailed:  "Parameter validation failed",
+		TextJSONFormatError:   "Invalid JSON, please check parameter types",
+		TextParamsTypeError:   "Parameter type error",
+		TextFieldTypeMismatch: "Field type does not match the expected type",
+		TextRequestFormat:     "Invalid request format: %s",
+		TextFieldRequired:     "Field is required",
+		TextFieldURL:          "Must be a valid URL",
+		TextFieldEmail:        "Must be a valid email address",
+		TextFieldInvalid:      "Invalid parameter format",
+		TextInvalidField:      "Invalid field: %s",
+		TextIPNotWhitelisted:  "IP %s is not whitelisted",
+		TextLimitValue:        "limit: %s",
+		TextBatchFileRequired: "File is required",
+		TextBatchFileUnread:   "Cannot read file",
+		TextBatchFileHash:     "file_hash does not match the file content",
+	},
+}
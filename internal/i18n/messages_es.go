@@ -0,0 +1,210 @@
+package i18n
+
+import "wht-order-api/internal/constant"
+
+// esCatalog 西班牙语
+var esCatalog = catalog{
+	codes: map[int]string{
+		// 系统错误
+		constant.CodeSuccess:            "Operación exitosa",
+		constant.CodeSystemError:        "Error del sistema",
+		constant.CodeDatabaseError:      "Error de base de datos",
+		constant.CodeRedisError:         "Error del servicio de caché",
+		constant.CodeInternalError:      "No se pudo leer el cuerpo de la solicitud",
+		constant.CodeServiceUnavailable: "Servicio temporalmente no disponible",
+		constant.CodeTimeout:            "Solicitud expirada, vuelva a enviarla",
+		constant.CodeRateLimit:          "Límite de solicitudes excedido, inténtelo más tarde",
+		constant.CodeCircuitBreak:       "Servicio ocupado, inténtelo más tarde",
+
+		// 参数错误
+		constant.CodeInvalidParams:     "Formato de parámetros incorrecto; los parámetros no cumplen el formato o la especificación esperada",
+		constant.CodeMissingParams:     "Faltan parámetros obligatorios en la solicitud",
+		constant.CodeParamsFormatError: "Error de formato en el valor del parámetro",
+		constant.CodeParamsTypeError:   "Error de tipo; el tipo del valor no coincide con el esperado",
+		constant.CodeParamsRangeError:  "Parámetro fuera del rango permitido",
+		constant.CodeDuplicateRequest:  "Solicitud duplicada, no la reenvíe",
+
+		// 认证授权错误
+		constant.CodeUnauthorized:     "No se pudo reconocer la dirección IP",
+		constant.CodeTokenExpired:     "Token expirado",
+		constant.CodeTokenInvalid:     "Token inválido",
+		constant.CodeSignatureError:   "Verificación de firma fallida",
+		constant.CodeAccessDenied:     "Acceso denegado",
+		constant.CodeIPNotWhitelisted: "IP fuera de la lista blanca",
+		constant.CodeMerchantDisabled: "Comercio no activado",
+		constant.CodeMerchantAbnormal: "Comercio en estado anómalo",
+
+		constant.CodeTransactionFailed: "Transacción fallida",
+
+		// 商户相关错误
+		constant.CodeMerchantNotFound:     "Comercio no encontrado",
+		constant.CodeMerchantBalanceLow:   "Saldo del comercio insuficiente",
+		constant.CodeMerchantRateInvalid:  "Tarifa del comercio inválida",
+		constant.CodeMerchantLimitReached: "Límite del comercio alcanzado",
+		constant.CodeMerchantKeyInvalid:   "Clave del comercio inválida",
+
+		// 订单相关错误
+		constant.CodeOrderNotFound:      "Orden no encontrada",
+		constant.CodeOrderAlreadyExist:  "La orden ya existe",
+		constant.CodeOrderStatusInvalid: "Estado de la orden inválido",
+		constant.CodeOrderAmountInvalid: "Monto de la orden inválido",
+		constant.CodeOrderExpired:       "Orden expirada",
+		constant.CodeOrderPaid:          "Orden ya pagada",
+		constant.CodeOrderRefunded:      "Orden ya reembolsada",
+		constant.CodeOrderClosed:        "Orden cerrada",
+
+		// 批量代付相关错误
+		constant.CodeBatchFileInvalid:  "Archivo de lote inválido",
+		constant.CodeBatchRowInvalid:   "El lote contiene filas inválidas",
+		constant.CodeBatchAlreadyExist: "El lote ya existe",
+		constant.CodeBatchTooLarge:     "Lote demasiado grande",
+		constant.CodeBatchNotFound:     "Lote no encontrado",
+
+		// 代付复核相关错误
+		constant.CodeApprovalNotFound:       "Aprobación no encontrada",
+		constant.CodeApprovalAlreadyDecided: "La aprobación ya fue resuelta",
+		constant.CodeApprovalActionInvalid:  "Acción de aprobación inválida",
+
+		// 商户钱包换汇相关错误
+		constant.CodeFxCurrencyInvalid:  "Moneda de cambio inválida",
+		constant.CodeFxRateInvalid:      "Tipo de cambio o monto inválido",
+		constant.CodeFxBalanceLow:       "Saldo insuficiente en la billetera de origen",
+		constant.CodeFxConversionNoUsed: "El número de conversión ya fue usado por otro comercio",
+
+		// 商户提现相关错误
+		constant.CodeWithdrawDisabled:            "Los retiros no están habilitados",
+		constant.CodeWithdrawDestinationNotFound: "Cuenta de retiro no encontrada o deshabilitada",
+		constant.CodeWithdrawDestinationCooling:  "La cuenta de retiro está en periodo de espera",
+		constant.CodeWithdrawAmountInvalid:       "Monto de retiro fuera de rango",
+		constant.CodeWithdrawFeeRuleMissing:      "Regla de comisión de retiro no configurada",
+		constant.CodeWithdrawAlreadyExist:        "El número de retiro ya existe",
+		constant.CodeWithdrawNotFound:            "Retiro no encontrado",
+		constant.CodeWithdrawCurrencyMismatch:    "La moneda de la cuenta no coincide con la del canal",
+
+		// 累计限额相关错误
+		constant.CodeLimitMerchantDailyAmount:   "Límite diario de monto del comercio excedido",
+		constant.CodeLimitMerchantDailyCount:    "Límite diario de transacciones del comercio excedido",
+		constant.CodeLimitMerchantMonthlyAmount: "Límite mensual de monto del comercio excedido",
+		constant.CodeLimitMerchantMonthlyCount:  "Límite mensual de transacciones del comercio excedido",
+		constant.CodeLimitChannelDailyAmount:    "Límite diario de monto del canal excedido",
+		constant.CodeLimitChannelDailyCount:     "Límite diario de transacciones del canal excedido",
+		constant.CodeLimitPayerDailyAmount:      "Límite diario de monto de la cuenta beneficiaria excedido",
+		constant.CodeLimitPayerDailyCount:       "Límite diario de transacciones de la cuenta beneficiaria excedido",
+
+		// 支付通道相关错误
+		constant.CodeChannelNotFound:     "Canal no encontrado",
+		constant.CodeChannelDisabled:     "Canal deshabilitado",
+		constant.CodeChannelBusy:         "Canal ocupado",
+		constant.CodeChannelMaintenance:  "Canal en mantenimiento",
+		constant.CodeChannelRateInvalid:  "Tarifa del canal inválida",
+		constant.CodeChannelLimitReached: "Límite del canal alcanzado",
+		constant.CodeChannelUnavailable:  "Canal no disponible",
+
+		// 支付相关错误
+		constant.CodePaymentFailed:        "Pago fallido",
+		constant.CodePaymentProcessing:    "Pago en proceso",
+		constant.CodePaymentTimeout:       "Tiempo de pago agotado",
+		constant.CodePaymentAmountError:   "Monto de pago incorrecto",
+		constant.CodePaymentCurrencyError: "Moneda de pago incorrecta",
+		constant.CodePaymentMethodError:   "Método de pago incorrecto",
+
+		// 风控相关错误
+		constant.CodeRiskRejected:      "Rechazado por control de riesgos",
+		constant.CodeRiskSuspicious:    "Transacción sospechosa",
+		constant.CodeRiskBlacklist:     "Cuenta en lista negra",
+		constant.CodeRiskHighFrequency: "Frecuencia de transacciones demasiado alta",
+		constant.CodeRiskAmountLimit:   "Monto fuera del límite permitido",
+		constant.CodeRiskGeoBlocked:    "Región no soportada",
+
+		// 结算相关错误
+		constant.CodeSettlementFailed:     "Liquidación fallida",
+		constant.CodeSettlementProcessing: "Liquidación en proceso, no la reenvíe",
+		constant.CodeSettlementBalanceLow: "Saldo de liquidación insuficiente",
+		constant.CodeSettlementLimit:      "El monto de liquidación excede el límite por operación",
+		constant.CodeSettlementTimeLimit:  "Fuera del horario de liquidación",
+		constant.CodeSettlementBankError:  "Error del sistema de liquidación bancaria",
+
+		// 退款相关错误
+		constant.CodeRefundFailed:       "Reembolso fallido",
+		constant.CodeRefundProcessing:   "Reembolso en proceso, no lo reenvíe",
+		constant.CodeRefundAmountError:  "El monto excede lo reembolsable",
+		constant.CodeRefundTimeLimit:    "El plazo de reembolso ha vencido",
+		constant.CodeRefundOrderInvalid: "El estado de la orden no permite reembolso",
+		constant.CodeRefundChannelError: "Error en el canal de reembolso",
+
+		// 通知相关错误
+		constant.CodeNotifyFailed:      "Error al enviar la notificación",
+		constant.CodeNotifyTimeout:     "Tiempo de notificación agotado",
+		constant.CodeNotifySignError:   "Firma de la notificación inválida",
+		constant.CodeNotifyFormatError: "Formato de notificación incorrecto",
+		constant.CodeNotifyRepeat:      "Notificación duplicada",
+
+		// 对账相关错误
+		constant.CodeReconFileError:    "Archivo de conciliación inválido",
+		constant.CodeReconDataMismatch: "Datos de conciliación no coinciden",
+		constant.CodeReconDownloadFail: "Error al descargar el archivo de conciliación",
+		constant.CodeReconProcessFail:  "Error al procesar la conciliación",
+		constant.CodeReconNoData:       "Sin datos de conciliación",
+
+		// 配置相关错误
+		constant.CodeConfigNotFound:   "Configuración no encontrada",
+		constant.CodeConfigInvalid:    "Configuración inválida",
+		constant.CodeConfigUpdateFail: "Error al actualizar la configuración",
+		constant.CodeConfigReadOnly:   "La configuración es de solo lectura",
+
+		// 汇率相关错误
+		constant.CodeRateNotFound:   "Tipo de cambio no encontrado",
+		constant.CodeRateExpired:    "Tipo de cambio expirado",
+		constant.CodeRateInvalid:    "Tipo de cambio inválido",
+		constant.CodeRateUpdateFail: "Error al actualizar el tipo de cambio",
+
+		// 账户相关错误
+		constant.CodeAccountNotFound:         "Cuenta no encontrada",
+		constant.CodeAccountFrozen:           "Cuenta congelada",
+		constant.CodeAccountBalanceLow:       "Saldo de la cuenta insuficiente",
+		constant.CodeAccountPasswordError:    "Contraseña de la cuenta incorrecta",
+		constant.CodeAccountPermissionDenied: "Permisos de la cuenta insuficientes",
+
+		// 上游错误
+		constant.CodeUpstreamError:               "Error del canal proveedor",
+		constant.CodeUpstreamTimeout:             "Tiempo de espera del canal proveedor agotado",
+		constant.CodeUpstreamRejected:            "Transacción rechazada por el canal proveedor",
+		constant.CodeUpstreamBalanceInsufficient: "Saldo insuficiente en el canal proveedor",
+		constant.CodeUpstreamInvalidAccount:      "Cuenta del canal proveedor inválida",
+		constant.CodeUpstreamNetworkError:        "Error de red con el canal proveedor",
+		constant.CodeUpstreamDataFormatError:     "Formato de datos del canal proveedor incorrecto",
+		constant.CodeUpstreamSignError:           "Error de firma del canal proveedor",
+		constant.CodeUpstreamRateLimit:           "Límite de solicitudes del canal proveedor excedido",
+		constant.CodeUpstreamMaintenance:         "Canal proveedor en mantenimiento",
+		constant.CodeUpstreamRiskControl:         "Bloqueado por el control de riesgos del canal proveedor",
+		constant.CodeUpstreamCurrencyUnsupported: "Moneda no soportada por el canal proveedor",
+		constant.CodeUpstreamAmountLimit:         "Monto fuera del límite del canal proveedor",
+		constant.CodeUpstreamBankProcessing:      "El banco del canal proveedor está procesando",
+		constant.CodeUpstreamDuplicateOrder:      "Orden duplicada en el canal proveedor",
+		constant.CodeUpstreamChannelClosed:       "Canal proveedor cerrado",
+
+		// 银行错误
+		constant.CodeBankRejected: "Transacción rechazada por el banco",
+		constant.CodeBankTimeout:  "Tiempo de espera del banco agotado",
+		constant.CodeCardInvalid:  "Cuenta beneficiaria inválida",
+	},
+	texts: map[string]string{
+		TextSuccess:           "Éxito",
+		TextUnknownError:      "Error desconocido",
+		TextValidationFailed:  "La validación de parámetros falló",
+		TextJSONFormatError:   "JSON inválido, verifique los tipos de los parámetros",
+		TextParamsTypeError:   "Error de tipo de parámetro",
+		TextFieldTypeMismatch: "El tipo del campo no coincide con el esperado",
+		TextRequestFormat:     "Formato de solicitud inválido: %s",
+		TextFieldRequired:     "El campo es obligatorio",
+		TextFieldURL:          "Debe ser una URL válida",
+		TextFieldEmail:        "Debe ser un correo electrónico válido",
+		TextFieldInvalid:      "Formato de parámetro inválido",
+		TextInvalidField:      "Campo inválido: %s",
+		TextIPNotWhitelisted:  "La IP %s no está en la lista blanca",
+		TextLimitValue:        "límite: %s",
+		TextBatchFileRequired: "El archivo es obligatorio",
+		TextBatchFileUnread:   "No se puede leer el archivo",
+		TextBatchFileHash:     "file_hash no coincide con el contenido del archivo",
+	},
+}
@@ -0,0 +1,210 @@
+package i18n
+
+import "wht-order-api/internal/constant"
+
+// ptCatalog 葡萄牙语
+var ptCatalog = catalog{
+	codes: map[int]string{
+		// 系统错误
+		constant.CodeSuccess:            "Operação realizada com sucesso",
+		constant.CodeSystemError:        "Erro do sistema",
+		constant.CodeDatabaseError:      "Erro de banco de dados",
+		constant.CodeRedisError:         "Erro do serviço de cache",
+		constant.CodeInternalError:      "Falha ao ler o corpo da requisição",
+		constant.CodeServiceUnavailable: "Serviço temporariamente indisponível",
+		constant.CodeTimeout:            "Requisição expirada, reenvie a requisição",
+		constant.CodeRateLimit:          "Limite de requisições excedido, tente novamente mais tarde",
+		constant.CodeCircuitBreak:       "Serviço ocupado, tente novamente mais tarde",
+
+		// 参数错误
+		constant.CodeInvalidParams:     "Formato de parâmetros incorreto; os parâmetros não seguem o formato ou a especificação esperada",
+		constant.CodeMissingParams:     "Parâmetros obrigatórios ausentes na requisição",
+		constant.CodeParamsFormatError: "Erro de formato no valor do parâmetro",
+		constant.CodeParamsTypeError:   "Erro de tipo; o tipo do valor não corresponde ao esperado",
+		constant.CodeParamsRangeError:  "Parâmetro fora do intervalo permitido",
+		constant.CodeDuplicateRequest:  "Requisição duplicada, não reenvie",
+
+		// 认证授权错误
+		constant.CodeUnauthorized:     "Não foi possível reconhecer o endereço IP",
+		constant.CodeTokenExpired:     "Token expirado",
+		constant.CodeTokenInvalid:     "Token inválido",
+		constant.CodeSignatureError:   "Falha na verificação da assinatura",
+		constant.CodeAccessDenied:     "Acesso negado",
+		constant.CodeIPNotWhitelisted: "IP fora da lista de permissões",
+		constant.CodeMerchantDisabled: "Lojista não ativado",
+		constant.CodeMerchantAbnormal: "Lojista em situação irregular",
+
+		constant.CodeTransactionFailed: "Transação falhou",
+
+		// 商户相关错误
+		constant.CodeMerchantNotFound:     "Lojista não encontrado",
+		constant.CodeMerchantBalanceLow:   "Saldo do lojista insuficiente",
+		constant.CodeMerchantRateInvalid:  "Taxa do lojista inválida",
+		constant.CodeMerchantLimitReached: "Limite do lojista atingido",
+		constant.CodeMerchantKeyInvalid:   "Chave do lojista inválida",
+
+		// 订单相关错误
+		constant.CodeOrderNotFound:      "Pedido não encontrado",
+		constant.CodeOrderAlreadyExist:  "O pedido já existe",
+		constant.CodeOrderStatusInvalid: "Status do pedido inválido",
+		constant.CodeOrderAmountInvalid: "Valor do pedido inválido",
+		constant.CodeOrderExpired:       "Pedido expirado",
+		constant.CodeOrderPaid:          "Pedido já pago",
+		constant.CodeOrderRefunded:      "Pedido já reembolsado",
+		constant.CodeOrderClosed:        "Pedido encerrado",
+
+		// 批量代付相关错误
+		constant.CodeBatchFileInvalid:  "Arquivo de lote inválido",
+		constant.CodeBatchRowInvalid:   "O lote contém linhas inválidas",
+		constant.CodeBatchAlreadyExist: "O lote já existe",
+		constant.CodeBatchTooLarge:     "Lote muito grande",
+		constant.CodeBatchNotFound:     "Lote não encontrado",
+
+		// 代付复核相关错误
+		constant.CodeApprovalNotFound:       "Aprovação não encontrada",
+		constant.CodeApprovalAlreadyDecided: "A aprovação já foi decidida",
+		constant.CodeApprovalActionInvalid:  "Ação de aprovação inválida",
+
+		// 商户钱包换汇相关错误
+		constant.CodeFxCurrencyInvalid:  "Moeda de câmbio inválida",
+		constant.CodeFxRateInvalid:      "Taxa de câmbio ou valor inválido",
+		constant.CodeFxBalanceLow:       "Saldo insuficiente na carteira de origem",
+		constant.CodeFxConversionNoUsed: "Número de conversão já usado por outro comerciante",
+
+		// 商户提现相关错误
+		constant.CodeWithdrawDisabled:            "Saques não estão habilitados",
+		constant.CodeWithdrawDestinationNotFound: "Conta de saque não encontrada ou desativada",
+		constant.CodeWithdrawDestinationCooling:  "A conta de saque está em período de carência",
+		constant.CodeWithdrawAmountInvalid:       "Valor de saque fora do intervalo permitido",
+		constant.CodeWithdrawFeeRuleMissing:      "Regra de tarifa de saque não configurada",
+		constant.CodeWithdrawAlreadyExist:        "O número de saque já existe",
+		constant.CodeWithdrawNotFound:            "Saque não encontrado",
+		constant.CodeWithdrawCurrencyMismatch:    "A moeda da conta não corresponde à do canal",
+
+		// 累计限额相关错误
+		constant.CodeLimitMerchantDailyAmount:   "Limite diário de valor do lojista excedido",
+		constant.CodeLimitMerchantDailyCount:    "Limite diário de transações do lojista excedido",
+		constant.CodeLimitMerchantMonthlyAmount: "Limite mensal de valor do lojista excedido",
+		constant.CodeLimitMerchantMonthlyCount:  "Limite mensal de transações do lojista excedido",
+		constant.CodeLimitChannelDailyAmount:    "Limite diário de valor do canal excedido",
+		constant.CodeLimitChannelDailyCount:     "Limite diário de transações do canal excedido",
+		constant.CodeLimitPayerDailyAmount:      "Limite diário de valor da conta favorecida excedido",
+		constant.CodeLimitPayerDailyCount:       "Limite diário de transações da conta favorecida excedido",
+
+		// 支付通道相关错误
+		constant.CodeChannelNotFound:     "Canal não encontrado",
+		constant.CodeChannelDisabled:     "Canal desativado",
+		constant.CodeChannelBusy:         "Canal ocupado",
+		constant.CodeChannelMaintenance:  "Canal em manutenção",
+		constant.CodeChannelRateInvalid:  "Taxa do canal inválida",
+		constant.CodeChannelLimitReached: "Limite do canal atingido",
+		constant.CodeChannelUnavailable:  "Canal indisponível",
+
+		// 支付相关错误
+		constant.CodePaymentFailed:        "Pagamento falhou",
+		constant.CodePaymentProcessing:    "Pagamento em processamento",
+		constant.CodePaymentTimeout:       "Tempo de pagamento esgotado",
+		constant.CodePaymentAmountError:   "Valor de pagamento incorreto",
+		constant.CodePaymentCurrencyError: "Moeda de pagamento incorreta",
+		constant.CodePaymentMethodError:   "Método de pagamento incorreto",
+
+		// 风控相关错误
+		constant.CodeRiskRejected:      "Recusado pelo controle de risco",
+		constant.CodeRiskSuspicious:    "Transação suspeita",
+		constant.CodeRiskBlacklist:     "Conta na lista de bloqueio",
+		constant.CodeRiskHighFrequency: "Frequência de transações muito alta",
+		constant.CodeRiskAmountLimit:   "Valor acima do limite permitido",
+		constant.CodeRiskGeoBlocked:    "Região não suportada",
+
+		// 结算相关错误
+		constant.CodeSettlementFailed:     "Liquidação falhou",
+		constant.CodeSettlementProcessing: "Liquidação em processamento, não reenvie",
+		constant.CodeSettlementBalanceLow: "Saldo de liquidação insuficiente",
+		constant.CodeSettlementLimit:      "O valor de liquidação excede o limite por operação",
+		constant.CodeSettlementTimeLimit:  "Fora do horário de liquidação",
+		constant.CodeSettlementBankError:  "Erro no sistema de liquidação bancária",
+
+		// 退款相关错误
+		constant.CodeRefundFailed:       "Reembolso falhou",
+		constant.CodeRefundProcessing:   "Reembolso em processamento, não reenvie",
+		constant.CodeRefundAmountError:  "O valor excede o montante reembolsável",
+		constant.CodeRefundTimeLimit:    "O prazo de reembolso expirou",
+		constant.CodeRefundOrderInvalid: "O status do pedido não permite reembolso",
+		constant.CodeRefundChannelError: "Erro no canal de reembolso",
+
+		// 通知相关错误
+		constant.CodeNotifyFailed:      "Falha ao enviar a notificação",
+		constant.CodeNotifyTimeout:     "Tempo de notificação esgotado",
+		constant.CodeNotifySignError:   "Assinatura da notificação inválida",
+		constant.CodeNotifyFormatError: "Formato da notificação incorreto",
+		constant.CodeNotifyRepeat:      "Notificação duplicada",
+
+		// 对账相关错误
+		constant.CodeReconFileError:    "Arquivo de conciliação inválido",
+		constant.CodeReconDataMismatch: "Dados de conciliação divergentes",
+		constant.CodeReconDownloadFail: "Falha ao baixar o arquivo de conciliação",
+		constant.CodeReconProcessFail:  "Falha ao processar a conciliação",
+		constant.CodeReconNoData:       "Sem dados de conciliação",
+
+		// 配置相关错误
+		constant.CodeConfigNotFound:   "Configuração não encontrada",
+		constant.CodeConfigInvalid:    "Configuração inválida",
+		constant.CodeConfigUpdateFail: "Falha ao atualizar a configuração",
+		constant.CodeConfigReadOnly:   "A configuração é somente leitura",
+
+		// 汇率相关错误
+		constant.CodeRateNotFound:   "Taxa de câmbio não encontrada",
+		constant.CodeRateExpired:    "Taxa de câmbio expirada",
+		constant.CodeRateInvalid:    "Taxa de câmbio inválida",
+		constant.CodeRateUpdateFail: "Falha ao atualizar a taxa de câmbio",
+
+		// 账户相关错误
+		constant.CodeAccountNotFound:         "Conta não encontrada",
+		constant.CodeAccountFrozen:           "Conta bloqueada",
+		constant.CodeAccountBalanceLow:       "Saldo da conta insuficiente",
+		constant.CodeAccountPasswordError:    "Senha da conta incorreta",
+		constant.CodeAccountPermissionDenied: "Permissões da conta insuficientes",
+
+		// 上游错误
+		constant.CodeUpstreamError:               "Erro do canal provedor",
+		constant.CodeUpstreamTimeout:             "Tempo esgotado no canal provedor",
+		constant.CodeUpstreamRejected:            "Transação recusada pelo canal provedor",
+		constant.CodeUpstreamBalanceInsufficient: "Saldo insuficiente no canal provedor",
+		constant.CodeUpstreamInvalidAccount:      "Conta do canal provedor inválida",
+		constant.CodeUpstreamNetworkError:        "Erro de rede com o canal provedor",
+		constant.CodeUpstreamDataFormatError:     "Formato de dados do canal provedor incorreto",
+		constant.CodeUpstreamSignError:           "Erro de assinatura do canal provedor",
+		constant.CodeUpstreamRateLimit:           "Limite de requisições do canal provedor excedido",
+		constant.CodeUpstreamMaintenance:         "Canal provedor em manutenção",
+		constant.CodeUpstreamRiskControl:         "Bloqueado pelo controle de risco do canal provedor",
+		constant.CodeUpstreamCurrencyUnsupported: "Moeda não suportada pelo canal provedor",
+		constant.CodeUpstreamAmountLimit:         "Valor fora do limite do canal provedor",
+		constant.CodeUpstreamBankProcessing:      "O banco do canal provedor está processando",
+		constant.CodeUpstreamDuplicateOrder:      "Pedido duplicado no canal provedor",
+		constant.CodeUpstreamChannelClosed:       "Canal provedor encerrado",
+
+		// 银行错误
+		constant.CodeBankRejected: "Transação recusada pelo banco",
+		constant.CodeBankTimeout:  "Tempo de resposta do banco esgotado",
+		constant.CodeCardInvalid:  "Conta favorecida inválida",
+	},
+	texts: map[string]string{
+		TextSuccess:           "Sucesso",
+		TextUnknownError:      "Erro desconhecido",
+		TextValidationFailed:  "Falha na validação dos parâmetros",
+		TextJSONFormatError:   "JSON inválido, verifique os tipos dos parâmetros",
+		TextParamsTypeError:   "Erro de tipo de parâmetro",
+		TextFieldTypeMismatch: "O tipo do campo não corresponde ao esperado",
+		TextRequestFormat:     "Formato de requisição inválido: %s",
+		TextFieldRequired:     "O campo é obrigatório",
+		TextFieldURL:          "Deve ser uma URL válida",
+		TextFieldEmail:        "Deve ser um e-mail válido",
+		TextFieldInvalid:      "Formato de parâmetro inválido",
+		TextInvalidField:      "Campo inválido: %s",
+		TextIPNotWhitelisted:  "O IP %s não está na lista de permissões",
+		TextLimitValue:        "limite: %s",
+		TextBatchFileRequired: "O arquivo é obrigatório",
+		TextBatchFileUnread:   "Não foi possível ler o arquivo",
+		TextBatchFileHash:     "file_hash não corresponde ao conteúdo do arquivo",
+	},
+}
@@ -0,0 +1,210 @@
+package i18n
+
+import "wht-order-api/internal/constant"
+
+// zhCatalog 简体中文
+var zhCatalog = catalog{
+	codes: map[int]string{
+		// 系统错误
+		constant.CodeSuccess:            "操作成功",
+		constant.CodeSystemError:        "系统错误",
+		constant.CodeDatabaseError:      "数据库错误",
+		constant.CodeRedisError:         "缓存服务错误",
+		constant.CodeInternalError:      "读取请求体失败",
+		constant.CodeServiceUnavailable: "服务暂时不可用",
+		constant.CodeTimeout:            "请求过期,重新发起请求",
+		constant.CodeRateLimit:          "请求频率超过限制，请稍后重试",
+		constant.CodeCircuitBreak:       "服务繁忙，请稍后重试",
+
+		// 参数错误
+		constant.CodeInvalidParams:     "参数格式错误，请求参数不符合预期格式或规范",
+		constant.CodeMissingParams:     "缺少必要参数，请求中缺失必须提供的参数字段",
+		constant.CodeParamsFormatError: "参数格式错误，参数值格式不正确",
+		constant.CodeParamsTypeError:   "参数类型错误，参数值类型与预期类型不匹配",
+		constant.CodeParamsRangeError:  "参数范围错误，参数值超出允许范围",
+		constant.CodeDuplicateRequest:  "重复请求，请勿重复提交",
+
+		// 认证授权错误
+		constant.CodeUnauthorized:     "无法识别IP",
+		constant.CodeTokenExpired:     "Token已过期",
+		constant.CodeTokenInvalid:     "Token无效",
+		constant.CodeSignatureError:   "签名验证失败",
+		constant.CodeAccessDenied:     "访问权限不足",
+		constant.CodeIPNotWhitelisted: "IP不在白名单内",
+		constant.CodeMerchantDisabled: "商户未启用",
+		constant.CodeMerchantAbnormal: "商户异常",
+
+		constant.CodeTransactionFailed: "交易失败",
+
+		// 商户相关错误
+		constant.CodeMerchantNotFound:     "商户不存在",
+		constant.CodeMerchantBalanceLow:   "商户余额不足",
+		constant.CodeMerchantRateInvalid:  "商户费率配置无效",
+		constant.CodeMerchantLimitReached: "商户交易限额已满",
+		constant.CodeMerchantKeyInvalid:   "商户密钥无效",
+
+		// 订单相关错误
+		constant.CodeOrderNotFound:      "订单不存在",
+		constant.CodeOrderAlreadyExist:  "订单已存在",
+		constant.CodeOrderStatusInvalid: "订单状态无效",
+		constant.CodeOrderAmountInvalid: "订单金额无效",
+		constant.CodeOrderExpired:       "订单已过期",
+		constant.CodeOrderPaid:          "订单已支付",
+		constant.CodeOrderRefunded:      "订单已退款",
+		constant.CodeOrderClosed:        "订单已关闭",
+
+		// 批量代付相关错误
+		constant.CodeBatchFileInvalid:  "批量文件格式错误",
+		constant.CodeBatchRowInvalid:   "批量文件存在无效行",
+		constant.CodeBatchAlreadyExist: "批次号已存在",
+		constant.CodeBatchTooLarge:     "批量行数或文件大小超过限制",
+		constant.CodeBatchNotFound:     "批次不存在",
+
+		// 代付复核相关错误
+		constant.CodeApprovalNotFound:       "复核记录不存在",
+		constant.CodeApprovalAlreadyDecided: "复核记录已处理",
+		constant.CodeApprovalActionInvalid:  "复核操作无效",
+
+		// 商户钱包换汇相关错误
+		constant.CodeFxCurrencyInvalid:  "换汇币种无效",
+		constant.CodeFxRateInvalid:      "换汇汇率或金额无效",
+		constant.CodeFxBalanceLow:       "源币种钱包余额不足",
+		constant.CodeFxConversionNoUsed: "换汇单号已被其他商户使用",
+
+		// 商户提现相关错误
+		constant.CodeWithdrawDisabled:            "提现功能未开放",
+		constant.CodeWithdrawDestinationNotFound: "提现账户不存在或已停用",
+		constant.CodeWithdrawDestinationCooling:  "提现账户处于变更冷却期，暂不可提现",
+		constant.CodeWithdrawAmountInvalid:       "提现金额不在允许范围内",
+		constant.CodeWithdrawFeeRuleMissing:      "未配置提现手续费规则",
+		constant.CodeWithdrawAlreadyExist:        "提现单号已存在，请勿重复提交",
+		constant.CodeWithdrawNotFound:            "提现订单不存在",
+		constant.CodeWithdrawCurrencyMismatch:    "提现账户币种与通道币种不一致",
+
+		// 累计限额相关错误
+		constant.CodeLimitMerchantDailyAmount:   "超出商户单日累计金额限额",
+		constant.CodeLimitMerchantDailyCount:    "超出商户单日累计笔数限额",
+		constant.CodeLimitMerchantMonthlyAmount: "超出商户单月累计金额限额",
+		constant.CodeLimitMerchantMonthlyCount:  "超出商户单月累计笔数限额",
+		constant.CodeLimitChannelDailyAmount:    "超出通道单日累计金额限额",
+		constant.CodeLimitChannelDailyCount:     "超出通道单日累计笔数限额",
+		constant.CodeLimitPayerDailyAmount:      "超出收款账户单日累计金额限额",
+		constant.CodeLimitPayerDailyCount:       "超出收款账户单日累计笔数限额",
+
+		// 支付通道相关错误
+		constant.CodeChannelNotFound:     "支付通道不存在",
+		constant.CodeChannelDisabled:     "支付通道已禁用",
+		constant.CodeChannelBusy:         "支付通道繁忙",
+		constant.CodeChannelMaintenance:  "支付通道维护中",
+		constant.CodeChannelRateInvalid:  "通道费率配置错误",
+		constant.CodeChannelLimitReached: "通道交易限额已满",
+		constant.CodeChannelUnavailable:  "支付通道暂时不可用",
+
+		// 支付相关错误
+		constant.CodePaymentFailed:        "支付失败",
+		constant.CodePaymentProcessing:    "支付处理中",
+		constant.CodePaymentTimeout:       "支付超时",
+		constant.CodePaymentAmountError:   "支付金额错误",
+		constant.CodePaymentCurrencyError: "支付币种错误",
+		constant.CodePaymentMethodError:   "支付方式错误",
+
+		// 风控相关错误
+		constant.CodeRiskRejected:      "风控系统拒绝交易",
+		constant.CodeRiskSuspicious:    "交易行为可疑",
+		constant.CodeRiskBlacklist:     "账户存在风险",
+		constant.CodeRiskHighFrequency: "交易频率过高",
+		constant.CodeRiskAmountLimit:   "交易金额超过限制",
+		constant.CodeRiskGeoBlocked:    "地区暂不支持",
+
+		// 结算相关错误
+		constant.CodeSettlementFailed:     "结算失败",
+		constant.CodeSettlementProcessing: "结算处理中，请勿重复提交",
+		constant.CodeSettlementBalanceLow: "结算余额不足",
+		constant.CodeSettlementLimit:      "结算金额超过单笔限额",
+		constant.CodeSettlementTimeLimit:  "不在结算时间范围内",
+		constant.CodeSettlementBankError:  "银行结算系统异常",
+
+		// 退款相关错误
+		constant.CodeRefundFailed:       "退款失败",
+		constant.CodeRefundProcessing:   "退款处理中，请勿重复提交",
+		constant.CodeRefundAmountError:  "退款金额超过可退金额",
+		constant.CodeRefundTimeLimit:    "已超过退款时间限制",
+		constant.CodeRefundOrderInvalid: "订单状态不支持退款",
+		constant.CodeRefundChannelError: "退款通道异常",
+
+		// 通知相关错误
+		constant.CodeNotifyFailed:      "通知发送失败",
+		constant.CodeNotifyTimeout:     "通知超时",
+		constant.CodeNotifySignError:   "通知签名验证失败",
+		constant.CodeNotifyFormatError: "通知格式错误",
+		constant.CodeNotifyRepeat:      "重复通知",
+
+		// 对账相关错误
+		constant.CodeReconFileError:    "对账文件格式错误",
+		constant.CodeReconDataMismatch: "对账数据不一致",
+		constant.CodeReconDownloadFail: "对账文件下载失败",
+		constant.CodeReconProcessFail:  "对账处理失败",
+		constant.CodeReconNoData:       "暂无对账数据",
+
+		// 配置相关错误
+		constant.CodeConfigNotFound:   "配置信息不存在",
+		constant.CodeConfigInvalid:    "配置信息无效",
+		constant.CodeConfigUpdateFail: "配置更新失败",
+		constant.CodeConfigReadOnly:   "配置为只读",
+
+		// 汇率相关错误
+		constant.CodeRateNotFound:   "汇率信息不存在",
+		constant.CodeRateExpired:    "汇率已过期",
+		constant.CodeRateInvalid:    "汇率无效",
+		constant.CodeRateUpdateFail: "汇率更新失败",
+
+		// 账户相关错误
+		constant.CodeAccountNotFound:         "账户不存在",
+		constant.CodeAccountFrozen:           "账户已冻结",
+		constant.CodeAccountBalanceLow:       "账户余额不足",
+		constant.CodeAccountPasswordError:    "账户密码错误",
+		constant.CodeAccountPermissionDenied: "账户权限不足",
+
+		// 上游错误
+		constant.CodeUpstreamError:               "上游通道错误",
+		constant.CodeUpstreamTimeout:             "上游通道请求超时",
+		constant.CodeUpstreamRejected:            "上游通道拒绝交易",
+		constant.CodeUpstreamBalanceInsufficient: "上游通道余额不足",
+		constant.CodeUpstreamInvalidAccount:      "上游通道账户异常",
+		constant.CodeUpstreamNetworkError:        "上游通道网络异常",
+		constant.CodeUpstreamDataFormatError:     "上游通道数据格式错误",
+		constant.CodeUpstreamSignError:           "上游通道签名错误",
+		constant.CodeUpstreamRateLimit:           "上游通道请求频率超限",
+		constant.CodeUpstreamMaintenance:         "上游通道维护中",
+		constant.CodeUpstreamRiskControl:         "上游通道风控拦截",
+		constant.CodeUpstreamCurrencyUnsupported: "上游通道不支持该币种",
+		constant.CodeUpstreamAmountLimit:         "上游通道金额超限",
+		constant.CodeUpstreamBankProcessing:      "上游通道银行处理中",
+		constant.CodeUpstreamDuplicateOrder:      "上游通道订单重复",
+		constant.CodeUpstreamChannelClosed:       "上游通道已关闭",
+
+		// 银行错误
+		constant.CodeBankRejected: "银行拒绝交易",
+		constant.CodeBankTimeout:  "银行处理超时",
+		constant.CodeCardInvalid:  "收款账户无效",
+	},
+	texts: map[string]string{
+		TextSuccess:           "成功",
+		TextUnknownError:      "未知错误",
+		TextValidationFailed:  "参数校验失败",
+		TextJSONFormatError:   "JSON格式错误，请检查参数类型",
+		TextParamsTypeError:   "参数类型错误",
+		TextFieldTypeMismatch: "字段类型与预期不符",
+		TextRequestFormat:     "请求格式错误: %s",
+		TextFieldRequired:     "字段不能为空",
+		TextFieldURL:          "必须是合法的 URL 地址",
+		TextFieldEmail:        "必须是合法的邮箱格式",
+		TextFieldInvalid:      "参数格式错误",
+		TextInvalidField:      "字段校验失败: %s",
+		TextIPNotWhitelisted:  "IP %s 不在白名单内",
+		TextLimitValue:        "限额: %s",
+		TextBatchFileRequired: "请上传批量文件",
+		TextBatchFileUnread:   "无法读取文件",
+		TextBatchFileHash:     "file_hash 与文件内容不一致",
+	},
+}
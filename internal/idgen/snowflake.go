@@ -6,6 +6,7 @@ import (
 	"log"
 	"sync"
 	"time"
+	"wht-order-api/internal/lifecycle"
 )
 
 var (
@@ -40,11 +41,17 @@ func New() uint64 {
 func CheckSystemClock() {
 	last := time.Now().UnixMilli()
 	ticker := time.NewTicker(time.Second)
-	for now := range ticker.C {
-		current := now.UnixMilli()
-		if current < last {
-			log.Fatalf("[IDGen] System clock moved backward: last=%d, now=%d", last, current)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-lifecycle.Stopping():
+			return
+		case now := <-ticker.C:
+			current := now.UnixMilli()
+			if current < last {
+				log.Fatalf("[IDGen] System clock moved backward: last=%d, now=%d", last, current)
+			}
+			last = current
 		}
-		last = current
 	}
 }
@@ -0,0 +1,86 @@
+// Package lifecycle 进程生命周期：跟踪服务内启动的后台协程，收到退出信号后通知常驻任务停止，
+// 并在截止时间内等待所有受跟踪协程完成，之后再按顺序关闭 MQ、Redis、数据库。
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/system"
+)
+
+var (
+	mu      sync.Mutex
+	running int64
+	// drained 受跟踪协程数归零时关闭；不用 WaitGroup：Wait 超时返回后仍可能有新协程加入
+	drained  = closedChan()
+	stopping = make(chan struct{})
+	stopOnce sync.Once
+)
+
+func closedChan() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+// Go 启动受跟踪的后台协程（退出时等待其完成），panic 恢复并告警
+func Go(name string, fn func()) {
+	mu.Lock()
+	if running == 0 {
+		drained = make(chan struct{})
+	}
+	running++
+	mu.Unlock()
+	go func() {
+		defer func() {
+			mu.Lock()
+			running--
+			if running == 0 {
+				close(drained)
+			}
+			mu.Unlock()
+		}()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[PANIC] 后台任务 %s panic: %v\n%s", name, r, debug.Stack())
+				notify.Notify(system.BotChatID, "error", "后台任务Panic",
+					fmt.Sprintf("🚨 后台任务 `%s` panic: %v", name, r), true)
+			}
+		}()
+		fn()
+	}()
+}
+
+// Stopping 进程开始退出时关闭，常驻后台任务据此结束循环
+func Stopping() <-chan struct{} {
+	return stopping
+}
+
+// Stop 通知常驻后台任务停止（幂等）
+func Stop() {
+	stopOnce.Do(func() { close(stopping) })
+}
+
+// Running 仍在运行的受跟踪协程数量
+func Running() int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return running
+}
+
+// Wait 等待所有受跟踪协程结束，ctx 到期时返回错误（剩余协程随进程退出被中断）
+func Wait(ctx context.Context) error {
+	mu.Lock()
+	done := drained
+	mu.Unlock()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待后台任务超时，剩余 %d 个: %w", Running(), ctx.Err())
+	}
+}
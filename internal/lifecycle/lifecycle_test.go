@@ -0,0 +1,75 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 包级状态共享：Stop 只能触发一次，放在最后一个用例
+
+func TestWaitForTrackedGoroutines(t *testing.T) {
+	var done atomic.Int32
+	for i := 0; i < 5; i++ {
+		Go("test-task", func() {
+			time.Sleep(20 * time.Millisecond)
+			done.Add(1)
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Wait(ctx); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if done.Load() != 5 {
+		t.Fatalf("completed = %d, want 5", done.Load())
+	}
+	if Running() != 0 {
+		t.Fatalf("Running = %d, want 0", Running())
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	release := make(chan struct{})
+	Go("test-blocked", func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait err = %v, want deadline exceeded", err)
+	}
+	if Running() != 1 {
+		t.Fatalf("Running = %d, want 1", Running())
+	}
+
+	close(release)
+	if err := Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+}
+
+func TestStopEndsLoops(t *testing.T) {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	Go("test-loop", func() {
+		for {
+			select {
+			case <-Stopping():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+
+	Stop()
+	Stop() // 幂等
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Wait(ctx); err != nil {
+		t.Fatalf("Wait after Stop: %v", err)
+	}
+}
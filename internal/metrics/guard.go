@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"sync"
+	"wht-order-api/internal/config"
+)
+
+const (
+	labelUnknown = "unknown"
+	labelOther   = "other"
+
+	defaultMaxLabelValues = 200
+)
+
+// labelGuard 高基数标签保护：每个标签最多保留 max 个取值，超出后统一归入 other，
+// 避免商户号、通道编码等取值无限增长撑爆时序数量（订单号等唯一值禁止作为标签）
+type labelGuard struct {
+	mu   sync.Mutex
+	seen map[string]map[string]struct{}
+}
+
+var guard = &labelGuard{seen: map[string]map[string]struct{}{}}
+
+func (g *labelGuard) value(label, v string) string {
+	if v == "" {
+		return labelUnknown
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	values := g.seen[label]
+	if values == nil {
+		values = map[string]struct{}{}
+		g.seen[label] = values
+	}
+	if _, ok := values[v]; ok {
+		return v
+	}
+	if len(values) >= maxLabelValues() {
+		return labelOther
+	}
+	values[v] = struct{}{}
+	return v
+}
+
+func maxLabelValues() int {
+	if n := config.C.Metrics.MaxLabelValues; n > 0 {
+		return n
+	}
+	return defaultMaxLabelValues
+}
@@ -0,0 +1,185 @@
+// Package metrics Prometheus 指标：HTTP 请求、下单、上游调用、回调、商户通知、MQ、结算、通道健康与连接池。
+// 标签只使用有限取值（路由模板、通道/接口编码、商户号等经 labelGuard 限量），禁止使用订单号等唯一值。
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wht"
+
+// Registry 独立注册表（不混入第三方库注册到默认注册表的指标）
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "http_requests_total",
+		Help: "HTTP 请求数（按路由模板、方法、HTTP 状态码、业务码）",
+	}, []string{"route", "method", "status", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "http_request_duration_seconds",
+		Help:    "HTTP 请求耗时",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"route", "method"})
+
+	ordersCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "orders_created_total",
+		Help: "创建订单数（按类型、通道、币种、商户）",
+	}, []string{"mode", "channel", "currency", "merchant"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "upstream_request_duration_seconds",
+		Help:    "上游下单调用耗时与结果（result 为 success 或平台错误码）",
+		Buckets: []float64{.1, .25, .5, 1, 2, 5, 10, 20, 30},
+	}, []string{"mode", "interface_code", "upstream", "result"})
+	upstreamRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "upstream_retries_total",
+		Help: "上游请求重试次数（DoWithRetry 首次之后的尝试）",
+	}, []string{"mode", "interface_code"})
+	upstreamFailover = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "upstream_failover_total",
+		Help: "下单时上游失败切换下一个通道产品的次数",
+	}, []string{"mode", "channel"})
+
+	callbackLag = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "callback_lag_seconds",
+		Help:    "上游回调消息从发布到开始处理的延迟",
+		Buckets: []float64{.1, .5, 1, 5, 15, 60, 300, 900, 3600},
+	}, []string{"mode"})
+	merchantNotify = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "merchant_notify_attempts_total",
+		Help: "通知商户次数（按类型、结果）",
+	}, []string{"mode", "result"})
+
+	consumerInflight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Name: "mq_consumer_inflight",
+		Help: "MQ 消费者正在处理的消息数",
+	}, []string{"consumer"})
+	publishFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "mq_publish_failures_total",
+		Help: "MQ 发布失败数（按生产者、原因）",
+	}, []string{"producer", "reason"})
+
+	settlementFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "settlement_failures_total",
+		Help: "结算失败数（按类型、币种）",
+	}, []string{"mode", "currency"})
+
+	upstreamFailCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Name: "channel_upstream_fail_count",
+		Help: "上游通道 5 分钟窗口内失败次数（≥3 权重减半；成功后清零，窗口过期前保持最近值）",
+	}, []string{"mode", "upstream", "channel"})
+	productCircuitOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Name: "pay_product_circuit_open",
+		Help: "支付产品熔断状态（1 熔断，0 正常）",
+	}, []string{"product"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		ordersCreated,
+		upstreamDuration, upstreamRetries, upstreamFailover,
+		callbackLag, merchantNotify,
+		consumerInflight, publishFailures,
+		settlementFailures,
+		upstreamFailCount, productCircuitOpen,
+	)
+}
+
+// Handler /metrics 输出
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTP 记录请求；route 为路由模板（未匹配路由传空），code 为响应业务码（未经统一响应返回传空）
+func ObserveHTTP(route, method string, status int, code string, d time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	if code == "" {
+		code = "none"
+	}
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(status), code).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(d.Seconds())
+}
+
+// OrderCreated 订单落库成功
+func OrderCreated(mode, channel, currency, merchant string) {
+	ordersCreated.WithLabelValues(mode,
+		guard.value("channel", channel), guard.value("currency", currency), guard.value("merchant", merchant)).Inc()
+}
+
+// ObserveUpstream 记录一次上游下单调用；result 为 success 或平台错误码
+func ObserveUpstream(mode, interfaceCode, upstream, result string, d time.Duration) {
+	upstreamDuration.WithLabelValues(mode,
+		guard.value("interface_code", interfaceCode), guard.value("upstream", upstream), result).Observe(d.Seconds())
+}
+
+// UpstreamRetried 上游请求在 DoWithRetry 中共尝试 attempts 次
+func UpstreamRetried(mode, interfaceCode string, attempts int) {
+	if attempts > 1 {
+		upstreamRetries.WithLabelValues(mode, guard.value("interface_code", interfaceCode)).Add(float64(attempts - 1))
+	}
+}
+
+// UpstreamFailover 当前通道产品失败，切换下一个
+func UpstreamFailover(mode, channel string) {
+	upstreamFailover.WithLabelValues(mode, guard.value("channel", channel)).Inc()
+}
+
+// ObserveCallbackLag 回调消息发布时间缺失时不记录
+func ObserveCallbackLag(mode string, published time.Time) {
+	if published.IsZero() {
+		return
+	}
+	callbackLag.WithLabelValues(mode).Observe(time.Since(published).Seconds())
+}
+
+// MerchantNotified 一次商户通知尝试
+func MerchantNotified(mode string, err error) {
+	result := "success"
+	if err != nil {
+		result = "fail"
+	}
+	merchantNotify.WithLabelValues(mode, result).Inc()
+}
+
+// TrackInflight 消息开始处理，返回处理结束时调用的函数
+func TrackInflight(consumer string) func() {
+	g := consumerInflight.WithLabelValues(consumer)
+	g.Inc()
+	return g.Dec
+}
+
+// PublishFailed MQ 发布失败
+func PublishFailed(producer, reason string) {
+	publishFailures.WithLabelValues(guard.value("producer", producer), reason).Inc()
+}
+
+// SettlementFailed 结算失败
+func SettlementFailed(mode, currency string) {
+	settlementFailures.WithLabelValues(mode, guard.value("currency", currency)).Inc()
+}
+
+// SetUpstreamFailCount 上游通道失败计数（清零时传 0）
+func SetUpstreamFailCount(mode, upstream, channel string, n int64) {
+	upstreamFailCount.WithLabelValues(mode, guard.value("upstream", upstream), guard.value("channel", channel)).Set(float64(n))
+}
+
+// SetProductCircuit 支付产品熔断状态
+func SetProductCircuit(productID int64, open bool) {
+	v := 0.0
+	if open {
+		v = 1
+	}
+	productCircuitOpen.WithLabelValues(guard.value("product", strconv.FormatInt(productID, 10))).Set(v)
+}
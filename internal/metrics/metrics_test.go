@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func useMaxLabelValues(t *testing.T, n int) {
+	t.Helper()
+	prev := config.C.Metrics
+	config.C.Metrics.MaxLabelValues = n
+	guard = &labelGuard{seen: map[string]map[string]struct{}{}}
+	t.Cleanup(func() {
+		config.C.Metrics = prev
+		guard = &labelGuard{seen: map[string]map[string]struct{}{}}
+	})
+}
+
+func TestLabelGuardCapsValues(t *testing.T) {
+	useMaxLabelValues(t, 3)
+
+	for i := 0; i < 3; i++ {
+		v := fmt.Sprintf("M%d", i)
+		if got := guard.value("merchant", v); got != v {
+			t.Fatalf("value(%s) = %s, want %s", v, got, v)
+		}
+	}
+	if got := guard.value("merchant", "M9"); got != labelOther {
+		t.Fatalf("over limit = %s, want %s", got, labelOther)
+	}
+	// 已登记的取值不受上限影响，各标签独立计数
+	if got := guard.value("merchant", "M1"); got != "M1" {
+		t.Fatalf("known value = %s, want M1", got)
+	}
+	if got := guard.value("channel", "BANK"); got != "BANK" {
+		t.Fatalf("other label = %s, want BANK", got)
+	}
+	if got := guard.value("merchant", ""); got != labelUnknown {
+		t.Fatalf("empty = %s, want %s", got, labelUnknown)
+	}
+}
+
+func TestOrderCreatedFoldsExcessMerchants(t *testing.T) {
+	useMaxLabelValues(t, 2)
+	ordersCreated.Reset()
+
+	for _, m := range []string{"M1", "M2", "M3", "M4", "M1"} {
+		OrderCreated("receive", "BANK", "INR", m)
+	}
+
+	if got := testutil.ToFloat64(ordersCreated.WithLabelValues("receive", "BANK", "INR", "M1")); got != 2 {
+		t.Fatalf("M1 = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(ordersCreated.WithLabelValues("receive", "BANK", "INR", labelOther)); got != 2 {
+		t.Fatalf("other = %v, want 2", got)
+	}
+	if n := testutil.CollectAndCount(ordersCreated); n != 3 {
+		t.Fatalf("series = %d, want 3", n)
+	}
+}
+
+func TestRecorders(t *testing.T) {
+	useMaxLabelValues(t, 10)
+	upstreamRetries.Reset()
+	merchantNotify.Reset()
+	consumerInflight.Reset()
+	callbackLag.Reset()
+
+	UpstreamRetried("payout", "PAY01", 1)
+	UpstreamRetried("payout", "PAY01", 3)
+	if got := testutil.ToFloat64(upstreamRetries.WithLabelValues("payout", "PAY01")); got != 2 {
+		t.Fatalf("retries = %v, want 2", got)
+	}
+
+	MerchantNotified("receive", errors.New("timeout"))
+	MerchantNotified("receive", nil)
+	if got := testutil.ToFloat64(merchantNotify.WithLabelValues("receive", "fail")); got != 1 {
+		t.Fatalf("notify fail = %v, want 1", got)
+	}
+
+	done := TrackInflight("receive")
+	if got := testutil.ToFloat64(consumerInflight.WithLabelValues("receive")); got != 1 {
+		t.Fatalf("inflight = %v, want 1", got)
+	}
+	done()
+	if got := testutil.ToFloat64(consumerInflight.WithLabelValues("receive")); got != 0 {
+		t.Fatalf("inflight after done = %v, want 0", got)
+	}
+
+	ObserveCallbackLag("payout", time.Time{})
+	if n := testutil.CollectAndCount(callbackLag); n != 0 {
+		t.Fatalf("lag without timestamp recorded %d series", n)
+	}
+	ObserveCallbackLag("payout", time.Now().Add(-2*time.Second))
+	if n := testutil.CollectAndCount(callbackLag); n != 1 {
+		t.Fatalf("lag series = %d, want 1", n)
+	}
+}
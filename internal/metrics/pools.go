@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"log"
+
+	"wht-order-api/internal/dal"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// RegisterPools 注册 MySQL（主库、订单库）与 Redis 连接池统计，需在 dal 初始化之后调用
+func RegisterPools() {
+	registerDB("main", dal.MainDB)
+	registerDB("order", dal.OrderDB)
+	if dal.RedisClient != nil {
+		Registry.MustRegister(newRedisPoolCollector(dal.RedisClient))
+	}
+}
+
+func registerDB(name string, db *gorm.DB) {
+	if db == nil {
+		return
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Printf("[METRICS] ⚠️ 获取 %s 连接池失败: %v", name, err)
+		return
+	}
+	Registry.MustRegister(collectors.NewDBStatsCollector(sqlDB, name))
+}
+
+// redisPoolCollector 抓取时读取 go-redis 连接池统计
+type redisPoolCollector struct {
+	client *redis.Client
+
+	hits, misses, timeouts       *prometheus.Desc
+	totalConns, idleConns, stale *prometheus.Desc
+}
+
+func newRedisPoolCollector(client *redis.Client) *redisPoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", name), help, nil, nil)
+	}
+	return &redisPoolCollector{
+		client:     client,
+		hits:       desc("hits_total", "从连接池取到空闲连接的次数"),
+		misses:     desc("misses_total", "连接池无空闲连接需新建的次数"),
+		timeouts:   desc("timeouts_total", "等待连接超时次数"),
+		totalConns: desc("conns", "连接总数"),
+		idleConns:  desc("idle_conns", "空闲连接数"),
+		stale:      desc("stale_conns_total", "被回收的过期连接数"),
+	}
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.stale
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(s.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(s.StaleConns))
+}
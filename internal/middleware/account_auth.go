@@ -9,6 +9,7 @@ import (
 	"time"
 	"wht-order-api/internal/dao"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/i18n"
 	"wht-order-api/internal/service"
 	"wht-order-api/internal/utils"
 
@@ -68,6 +69,8 @@ func AccountAuth() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		// 商户偏好语言（请求携带 Accept-Language 时以请求为准）
+		i18n.UseMerchantLanguage(c, merchant.Language)
 
 		// 获取请求IP
 		clientId := utils.GetClientIP(c)
@@ -113,3 +116,93 @@ func AccountAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AccountStatementAuth 中间件：验证 资金流水查询 POST JSON 请求签名
+func AccountStatementAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodPost || c.ContentType() != "application/json" {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid request params"})
+			c.Abort()
+			return
+		}
+
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "cannot read body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+		var req dto.AccountStatementReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("Account Statement:错误不能解析数据: %v", err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid request params"})
+			c.Abort()
+			return
+		}
+
+		// 校验请求时间
+		tsInt, err := utils.ParseTimestamp(req.TranDatetime)
+		if err != nil || !utils.IsTimestampValid(tsInt, 1*time.Minute) {
+			log.Printf("请求超时: %v", req.TranDatetime)
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "msg": "request timeout"})
+			c.Abort()
+			return
+		}
+
+		mainDao := dao.NewMainDao()
+		merchant, mErr := mainDao.GetMerchant(req.MerchantNo)
+		if mErr != nil || merchant.Status != 1 {
+			log.Printf("商户不存在: %v", req.MerchantNo)
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "unauthorized"})
+			c.Abort()
+			return
+		}
+		// 商户偏好语言（请求携带 Accept-Language 时以请求为准）
+		i18n.UseMerchantLanguage(c, merchant.Language)
+
+		clientId := utils.GetClientIP(c)
+		if clientId == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "Unauthorized,IP Error"})
+			c.Abort()
+			return
+		}
+
+		globalService := service.NewGlobalWhitelistService()
+		verifyService := service.NewVerifyIpWhitelistService()
+		if !globalService.IsGlobal(clientId) {
+			if !verifyService.VerifyIpWhitelist(clientId, merchant.MerchantID, 2) {
+				log.Printf("IP不允许访问: %+v,IP: %v", merchant.MerchantID, clientId)
+				c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": fmt.Sprintf("Unauthorized,IP[%v] is not whitelisted", clientId)})
+				c.Abort()
+				return
+			}
+		}
+
+		// 所有查询条件均参与签名（空值不参与）
+		params := map[string]string{
+			"version":       req.Version,
+			"merchant_no":   req.MerchantNo,
+			"currency":      req.Currency,
+			"types":         req.Types,
+			"start_time":    req.StartTime,
+			"end_time":      req.EndTime,
+			"order_no":      req.OrderNo,
+			"page":          req.Page,
+			"page_size":     req.PageSize,
+			"format":        req.Format,
+			"period":        req.Period,
+			"date":          req.Date,
+			"tran_datetime": req.TranDatetime,
+			"sign":          req.Sign,
+		}
+		if !utils.VerifySign(params, merchant.ApiKey) {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "invalid signature"})
+			c.Abort()
+			return
+		}
+		c.Set("account_statement_request", req)
+		c.Next()
+	}
+}
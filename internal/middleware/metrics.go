@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wht-order-api/internal/metrics"
+	"wht-order-api/internal/utils"
+)
+
+// Metrics 请求数与耗时指标（按路由模板统计，不使用原始 URL 避免路径参数撑大标签）
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		code := ""
+		if v, ok := c.Get(utils.ResponseCodeKey); ok {
+			if n, ok := v.(int); ok {
+				code = strconv.Itoa(n)
+			}
+		}
+		metrics.ObserveHTTP(c.FullPath(), c.Request.Method, c.Writer.Status(), code, time.Since(start))
+	}
+}
+
+// MetricsAuth 指标抓取仅允许内网来源
+func MetricsAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		for _, prefix := range []string{"127.0.0.1", "192.168.", "10.", "::1"} {
+			if strings.HasPrefix(ip, prefix) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/i18n"
+	"wht-order-api/internal/service"
+	"wht-order-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PayoutBatchCreateAuth 中间件：验证批量代付文件上传（multipart/form-data）签名
+func PayoutBatchCreateAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		if c.Request.Method != http.MethodPost || c.ContentType() != "multipart/form-data" {
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeInvalidParams))
+			c.Abort()
+			return
+		}
+
+		// 限制上传大小（文件 + 表单字段）
+		maxSize := config.C.Batch.MaxFileSize
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+64<<10)
+
+		var req dto.CreatePayoutBatchReq
+		if err := c.ShouldBind(&req); err != nil {
+			log.Printf("[PayoutBatch] 参数解析失败: %v", err)
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeInvalidParams))
+			c.Abort()
+			return
+		}
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.CustomError(c, constant.CodeBatchFileInvalid, i18n.Text(i18n.FromContext(c), i18n.TextBatchFileRequired)))
+			c.Abort()
+			return
+		}
+		if fileHeader.Size > maxSize {
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeBatchTooLarge))
+			c.Abort()
+			return
+		}
+		f, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.CustomError(c, constant.CodeBatchFileInvalid, i18n.Text(i18n.FromContext(c), i18n.TextBatchFileUnread)))
+			c.Abort()
+			return
+		}
+		content, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+		_ = f.Close()
+		if err != nil || int64(len(content)) > maxSize {
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeBatchTooLarge))
+			c.Abort()
+			return
+		}
+
+		// 校验文件摘要（file_hash 参与签名，防止文件被篡改）
+		sum := md5.Sum(content)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), req.FileHash) {
+			c.JSON(http.StatusBadRequest, utils.CustomError(c, constant.CodeBatchFileInvalid, i18n.Text(i18n.FromContext(c), i18n.TextBatchFileHash)))
+			c.Abort()
+			return
+		}
+
+		// 校验时间戳
+		tsInt, err := utils.ParseTimestamp(req.TranDatetime)
+		if err != nil || !utils.IsTimestampValid(tsInt, time.Minute) {
+			c.JSON(http.StatusForbidden, utils.Error(c, constant.CodeTimeout))
+			c.Abort()
+			return
+		}
+
+		// 校验商户
+		mainDao := dao.NewMainDao()
+		merchant, mErr := mainDao.GetMerchant(req.MerchantNo)
+		if mErr != nil {
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeMerchantAbnormal))
+			c.Abort()
+			return
+		}
+		if merchant.Status != 1 {
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeMerchantDisabled))
+			c.Abort()
+			return
+		}
+		// 商户偏好语言（请求携带 Accept-Language 时以请求为准）
+		i18n.UseMerchantLanguage(c, merchant.Language)
+
+		// 获取客户端 IP
+		clientId := utils.GetClientIP(c)
+		if clientId == "" {
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeUnauthorized))
+			c.Abort()
+			return
+		}
+		req.ClientId = clientId
+
+		// 验证 IP 白名单（类型 2 = 代付）
+		globalService := service.NewGlobalWhitelistService()
+		verifyService := service.NewVerifyIpWhitelistService()
+		if !globalService.IsGlobal(clientId) {
+			if !verifyService.VerifyIpWhitelist(clientId, merchant.MerchantID, 2) {
+				c.JSON(http.StatusUnauthorized, utils.CustomError(c, constant.CodeIPNotWhitelisted, i18n.Text(i18n.FromContext(c), i18n.TextIPNotWhitelisted, clientId)))
+				c.Abort()
+				return
+			}
+		}
+
+		// 构造签名参数（排除 sign）
+		params := map[string]string{
+			"version":          req.Version,
+			"merchant_no":      req.MerchantNo,
+			"batch_no":         req.BatchNo,
+			"tran_datetime":    req.TranDatetime,
+			"pay_type":         req.PayType,
+			"file_type":        req.FileType,
+			"file_hash":        req.FileHash,
+			"notify_url":       req.NotifyUrl,
+			"order_notify_url": req.OrderNotifyUrl,
+			"sign":             req.Sign,
+		}
+		if !utils.VerifySign(params, merchant.ApiKey) {
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeSignatureError))
+			c.Abort()
+			return
+		}
+
+		log.Printf("[PayoutBatch] ✅ 验签通过 商户号=%s 批次号=%s 文件大小=%d IP=%s 耗时=%v",
+			req.MerchantNo, req.BatchNo, len(content), clientId, time.Since(start))
+
+		c.Set("payout_batch_request", req)
+		c.Set("payout_batch_file", content)
+		c.Set("request_type", "payout_batch")
+		c.Next()
+	}
+}
+
+// PayoutBatchQueryAuth 中间件：验证 查询批量代付 POST JSON 请求签名
+func PayoutBatchQueryAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodPost || c.ContentType() != "application/json" {
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeInvalidParams))
+			c.Abort()
+			return
+		}
+
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeInternalError))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+		var req dto.QueryPayoutBatchReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("PayoutBatch Query:错误不能解析数据: %v", err.Error())
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeParamsFormatError))
+			c.Abort()
+			return
+		}
+
+		// 校验请求时间
+		tsInt, err := utils.ParseTimestamp(req.TranDatetime)
+		if err != nil || !utils.IsTimestampValid(tsInt, 1*time.Minute) {
+			c.JSON(http.StatusForbidden, utils.Error(c, constant.CodeTimeout))
+			c.Abort()
+			return
+		}
+
+		mainDao := dao.NewMainDao()
+		merchant, mErr := mainDao.GetMerchant(req.MerchantNo)
+		if mErr != nil {
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeMerchantAbnormal))
+			c.Abort()
+			return
+		}
+		if merchant.Status != 1 {
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeMerchantDisabled))
+			c.Abort()
+			return
+		}
+		// 商户偏好语言（请求携带 Accept-Language 时以请求为准）
+		i18n.UseMerchantLanguage(c, merchant.Language)
+
+		clientId := utils.GetClientIP(c)
+		if clientId == "" {
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeUnauthorized))
+			c.Abort()
+			return
+		}
+
+		globalService := service.NewGlobalWhitelistService()
+		verifyService := service.NewVerifyIpWhitelistService()
+		if !globalService.IsGlobal(clientId) {
+			if !verifyService.VerifyIpWhitelist(clientId, merchant.MerchantID, 2) {
+				c.JSON(http.StatusUnauthorized, utils.CustomError(c, constant.CodeIPNotWhitelisted, i18n.Text(i18n.FromContext(c), i18n.TextIPNotWhitelisted, clientId)))
+				c.Abort()
+				return
+			}
+		}
+
+		params := map[string]string{
+			"version":       req.Version,
+			"merchant_no":   req.MerchantNo,
+			"batch_no":      req.BatchNo,
+			"tran_datetime": req.TranDatetime,
+			"sign":          req.Sign,
+		}
+		if !utils.VerifySign(params, merchant.ApiKey) {
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeSignatureError))
+			c.Abort()
+			return
+		}
+		c.Set("payout_batch_query_request", req)
+		c.Set("request_type", "payout_batch")
+		c.Next()
+	}
+}
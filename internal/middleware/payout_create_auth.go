@@ -10,15 +10,19 @@ import (
 	"net/http"
 	"strings"
 	"time"
+	"wht-order-api/internal/beneficiary"
 	"wht-order-api/internal/constant"
 	"wht-order-api/internal/dao"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/i18n"
 	"wht-order-api/internal/notify"
+	"wht-order-api/internal/risk"
 	"wht-order-api/internal/service"
 	"wht-order-api/internal/system"
 	"wht-order-api/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 )
 
 // 统一错误响应 + 通知
@@ -64,7 +68,7 @@ func PayoutCreateAuth() gin.HandlerFunc {
 
 		bodyBytes, err := io.ReadAll(c.Request.Body)
 		if err != nil {
-			failPayoutWithTgNotify(c, dto.CreatePayoutOrderReq{}, http.StatusBadRequest, "无法读取请求体", utils.Error(constant.CodeInternalError))
+			failPayoutWithTgNotify(c, dto.CreatePayoutOrderReq{}, http.StatusBadRequest, "无法读取请求体", utils.Error(c, constant.CodeInternalError))
 			return
 		}
 		c.Request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
@@ -77,12 +81,12 @@ func PayoutCreateAuth() gin.HandlerFunc {
 				for _, fe := range ve {
 					errFields = append(errFields, map[string]string{
 						"field": fe.Field(),
-						"error": utils.ValidationMsg(fe),
+						"error": utils.ValidationMsg(c, fe),
 					})
 				}
 				failPayoutWithTgNotify(c, req, http.StatusBadRequest, "参数校验失败", gin.H{
 					"code":   400,
-					"msg":    "参数校验失败",
+					"msg":    i18n.Text(i18n.FromContext(c), i18n.TextValidationFailed),
 					"errors": errFields,
 				})
 				return
@@ -90,7 +94,7 @@ func PayoutCreateAuth() gin.HandlerFunc {
 
 			failPayoutWithTgNotify(c, req, http.StatusBadRequest, "请求格式错误", gin.H{
 				"code": 400,
-				"msg":  "JSON格式错误，请检查参数类型",
+				"msg":  i18n.Text(i18n.FromContext(c), i18n.TextJSONFormatError),
 			})
 			return
 		}
@@ -98,7 +102,7 @@ func PayoutCreateAuth() gin.HandlerFunc {
 		// 校验时间戳
 		tsInt, err := utils.ParseTimestamp(req.TranDatetime)
 		if err != nil || !utils.IsTimestampValid(tsInt, time.Minute) {
-			failPayoutWithTgNotify(c, req, http.StatusForbidden, "请求过期", utils.Error(constant.CodeTimeout))
+			failPayoutWithTgNotify(c, req, http.StatusForbidden, "请求过期", utils.Error(c, constant.CodeTimeout))
 			return
 		}
 
@@ -107,27 +111,23 @@ func PayoutCreateAuth() gin.HandlerFunc {
 		// 校验商户
 		merchant, mErr := mainDao.GetMerchant(req.MerchantNo)
 		if mErr != nil {
-			failPayoutWithTgNotify(c, req, http.StatusUnauthorized, "商户异常", utils.Error(constant.CodeMerchantAbnormal))
+			failPayoutWithTgNotify(c, req, http.StatusUnauthorized, "商户异常", utils.Error(c, constant.CodeMerchantAbnormal))
 			return
 		}
 		if merchant.Status != 1 {
-			failPayoutWithTgNotify(c, req, http.StatusUnauthorized, "商户未启用", utils.Error(constant.CodeMerchantDisabled))
+			failPayoutWithTgNotify(c, req, http.StatusUnauthorized, "商户未启用", utils.Error(c, constant.CodeMerchantDisabled))
 			return
 		}
+		// 商户偏好语言（请求携带 Accept-Language 时以请求为准）
+		i18n.UseMerchantLanguage(c, merchant.Language)
 
-		// 校验银行编码
-		if req.BankCode != "" {
-			if _, err := mainDao.QueryPlatformBankInfo(req.BankCode, merchant.Currency); err != nil {
-				msg := fmt.Sprintf("银行编码不存在: %s", req.BankCode)
-				failPayoutWithTgNotify(c, req, http.StatusBadRequest, msg, gin.H{"code": 400, "msg": msg})
-				return
-			}
-		}
+		// 订单币种以通道币种为准（商户每个币种独立钱包）
+		currency := orderCurrency(mainDao, req.PayType, merchant.Currency)
 
 		// 获取客户端 IP
 		clientId := utils.GetClientIP(c)
 		if clientId == "" {
-			failPayoutWithTgNotify(c, req, http.StatusUnauthorized, "无法识别客户端IP", utils.Error(constant.CodeUnauthorized))
+			failPayoutWithTgNotify(c, req, http.StatusUnauthorized, "无法识别客户端IP", utils.Error(c, constant.CodeUnauthorized))
 			return
 		}
 		req.ClientId = clientId
@@ -146,35 +146,18 @@ func PayoutCreateAuth() gin.HandlerFunc {
 		// =============================
 		// 虚拟币支付方式校验
 		// =============================
-		if utils.IsCryptoCurrency(merchant.Currency) {
-
-			if req.PayMethod == "" {
-				failPayoutWithTgNotify(c, req, http.StatusBadRequest,
-					"虚拟币支付方式(payMethod)不能为空",
-					gin.H{"code": constant.CodeInvalidParams, "msg": "payMethod不能为空"})
-				return
-			}
-
-			supported, ok := utils.CryptoPayMethods[strings.ToUpper(merchant.Currency)]
-			if !ok {
-				failPayoutWithTgNotify(c, req, http.StatusBadRequest,
-					"不支持的虚拟币币种",
-					gin.H{"code": constant.CodeInvalidParams, "msg": "不支持的虚拟币币种"})
+		if utils.IsCryptoCurrency(currency) {
+			if err := utils.CheckCryptoPayMethod(currency, req.PayMethod); err != nil {
+				failPayoutWithTgNotify(c, req, http.StatusBadRequest, err.Error(), gin.H{
+					"code":                 constant.CodeInvalidParams,
+					"msg":                  err.Error(),
+					"supported_pay_method": utils.CryptoPayMethods[strings.ToUpper(currency)],
+				})
 				return
 			}
-
-			if !utils.InArray(req.PayMethod, supported) {
-				failPayoutWithTgNotify(
-					c,
-					req,
-					http.StatusBadRequest,
-					fmt.Sprintf("虚拟币支付方式不支持，允许：%v", strings.Join(supported, ",")),
-					gin.H{
-						"code":                 constant.CodeInvalidParams,
-						"msg":                  "虚拟币payMethod不支持",
-						"supported_pay_method": supported,
-					},
-				)
+			// 金额精度不能超过代币精度
+			if err := utils.CheckCryptoAmount(req.PayMethod, parseAmount(req.Amount)); err != nil {
+				failPayoutWithTgNotify(c, req, http.StatusBadRequest, err.Error(), gin.H{"code": constant.CodeInvalidParams, "msg": err.Error()})
 				return
 			}
 		}
@@ -206,10 +189,53 @@ func PayoutCreateAuth() gin.HandlerFunc {
 
 		// 验签
 		if !utils.VerifySign(params, merchant.ApiKey) {
-			failPayoutWithTgNotify(c, req, http.StatusUnauthorized, "签名验证失败", utils.Error(constant.CodeSignatureError))
+			failPayoutWithTgNotify(c, req, http.StatusUnauthorized, "签名验证失败", utils.Error(c, constant.CodeSignatureError))
+			return
+		}
+
+		// 校验收款账户（按币种/支付方式校验账号格式，银行编码需存在于 w_bank_code）
+		if err := beneficiary.Check(mainDao, beneficiary.Account{
+			Currency:     currency,
+			PayMethod:    req.PayMethod,
+			AccNo:        req.AccNo,
+			AccName:      req.AccName,
+			BankCode:     req.BankCode,
+			CciNo:        req.CciNo,
+			AccountType:  req.AccountType,
+			IdentityType: req.IdentityType,
+			IdentityNum:  req.IdentityNum,
+			Address:      req.Address,
+			Network:      req.Network,
+		}); err != nil {
+			msg := fmt.Sprintf("收款账户校验失败: %v", err)
+			failPayoutWithTgNotify(c, req, http.StatusBadRequest, msg, beneficiaryError(c, err))
 			return
 		}
 
+		// 风控评估：拒绝直接返回，复核交由代付复核流程
+		riskResult := evaluateRisk(risk.Input{
+			OrderType:   2,
+			MerchantID:  merchant.MerchantID,
+			MerchantNo:  req.MerchantNo,
+			TranFlow:    req.TranFlow,
+			PayType:     req.PayType,
+			Currency:    currency,
+			Amount:      parseAmount(req.Amount),
+			IP:          clientId,
+			AccNo:       req.AccNo,
+			Email:       req.PayEmail,
+			Phone:       req.PayPhone,
+			IdentityNum: req.IdentityNum,
+		})
+		switch riskResult.Action {
+		case risk.ActionDeny:
+			failPayoutWithTgNotify(c, req, http.StatusForbidden, "风控拒绝: "+riskResult.Reason(), utils.Error(c, riskResult.Code()))
+			return
+		case risk.ActionReview:
+			req.RiskReview = riskResult.Reason()
+		}
+		defer settleRisk(c, riskResult)
+
 		log.Printf("[Payout] ✅ 验签通过 商户号=%s 通道=%s IP=%s 耗时=%v",
 			req.MerchantNo, req.PayType, clientId, time.Since(start))
 
@@ -218,3 +244,46 @@ func PayoutCreateAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// evaluateRisk 下单风控评估（引擎未初始化时放行）
+func evaluateRisk(in risk.Input) risk.Result {
+	engine := risk.Default()
+	if engine == nil {
+		return risk.Result{Action: risk.ActionAllow}
+	}
+	return engine.Evaluate(in)
+}
+
+// beneficiaryError 收款账户校验失败响应：按请求语言返回错误码文案，detail 仅指出字段，不透传校验器原始信息
+func beneficiaryError(c *gin.Context, err error) utils.Response {
+	var ve *beneficiary.ValidationError
+	if errors.As(err, &ve) {
+		return utils.CustomError(c, constant.CodeCardInvalid, i18n.Text(i18n.FromContext(c), i18n.TextInvalidField, ve.Field))
+	}
+	return utils.Error(c, constant.CodeCardInvalid)
+}
+
+// settleRisk 下单完成后确认风控计数：平台订单已创建则计入，否则回滚（下单失败不占用频率额度）
+func settleRisk(c *gin.Context, result risk.Result) {
+	if ctxVal, ok := c.Get("audit_ctx"); ok {
+		if auditCtx, ok := ctxVal.(*dto.AuditContextPayload); ok && auditCtx.PlatformOrderID != 0 {
+			result.Commit()
+			return
+		}
+	}
+	result.Release()
+}
+
+// parseAmount 解析请求金额（格式错误按 0 处理，由下单流程校验）
+func parseAmount(amount string) decimal.Decimal {
+	v, _ := decimal.NewFromString(strings.TrimSpace(amount))
+	return v
+}
+
+// orderCurrency 按通道编码解析订单币种，通道不存在时回退商户默认币种
+func orderCurrency(mainDao *dao.MainDao, payType, fallback string) string {
+	if ch, err := mainDao.GetSysChannel(payType); err == nil && ch != nil && ch.Currency != "" {
+		return ch.Currency
+	}
+	return fallback
+}
@@ -2,13 +2,14 @@ package middleware
 
 import (
 	"bytes"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"time"
+	"wht-order-api/internal/constant"
 	"wht-order-api/internal/dao"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/i18n"
 	"wht-order-api/internal/service"
 	"wht-order-api/internal/utils"
 
@@ -32,7 +33,7 @@ func PayoutQueryAuth() gin.HandlerFunc {
 		bodyBytes, err := io.ReadAll(c.Request.Body)
 		if err != nil {
 			log.Printf("收到代收查询数据: %+v\n", 222)
-			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "cannot read body"})
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeInternalError))
 			c.Abort()
 			return
 		}
@@ -44,7 +45,7 @@ func PayoutQueryAuth() gin.HandlerFunc {
 		var req dto.QueryPayoutOrderReq
 		if err := c.ShouldBindJSON(&req); err != nil {
 			log.Printf("Receive Query:错误不能解析数据: %v", err.Error())
-			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid request params"})
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeParamsFormatError))
 			c.Abort()
 			return
 		}
@@ -54,7 +55,7 @@ func PayoutQueryAuth() gin.HandlerFunc {
 		log.Printf("请求时间: %v", tsInt)
 		if err != nil || !utils.IsTimestampValid(tsInt, 1*time.Minute) {
 			log.Printf("请求超时: %v", req.TranDatetime)
-			c.JSON(http.StatusForbidden, gin.H{"code": 403, "msg": "request timeout"})
+			c.JSON(http.StatusForbidden, utils.Error(c, constant.CodeTimeout))
 			c.Abort()
 			return
 		}
@@ -64,16 +65,18 @@ func PayoutQueryAuth() gin.HandlerFunc {
 		merchant, _ := mainDao.GetMerchant(req.MerchantNo)
 		if merchant.Status != 1 {
 			log.Printf("商户不存在: %v", merchant)
-			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "unauthorized"})
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeMerchantDisabled))
 			c.Abort()
 			return
 		}
+		// 商户偏好语言（请求携带 Accept-Language 时以请求为准）
+		i18n.UseMerchantLanguage(c, merchant.Language)
 
 		// 获取请求IP
 		clientId := utils.GetClientIP(c)
 		if clientId == "" {
 			log.Printf("未获取到客户端IP: %+v", merchant)
-			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "Unauthorized,IP Error"})
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeUnauthorized))
 			c.Abort()
 			return
 		}
@@ -86,7 +89,7 @@ func PayoutQueryAuth() gin.HandlerFunc {
 			canAccess := verifyService.VerifyIpWhitelist(clientId, merchant.MerchantID, 2)
 			if !canAccess {
 				log.Printf("IP不允许访问: %+v,IP: %v", merchant, clientId)
-				c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": fmt.Sprintf("Unauthorized,IP[%v] is not whitelisted", clientId)})
+				c.JSON(http.StatusUnauthorized, utils.CustomError(c, constant.CodeIPNotWhitelisted, i18n.Text(i18n.FromContext(c), i18n.TextIPNotWhitelisted, clientId)))
 				c.Abort()
 				return
 			}
@@ -106,7 +109,7 @@ func PayoutQueryAuth() gin.HandlerFunc {
 		//log.Printf("待验参数: %v", params)
 		// 验签
 		if !utils.VerifySign(params, apiKey) {
-			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "invalid signature"})
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeSignatureError))
 			c.Abort()
 			return
 		}
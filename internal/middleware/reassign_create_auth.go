@@ -3,15 +3,16 @@ package middleware
 import (
 	"bytes"
 	"errors"
-	"fmt"
 	"github.com/go-playground/validator/v10"
 	"io"
 	"log"
 	"net/http"
 	"strings"
 	"time"
+	"wht-order-api/internal/constant"
 	"wht-order-api/internal/dao"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/i18n"
 	"wht-order-api/internal/service"
 	"wht-order-api/internal/utils"
 
@@ -35,7 +36,7 @@ func ReassignCreateAuth() gin.HandlerFunc {
 		bodyBytes, err := io.ReadAll(c.Request.Body)
 		if err != nil {
 			log.Printf("收到回调数据: %+v\n", 222)
-			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "cannot read body"})
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeInternalError))
 			c.Abort()
 			return
 		}
@@ -52,13 +53,13 @@ func ReassignCreateAuth() gin.HandlerFunc {
 				errFields := make([]map[string]string, 0)
 				for _, fe := range ve {
 					errFields = append(errFields, map[string]string{
-						"field": fe.Field(),              // 字段名
-						"error": utils.ValidationMsg(fe), // 错误信息
+						"field": fe.Field(),                 // 字段名
+						"error": utils.ValidationMsg(c, fe), // 错误信息
 					})
 				}
 				c.JSON(http.StatusBadRequest, gin.H{
 					"code":   400,
-					"msg":    "参数校验失败",
+					"msg":    i18n.Text(i18n.FromContext(c), i18n.TextValidationFailed),
 					"errors": errFields,
 				})
 				c.Abort()
@@ -66,9 +67,10 @@ func ReassignCreateAuth() gin.HandlerFunc {
 			}
 
 			// 非字段错误（如 JSON 格式错误）
+			log.Printf("Reassign Create:错误不能解析数据: %v", err)
 			c.JSON(http.StatusBadRequest, gin.H{
 				"code": 400,
-				"msg":  "请求格式错误: " + err.Error(),
+				"msg":  i18n.Text(i18n.FromContext(c), i18n.TextJSONFormatError),
 			})
 			c.Abort()
 			return
@@ -79,7 +81,7 @@ func ReassignCreateAuth() gin.HandlerFunc {
 		log.Printf("请求时间: %v", tsInt)
 		if err != nil || !utils.IsTimestampValid(tsInt, 1*time.Minute) {
 			log.Printf("请求超时: %v", req.TranDatetime)
-			c.JSON(http.StatusForbidden, gin.H{"code": 403, "msg": "request timeout"})
+			c.JSON(http.StatusForbidden, utils.Error(c, constant.CodeTimeout))
 			c.Abort()
 			return
 		}
@@ -90,19 +92,23 @@ func ReassignCreateAuth() gin.HandlerFunc {
 		merchant, _ := mainDao.GetMerchant(req.MerchantNo)
 		if merchant.Status != 1 {
 			log.Printf("商户不存在: %v", merchant)
-			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "unauthorized"})
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeMerchantDisabled))
 			c.Abort()
 			return
 		}
+		// 商户偏好语言（请求携带 Accept-Language 时以请求为准）
+		i18n.UseMerchantLanguage(c, merchant.Language)
+
+		// 订单币种以通道币种为准（商户每个币种独立钱包）
+		currency := orderCurrency(mainDao, req.PayType, merchant.Currency)
 
 		// 校验银行编码
 		if req.BankCode != "" {
 			// 根据接平台银行编码查询平台银行信息
-			_, pbErr := mainDao.QueryPlatformBankInfo(req.BankCode, merchant.Currency)
+			_, pbErr := mainDao.QueryPlatformBankInfo(req.BankCode, currency)
 			if pbErr != nil {
-				resultMsg := fmt.Sprintf("Bank code does not exist,%s", req.BankCode)
-				log.Printf(resultMsg)
-				c.JSON(http.StatusForbidden, gin.H{"code": 400, "msg": resultMsg})
+				log.Printf("Bank code does not exist,%s", req.BankCode)
+				c.JSON(http.StatusForbidden, utils.CustomError(c, constant.CodeCardInvalid, i18n.Text(i18n.FromContext(c), i18n.TextInvalidField, "bank_code")))
 				c.Abort()
 				return
 			}
@@ -112,7 +118,7 @@ func ReassignCreateAuth() gin.HandlerFunc {
 		clientId := utils.GetClientIP(c)
 		if clientId == "" {
 			log.Printf("未获取到客户端IP: %+v", merchant)
-			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "Unauthorized,IP Error"})
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeUnauthorized))
 			c.Abort()
 			return
 		}
@@ -125,7 +131,7 @@ func ReassignCreateAuth() gin.HandlerFunc {
 			canAccess := verifyService.VerifyIpWhitelist(clientId, merchant.MerchantID, 2)
 			if !canAccess {
 				log.Printf("IP不允许访问: %+v,IP: %v", merchant, clientId)
-				c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": fmt.Sprintf("Unauthorized,IP[%v] is not whitelisted", clientId)})
+				c.JSON(http.StatusUnauthorized, utils.CustomError(c, constant.CodeIPNotWhitelisted, i18n.Text(i18n.FromContext(c), i18n.TextIPNotWhitelisted, clientId)))
 				c.Abort()
 				return
 			}
@@ -134,13 +140,10 @@ func ReassignCreateAuth() gin.HandlerFunc {
 		// =====================================================================================
 		// 🟡【新增】虚拟货币业务 → 校验 pay_method（USDT / USDC / ...）
 		// =====================================================================================
-		if utils.IsCryptoCurrency(merchant.Currency) {
+		if utils.IsCryptoCurrency(currency) {
 
 			if req.PayMethod == "" {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"code": 400,
-					"msg":  "虚拟币业务必须提供 pay_method",
-				})
+				c.JSON(http.StatusBadRequest, utils.CustomError(c, constant.CodeInvalidParams, i18n.Text(i18n.FromContext(c), i18n.TextInvalidField, "pay_method")))
 				c.Abort()
 				return
 			}
@@ -148,23 +151,16 @@ func ReassignCreateAuth() gin.HandlerFunc {
 			// 解析 payMethod 获取 币种、链、协议
 			info, err := utils.ParsePayMethod(req.PayMethod)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"code": 400,
-					"msg":  fmt.Sprintf("不支持的 pay_method: %s", req.PayMethod),
-				})
+				log.Printf("不支持的 pay_method: %s, err: %v", req.PayMethod, err)
+				c.JSON(http.StatusBadRequest, utils.CustomError(c, constant.CodeInvalidParams, i18n.Text(i18n.FromContext(c), i18n.TextInvalidField, "pay_method")))
 				c.Abort()
 				return
 			}
 
 			// 币种必须一致
-			if info.Currency != strings.ToUpper(merchant.Currency) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"code": 400,
-					"msg": fmt.Sprintf(
-						"pay_method %s 和商户币种 %s 不匹配，请检查",
-						req.PayMethod, merchant.Currency,
-					),
-				})
+			if info.Currency != strings.ToUpper(currency) {
+				log.Printf("pay_method %s 和商户币种 %s 不匹配", req.PayMethod, currency)
+				c.JSON(http.StatusBadRequest, utils.CustomError(c, constant.CodeInvalidParams, i18n.Text(i18n.FromContext(c), i18n.TextInvalidField, "pay_method")))
 				c.Abort()
 				return
 			}
@@ -197,7 +193,7 @@ func ReassignCreateAuth() gin.HandlerFunc {
 		//log.Printf("待验参数: %v", params)
 		// 验签
 		if !utils.VerifySign(params, apiKey) {
-			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "invalid signature"})
+			c.JSON(http.StatusUnauthorized, utils.Error(c, constant.CodeSignatureError))
 			c.Abort()
 			return
 		}
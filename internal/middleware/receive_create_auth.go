@@ -14,7 +14,9 @@ import (
 	"wht-order-api/internal/constant"
 	"wht-order-api/internal/dao"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/i18n"
 	"wht-order-api/internal/notify"
+	"wht-order-api/internal/risk"
 	"wht-order-api/internal/service"
 	"wht-order-api/internal/system"
 	"wht-order-api/internal/utils"
@@ -56,7 +58,7 @@ func ReceiveCreateAuth() gin.HandlerFunc {
 		start := time.Now()
 
 		if c.Request.Method != http.MethodPost || c.ContentType() != "application/json" {
-			c.JSON(http.StatusBadRequest, utils.Error(constant.CodeInvalidParams))
+			c.JSON(http.StatusBadRequest, utils.Error(c, constant.CodeInvalidParams))
 			c.Abort()
 			return
 		}
@@ -67,7 +69,7 @@ func ReceiveCreateAuth() gin.HandlerFunc {
 		// 读取 Body
 		bodyBytes, err := io.ReadAll(c.Request.Body)
 		if err != nil {
-			failWithNotify(c, dto.CreateOrderReq{}, http.StatusBadRequest, "读取请求体失败", utils.Error(constant.CodeInternalError))
+			failWithNotify(c, dto.CreateOrderReq{}, http.StatusBadRequest, "读取请求体失败", utils.Error(c, constant.CodeInternalError))
 			return
 		}
 		c.Request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
@@ -82,12 +84,12 @@ func ReceiveCreateAuth() gin.HandlerFunc {
 				for _, fe := range ve {
 					errFields = append(errFields, map[string]string{
 						"field": fe.Field(),
-						"error": utils.ValidationMsg(fe),
+						"error": utils.ValidationMsg(c, fe),
 					})
 				}
 				failWithNotify(c, req, http.StatusBadRequest, "参数校验失败", gin.H{
 					"code":   400,
-					"msg":    "参数校验失败",
+					"msg":    i18n.Text(i18n.FromContext(c), i18n.TextValidationFailed),
 					"errors": errFields,
 				})
 				return
@@ -102,23 +104,23 @@ func ReceiveCreateAuth() gin.HandlerFunc {
 				}
 				paramErr := gin.H{
 					"code": 400,
-					"msg":  "参数类型错误",
+					"msg":  i18n.Text(i18n.FromContext(c), i18n.TextParamsTypeError),
 					"errors": []map[string]string{
-						{"field": fieldName, "error": "字段类型与预期不符"},
+						{"field": fieldName, "error": i18n.Text(i18n.FromContext(c), i18n.TextFieldTypeMismatch)},
 					},
 				}
 				failWithNotify(c, req, http.StatusBadRequest, "JSON类型错误", paramErr)
 				return
 			}
 
-			failWithNotify(c, req, http.StatusBadRequest, "JSON解析错误", utils.Error(constant.CodeParamsFormatError))
+			failWithNotify(c, req, http.StatusBadRequest, "JSON解析错误", utils.Error(c, constant.CodeParamsFormatError))
 			return
 		}
 
 		// 校验时间戳
 		tsInt, err := utils.ParseTimestamp(req.TranDatetime)
 		if err != nil || !utils.IsTimestampValid(tsInt, time.Minute) {
-			failWithNotify(c, req, http.StatusForbidden, "请求过期", utils.Error(constant.CodeTimeout))
+			failWithNotify(c, req, http.StatusForbidden, "请求过期", utils.Error(c, constant.CodeTimeout))
 			return
 		}
 
@@ -126,17 +128,19 @@ func ReceiveCreateAuth() gin.HandlerFunc {
 		mainDao := dao.NewMainDao()
 		merchant, mErr := mainDao.GetMerchant(req.MerchantNo)
 		if mErr != nil {
-			failWithNotify(c, req, http.StatusUnauthorized, "商户异常", utils.Error(constant.CodeMerchantAbnormal))
+			failWithNotify(c, req, http.StatusUnauthorized, "商户异常", utils.Error(c, constant.CodeMerchantAbnormal))
 			return
 		}
 		if merchant.Status != 1 {
-			failWithNotify(c, req, http.StatusUnauthorized, "商户未启用", utils.Error(constant.CodeMerchantDisabled))
+			failWithNotify(c, req, http.StatusUnauthorized, "商户未启用", utils.Error(c, constant.CodeMerchantDisabled))
 			return
 		}
+		// 商户偏好语言（请求携带 Accept-Language 时以请求为准）
+		i18n.UseMerchantLanguage(c, merchant.Language)
 
 		clientId := utils.GetClientIP(c)
 		if clientId == "" {
-			failWithNotify(c, req, http.StatusUnauthorized, "无法识别IP", utils.Error(constant.CodeUnauthorized))
+			failWithNotify(c, req, http.StatusUnauthorized, "无法识别IP", utils.Error(c, constant.CodeUnauthorized))
 			return
 		}
 		// 全局白名单校验
@@ -162,41 +166,24 @@ func ReceiveCreateAuth() gin.HandlerFunc {
 
 		// 通道是否启用
 		if !verifyService.VerifyChannelValid(merchant.MerchantID, req.PayType) {
-			failWithNotify(c, req, http.StatusUnauthorized, "通道未启用", utils.Error(constant.CodeChannelDisabled))
+			failWithNotify(c, req, http.StatusUnauthorized, "通道未启用", utils.Error(c, constant.CodeChannelDisabled))
 			return
 		}
 		// =============================
 		// 虚拟币支付方式校验
 		// =============================
 		if utils.IsCryptoCurrency(merchant.Currency) {
-
-			if req.PayMethod == "" {
-				failWithNotify(c, req, http.StatusBadRequest,
-					"虚拟币支付方式(payMethod)不能为空",
-					gin.H{"code": constant.CodeInvalidParams, "msg": "payMethod不能为空"})
-				return
-			}
-
-			supported, ok := utils.CryptoPayMethods[strings.ToUpper(merchant.Currency)]
-			if !ok {
-				failWithNotify(c, req, http.StatusBadRequest,
-					"不支持的虚拟币币种",
-					gin.H{"code": constant.CodeInvalidParams, "msg": "不支持的虚拟币币种"})
+			if err := utils.CheckCryptoPayMethod(merchant.Currency, req.PayMethod); err != nil {
+				failWithNotify(c, req, http.StatusBadRequest, err.Error(), gin.H{
+					"code":                 constant.CodeInvalidParams,
+					"msg":                  err.Error(),
+					"supported_pay_method": utils.CryptoPayMethods[strings.ToUpper(merchant.Currency)],
+				})
 				return
 			}
-
-			if !utils.InArray(req.PayMethod, supported) {
-				failWithNotify(
-					c,
-					req,
-					http.StatusBadRequest,
-					fmt.Sprintf("虚拟币支付方式不支持，允许：%v", strings.Join(supported, ",")),
-					gin.H{
-						"code":                 constant.CodeInvalidParams,
-						"msg":                  "虚拟币payMethod不支持",
-						"supported_pay_method": supported,
-					},
-				)
+			// 金额精度不能超过代币精度
+			if err := utils.CheckCryptoAmount(req.PayMethod, parseAmount(req.Amount)); err != nil {
+				failWithNotify(c, req, http.StatusBadRequest, err.Error(), gin.H{"code": constant.CodeInvalidParams, "msg": err.Error()})
 				return
 			}
 		}
@@ -225,9 +212,30 @@ func ReceiveCreateAuth() gin.HandlerFunc {
 		}
 		if !utils.VerifySign(params, merchant.ApiKey) {
 			go notify.SendTelegramMessage(merchant.TelegramGroupChatId, "签名验证失败")
-			failWithNotify(c, req, http.StatusUnauthorized, "签名验证失败", utils.Error(constant.CodeSignatureError))
+			failWithNotify(c, req, http.StatusUnauthorized, "签名验证失败", utils.Error(c, constant.CodeSignatureError))
+			return
+		}
+
+		// 风控评估：拒绝直接返回，复核放行并发布风控事件
+		riskResult := evaluateRisk(risk.Input{
+			OrderType:   1,
+			MerchantID:  merchant.MerchantID,
+			MerchantNo:  req.MerchantNo,
+			TranFlow:    req.TranFlow,
+			PayType:     req.PayType,
+			Currency:    orderCurrency(mainDao, req.PayType, merchant.Currency),
+			Amount:      parseAmount(req.Amount),
+			IP:          clientId,
+			AccNo:       req.AccNo,
+			Email:       req.PayEmail,
+			Phone:       req.PayPhone,
+			IdentityNum: req.IdentityNum,
+		})
+		if riskResult.Action == risk.ActionDeny {
+			failWithNotify(c, req, http.StatusForbidden, "风控拒绝: "+riskResult.Reason(), utils.Error(c, riskResult.Code()))
 			return
 		}
+		defer settleRisk(c, riskResult)
 
 		// ✅ 验证通过
 		log.Printf("[Receive] 校验通过: 商户号=%s, 通道=%s, 耗时=%v", req.MerchantNo, req.PayType, time.Since(start))
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/i18n"
+	"wht-order-api/internal/service"
+	"wht-order-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withdrawVerify 提现类接口公共校验：请求时间、商户状态、IP 白名单（代付类型）、签名
+// 校验失败时已写入响应并中断，返回 false
+func withdrawVerify(c *gin.Context, merchantNo, tranDatetime string, params map[string]string) bool {
+	tsInt, err := utils.ParseTimestamp(tranDatetime)
+	if err != nil || !utils.IsTimestampValid(tsInt, 1*time.Minute) {
+		log.Printf("请求超时: %v", tranDatetime)
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "msg": "request timeout"})
+		c.Abort()
+		return false
+	}
+
+	merchant, mErr := dao.NewMainDao().GetMerchant(merchantNo)
+	if mErr != nil || merchant == nil || merchant.Status != 1 {
+		log.Printf("商户不存在: %v", merchantNo)
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "unauthorized"})
+		c.Abort()
+		return false
+	}
+	// 商户偏好语言（请求携带 Accept-Language 时以请求为准）
+	i18n.UseMerchantLanguage(c, merchant.Language)
+
+	clientId := utils.GetClientIP(c)
+	if clientId == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "Unauthorized,IP Error"})
+		c.Abort()
+		return false
+	}
+	if !service.NewGlobalWhitelistService().IsGlobal(clientId) {
+		if !service.NewVerifyIpWhitelistService().VerifyIpWhitelist(clientId, merchant.MerchantID, 2) {
+			log.Printf("IP不允许访问: %+v,IP: %v", merchant.MerchantID, clientId)
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": fmt.Sprintf("Unauthorized,IP[%v] is not whitelisted", clientId)})
+			c.Abort()
+			return false
+		}
+	}
+
+	if !utils.VerifySign(params, merchant.ApiKey) {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "invalid signature"})
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// WithdrawCreateAuth 中间件：验证 商户提现下单 POST JSON 请求签名
+func WithdrawCreateAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.CreateWithdrawReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("Withdraw Create:错误不能解析数据: %v", err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid request params"})
+			c.Abort()
+			return
+		}
+		params := map[string]string{
+			"version":        req.Version,
+			"merchant_no":    req.MerchantNo,
+			"withdraw_no":    req.WithdrawNo,
+			"destination_id": req.DestinationId,
+			"pay_type":       req.PayType,
+			"amount":         req.Amount,
+			"notify_url":     req.NotifyUrl,
+			"tran_datetime":  req.TranDatetime,
+			"sign":           req.Sign,
+		}
+		if !withdrawVerify(c, req.MerchantNo, req.TranDatetime, params) {
+			return
+		}
+		req.ClientId = utils.GetClientIP(c)
+		c.Set("withdraw_request", req)
+		c.Set("request_type", "withdraw")
+		c.Next()
+	}
+}
+
+// WithdrawQueryAuth 中间件：验证 商户提现查询 POST JSON 请求签名
+func WithdrawQueryAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.QueryWithdrawReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("Withdraw Query:错误不能解析数据: %v", err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid request params"})
+			c.Abort()
+			return
+		}
+		params := map[string]string{
+			"version":       req.Version,
+			"merchant_no":   req.MerchantNo,
+			"withdraw_no":   req.WithdrawNo,
+			"tran_datetime": req.TranDatetime,
+			"sign":          req.Sign,
+		}
+		if !withdrawVerify(c, req.MerchantNo, req.TranDatetime, params) {
+			return
+		}
+		c.Set("withdraw_query_request", req)
+		c.Set("request_type", "withdraw")
+		c.Next()
+	}
+}
+
+// WithdrawDestinationSaveAuth 中间件：验证 提现账户新增/修改 POST JSON 请求签名
+func WithdrawDestinationSaveAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.WithdrawDestinationSaveReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("Withdraw Destination:错误不能解析数据: %v", err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid request params"})
+			c.Abort()
+			return
+		}
+		params := map[string]string{
+			"version":        req.Version,
+			"merchant_no":    req.MerchantNo,
+			"destination_id": req.DestinationId,
+			"currency":       req.Currency,
+			"label":          req.Label,
+			"acc_no":         req.AccNo,
+			"acc_name":       req.AccName,
+			"bank_code":      req.BankCode,
+			"bank_name":      req.BankName,
+			"pay_method":     req.PayMethod,
+			"account_type":   req.AccountType,
+			"cci_no":         req.CciNo,
+			"identity_num":   req.IdentityNum,
+			"status":         req.Status,
+			"tran_datetime":  req.TranDatetime,
+			"sign":           req.Sign,
+		}
+		if !withdrawVerify(c, req.MerchantNo, req.TranDatetime, params) {
+			return
+		}
+		c.Set("withdraw_destination_request", req)
+		c.Next()
+	}
+}
+
+// WithdrawDestinationListAuth 中间件：验证 提现账户列表 POST JSON 请求签名
+func WithdrawDestinationListAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.WithdrawDestinationListReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("Withdraw Destination List:错误不能解析数据: %v", err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "invalid request params"})
+			c.Abort()
+			return
+		}
+		params := map[string]string{
+			"version":       req.Version,
+			"merchant_no":   req.MerchantNo,
+			"tran_datetime": req.TranDatetime,
+			"sign":          req.Sign,
+		}
+		if !withdrawVerify(c, req.MerchantNo, req.TranDatetime, params) {
+			return
+		}
+		c.Set("withdraw_destination_list_request", req)
+		c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package mainmodel
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// FeeScheduleTier 阶梯档位
+type FeeScheduleTier struct {
+	From  decimal.Decimal `json:"from"`  // 档位起点（含）
+	Rate  decimal.Decimal `json:"rate"`  // 费率（%）
+	Fixed decimal.Decimal `json:"fixed"` // 单笔固定费用
+}
+
+// FeeScheduleTiers 阶梯档位列表（JSON 存储）
+type FeeScheduleTiers []FeeScheduleTier
+
+func (t *FeeScheduleTiers) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("FeeScheduleTiers scan failed: %v", value)
+	}
+	return json.Unmarshal(bytes, t)
+}
+
+func (t FeeScheduleTiers) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// FeeSchedule 阶梯/交易量费率方案
+// 同一系统通道+币种下，按 适用方 → 归属ID → 支付方式 匹配，未命中时使用通道平铺费率
+type FeeSchedule struct {
+	ID           uint64           `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                   // 主键
+	Party        int8             `gorm:"column:party;not null" json:"party"`                             // 适用方 1商户 2代理 3上游
+	OwnerID      uint64           `gorm:"column:owner_id;not null;default:0" json:"ownerId"`              // 商户ID/代理ID/上游通道产品ID，0 通用
+	SysChannelID int64            `gorm:"column:sys_channel_id;not null" json:"sysChannelId"`             // 系统通道ID
+	Currency     string           `gorm:"column:currency;size:10;not null" json:"currency"`               // 币种
+	PayMethod    string           `gorm:"column:pay_method;size:30;not null;default:''" json:"payMethod"` // 支付方式，空为通用
+	TierMode     int8             `gorm:"column:tier_mode;not null" json:"tierMode"`                      // 1按单笔金额 2按当月交易量
+	Tiers        FeeScheduleTiers `gorm:"column:tiers;type:json" json:"tiers"`                            // 阶梯档位
+	MinFee       decimal.Decimal  `gorm:"column:min_fee;type:decimal(18,4);not null" json:"minFee"`       // 保底手续费，0 不限
+	MaxFee       decimal.Decimal  `gorm:"column:max_fee;type:decimal(18,4);not null" json:"maxFee"`       // 封顶手续费，0 不限
+	Status       int8             `gorm:"column:status;not null;default:1" json:"status"`                 // 0停用 1启用
+	Remark       string           `gorm:"column:remark;size:255" json:"remark"`                           // 备注
+	UpdateTime   *time.Time       `gorm:"column:update_time" json:"updateTime"`                           // 更新时间
+}
+
+func (FeeSchedule) TableName() string {
+	return "w_fee_schedule"
+}
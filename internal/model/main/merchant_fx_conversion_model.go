@@ -0,0 +1,25 @@
+package mainmodel
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// MerchantFxConversion 商户钱包换汇记录
+type MerchantFxConversion struct {
+	ID           uint64          `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                      // 主键
+	ConversionNo string          `gorm:"column:conversion_no;size:64;not null" json:"conversion_no"`        // 换汇单号（幂等键）
+	UID          uint64          `gorm:"column:uid;not null" json:"uid"`                                    // 商户ID
+	FromCurrency string          `gorm:"column:from_currency;size:10;not null" json:"from_currency"`        // 源币种
+	ToCurrency   string          `gorm:"column:to_currency;size:10;not null" json:"to_currency"`            // 目标币种
+	FromAmount   decimal.Decimal `gorm:"column:from_amount;type:decimal(18,4);not null" json:"from_amount"` // 转出金额
+	ToAmount     decimal.Decimal `gorm:"column:to_amount;type:decimal(18,4);not null" json:"to_amount"`     // 转入金额
+	Rate         decimal.Decimal `gorm:"column:rate;type:decimal(20,8);not null" json:"rate"`               // 汇率（1 源币种 = rate 目标币种）
+	Operator     string          `gorm:"column:operator;size:30;not null" json:"operator"`                  // 操作人
+	Remark       string          `gorm:"column:remark;size:255" json:"remark"`                              // 备注
+	CreateTime   time.Time       `gorm:"column:create_time;not null" json:"create_time"`                    // 创建时间
+}
+
+func (MerchantFxConversion) TableName() string {
+	return "w_merchant_fx_conversion"
+}
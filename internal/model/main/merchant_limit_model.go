@@ -0,0 +1,36 @@
+package mainmodel
+
+import "github.com/shopspring/decimal"
+
+// MerchantLimit 商户累计限额（m_id=0 为币种默认规则，金额/笔数为 0 表示不限）
+type MerchantLimit struct {
+	ID               uint64          `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                                  // 主键
+	MID              uint64          `gorm:"column:m_id;not null" json:"mId"`                                               // 商户ID，0 为默认
+	Currency         string          `gorm:"column:currency;size:10;not null" json:"currency"`                              // 币种
+	OrderType        int8            `gorm:"column:order_type;not null" json:"orderType"`                                   // 订单类型 1代收 2代付
+	DailyAmount      decimal.Decimal `gorm:"column:daily_amount;type:decimal(18,4);not null" json:"dailyAmount"`            // 单日累计金额上限
+	DailyCount       int64           `gorm:"column:daily_count;not null" json:"dailyCount"`                                 // 单日累计笔数上限
+	MonthlyAmount    decimal.Decimal `gorm:"column:monthly_amount;type:decimal(18,4);not null" json:"monthlyAmount"`        // 单月累计金额上限
+	MonthlyCount     int64           `gorm:"column:monthly_count;not null" json:"monthlyCount"`                             // 单月累计笔数上限
+	PayerDailyAmount decimal.Decimal `gorm:"column:payer_daily_amount;type:decimal(18,4);not null" json:"payerDailyAmount"` // 单个收款账户单日金额上限（仅代付）
+	PayerDailyCount  int64           `gorm:"column:payer_daily_count;not null" json:"payerDailyCount"`                      // 单个收款账户单日笔数上限（仅代付）
+	Status           int8            `gorm:"column:status;not null;default:1" json:"status"`                                // 状态 0停用 1启用
+}
+
+func (MerchantLimit) TableName() string {
+	return "w_merchant_limit"
+}
+
+// ChannelLimit 系统通道单日累计限额（金额/笔数为 0 表示不限）
+type ChannelLimit struct {
+	ID           uint64          `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                       // 主键
+	SysChannelID int64           `gorm:"column:sys_channel_id;not null" json:"sysChannelId"`                 // 系统通道ID
+	Currency     string          `gorm:"column:currency;size:10;not null" json:"currency"`                   // 币种
+	DailyAmount  decimal.Decimal `gorm:"column:daily_amount;type:decimal(18,4);not null" json:"dailyAmount"` // 单日累计金额上限
+	DailyCount   int64           `gorm:"column:daily_count;not null" json:"dailyCount"`                      // 单日累计笔数上限
+	Status       int8            `gorm:"column:status;not null;default:1" json:"status"`                     // 状态 0停用 1启用
+}
+
+func (ChannelLimit) TableName() string {
+	return "w_channel_limit"
+}
@@ -12,6 +12,7 @@ type Merchant struct {
 	ApiKey              string `gorm:"column:api_key"`
 	ApiIp               string `gorm:"column:api_ip"`
 	TelegramGroupChatId string `gorm:"telegram_group_chat_id"` //飞机群ID
+	Language            string `gorm:"column:language"`        // 响应语言偏好 zh/en/es/pt，为空按 Accept-Language
 }
 
 func (Merchant) TableName() string { return "w_merchant" }
@@ -0,0 +1,17 @@
+package mainmodel
+
+import "github.com/shopspring/decimal"
+
+// MerchantPayoutApproval 商户级代付复核规则（优先于币种默认阈值）
+type MerchantPayoutApproval struct {
+	ID                   uint64          `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                       // 主键
+	MID                  uint64          `gorm:"column:m_id;not null" json:"mId"`                                    // 商户ID
+	Currency             string          `gorm:"column:currency;size:10;not null" json:"currency"`                   // 币种
+	Threshold            decimal.Decimal `gorm:"column:threshold;type:decimal(18,4);not null" json:"threshold"`      // 复核阈值（0 表示不按金额复核）
+	FirstTimeBeneficiary int8            `gorm:"column:first_time_beneficiary;not null" json:"firstTimeBeneficiary"` // 首次出款账户是否复核 0否 1是
+	Status               int8            `gorm:"column:status;not null;default:1" json:"status"`                     // 状态 0停用 1启用
+}
+
+func (MerchantPayoutApproval) TableName() string {
+	return "w_merchant_payout_approval"
+}
@@ -0,0 +1,48 @@
+package mainmodel
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// MerchantWithdrawDestination 商户预登记提现账户
+type MerchantWithdrawDestination struct {
+	ID          uint64    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`             // 主键
+	MID         uint64    `gorm:"column:m_id;not null" json:"m_id"`                         // 商户ID
+	Currency    string    `gorm:"column:currency;size:10;not null" json:"currency"`         // 币种
+	Label       string    `gorm:"column:label;size:50" json:"label"`                        // 账户别名
+	AccountNo   string    `gorm:"column:account_no;size:128;not null" json:"account_no"`    // 收款账号/虚拟币地址
+	AccountName string    `gorm:"column:account_name;size:64;not null" json:"account_name"` // 收款人
+	BankCode    string    `gorm:"column:bank_code;size:30" json:"bank_code"`                // 平台银行编码
+	BankName    string    `gorm:"column:bank_name;size:64" json:"bank_name"`                // 银行名称
+	PayMethod   string    `gorm:"column:pay_method;size:30" json:"pay_method"`              // 支付方式（虚拟币为链）
+	AccountType string    `gorm:"column:account_type;size:30" json:"account_type"`          // 账户类型
+	CciNo       string    `gorm:"column:cci_no;size:64" json:"cci_no"`                      // 银行间账户
+	IdentityNum string    `gorm:"column:identity_num;size:30" json:"identity_num"`          // 证件号码
+	Status      int8      `gorm:"column:status;not null;default:1" json:"status"`           // 0停用 1启用
+	ActiveAt    time.Time `gorm:"column:active_at;not null" json:"active_at"`               // 冷却期结束时间，之后方可提现
+	CreateTime  time.Time `gorm:"column:create_time;not null" json:"create_time"`           // 创建时间
+	UpdateTime  time.Time `gorm:"column:update_time;not null" json:"update_time"`           // 更新时间
+}
+
+func (MerchantWithdrawDestination) TableName() string {
+	return "w_merchant_withdraw_destination"
+}
+
+// MerchantWithdrawFee 提现手续费规则（m_id=0 为币种默认规则）
+type MerchantWithdrawFee struct {
+	ID        uint64          `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                    // 主键
+	MID       uint64          `gorm:"column:m_id;not null;default:0" json:"m_id"`                      // 商户ID，0 为默认
+	Currency  string          `gorm:"column:currency;size:10;not null" json:"currency"`                // 币种
+	FeeRate   decimal.Decimal `gorm:"column:fee_rate;type:decimal(10,4);not null" json:"fee_rate"`     // 费率（%）
+	FixedFee  decimal.Decimal `gorm:"column:fixed_fee;type:decimal(18,4);not null" json:"fixed_fee"`   // 单笔固定费用
+	MinFee    decimal.Decimal `gorm:"column:min_fee;type:decimal(18,4);not null" json:"min_fee"`       // 最低手续费
+	MaxFee    decimal.Decimal `gorm:"column:max_fee;type:decimal(18,4);not null" json:"max_fee"`       // 最高手续费，0 不限
+	MinAmount decimal.Decimal `gorm:"column:min_amount;type:decimal(18,4);not null" json:"min_amount"` // 单笔最低提现
+	MaxAmount decimal.Decimal `gorm:"column:max_amount;type:decimal(18,4);not null" json:"max_amount"` // 单笔最高提现，0 不限
+	Status    int8            `gorm:"column:status;not null;default:1" json:"status"`                  // 0停用 1启用
+}
+
+func (MerchantWithdrawFee) TableName() string {
+	return "w_merchant_withdraw_fee"
+}
@@ -12,6 +12,7 @@ type MoneyLog struct {
 	OrderNo     string          `gorm:"column:order_no;size:50;not null" json:"orderNo"`                  // 平台订单编码
 	MOrderNo    string          `gorm:"column:m_order_no;size:50;not null" json:"mOrderNo"`               // 商户订单编码
 	Type        int8            `gorm:"column:type;not null" json:"type"`                                 // 收益类型
+	Attempt     int             `gorm:"column:attempt;not null;default:0" json:"attempt"`                 // 改派次数（同一订单多次调整冻结时区分，默认 0）
 	Operator    string          `gorm:"column:operator;size:30;not null" json:"operator"`                 // 操作者
 	Currency    string          `gorm:"column:currency;size:10;not null" json:"currency"`                 // 币种
 	Description string          `gorm:"column:description;size:30" json:"description"`                    // 备注
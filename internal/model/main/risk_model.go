@@ -0,0 +1,37 @@
+package mainmodel
+
+import "time"
+
+// RiskRule 风控规则（params 为各规则类型的 JSON 参数）
+type RiskRule struct {
+	ID         uint64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`      // 主键
+	Name       string     `gorm:"column:name;size:64;not null" json:"name"`          // 规则名称
+	RuleType   string     `gorm:"column:rule_type;size:32;not null" json:"ruleType"` // 规则类型 blacklist/velocity/amount_anomaly/round_amount/structuring
+	OrderType  int8       `gorm:"column:order_type;not null" json:"orderType"`       // 订单类型 0全部 1代收 2代付
+	MID        uint64     `gorm:"column:m_id;not null" json:"mId"`                   // 商户ID，0 为全部商户
+	Params     string     `gorm:"column:params;type:json" json:"params"`             // 规则参数
+	Action     int8       `gorm:"column:action;not null" json:"action"`              // 命中动作 1放行(仅计分) 2复核 3拒绝
+	Score      int        `gorm:"column:score;not null" json:"score"`                // 命中分值
+	Priority   int        `gorm:"column:priority;not null" json:"priority"`          // 优先级，越小越先执行
+	Status     int8       `gorm:"column:status;not null;default:1" json:"status"`    // 状态 0停用 1启用
+	UpdateTime *time.Time `gorm:"column:update_time" json:"updateTime"`              // 更新时间
+}
+
+func (RiskRule) TableName() string {
+	return "w_risk_rule"
+}
+
+// RiskBlacklist 风控黑名单
+type RiskBlacklist struct {
+	ID         uint64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`   // 主键
+	Field      string     `gorm:"column:field;size:32;not null" json:"field"`     // 字段 ip/acc_no/email/phone/identity_num
+	Value      string     `gorm:"column:value;size:128;not null" json:"value"`    // 值
+	MID        uint64     `gorm:"column:m_id;not null" json:"mId"`                // 商户ID，0 为全局
+	Remark     string     `gorm:"column:remark;size:255" json:"remark"`           // 备注
+	Status     int8       `gorm:"column:status;not null;default:1" json:"status"` // 状态 0停用 1启用
+	UpdateTime *time.Time `gorm:"column:update_time" json:"updateTime"`           // 更新时间
+}
+
+func (RiskBlacklist) TableName() string {
+	return "w_risk_blacklist"
+}
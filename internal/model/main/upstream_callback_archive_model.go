@@ -0,0 +1,30 @@
+package mainmodel
+
+import "time"
+
+// 回调归档处理结果
+const (
+	CallbackArchiveAccepted    int8 = 1 // 已入队
+	CallbackArchiveRejected    int8 = 2 // 校验未通过（IP/签名/解析）
+	CallbackArchiveEnqueueFail int8 = 3 // 入队失败
+)
+
+// UpstreamCallbackArchive 上游回调原始报文归档（用于争议处理与对账核查）
+type UpstreamCallbackArchive struct {
+	ID            uint64    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                // 主键
+	InterfaceCode string    `gorm:"column:interface_code;size:64;not null" json:"interfaceCode"` // 上游接口编码
+	Mode          string    `gorm:"column:mode;size:16;not null" json:"mode"`                    // receive / payout
+	MOrderID      string    `gorm:"column:m_order_id;size:64" json:"mOrderId"`                   // 平台交易订单号（解析成功才有）
+	UpOrderID     string    `gorm:"column:up_order_id;size:64" json:"upOrderId"`                 // 上游流水号
+	UpStatus      string    `gorm:"column:up_status;size:16" json:"upStatus"`                    // 回调状态
+	RemoteIP      string    `gorm:"column:remote_ip;size:64;not null" json:"remoteIp"`           // 实际来源 IP
+	Headers       string    `gorm:"column:headers;type:text" json:"headers"`                     // 原始请求头（JSON）
+	Body          string    `gorm:"column:body;type:mediumtext" json:"body"`                     // 原始请求体
+	Result        int8      `gorm:"column:result;not null" json:"result"`                        // 处理结果 1已入队 2拒绝 3入队失败
+	Remark        string    `gorm:"column:remark;size:255" json:"remark"`                        // 拒绝/失败原因
+	CreateTime    time.Time `gorm:"column:create_time" json:"createTime"`                        // 接收时间
+}
+
+func (UpstreamCallbackArchive) TableName() string {
+	return "w_upstream_callback_archive"
+}
@@ -0,0 +1,18 @@
+package mainmodel
+
+// UpstreamErrorMapping 上游错误码映射（interface_code='*' 为全部接口通用；upstream_code 与 msg_keyword 至少填一个）
+type UpstreamErrorMapping struct {
+	ID            uint64 `gorm:"column:id;primaryKey;autoIncrement" json:"id"`                // 主键
+	InterfaceCode string `gorm:"column:interface_code;size:64;not null" json:"interfaceCode"` // 上游接口编码，* 为通用
+	UpstreamCode  string `gorm:"column:upstream_code;size:64;not null" json:"upstreamCode"`   // 上游返回码，为空表示不限
+	MsgKeyword    string `gorm:"column:msg_keyword;size:128;not null" json:"msgKeyword"`      // 上游返回信息关键字（忽略大小写），为空表示不限
+	Code          int    `gorm:"column:code;not null" json:"code"`                            // 映射后的平台错误码（3xxx/4xxx）
+	Retryable     int8   `gorm:"column:retryable;not null;default:0" json:"retryable"`        // 是否可重试/切换通道 0否 1是
+	Priority      int    `gorm:"column:priority;not null;default:0" json:"priority"`          // 优先级，越小越先匹配
+	Remark        string `gorm:"column:remark;size:255" json:"remark"`                        // 备注
+	Status        int8   `gorm:"column:status;not null;default:1" json:"status"`              // 状态 0停用 1启用
+}
+
+func (UpstreamErrorMapping) TableName() string {
+	return "w_upstream_error_mapping"
+}
@@ -0,0 +1,27 @@
+package ordermodel
+
+import "time"
+
+// 回调去重记录状态
+const (
+	CallbackDedupProcessing int8 = 0 // 处理中（处理失败会删除记录以便重试）
+	CallbackDedupDone       int8 = 1 // 已处理
+)
+
+// CallbackDedupM 上游回调去重：(类型, 交易订单号, 回调状态, 上游消息ID) 唯一；
+// 终态回调（成功/失败）额外占用 TerminalUpOrderID，同一笔交易只允许一个终态回调
+type CallbackDedupM struct {
+	ID                uint64    `gorm:"primaryKey;autoIncrement;column:id" json:"id"`                                                                                             // 主键ID
+	Mode              string    `gorm:"column:mode;type:varchar(16);not null;uniqueIndex:uniq_callback_msg,priority:1;uniqueIndex:uniq_callback_terminal,priority:1" json:"mode"` // receive/payout
+	UpOrderID         uint64    `gorm:"column:up_order_id;not null;uniqueIndex:uniq_callback_msg,priority:2" json:"upOrderId"`                                                    // 交易订单号
+	Status            string    `gorm:"column:status;type:varchar(8);not null;uniqueIndex:uniq_callback_msg,priority:3" json:"status"`                                            // 上游回调状态 0000/0001/0005
+	MsgID             string    `gorm:"column:msg_id;type:varchar(128);not null;uniqueIndex:uniq_callback_msg,priority:4" json:"msgId"`                                           // 上游消息ID（上游流水号）
+	TerminalUpOrderID *uint64   `gorm:"column:terminal_up_order_id;uniqueIndex:uniq_callback_terminal,priority:2" json:"terminalUpOrderId"`                                       // 终态回调占位，非终态为 NULL
+	State             int8      `gorm:"column:state;not null" json:"state"`                                                                                                       // 0处理中 1已处理
+	CreateTime        time.Time `gorm:"column:create_time;not null" json:"createTime"`                                                                                            // 创建时间
+	UpdateTime        time.Time `gorm:"column:update_time;not null" json:"updateTime"`                                                                                            // 最近认领时间
+}
+
+func (CallbackDedupM) TableName() string {
+	return "p_callback_dedup"
+}
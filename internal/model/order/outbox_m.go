@@ -0,0 +1,30 @@
+package ordermodel
+
+import "time"
+
+// 发件箱事件状态
+const (
+	OutboxPending int8 = 0 // 待投递
+	OutboxSent    int8 = 1 // 已投递（broker 已确认）
+	OutboxDead    int8 = 2 // 超过最大重试次数，需人工处理
+)
+
+// OutboxEventM 事务发件箱：与订单/状态变更在同一事务写入，由 outbox.Relay 异步投递到 MQ
+type OutboxEventM struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`                         // 主键ID
+	EventID     string     `gorm:"column:event_id;type:varchar(64);not null;uniqueIndex" json:"eventId"` // 事件ID（消费端按此去重）
+	Topic       string     `gorm:"column:topic;type:varchar(64);not null" json:"topic"`                  // 生产者配置名（rabbitmq.producers.name）
+	AggregateID uint64     `gorm:"column:aggregate_id;not null;index" json:"aggregateId"`                // 关联平台订单号
+	Payload     string     `gorm:"column:payload;type:json;not null" json:"payload"`                     // 消息体
+	Status      int8       `gorm:"column:status;not null" json:"status"`                                 // 0待投递 1已投递 2死信
+	Attempts    int        `gorm:"column:attempts;not null" json:"attempts"`                             // 投递失败次数
+	NextRetryAt time.Time  `gorm:"column:next_retry_at;not null" json:"nextRetryAt"`                     // 下次投递时间
+	LastError   string     `gorm:"column:last_error;type:varchar(255)" json:"lastError"`                 // 最近一次投递错误
+	SentTime    *time.Time `gorm:"column:sent_time" json:"sentTime"`                                     // 投递成功时间
+	CreateTime  time.Time  `gorm:"column:create_time;not null" json:"createTime"`                        // 创建时间
+	UpdateTime  *time.Time `gorm:"column:update_time" json:"updateTime"`                                 // 更新时间
+}
+
+func (OutboxEventM) TableName() string {
+	return "p_outbox"
+}
@@ -0,0 +1,58 @@
+package ordermodel
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PayoutStatusAwaitingApproval 代付订单状态：待复核（资金已冻结，未提交上游）
+const PayoutStatusAwaitingApproval int8 = 7
+
+// 复核状态
+const (
+	PayoutApprovalPending  int8 = 0 // 待复核
+	PayoutApprovalApproved int8 = 1 // 已通过
+	PayoutApprovalRejected int8 = 2 // 已驳回
+	PayoutApprovalExpired  int8 = 3 // 超时自动驳回
+)
+
+// PayoutApprovalM 大额代付复核记录（兼作复核审计）
+type PayoutApprovalM struct {
+	ID              uint64          `gorm:"primaryKey;autoIncrement;column:id" json:"id"`                         // 主键ID
+	OrderID         uint64          `gorm:"column:order_id;not null;uniqueIndex" json:"orderId"`                  // 平台订单ID
+	MID             uint64          `gorm:"column:m_id;not null" json:"mId"`                                      // 商户ID
+	MOrderID        string          `gorm:"column:m_order_id;type:varchar(50);not null" json:"mOrderId"`          // 商户订单号
+	Currency        string          `gorm:"column:currency;type:varchar(10);not null" json:"currency"`            // 币种
+	Amount          decimal.Decimal `gorm:"column:amount;type:decimal(18,4);not null" json:"amount"`              // 订单金额
+	FreezeAmount    decimal.Decimal `gorm:"column:freeze_amount;type:decimal(18,4);not null" json:"freezeAmount"` // 冻结金额
+	Reason          string          `gorm:"column:reason;type:varchar(255)" json:"reason"`                        // 触发复核原因
+	Payload         string          `gorm:"column:payload;type:json" json:"payload"`                              // 商户原始请求
+	Status          int8            `gorm:"column:status;not null" json:"status"`                                 // 0待复核 1通过 2驳回 3超时
+	OrderCreateTime time.Time       `gorm:"column:order_create_time;not null" json:"orderCreateTime"`             // 订单创建时间（用于定位分表）
+	ExpireAt        time.Time       `gorm:"column:expire_at;not null" json:"expireAt"`                            // 超时时间
+	Operator        string          `gorm:"column:operator;type:varchar(50)" json:"operator"`                     // 复核人
+	OperatorIP      string          `gorm:"column:operator_ip;type:varchar(64)" json:"operatorIp"`                // 复核IP
+	Remark          string          `gorm:"column:remark;type:varchar(255)" json:"remark"`                        // 复核备注
+	DecideTime      *time.Time      `gorm:"column:decide_time" json:"decideTime"`                                 // 复核时间
+	CreateTime      time.Time       `gorm:"column:create_time;not null" json:"createTime"`                        // 创建时间
+	UpdateTime      *time.Time      `gorm:"column:update_time" json:"updateTime"`                                 // 更新时间
+}
+
+func (PayoutApprovalM) TableName() string {
+	return "p_out_approval"
+}
+
+// PayoutBeneficiaryM 商户已成功出款的收款账户（用于识别首次出款）
+type PayoutBeneficiaryM struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement;column:id" json:"id"`              // 主键ID
+	MID          uint64    `gorm:"column:m_id;not null" json:"mId"`                           // 商户ID
+	Currency     string    `gorm:"column:currency;type:varchar(10);not null" json:"currency"` // 币种
+	AccNo        string    `gorm:"column:acc_no;type:varchar(64);not null" json:"accNo"`      // 收款账号
+	FirstOrderID uint64    `gorm:"column:first_order_id;not null" json:"firstOrderId"`        // 首笔成功订单
+	CreateTime   time.Time `gorm:"column:create_time;not null" json:"createTime"`             // 创建时间
+}
+
+func (PayoutBeneficiaryM) TableName() string {
+	return "p_out_beneficiary"
+}
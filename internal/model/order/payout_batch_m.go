@@ -0,0 +1,67 @@
+package ordermodel
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// 批次状态
+const (
+	PayoutBatchStatusProcessing int8 = 0 // 处理中
+	PayoutBatchStatusCompleted  int8 = 1 // 已完成（全部行已处理）
+)
+
+// 批次行状态
+const (
+	PayoutBatchItemPending   int8 = 0 // 待处理
+	PayoutBatchItemSubmitted int8 = 1 // 已创建订单并提交上游
+	PayoutBatchItemFailed    int8 = 2 // 创建失败或上游拒绝（冻结已释放）
+	PayoutBatchItemManual    int8 = 3 // 已创建订单，上游全部失败转人工
+)
+
+// PayoutBatchM 批量代付批次
+type PayoutBatchM struct {
+	BatchID        uint64          `gorm:"column:batch_id;primaryKey" json:"batchId"`                                                 // 平台批次ID
+	MID            uint64          `gorm:"column:m_id;not null;index:uniq_merchant_batch,unique" json:"mId"`                          // 商户ID
+	BatchNo        string          `gorm:"column:batch_no;type:varchar(64);not null;index:uniq_merchant_batch,unique" json:"batchNo"` // 商户批次号
+	PayType        string          `gorm:"column:pay_type;type:varchar(30);not null" json:"payType"`                                  // 通道编码
+	Currency       string          `gorm:"column:currency;type:char(3);not null" json:"currency"`                                     // 货币代码
+	FileType       string          `gorm:"column:file_type;type:varchar(10);not null" json:"fileType"`                                // 文件类型
+	FileHash       string          `gorm:"column:file_hash;type:varchar(32);not null" json:"fileHash"`                                // 文件MD5
+	TotalCount     int             `gorm:"column:total_count;not null" json:"totalCount"`                                             // 总笔数
+	TotalAmount    decimal.Decimal `gorm:"column:total_amount;type:decimal(18,4);not null" json:"totalAmount"`                        // 总金额
+	FreezeAmount   decimal.Decimal `gorm:"column:freeze_amount;type:decimal(18,4);not null" json:"freezeAmount"`                      // 整批冻结金额
+	SubmittedCount int             `gorm:"column:submitted_count;not null" json:"submittedCount"`                                     // 已提交笔数
+	FailCount      int             `gorm:"column:fail_count;not null" json:"failCount"`                                               // 失败笔数
+	Status         int8            `gorm:"column:status;not null" json:"status"`                                                      // 批次状态
+	NotifyURL      string          `gorm:"column:notify_url;type:varchar(255)" json:"notifyUrl"`                                      // 批次完成回调地址
+	OrderNotifyURL string          `gorm:"column:order_notify_url;type:varchar(255)" json:"orderNotifyUrl"`                           // 单笔订单回调地址
+	NotifyStatus   int8            `gorm:"column:notify_status;not null" json:"notifyStatus"`                                         // 批次回调状态 0未通知 1成功 2失败
+	ClientIP       string          `gorm:"column:client_ip;type:varchar(32)" json:"clientIp"`                                         // 提交IP
+	CreateTime     time.Time       `gorm:"column:create_time;not null" json:"createTime"`                                             // 创建时间
+	FinishTime     *time.Time      `gorm:"column:finish_time" json:"finishTime"`                                                      // 完成时间
+}
+
+func (PayoutBatchM) TableName() string {
+	return "p_out_batch"
+}
+
+// PayoutBatchItemM 批量代付批次行
+type PayoutBatchItemM struct {
+	ID           uint64          `gorm:"primaryKey;autoIncrement;column:id" json:"id"`                         // 主键ID
+	BatchID      uint64          `gorm:"column:batch_id;not null;index:uniq_batch_line,unique" json:"batchId"` // 平台批次ID
+	LineNo       int             `gorm:"column:line_no;not null;index:uniq_batch_line,unique" json:"lineNo"`   // 行号
+	TranFlow     string          `gorm:"column:tran_flow;type:varchar(64);not null" json:"tranFlow"`           // 商户订单号
+	Amount       decimal.Decimal `gorm:"column:amount;type:decimal(18,4);not null" json:"amount"`              // 订单金额
+	FreezeAmount decimal.Decimal `gorm:"column:freeze_amount;type:decimal(18,4);not null" json:"freezeAmount"` // 预留冻结金额
+	Payload      string          `gorm:"column:payload;type:text;not null" json:"payload"`                     // 行原始数据JSON
+	OrderID      uint64          `gorm:"column:order_id;not null" json:"orderId"`                              // 平台订单ID
+	Status       int8            `gorm:"column:status;not null" json:"status"`                                 // 行状态
+	ErrorMsg     string          `gorm:"column:error_msg;type:varchar(255)" json:"errorMsg"`                   // 失败原因
+	CreateTime   time.Time       `gorm:"column:create_time;not null" json:"createTime"`                        // 创建时间
+	UpdateTime   *time.Time      `gorm:"column:update_time" json:"updateTime"`                                 // 更新时间
+}
+
+func (PayoutBatchItemM) TableName() string {
+	return "p_out_batch_item"
+}
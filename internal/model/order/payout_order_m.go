@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/shopspring/decimal"
 	"time"
+	"wht-order-api/internal/dto"
 )
 
 type PayoutSettleSnapshot struct {
@@ -25,6 +26,14 @@ type PayoutSettleSnapshot struct {
 	UpstreamCost   decimal.Decimal `json:"upstreamCost"`
 	AgentIncome    decimal.Decimal `json:"agentIncome"`
 	PlatformProfit decimal.Decimal `json:"platformProfit"`
+
+	// 费率方案命中档位（平铺费率时为空）
+	MerchantFeeTier string `json:"merchantFeeTier,omitempty"`
+	AgentFeeTier    string `json:"agentFeeTier,omitempty"`
+	UpFeeTier       string `json:"upFeeTier,omitempty"`
+
+	// 多级代理分润明细（AgentIncome 为各级分润合计）
+	AgentLegs []dto.AgentLeg `json:"agentLegs,omitempty"`
 }
 
 func (s *PayoutSettleSnapshot) Scan(value interface{}) error {
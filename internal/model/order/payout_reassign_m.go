@@ -0,0 +1,30 @@
+package ordermodel
+
+import "time"
+
+// 改派链路结果
+const (
+	ReassignResultPending    int8 = 0 // 已提交上游，等待回调
+	ReassignResultSuccess    int8 = 1 // 上游回调成功
+	ReassignResultFail       int8 = 2 // 上游回调失败
+	ReassignResultSubmitFail int8 = 3 // 提交上游失败
+)
+
+// PayoutReassignM 代付订单改派链路（每次上游尝试一条记录，attempt=0 为首次下单）
+type PayoutReassignM struct {
+	ID           uint64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`                            // 主键ID
+	OrderID      uint64     `gorm:"column:order_id;not null;index:uniq_order_attempt,unique" json:"orderId"` // 平台订单ID
+	Attempt      int        `gorm:"column:attempt;not null;index:uniq_order_attempt,unique" json:"attempt"`  // 尝试序号
+	UpOrderID    uint64     `gorm:"column:up_order_id;not null" json:"upOrderId"`                            // 上游交易订单ID
+	SupplierID   int64      `gorm:"column:supplier_id;not null" json:"supplierId"`                           // 上游供应商ID
+	UpChannelID  int64      `gorm:"column:up_channel_id;not null" json:"upChannelId"`                        // 上游通道ID
+	UpstreamCode string     `gorm:"column:up_channel_code;type:varchar(30)" json:"upChannelCode"`            // 上游通道编码
+	Result       int8       `gorm:"column:result;not null" json:"result"`                                    // 结果 0处理中 1成功 2失败 3提交失败
+	Reason       string     `gorm:"column:reason;type:varchar(255)" json:"reason"`                           // 失败原因
+	CreateTime   time.Time  `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"createTime"` // 创建时间
+	UpdateTime   *time.Time `gorm:"column:update_time" json:"updateTime"`                                    // 更新时间
+}
+
+func (PayoutReassignM) TableName() string {
+	return "p_out_order_reassign"
+}
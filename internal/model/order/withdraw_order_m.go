@@ -0,0 +1,60 @@
+package ordermodel
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 提现订单状态（与代付订单状态编码保持一致，便于统一转换对外状态）
+const (
+	WithdrawStatusProcessing int8 = 1 // 处理中（资金已冻结，已提交上游）
+	WithdrawStatusSuccess    int8 = 2 // 提现成功
+	WithdrawStatusFailed     int8 = 3 // 提现失败（冻结资金已退回）
+	WithdrawStatusManual     int8 = 6 // 人工处理（上游全部提交失败，资金保持冻结）
+)
+
+// WithdrawOrderM 商户提现订单（按月分表 p_withdraw_order_YYYYMM_pN）
+type WithdrawOrderM struct {
+	OrderID       uint64          `gorm:"column:order_id;primaryKey;not null" json:"orderId"`                   // 平台提现单号
+	MID           uint64          `gorm:"column:m_id;not null" json:"mId"`                                      // 商户ID
+	WithdrawNo    string          `gorm:"column:withdraw_no;type:varchar(64);not null" json:"withdrawNo"`       // 商户提现单号
+	DestinationID uint64          `gorm:"column:destination_id;not null" json:"destinationId"`                  // 提现账户ID
+	Currency      string          `gorm:"column:currency;type:varchar(10);not null" json:"currency"`            // 币种
+	Amount        decimal.Decimal `gorm:"column:amount;type:decimal(18,4);not null" json:"amount"`              // 到账金额
+	Fee           decimal.Decimal `gorm:"column:fee;type:decimal(18,4);not null" json:"fee"`                    // 提现手续费
+	FreezeAmount  decimal.Decimal `gorm:"column:freeze_amount;type:decimal(18,4);not null" json:"freezeAmount"` // 冻结金额（到账金额+手续费）
+	Cost          decimal.Decimal `gorm:"column:cost;type:decimal(18,4);not null" json:"cost"`                  // 上游成本
+	Profit        decimal.Decimal `gorm:"column:profit;type:decimal(18,4);not null" json:"profit"`              // 平台利润
+	PayType       string          `gorm:"column:pay_type;type:varchar(30);not null" json:"payType"`             // 系统通道编码
+	AccountNo     string          `gorm:"column:account_no;type:varchar(128);not null" json:"accountNo"`        // 收款账号/地址
+	AccountName   string          `gorm:"column:account_name;type:varchar(64);not null" json:"accountName"`     // 收款人
+	BankCode      string          `gorm:"column:bank_code;type:varchar(30)" json:"bankCode"`                    // 银行编码
+	PayMethod     string          `gorm:"column:pay_method;type:varchar(30)" json:"payMethod"`                  // 支付方式/链
+	SupplierID    int64           `gorm:"column:supplier_id;not null" json:"supplierId"`                        // 上游供应商ID
+	UpChannelID   int64           `gorm:"column:up_channel_id;not null" json:"upChannelId"`                     // 上游通道ID
+	UpOrderID     *uint64         `gorm:"column:up_order_id" json:"upOrderId"`                                  // 上游交易订单ID
+	Status        int8            `gorm:"column:status;not null" json:"status"`                                 // 订单状态
+	NotifyURL     string          `gorm:"column:notify_url;type:varchar(255)" json:"notifyUrl"`                 // 商户回调地址
+	NotifyStatus  int8            `gorm:"column:notify_status;not null" json:"notifyStatus"`                    // 0未通知 1成功 2失败
+	Remark        string          `gorm:"column:remark;type:varchar(255)" json:"remark"`                        // 备注
+	ClientIP      string          `gorm:"column:client_ip;type:varchar(64)" json:"clientIp"`                    // 下单IP
+	CreateTime    time.Time       `gorm:"column:create_time;not null" json:"createTime"`                        // 创建时间
+	UpdateTime    *time.Time      `gorm:"column:update_time" json:"updateTime"`                                 // 更新时间
+	FinishTime    *time.Time      `gorm:"column:finish_time" json:"finishTime"`                                 // 完成时间
+	NotifyTime    *time.Time      `gorm:"column:notify_time" json:"notifyTime"`                                 // 通知时间
+}
+
+// WithdrawOrderIndexM 商户提现单号索引（订单库，不分表，用于幂等与按商户单号定位分表）
+type WithdrawOrderIndexM struct {
+	ID             uint64    `gorm:"primaryKey;autoIncrement;column:id" json:"id"`                   // 主键ID
+	MID            uint64    `gorm:"column:m_id;not null" json:"mId"`                                // 商户ID
+	WithdrawNo     string    `gorm:"column:withdraw_no;type:varchar(64);not null" json:"withdrawNo"` // 商户提现单号
+	OrderID        uint64    `gorm:"column:order_id;not null" json:"orderId"`                        // 平台提现单号
+	OrderTableName string    `gorm:"column:order_table_name;type:varchar(50)" json:"orderTableName"` // 提现订单分表
+	CreateTime     time.Time `gorm:"column:create_time;not null" json:"createTime"`                  // 创建时间
+}
+
+func (WithdrawOrderIndexM) TableName() string {
+	return "p_withdraw_order_index"
+}
@@ -0,0 +1,23 @@
+package mq
+
+import (
+	"github.com/streadway/amqp"
+	"strconv"
+	"wht-order-api/internal/callback"
+	"wht-order-api/internal/metrics"
+)
+
+// dedupCallback 回调处理前按 (交易订单号, 回调状态, 上游消息ID) 原子认领，重复消息确认为空操作。
+// 上游消息ID 取上游流水号，缺失时使用 AMQP message_id；交易订单号非法时交由回调处理返回永久错误
+func dedupCallback(mode string, d amqp.Delivery, mOrderID, status, upOrderNo string, fn func() error) error {
+	metrics.ObserveCallbackLag(mode, d.Timestamp)
+	upOrderID, err := strconv.ParseUint(mOrderID, 10, 64)
+	if err != nil {
+		return fn()
+	}
+	msgID := upOrderNo
+	if msgID == "" {
+		msgID = d.MessageId
+	}
+	return callback.NewCallbackDedup().Process(mode, upOrderID, status, msgID, fn)
+}
@@ -0,0 +1,131 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"github.com/streadway/amqp"
+	"sync"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/dal"
+)
+
+var errNotConnected = errors.New("RabbitMQ 连接不可用")
+
+// pubChannel 发布专用通道（确认模式 + mandatory 退回监听），同一时刻只被一个发布方持有
+type pubChannel struct {
+	ch       *amqp.Channel
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+	closed   chan *amqp.Error
+	seq      uint64 // 本通道已发布序号，与确认的 DeliveryTag 一一对应
+}
+
+func openPubChannel() (*pubChannel, error) {
+	// 断线时不在发布路径上阻塞等待重连（由 dal 后台自愈），调用方转入本地缓冲
+	if !dal.IsConnected() {
+		return nil, errNotConnected
+	}
+	conn := dal.GetConnection()
+	if conn == nil {
+		return nil, errNotConnected
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("创建发布通道失败: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("开启发布确认失败: %w", err)
+	}
+	return &pubChannel{
+		ch:       ch,
+		confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		returns:  ch.NotifyReturn(make(chan amqp.Return, 1)),
+		closed:   ch.NotifyClose(make(chan *amqp.Error, 1)),
+	}, nil
+}
+
+func (c *pubChannel) alive() bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+// channelPool 发布通道池：与消费者使用的 dal.GetChannel() 隔离，单个通道异常不影响其他发布
+type channelPool struct {
+	idle  chan *pubChannel
+	slots chan struct{} // 已创建通道的令牌，限制通道总数
+}
+
+func newChannelPool(size int) *channelPool {
+	return &channelPool{
+		idle:  make(chan *pubChannel, size),
+		slots: make(chan struct{}, size),
+	}
+}
+
+var (
+	poolOnce sync.Once
+	pubPool  *channelPool
+)
+
+// pool 首次使用时按配置创建通道池并启动缓冲补发
+func pool() *channelPool {
+	poolOnce.Do(func() {
+		pubPool = newChannelPool(config.C.RabbitMQ.Publisher.PoolSize)
+		go pending.flushLoop()
+	})
+	return pubPool
+}
+
+// get 优先复用空闲通道，未达上限时新建，全部占用时等待至超时
+func (p *channelPool) get(timeout time.Duration) (*pubChannel, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case c := <-p.idle:
+			if c.alive() {
+				return c, nil
+			}
+			p.discard(c)
+			continue
+		default:
+		}
+
+		select {
+		case c := <-p.idle:
+			if c.alive() {
+				return c, nil
+			}
+			p.discard(c)
+		case p.slots <- struct{}{}:
+			c, err := openPubChannel()
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			return c, nil
+		case <-timer.C:
+			return nil, errors.New("获取发布通道超时")
+		}
+	}
+}
+
+// put 归还通道；异常通道（超时、确认错位、已关闭）直接丢弃，下次按需重建
+func (p *channelPool) put(c *pubChannel, healthy bool) {
+	if !healthy || !c.alive() {
+		p.discard(c)
+		return
+	}
+	p.idle <- c
+}
+
+func (p *channelPool) discard(c *pubChannel) {
+	_ = c.ch.Close()
+	<-p.slots
+}
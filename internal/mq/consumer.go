@@ -1,13 +1,56 @@
 package mq
 
 import (
+	"fmt"
 	"github.com/streadway/amqp"
 	"log"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
 	"wht-order-api/internal/config"
 	"wht-order-api/internal/dal"
+	"wht-order-api/internal/event"
+	"wht-order-api/internal/metrics"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/system"
+	"wht-order-api/internal/utils"
 )
 
-func StartConsumer(name string, handler func(amqp.Delivery)) {
+const (
+	headerRetryCount = "x-retry-count" // 已重试次数
+	headerLastError  = "x-last-error"  // 最近一次处理错误
+	dlqRoutingKey    = "dlq"
+	reviewRoutingKey = "review"
+	maxErrorHeader   = 512
+)
+
+// Handler 消息处理函数：返回 nil 确认消费；event.Permanent 标记的错误直接转死信，
+// event.ManualReview 转人工复核队列，其余错误按梯度延迟重试
+type Handler func(amqp.Delivery) error
+
+// 正在消费的 consumer tag，退出时逐个取消订阅
+var (
+	activeMu   sync.Mutex
+	activeTags = map[string]*amqp.Channel{}
+)
+
+// consumer 单个消费者的重试拓扑：
+//
+//	<queue>.retry（direct 交换机）
+//	  ├─ <delay> → <queue>.retry.<delay>（x-message-ttl，过期后经默认交换机死信回 <queue>）
+//	  ├─ dlq     → <queue>.dlq
+//	  └─ review  → <queue>.review（状态冲突，人工复核）
+//
+// 主队列参数保持不变（已存在的队列改参数会声明失败），失败消息由消费端带上重试次数头重新发布到重试交换机后再确认原消息
+type consumer struct {
+	cfg           config.RabbitConsumerCfg
+	handler       Handler
+	retryExchange string
+	republish     func(target *config.RabbitProducerCfg, msg amqp.Publishing) error
+}
+
+func StartConsumer(name string, handler Handler) {
 	var cfg *config.RabbitConsumerCfg
 	for _, c := range config.C.RabbitMQ.Consumers {
 		if c.Name == name {
@@ -68,10 +111,17 @@ func StartConsumer(name string, handler func(amqp.Delivery)) {
 		return
 	}
 
+	c := newConsumer(*cfg, handler)
+	if err := c.declareRetryTopology(ch); err != nil {
+		log.Printf("❌ 重试队列声明失败 [%s]: %v", cfg.Queue, err)
+		return
+	}
+
 	// 开始消费
+	tag := fmt.Sprintf("%s-%d", name, os.Getpid())
 	msgs, err := ch.Consume(
 		cfg.Queue,
-		"",
+		tag,
 		false, false, false, false, nil,
 	)
 	if err != nil {
@@ -79,9 +129,184 @@ func StartConsumer(name string, handler func(amqp.Delivery)) {
 		return
 	}
 
-	log.Printf("✅ [%s] 正在监听队列: %s", name, cfg.Queue)
+	log.Printf("✅ [%s] 正在监听队列: %s 并发=%d 重试梯度=%v", name, cfg.Queue, cfg.Workers, cfg.RetryDelays)
+
+	activeMu.Lock()
+	activeTags[tag] = ch
+	activeMu.Unlock()
+	defer func() {
+		activeMu.Lock()
+		delete(activeTags, tag)
+		activeMu.Unlock()
+	}()
+
+	// 取消订阅后 msgs 在已预取的消息投递完后关闭，worker 处理完手头消息再退出
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range msgs {
+				c.handle(d)
+			}
+		}()
+	}
+	wg.Wait()
+	log.Printf("🛑 [%s] 已停止消费队列: %s", name, cfg.Queue)
+}
+
+// StopConsumers 取消全部订阅（不再接收新消息），在途消息由 StartConsumer 处理完后返回
+func StopConsumers() {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	for tag, ch := range activeTags {
+		if err := ch.Cancel(tag, false); err != nil {
+			log.Printf("⚠️ 取消订阅失败 [%s]: %v", tag, err)
+		}
+	}
+}
+
+func newConsumer(cfg config.RabbitConsumerCfg, handler Handler) *consumer {
+	return &consumer{
+		cfg:           cfg,
+		handler:       handler,
+		retryExchange: cfg.Queue + ".retry",
+		republish:     publishDelivery,
+	}
+}
+
+func (c *consumer) retryQueue(delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", c.cfg.Queue, delay)
+}
+
+func (c *consumer) deadQueue() string {
+	return c.cfg.Queue + ".dlq"
+}
+
+func (c *consumer) reviewQueue() string {
+	return c.cfg.Queue + ".review"
+}
+
+// declareRetryTopology 声明重试交换机、各延迟梯度的 TTL 队列、死信队列与人工复核队列
+func (c *consumer) declareRetryTopology(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(c.retryExchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("ExchangeDeclare [%s]: %w", c.retryExchange, err)
+	}
+	for _, delay := range c.cfg.RetryDelays {
+		q := c.retryQueue(delay)
+		if _, err := ch.QueueDeclare(q, true, false, false, false, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": c.cfg.Queue,
+		}); err != nil {
+			return fmt.Errorf("QueueDeclare [%s]: %w", q, err)
+		}
+		if err := ch.QueueBind(q, delay.String(), c.retryExchange, false, nil); err != nil {
+			return fmt.Errorf("QueueBind [%s]: %w", q, err)
+		}
+	}
+	if _, err := ch.QueueDeclare(c.deadQueue(), true, false, false, false, nil); err != nil {
+		return fmt.Errorf("QueueDeclare [%s]: %w", c.deadQueue(), err)
+	}
+	if err := ch.QueueBind(c.deadQueue(), dlqRoutingKey, c.retryExchange, false, nil); err != nil {
+		return fmt.Errorf("QueueBind [%s]: %w", c.deadQueue(), err)
+	}
+	if _, err := ch.QueueDeclare(c.reviewQueue(), true, false, false, false, nil); err != nil {
+		return fmt.Errorf("QueueDeclare [%s]: %w", c.reviewQueue(), err)
+	}
+	if err := ch.QueueBind(c.reviewQueue(), reviewRoutingKey, c.retryExchange, false, nil); err != nil {
+		return fmt.Errorf("QueueBind [%s]: %w", c.reviewQueue(), err)
+	}
+	return nil
+}
+
+// handle 处理单条消息：成功确认；失败转入重试队列或死信队列后再确认原消息，转发失败则重新入队避免丢失
+func (c *consumer) handle(d amqp.Delivery) {
+	defer metrics.TrackInflight(c.cfg.Name)()
+	err := c.invoke(d)
+	if err == nil {
+		_ = d.Ack(false)
+		return
+	}
+
+	retries := retryCount(d.Headers)
+	routingKey, dead := dlqRoutingKey, true
+	switch {
+	case event.IsManualReview(err):
+		routingKey = reviewRoutingKey
+	case !event.IsPermanent(err) && retries < len(c.cfg.RetryDelays):
+		routingKey, dead = c.cfg.RetryDelays[retries].String(), false
+	}
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	if !dead {
+		headers[headerRetryCount] = int32(retries + 1)
+	}
+	errText := utils.TruncateRunes(err.Error(), maxErrorHeader)
+	headers[headerLastError] = errText
+
+	target := &config.RabbitProducerCfg{
+		Name:         c.retryExchange,
+		Exchange:     c.retryExchange,
+		ExchangeType: amqp.ExchangeDirect,
+		RoutingKey:   routingKey,
+	}
+	if pubErr := c.republish(target, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		MessageId:    d.MessageId,
+		Body:         d.Body,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+	}); pubErr != nil {
+		log.Printf("❌ [%s] 转发重试/死信队列失败，消息重新入队: %v", c.cfg.Name, pubErr)
+		_ = d.Nack(false, true)
+		return
+	}
+	_ = d.Ack(false)
+
+	if routingKey == reviewRoutingKey {
+		// 冲突详情已由回调处理方告警
+		log.Printf("🧐 [%s] 消息转入人工复核队列 %s: %v", c.cfg.Name, c.reviewQueue(), err)
+		return
+	}
+	if dead {
+		log.Printf("☠️ [%s] 消息转入死信队列 %s 重试次数=%d 永久错误=%v: %v", c.cfg.Name, c.deadQueue(), retries, event.IsPermanent(err), err)
+		notify.Notify(system.BotChatID, "error", "MQ消息转入死信队列",
+			fmt.Sprintf("🚨 消息处理失败已转入死信队列\n队列: `%s`\n重试次数: `%d`\n永久错误: `%v`\n错误: `%s`",
+				c.deadQueue(), retries, event.IsPermanent(err), errText), true)
+		return
+	}
+	log.Printf("🔁 [%s] 消息处理失败，%s 后第 %d 次重试: %v", c.cfg.Name, routingKey, retries+1, err)
+}
+
+// invoke 调用处理函数，panic 按临时错误处理
+func (c *consumer) invoke(d amqp.Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] [%s] 消息处理 panic: %v\n%s", c.cfg.Name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return c.handler(d)
+}
 
-	for d := range msgs {
-		go handler(d)
+// retryCount 读取已重试次数（头缺失视为首次投递）
+func retryCount(h amqp.Table) int {
+	switch v := h[headerRetryCount].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case int16:
+		return int(v)
+	case int8:
+		return int(v)
 	}
+	return 0
 }
@@ -0,0 +1,126 @@
+package mq
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/event"
+
+	"github.com/streadway/amqp"
+)
+
+type fakeAcker struct {
+	acked, nacked, requeued bool
+}
+
+func (a *fakeAcker) Ack(tag uint64, multiple bool) error { a.acked = true; return nil }
+func (a *fakeAcker) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+func (a *fakeAcker) Reject(tag uint64, requeue bool) error { a.nacked = true; return nil }
+
+type republished struct {
+	routingKey string
+	msg        amqp.Publishing
+}
+
+func newTestConsumer(handler Handler, pubErr error) (*consumer, *[]republished) {
+	c := newConsumer(config.RabbitConsumerCfg{
+		Name:        "receive",
+		Queue:       "receive.q",
+		Workers:     1,
+		RetryDelays: []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute},
+	}, handler)
+	var out []republished
+	c.republish = func(target *config.RabbitProducerCfg, msg amqp.Publishing) error {
+		if pubErr != nil {
+			return pubErr
+		}
+		if target.Exchange != "receive.q.retry" {
+			return errors.New("unexpected exchange " + target.Exchange)
+		}
+		out = append(out, republished{target.RoutingKey, msg})
+		return nil
+	}
+	return c, &out
+}
+
+func delivery(headers amqp.Table) (amqp.Delivery, *fakeAcker) {
+	a := &fakeAcker{}
+	return amqp.Delivery{Acknowledger: a, Headers: headers, MessageId: "m1", Body: []byte(`{"a":1}`)}, a
+}
+
+func TestConsumerHandleSuccess(t *testing.T) {
+	c, out := newTestConsumer(func(amqp.Delivery) error { return nil }, nil)
+	d, a := delivery(nil)
+	c.handle(d)
+	if !a.acked || a.nacked || len(*out) != 0 {
+		t.Errorf("acked=%v nacked=%v republished=%v", a.acked, a.nacked, *out)
+	}
+}
+
+func TestConsumerHandleTransientRetries(t *testing.T) {
+	c, out := newTestConsumer(func(amqp.Delivery) error { return errors.New("db down") }, nil)
+
+	tests := []struct {
+		headers amqp.Table
+		wantKey string
+		wantCnt any
+	}{
+		{nil, "10s", int32(1)},
+		{amqp.Table{headerRetryCount: int32(1)}, "1m0s", int32(2)},
+		{amqp.Table{headerRetryCount: int64(2)}, "10m0s", int32(3)},
+		// 重试梯度用尽转死信，重试次数保持不变
+		{amqp.Table{headerRetryCount: int32(3)}, dlqRoutingKey, int32(3)},
+	}
+	for i, tt := range tests {
+		d, a := delivery(tt.headers)
+		c.handle(d)
+		if !a.acked {
+			t.Fatalf("#%d original not acked", i)
+		}
+		got := (*out)[i]
+		if got.routingKey != tt.wantKey || got.msg.Headers[headerRetryCount] != tt.wantCnt ||
+			got.msg.Headers[headerLastError] != "db down" || got.msg.MessageId != "m1" || string(got.msg.Body) != `{"a":1}` {
+			t.Errorf("#%d republished = %s %+v", i, got.routingKey, got.msg.Headers)
+		}
+	}
+}
+
+func TestConsumerHandlePermanentGoesToDLQ(t *testing.T) {
+	c, out := newTestConsumer(func(amqp.Delivery) error { return event.Permanent(errors.New("ip not whitelisted")) }, nil)
+	d, a := delivery(nil)
+	c.handle(d)
+	if !a.acked || len(*out) != 1 || (*out)[0].routingKey != dlqRoutingKey {
+		t.Errorf("acked=%v republished=%+v, want dlq", a.acked, *out)
+	}
+}
+
+func TestConsumerHandlePanicRetries(t *testing.T) {
+	c, out := newTestConsumer(func(amqp.Delivery) error { panic("boom") }, nil)
+	d, a := delivery(nil)
+	c.handle(d)
+	if !a.acked || len(*out) != 1 || (*out)[0].routingKey != "10s" {
+		t.Errorf("acked=%v republished=%+v, want retry", a.acked, *out)
+	}
+}
+
+func TestConsumerHandleRepublishFailureRequeues(t *testing.T) {
+	c, _ := newTestConsumer(func(amqp.Delivery) error { return errors.New("db down") }, errors.New("broker down"))
+	d, a := delivery(nil)
+	c.handle(d)
+	if a.acked || !a.nacked || !a.requeued {
+		t.Errorf("acked=%v nacked=%v requeued=%v, want requeue", a.acked, a.nacked, a.requeued)
+	}
+}
+
+func TestConsumerHandleManualReview(t *testing.T) {
+	c, out := newTestConsumer(func(amqp.Delivery) error { return event.ManualReview(errors.New("success then fail")) }, nil)
+	d, a := delivery(nil)
+	c.handle(d)
+	if !a.acked || len(*out) != 1 || (*out)[0].routingKey != reviewRoutingKey {
+		t.Errorf("acked=%v republished=%+v, want review", a.acked, *out)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"wht-order-api/internal/callback"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
 )
 
 // StartPayoutConsumer 代付消息队列
@@ -15,24 +16,24 @@ func StartPayoutConsumer() {
 	StartConsumer("payout", payoutHandleOrderMessage)
 }
 
-func payoutHandleOrderMessage(d amqp.Delivery) {
+func payoutHandleOrderMessage(d amqp.Delivery) error {
 	var msg dto.PayoutHyperfOrderMessage
 	if err := json.Unmarshal(d.Body, &msg); err != nil {
 		log.Printf("❌ [CALLBACK-PAYOUT] 消息解析失败: %v", err)
-		d.Nack(false, false)
-		return
+		return event.Permanent(err)
 	}
 
 	log.Printf("📨 [CALLBACK-PAYOUT] 收到代付回调: MOrderID=%s, Status=%s", msg.MOrderID, msg.Status)
 
 	pub := NewPublisher()
-	err := callback.NewPayoutCallback(pub).HandleUpstreamCallback(&msg)
+	err := dedupCallback("payout", d, msg.MOrderID, msg.Status, msg.UpOrderID, func() error {
+		return callback.NewPayoutCallback(pub).HandleUpstreamCallback(&msg)
+	})
 	if err != nil {
 		log.Printf("❌ [CALLBACK-PAYOUT] 回调处理失败: %v", err)
-		d.Nack(false, false)
-		return
+		return err
 	}
 
-	d.Ack(false)
 	log.Printf("✅ [CALLBACK-PAYOUT]回调处理完成: %s", msg.MOrderID)
+	return nil
 }
@@ -0,0 +1,117 @@
+package mq
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/metrics"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/system"
+)
+
+const flushInterval = time.Second
+
+// pendingMsg 断线期间暂存的消息
+type pendingMsg struct {
+	target    config.RabbitProducerCfg
+	messageID string
+	body      []byte
+	at        time.Time
+}
+
+// pendingBuffer 断线重连期间的本地有界缓冲（进程内存，重启即丢失；需要可靠投递的事件走发件箱）
+type pendingBuffer struct {
+	mu    sync.Mutex
+	items []pendingMsg
+}
+
+var pending = &pendingBuffer{}
+
+// push 暂存消息，缓冲已满时返回 false
+func (b *pendingBuffer) push(m pendingMsg) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.items) >= config.C.RabbitMQ.Publisher.BufferSize {
+		return false
+	}
+	b.items = append(b.items, m)
+	return true
+}
+
+func (b *pendingBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.items)
+}
+
+func (b *pendingBuffer) front() (pendingMsg, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.items) == 0 {
+		return pendingMsg{}, false
+	}
+	return b.items[0], true
+}
+
+func (b *pendingBuffer) popFront() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.items) > 0 {
+		b.items[0] = pendingMsg{}
+		b.items = b.items[1:]
+	}
+}
+
+// flushLoop 连接恢复后按暂存顺序补发
+func (b *pendingBuffer) flushLoop() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if b.len() > 0 && dal.IsConnected() {
+			b.flush()
+		}
+	}
+}
+
+// flush 逐条补发：断线则停止等待下一轮；broker 拒绝/无法路由的消息告警后丢弃，避免阻塞队列
+func (b *pendingBuffer) flush() {
+	n := 0
+	for {
+		m, ok := b.front()
+		if !ok {
+			break
+		}
+		err := publishConfirmed(&m.target, m.messageID, m.body)
+		if err != nil && !dal.IsConnected() {
+			break
+		}
+		b.popFront()
+		if err != nil {
+			metrics.PublishFailed(m.target.Name, publishFailReason(err))
+			log.Printf("[MQ] ❌ 缓冲消息补发失败，已丢弃 [%s→%s]: %v body=%s", m.target.Exchange, m.target.RoutingKey, err, string(m.body))
+			notify.Notify(system.BotChatID, "error", "MQ缓冲消息补发失败",
+				fmt.Sprintf("🚨 断线缓冲消息补发失败已丢弃\n生产者: `%s`\n暂存时间: `%s`\n错误: `%v`",
+					m.target.Name, m.at.Format(time.DateTime), err), true)
+			continue
+		}
+		n++
+	}
+	if n > 0 {
+		log.Printf("[MQ] ✅ 断线缓冲消息已补发 %d 条，剩余 %d 条", n, b.len())
+	}
+}
+
+// FlushPending 进程退出前尝试补发断线缓冲，返回仍未投递的数量（随进程退出丢失）
+func FlushPending() int {
+	if pending.len() > 0 && dal.IsConnected() {
+		pending.flush()
+	}
+	n := pending.len()
+	if n > 0 {
+		log.Printf("[MQ] ⚠️ 退出时仍有 %d 条断线缓冲消息未投递", n)
+	}
+	return n
+}
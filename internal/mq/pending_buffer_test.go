@@ -0,0 +1,33 @@
+package mq
+
+import (
+	"testing"
+	"wht-order-api/internal/config"
+)
+
+func TestPendingBufferBounded(t *testing.T) {
+	prev := config.C.RabbitMQ.Publisher.BufferSize
+	config.C.RabbitMQ.Publisher.BufferSize = 2
+	t.Cleanup(func() { config.C.RabbitMQ.Publisher.BufferSize = prev })
+
+	b := &pendingBuffer{}
+	for i, body := range []string{"a", "b", "c"} {
+		ok := b.push(pendingMsg{body: []byte(body)})
+		if want := i < 2; ok != want {
+			t.Errorf("push %s = %v, want %v", body, ok, want)
+		}
+	}
+
+	// 未连接时补发立即停止，消息按原顺序保留
+	b.flush()
+	if n := b.len(); n != 2 {
+		t.Fatalf("len after flush = %d, want 2", n)
+	}
+	if m, _ := b.front(); string(m.body) != "a" {
+		t.Errorf("front = %q, want a", m.body)
+	}
+	b.popFront()
+	if m, _ := b.front(); string(m.body) != "b" || b.len() != 1 {
+		t.Errorf("front = %q len %d, want b/1", m.body, b.len())
+	}
+}
@@ -2,13 +2,18 @@ package mq
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/streadway/amqp"
 	"log"
+	"sync"
 	"time"
 	"wht-order-api/internal/config"
 	"wht-order-api/internal/dal"
 	"wht-order-api/internal/event"
+	"wht-order-api/internal/metrics"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/system"
 )
 
 type Publisher struct{}
@@ -18,7 +23,21 @@ func NewPublisher() event.Publisher {
 	return &Publisher{}
 }
 
-// Publish 发布消息到指定生产者配置的 exchange/routingKey
+// NewConfirmPublisher 发件箱投递使用（需要 PublishConfirm）
+func NewConfirmPublisher() *Publisher {
+	return &Publisher{}
+}
+
+var (
+	errNacked     = errors.New("broker 拒绝消息")
+	errUnroutable = errors.New("消息无法路由")
+
+	// 已声明的交换机（按生产者名缓存，进程内只声明一次）
+	declared sync.Map
+)
+
+// Publish 发布消息到指定生产者配置的 exchange/routingKey，等待 broker 确认；
+// 断线重连期间暂存本地有界缓冲，恢复后补发
 func (p *Publisher) Publish(name string, payload any) error {
 	target := findProducer(name)
 	if target == nil {
@@ -30,59 +49,138 @@ func (p *Publisher) Publish(name string, payload any) error {
 		return fmt.Errorf("消息序列化失败: %w", err)
 	}
 
-	// 尝试发布（最多两次，第二次会触发重连）
-	if err := p.publishOnce(target, body); err != nil {
-		// 若通道/连接问题，触发自愈后再试一次
-		log.Printf("[MQ] ⚠️ 发布失败，尝试自愈后重试: %v", err)
-		// 触发自愈：拿一次 channel（内部会重连）
-		if ch := dal.GetChannel(); ch == nil {
-			return fmt.Errorf("自愈失败：无法获取 MQ 通道: %w", err)
-		}
-		if err2 := p.publishOnce(target, body); err2 != nil {
-			return fmt.Errorf("发布失败（重试后）[%s→%s]: %w", target.Exchange, target.RoutingKey, err2)
+	err = publishConfirmed(target, "", body)
+	if err != nil && !dal.IsConnected() {
+		if pending.push(pendingMsg{target: *target, body: body, at: time.Now()}) {
+			log.Printf("[MQ] ⏸️ 连接中断，消息已暂存本地缓冲 [%s → %s]", target.Name, target.RoutingKey)
+			return nil
 		}
+		metrics.PublishFailed(target.Name, "buffer_full")
+		notify.Notify(system.BotChatID, "error", "MQ本地缓冲已满",
+			fmt.Sprintf("🚨 MQ 断线且本地缓冲已满（%d），消息发布失败\n生产者: `%s`",
+				config.C.RabbitMQ.Publisher.BufferSize, target.Name), true)
+		return fmt.Errorf("MQ 断线且本地缓冲已满 [%s]: %w", target.Name, err)
+	}
+	// 通道级异常（超时、通道关闭）换一个通道重试一次；broker 明确拒绝或无法路由不重试
+	if err != nil && !errors.Is(err, errNacked) && !errors.Is(err, errUnroutable) {
+		log.Printf("[MQ] ⚠️ 发布失败，换通道重试: %v", err)
+		err = publishConfirmed(target, "", body)
+	}
+	if err != nil {
+		metrics.PublishFailed(target.Name, publishFailReason(err))
+		return fmt.Errorf("发布失败 [%s→%s]: %w", target.Exchange, target.RoutingKey, err)
 	}
 
 	log.Printf("[MQ] ✅ 成功发布 [%s → %s]: %s", target.Name, target.RoutingKey, string(body))
 	return nil
 }
 
-func (p *Publisher) publishOnce(target *config.RabbitProducerCfg, body []byte) error {
-	ch := dal.GetChannel()
-	if ch == nil {
-		return fmt.Errorf("RabbitMQ 通道不可用")
+// PublishConfirm 以发布确认模式投递并等待 broker ack，messageID 写入 AMQP message_id 供消费端去重。
+// 不走本地缓冲：发件箱自身持久化并负责重试
+func (p *Publisher) PublishConfirm(name, messageID string, body []byte) error {
+	target := findProducer(name)
+	if target == nil {
+		return fmt.Errorf("未找到生产者配置: %s", name)
 	}
+	if err := publishConfirmed(target, messageID, body); err != nil {
+		metrics.PublishFailed(target.Name, publishFailReason(err))
+		return err
+	}
+	return nil
+}
 
-	// 幂等声明交换机
-	if err := ch.ExchangeDeclare(
-		target.Exchange,
-		target.ExchangeType,
-		true,  // durable
-		false, // auto-delete
-		false, // internal
-		false, // no-wait
-		nil,
-	); err != nil {
-		return fmt.Errorf("交换机声明失败 [%s]: %w", target.Exchange, err)
+// publishFailReason 发布失败原因（指标标签）
+func publishFailReason(err error) string {
+	switch {
+	case errors.Is(err, errNacked):
+		return "nack"
+	case errors.Is(err, errUnroutable):
+		return "unroutable"
+	case errors.Is(err, errNotConnected):
+		return "disconnected"
+	default:
+		return "error"
 	}
+}
 
-	pub := amqp.Publishing{
+// publishConfirmed 以持久化 JSON 消息发布并等待确认
+func publishConfirmed(target *config.RabbitProducerCfg, messageID string, body []byte) error {
+	return publishDelivery(target, amqp.Publishing{
 		ContentType:  "application/json",
+		MessageId:    messageID,
 		Body:         body,
 		DeliveryMode: amqp.Persistent, // 持久化
 		Timestamp:    time.Now(),
+	})
+}
+
+// publishDelivery 从通道池取通道发布（mandatory），等待本条消息的确认并检查是否被退回
+func publishDelivery(target *config.RabbitProducerCfg, msg amqp.Publishing) error {
+	timeout := config.C.RabbitMQ.Publisher.ConfirmTimeout
+	c, err := pool().get(timeout)
+	if err != nil {
+		return err
 	}
+	healthy := false
+	defer func() { pool().put(c, healthy) }()
 
-	if err := ch.Publish(
-		target.Exchange,
-		target.RoutingKey,
-		false, // mandatory
-		false, // immediate
-		pub,
-	); err != nil {
+	if err := declareExchange(c, target); err != nil {
+		return err
+	}
+	if err := c.ch.Publish(target.Exchange, target.RoutingKey, true, false, msg); err != nil {
 		return fmt.Errorf("Publish 错误: %w", err)
 	}
+	c.seq++
 
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case conf, ok := <-c.confirms:
+		if !ok {
+			return errors.New("等待确认时通道已关闭")
+		}
+		if conf.DeliveryTag != c.seq {
+			// 迟到的确认与当前消息错位，丢弃通道
+			return fmt.Errorf("确认序号错位 want=%d got=%d", c.seq, conf.DeliveryTag)
+		}
+		healthy = true
+		// broker 先发 basic.return 再发 ack，收到确认时退回消息已在 returns 中
+		var ret *amqp.Return
+		select {
+		case r := <-c.returns:
+			ret = &r
+		default:
+		}
+		if !conf.Ack {
+			return fmt.Errorf("%w [%s→%s] message_id=%s", errNacked, target.Exchange, target.RoutingKey, msg.MessageId)
+		}
+		if ret != nil {
+			return fmt.Errorf("%w [%s→%s] %d %s", errUnroutable, target.Exchange, target.RoutingKey, ret.ReplyCode, ret.ReplyText)
+		}
+		return nil
+	case <-timer.C:
+		// 超时后丢弃通道，避免迟到的确认与下一条消息错位
+		return fmt.Errorf("等待 broker 确认超时 [%s→%s] message_id=%s", target.Exchange, target.RoutingKey, msg.MessageId)
+	}
+}
+
+// declareExchange 幂等声明交换机，成功后按生产者缓存，后续发布不再声明
+func declareExchange(c *pubChannel, target *config.RabbitProducerCfg) error {
+	if _, ok := declared.Load(target.Name); ok {
+		return nil
+	}
+	if err := c.ch.ExchangeDeclare(
+		target.Exchange,
+		target.ExchangeType,
+		true,  // durable
+		false, // auto-delete
+		false, // internal
+		false, // no-wait
+		nil,
+	); err != nil {
+		return fmt.Errorf("交换机声明失败 [%s]: %w", target.Exchange, err)
+	}
+	declared.Store(target.Name, struct{}{})
 	return nil
 }
 
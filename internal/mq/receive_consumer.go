@@ -6,31 +6,33 @@ import (
 	"log"
 	"wht-order-api/internal/callback"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
 )
 
 func StartReceiveConsumer() {
 	StartConsumer("receive", receiveHandleOrderMessage)
 }
 
-func receiveHandleOrderMessage(d amqp.Delivery) {
+func receiveHandleOrderMessage(d amqp.Delivery) error {
 	var msg dto.ReceiveHyperfOrderMessage
 	if err := json.Unmarshal(d.Body, &msg); err != nil {
 		log.Printf("❌ [CALLBACK-RECEIVE] Failed to unmarshal order message: %v", err)
-		d.Nack(false, false)
-		return
+		return event.Permanent(err)
 	}
 
-	log.Printf("📨 [CALLBACK-RECEIVE] Received order message: MOrderID=%s, Status=%s, Amount=%.2f",
-		msg.MOrderID, msg.Status, msg.Amount)
+	log.Printf("📨 [CALLBACK-RECEIVE] Received order message: MOrderID=%s, Status=%s, Amount=%s",
+		msg.MOrderID, msg.Status, msg.Amount.StringFixed(2))
 
 	// 创建 Publisher 实例
 	pub := NewPublisher()
-	if err := callback.NewReceiveCallback(pub).HandleUpstreamCallback(&msg); err != nil {
+	err := dedupCallback("receive", d, msg.MOrderID, msg.Status, msg.UpOrderID, func() error {
+		return callback.NewReceiveCallback(pub).HandleUpstreamCallback(&msg)
+	})
+	if err != nil {
 		log.Printf("❌ [CALLBACK-RECEIVE] Failed to process order notification: %v", err)
-		d.Nack(false, false)
-		return
+		return err
 	}
 
-	d.Ack(false)
 	log.Printf("✅ [CALLBACK-RECEIVE] Successfully processed order: %s", msg.MOrderID)
+	return nil
 }
@@ -0,0 +1,47 @@
+// Package outbox 事务发件箱：业务事件与订单/状态变更在同一订单库事务内写入 p_outbox，
+// 由 Relay 定时扫描并以发布确认模式投递到 MQ，失败退避重试。
+//
+// 投递语义为至少一次：broker 确认后、标记已投递前进程退出会导致重复投递，
+// 消费端按事件ID（AMQP message_id，订单统计消息体的 EventID）去重。
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/idgen"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+// TopicOrderStat 订单统计（rabbitmq.producers 中的生产者名）
+const TopicOrderStat = "order_stat"
+
+// NewEvent 构造待投递事件，aggregateID 为关联的平台订单号
+func NewEvent(topic string, aggregateID uint64, eventID string, payload any) (*ordermodel.OutboxEventM, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("outbox payload marshal failed: %w", err)
+	}
+	now := time.Now()
+	return &ordermodel.OutboxEventM{
+		EventID:     eventID,
+		Topic:       topic,
+		AggregateID: aggregateID,
+		Payload:     string(body),
+		Status:      ordermodel.OutboxPending,
+		NextRetryAt: now,
+		CreateTime:  now,
+	}, nil
+}
+
+// NewOrderStat 订单统计事件（生成事件ID并写入消息体）
+func NewOrderStat(msg *dto.OrderMessageMQ) (*ordermodel.OutboxEventM, error) {
+	orderID, err := strconv.ParseUint(msg.OrderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("order_stat invalid order id %q: %w", msg.OrderID, err)
+	}
+	msg.EventID = strconv.FormatUint(idgen.New(), 10)
+	return NewEvent(TopicOrderStat, orderID, msg.EventID, msg)
+}
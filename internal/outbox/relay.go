@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/lifecycle"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/system"
+	"wht-order-api/internal/utils"
+)
+
+const (
+	relayLockKey    = "outbox_relay_lock"
+	relayLockTTL    = 30 * time.Second
+	maxRetryBackoff = 5 * time.Minute
+	purgeInterval   = time.Hour
+	purgeBatch      = 1000
+)
+
+// Publisher 支持发布确认的投递端（mq.Publisher 实现），messageID 写入 AMQP message_id
+type Publisher interface {
+	PublishConfirm(topic, messageID string, body []byte) error
+}
+
+// Relay 发件箱投递（多实例通过 Redis 锁互斥）
+type Relay struct {
+	repo      dao.OutboxRepository
+	pub       Publisher
+	lastPurge time.Time
+}
+
+func NewRelay(pub Publisher) *Relay {
+	return NewRelayWithRepo(dao.NewOutboxDao(), pub)
+}
+
+// NewRelayWithRepo 注入仓储（单元测试使用内存实现）
+func NewRelayWithRepo(repo dao.OutboxRepository, pub Publisher) *Relay {
+	return &Relay{repo: repo, pub: pub, lastPurge: time.Now()}
+}
+
+// Run 定时投递到期事件
+func (r *Relay) Run() {
+	ticker := time.NewTicker(config.C.Outbox.PollInterval)
+	defer ticker.Stop()
+	log.Printf("[OUTBOX] 发件箱投递已启动 间隔=%v 批量=%d 最大次数=%d",
+		config.C.Outbox.PollInterval, config.C.Outbox.BatchSize, config.C.Outbox.MaxAttempts)
+	for {
+		select {
+		case <-lifecycle.Stopping():
+			log.Printf("[OUTBOX] 发件箱投递已停止")
+			return
+		case <-ticker.C:
+			r.RelayOnce()
+		}
+	}
+}
+
+// RelayOnce 投递一批到期事件，返回投递成功数量
+func (r *Relay) RelayOnce() (sent int) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("[PANIC] outbox relay panic: %v\n%s", rec, debug.Stack())
+			notify.Notify(system.BotChatID, "error", "发件箱投递Panic", fmt.Sprintf("panic: %v", rec), true)
+		}
+	}()
+
+	ok, err := dal.RedisClient.SetNX(dal.RedisCtx, relayLockKey, 1, relayLockTTL).Result()
+	if err != nil || !ok {
+		return 0
+	}
+	defer dal.RedisClient.Del(dal.RedisCtx, relayLockKey)
+
+	now := time.Now()
+	list, err := r.repo.ListDue(now, config.C.Outbox.BatchSize)
+	if err != nil {
+		log.Printf("[OUTBOX] 查询待投递事件失败: %v", err)
+		return 0
+	}
+	for i := range list {
+		if err := r.deliver(&list[i]); err != nil {
+			// broker 不可用时同批其余事件大概率同样失败，留到下一轮，避免集中消耗重试次数
+			break
+		}
+		sent++
+	}
+
+	if now.Sub(r.lastPurge) >= purgeInterval {
+		r.lastPurge = now
+		if n, err := r.repo.PurgeSent(now.Add(-config.C.Outbox.Retention), purgeBatch); err != nil {
+			log.Printf("[OUTBOX] 清理已投递事件失败: %v", err)
+		} else if n > 0 {
+			log.Printf("[OUTBOX] 已清理已投递事件 %d 条", n)
+		}
+	}
+	return sent
+}
+
+func (r *Relay) deliver(e *ordermodel.OutboxEventM) error {
+	pubErr := r.pub.PublishConfirm(e.Topic, e.EventID, []byte(e.Payload))
+	if pubErr == nil {
+		if err := r.repo.MarkSent(e.ID, time.Now()); err != nil {
+			// 已投递但未标记成功，下一轮会重复投递，由消费端按事件ID去重
+			log.Printf("[OUTBOX] 标记已投递失败 event=%s err=%v", e.EventID, err)
+		}
+		return nil
+	}
+
+	attempts := e.Attempts + 1
+	dead := attempts >= config.C.Outbox.MaxAttempts
+	lastErr := utils.TruncateRunes(pubErr.Error(), 255)
+	if err := r.repo.MarkRetry(e.ID, attempts, time.Now().Add(retryBackoff(attempts)), lastErr, dead); err != nil {
+		log.Printf("[OUTBOX] 记录投递失败异常 event=%s err=%v", e.EventID, err)
+	}
+	log.Printf("[OUTBOX] ⚠️ 投递失败 event=%s topic=%s 次数=%d err=%v", e.EventID, e.Topic, attempts, pubErr)
+	if dead {
+		notify.Notify(system.BotChatID, "error", "发件箱事件投递失败",
+			fmt.Sprintf("🚨 事件投递次数已达上限，转死信\n事件ID: `%s`\n主题: `%s`\n平台订单号: `%d`\n次数: `%d`\n错误: `%s`",
+				e.EventID, e.Topic, e.AggregateID, attempts, lastErr), true)
+	}
+	return pubErr
+}
+
+// retryBackoff 指数退避：2s、4s、8s … 封顶 5 分钟
+func retryBackoff(attempts int) time.Duration {
+	if attempts > 8 {
+		return maxRetryBackoff
+	}
+	d := time.Second << attempts
+	if d > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return d
+}
@@ -0,0 +1,120 @@
+package outbox
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	ordermodel "wht-order-api/internal/model/order"
+)
+
+type fakePublisher struct {
+	fail map[string]bool // 按事件ID模拟投递失败
+	sent []string
+}
+
+func (p *fakePublisher) PublishConfirm(topic, messageID string, body []byte) error {
+	if p.fail[messageID] {
+		return errors.New("broker unavailable")
+	}
+	p.sent = append(p.sent, messageID)
+	return nil
+}
+
+func newRelayFixture(t *testing.T, n int) (*memdao.OutboxStore, *fakePublisher, *Relay) {
+	t.Helper()
+	fakeredis.Use(t)
+	prev := config.C.Outbox
+	config.C.Outbox = config.OutboxCfg{PollInterval: time.Second, BatchSize: 10, MaxAttempts: 3, Retention: time.Hour}
+	t.Cleanup(func() { config.C.Outbox = prev })
+
+	store := memdao.NewOutboxStore()
+	for i := 1; i <= n; i++ {
+		evt, err := NewEvent(TopicOrderStat, uint64(i), "E"+strconv.Itoa(i), map[string]int{"i": i})
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		if err := store.Insert(evt); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	pub := &fakePublisher{fail: map[string]bool{}}
+	return store, pub, NewRelayWithRepo(store, pub)
+}
+
+func TestRelayOnceMarksSent(t *testing.T) {
+	store, pub, relay := newRelayFixture(t, 2)
+
+	if sent := relay.RelayOnce(); sent != 2 {
+		t.Fatalf("sent = %d, want 2", sent)
+	}
+	if len(pub.sent) != 2 || pub.sent[0] != "E1" || pub.sent[1] != "E2" {
+		t.Errorf("published = %v, want [E1 E2]", pub.sent)
+	}
+	for _, e := range store.Events() {
+		if e.Status != ordermodel.OutboxSent || e.SentTime == nil {
+			t.Errorf("event %s = status %d, sentTime %v", e.EventID, e.Status, e.SentTime)
+		}
+	}
+	// 已投递事件不再重复投递
+	if sent := relay.RelayOnce(); sent != 0 {
+		t.Errorf("second round sent = %d, want 0", sent)
+	}
+}
+
+func TestRelayOnceFailureReschedules(t *testing.T) {
+	store, pub, relay := newRelayFixture(t, 2)
+	pub.fail["E1"] = true
+
+	before := time.Now()
+	if sent := relay.RelayOnce(); sent != 0 {
+		t.Fatalf("sent = %d, want 0", sent)
+	}
+	evts := store.Events()
+	// 首个失败后本批中止，E2 原样保留
+	if e := evts[0]; e.Status != ordermodel.OutboxPending || e.Attempts != 1 || e.LastError == "" || !e.NextRetryAt.After(before) {
+		t.Errorf("E1 = %+v, want rescheduled with attempts 1", e)
+	}
+	if e := evts[1]; e.Status != ordermodel.OutboxPending || e.Attempts != 0 {
+		t.Errorf("E2 = %+v, want untouched", e)
+	}
+	if len(pub.sent) != 0 {
+		t.Errorf("published = %v, want none", pub.sent)
+	}
+}
+
+func TestRelayOnceDeadAfterMaxAttempts(t *testing.T) {
+	store, pub, relay := newRelayFixture(t, 1)
+	pub.fail["E1"] = true
+
+	for i := 0; i < config.C.Outbox.MaxAttempts; i++ {
+		relay.RelayOnce()
+		// 跳过退避等待
+		_ = store.MarkRetry(1, store.Events()[0].Attempts, time.Now(), store.Events()[0].LastError, false)
+	}
+	e := store.Events()[0]
+	if e.Status != ordermodel.OutboxDead || e.Attempts != config.C.Outbox.MaxAttempts {
+		t.Errorf("event = status %d, attempts %d, want dead after %d", e.Status, e.Attempts, config.C.Outbox.MaxAttempts)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{8, 256 * time.Second},
+		{9, maxRetryBackoff},
+		{30, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package risk
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
+	"wht-order-api/internal/lifecycle"
+
+	"github.com/shopspring/decimal"
+)
+
+// Action 风控结论
+type Action int8
+
+const (
+	ActionAllow  Action = 1 // 放行
+	ActionReview Action = 2 // 复核
+	ActionDeny   Action = 3 // 拒绝
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionReview:
+		return "review"
+	case ActionDeny:
+		return "deny"
+	default:
+		return "allow"
+	}
+}
+
+// Input 风控评估入参（下单请求中的风险要素）
+type Input struct {
+	OrderType   int8 // 1代收 2代付
+	MerchantID  uint64
+	MerchantNo  string
+	TranFlow    string
+	PayType     string
+	Currency    string
+	Amount      decimal.Decimal
+	IP          string
+	AccNo       string
+	Email       string
+	Phone       string
+	IdentityNum string
+
+	usage *usage // 评估时由引擎设置，规则计数记入其中
+}
+
+// Rule 风控规则：未命中返回 nil
+type Rule interface {
+	Meta() RuleMeta
+	Evaluate(in Input) (*dto.RiskHit, error)
+}
+
+// RuleMeta 规则公共属性
+type RuleMeta struct {
+	ID        uint64
+	Name      string
+	Type      string
+	OrderType int8
+	MID       uint64
+	Action    Action
+	Score     int
+}
+
+// applies 规则是否适用于本单
+func (m RuleMeta) applies(in Input) bool {
+	return (m.OrderType == 0 || m.OrderType == in.OrderType) && (m.MID == 0 || m.MID == in.MerchantID)
+}
+
+// hit 按规则配置生成命中明细
+func (m RuleMeta) hit(reason string) *dto.RiskHit {
+	return &dto.RiskHit{
+		RuleID:   m.ID,
+		RuleName: m.Name,
+		RuleType: m.Type,
+		Action:   m.Action.String(),
+		Score:    m.Score,
+		Reason:   reason,
+	}
+}
+
+// Result 风控评估结果
+type Result struct {
+	Action Action
+	Score  int
+	Hits   []dto.RiskHit
+
+	usage *usage // 本次评估计入的统计，下单完成后 Commit 或 Release
+}
+
+// Commit 订单已落库：计入商户历史金额统计，窗口计数保留（与 Release 互斥，仅首次调用生效）
+func (r Result) Commit() {
+	if r.usage == nil {
+		return
+	}
+	r.usage.once.Do(func() { recordAmount(r.usage.in) })
+}
+
+// Release 拒绝或下单失败：回滚本次评估计入的窗口计数，不计入金额统计
+func (r Result) Release() {
+	if r.usage == nil {
+		return
+	}
+	r.usage.once.Do(r.usage.rollback)
+}
+
+// Reason 命中原因摘要
+func (r Result) Reason() string {
+	reasons := make([]string, 0, len(r.Hits))
+	for _, h := range r.Hits {
+		reasons = append(reasons, fmt.Sprintf("%s(%s)", h.RuleName, h.Reason))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// Code 拒绝时对外返回的错误码（按首个拒绝规则类型区分）
+func (r Result) Code() int {
+	for _, h := range r.Hits {
+		if h.Action != ActionDeny.String() {
+			continue
+		}
+		switch h.RuleType {
+		case RuleBlacklist:
+			return constant.CodeRiskBlacklist
+		case RuleVelocity:
+			return constant.CodeRiskHighFrequency
+		case RuleAmountAnomaly:
+			return constant.CodeRiskAmountLimit
+		}
+		return constant.CodeRiskRejected
+	}
+	return constant.CodeRiskRejected
+}
+
+// ruleSet 一次加载的规则快照
+type ruleSet struct {
+	rules   []Rule
+	version string
+}
+
+// Engine 风控规则引擎：规则从数据库加载并定时热更新，评估时读取当前快照
+type Engine struct {
+	mainDao *dao.MainDao
+	pub     event.Publisher
+	current atomic.Value // *ruleSet
+}
+
+var defaultEngine atomic.Value // *Engine
+
+func NewEngine(pub event.Publisher) *Engine {
+	e := &Engine{mainDao: dao.NewMainDao(), pub: pub}
+	e.current.Store(&ruleSet{})
+	return e
+}
+
+// Init 初始化全局风控引擎并加载规则
+func Init(pub event.Publisher) *Engine {
+	e := NewEngine(pub)
+	if config.C.Risk.Enabled {
+		if err := e.Reload(); err != nil {
+			log.Printf("[RISK] ⚠️ 初始加载风控规则失败: %v", err)
+		}
+	}
+	defaultEngine.Store(e)
+	return e
+}
+
+// Default 全局风控引擎（未初始化时返回 nil）
+func Default() *Engine {
+	e, _ := defaultEngine.Load().(*Engine)
+	return e
+}
+
+// Run 定时热加载规则
+func (e *Engine) Run() {
+	if !config.C.Risk.Enabled {
+		return
+	}
+	ticker := time.NewTicker(config.C.Risk.ReloadInterval)
+	defer ticker.Stop()
+	log.Printf("[RISK] 规则热加载已启动 间隔=%v", config.C.Risk.ReloadInterval)
+	for {
+		select {
+		case <-lifecycle.Stopping():
+			log.Printf("[RISK] 规则热加载已停止")
+			return
+		case <-ticker.C:
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("[PANIC] risk reload panic: %v\n%s", r, debug.Stack())
+				}
+			}()
+			if err := e.Reload(); err != nil {
+				log.Printf("[RISK] ❌ 热加载风控规则失败，继续使用旧规则: %v", err)
+			}
+		}()
+	}
+}
+
+// Reload 从数据库加载规则与黑名单，构建新的规则快照
+func (e *Engine) Reload() error {
+	rows, err := e.mainDao.ListRiskRules()
+	if err != nil {
+		return err
+	}
+	blacklist, err := e.mainDao.ListRiskBlacklist()
+	if err != nil {
+		return err
+	}
+
+	// 版本：规则/黑名单数量 + 最近更新时间，无变化时不重建
+	var latest time.Time
+	for _, r := range rows {
+		if r.UpdateTime != nil && r.UpdateTime.After(latest) {
+			latest = *r.UpdateTime
+		}
+	}
+	for _, b := range blacklist {
+		if b.UpdateTime != nil && b.UpdateTime.After(latest) {
+			latest = *b.UpdateTime
+		}
+	}
+	version := fmt.Sprintf("%d:%d:%d", len(rows), len(blacklist), latest.Unix())
+	if old := e.current.Load().(*ruleSet); old.version == version {
+		return nil
+	}
+
+	rules := make([]Rule, 0, len(rows))
+	for _, row := range rows {
+		rule, err := buildRule(row, blacklist)
+		if err != nil {
+			log.Printf("[RISK] ⚠️ 规则配置无效已跳过 id=%d name=%s err=%v", row.ID, row.Name, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	e.current.Store(&ruleSet{rules: rules, version: version})
+	log.Printf("[RISK] ✅ 风控规则已加载 规则=%d 黑名单=%d 版本=%s", len(rules), len(blacklist), version)
+	return nil
+}
+
+// Evaluate 评估下单请求：取命中规则中最严格的动作，累计分值达到阈值时升级为复核/拒绝
+// 未拒绝时调用方须在下单完成后调用 Commit（订单已落库）或 Release（下单失败）
+func (e *Engine) Evaluate(in Input) Result {
+	result := Result{Action: ActionAllow}
+	if !config.C.Risk.Enabled {
+		return result
+	}
+
+	result.usage = &usage{in: in}
+	in.usage = result.usage
+	set := e.current.Load().(*ruleSet)
+	for _, rule := range set.rules {
+		meta := rule.Meta()
+		if !meta.applies(in) {
+			continue
+		}
+		hit, err := rule.Evaluate(in)
+		if err != nil {
+			// 单条规则异常不影响其他规则
+			log.Printf("[RISK] ⚠️ 规则执行异常 id=%d name=%s err=%v", meta.ID, meta.Name, err)
+			continue
+		}
+		if hit == nil {
+			continue
+		}
+		result.Hits = append(result.Hits, *hit)
+		result.Score += hit.Score
+		if meta.Action > result.Action {
+			result.Action = meta.Action
+		}
+	}
+
+	if deny := config.C.Risk.DenyScore; deny > 0 && result.Score >= deny {
+		result.Action = ActionDeny
+	} else if review := config.C.Risk.ReviewScore; review > 0 && result.Score >= review && result.Action < ActionReview {
+		result.Action = ActionReview
+	}
+
+	// 拒绝的请求不占用频率额度；放行/复核的计数待下单结果确认
+	if result.Action == ActionDeny {
+		result.Release()
+	}
+	if result.Action != ActionAllow {
+		log.Printf("[RISK] ⛔ 风控命中 merchant=%s tranFlow=%s action=%s score=%d hits=%s",
+			in.MerchantNo, in.TranFlow, result.Action, result.Score, result.Reason())
+		lifecycle.Go("risk-notify", func() { e.publish(in, result) })
+	}
+	return result
+}
+
+// publish 复核/拒绝发布 risk_notify 事件
+func (e *Engine) publish(in Input, result Result) {
+	if e.pub == nil {
+		return
+	}
+	msg := dto.RiskNotifyMessage{
+		OrderType:  in.OrderType,
+		MerchantID: in.MerchantID,
+		MerchantNo: in.MerchantNo,
+		TranFlow:   in.TranFlow,
+		PayType:    in.PayType,
+		Currency:   in.Currency,
+		Amount:     in.Amount.String(),
+		ClientIP:   in.IP,
+		AccNo:      in.AccNo,
+		Action:     result.Action.String(),
+		Score:      result.Score,
+		Hits:       result.Hits,
+		CreatedAt:  time.Now(),
+	}
+	if err := e.pub.Publish("risk_notify", msg); err != nil {
+		log.Printf("[RISK] ❌ 发布风控事件失败 merchant=%s tranFlow=%s err=%v", in.MerchantNo, in.TranFlow, err)
+	}
+}
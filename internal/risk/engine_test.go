@@ -0,0 +1,77 @@
+package risk
+
+import (
+	"testing"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dal/fakeredis"
+	mainmodel "wht-order-api/internal/model/main"
+
+	"github.com/shopspring/decimal"
+)
+
+// newTestEngine 同一 IP 10 分钟内最多 2 笔，超出拒绝
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	fakeredis.Use(t)
+	prev := config.C.Risk
+	config.C.Risk.Enabled = true
+	config.C.Risk.ReviewScore, config.C.Risk.DenyScore = 0, 0
+	t.Cleanup(func() { config.C.Risk = prev })
+
+	rule, err := buildRule(mainmodel.RiskRule{
+		ID: 1, Name: "ip 频率", RuleType: RuleVelocity, Action: int8(ActionDeny), Score: 10,
+		Params: `{"dimension":"ip","window":"10m","max":2}`,
+	}, nil)
+	if err != nil {
+		t.Fatalf("build rule: %v", err)
+	}
+	e := &Engine{}
+	e.current.Store(&ruleSet{rules: []Rule{rule}})
+	return e
+}
+
+func velocityCount(t *testing.T) string {
+	t.Helper()
+	keys, err := dal.RedisClient.Keys(dal.RedisCtx, riskVelocityPrefix+"*").Result()
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("velocity keys = %v, err = %v", keys, err)
+	}
+	v, _ := dal.RedisClient.Get(dal.RedisCtx, keys[0]).Result()
+	return v
+}
+
+func TestEvaluateCountsOnlyCreatedOrders(t *testing.T) {
+	e := newTestEngine(t)
+	in := Input{OrderType: 2, MerchantID: 1001, MerchantNo: "APP1001", Currency: "BRL", Amount: decimal.NewFromInt(100), IP: "1.2.3.4"}
+
+	// 下单失败回滚，不占用频率额度
+	for i := 0; i < 3; i++ {
+		r := e.Evaluate(in)
+		if r.Action != ActionAllow {
+			t.Fatalf("attempt %d: action = %s, want allow", i, r.Action)
+		}
+		r.Release()
+	}
+	if got := velocityCount(t); got != "0" {
+		t.Fatalf("count after failed creates = %s, want 0", got)
+	}
+
+	// 两笔落库后第三笔超限拒绝，拒绝本身不计数
+	for i := 0; i < 2; i++ {
+		e.Evaluate(in).Commit()
+	}
+	denied := e.Evaluate(in)
+	if denied.Action != ActionDeny {
+		t.Fatalf("action = %s, want deny", denied.Action)
+	}
+	if got := velocityCount(t); got != "2" {
+		t.Errorf("count after deny = %s, want 2", got)
+	}
+	// 已确认/已回滚后重复调用无效果
+	denied.Commit()
+	denied.Release()
+	if got := velocityCount(t); got != "2" {
+		t.Errorf("count after repeated settle = %s, want 2", got)
+	}
+}
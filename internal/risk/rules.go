@@ -0,0 +1,360 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dto"
+	mainmodel "wht-order-api/internal/model/main"
+
+	"github.com/shopspring/decimal"
+)
+
+// 规则类型
+const (
+	RuleBlacklist     = "blacklist"      // 黑名单
+	RuleVelocity      = "velocity"       // 频率
+	RuleAmountAnomaly = "amount_anomaly" // 金额偏离商户历史
+	RuleRoundAmount   = "round_amount"   // 整数金额
+	RuleStructuring   = "structuring"    // 略低于阈值的拆单
+)
+
+const (
+	riskVelocityPrefix    = "risk_velocity:"    // risk_velocity:{规则ID}:{维度值}:{窗口}
+	riskStructuringPrefix = "risk_structuring:" // risk_structuring:{规则ID}:{维度值}:{窗口}
+	riskAmountStatPrefix  = "risk_amount_stat:" // risk_amount_stat:{商户ID}:{订单类型}:{币种}，hash 字段 cnt/sum/sq
+	riskAmountStatTTL     = 30 * 24 * time.Hour
+)
+
+// dimensionValue 取订单在指定维度上的值
+func dimensionValue(in Input, dimension string) string {
+	switch dimension {
+	case "ip":
+		return in.IP
+	case "acc_no":
+		return in.AccNo
+	case "email":
+		return in.Email
+	case "phone":
+		return in.Phone
+	case "identity_num":
+		return in.IdentityNum
+	case "merchant":
+		return strconv.FormatUint(in.MerchantID, 10)
+	}
+	return ""
+}
+
+func normalize(v string) string {
+	return strings.ToLower(strings.TrimSpace(v))
+}
+
+// buildRule 按规则类型解析参数
+func buildRule(row mainmodel.RiskRule, blacklist []mainmodel.RiskBlacklist) (Rule, error) {
+	meta := RuleMeta{
+		ID:        row.ID,
+		Name:      row.Name,
+		Type:      row.RuleType,
+		OrderType: row.OrderType,
+		MID:       row.MID,
+		Action:    Action(row.Action),
+		Score:     row.Score,
+	}
+	if meta.Action < ActionAllow || meta.Action > ActionDeny {
+		return nil, fmt.Errorf("invalid action: %d", row.Action)
+	}
+	params := []byte(row.Params)
+	if len(params) == 0 {
+		params = []byte("{}")
+	}
+
+	switch row.RuleType {
+	case RuleBlacklist:
+		r := &blacklistRule{meta: meta, entries: map[string][]uint64{}}
+		if err := json.Unmarshal(params, &r.params); err != nil {
+			return nil, err
+		}
+		for _, b := range blacklist {
+			key := normalize(b.Field) + ":" + normalize(b.Value)
+			r.entries[key] = append(r.entries[key], b.MID)
+		}
+		return r, nil
+	case RuleVelocity:
+		r := &velocityRule{meta: meta}
+		if err := json.Unmarshal(params, &r.params); err != nil {
+			return nil, err
+		}
+		if r.params.Max <= 0 || r.params.Window.Duration() <= 0 || r.params.Dimension == "" {
+			return nil, errors.New("velocity requires dimension, window and max")
+		}
+		return r, nil
+	case RuleAmountAnomaly:
+		r := &amountAnomalyRule{meta: meta}
+		if err := json.Unmarshal(params, &r.params); err != nil {
+			return nil, err
+		}
+		if r.params.Multiple.LessThanOrEqual(decimal.Zero) && r.params.StdDev.LessThanOrEqual(decimal.Zero) {
+			return nil, errors.New("amount_anomaly requires multiple or stdDev")
+		}
+		return r, nil
+	case RuleRoundAmount:
+		r := &roundAmountRule{meta: meta}
+		if err := json.Unmarshal(params, &r.params); err != nil {
+			return nil, err
+		}
+		if r.params.Unit.LessThanOrEqual(decimal.Zero) {
+			return nil, errors.New("round_amount requires unit")
+		}
+		return r, nil
+	case RuleStructuring:
+		r := &structuringRule{meta: meta}
+		if err := json.Unmarshal(params, &r.params); err != nil {
+			return nil, err
+		}
+		if r.params.Threshold.LessThanOrEqual(decimal.Zero) || r.params.Max <= 0 || r.params.Window.Duration() <= 0 {
+			return nil, errors.New("structuring requires threshold, window and max")
+		}
+		if r.params.Dimension == "" {
+			r.params.Dimension = "acc_no"
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown rule type: %s", row.RuleType)
+}
+
+// duration 支持 "10m" 形式的 JSON 时长
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// usage 一次评估计入的风控统计：窗口计数在评估时 +1（需原子判断是否超限），
+// 下单失败时回滚；金额统计在订单落库后才计入
+type usage struct {
+	once sync.Once
+	in   Input
+	keys []string // 已 +1 的窗口计数键
+}
+
+// windowCount 固定窗口计数 +1 并返回当前窗口计数
+func (u *usage) windowCount(prefix string, ruleID uint64, value string, window time.Duration) (int64, error) {
+	bucket := time.Now().Unix() / int64(window.Seconds())
+	key := fmt.Sprintf("%s%d:%s:%d", prefix, ruleID, value, bucket)
+	pipe := dal.RedisClient.TxPipeline()
+	incr := pipe.Incr(dal.RedisCtx, key)
+	pipe.Expire(dal.RedisCtx, key, window)
+	if _, err := pipe.Exec(dal.RedisCtx); err != nil {
+		return 0, err
+	}
+	u.keys = append(u.keys, key)
+	return incr.Val(), nil
+}
+
+// rollback 撤销本次评估的窗口计数
+func (u *usage) rollback() {
+	if dal.RedisClient == nil || len(u.keys) == 0 {
+		return
+	}
+	pipe := dal.RedisClient.TxPipeline()
+	for _, key := range u.keys {
+		pipe.Decr(dal.RedisCtx, key)
+	}
+	if _, err := pipe.Exec(dal.RedisCtx); err != nil {
+		log.Printf("[RISK] ⚠️ 回滚风控计数失败 merchant=%s tranFlow=%s err=%v", u.in.MerchantNo, u.in.TranFlow, err)
+	}
+}
+
+// ================== 黑名单 ==================
+
+type blacklistRule struct {
+	meta   RuleMeta
+	params struct {
+		Fields []string `json:"fields"` // 校验字段，为空则全部
+	}
+	entries map[string][]uint64 // field:value → 商户ID 列表（0 为全局）
+}
+
+func (r *blacklistRule) Meta() RuleMeta { return r.meta }
+
+func (r *blacklistRule) Evaluate(in Input) (*dto.RiskHit, error) {
+	fields := r.params.Fields
+	if len(fields) == 0 {
+		fields = []string{"ip", "acc_no", "email", "phone", "identity_num"}
+	}
+	for _, field := range fields {
+		value := normalize(dimensionValue(in, field))
+		if value == "" {
+			continue
+		}
+		for _, mId := range r.entries[field+":"+value] {
+			if mId == 0 || mId == in.MerchantID {
+				return r.meta.hit(fmt.Sprintf("%s 命中黑名单", field)), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ================== 频率 ==================
+
+type velocityRule struct {
+	meta   RuleMeta
+	params struct {
+		Dimension string   `json:"dimension"`
+		Window    duration `json:"window"`
+		Max       int64    `json:"max"`
+	}
+}
+
+func (r *velocityRule) Meta() RuleMeta { return r.meta }
+
+func (r *velocityRule) Evaluate(in Input) (*dto.RiskHit, error) {
+	value := normalize(dimensionValue(in, r.params.Dimension))
+	if value == "" {
+		return nil, nil
+	}
+	count, err := in.usage.windowCount(riskVelocityPrefix, r.meta.ID, value, r.params.Window.Duration())
+	if err != nil {
+		return nil, err
+	}
+	if count > r.params.Max {
+		return r.meta.hit(fmt.Sprintf("%s %v 内 %d 笔，上限 %d", r.params.Dimension, r.params.Window.Duration(), count, r.params.Max)), nil
+	}
+	return nil, nil
+}
+
+// ================== 金额偏离商户历史 ==================
+
+type amountAnomalyRule struct {
+	meta   RuleMeta
+	params struct {
+		MinSamples int64           `json:"minSamples"` // 历史样本不足时不判断
+		Multiple   decimal.Decimal `json:"multiple"`   // 超过历史均值的倍数
+		StdDev     decimal.Decimal `json:"stdDev"`     // 超过均值 + N 倍标准差
+	}
+}
+
+func (r *amountAnomalyRule) Meta() RuleMeta { return r.meta }
+
+func (r *amountAnomalyRule) Evaluate(in Input) (*dto.RiskHit, error) {
+	key := fmt.Sprintf("%s%d:%d:%s", riskAmountStatPrefix, in.MerchantID, in.OrderType, in.Currency)
+	stat, err := dal.RedisClient.HGetAll(dal.RedisCtx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	cnt, _ := strconv.ParseInt(stat["cnt"], 10, 64)
+	if cnt == 0 || cnt < r.params.MinSamples {
+		return nil, nil
+	}
+	sum, _ := decimal.NewFromString(stat["sum"])
+	sq, _ := decimal.NewFromString(stat["sq"])
+	n := decimal.NewFromInt(cnt)
+	mean := sum.Div(n)
+
+	if r.params.Multiple.GreaterThan(decimal.Zero) && in.Amount.GreaterThan(mean.Mul(r.params.Multiple)) {
+		return r.meta.hit(fmt.Sprintf("金额 %s 超过历史均值 %s 的 %s 倍", in.Amount, mean.StringFixed(2), r.params.Multiple)), nil
+	}
+	if r.params.StdDev.GreaterThan(decimal.Zero) {
+		variance := sq.Div(n).Sub(mean.Mul(mean))
+		if variance.GreaterThan(decimal.Zero) {
+			std, _ := variance.Float64()
+			limit := mean.Add(decimal.NewFromFloat(math.Sqrt(std)).Mul(r.params.StdDev))
+			if in.Amount.GreaterThan(limit) {
+				return r.meta.hit(fmt.Sprintf("金额 %s 超过历史均值 %s + %s 倍标准差", in.Amount, mean.StringFixed(2), r.params.StdDev)), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// recordAmount 累计商户历史金额统计（已落库的订单）
+func recordAmount(in Input) {
+	if dal.RedisClient == nil || in.MerchantID == 0 {
+		return
+	}
+	key := fmt.Sprintf("%s%d:%d:%s", riskAmountStatPrefix, in.MerchantID, in.OrderType, in.Currency)
+	amount, _ := in.Amount.Float64()
+	pipe := dal.RedisClient.TxPipeline()
+	pipe.HIncrBy(dal.RedisCtx, key, "cnt", 1)
+	pipe.HIncrByFloat(dal.RedisCtx, key, "sum", amount)
+	pipe.HIncrByFloat(dal.RedisCtx, key, "sq", amount*amount)
+	pipe.Expire(dal.RedisCtx, key, riskAmountStatTTL)
+	_, _ = pipe.Exec(dal.RedisCtx)
+}
+
+// ================== 整数金额 ==================
+
+type roundAmountRule struct {
+	meta   RuleMeta
+	params struct {
+		Unit      decimal.Decimal `json:"unit"`      // 整数单位，如 1000
+		MinAmount decimal.Decimal `json:"minAmount"` // 低于该金额不判断
+	}
+}
+
+func (r *roundAmountRule) Meta() RuleMeta { return r.meta }
+
+func (r *roundAmountRule) Evaluate(in Input) (*dto.RiskHit, error) {
+	if in.Amount.LessThan(r.params.MinAmount) {
+		return nil, nil
+	}
+	if in.Amount.Mod(r.params.Unit).IsZero() {
+		return r.meta.hit(fmt.Sprintf("金额 %s 为 %s 的整数倍", in.Amount, r.params.Unit)), nil
+	}
+	return nil, nil
+}
+
+// ================== 拆单 ==================
+
+type structuringRule struct {
+	meta   RuleMeta
+	params struct {
+		Threshold decimal.Decimal `json:"threshold"` // 监管/复核阈值
+		Margin    decimal.Decimal `json:"margin"`    // 低于阈值的比例区间，如 0.1 表示 [90%, 100%)
+		Window    duration        `json:"window"`
+		Max       int64           `json:"max"` // 窗口内接近阈值的笔数上限
+		Dimension string          `json:"dimension"`
+	}
+}
+
+func (r *structuringRule) Meta() RuleMeta { return r.meta }
+
+func (r *structuringRule) Evaluate(in Input) (*dto.RiskHit, error) {
+	lower := r.params.Threshold.Mul(decimal.NewFromInt(1).Sub(r.params.Margin))
+	if in.Amount.GreaterThanOrEqual(r.params.Threshold) || in.Amount.LessThan(lower) {
+		return nil, nil
+	}
+	value := normalize(dimensionValue(in, r.params.Dimension))
+	if value == "" {
+		return nil, nil
+	}
+	count, err := in.usage.windowCount(riskStructuringPrefix, r.meta.ID, value, r.params.Window.Duration())
+	if err != nil {
+		return nil, err
+	}
+	if count > r.params.Max {
+		return r.meta.hit(fmt.Sprintf("%s %v 内 %d 笔金额略低于阈值 %s", r.params.Dimension, r.params.Window.Duration(), count, r.params.Threshold)), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	mainmodel "wht-order-api/internal/model/main"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/system"
+	"wht-order-api/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrFxCurrencyInvalid  = errors.New("换汇币种无效")
+	ErrFxRateInvalid      = errors.New("换汇汇率或金额无效")
+	ErrFxBalanceLow       = errors.New("源币种钱包余额不足")
+	ErrFxConversionNoUsed = errors.New("换汇单号已被其他商户使用")
+)
+
+// fxWalletScale 钱包金额存储精度（w_merchant_money.money decimal(18,4)）
+const fxWalletScale int32 = 4
+
+// fxAmountScale 换汇金额精度：法币到分；虚拟币按代币精度，且不超过钱包存储精度
+func fxAmountScale(currency string) int32 {
+	if !utils.IsCryptoCurrency(currency) {
+		return 2
+	}
+	if d, ok := utils.CurrencyDecimals(currency); ok && d < fxWalletScale {
+		return d
+	}
+	return fxWalletScale
+}
+
+// ConvertCurrency 商户钱包之间按指定汇率换汇，双边记资金流水
+func (s *AccountService) ConvertCurrency(req dto.AccountFxConvertReq) (dto.AccountFxConvertResp, error) {
+	var resp dto.AccountFxConvertResp
+
+	from := strings.ToUpper(strings.TrimSpace(req.FromCurrency))
+	to := strings.ToUpper(strings.TrimSpace(req.ToCurrency))
+	if from == "" || to == "" || from == to {
+		return resp, ErrFxCurrencyInvalid
+	}
+	fromScale, toScale := fxAmountScale(from), fxAmountScale(to)
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil || amount.LessThanOrEqual(decimal.Zero) || !amount.Equal(amount.Truncate(fromScale)) {
+		return resp, ErrFxRateInvalid
+	}
+	rate, err := decimal.NewFromString(req.Rate)
+	if err != nil || rate.LessThanOrEqual(decimal.Zero) {
+		return resp, ErrFxRateInvalid
+	}
+	// 目标金额按目标币种精度向下取整，避免平台多付
+	toAmount := amount.Mul(rate).Truncate(toScale)
+	if toAmount.LessThanOrEqual(decimal.Zero) {
+		return resp, ErrFxRateInvalid
+	}
+
+	merchant, err := s.mainDao.GetMerchant(req.MerchantNo)
+	if err != nil || merchant == nil || merchant.Status != 1 {
+		return resp, errors.New("商户不存在")
+	}
+
+	conv := &mainmodel.MerchantFxConversion{
+		ConversionNo: req.ConversionNo,
+		UID:          merchant.MerchantID,
+		FromCurrency: from,
+		ToCurrency:   to,
+		FromAmount:   amount,
+		ToAmount:     toAmount,
+		Rate:         rate,
+		Operator:     req.Operator,
+		Remark:       req.Remark,
+	}
+	existed, err := s.mainDao.ConvertMerchantWallet(conv)
+	if err != nil {
+		if errors.Is(err, dao.ErrFxInsufficientBalance) {
+			return resp, ErrFxBalanceLow
+		}
+		if errors.Is(err, dao.ErrFxConversionNoUsed) {
+			return resp, ErrFxConversionNoUsed
+		}
+		return resp, fmt.Errorf("换汇失败: %w", err)
+	}
+	if existed {
+		log.Printf("[FX] 换汇单号已处理，直接返回 conversion_no=%s", conv.ConversionNo)
+	} else {
+		log.Printf("[FX] ✅ 换汇成功 商户=%s %s %s -> %s %s 汇率=%s 操作人=%s",
+			req.MerchantNo, amount.String(), from, toAmount.String(), to, rate.String(), req.Operator)
+		msg := fmt.Sprintf("💱 商户钱包换汇\n商户号: `%s`\n换汇单号: `%s`\n转出: `%s %s`\n转入: `%s %s`\n汇率: `%s`\n操作人: `%s`",
+			req.MerchantNo, conv.ConversionNo, amount.StringFixed(fromScale), from, toAmount.StringFixed(toScale), to, rate.String(), req.Operator)
+		go notify.Notify(system.BotChatID, "info", "商户钱包换汇", msg, true)
+	}
+
+	resp = dto.AccountFxConvertResp{
+		ConversionNo: conv.ConversionNo,
+		MerchantNo:   req.MerchantNo,
+		FromCurrency: conv.FromCurrency,
+		ToCurrency:   conv.ToCurrency,
+		FromAmount:   conv.FromAmount.StringFixed(fxAmountScale(conv.FromCurrency)),
+		ToAmount:     conv.ToAmount.StringFixed(fxAmountScale(conv.ToCurrency)),
+		Rate:         conv.Rate.String(),
+		CreateTime:   conv.CreateTime.Format("2006-01-02 15:04:05"),
+	}
+	return resp, nil
+}
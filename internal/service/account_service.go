@@ -29,7 +29,21 @@ func (s *AccountService) Get(mId string, currency string) (dto.AccountResp, erro
 		return resp, errors.New("商户不存在")
 	}
 
-	resp, _ = s.mainDao.GetAccountDetail(merchant.MerchantID, currency)
+	// 每个币种一个钱包：指定币种时返回该币种余额，同时列出全部钱包
+	if currency != "" {
+		resp, _ = s.mainDao.GetAccountDetail(merchant.MerchantID, currency)
+	} else {
+		resp.Code = "0"
+		resp.Msg = "成功"
+		resp.AccName = merchant.NickName
+		resp.MerchantNo = mId
+	}
+	wallets, err := s.mainDao.ListMerchantWallets(merchant.MerchantID)
+	if err != nil {
+		log.Printf("查询商户钱包失败: %v", err)
+		return resp, errors.New("查询商户钱包失败")
+	}
+	resp.Wallets = wallets
 
 	return resp, nil
 }
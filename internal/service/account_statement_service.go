@@ -0,0 +1,289 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	mainmodel "wht-order-api/internal/model/main"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	statementTimeLayout      = "2006-01-02 15:04:05"
+	statementDefaultPageSize = 20
+	statementMaxPageSize     = 100
+	statementMaxRange        = 31 * 24 * time.Hour // JSON 查询最大时间跨度
+	statementExportBatch     = 500
+)
+
+// ErrStatementParams 流水查询参数错误
+var ErrStatementParams = errors.New("资金流水查询参数错误")
+
+// statementTypeGroups 对外流水类型 -> 资金流水类型编码
+var statementTypeGroups = map[string][]int8{
+	"deposit":    {dto.MoneyLogTypeDeposit},
+	"payout":     {dto.MoneyLogTypePayout},
+	"freeze":     {dto.MoneyLogTypeFreeze, dto.MoneyLogTypeFreezeAdditional},
+	"unfreeze":   {dto.MoneyLogTypeUnfreeze, dto.MoneyLogTypeUnfreezeDel, dto.MoneyLogTypeUnfreezeExcess},
+	"commission": {dto.MoneyLogTypeDepositComm, dto.MoneyLogTypePayoutComm},
+	"fx":         {dto.MoneyLogTypeFxOut, dto.MoneyLogTypeFxIn},
+}
+
+// statementTypeName 资金流水类型编码 -> 对外流水类型
+func statementTypeName(t int8) string {
+	for name, codes := range statementTypeGroups {
+		for _, c := range codes {
+			if c == t {
+				return name
+			}
+		}
+	}
+	return "other"
+}
+
+// statementQuery 已校验的流水查询条件
+type statementQuery struct {
+	merchantNo string
+	filter     dao.MoneyLogFilter
+	page       int
+	pageSize   int
+}
+
+func statementParamErr(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrStatementParams, fmt.Sprintf(format, args...))
+}
+
+// parseStatementTypes 解析逗号分隔的流水类型
+func parseStatementTypes(raw string) ([]int8, error) {
+	var codes []int8
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		group, ok := statementTypeGroups[t]
+		if !ok {
+			return nil, statementParamErr("不支持的流水类型 %s", t)
+		}
+		codes = append(codes, group...)
+	}
+	return codes, nil
+}
+
+// statementPeriod 解析 CSV 对账周期（day: yyyy-MM-dd，month: yyyy-MM）
+func statementPeriod(period, date string) (time.Time, time.Time, error) {
+	switch strings.ToLower(period) {
+	case "day":
+		start, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, statementParamErr("date 格式应为 yyyy-MM-dd")
+		}
+		return start, start.AddDate(0, 0, 1), nil
+	case "month":
+		start, err := time.ParseInLocation("2006-01", date, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, statementParamErr("date 格式应为 yyyy-MM")
+		}
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, statementParamErr("period 仅支持 day | month")
+}
+
+func (s *AccountService) parseStatementReq(req dto.AccountStatementReq) (*statementQuery, error) {
+	merchant, err := s.mainDao.GetMerchant(req.MerchantNo)
+	if err != nil || merchant.Status != 1 {
+		log.Printf("商户不存在: %v", req.MerchantNo)
+		return nil, errors.New("商户不存在")
+	}
+
+	q := &statementQuery{
+		merchantNo: req.MerchantNo,
+		filter: dao.MoneyLogFilter{
+			UID:      merchant.MerchantID,
+			Currency: strings.ToUpper(req.Currency),
+			OrderNo:  strings.TrimSpace(req.OrderNo),
+		},
+		page:     1,
+		pageSize: statementDefaultPageSize,
+	}
+	if q.filter.Types, err = parseStatementTypes(req.Types); err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(req.Format, "csv") {
+		q.filter.Start, q.filter.End, err = statementPeriod(req.Period, req.Date)
+		return q, err
+	}
+
+	// JSON 查询：默认当天，时间跨度不超过 31 天
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, 1)
+	if req.StartTime != "" {
+		if start, err = time.ParseInLocation(statementTimeLayout, req.StartTime, time.Local); err != nil {
+			return nil, statementParamErr("start_time 格式应为 yyyy-MM-dd HH:mm:ss")
+		}
+		if req.EndTime == "" {
+			end = start.AddDate(0, 0, 1)
+		}
+	}
+	if req.EndTime != "" {
+		if end, err = time.ParseInLocation(statementTimeLayout, req.EndTime, time.Local); err != nil {
+			return nil, statementParamErr("end_time 格式应为 yyyy-MM-dd HH:mm:ss")
+		}
+		if req.StartTime == "" {
+			start = end.AddDate(0, 0, -1)
+		}
+	}
+	if !end.After(start) {
+		return nil, statementParamErr("end_time 必须大于 start_time")
+	}
+	if end.Sub(start) > statementMaxRange {
+		return nil, statementParamErr("查询时间跨度不能超过 31 天")
+	}
+	q.filter.Start, q.filter.End = start, end
+
+	if req.Page != "" {
+		if q.page, err = strconv.Atoi(req.Page); err != nil || q.page < 1 {
+			return nil, statementParamErr("page 必须为正整数")
+		}
+	}
+	if req.PageSize != "" {
+		if q.pageSize, err = strconv.Atoi(req.PageSize); err != nil || q.pageSize < 1 {
+			return nil, statementParamErr("page_size 必须为正整数")
+		}
+		if q.pageSize > statementMaxPageSize {
+			q.pageSize = statementMaxPageSize
+		}
+	}
+	return q, nil
+}
+
+// statementBalances 计算期初、期末余额（不受类型/订单号筛选影响）
+func (s *AccountService) statementBalances(f dao.MoneyLogFilter) (decimal.Decimal, decimal.Decimal, error) {
+	opening, ok, err := s.mainDao.GetBalanceAt(f.UID, f.Currency, f.Start)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	if !ok {
+		// 没有任何流水：以当前账户余额为准
+		account, aErr := s.mainDao.GetAccountDetail(f.UID, f.Currency)
+		if aErr == nil {
+			opening, _ = decimal.NewFromString(account.Amount)
+		}
+		return opening, opening, nil
+	}
+	closing, ok, err := s.mainDao.GetBalanceAt(f.UID, f.Currency, f.End)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	if !ok {
+		closing = opening
+	}
+	return opening, closing, nil
+}
+
+func toStatementItem(l mainmodel.MoneyLog) dto.AccountStatementItem {
+	return dto.AccountStatementItem{
+		Id:          l.ID,
+		Type:        l.Type,
+		TypeName:    statementTypeName(l.Type),
+		Money:       l.Money.StringFixed(2),
+		OldBalance:  l.OldBalance.StringFixed(2),
+		Balance:     l.Balance.StringFixed(2),
+		OrderNo:     l.OrderNo,
+		MOrderNo:    l.MOrderNo,
+		Description: l.Description,
+		CreateTime:  l.CreateTime.Format(statementTimeLayout),
+	}
+}
+
+// Statement 分页查询商户资金流水
+func (s *AccountService) Statement(req dto.AccountStatementReq) (dto.AccountStatementResp, error) {
+	var resp dto.AccountStatementResp
+	q, err := s.parseStatementReq(req)
+	if err != nil {
+		return resp, err
+	}
+
+	opening, closing, err := s.statementBalances(q.filter)
+	if err != nil {
+		return resp, fmt.Errorf("计算期初期末余额失败: %w", err)
+	}
+	logs, total, err := s.mainDao.ListMoneyLogs(q.filter, q.pageSize, (q.page-1)*q.pageSize)
+	if err != nil {
+		return resp, err
+	}
+
+	resp = dto.AccountStatementResp{
+		Code:           "0",
+		Msg:            "成功",
+		MerchantNo:     q.merchantNo,
+		Currency:       q.filter.Currency,
+		StartTime:      q.filter.Start.Format(statementTimeLayout),
+		EndTime:        q.filter.End.Format(statementTimeLayout),
+		OpeningBalance: opening.StringFixed(2),
+		ClosingBalance: closing.StringFixed(2),
+		Total:          total,
+		Page:           q.page,
+		PageSize:       q.pageSize,
+		List:           make([]dto.AccountStatementItem, 0, len(logs)),
+	}
+	for _, l := range logs {
+		resp.List = append(resp.List, toStatementItem(l))
+	}
+	return resp, nil
+}
+
+// StatementFileName 校验 CSV 对账单请求并返回文件名
+func (s *AccountService) StatementFileName(req dto.AccountStatementReq) (string, error) {
+	if _, _, err := statementPeriod(req.Period, req.Date); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("statement_%s_%s_%s.csv", req.MerchantNo, strings.ToUpper(req.Currency), req.Date), nil
+}
+
+// WriteStatementCSV 输出日/月对账单 CSV（分批读取，避免整月流水一次加载）
+func (s *AccountService) WriteStatementCSV(w io.Writer, req dto.AccountStatementReq) error {
+	q, err := s.parseStatementReq(req)
+	if err != nil {
+		return err
+	}
+	opening, closing, err := s.statementBalances(q.filter)
+	if err != nil {
+		return fmt.Errorf("计算期初期末余额失败: %w", err)
+	}
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"merchant_no", q.merchantNo, "currency", q.filter.Currency})
+	_ = cw.Write([]string{"start_time", q.filter.Start.Format(statementTimeLayout), "end_time", q.filter.End.Format(statementTimeLayout)})
+	_ = cw.Write([]string{"opening_balance", opening.StringFixed(2), "closing_balance", closing.StringFixed(2)})
+	_ = cw.Write([]string{"id", "create_time", "type", "money", "old_balance", "balance", "order_no", "m_order_no", "description"})
+
+	err = s.mainDao.EachMoneyLogs(q.filter, statementExportBatch, func(logs []mainmodel.MoneyLog) error {
+		for _, l := range logs {
+			it := toStatementItem(l)
+			if wErr := cw.Write([]string{
+				strconv.FormatUint(it.Id, 10), it.CreateTime, it.TypeName, it.Money,
+				it.OldBalance, it.Balance, it.OrderNo, it.MOrderNo, it.Description,
+			}); wErr != nil {
+				return wErr
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return fmt.Errorf("导出资金流水失败: %w", err)
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,493 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/copier"
+	"github.com/shopspring/decimal"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
+	"wht-order-api/internal/idgen"
+	"wht-order-api/internal/lifecycle"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/system"
+)
+
+const (
+	payoutReassignLockKey = "payout_reassign_lock:"
+	payoutRequestCacheKey = "payout_req:"
+)
+
+// AutoReassignService 代付自动改派引擎
+// 上游回调失败后，按权重轮询选择下一个未失败过的上游通道重新提交，
+// 重新计算费用并调整冻结金额，同时记录每次尝试的改派链路。
+type AutoReassignService struct {
+	payoutSvc *PayoutOrderService
+	mainDao   dao.MainRepository
+	orderDao  dao.PayoutOrderRepository
+}
+
+func NewAutoReassignService(pub event.Publisher) *AutoReassignService {
+	return newAutoReassignService(NewPayoutOrderService(pub))
+}
+
+// NewAutoReassignServiceWithRepos 注入仓储（单元测试使用内存实现；改派不访问索引表）
+func NewAutoReassignServiceWithRepos(pub event.Publisher, mainRepo dao.MainRepository, orderRepo dao.PayoutOrderRepository) *AutoReassignService {
+	return newAutoReassignService(NewPayoutOrderServiceWithRepos(pub, mainRepo, orderRepo, nil))
+}
+
+func newAutoReassignService(payoutSvc *PayoutOrderService) *AutoReassignService {
+	return &AutoReassignService{
+		payoutSvc: payoutSvc,
+		mainDao:   payoutSvc.mainDao,
+		orderDao:  payoutSvc.orderDao,
+	}
+}
+
+// Enabled 是否开启自动改派
+func (s *AutoReassignService) Enabled() bool {
+	return config.C.Reassign.Enabled
+}
+
+// HandlePayoutFailure 处理上游代付失败
+// 返回 true 表示已处理（已改派到新通道或为过期回调），false 表示改派次数/时间用尽，需进入人工流程
+func (s *AutoReassignService) HandlePayoutFailure(orderId, failedUpOrderId uint64, reason string) (handled bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] auto reassign panic: %v\n%s", r, debug.Stack())
+			notify.Notify(system.BotChatID, "error", "代付自动改派Panic", fmt.Sprintf("平台订单号: %d\npanic: %v", orderId, r), true)
+			handled, err = false, fmt.Errorf("auto reassign panic: %v", r)
+		}
+	}()
+
+	if !s.Enabled() {
+		return false, nil
+	}
+
+	// 1 订单级锁，防止并发回调重复改派
+	lockKey := payoutReassignLockKey + strconv.FormatUint(orderId, 10)
+	ok, lockErr := dal.RedisClient.SetNX(dal.RedisCtx, lockKey, failedUpOrderId, time.Minute).Result()
+	if lockErr != nil {
+		return false, fmt.Errorf("acquire reassign lock failed: %w", lockErr)
+	}
+	if !ok {
+		log.Printf("[AUTO-REASSIGN] 订单正在改派中，跳过 order=%d upOrderId=%d", orderId, failedUpOrderId)
+		return true, nil
+	}
+	defer dal.RedisClient.Del(dal.RedisCtx, lockKey)
+
+	// 2 订单
+	now := time.Now()
+	orderTable := shard.OutOrderShard.GetTable(orderId, now)
+	order, err := s.orderDao.GetByOrderId(orderTable, orderId)
+	if err != nil {
+		return false, fmt.Errorf("get order failed: %w", err)
+	}
+	if order == nil {
+		return false, fmt.Errorf("order not found: %d", orderId)
+	}
+	if order.Status == 2 {
+		log.Printf("[AUTO-REASSIGN] 订单已成功，忽略失败回调 order=%d upOrderId=%d", orderId, failedUpOrderId)
+		return true, nil
+	}
+	// 过期回调：订单已绑定到其他上游交易
+	if order.UpOrderID != nil && *order.UpOrderID != failedUpOrderId {
+		log.Printf("[AUTO-REASSIGN] 过期失败回调，订单当前交易=%d，回调交易=%d", *order.UpOrderID, failedUpOrderId)
+		return true, nil
+	}
+
+	// 3 改派链路：记录本次失败
+	chain, err := s.recordFailure(order, failedUpOrderId, reason)
+	if err != nil {
+		return false, err
+	}
+	attempt := 0
+	failedUpstreams := make(map[int64]bool, len(chain))
+	for _, c := range chain {
+		if c.Attempt > attempt {
+			attempt = c.Attempt
+		}
+		failedUpstreams[c.SupplierID] = true
+	}
+
+	// 4 次数 & 时间预算
+	if exhausted, why := s.budgetExhausted(order, attempt, now); exhausted {
+		s.markManual(order, chain, why)
+		return false, nil
+	}
+
+	// 5 商户 & 原始请求
+	merchant, err := s.mainDao.GetMerchantId(strconv.FormatUint(order.MID, 10))
+	if err != nil || merchant == nil {
+		return false, fmt.Errorf("merchant invalid: %w", err)
+	}
+	req := s.loadPayoutRequest(order, merchant)
+
+	// 6 候选通道（排除已失败的上游）
+	channelCode := ""
+	if order.ChannelCode != nil {
+		channelCode = *order.ChannelCode
+	}
+	products, err := s.payoutSvc.selectWeightedPollingChannels(uint(order.MID), channelCode, 2, order.Currency, order.Amount)
+	if err != nil {
+		s.markManual(order, chain, fmt.Sprintf("无可用改派通道: %v", err))
+		return false, nil
+	}
+	var candidates []dto.PayProductVo
+	for _, p := range products {
+		if !failedUpstreams[p.UpstreamId] {
+			candidates = append(candidates, p)
+		}
+	}
+
+	// 7 依次尝试候选通道
+	freezeAmount := order.FreezeAmount
+	for _, product := range candidates {
+		if exhausted, why := s.budgetExhausted(order, attempt, time.Now()); exhausted {
+			s.markManual(order, chain, why)
+			return false, nil
+		}
+		attempt++
+
+		item, newFreeze, dErr := s.dispatch(order, merchant, product, freezeAmount, attempt)
+		if dErr != nil {
+			log.Printf("[AUTO-REASSIGN] 改派准备失败 order=%d attempt=%d 通道=%s/%s err=%v",
+				order.OrderID, attempt, product.SysChannelCode, product.UpstreamCode, dErr)
+			chain = append(chain, s.appendChain(order.OrderID, attempt, 0, product, ordermodel.ReassignResultSubmitFail, dErr.Error()))
+			continue
+		}
+		freezeAmount = newFreeze
+		txId := item.UpOrderID
+		order.UpOrderID = &txId
+		chain = append(chain, item)
+
+		_, callErr := s.payoutSvc.callUpstreamService(merchant, &req, &product, txId, order)
+		if callErr == nil {
+			s.payoutSvc.clearUpstreamFail(uint64(product.UpstreamId), product.UpstreamCode, product.SysChannelCode)
+			pid := product.ID
+			lifecycle.Go("success-rate", func() {
+				if e := s.mainDao.UpdateSuccessRate(pid, true); e != nil {
+					log.Printf("update channel success rate failed: %v", e)
+				}
+			})
+
+			log.Printf("[AUTO-REASSIGN] ✅ 改派提交成功 order=%d attempt=%d 通道=%s/%s 交易=%d",
+				order.OrderID, attempt, product.SysChannelCode, product.UpstreamCode, txId)
+			notify.Notify(system.BotChatID, "info", "代付自动改派",
+				fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n第 `%d` 次改派\n新通道: `%s/%s`\n供应商: `%s`\n交易订单号: `%d`\n上次失败原因: `%s`",
+					order.OrderID, order.MOrderID, attempt, product.SysChannelCode, product.UpstreamCode, product.UpstreamTitle, txId, reason), false)
+			return true, nil
+		}
+
+		// 提交上游失败，继续下一个通道
+		log.Printf("[AUTO-REASSIGN] 改派提交上游失败 order=%d attempt=%d 通道=%s/%s err=%v",
+			order.OrderID, attempt, product.SysChannelCode, product.UpstreamCode, callErr)
+		s.payoutSvc.recordUpstreamFail(uint64(product.UpstreamId), product.UpstreamTitle, product.UpstreamCode, product.SysChannelCode)
+		pid := product.ID
+		lifecycle.Go("success-rate", func() {
+			if e := s.mainDao.UpdateSuccessRate(pid, false); e != nil {
+				log.Printf("update channel fail rate failed: %v", e)
+			}
+		})
+		if uErr := s.orderDao.UpdateReassignResult(order.OrderID, txId, ordermodel.ReassignResultSubmitFail, truncateReason(callErr.Error())); uErr != nil {
+			log.Printf("[AUTO-REASSIGN] 更新改派链路失败 order=%d err=%v", order.OrderID, uErr)
+		}
+		chain[len(chain)-1].Result = ordermodel.ReassignResultSubmitFail
+		chain[len(chain)-1].Reason = truncateReason(callErr.Error())
+
+		if !isRetryableUpstreamErr(callErr) {
+			log.Printf("[AUTO-REASSIGN] 上游错误不可重试，停止改派 order=%d attempt=%d err=%v", order.OrderID, attempt, callErr)
+			break
+		}
+	}
+
+	s.markManual(order, chain, "所有可用上游均已尝试失败")
+	return false, nil
+}
+
+// recordFailure 记录本次上游失败；首次改派时补录首次下单的链路记录
+func (s *AutoReassignService) recordFailure(order *ordermodel.MerchantPayOutOrderM, failedUpOrderId uint64, reason string) ([]ordermodel.PayoutReassignM, error) {
+	chain, err := s.orderDao.ListReassignByOrderId(order.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("list reassign chain failed: %w", err)
+	}
+
+	reason = truncateReason(reason)
+	for i := range chain {
+		if chain[i].UpOrderID == failedUpOrderId {
+			if err := s.orderDao.UpdateReassignResult(order.OrderID, failedUpOrderId, ordermodel.ReassignResultFail, reason); err != nil {
+				return nil, fmt.Errorf("update reassign chain failed: %w", err)
+			}
+			chain[i].Result = ordermodel.ReassignResultFail
+			chain[i].Reason = reason
+			return chain, nil
+		}
+	}
+
+	// 链路中没有该交易：首次下单的上游失败
+	first := ordermodel.PayoutReassignM{
+		OrderID:     order.OrderID,
+		Attempt:     len(chain),
+		UpOrderID:   failedUpOrderId,
+		SupplierID:  order.SupplierID,
+		UpChannelID: order.UpChannelID,
+		Result:      ordermodel.ReassignResultFail,
+		Reason:      reason,
+		CreateTime:  time.Now(),
+	}
+	if order.UpChannelCode != nil {
+		first.UpstreamCode = *order.UpChannelCode
+	}
+	if err := s.orderDao.InsertReassign(&first); err != nil {
+		return nil, fmt.Errorf("insert reassign chain failed: %w", err)
+	}
+	return append(chain, first), nil
+}
+
+// appendChain 追加一条改派链路记录
+func (s *AutoReassignService) appendChain(orderId uint64, attempt int, txId uint64, product dto.PayProductVo, result int8, reason string) ordermodel.PayoutReassignM {
+	item := ordermodel.PayoutReassignM{
+		OrderID:      orderId,
+		Attempt:      attempt,
+		UpOrderID:    txId,
+		SupplierID:   product.UpstreamId,
+		UpChannelID:  product.ID,
+		UpstreamCode: product.UpstreamCode,
+		Result:       result,
+		Reason:       truncateReason(reason),
+		CreateTime:   time.Now(),
+	}
+	if err := s.orderDao.InsertReassign(&item); err != nil {
+		log.Printf("[AUTO-REASSIGN] 写入改派链路失败 order=%d attempt=%d err=%v", orderId, attempt, err)
+	}
+	return item
+}
+
+// budgetExhausted 判断改派次数或时间预算是否用尽
+func (s *AutoReassignService) budgetExhausted(order *ordermodel.MerchantPayOutOrderM, attempt int, now time.Time) (bool, string) {
+	cfg := config.C.Reassign
+	if attempt >= cfg.MaxAttempts {
+		return true, fmt.Sprintf("已达最大改派次数 %d", cfg.MaxAttempts)
+	}
+	if order.CreateTime != nil && now.Sub(*order.CreateTime) > cfg.TimeBudget {
+		return true, fmt.Sprintf("超出改派时间窗口 %s", cfg.TimeBudget)
+	}
+	return false, ""
+}
+
+// dispatch 切换订单到新通道：重新计算费用、调整冻结、创建新的上游交易并记录链路
+func (s *AutoReassignService) dispatch(
+	order *ordermodel.MerchantPayOutOrderM,
+	merchant *mainmodel.Merchant,
+	product dto.PayProductVo,
+	oldFreeze decimal.Decimal,
+	attempt int,
+) (ordermodel.PayoutReassignM, decimal.Decimal, error) {
+	amount := order.Amount
+
+	// 1 重新计算结算
+	settle, err := s.payoutSvc.calculateSettlement(merchant, product, amount, order.PayMethod)
+	if err != nil {
+		return ordermodel.PayoutReassignM{}, oldFreeze, fmt.Errorf("recalculate settlement failed: %w", err)
+	}
+	var orderSettle dto.SettlementResult
+	if err := copier.Copy(&orderSettle, &settle); err != nil {
+		return ordermodel.PayoutReassignM{}, oldFreeze, fmt.Errorf("copy settlement failed: %w", err)
+	}
+
+	// 2 调整冻结金额：补冻结或释放多余冻结（资金流水按 订单号+改派次数 幂等）
+	orderNo := strconv.FormatUint(order.OrderID, 10)
+	newFreeze := amount.Add(settle.MerchantTotalFee).Add(settle.AgentTotalFee)
+	diff := newFreeze.Sub(oldFreeze)
+	if diff.GreaterThan(decimal.Zero) {
+		if err := s.mainDao.FreezeAdditionalAmount(merchant.MerchantID, order.Currency, orderNo, attempt, diff, merchant.NickName, order.MOrderID); err != nil {
+			return ordermodel.PayoutReassignM{}, oldFreeze, fmt.Errorf("freeze additional amount failed: %w", err)
+		}
+		log.Printf("[AUTO-REASSIGN-FREEZE] 补冻结差额 order=%d diff=%s (旧=%s, 新=%s)",
+			order.OrderID, diff.StringFixed(4), oldFreeze.StringFixed(4), newFreeze.StringFixed(4))
+	} else if diff.LessThan(decimal.Zero) {
+		if err := s.mainDao.ReleaseExcessFreeze(merchant.MerchantID, order.Currency, orderNo, attempt, diff.Neg(), merchant.NickName, order.MOrderID); err != nil {
+			return ordermodel.PayoutReassignM{}, oldFreeze, fmt.Errorf("release excess freeze failed: %w", err)
+		}
+		log.Printf("[AUTO-REASSIGN-FREEZE] 释放多余冻结 order=%d diff=%s (旧=%s, 新=%s)",
+			order.OrderID, diff.Neg().StringFixed(4), oldFreeze.StringFixed(4), newFreeze.StringFixed(4))
+	}
+
+	// 3 新上游交易 + 订单绑定 + 链路记录（订单库事务）
+	now := time.Now()
+	costFee := amount.Mul(product.CostRate).Div(decimal.NewFromInt(100)).Add(product.CostFee)
+	orderFee := amount.Mul(product.MDefaultRate).Div(decimal.NewFromInt(100)).Add(product.MSingleFee)
+	profitFee := orderFee.Sub(costFee)
+	txId := idgen.New()
+	item := ordermodel.PayoutReassignM{
+		OrderID:      order.OrderID,
+		Attempt:      attempt,
+		UpOrderID:    txId,
+		SupplierID:   product.UpstreamId,
+		UpChannelID:  product.ID,
+		UpstreamCode: product.UpstreamCode,
+		Result:       ordermodel.ReassignResultPending,
+		CreateTime:   now,
+	}
+
+	err = s.orderDao.Transaction(func(orderDao dao.PayoutOrderRepository) error {
+		tx := &ordermodel.PayoutUpstreamTxM{
+			OrderID:    order.OrderID,
+			MerchantID: strconv.FormatUint(merchant.MerchantID, 10),
+			SupplierId: uint64(product.UpstreamId),
+			Amount:     amount,
+			Currency:   product.Currency,
+			Status:     0,
+			UpOrderId:  txId,
+			CreateTime: &now,
+		}
+		if err := orderDao.InsertTx(shard.UpOutOrderShard.GetTable(txId, now), tx); err != nil {
+			return fmt.Errorf("insert transaction failed: %w", err)
+		}
+
+		orderTable := shard.OutOrderShard.GetTable(order.OrderID, now)
+		if err := orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+			"up_order_id":      txId,
+			"reassign_order":   1,
+			"supplier_id":      product.UpstreamId,
+			"channel_id":       product.SysChannelID,
+			"up_channel_id":    product.ID,
+			"channel_code":     product.SysChannelCode,
+			"channel_title":    product.SysChannelTitle,
+			"up_channel_code":  product.UpstreamCode,
+			"up_channel_title": product.UpChannelTitle,
+			"m_rate":           product.MDefaultRate,
+			"up_rate":          product.CostRate,
+			"m_fixed_fee":      product.MSingleFee,
+			"up_fixed_fee":     product.CostFee,
+			"fees":             settle.MerchantTotalFee,
+			"cost":             costFee,
+			"profit":           profitFee,
+			"freeze_amount":    newFreeze,
+			"settle_snapshot":  ordermodel.PayoutSettleSnapshot(orderSettle),
+			"status":           1,
+			"remark":           fmt.Sprintf("自动改派#%d→%s/%s %s", attempt, product.SysChannelCode, product.UpstreamCode, now.Format("15:04:05")),
+			"update_time":      now,
+		}); err != nil {
+			return fmt.Errorf("update order bind failed: %w", err)
+		}
+
+		return orderDao.InsertReassign(&item)
+	})
+	if err != nil {
+		// 订单未切换成功，冻结差额回滚
+		s.rollbackFreeze(order, merchant, diff, attempt)
+		return ordermodel.PayoutReassignM{}, oldFreeze, err
+	}
+
+	order.FreezeAmount = newFreeze
+	order.SupplierID = product.UpstreamId
+	order.UpChannelID = product.ID
+	order.SettleSnapshot = ordermodel.PayoutSettleSnapshot(orderSettle)
+	return item, newFreeze, nil
+}
+
+// rollbackFreeze 订单切换失败时回滚冻结差额（与调整时同一幂等键，流水类型相反）
+func (s *AutoReassignService) rollbackFreeze(order *ordermodel.MerchantPayOutOrderM, merchant *mainmodel.Merchant, diff decimal.Decimal, attempt int) {
+	orderNo := strconv.FormatUint(order.OrderID, 10)
+	var err error
+	if diff.GreaterThan(decimal.Zero) {
+		err = s.mainDao.ReleaseExcessFreeze(merchant.MerchantID, order.Currency, orderNo, attempt, diff, merchant.NickName, order.MOrderID)
+	} else if diff.LessThan(decimal.Zero) {
+		err = s.mainDao.FreezeAdditionalAmount(merchant.MerchantID, order.Currency, orderNo, attempt, diff.Neg(), merchant.NickName, order.MOrderID)
+	}
+	if err != nil {
+		notify.Notify(system.BotChatID, "error", "代付自动改派冻结回滚失败",
+			fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n差额: `%s`\n错误: `%v`\n\n请人工核对商户冻结资金。",
+				order.OrderID, order.MOrderID, diff.StringFixed(4), err), true)
+	}
+}
+
+// markManual 改派用尽，订单转人工处理
+func (s *AutoReassignService) markManual(order *ordermodel.MerchantPayOutOrderM, chain []ordermodel.PayoutReassignM, why string) {
+	orderTable := shard.OutOrderShard.GetTable(order.OrderID, time.Now())
+	if err := s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+		"status":      6, // 人工处理
+		"remark":      fmt.Sprintf("自动改派结束, 等待人工介入: %s", why),
+		"update_time": time.Now(),
+	}); err != nil {
+		log.Printf("[WARN] 更新订单状态失败 order=%d err=%v", order.OrderID, err)
+	}
+
+	var sb strings.Builder
+	for _, c := range chain {
+		sb.WriteString(fmt.Sprintf("#%d 供应商=%d 通道=%s 结果=%d %s\n", c.Attempt, c.SupplierID, c.UpstreamCode, c.Result, c.Reason))
+	}
+	log.Printf("[AUTO-REASSIGN] 改派结束转人工 order=%d 原因=%s", order.OrderID, why)
+	notify.Notify(system.BotChatID, "error", "代付自动改派结束",
+		fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n金额: `%s`\n原因: `%s`\n\n改派链路:\n%s\n当前资金已冻结，请人工处理。",
+			order.OrderID, order.MOrderID, order.Amount.String(), why, sb.String()), true)
+}
+
+// loadPayoutRequest 读取下单时缓存的商户请求，缓存缺失时根据订单信息重建
+func (s *AutoReassignService) loadPayoutRequest(order *ordermodel.MerchantPayOutOrderM, merchant *mainmodel.Merchant) dto.CreatePayoutOrderReq {
+	cacheKey := payoutRequestCacheKey + strconv.FormatUint(order.OrderID, 10)
+	if cached, err := dal.RedisClient.Get(dal.RedisCtx, cacheKey).Result(); err == nil && cached != "" {
+		var req dto.CreatePayoutOrderReq
+		if jErr := json.Unmarshal([]byte(cached), &req); jErr == nil {
+			return req
+		}
+	}
+
+	req := dto.CreatePayoutOrderReq{
+		MerchantNo:   merchant.AppId,
+		TranFlow:     order.MOrderID,
+		Amount:       order.Amount.String(),
+		NotifyUrl:    order.NotifyURL,
+		AccNo:        order.AccountNo,
+		AccName:      order.AccountName,
+		PayMethod:    order.PayMethod,
+		BankCode:     order.BankCode,
+		BankName:     order.BankName,
+		PayEmail:     order.PayEmail,
+		PayPhone:     order.PayPhone,
+		IdentityType: order.IdentityType,
+		IdentityNum:  order.IdentityNum,
+		ClientId:     order.MIP,
+	}
+	if order.ChannelCode != nil {
+		req.PayType = *order.ChannelCode
+	}
+	return req
+}
+
+// cachePayoutRequest 缓存商户下单请求，供自动改派重新提交上游使用
+func cachePayoutRequest(oid uint64, req dto.CreatePayoutOrderReq) {
+	if !config.C.Reassign.Enabled {
+		return
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		log.Printf("缓存代付请求序列化失败: oid=%d err=%v", oid, err)
+		return
+	}
+	ttl := config.C.Reassign.TimeBudget + time.Hour
+	if err := dal.RedisClient.Set(dal.RedisCtx, payoutRequestCacheKey+strconv.FormatUint(oid, 10), data, ttl).Err(); err != nil {
+		log.Printf("缓存代付请求失败: oid=%d err=%v", oid, err)
+	}
+}
+
+func truncateReason(reason string) string {
+	r := []rune(reason)
+	if len(r) > 250 {
+		return string(r[:250])
+	}
+	return reason
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/idgen"
+	"wht-order-api/internal/lifecycle"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/shard"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	reassignChannelCode   = "BR_PIX_OUT"
+	reassignInterfaceCode = "test_reassign_flow"
+)
+
+// fakePayoutConnector 代付原生连接器替身：余额充足，代付下单受理（reject 时同步返回失败）
+type fakePayoutConnector struct {
+	connector.Connector
+	calls  int
+	reject bool
+}
+
+func (c *fakePayoutConnector) Balance(ctx context.Context, req dto.UpstreamRequest) (decimal.Decimal, error) {
+	return decimal.NewFromInt(100000), nil
+}
+
+func (c *fakePayoutConnector) CreatePayout(ctx context.Context, req dto.UpstreamRequest) (*connector.OrderResult, error) {
+	c.calls++
+	if c.reject {
+		return &connector.OrderResult{Status: connector.StatusFail, Code: "ACCOUNT_INVALID", Msg: "invalid account"}, nil
+	}
+	return &connector.OrderResult{UpOrderNo: "UP-" + req.MchOrderId, Status: connector.StatusPending}, nil
+}
+
+type reassignFixture struct {
+	main   *memdao.MainStore
+	orders *memdao.PayoutOrderStore
+	conn   *fakePayoutConnector
+	payout *PayoutOrderService
+	svc    *AutoReassignService
+}
+
+// newReassignFixture 商户 1001（无代理）开通 BR_PIX_OUT，余额 1000 BRL；初始只挂上游 401（费率 3%）
+func newReassignFixture(t *testing.T, maxAttempts int) *reassignFixture {
+	t.Helper()
+	fakeredis.Use(t)
+	shard.InitShardEngines()
+	if err := idgen.InitNode("default", 1); err != nil {
+		t.Fatalf("init idgen: %v", err)
+	}
+	prevTimeout, prevApproval, prevReassign := config.C.Upstream.Timeout.Payout, config.C.Approval.Enabled, config.C.Reassign
+	config.C.Upstream.Timeout.Payout = 5 * time.Second
+	config.C.Approval.Enabled = false
+	config.C.Reassign.Enabled, config.C.Reassign.MaxAttempts, config.C.Reassign.TimeBudget = true, maxAttempts, time.Hour
+	t.Cleanup(func() {
+		config.C.Upstream.Timeout.Payout, config.C.Approval.Enabled, config.C.Reassign = prevTimeout, prevApproval, prevReassign
+	})
+
+	f := &reassignFixture{
+		main:   memdao.NewMainStore(),
+		orders: memdao.NewPayoutOrderStore(),
+		conn:   &fakePayoutConnector{},
+	}
+	connector.Register(reassignInterfaceCode, f.conn)
+	f.main.AddMerchant(mainmodel.Merchant{MerchantID: testMerchantID, NickName: "m1", AppId: "APP1001", ApiKey: "secret", Status: 1, Currency: "BRL"})
+	f.main.AddCountry(dto.CurrencyCodeResponse{ID: 76, Code: "BRL", Country: "Brazil"})
+	f.main.AddSysChannel(dto.PayWayVo{Id: 8, Title: "PIX 代付", Currency: "BRL", Coding: reassignChannelCode, Type: 2, Status: 1})
+	f.main.AddMerchantChannel(dto.MerchantChannelDTO{
+		MId: testMerchantID, SysChannelId: 8, Status: 1, Type: 2, DispatchMode: 1, Currency: "BRL",
+		SysChannelCode: reassignChannelCode, DefaultRate: decimal.NewFromInt(3),
+	})
+	f.addProduct(31, 401, 3)
+	f.main.SetAccount(testMerchantID, "BRL", decimal.NewFromInt(1000), decimal.Zero)
+
+	f.payout = NewPayoutOrderServiceWithRepos(nopPublisher{}, f.main, f.orders, f.orders.Index)
+	t.Cleanup(f.payout.Shutdown)
+	f.svc = NewAutoReassignServiceWithRepos(nopPublisher{}, f.main, f.orders)
+	t.Cleanup(f.svc.payoutSvc.Shutdown)
+	return f
+}
+
+// addProduct 挂一个上游产品，商户费率 rate%，无单笔费用
+func (f *reassignFixture) addProduct(id, upstreamId int64, rate int64) {
+	minAmount, maxAmount := decimal.NewFromInt(10), decimal.NewFromInt(5000)
+	f.main.AddPayProduct(testMerchantID, dto.PayProductVo{
+		ID: id, Currency: "BRL", Type: 2, Status: 1,
+		UpstreamId: upstreamId, UpstreamCode: "UP_" + strconv.FormatInt(upstreamId, 10), UpstreamTitle: "up", UpstreamWeight: 10,
+		InterfaceCode: reassignInterfaceCode,
+		SysChannelID:  8, SysChannelCode: reassignChannelCode, SysChannelTitle: "PIX 代付",
+		MDefaultRate: decimal.NewFromInt(rate), CostRate: decimal.NewFromInt(1),
+		MinAmount: &minAmount, MaxAmount: &maxAmount,
+	})
+}
+
+// create 500 BRL 代付下单，返回落库订单
+func (f *reassignFixture) create(t *testing.T) *ordermodel.MerchantPayOutOrderM {
+	t.Helper()
+	if _, err := f.payout.Create(dto.CreatePayoutOrderReq{
+		MerchantNo: "APP1001", TranFlow: "P-9001", Amount: "500", PayType: reassignChannelCode,
+		AccNo: "12345678", AccName: "Joao", PayMethod: "PIX",
+	}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	waitLifecycle(t)
+	all := f.orders.Orders()
+	if len(all) != 1 || all[0].UpOrderID == nil {
+		t.Fatalf("orders = %+v", all)
+	}
+	return f.order(t, all[0].OrderID)
+}
+
+func (f *reassignFixture) order(t *testing.T, orderID uint64) *ordermodel.MerchantPayOutOrderM {
+	t.Helper()
+	o, err := f.orders.GetByOrderId(shard.OutOrderShard.GetTable(orderID, time.Now()), orderID)
+	if err != nil || o == nil {
+		t.Fatalf("load order: %v", err)
+	}
+	return o
+}
+
+func (f *reassignFixture) fail(t *testing.T, orderID, upOrderID uint64) bool {
+	t.Helper()
+	handled, err := f.svc.HandlePayoutFailure(orderID, upOrderID, "上游回调失败")
+	if err != nil {
+		t.Fatalf("handle failure: %v", err)
+	}
+	waitLifecycle(t)
+	return handled
+}
+
+func (f *reassignFixture) balance(t *testing.T) (money, freeze decimal.Decimal) {
+	t.Helper()
+	acc, ok := f.main.Account(testMerchantID, "BRL")
+	if !ok {
+		t.Fatal("account not found")
+	}
+	return acc.Money, acc.FreezeMoney
+}
+
+// waitLifecycle 等待异步任务（成功率、请求缓存）结束，避免用例结束后访问已关闭的 fakeredis
+func waitLifecycle(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := lifecycle.Wait(ctx); err != nil {
+		t.Fatalf("background tasks: %v", err)
+	}
+}
+
+func TestAutoReassignToCheaperUpstream(t *testing.T) {
+	f := newReassignFixture(t, 3)
+	first := f.create(t)
+	// 下单冻结 500 + 3% 手续费 15
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(485)) || !freeze.Equal(decimal.NewFromInt(515)) {
+		t.Fatalf("after create = %s/%s, want 485/515", money, freeze)
+	}
+
+	f.addProduct(32, 402, 2)
+	if !f.fail(t, first.OrderID, *first.UpOrderID) {
+		t.Fatal("handled = false, want reassigned")
+	}
+
+	o := f.order(t, first.OrderID)
+	if o.SupplierID != 402 || o.UpOrderID == nil || *o.UpOrderID == *first.UpOrderID || o.Status != 1 {
+		t.Errorf("order = supplier %d, upOrderId %v, status %d", o.SupplierID, o.UpOrderID, o.Status)
+	}
+	// 新通道 2%：冻结 510，释放多余的 5
+	if !o.FreezeAmount.Equal(decimal.NewFromInt(510)) || !o.SettleSnapshot.MerchantTotalFee.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("order freeze/fee = %s/%s, want 510/10", o.FreezeAmount, o.SettleSnapshot.MerchantTotalFee)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(490)) || !freeze.Equal(decimal.NewFromInt(510)) {
+		t.Errorf("after reassign = %s/%s, want 490/510", money, freeze)
+	}
+	logs := f.main.MoneyLogs(testMerchantID)
+	last := logs[len(logs)-1]
+	if last.Type != dto.MoneyLogTypeUnfreezeExcess || last.OrderNo != strconv.FormatUint(o.OrderID, 10) || last.Attempt != 1 {
+		t.Errorf("money log = type %d, order_no %s, attempt %d", last.Type, last.OrderNo, last.Attempt)
+	}
+
+	chain, _ := f.orders.ListReassignByOrderId(o.OrderID)
+	if len(chain) != 2 || chain[0].Result != ordermodel.ReassignResultFail || chain[1].SupplierID != 402 || chain[1].UpOrderID != *o.UpOrderID {
+		t.Errorf("chain = %+v", chain)
+	}
+	if f.conn.calls != 2 {
+		t.Errorf("upstream calls = %d, want 2", f.conn.calls)
+	}
+}
+
+func TestAutoReassignToMoreExpensiveUpstream(t *testing.T) {
+	f := newReassignFixture(t, 3)
+	first := f.create(t)
+
+	f.addProduct(33, 403, 4)
+	if !f.fail(t, first.OrderID, *first.UpOrderID) {
+		t.Fatal("handled = false, want reassigned")
+	}
+
+	// 新通道 4%：冻结 520，从余额补冻结 5
+	o := f.order(t, first.OrderID)
+	if o.SupplierID != 403 || !o.FreezeAmount.Equal(decimal.NewFromInt(520)) {
+		t.Errorf("order = supplier %d, freeze %s", o.SupplierID, o.FreezeAmount)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(480)) || !freeze.Equal(decimal.NewFromInt(520)) {
+		t.Errorf("after reassign = %s/%s, want 480/520", money, freeze)
+	}
+	logs := f.main.MoneyLogs(testMerchantID)
+	last := logs[len(logs)-1]
+	if last.Type != dto.MoneyLogTypeFreezeAdditional || last.OrderNo != strconv.FormatUint(o.OrderID, 10) || last.Attempt != 1 {
+		t.Errorf("money log = type %d, order_no %s, attempt %d", last.Type, last.OrderNo, last.Attempt)
+	}
+}
+
+func TestAutoReassignBudgetExhaustedGoesManual(t *testing.T) {
+	f := newReassignFixture(t, 1)
+	first := f.create(t)
+	f.addProduct(32, 402, 2)
+
+	if !f.fail(t, first.OrderID, *first.UpOrderID) {
+		t.Fatal("first failure: handled = false, want reassigned")
+	}
+	second := f.order(t, first.OrderID)
+
+	// 已改派 1 次达到上限：第二次失败转人工，资金保持冻结
+	if f.fail(t, second.OrderID, *second.UpOrderID) {
+		t.Fatal("second failure: handled = true, want manual")
+	}
+	o := f.order(t, first.OrderID)
+	if o.Status != 6 || *o.UpOrderID != *second.UpOrderID {
+		t.Errorf("order = status %d, upOrderId %d", o.Status, *o.UpOrderID)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(490)) || !freeze.Equal(decimal.NewFromInt(510)) {
+		t.Errorf("balance = %s/%s, want 490/510", money, freeze)
+	}
+	chain, _ := f.orders.ListReassignByOrderId(o.OrderID)
+	if len(chain) != 2 || chain[1].Result != ordermodel.ReassignResultFail {
+		t.Errorf("chain = %+v", chain)
+	}
+	if f.conn.calls != 2 {
+		t.Errorf("upstream calls = %d, want 2", f.conn.calls)
+	}
+}
+
+func TestAutoReassignIgnoresStaleCallback(t *testing.T) {
+	f := newReassignFixture(t, 3)
+	first := f.create(t)
+	f.addProduct(32, 402, 2)
+	if !f.fail(t, first.OrderID, *first.UpOrderID) {
+		t.Fatal("handled = false, want reassigned")
+	}
+	current := f.order(t, first.OrderID)
+	logCount := len(f.main.MoneyLogs(testMerchantID))
+
+	// 旧交易的重复失败回调：订单已绑定新交易，直接忽略
+	if !f.fail(t, first.OrderID, *first.UpOrderID) {
+		t.Fatal("stale callback: handled = false, want ignored")
+	}
+	o := f.order(t, first.OrderID)
+	if *o.UpOrderID != *current.UpOrderID || o.Status != 1 || !o.FreezeAmount.Equal(current.FreezeAmount) {
+		t.Errorf("order changed: upOrderId %d, status %d, freeze %s", *o.UpOrderID, o.Status, o.FreezeAmount)
+	}
+	if n := len(f.main.MoneyLogs(testMerchantID)); n != logCount {
+		t.Errorf("money logs = %d, want %d", n, logCount)
+	}
+	if chain, _ := f.orders.ListReassignByOrderId(o.OrderID); len(chain) != 2 {
+		t.Errorf("chain = %d, want 2", len(chain))
+	}
+	if f.conn.calls != 2 {
+		t.Errorf("upstream calls = %d, want 2", f.conn.calls)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
 	"log"
@@ -11,16 +12,34 @@ import (
 	"strings"
 	"time"
 	"wht-order-api/internal/config"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/constant"
 	"wht-order-api/internal/dto"
+	"wht-order-api/internal/metrics"
 	"wht-order-api/internal/notify"
 	"wht-order-api/internal/utils"
 )
 
-// CallUpstreamReceiveService 调用上游服务下单 - 代收
+// ErrUpstreamBalanceInsufficient 上游可用余额不足（代付可排队等待上游补款）
+var ErrUpstreamBalanceInsufficient = errors.New("上游余额不足")
+
+// CallUpstreamReceiveService 调用上游服务下单 - 代收（失败返回 *UpstreamError）
 func CallUpstreamReceiveService(ctx context.Context, req dto.UpstreamRequest, mchReq *dto.CreateOrderReq) (string, string, string, error) {
+	start := time.Now()
+	mOrderId, upOrderNo, payUrl, err := callUpstreamReceive(ctx, req, mchReq)
+	observeUpstreamCall("receive", req, start, err)
+	return mOrderId, upOrderNo, payUrl, err
+}
+
+func callUpstreamReceive(ctx context.Context, req dto.UpstreamRequest, mchReq *dto.CreateOrderReq) (string, string, string, error) {
 	ctxTimeout, cancel := context.WithTimeout(ctx, config.C.Upstream.Timeout.Receive)
 	defer cancel()
 
+	// ✅ 已接入原生连接器的接口直接调用，未接入的继续走 PHP 网关
+	if conn, ok := connector.Get(req.ProviderKey); ok {
+		return callNativeReceive(ctxTimeout, conn, req, mchReq)
+	}
+
 	params := map[string]interface{}{
 		"mchNo":             req.MchNo,
 		"amount":            req.Amount,
@@ -58,26 +77,29 @@ func CallUpstreamReceiveService(ctx context.Context, req dto.UpstreamRequest, mc
 		notify.NotifyUpstreamAlert("warn", "代收上游不可用", upstreamUrl, mchReq, params, nil, map[string]string{
 			"错误": err.Error(),
 		})
-		return "", "", "", fmt.Errorf("上游服务不可用")
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamNetworkError, Retryable: true, Err: fmt.Errorf("上游服务不可用: %w", err)}
 	}
 
 	// ✅ 带重试逻辑
 	var resp string
+	attempts := 0
 	err := utils.DoWithRetry(ctxTimeout, config.C.Upstream.Retry.Times, config.C.Upstream.Retry.Interval, func() error {
+		attempts++
 		r, err := utils.HttpPostJsonWithContext(ctxTimeout, upstreamUrl, params)
 		if err != nil {
-			return err
+			return retryableTransport(err)
 		}
 		resp = r
 		return nil
 	})
+	metrics.UpstreamRetried("receive", req.ProviderKey, attempts)
 	if err != nil {
 		log.Printf("[Upstream-Receive] 请求失败(重试后仍失败): %v", err)
 		notify.NotifyUpstreamAlert("error", "代收上游请求失败(重试后仍失败)", upstreamUrl, mchReq, params, resp, map[string]string{
 			"错误":   err.Error(),
 			"重试次数": strconv.Itoa(config.C.Upstream.Retry.Times),
 		})
-		return "", "", "", fmt.Errorf("请求上游失败")
+		return "", "", "", classifyTransportError(err)
 	}
 
 	log.Printf("[Upstream-Receive] 响应原始数据: %s", resp)
@@ -100,7 +122,8 @@ func CallUpstreamReceiveService(ctx context.Context, req dto.UpstreamRequest, mc
 		notify.NotifyUpstreamAlert("error", "代收上游响应解析失败", upstreamUrl, mchReq, params, resp, map[string]string{
 			"错误": respErr.Error(),
 		})
-		return "", "", "", fmt.Errorf("上游响应解析失败")
+		// 代收未拿到支付链接即无成交风险，可切换通道
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamDataFormatError, Retryable: true, Err: fmt.Errorf("上游响应解析失败: %w", respErr)}
 	}
 
 	// ✅ 判断响应成功
@@ -110,13 +133,13 @@ func CallUpstreamReceiveService(ctx context.Context, req dto.UpstreamRequest, mc
 			"上游Code": string(response.Data.Code),
 			"上游Msg":  fmt.Sprintf("%v", response.Data.Msg),
 		})
-		return "", "", "", fmt.Errorf("交易失败")
+		return "", "", "", mapUpstreamError(req.ProviderKey, string(response.Data.Code), response.Data.Msg.Text, errors.New("交易失败"))
 	}
 
 	if response.Data.PayUrl == "" {
 		log.Printf("[Upstream-Receive] 上游返回错误: payUrl 无效")
 		notify.NotifyUpstreamAlert("warn", "代收上游返回无效支付链接", upstreamUrl, mchReq, params, response, nil)
-		return "", "", "", fmt.Errorf("交易失败")
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamError, Retryable: true, Err: errors.New("上游返回支付链接为空")}
 	}
 
 	log.Printf("[Upstream-Receive] 收单下单成功, upOrderNo=%s, payUrl=%s, mOrderId=%s",
@@ -125,11 +148,23 @@ func CallUpstreamReceiveService(ctx context.Context, req dto.UpstreamRequest, mc
 	return response.Data.MOrderId, response.Data.UpOrderNo, response.Data.PayUrl, nil
 }
 
-// CallUpstreamPayoutService 调用上游服务下单 - 代付
+// CallUpstreamPayoutService 调用上游服务下单 - 代付（失败返回 *UpstreamError，余额不足为 Code=CodeUpstreamBalanceInsufficient，可用 isUpstreamBalanceLow 判断）
 func CallUpstreamPayoutService(ctx context.Context, req dto.UpstreamRequest, mchReq *dto.CreatePayoutOrderReq) (string, string, string, error) {
+	start := time.Now()
+	mOrderId, upOrderNo, payUrl, err := callUpstreamPayout(ctx, req, mchReq)
+	observeUpstreamCall("payout", req, start, err)
+	return mOrderId, upOrderNo, payUrl, err
+}
+
+func callUpstreamPayout(ctx context.Context, req dto.UpstreamRequest, mchReq *dto.CreatePayoutOrderReq) (string, string, string, error) {
 	ctxTimeout, cancel := context.WithTimeout(ctx, config.C.Upstream.Timeout.Payout)
 	defer cancel()
 
+	// ✅ 已接入原生连接器的接口直接调用，未接入的继续走 PHP 网关
+	if conn, ok := connector.Get(req.ProviderKey); ok {
+		return callNativePayout(ctxTimeout, conn, req, mchReq)
+	}
+
 	params := map[string]interface{}{
 		"mchNo":             req.MchNo,
 		"amount":            req.Amount,
@@ -172,7 +207,7 @@ func CallUpstreamPayoutService(ctx context.Context, req dto.UpstreamRequest, mch
 		notify.NotifyUpstreamAlert("warn", "代付上游不可用", upstreamUrl, mchReq, params, nil, map[string]string{
 			"错误": err.Error(),
 		})
-		return "", "", "", fmt.Errorf("上游服务不可用")
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamNetworkError, Retryable: true, Err: fmt.Errorf("上游服务不可用: %w", err)}
 	}
 
 	// ✅ 查询上游余额
@@ -182,7 +217,7 @@ func CallUpstreamPayoutService(ctx context.Context, req dto.UpstreamRequest, mch
 		notify.NotifyUpstreamAlert("error", "代付上游余额查询失败", req.QueryUrl, mchReq, req, nil, map[string]string{
 			"错误": queryErr.Error(),
 		})
-		return "", "", "", fmt.Errorf("查询上游余额失败: %v", queryErr)
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamError, Retryable: true, Err: fmt.Errorf("查询上游余额失败: %w", queryErr)}
 	}
 
 	log.Printf("[Upstream-Payout] 上游余额: %v, 代付金额: %v", balance, req.Amount)
@@ -192,26 +227,29 @@ func CallUpstreamPayoutService(ctx context.Context, req dto.UpstreamRequest, mch
 			"上游余额": fmt.Sprintf("%v", balance),
 			"代付金额": fmt.Sprintf("%v", req.Amount),
 		})
-		return "", "", "", fmt.Errorf("上游余额不足")
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamBalanceInsufficient, Retryable: true, Err: ErrUpstreamBalanceInsufficient}
 	}
 
 	// ✅ 带重试逻辑
 	var resp string
+	attempts := 0
 	err := utils.DoWithRetry(ctxTimeout, config.C.Upstream.Retry.Times, config.C.Upstream.Retry.Interval, func() error {
+		attempts++
 		r, e := utils.HttpPostJsonWithContext(ctxTimeout, upstreamUrl, params)
 		if e != nil {
-			return e
+			return retryablePayoutTransport(e)
 		}
 		resp = r
 		return nil
 	})
+	metrics.UpstreamRetried("payout", req.ProviderKey, attempts)
 	if err != nil {
 		log.Printf("[Upstream-Payout] 请求失败(重试后仍失败): %v", err)
 		notify.NotifyUpstreamAlert("error", "代付上游请求失败(重试后仍失败)", upstreamUrl, mchReq, params, resp, map[string]string{
 			"错误":   err.Error(),
 			"重试次数": strconv.Itoa(config.C.Upstream.Retry.Times),
 		})
-		return "", "", "", fmt.Errorf("请求上游失败")
+		return "", "", "", classifyPayoutTransportError(err)
 	}
 
 	log.Printf("[Upstream-Payout] 响应原始数据: %s", resp)
@@ -237,7 +275,8 @@ func CallUpstreamPayoutService(ctx context.Context, req dto.UpstreamRequest, mch
 		notify.NotifyUpstreamAlert("error", "代付上游响应解析失败", upstreamUrl, mchReq, params, resp, map[string]string{
 			"错误": err.Error(),
 		})
-		return "", "", "", fmt.Errorf("上游响应解析失败")
+		// 代付上游可能已受理，切换通道有重复出款风险
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamDataFormatError, Retryable: false, Err: fmt.Errorf("上游响应解析失败: %w", err)}
 	}
 
 	// ✅ 响应检查
@@ -247,7 +286,7 @@ func CallUpstreamPayoutService(ctx context.Context, req dto.UpstreamRequest, mch
 			"上游Code": string(response.Data.Code),
 			"上游Msg":  fmt.Sprintf("%v", response.Data.Msg),
 		})
-		return "", "", "", fmt.Errorf("交易失败")
+		return "", "", "", mapUpstreamError(req.ProviderKey, string(response.Data.Code), response.Data.Msg.Text, errors.New("交易失败"))
 	}
 
 	log.Printf("[Upstream-Payout] 代付下单成功, upOrderNo=%s, mOrderId=%s, status=%s",
@@ -256,8 +295,12 @@ func CallUpstreamPayoutService(ctx context.Context, req dto.UpstreamRequest, mch
 	return response.Data.MOrderId, response.Data.UpOrderNo, response.Data.PayUrl, nil
 }
 
-// CheckUpstreamBalance 查询上游余额接口（支持重试 + 超时 + 报警）
+// CheckUpstreamBalance 查询上游余额接口（支持重试 + 超时 + 报警；已接入原生连接器的接口直接查询）
 func CheckUpstreamBalance(ctx context.Context, req dto.UpstreamRequest, mchReq *dto.CreatePayoutOrderReq) (decimal.Decimal, error) {
+	if conn, ok := connector.Get(req.ProviderKey); ok {
+		return conn.Balance(ctx, req)
+	}
+
 	upstreamBalanceUrl := config.C.Upstream.BalanceApiUrl
 	if upstreamBalanceUrl == "" {
 		return decimal.Zero, fmt.Errorf("未配置上游余额查询地址")
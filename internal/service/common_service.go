@@ -7,9 +7,9 @@ import (
 )
 
 type CommonService struct {
-	mainDao       *dao.MainDao
-	orderDao      *dao.OrderDao
-	indexTableDao *dao.IndexTableDao
+	mainDao       dao.MainRepository
+	orderDao      dao.OrderRepository
+	indexTableDao dao.IndexTableRepository
 }
 
 func NewCommonService() *CommonService {
@@ -20,6 +20,15 @@ func NewCommonService() *CommonService {
 	}
 }
 
+// NewCommonServiceWithRepos 注入仓储（单元测试使用内存实现）
+func NewCommonServiceWithRepos(mainRepo dao.MainRepository, orderRepo dao.OrderRepository, indexRepo dao.IndexTableRepository) *CommonService {
+	return &CommonService{
+		mainDao:       mainRepo,
+		orderDao:      orderRepo,
+		indexTableDao: indexRepo,
+	}
+}
+
 func (s *CommonService) GetMerchantChannelInfo(mid uint64, channelCode string) (*dto.MerchantChannelDTO, error) {
 
 	var detail *dto.MerchantChannelDTO
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	mainmodel "wht-order-api/internal/model/main"
+	"wht-order-api/internal/settlement"
+	"wht-order-api/internal/utils"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/singleflight"
+)
+
+const feeScheduleCachePrefix = "fee_schedule_cache:" // fee_schedule_cache:{系统通道ID}:{币种}
+
+// FeeScheduleService 阶梯/交易量费率方案：为商户、代理、上游选出适用费率并计算结算
+type FeeScheduleService struct {
+	mainDao       dao.MainRepository
+	scheduleGroup singleflight.Group
+}
+
+func NewFeeScheduleService(mainDao dao.MainRepository) *FeeScheduleService {
+	return &FeeScheduleService{mainDao: mainDao}
+}
+
+// schedules 通道+币种下的费率方案（缓存 5 分钟），查询失败时返回空，回退平铺费率
+func (s *FeeScheduleService) schedules(sysChannelID int64, currency string) []dto.FeeSchedule {
+	cacheKey := fmt.Sprintf("%s%d:%s", feeScheduleCachePrefix, sysChannelID, currency)
+	result, err, _ := s.scheduleGroup.Do(cacheKey, func() (interface{}, error) {
+		cached, err := dal.RedisClient.Get(dal.RedisCtx, cacheKey).Result()
+		if err == nil && cached != "" {
+			var list []dto.FeeSchedule
+			if err := utils.JSONToMap(cached, &list); err == nil {
+				return list, nil
+			}
+		}
+
+		list, err := s.mainDao.ListFeeSchedules(sysChannelID, currency)
+		if err != nil {
+			return nil, err
+		}
+		if listJSON := utils.MapToJSON(list); listJSON != "" {
+			dal.RedisClient.Set(dal.RedisCtx, cacheKey, listJSON, 5*time.Minute)
+		}
+		return list, nil
+	})
+	if err != nil {
+		log.Printf("[FEE] 获取费率方案失败，使用平铺费率 sys_channel=%d currency=%s err=%v", sysChannelID, currency, err)
+		return nil
+	}
+	list, _ := result.([]dto.FeeSchedule)
+	return list
+}
+
+// agentQuotes 沿代理链取各级代理的平铺费率（商户直属代理为第 1 级）
+func (s *FeeScheduleService) agentQuotes(merchant *mainmodel.Merchant, sysChannelID int64) []dto.AgentQuote {
+	chain, err := s.mainDao.ListAgentChain(merchant.MerchantID, merchant.PId, sysChannelID)
+	if err != nil {
+		log.Printf("get agent chain failed[获取代理链失败]: merchant=%d err=%v", merchant.MerchantID, err)
+		// 不返回错误，已查到的层级照常计算
+	}
+	quotes := make([]dto.AgentQuote, 0, len(chain))
+	for i, a := range chain {
+		quotes = append(quotes, dto.AgentQuote{
+			AgentID: uint64(a.AID),
+			Level:   i + 1,
+			Quote:   utils.FlatFeeQuote(a.DefaultRate, a.SingleFee),
+		})
+	}
+	return quotes
+}
+
+// Settle 计算订单结算：商户/各级代理/上游各自命中费率方案则按阶梯计价，否则沿用平铺费率
+func (s *FeeScheduleService) Settle(
+	merchant *mainmodel.Merchant,
+	product dto.PayProductVo,
+	amount decimal.Decimal,
+	payMethod string,
+	mode string,
+) dto.SettlementResult {
+	merchantQuote := utils.FlatFeeQuote(product.MDefaultRate, product.MSingleFee)
+	if product.HasMinFee == 1 {
+		merchantQuote.MinFee = product.MinFee
+	}
+	agentQuotes := s.agentQuotes(merchant, product.SysChannelID)
+	upQuote := utils.FlatFeeQuote(product.CostRate, product.CostFee)
+
+	if list := s.schedules(product.SysChannelID, product.Currency); len(list) > 0 {
+		// 交易量仅在命中交易量档方案时读取
+		var volume *decimal.Decimal
+		monthlyVolume := func() decimal.Decimal {
+			if volume == nil {
+				v := settlement.MonthlyVolume(merchant.MerchantID, product.Currency)
+				volume = &v
+			}
+			return *volume
+		}
+		// 命中方案时以方案档位（含保底/封顶）为准，未命中沿用平铺费率
+		quote := func(party int8, ownerID uint64, flat dto.FeeQuote) dto.FeeQuote {
+			schedule, ok := utils.MatchFeeSchedule(list, party, ownerID, payMethod)
+			if !ok {
+				return flat
+			}
+			v := decimal.Zero
+			if schedule.TierMode == dto.FeeTierModeVolume {
+				v = monthlyVolume()
+			}
+			if q, hit := utils.EvaluateFeeSchedule(schedule, amount, v); hit {
+				return q
+			}
+			return flat
+		}
+		merchantQuote = quote(dto.FeePartyMerchant, merchant.MerchantID, merchantQuote)
+		for i := range agentQuotes {
+			agentQuotes[i].Quote = quote(dto.FeePartyAgent, agentQuotes[i].AgentID, agentQuotes[i].Quote)
+		}
+		upQuote = quote(dto.FeePartyUpstream, uint64(product.ID), upQuote)
+	}
+
+	return utils.CalculateByQuotes(amount, merchantQuote, agentQuotes, upQuote, mode, product.Currency)
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/i18n"
+	"wht-order-api/internal/lifecycle"
+	mainmodel "wht-order-api/internal/model/main"
+	"wht-order-api/internal/utils"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	limitCounterPrefix   = "txn_limit:"            // txn_limit:{维度}:...:{日期}，hash 字段 amt/cnt
+	limitRuleCachePrefix = "txn_limit_rule:"       // txn_limit_rule:{商户ID}:{订单类型}:{系统通道ID}:{币种}
+	limitHoldPrefix      = "txn_limit_hold:"       // txn_limit_hold:{订单ID}，已落单未终态的预占（计数器 + 金额）
+	limitExpireQueue     = "txn_limit_expire"      // 代收未支付超时回滚队列（ZSET，score 为超时时间戳）
+	limitExpireLock      = "txn_limit_expire_scan" // 超时扫描多实例互斥锁
+
+	limitDailyTTL   = 26 * time.Hour
+	limitMonthlyTTL = 32 * 24 * time.Hour
+)
+
+// limitReserveScript 原子校验并累加全部计数器：任一维度超限则不累加，返回超限维度序号（从 1 开始，奇数为金额、偶数为笔数）
+// KEYS: 计数器；ARGV[1]: 本单金额；其后每个计数器依次为 金额上限、笔数上限、过期秒数（上限为 0 表示不限）
+var limitReserveScript = redis.NewScript(`
+local amount = tonumber(ARGV[1])
+for i = 1, #KEYS do
+	local base = 1 + (i - 1) * 3
+	local maxAmt = tonumber(ARGV[base + 1])
+	local maxCnt = tonumber(ARGV[base + 2])
+	if maxAmt > 0 then
+		local cur = tonumber(redis.call('HGET', KEYS[i], 'amt') or '0')
+		if cur + amount > maxAmt then
+			return i * 2 - 1
+		end
+	end
+	if maxCnt > 0 then
+		local cnt = tonumber(redis.call('HGET', KEYS[i], 'cnt') or '0')
+		if cnt + 1 > maxCnt then
+			return i * 2
+		end
+	end
+end
+for i = 1, #KEYS do
+	local base = 1 + (i - 1) * 3
+	redis.call('HINCRBYFLOAT', KEYS[i], 'amt', ARGV[1])
+	redis.call('HINCRBY', KEYS[i], 'cnt', 1)
+	redis.call('EXPIRE', KEYS[i], tonumber(ARGV[base + 3]))
+end
+return 0
+`)
+
+// limitReleaseScript 回滚预占额度（计数器已过期则忽略）
+var limitReleaseScript = redis.NewScript(`
+for i = 1, #KEYS do
+	if redis.call('EXISTS', KEYS[i]) == 1 then
+		redis.call('HINCRBYFLOAT', KEYS[i], 'amt', '-' .. ARGV[1])
+		redis.call('HINCRBY', KEYS[i], 'cnt', -1)
+	end
+end
+return 0
+`)
+
+// LimitError 累计限额超限错误（Code 为对外错误码，文案由 handler 按请求语言渲染）
+type LimitError struct {
+	Code  int
+	Limit string
+}
+
+func (e *LimitError) Error() string {
+	return e.Message(i18n.LangEN)
+}
+
+// Message 按语言渲染超限文案
+func (e *LimitError) Message(lang string) string {
+	msg, _ := i18n.Message(lang, e.Code)
+	return fmt.Sprintf("%s, %s", msg, i18n.Text(lang, i18n.TextLimitValue, e.Limit))
+}
+
+// LimitReservation 已预占的限额，下单失败时通过 Release 回滚
+type LimitReservation struct {
+	keys    []string
+	amount  string
+	orderID uint64 // Bind 后非 0，回滚走订单预占记录
+	release sync.Once
+}
+
+// limitHold 订单预占记录：成功时删除（保留计数），失败/超时时按记录回滚
+type limitHold struct {
+	Keys   []string `json:"keys"`
+	Amount string   `json:"amount"`
+}
+
+// limitRules 商户 + 通道限额规则（缓存用）
+type limitRules struct {
+	Merchant *mainmodel.MerchantLimit `json:"merchant"`
+	Channel  *mainmodel.ChannelLimit  `json:"channel"`
+}
+
+// limitCheck 单个计数器的校验项
+type limitCheck struct {
+	key       string
+	maxAmount decimal.Decimal
+	maxCount  int64
+	ttl       time.Duration
+	amountErr int
+	countErr  int
+}
+
+// LimitService 商户日/月、通道日、收款账户日累计限额（Redis 计数器原子预占）
+type LimitService struct {
+	mainDao   dao.MainRepository
+	ruleGroup singleflight.Group
+}
+
+func NewLimitService(mainDao dao.MainRepository) *LimitService {
+	return &LimitService{mainDao: mainDao}
+}
+
+// rules 商户/通道限额规则（缓存 5 分钟）
+func (s *LimitService) rules(mId uint64, orderType int8, sysChannelID uint64, currency string) (*limitRules, error) {
+	cacheKey := fmt.Sprintf("%s%d:%d:%d:%s", limitRuleCachePrefix, mId, orderType, sysChannelID, currency)
+	result, err, _ := s.ruleGroup.Do(cacheKey, func() (interface{}, error) {
+		cached, err := dal.RedisClient.Get(dal.RedisCtx, cacheKey).Result()
+		if err == nil && cached != "" {
+			var rules limitRules
+			if err := utils.JSONToMap(cached, &rules); err == nil {
+				return &rules, nil
+			}
+		}
+
+		merchantLimit, err := s.mainDao.GetMerchantLimit(mId, currency, orderType)
+		if err != nil {
+			return nil, err
+		}
+		channelLimit, err := s.mainDao.GetChannelLimit(sysChannelID, currency)
+		if err != nil {
+			return nil, err
+		}
+		rules := &limitRules{Merchant: merchantLimit, Channel: channelLimit}
+		if rulesJSON := utils.MapToJSON(rules); rulesJSON != "" {
+			dal.RedisClient.Set(dal.RedisCtx, cacheKey, rulesJSON, 5*time.Minute)
+		}
+		return rules, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*limitRules), nil
+}
+
+// checks 按规则生成本单需要校验的计数器
+func (s *LimitService) checks(rules *limitRules, orderType int8, mId, sysChannelID uint64, currency, accNo string, now time.Time) []limitCheck {
+	day := now.Format("20060102")
+	month := now.Format("200601")
+	var list []limitCheck
+
+	if m := rules.Merchant; m != nil {
+		list = append(list,
+			limitCheck{
+				key:       fmt.Sprintf("%sm:%d:%d:%s:d:%s", limitCounterPrefix, mId, orderType, currency, day),
+				maxAmount: m.DailyAmount, maxCount: m.DailyCount, ttl: limitDailyTTL,
+				amountErr: constant.CodeLimitMerchantDailyAmount, countErr: constant.CodeLimitMerchantDailyCount,
+			},
+			limitCheck{
+				key:       fmt.Sprintf("%sm:%d:%d:%s:m:%s", limitCounterPrefix, mId, orderType, currency, month),
+				maxAmount: m.MonthlyAmount, maxCount: m.MonthlyCount, ttl: limitMonthlyTTL,
+				amountErr: constant.CodeLimitMerchantMonthlyAmount, countErr: constant.CodeLimitMerchantMonthlyCount,
+			},
+		)
+		// 收款账户限额仅针对代付
+		if accNo = strings.TrimSpace(accNo); orderType == 2 && accNo != "" {
+			list = append(list, limitCheck{
+				key:       fmt.Sprintf("%sp:%d:%s:%s:d:%s", limitCounterPrefix, mId, currency, accNo, day),
+				maxAmount: m.PayerDailyAmount, maxCount: m.PayerDailyCount, ttl: limitDailyTTL,
+				amountErr: constant.CodeLimitPayerDailyAmount, countErr: constant.CodeLimitPayerDailyCount,
+			})
+		}
+	}
+	if c := rules.Channel; c != nil {
+		list = append(list, limitCheck{
+			key:       fmt.Sprintf("%sc:%d:%s:d:%s", limitCounterPrefix, sysChannelID, currency, day),
+			maxAmount: c.DailyAmount, maxCount: c.DailyCount, ttl: limitDailyTTL,
+			amountErr: constant.CodeLimitChannelDailyAmount, countErr: constant.CodeLimitChannelDailyCount,
+		})
+	}
+
+	// 未设置上限的维度不计数
+	filtered := list[:0]
+	for _, c := range list {
+		if c.maxAmount.GreaterThan(decimal.Zero) || c.maxCount > 0 {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// Reserve 校验并预占累计限额：超限返回 *LimitError；未开启或无规则时返回 nil 预占
+// orderType: 1代收 2代付；accNo 为代付收款账户
+func (s *LimitService) Reserve(orderType int8, mId, sysChannelID uint64, currency, accNo string, amount decimal.Decimal) (*LimitReservation, error) {
+	if !config.C.Limit.Enabled {
+		return nil, nil
+	}
+
+	rules, err := s.rules(mId, orderType, sysChannelID, currency)
+	if err != nil {
+		return s.failOpen(mId, currency, fmt.Errorf("get limit rules failed: %w", err))
+	}
+	checks := s.checks(rules, orderType, mId, sysChannelID, currency, accNo, time.Now())
+	if len(checks) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(checks))
+	args := make([]interface{}, 0, 1+len(checks)*3)
+	args = append(args, amount.String())
+	for _, c := range checks {
+		keys = append(keys, c.key)
+		args = append(args, c.maxAmount.String(), c.maxCount, int64(c.ttl.Seconds()))
+	}
+
+	hit, err := limitReserveScript.Run(dal.RedisCtx, dal.RedisClient, keys, args...).Int()
+	if err != nil {
+		return s.failOpen(mId, currency, fmt.Errorf("reserve limit failed: %w", err))
+	}
+	if hit > 0 {
+		c := checks[(hit-1)/2]
+		code, limit := c.amountErr, c.maxAmount.String()
+		if hit%2 == 0 {
+			code, limit = c.countErr, fmt.Sprintf("%d", c.maxCount)
+		}
+		log.Printf("[LIMIT] ⛔ 累计限额超限 merchant=%d type=%d currency=%s key=%s code=%d limit=%s amount=%s",
+			mId, orderType, currency, c.key, code, limit, amount.String())
+		return nil, &LimitError{Code: code, Limit: limit}
+	}
+	return &LimitReservation{keys: keys, amount: amount.String()}, nil
+}
+
+// Release 回滚预占的限额（可重复调用，仅回滚一次）
+func (s *LimitService) Release(r *LimitReservation) {
+	if r == nil {
+		return
+	}
+	r.release.Do(func() {
+		if r.orderID != 0 {
+			s.ReleaseOrder(r.orderID)
+			return
+		}
+		s.release(r.keys, r.amount)
+	})
+}
+
+func (s *LimitService) release(keys []string, amount string) {
+	if err := limitReleaseScript.Run(dal.RedisCtx, dal.RedisClient, keys, amount).Err(); err != nil {
+		log.Printf("[LIMIT] ❌ 回滚累计限额失败 keys=%v amount=%s err=%v", keys, amount, err)
+	}
+}
+
+// Bind 订单落库后记录预占，供订单终态时确认或回滚
+// orderType: 1代收（未支付超时自动回滚） 2代付（驳回/失败退款时回滚）
+func (s *LimitService) Bind(r *LimitReservation, orderType int8, orderID uint64) {
+	if r == nil {
+		return
+	}
+	holdJSON := utils.MapToJSON(limitHold{Keys: r.keys, Amount: r.amount})
+	if holdJSON == "" {
+		return
+	}
+	holdKey := fmt.Sprintf("%s%d", limitHoldPrefix, orderID)
+	if err := dal.RedisClient.Set(dal.RedisCtx, holdKey, holdJSON, limitMonthlyTTL).Err(); err != nil {
+		log.Printf("[LIMIT] ⚠️ 记录订单预占失败，订单失败时无法回滚 order=%d err=%v", orderID, err)
+		return
+	}
+	r.orderID = orderID
+	if orderType == 1 {
+		expireAt := time.Now().Add(config.C.Limit.ReceiveExpireAfter)
+		if err := dal.RedisClient.ZAdd(dal.RedisCtx, limitExpireQueue, &redis.Z{
+			Score:  float64(expireAt.Unix()),
+			Member: strconv.FormatUint(orderID, 10),
+		}).Err(); err != nil {
+			log.Printf("[LIMIT] ⚠️ 加入超时回滚队列失败 order=%d err=%v", orderID, err)
+		}
+	}
+}
+
+// Settle 订单成功：删除预占记录，额度保留计数
+func (s *LimitService) Settle(orderID uint64) {
+	member := strconv.FormatUint(orderID, 10)
+	if err := dal.RedisClient.Del(dal.RedisCtx, limitHoldPrefix+member).Err(); err != nil {
+		log.Printf("[LIMIT] ⚠️ 删除订单预占失败 order=%d err=%v", orderID, err)
+	}
+	dal.RedisClient.ZRem(dal.RedisCtx, limitExpireQueue, member)
+}
+
+// ReleaseOrder 订单失败/驳回/超时：按预占记录回滚额度（以删除记录成功为准，仅回滚一次）
+func (s *LimitService) ReleaseOrder(orderID uint64) {
+	member := strconv.FormatUint(orderID, 10)
+	holdKey := limitHoldPrefix + member
+	defer dal.RedisClient.ZRem(dal.RedisCtx, limitExpireQueue, member)
+
+	holdJSON, err := dal.RedisClient.Get(dal.RedisCtx, holdKey).Result()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("[LIMIT] ❌ 读取订单预占失败 order=%d err=%v", orderID, err)
+		}
+		return
+	}
+	var hold limitHold
+	if err := utils.JSONToMap(holdJSON, &hold); err != nil || len(hold.Keys) == 0 {
+		log.Printf("[LIMIT] ❌ 订单预占记录异常 order=%d data=%s err=%v", orderID, holdJSON, err)
+		dal.RedisClient.Del(dal.RedisCtx, holdKey)
+		return
+	}
+	// 与 Settle/并发回滚竞争：删除成功者负责回滚
+	if n, err := dal.RedisClient.Del(dal.RedisCtx, holdKey).Result(); err != nil || n == 0 {
+		return
+	}
+	s.release(hold.Keys, hold.Amount)
+	log.Printf("[LIMIT] ↩️ 订单未成功，已回滚累计限额 order=%d amount=%s", orderID, hold.Amount)
+}
+
+// RunExpiry 定时回滚超时未支付代收订单的预占额度（多实例通过 Redis 锁互斥）
+// 超时后才到达的成功回调不再计入限额
+func (s *LimitService) RunExpiry() {
+	if !config.C.Limit.Enabled {
+		return
+	}
+	ticker := time.NewTicker(config.C.Limit.ReleaseScanInterval)
+	defer ticker.Stop()
+	log.Printf("[LIMIT] 超时预占扫描已启动 间隔=%v 代收超时=%v", config.C.Limit.ReleaseScanInterval, config.C.Limit.ReceiveExpireAfter)
+	for {
+		select {
+		case <-lifecycle.Stopping():
+			log.Printf("[LIMIT] 超时预占扫描已停止")
+			return
+		case <-ticker.C:
+			s.expireOnce()
+		}
+	}
+}
+
+func (s *LimitService) expireOnce() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] limit expiry panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	ok, err := dal.RedisClient.SetNX(dal.RedisCtx, limitExpireLock, 1, config.C.Limit.ReleaseScanInterval).Result()
+	if err != nil || !ok {
+		return
+	}
+	members, err := dal.RedisClient.ZRangeByScore(dal.RedisCtx, limitExpireQueue, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: 200,
+	}).Result()
+	if err != nil {
+		log.Printf("[LIMIT] 查询超时预占失败: %v", err)
+		return
+	}
+	for _, m := range members {
+		orderID, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			dal.RedisClient.ZRem(dal.RedisCtx, limitExpireQueue, m)
+			continue
+		}
+		s.ReleaseOrder(orderID)
+	}
+}
+
+// failOpen Redis/数据库异常时按配置放行或拒单
+func (s *LimitService) failOpen(mId uint64, currency string, err error) (*LimitReservation, error) {
+	log.Printf("[LIMIT] ⚠️ 累计限额校验异常 merchant=%d currency=%s failOpen=%v err=%v", mId, currency, config.C.Limit.FailOpen, err)
+	if config.C.Limit.FailOpen {
+		return nil, nil
+	}
+	return nil, err
+}
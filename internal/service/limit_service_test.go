@@ -0,0 +1,62 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/i18n"
+)
+
+func TestLimitHoldSettleAndRelease(t *testing.T) {
+	fakeredis.Use(t)
+	prev := config.C.Limit.ReceiveExpireAfter
+	config.C.Limit.ReceiveExpireAfter = 30 * time.Minute
+	t.Cleanup(func() { config.C.Limit.ReceiveExpireAfter = prev })
+	svc := NewLimitService(memdao.NewMainStore())
+
+	// 成功：删除预占记录与超时队列，额度保留
+	paid := &LimitReservation{keys: []string{"txn_limit:m:1:1:INR:d:20261019"}, amount: "100"}
+	svc.Bind(paid, 1, 1001)
+	if paid.orderID != 1001 {
+		t.Fatalf("reservation not bound")
+	}
+	if n, _ := dal.RedisClient.ZCard(dal.RedisCtx, limitExpireQueue).Result(); n != 1 {
+		t.Fatalf("expire queue size = %d, want 1", n)
+	}
+	svc.Settle(1001)
+	if n, _ := dal.RedisClient.Exists(dal.RedisCtx, limitHoldPrefix+"1001").Result(); n != 0 {
+		t.Fatalf("hold not removed after settle")
+	}
+	if n, _ := dal.RedisClient.ZCard(dal.RedisCtx, limitExpireQueue).Result(); n != 0 {
+		t.Fatalf("expire queue size = %d after settle, want 0", n)
+	}
+
+	// 失败：预占记录仅被回滚一次，代付不进超时队列
+	failed := &LimitReservation{keys: []string{"txn_limit:m:1:2:INR:d:20261019"}, amount: "50"}
+	svc.Bind(failed, 2, 1002)
+	if n, _ := dal.RedisClient.ZCard(dal.RedisCtx, limitExpireQueue).Result(); n != 0 {
+		t.Fatalf("payout hold should not be queued for expiry")
+	}
+	svc.Release(failed)
+	if n, _ := dal.RedisClient.Exists(dal.RedisCtx, limitHoldPrefix+"1002").Result(); n != 0 {
+		t.Fatalf("hold not removed after release")
+	}
+	// 成功回调晚于回滚：无记录，不影响计数
+	svc.Settle(1002)
+	svc.ReleaseOrder(1002)
+}
+
+func TestLimitErrorMessageFollowsLanguage(t *testing.T) {
+	le := &LimitError{Code: constant.CodeLimitMerchantDailyAmount, Limit: "1000"}
+	if got := le.Message(i18n.LangPT); got != "Limite diário de valor do lojista excedido, limite: 1000" {
+		t.Errorf("pt message = %q", got)
+	}
+	if got := le.Error(); !strings.HasPrefix(got, "Merchant daily amount limit exceeded") {
+		t.Errorf("error = %q, want English for logs", got)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/notify"
+)
+
+const nativeQueryTimeout = 10 * time.Second
+
+// nativeUpstreamError 原生连接器错误转换：业务失败按接口映射表映射，其余按 classify 分类网络异常
+func nativeUpstreamError(interfaceCode string, err error, classify func(error) *UpstreamError) *UpstreamError {
+	var be *connector.BusinessError
+	if errors.As(err, &be) {
+		return mapUpstreamError(interfaceCode, be.Code, be.Msg, errors.New("交易失败"))
+	}
+	var ue *UpstreamError
+	if errors.As(err, &ue) {
+		return ue
+	}
+	return classify(err)
+}
+
+// nativeResultError 上游同步返回失败状态（未受理）：按接口映射表映射，允许切换通道并走失败退款
+func nativeResultError(interfaceCode string, result *connector.OrderResult) *UpstreamError {
+	if result.Status != connector.StatusFail {
+		return nil
+	}
+	return mapUpstreamError(interfaceCode, result.Code, result.Msg,
+		fmt.Errorf("上游同步返回失败: code=%s, msg=%s", result.Code, result.Msg))
+}
+
+// callNativeReceive 原生连接器代收下单（返回值与 CallUpstreamReceiveService 一致）
+func callNativeReceive(ctx context.Context, conn connector.Connector, req dto.UpstreamRequest, mchReq *dto.CreateOrderReq) (string, string, string, error) {
+	log.Printf("[Upstream-Receive-Native] 接口: %s, 平台交易号: %s", req.ProviderKey, req.MchOrderId)
+
+	result, err := conn.CreateReceive(ctx, req)
+	if err != nil {
+		ue := nativeUpstreamError(req.ProviderKey, err, classifyTransportError)
+		log.Printf("[Upstream-Receive-Native] 下单失败: %v", ue)
+		notify.NotifyUpstreamAlert("warn", "代收原生连接器下单失败", req.ProviderKey, mchReq, req, nil, map[string]string{
+			"错误": err.Error(),
+		})
+		return "", "", "", ue
+	}
+	if ue := nativeResultError(req.ProviderKey, result); ue != nil {
+		log.Printf("[Upstream-Receive-Native] 上游同步返回失败: %v", ue)
+		notify.NotifyUpstreamAlert("warn", "代收原生连接器下单失败", req.ProviderKey, mchReq, req, result, nil)
+		return "", "", "", ue
+	}
+	if result.PayUrl == "" {
+		notify.NotifyUpstreamAlert("warn", "代收原生连接器返回无效支付链接", req.ProviderKey, mchReq, req, result, nil)
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamError, Retryable: true, Err: errors.New("上游返回支付链接为空")}
+	}
+
+	log.Printf("[Upstream-Receive-Native] 收单下单成功, upOrderNo=%s, payUrl=%s", result.UpOrderNo, result.PayUrl)
+	return req.MchOrderId, result.UpOrderNo, result.PayUrl, nil
+}
+
+// callNativePayout 原生连接器代付下单（先查上游余额，返回值与 CallUpstreamPayoutService 一致）
+func callNativePayout(ctx context.Context, conn connector.Connector, req dto.UpstreamRequest, mchReq *dto.CreatePayoutOrderReq) (string, string, string, error) {
+	log.Printf("[Upstream-Payout-Native] 接口: %s, 平台交易号: %s", req.ProviderKey, req.MchOrderId)
+
+	balance, err := conn.Balance(ctx, req)
+	if err != nil {
+		notify.NotifyUpstreamAlert("error", "代付原生连接器余额查询失败", req.ProviderKey, mchReq, req, nil, map[string]string{
+			"错误": err.Error(),
+		})
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamError, Retryable: true, Err: fmt.Errorf("查询上游余额失败: %w", err)}
+	}
+	if !balanceGreaterThanOrder(req.Amount, balance) {
+		log.Printf("[Upstream-Payout-Native] ⚠️ 上游余额不足，跳过下单: 余额=%s, 代付金额=%s", balance, req.Amount)
+		notify.NotifyUpstreamAlert("warn", "代付上游余额不足", req.ProviderKey, mchReq, req, nil, map[string]string{
+			"上游余额": balance.String(),
+			"代付金额": req.Amount,
+		})
+		return "", "", "", &UpstreamError{Code: constant.CodeUpstreamBalanceInsufficient, Retryable: true, Err: ErrUpstreamBalanceInsufficient}
+	}
+
+	result, err := conn.CreatePayout(ctx, req)
+	if err != nil {
+		ue := nativeUpstreamError(req.ProviderKey, err, classifyPayoutTransportError)
+		if ue.Unknown {
+			// 结果未知：先向上游查单，确认受理则按成功处理，确认失败才允许切换通道
+			if r, ok := resolveNativePayout(conn, req, ue); ok {
+				return req.MchOrderId, r.UpOrderNo, "", nil
+			}
+		}
+		log.Printf("[Upstream-Payout-Native] 下单失败: %v", ue)
+		notify.NotifyUpstreamAlert("warn", "代付原生连接器下单失败", req.ProviderKey, mchReq, req, nil, map[string]string{
+			"错误": err.Error(),
+		})
+		return "", "", "", ue
+	}
+	if ue := nativeResultError(req.ProviderKey, result); ue != nil {
+		log.Printf("[Upstream-Payout-Native] 上游同步返回失败: %v", ue)
+		notify.NotifyUpstreamAlert("warn", "代付原生连接器下单失败", req.ProviderKey, mchReq, req, result, nil)
+		return "", "", "", ue
+	}
+
+	log.Printf("[Upstream-Payout-Native] 代付下单成功, upOrderNo=%s, status=%s", result.UpOrderNo, result.Status)
+	return req.MchOrderId, result.UpOrderNo, result.PayUrl, nil
+}
+
+// resolveNativePayout 代付下单结果未知时查单：上游已受理返回 true；
+// 上游确认失败时将 ue 改为可切换通道；查单失败保持结果未知（转人工）
+func resolveNativePayout(conn connector.Connector, req dto.UpstreamRequest, ue *UpstreamError) (*connector.QueryResult, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), nativeQueryTimeout)
+	defer cancel()
+
+	r, err := conn.Query(ctx, req)
+	if err != nil {
+		log.Printf("[Upstream-Payout-Native] ⚠️ 结果未知且查单失败: 平台交易号=%s, err=%v", req.MchOrderId, err)
+		return nil, false
+	}
+	switch r.Status {
+	case connector.StatusSuccess, connector.StatusPending:
+		log.Printf("[Upstream-Payout-Native] 查单确认上游已受理: 平台交易号=%s, upOrderNo=%s, status=%s", req.MchOrderId, r.UpOrderNo, r.Status)
+		return r, true
+	case connector.StatusFail:
+		log.Printf("[Upstream-Payout-Native] 查单确认上游未出款: 平台交易号=%s, msg=%s", req.MchOrderId, r.Msg)
+		ue.Retryable, ue.Unknown = true, false
+	}
+	return nil, false
+}
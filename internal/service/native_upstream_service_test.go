@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wht-order-api/internal/connector/reference"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/dto"
+)
+
+// syncFailUpstream 参考协议上游：余额充足，下单受理但同步返回 FAIL
+func syncFailUpstream(t *testing.T) dto.UpstreamRequest {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		data := map[string]string{"upOrderNo": "UP-1", "status": "FAIL", "errCode": "R01", "errMsg": "risk rejected"}
+		if params["method"] == "balance" {
+			data = map[string]string{"balance": "100000"}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": "0", "data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return dto.UpstreamRequest{
+		MchNo: "UPM1", ApiKey: "secret", MchOrderId: "9001", Amount: "100", Currency: "BRL",
+		ProviderKey: "test_reference", SubmitUrl: srv.URL, QueryUrl: srv.URL,
+	}
+}
+
+func TestNativeSyncFailIsUpstreamError(t *testing.T) {
+	fakeredis.Use(t)
+	upstreamErrMapper.once.Do(func() { upstreamErrMapper.mainDao = memdao.NewMainStore() })
+	req := syncFailUpstream(t)
+	conn := reference.New(nil)
+
+	_, _, _, rErr := callNativeReceive(context.Background(), conn, req, &dto.CreateOrderReq{})
+	_, _, _, pErr := callNativePayout(context.Background(), conn, req, &dto.CreatePayoutOrderReq{})
+	for name, err := range map[string]error{"receive": rErr, "payout": pErr} {
+		var ue *UpstreamError
+		if !errors.As(err, &ue) {
+			t.Errorf("%s: err = %v, want *UpstreamError", name, err)
+			continue
+		}
+		// 上游已明确失败：允许切换通道，结果不是未知
+		if !ue.Retryable || ue.Unknown || ue.UpstreamCode != "R01" || ue.UpstreamMsg != "risk rejected" {
+			t.Errorf("%s: ue = %+v", name, ue)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
+	"wht-order-api/internal/lifecycle"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/system"
+)
+
+const (
+	payoutApprovalLockKey   = "payout_approval_lock:"
+	payoutApprovalExpireKey = "payout_approval_expire_scan"
+	// 订单状态：已驳回
+	payoutStatusRejected int8 = 4
+)
+
+var (
+	ErrApprovalNotFound       = errors.New("approval not found")
+	ErrApprovalAlreadyDecided = errors.New("approval already decided")
+)
+
+// PayoutApprovalService 大额代付复核（maker-checker）
+// 超过阈值或首次出款的代付在冻结资金后进入待复核状态，
+// 由内部接口通过（提交上游）或驳回（解冻退回余额），超时自动驳回。
+type PayoutApprovalService struct {
+	payoutSvc   *PayoutOrderService
+	mainDao     dao.MainRepository
+	orderDao    dao.PayoutOrderRepository
+	approvalDao dao.PayoutApprovalRepository
+}
+
+func NewPayoutApprovalService(pub event.Publisher) *PayoutApprovalService {
+	payoutSvc := NewPayoutOrderService(pub)
+	return newPayoutApprovalService(payoutSvc)
+}
+
+// NewPayoutApprovalServiceWithRepos 注入仓储（单元测试使用内存实现），代付下单同样使用注入的复核仓储
+func NewPayoutApprovalServiceWithRepos(pub event.Publisher, mainRepo dao.MainRepository, orderRepo dao.PayoutOrderRepository, indexRepo dao.IndexTableRepository, approvalRepo dao.PayoutApprovalRepository) *PayoutApprovalService {
+	payoutSvc := NewPayoutOrderServiceWithRepos(pub, mainRepo, orderRepo, indexRepo)
+	payoutSvc.approvalDao = approvalRepo
+	return newPayoutApprovalService(payoutSvc)
+}
+
+func newPayoutApprovalService(payoutSvc *PayoutOrderService) *PayoutApprovalService {
+	return &PayoutApprovalService{
+		payoutSvc:   payoutSvc,
+		mainDao:     payoutSvc.mainDao,
+		orderDao:    payoutSvc.orderDao,
+		approvalDao: payoutSvc.approvalDao,
+	}
+}
+
+// Enabled 是否开启代付复核
+func (s *PayoutApprovalService) Enabled() bool {
+	return config.C.Approval.Enabled
+}
+
+// Check 判断代付是否需要复核，返回是否需要及原因
+func (s *PayoutApprovalService) Check(merchant *mainmodel.Merchant, currency string, amount decimal.Decimal, accNo string) (bool, string) {
+	if !s.Enabled() {
+		return false, ""
+	}
+
+	// 商户级规则优先，其次币种默认阈值
+	threshold := decimal.Zero
+	firstTime := config.C.Approval.FirstTimeBeneficiary
+	rule, err := s.mainDao.GetPayoutApprovalRule(merchant.MerchantID, currency)
+	if err != nil {
+		log.Printf("[PAYOUT-APPROVAL] 查询商户复核规则失败 merchant=%d currency=%s err=%v", merchant.MerchantID, currency, err)
+	}
+	if rule != nil {
+		threshold = rule.Threshold
+		firstTime = rule.FirstTimeBeneficiary == 1
+	} else {
+		// viper 会将 map 键转为小写
+		for cur, v := range config.C.Approval.Thresholds {
+			if strings.EqualFold(cur, currency) {
+				threshold, _ = decimal.NewFromString(v)
+				break
+			}
+		}
+	}
+
+	if threshold.GreaterThan(decimal.Zero) && amount.GreaterThanOrEqual(threshold) {
+		return true, fmt.Sprintf("金额 %s 达到复核阈值 %s", amount.String(), threshold.String())
+	}
+	if firstTime && accNo != "" {
+		exists, err := s.approvalDao.BeneficiaryExists(merchant.MerchantID, currency, accNo)
+		if err != nil {
+			// 查询失败按需复核处理，避免漏审
+			return true, fmt.Sprintf("收款账户校验失败: %v", err)
+		}
+		if !exists {
+			return true, "首次出款收款账户"
+		}
+	}
+	return false, ""
+}
+
+// Hold 订单进入待复核（资金已冻结，不提交上游）
+func (s *PayoutApprovalService) Hold(order *ordermodel.MerchantPayOutOrderM, req dto.CreatePayoutOrderReq, reason string, now time.Time) error {
+	payload, _ := json.Marshal(req)
+	rec := &ordermodel.PayoutApprovalM{
+		OrderID:         order.OrderID,
+		MID:             order.MID,
+		MOrderID:        order.MOrderID,
+		Currency:        order.Currency,
+		Amount:          order.Amount,
+		FreezeAmount:    order.FreezeAmount,
+		Reason:          truncateReason(reason),
+		Payload:         string(payload),
+		Status:          ordermodel.PayoutApprovalPending,
+		OrderCreateTime: now,
+		ExpireAt:        now.Add(config.C.Approval.ExpireAfter),
+		CreateTime:      time.Now(),
+	}
+
+	orderTable := shard.OutOrderShard.GetTable(order.OrderID, now)
+	err := s.approvalDao.Transaction(func(repo dao.PayoutApprovalRepository, orderRepo dao.PayoutOrderRepository) error {
+		if err := repo.Insert(rec); err != nil {
+			return fmt.Errorf("insert approval failed: %w", err)
+		}
+		return orderRepo.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+			"status":      ordermodel.PayoutStatusAwaitingApproval,
+			"remark":      truncateReason("待复核: " + reason),
+			"update_time": time.Now(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[PAYOUT-APPROVAL] ⏸️ 代付进入待复核 order=%d 商户订单号=%s 金额=%s %s 原因=%s",
+		order.OrderID, order.MOrderID, order.Amount.String(), order.Currency, reason)
+	notify.Notify(system.BotChatID, "warn", "代付待复核",
+		fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n商户ID: `%d`\n金额: `%s %s`\n原因: `%s`\n超时时间: `%s`\n\n资金已冻结，请及时复核。",
+			order.OrderID, order.MOrderID, order.MID, order.Amount.String(), order.Currency, reason,
+			rec.ExpireAt.Format(time.DateTime)), true)
+	return nil
+}
+
+// Decide 复核操作：approve 通过提交上游；reject 驳回解冻
+func (s *PayoutApprovalService) Decide(req dto.PayoutApprovalDecideReq, operatorIP string) error {
+	orderId, err := strconv.ParseUint(req.OrderId, 10, 64)
+	if err != nil {
+		return ErrApprovalNotFound
+	}
+	switch req.Action {
+	case "approve":
+		return s.approve(orderId, req.Operator, operatorIP, req.Remark)
+	case "reject":
+		return s.reject(orderId, ordermodel.PayoutApprovalRejected, req.Operator, operatorIP, req.Remark)
+	default:
+		return fmt.Errorf("invalid action: %s", req.Action)
+	}
+}
+
+// List 待复核列表
+func (s *PayoutApprovalService) List(req dto.PayoutApprovalListReq) (dto.PayoutApprovalListResp, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	list, total, err := s.approvalDao.ListPending(req.MerchantId, req.PageSize, (req.Page-1)*req.PageSize)
+	if err != nil {
+		return dto.PayoutApprovalListResp{}, err
+	}
+	resp := dto.PayoutApprovalListResp{Total: total, List: make([]dto.PayoutApprovalVo, 0, len(list))}
+	for _, r := range list {
+		resp.List = append(resp.List, dto.PayoutApprovalVo{
+			OrderId:   strconv.FormatUint(r.OrderID, 10),
+			MId:       r.MID,
+			TranFlow:  r.MOrderID,
+			Currency:  r.Currency,
+			Amount:    r.Amount.String(),
+			Reason:    r.Reason,
+			ExpireAt:  r.ExpireAt.Format(time.DateTime),
+			CreatedAt: r.CreateTime.Format(time.DateTime),
+		})
+	}
+	return resp, nil
+}
+
+// lock 订单级复核锁，防止复核与超时扫描并发处理
+func (s *PayoutApprovalService) lock(orderId uint64) (func(), error) {
+	key := payoutApprovalLockKey + strconv.FormatUint(orderId, 10)
+	ok, err := dal.RedisClient.SetNX(dal.RedisCtx, key, 1, time.Minute).Result()
+	if err != nil {
+		return nil, fmt.Errorf("acquire approval lock failed: %w", err)
+	}
+	if !ok {
+		return nil, ErrApprovalAlreadyDecided
+	}
+	return func() { dal.RedisClient.Del(dal.RedisCtx, key) }, nil
+}
+
+// loadPending 读取待复核记录及订单
+func (s *PayoutApprovalService) loadPending(orderId uint64) (*ordermodel.PayoutApprovalM, *ordermodel.MerchantPayOutOrderM, error) {
+	rec, err := s.approvalDao.GetByOrderId(orderId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rec == nil {
+		return nil, nil, ErrApprovalNotFound
+	}
+	if rec.Status != ordermodel.PayoutApprovalPending {
+		return nil, nil, ErrApprovalAlreadyDecided
+	}
+	orderTable := shard.OutOrderShard.GetTable(orderId, rec.OrderCreateTime)
+	order, err := s.orderDao.GetByOrderId(orderTable, orderId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order == nil {
+		return nil, nil, ErrApprovalNotFound
+	}
+	if order.Status != ordermodel.PayoutStatusAwaitingApproval {
+		return nil, nil, ErrApprovalAlreadyDecided
+	}
+	return rec, order, nil
+}
+
+// approve 复核通过：恢复处理中并提交上游
+func (s *PayoutApprovalService) approve(orderId uint64, operator, operatorIP, remark string) error {
+	unlock, err := s.lock(orderId)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	rec, order, err := s.loadPending(orderId)
+	if err != nil {
+		return err
+	}
+	merchant, err := s.mainDao.GetMerchantId(strconv.FormatUint(order.MID, 10))
+	if err != nil || merchant == nil {
+		return fmt.Errorf("merchant invalid: %w", err)
+	}
+	var req dto.CreatePayoutOrderReq
+	if err := json.Unmarshal([]byte(rec.Payload), &req); err != nil {
+		return fmt.Errorf("decode approval payload failed: %w", err)
+	}
+	if order.UpOrderID == nil {
+		return fmt.Errorf("order has no upstream transaction: %d", orderId)
+	}
+	createTime := rec.OrderCreateTime
+	txTable := shard.UpOutOrderShard.GetTable(*order.UpOrderID, createTime)
+	tx, err := s.orderDao.GetTxByUpOrderId(txTable, *order.UpOrderID)
+	if err != nil || tx == nil {
+		return fmt.Errorf("upstream transaction not found: %d", *order.UpOrderID)
+	}
+
+	decided, err := s.approvalDao.Decide(orderId, ordermodel.PayoutApprovalApproved, operator, operatorIP, remark)
+	if err != nil {
+		return err
+	}
+	if !decided {
+		return ErrApprovalAlreadyDecided
+	}
+
+	orderTable := shard.OutOrderShard.GetTable(orderId, createTime)
+	if err := s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": orderId}, map[string]interface{}{
+		"status":      1,
+		"remark":      truncateReason(fmt.Sprintf("复核通过: %s %s", operator, remark)),
+		"update_time": time.Now(),
+	}); err != nil {
+		return fmt.Errorf("update order status failed: %w", err)
+	}
+	order.Status = 1
+
+	log.Printf("[PAYOUT-APPROVAL] ✅ 复核通过 order=%d 复核人=%s IP=%s", orderId, operator, operatorIP)
+
+	lifecycle.Go("payout-approval-submit", func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[PANIC] approval submit panic: %v\n%s", r, debug.Stack())
+				notify.Notify(system.BotChatID, "error", "代付复核Panic", fmt.Sprintf("平台订单号: %d\npanic: %v", orderId, r), true)
+			}
+		}()
+		products, pErr := s.payoutSvc.selectPayoutProducts(merchant, req.PayType, order.Currency, order.Amount)
+		if pErr != nil {
+			if uErr := s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": orderId}, map[string]interface{}{
+				"status":      6, // 人工处理
+				"remark":      truncateReason(fmt.Sprintf("复核通过后无可用通道, 等待人工介入: %v", pErr)),
+				"update_time": time.Now(),
+			}); uErr != nil {
+				log.Printf("[WARN] 更新订单状态失败 order=%d err=%v", orderId, uErr)
+			}
+			notify.Notify(system.BotChatID, "error", "代付复核通过后提交失败",
+				fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n金额: `%s`\n错误: `%v`\n\n当前资金已冻结，请人工处理。",
+					orderId, order.MOrderID, order.Amount.String(), pErr), true)
+			return
+		}
+		cachePayoutRequest(orderId, req)
+		// 全部上游失败时 submitToUpstreams 内部会转人工并告警
+		_ = s.payoutSvc.submitToUpstreams(merchant, &req, products, order, tx, order.Amount, createTime)
+	})
+	return nil
+}
+
+// reject 复核驳回 / 超时：解冻退回余额并通知商户
+func (s *PayoutApprovalService) reject(orderId uint64, status int8, operator, operatorIP, remark string) error {
+	unlock, err := s.lock(orderId)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	rec, order, err := s.loadPending(orderId)
+	if err != nil {
+		return err
+	}
+	merchant, err := s.mainDao.GetMerchantId(strconv.FormatUint(order.MID, 10))
+	if err != nil || merchant == nil {
+		return fmt.Errorf("merchant invalid: %w", err)
+	}
+
+	decided, err := s.approvalDao.Decide(orderId, status, operator, operatorIP, remark)
+	if err != nil {
+		return err
+	}
+	if !decided {
+		return ErrApprovalAlreadyDecided
+	}
+
+	// 解冻：按代付失败路径退回余额
+	settle := order.SettleSnapshot
+	if err := s.mainDao.HandlePayoutCallback(
+		merchant.MerchantID,
+		order.Currency,
+		strconv.FormatUint(orderId, 10),
+		order.MOrderID,
+		settle.MerchantTotalFee,
+		settle.AgentTotalFee,
+		false,
+		order.Amount,
+		operator,
+	); err != nil {
+		if rErr := s.approvalDao.Reopen(orderId, status); rErr != nil {
+			log.Printf("[PAYOUT-APPROVAL] 复核记录回滚失败 order=%d err=%v", orderId, rErr)
+		}
+		notify.Notify(system.BotChatID, "error", "代付复核驳回解冻失败",
+			fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n金额: `%s`\n错误: `%v`\n\n复核记录已恢复为待复核，请重试或人工处理。",
+				orderId, order.MOrderID, order.Amount.String(), err), true)
+		return fmt.Errorf("unfreeze failed: %w", err)
+	}
+
+	now := time.Now()
+	why := "复核驳回"
+	if status == ordermodel.PayoutApprovalExpired {
+		why = "复核超时自动驳回"
+	}
+	orderTable := shard.OutOrderShard.GetTable(orderId, rec.OrderCreateTime)
+	if err := s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": orderId}, map[string]interface{}{
+		"status":      payoutStatusRejected,
+		"remark":      truncateReason(fmt.Sprintf("%s: %s %s", why, operator, remark)),
+		"finish_time": now,
+		"update_time": now,
+	}); err != nil {
+		log.Printf("[WARN] 更新订单状态失败 order=%d err=%v", orderId, err)
+	}
+	s.payoutSvc.limitSvc.ReleaseOrder(orderId)
+
+	log.Printf("[PAYOUT-APPROVAL] ❌ %s order=%d 复核人=%s IP=%s 备注=%s", why, orderId, operator, operatorIP, remark)
+	notify.Notify(system.BotChatID, "warn", "代付复核驳回",
+		fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n金额: `%s %s`\n结果: `%s`\n复核人: `%s`\n备注: `%s`\n\n冻结资金已退回商户余额。",
+			orderId, order.MOrderID, order.Amount.String(), order.Currency, why, operator, remark), true)
+
+	lifecycle.Go("payout-approval-notify", func() {
+		notifyPayoutMerchantFinal(s.orderDao, merchant, order, orderTable, payoutStatusRejected, "Rejected 已驳回")
+	})
+	return nil
+}
+
+// RunExpiry 定时扫描超时未复核的代付并自动驳回（多实例通过 Redis 锁互斥）
+func (s *PayoutApprovalService) RunExpiry() {
+	if !s.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(config.C.Approval.ScanInterval)
+	defer ticker.Stop()
+	log.Printf("[PAYOUT-APPROVAL] 超时扫描已启动 间隔=%v 超时=%v", config.C.Approval.ScanInterval, config.C.Approval.ExpireAfter)
+	for {
+		select {
+		case <-lifecycle.Stopping():
+			log.Printf("[PAYOUT-APPROVAL] 超时扫描已停止")
+			return
+		case <-ticker.C:
+			s.expireOnce()
+		}
+	}
+}
+
+func (s *PayoutApprovalService) expireOnce() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] approval expiry panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	ok, err := dal.RedisClient.SetNX(dal.RedisCtx, payoutApprovalExpireKey, 1, config.C.Approval.ScanInterval).Result()
+	if err != nil || !ok {
+		return
+	}
+	list, err := s.approvalDao.ListExpired(time.Now(), 100)
+	if err != nil {
+		log.Printf("[PAYOUT-APPROVAL] 查询超时复核失败: %v", err)
+		return
+	}
+	for _, r := range list {
+		if err := s.reject(r.OrderID, ordermodel.PayoutApprovalExpired, "system", "", "复核超时"); err != nil &&
+			!errors.Is(err, ErrApprovalAlreadyDecided) {
+			log.Printf("[PAYOUT-APPROVAL] 超时自动驳回失败 order=%d err=%v", r.OrderID, err)
+		}
+	}
+}
+
+// RecordPayoutBeneficiary 代付成功后记录收款账户，用于首次出款识别
+func RecordPayoutBeneficiary(mId uint64, currency, accNo string, orderId uint64) {
+	if !config.C.Approval.Enabled || accNo == "" {
+		return
+	}
+	if err := dao.NewPayoutApprovalDao().RecordBeneficiary(mId, currency, accNo, orderId); err != nil {
+		log.Printf("[PAYOUT-APPROVAL] 记录收款账户失败 merchant=%d order=%d err=%v", mId, orderId, err)
+	}
+}
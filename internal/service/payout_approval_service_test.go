@@ -0,0 +1,282 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/idgen"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/shard"
+
+	"github.com/shopspring/decimal"
+)
+
+const approvalInterfaceCode = "test_approval_flow"
+
+type approvalFixture struct {
+	main      *memdao.MainStore
+	orders    *memdao.PayoutOrderStore
+	approvals *memdao.PayoutApprovalStore
+	conn      *fakePayoutConnector
+	svc       *PayoutApprovalService
+	merchant  *mainmodel.Merchant
+}
+
+// newApprovalFixture 商户 1001（无代理）开通 BR_PIX_OUT（费率 2%），余额 5000 BRL；BRL 默认复核阈值 1000
+func newApprovalFixture(t *testing.T) *approvalFixture {
+	t.Helper()
+	fakeredis.Use(t)
+	shard.InitShardEngines()
+	if err := idgen.InitNode("default", 1); err != nil {
+		t.Fatalf("init idgen: %v", err)
+	}
+	prevTimeout, prevApproval, prevReassign := config.C.Upstream.Timeout.Payout, config.C.Approval, config.C.Reassign.Enabled
+	config.C.Upstream.Timeout.Payout = 5 * time.Second
+	config.C.Approval = config.ApprovalCfg{
+		Enabled: true, Thresholds: map[string]string{"brl": "1000"},
+		ExpireAfter: 30 * time.Minute, ScanInterval: time.Minute,
+	}
+	config.C.Reassign.Enabled = false
+	t.Cleanup(func() {
+		config.C.Upstream.Timeout.Payout, config.C.Approval, config.C.Reassign.Enabled = prevTimeout, prevApproval, prevReassign
+	})
+
+	f := &approvalFixture{
+		main:   memdao.NewMainStore(),
+		orders: memdao.NewPayoutOrderStore(),
+		conn:   &fakePayoutConnector{},
+	}
+	f.approvals = memdao.NewPayoutApprovalStore(f.orders)
+	connector.Register(approvalInterfaceCode, f.conn)
+	f.merchant = &mainmodel.Merchant{MerchantID: testMerchantID, NickName: "m1", AppId: "APP1001", ApiKey: "secret", Status: 1, Currency: "BRL"}
+	f.main.AddMerchant(*f.merchant)
+	f.main.AddCountry(dto.CurrencyCodeResponse{ID: 76, Code: "BRL", Country: "Brazil"})
+	f.main.AddSysChannel(dto.PayWayVo{Id: 8, Title: "PIX 代付", Currency: "BRL", Coding: reassignChannelCode, Type: 2, Status: 1})
+	f.main.AddMerchantChannel(dto.MerchantChannelDTO{
+		MId: testMerchantID, SysChannelId: 8, Status: 1, Type: 2, DispatchMode: 1, Currency: "BRL",
+		SysChannelCode: reassignChannelCode, DefaultRate: decimal.NewFromInt(2),
+	})
+	minAmount, maxAmount := decimal.NewFromInt(10), decimal.NewFromInt(5000)
+	f.main.AddPayProduct(testMerchantID, dto.PayProductVo{
+		ID: 51, Currency: "BRL", Type: 2, Status: 1,
+		UpstreamId: 601, UpstreamCode: "UP_601", UpstreamTitle: "up", UpstreamWeight: 10, InterfaceCode: approvalInterfaceCode,
+		SysChannelID: 8, SysChannelCode: reassignChannelCode, SysChannelTitle: "PIX 代付",
+		MDefaultRate: decimal.NewFromInt(2), CostRate: decimal.NewFromInt(1),
+		MinAmount: &minAmount, MaxAmount: &maxAmount,
+	})
+	f.main.SetAccount(testMerchantID, "BRL", decimal.NewFromInt(5000), decimal.Zero)
+
+	f.svc = NewPayoutApprovalServiceWithRepos(nopPublisher{}, f.main, f.orders, f.orders.Index, f.approvals)
+	t.Cleanup(f.svc.payoutSvc.Shutdown)
+	return f
+}
+
+// hold 下单达到复核阈值，订单进入待复核
+func (f *approvalFixture) hold(t *testing.T, tranFlow, amount string) *ordermodel.MerchantPayOutOrderM {
+	t.Helper()
+	resp, err := f.svc.payoutSvc.Create(dto.CreatePayoutOrderReq{
+		MerchantNo: "APP1001", TranFlow: tranFlow, Amount: amount, PayType: reassignChannelCode,
+		AccNo: "12345678", AccName: "Joao", PayMethod: "PIX",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	waitLifecycle(t)
+	orderID, _ := strconv.ParseUint(resp.PaySerialNo, 10, 64)
+	o, err := f.orders.GetByOrderId(shard.OutOrderShard.GetTable(orderID, time.Now()), orderID)
+	if err != nil || o == nil {
+		t.Fatalf("load order: %v", err)
+	}
+	if o.Status != ordermodel.PayoutStatusAwaitingApproval || f.conn.calls != 0 {
+		t.Fatalf("order status = %d, upstream calls = %d, want awaiting approval without submit", o.Status, f.conn.calls)
+	}
+	return o
+}
+
+func (f *approvalFixture) decide(orderID uint64, action string) error {
+	return f.svc.Decide(dto.PayoutApprovalDecideReq{
+		OrderId: strconv.FormatUint(orderID, 10), Action: action, Operator: "checker", Remark: action,
+	}, "10.0.0.1")
+}
+
+func (f *approvalFixture) order(t *testing.T, orderID uint64) *ordermodel.MerchantPayOutOrderM {
+	t.Helper()
+	o, err := f.orders.GetByOrderId(shard.OutOrderShard.GetTable(orderID, time.Now()), orderID)
+	if err != nil || o == nil {
+		t.Fatalf("load order: %v", err)
+	}
+	return o
+}
+
+func (f *approvalFixture) balance(t *testing.T) (money, freeze decimal.Decimal) {
+	t.Helper()
+	acc, ok := f.main.Account(testMerchantID, "BRL")
+	if !ok {
+		t.Fatal("account not found")
+	}
+	return acc.Money, acc.FreezeMoney
+}
+
+func TestApprovalCheckThresholdAndFirstTimeBeneficiary(t *testing.T) {
+	f := newApprovalFixture(t)
+
+	// 币种默认阈值（配置键为小写）
+	if required, _ := f.svc.Check(f.merchant, "BRL", decimal.NewFromInt(999), "12345678"); required {
+		t.Error("amount below threshold requires approval")
+	}
+	if required, reason := f.svc.Check(f.merchant, "BRL", decimal.NewFromInt(1000), "12345678"); !required || !strings.Contains(reason, "阈值") {
+		t.Errorf("threshold check = %v/%q", required, reason)
+	}
+
+	// 商户级规则优先：不按金额复核，只复核首次出款账户
+	f.main.AddPayoutApprovalRule(mainmodel.MerchantPayoutApproval{MID: testMerchantID, Currency: "BRL", FirstTimeBeneficiary: 1, Status: 1})
+	if required, reason := f.svc.Check(f.merchant, "BRL", decimal.NewFromInt(3000), "12345678"); !required || reason != "首次出款收款账户" {
+		t.Errorf("first-time check = %v/%q", required, reason)
+	}
+	if err := f.approvals.RecordBeneficiary(testMerchantID, "BRL", "12345678", 1); err != nil {
+		t.Fatalf("record beneficiary: %v", err)
+	}
+	if required, _ := f.svc.Check(f.merchant, "BRL", decimal.NewFromInt(3000), "12345678"); required {
+		t.Error("known beneficiary still requires approval")
+	}
+
+	config.C.Approval.Enabled = false
+	if required, _ := f.svc.Check(f.merchant, "BRL", decimal.NewFromInt(3000), "87654321"); required {
+		t.Error("disabled approval requires approval")
+	}
+}
+
+func TestApprovalRejectRefundsFrozenFunds(t *testing.T) {
+	f := newApprovalFixture(t)
+	order := f.hold(t, "P-A001", "1000")
+	// 冻结 1000 + 手续费 20
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(3980)) || !freeze.Equal(decimal.NewFromInt(1020)) {
+		t.Fatalf("balance after hold = %s/%s, want 3980/1020", money, freeze)
+	}
+
+	if err := f.decide(order.OrderID, "reject"); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	waitLifecycle(t)
+	if o := f.order(t, order.OrderID); o.Status != payoutStatusRejected || o.FinishTime == nil {
+		t.Errorf("order = status %d, finishTime %v", o.Status, o.FinishTime)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(5000)) || !freeze.IsZero() {
+		t.Errorf("balance after reject = %s/%s, want 5000/0", money, freeze)
+	}
+	rec, _ := f.approvals.GetByOrderId(order.OrderID)
+	if rec == nil || rec.Status != ordermodel.PayoutApprovalRejected || rec.Operator != "checker" || rec.OperatorIP != "10.0.0.1" {
+		t.Errorf("approval = %+v", rec)
+	}
+
+	// 已驳回的订单不能再通过
+	if err := f.decide(order.OrderID, "approve"); !errors.Is(err, ErrApprovalAlreadyDecided) {
+		t.Errorf("approve after reject err = %v", err)
+	}
+	if f.conn.calls != 0 {
+		t.Errorf("upstream calls = %d, want 0", f.conn.calls)
+	}
+}
+
+func TestApprovalApproveSubmitsUpstream(t *testing.T) {
+	f := newApprovalFixture(t)
+	order := f.hold(t, "P-A002", "1000")
+
+	if err := f.decide(order.OrderID, "approve"); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	waitLifecycle(t)
+	if o := f.order(t, order.OrderID); o.Status != 1 || f.conn.calls != 1 {
+		t.Errorf("order status = %d, upstream calls = %d, want 1/1", o.Status, f.conn.calls)
+	}
+	// 通过后资金保持冻结，等待上游回调结算
+	if _, freeze := f.balance(t); !freeze.Equal(decimal.NewFromInt(1020)) {
+		t.Errorf("freeze = %s, want 1020", freeze)
+	}
+}
+
+func TestApprovalExpiryAutoRejects(t *testing.T) {
+	f := newApprovalFixture(t)
+	config.C.Approval.ExpireAfter = -time.Second
+	order := f.hold(t, "P-A003", "1000")
+
+	f.svc.expireOnce()
+	waitLifecycle(t)
+	rec, _ := f.approvals.GetByOrderId(order.OrderID)
+	if rec == nil || rec.Status != ordermodel.PayoutApprovalExpired || rec.Operator != "system" {
+		t.Fatalf("approval = %+v", rec)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(5000)) || !freeze.IsZero() {
+		t.Errorf("balance after expiry = %s/%s, want 5000/0", money, freeze)
+	}
+}
+
+func TestApprovalConcurrentDecideIsSerialised(t *testing.T) {
+	f := newApprovalFixture(t)
+	order := f.hold(t, "P-A004", "1000")
+
+	// 复核人同时点击通过/驳回：只有一个生效，其余返回已处理
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		action := "approve"
+		if i%2 == 1 {
+			action = "reject"
+		}
+		wg.Add(1)
+		go func(i int, action string) {
+			defer wg.Done()
+			errs[i] = f.decide(order.OrderID, action)
+		}(i, action)
+	}
+	wg.Wait()
+	waitLifecycle(t)
+
+	won := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, ErrApprovalAlreadyDecided):
+			t.Errorf("decide err = %v", err)
+		}
+	}
+	if won != 1 {
+		t.Fatalf("successful decides = %d, want 1", won)
+	}
+
+	rec, _ := f.approvals.GetByOrderId(order.OrderID)
+	money, freeze := f.balance(t)
+	switch rec.Status {
+	case ordermodel.PayoutApprovalApproved:
+		if f.conn.calls != 1 || !freeze.Equal(decimal.NewFromInt(1020)) {
+			t.Errorf("approved: upstream calls = %d, freeze = %s", f.conn.calls, freeze)
+		}
+	case ordermodel.PayoutApprovalRejected:
+		if f.conn.calls != 0 || !money.Equal(decimal.NewFromInt(5000)) || !freeze.IsZero() {
+			t.Errorf("rejected: upstream calls = %d, balance = %s/%s", f.conn.calls, money, freeze)
+		}
+	default:
+		t.Errorf("approval status = %d", rec.Status)
+	}
+	if logs := f.main.MoneyLogs(testMerchantID); len(logs) > 3 {
+		t.Errorf("money logs = %v, funds moved more than once", logTypesOf(logs))
+	}
+}
+
+func logTypesOf(logs []mainmodel.MoneyLog) []int8 {
+	out := make([]int8, 0, len(logs))
+	for _, l := range logs {
+		out = append(out, l.Type)
+	}
+	return out
+}
@@ -0,0 +1,811 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"wht-order-api/internal/beneficiary"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
+	"wht-order-api/internal/idgen"
+	"wht-order-api/internal/lifecycle"
+	"wht-order-api/internal/metrics"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/risk"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/system"
+	"wht-order-api/internal/utils"
+)
+
+const (
+	payoutBatchNotifyMaxRetry = 3
+
+	payoutBatchLeasePrefix = "payout_batch_lease:" // payout_batch_lease:{平台批次ID}，批次处理租约
+	payoutBatchLeaseTTL    = 2 * time.Minute
+	payoutBatchResumeKey   = "payout_batch_resume_lock"
+	payoutBatchResumeAfter = time.Minute // 创建超过该时长仍处理中且无租约的批次才接管
+)
+
+var (
+	batchWorkerOnce sync.Once
+	batchWorkerSem  chan struct{}
+)
+
+// batchWorkers 全局批量代付协程池（所有批次共享），限制对上游与数据库的并发压力
+func batchWorkers() chan struct{} {
+	batchWorkerOnce.Do(func() {
+		batchWorkerSem = make(chan struct{}, config.C.Batch.Workers)
+	})
+	return batchWorkerSem
+}
+
+// BatchError 批量代付业务错误（携带错误码与行明细）
+type BatchError struct {
+	Code int
+	Msg  string
+	Rows []dto.PayoutBatchRowError
+}
+
+func (e *BatchError) Error() string {
+	return e.Msg
+}
+
+// PayoutBatchService 批量代付服务
+type PayoutBatchService struct {
+	payoutSvc *PayoutOrderService
+	mainDao   dao.MainRepository
+	batchDao  *dao.PayoutBatchDao
+}
+
+func NewPayoutBatchService(pub event.Publisher) *PayoutBatchService {
+	payoutSvc := NewPayoutOrderService(pub)
+	return &PayoutBatchService{
+		payoutSvc: payoutSvc,
+		mainDao:   payoutSvc.mainDao,
+		batchDao:  dao.NewPayoutBatchDao(),
+	}
+}
+
+// Create 提交批量代付：逐行校验 → 整批冻结 → 落库 → 异步分发
+func (s *PayoutBatchService) Create(req dto.CreatePayoutBatchReq, content []byte) (resp dto.CreatePayoutBatchResp, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] payout batch Create panic: %v\n%s", r, debug.Stack())
+			notify.Notify(system.BotChatID, "error", "批量代付Panic", fmt.Sprintf("panic: %v", r), true)
+			err = fmt.Errorf("internal error")
+		}
+	}()
+
+	// 1 商户
+	merchant, err := s.payoutSvc.getMerchantWithCache(req.MerchantNo)
+	if err != nil || merchant == nil {
+		return resp, fmt.Errorf("merchant invalid: %w", err)
+	}
+
+	// 2 解析文件
+	rows, err := ParsePayoutBatchFile(req.FileType, content)
+	if err != nil {
+		return resp, &BatchError{Code: constant.CodeBatchFileInvalid, Msg: err.Error()}
+	}
+	if len(rows) == 0 {
+		return resp, &BatchError{Code: constant.CodeBatchFileInvalid, Msg: "batch file is empty"}
+	}
+	if len(rows) > config.C.Batch.MaxRows {
+		return resp, &BatchError{Code: constant.CodeBatchTooLarge, Msg: fmt.Sprintf("batch rows %d exceed limit %d", len(rows), config.C.Batch.MaxRows)}
+	}
+
+	// 3 系统通道 & 商户通道
+	channelDetail, err := s.payoutSvc.getSysChannelWithCache(req.PayType)
+	if err != nil || channelDetail == nil {
+		return resp, errors.New("channel invalid")
+	}
+	merchantChannelInfo, err := NewCommonService().GetMerchantChannelInfo(merchant.MerchantID, req.PayType)
+	if err != nil || merchantChannelInfo == nil {
+		return resp, fmt.Errorf("merchant channel invalid,payType: %s", req.PayType)
+	}
+
+	// 4 批次号幂等
+	exist, err := s.batchDao.GetByBatchNo(merchant.MerchantID, req.BatchNo)
+	if err != nil {
+		return resp, err
+	}
+	if exist != nil {
+		return resp, &BatchError{Code: constant.CodeBatchAlreadyExist, Msg: fmt.Sprintf("batch_no already exists: %s", req.BatchNo)}
+	}
+
+	// 5 候选通道（用于预估冻结金额）
+	var products []dto.PayProductVo
+	if merchantChannelInfo.DispatchMode == 2 {
+		single, sErr := s.payoutSvc.SelectSingleChannel(uint(merchant.MerchantID), req.PayType, 2, channelDetail.Currency)
+		if sErr != nil {
+			return resp, errors.New("no single channel available")
+		}
+		products = []dto.PayProductVo{single}
+	} else {
+		products, err = s.mainDao.GetAvailablePollingPayProducts(uint(merchant.MerchantID), req.PayType, channelDetail.Currency, 2)
+		if err != nil || len(products) == 0 {
+			return resp, errors.New("no channel products available")
+		}
+	}
+
+	// 6 逐行校验 + 预估冻结（取可用通道中冻结金额最高者，分发时多退少补）
+	batchID := idgen.New()
+	now := time.Now()
+	totalAmount, totalFreeze := decimal.Zero, decimal.Zero
+	items := make([]ordermodel.PayoutBatchItemM, 0, len(rows))
+	var rowErrs []dto.PayoutBatchRowError
+	seen := make(map[string]int, len(rows))
+
+	for _, row := range rows {
+		amount, reserve, vErr := s.validateRow(merchant, channelDetail.Currency, products, row, seen)
+		if vErr != nil {
+			rowErrs = append(rowErrs, dto.PayoutBatchRowError{LineNo: row.LineNo, TranFlow: row.TranFlow, Error: vErr.Error()})
+			continue
+		}
+		payload, _ := json.Marshal(row)
+		items = append(items, ordermodel.PayoutBatchItemM{
+			BatchID:      batchID,
+			LineNo:       row.LineNo,
+			TranFlow:     row.TranFlow,
+			Amount:       amount,
+			FreezeAmount: reserve,
+			Payload:      string(payload),
+			Status:       ordermodel.PayoutBatchItemPending,
+			CreateTime:   now,
+		})
+		totalAmount = totalAmount.Add(amount)
+		totalFreeze = totalFreeze.Add(reserve)
+	}
+	if len(rowErrs) > 0 {
+		return resp, &BatchError{Code: constant.CodeBatchRowInvalid, Msg: fmt.Sprintf("%d invalid rows", len(rowErrs)), Rows: rowErrs}
+	}
+
+	// 7 整批冻结（单条资金日志，余额不足则整批拒绝）
+	batchNo := strconv.FormatUint(batchID, 10)
+	if err := s.mainDao.FreezePayout(merchant.MerchantID, channelDetail.Currency, batchNo, req.BatchNo, totalFreeze, merchant.NickName); err != nil {
+		return resp, &BatchError{Code: constant.CodeMerchantBalanceLow, Msg: fmt.Sprintf("freeze batch amount failed: %v", err)}
+	}
+
+	// 8 落库
+	batch := &ordermodel.PayoutBatchM{
+		BatchID:        batchID,
+		MID:            merchant.MerchantID,
+		BatchNo:        req.BatchNo,
+		PayType:        req.PayType,
+		Currency:       channelDetail.Currency,
+		FileType:       strings.ToLower(req.FileType),
+		FileHash:       req.FileHash,
+		TotalCount:     len(items),
+		TotalAmount:    totalAmount,
+		FreezeAmount:   totalFreeze,
+		Status:         ordermodel.PayoutBatchStatusProcessing,
+		NotifyURL:      req.NotifyUrl,
+		OrderNotifyURL: req.OrderNotifyUrl,
+		ClientIP:       req.ClientId,
+		CreateTime:     now,
+	}
+	if err := s.batchDao.InsertBatch(batch, items); err != nil {
+		// 落库失败，整批解冻
+		if rErr := s.mainDao.ReleaseExcessFreeze(merchant.MerchantID, channelDetail.Currency, batchNo, 0, totalFreeze, merchant.NickName, req.BatchNo); rErr != nil {
+			notify.Notify(system.BotChatID, "error", "批量代付解冻失败",
+				fmt.Sprintf("商户号: `%s`\n批次号: `%s`\n冻结金额: `%s`\n错误: `%v`\n\n请人工核对商户冻结资金。",
+					req.MerchantNo, req.BatchNo, totalFreeze.StringFixed(4), rErr), true)
+		}
+		return resp, fmt.Errorf("create batch failed: %w", err)
+	}
+
+	log.Printf("[PAYOUT-BATCH] ✅ 批次已受理 商户号=%s 批次号=%s 平台批次=%d 笔数=%d 总金额=%s 冻结=%s",
+		req.MerchantNo, req.BatchNo, batchID, len(items), totalAmount.String(), totalFreeze.String())
+
+	// 9 异步分发
+	lifecycle.Go("payout-batch-process", func() { s.process(batch, merchant, req.PayType, items, false) })
+
+	resp = dto.CreatePayoutBatchResp{
+		Code:         "0",
+		Msg:          "success",
+		BatchNo:      req.BatchNo,
+		BatchSerial:  batchNo,
+		TotalCount:   len(items),
+		TotalAmount:  totalAmount.String(),
+		FreezeAmount: totalFreeze.String(),
+		SysTime:      strconv.FormatInt(utils.GetTimestampMs(), 10),
+	}
+	return resp, nil
+}
+
+// validateRow 校验单行并返回金额与预留冻结金额
+func (s *PayoutBatchService) validateRow(
+	merchant *mainmodel.Merchant,
+	currency string,
+	products []dto.PayProductVo,
+	row dto.PayoutBatchRow,
+	seen map[string]int,
+) (decimal.Decimal, decimal.Decimal, error) {
+	if row.TranFlow == "" || row.AccNo == "" || row.AccName == "" || row.PayMethod == "" {
+		return decimal.Zero, decimal.Zero, errors.New("tran_flow, acc_no, acc_name, pay_method are required")
+	}
+	if line, ok := seen[row.TranFlow]; ok {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("duplicate tran_flow with line %d", line)
+	}
+	seen[row.TranFlow] = row.LineNo
+
+	amount, err := decimal.NewFromString(strings.TrimSpace(row.Amount))
+	if err != nil || amount.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero, decimal.Zero, errors.New("amount invalid")
+	}
+	if utils.IsCryptoCurrency(currency) {
+		if err := utils.CheckCryptoPayMethod(currency, row.PayMethod); err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+		if err := utils.CheckCryptoAmount(row.PayMethod, amount); err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+	}
+	if err := beneficiary.Check(s.mainDao, beneficiary.Account{
+		Currency:     currency,
+		PayMethod:    row.PayMethod,
+		AccNo:        row.AccNo,
+		AccName:      row.AccName,
+		BankCode:     row.BankCode,
+		CciNo:        row.CciNo,
+		AccountType:  row.AccountType,
+		IdentityType: row.IdentityType,
+		IdentityNum:  row.IdentityNum,
+		Address:      row.Address,
+		Network:      row.Network,
+	}); err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+
+	reserve := decimal.Zero
+	for _, p := range products {
+		if !utils.MatchOrderRange(amount, fmt.Sprintf("%v-%v", p.MinAmount, p.MaxAmount)) {
+			continue
+		}
+		settle, err := s.payoutSvc.calculateSettlement(merchant, p, amount, row.PayMethod)
+		if err != nil {
+			continue
+		}
+		need := amount.Add(settle.MerchantTotalFee).Add(settle.AgentTotalFee)
+		if need.GreaterThan(reserve) {
+			reserve = need
+		}
+	}
+	if reserve.IsZero() {
+		return decimal.Zero, decimal.Zero, errors.New("no channel available for this amount")
+	}
+	return amount, reserve, nil
+}
+
+// process 通过全局协程池分发批次中待处理的行，全部完成后汇总并回调商户；
+// 持有批次租约期间其他实例不会接管，resumed 表示重启后恢复处理
+func (s *PayoutBatchService) process(batch *ordermodel.PayoutBatchM, merchant *mainmodel.Merchant, payType string, items []ordermodel.PayoutBatchItemM, resumed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] payout batch process panic: %v\n%s", r, debug.Stack())
+			notify.Notify(system.BotChatID, "error", "批量代付Panic", fmt.Sprintf("批次: %d\npanic: %v", batch.BatchID, r), true)
+		}
+	}()
+
+	release, ok := acquireBatchLease(batch.BatchID)
+	if !ok {
+		log.Printf("[PAYOUT-BATCH] 批次正由其他进程处理，跳过 batch=%d", batch.BatchID)
+		return
+	}
+	defer release()
+
+	// InsertBatch 回填了自增ID，重新读取以便按ID更新行状态（恢复时以库中行状态为准）
+	stored, err := s.batchDao.ListItems(batch.BatchID)
+	if err == nil && len(stored) == len(items) {
+		items = stored
+	}
+
+	sem := batchWorkers()
+	var wg sync.WaitGroup
+	results := make([]int8, len(items))
+	for i := range items {
+		if items[i].Status != ordermodel.PayoutBatchItemPending {
+			results[i] = items[i].Status
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[idx] = s.processItem(batch, merchant, payType, &items[idx], resumed)
+		}(i)
+	}
+	wg.Wait()
+
+	submitted, failed := 0, 0
+	for _, st := range results {
+		if st == ordermodel.PayoutBatchItemFailed {
+			failed++
+		} else {
+			submitted++
+		}
+	}
+	finish := time.Now()
+	batch.SubmittedCount, batch.FailCount, batch.Status, batch.FinishTime = submitted, failed, ordermodel.PayoutBatchStatusCompleted, &finish
+	if err := s.batchDao.UpdateBatch(batch.BatchID, map[string]interface{}{
+		"submitted_count": submitted,
+		"fail_count":      failed,
+		"status":          ordermodel.PayoutBatchStatusCompleted,
+		"finish_time":     finish,
+	}); err != nil {
+		log.Printf("[PAYOUT-BATCH] 更新批次状态失败 batch=%d err=%v", batch.BatchID, err)
+	}
+
+	log.Printf("[PAYOUT-BATCH] ✅ 批次处理完成 批次号=%s 平台批次=%d 提交=%d 失败=%d 耗时=%v",
+		batch.BatchNo, batch.BatchID, submitted, failed, finish.Sub(batch.CreateTime))
+
+	s.notifyMerchant(batch, merchant)
+}
+
+// processItem 处理单行：风控 → 选择通道 → 冻结多退少补 → 创建订单 → 提交上游
+func (s *PayoutBatchService) processItem(batch *ordermodel.PayoutBatchM, merchant *mainmodel.Merchant, payType string, item *ordermodel.PayoutBatchItemM, resumed bool) (status int8) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] payout batch item panic: %v\n%s", r, debug.Stack())
+			status = ordermodel.PayoutBatchItemFailed
+		}
+	}()
+
+	var row dto.PayoutBatchRow
+	_ = json.Unmarshal([]byte(item.Payload), &row)
+	req := dto.CreatePayoutOrderReq{
+		MerchantNo:   merchant.AppId,
+		TranFlow:     item.TranFlow,
+		Amount:       item.Amount.String(),
+		PayType:      payType,
+		NotifyUrl:    batch.OrderNotifyURL,
+		AccNo:        row.AccNo,
+		AccName:      row.AccName,
+		PayMethod:    row.PayMethod,
+		BankCode:     row.BankCode,
+		BankName:     row.BankName,
+		BranchBank:   row.BranchBank,
+		PayEmail:     row.PayEmail,
+		PayPhone:     row.PayPhone,
+		IdentityType: row.IdentityType,
+		IdentityNum:  row.IdentityNum,
+		ClientId:     batch.ClientIP,
+		AccountType:  row.AccountType,
+		CciNo:        row.CciNo,
+		Address:      row.Address,
+		Network:      row.Network,
+	}
+	amount := item.Amount
+	releaseNo := fmt.Sprintf("%d-%d", batch.BatchID, item.LineNo)
+
+	// 失败：释放该行预留冻结，回滚风控计数
+	var riskResult risk.Result
+	fail := func(reserve decimal.Decimal, reason string) int8 {
+		riskResult.Release()
+		if rErr := s.mainDao.ReleaseExcessFreeze(merchant.MerchantID, batch.Currency, releaseNo, 0, reserve, merchant.NickName, item.TranFlow); rErr != nil {
+			notify.Notify(system.BotChatID, "error", "批量代付解冻失败",
+				fmt.Sprintf("批次号: `%s`\n行号: `%d`\n商户订单号: `%s`\n金额: `%s`\n错误: `%v`\n\n请人工核对商户冻结资金。",
+					batch.BatchNo, item.LineNo, item.TranFlow, reserve.StringFixed(4), rErr), true)
+		}
+		if uErr := s.batchDao.UpdateItem(item.ID, 0, ordermodel.PayoutBatchItemFailed, truncateReason(reason)); uErr != nil {
+			log.Printf("[PAYOUT-BATCH] 更新批次行失败 batch=%d line=%d err=%v", batch.BatchID, item.LineNo, uErr)
+		}
+		return ordermodel.PayoutBatchItemFailed
+	}
+
+	// 0 逐笔风控（与单笔代付一致）：拒绝释放冻结，复核交由代付复核流程
+	riskResult = evaluateBatchItemRisk(batch, merchant, req, amount)
+	switch riskResult.Action {
+	case risk.ActionDeny:
+		return fail(item.FreezeAmount, "risk denied: "+riskResult.Reason())
+	case risk.ActionReview:
+		req.RiskReview = riskResult.Reason()
+	}
+
+	// 1 通道
+	products, err := s.payoutSvc.selectPayoutProducts(merchant, payType, batch.Currency, amount)
+	if err != nil {
+		return fail(item.FreezeAmount, err.Error())
+	}
+
+	// 2 幂等
+	oid, exists, err := s.payoutSvc.checkIdempotency(merchant.MerchantID, item.TranFlow)
+	if err != nil {
+		return fail(item.FreezeAmount, err.Error())
+	}
+	if exists {
+		if resumed {
+			// 进程中断前可能已为本行创建订单（冻结已转入订单），不释放冻结，转人工核对；风控计数已在首次处理时计入
+			riskResult.Release()
+			msg := "resumed: order already exists, check manually"
+			if uErr := s.batchDao.UpdateItem(item.ID, 0, ordermodel.PayoutBatchItemManual, msg); uErr != nil {
+				log.Printf("[PAYOUT-BATCH] 更新批次行失败 batch=%d line=%d err=%v", batch.BatchID, item.LineNo, uErr)
+			}
+			notify.Notify(system.BotChatID, "warn", "批量代付恢复待核对",
+				fmt.Sprintf("批次号: `%s`\n行号: `%d`\n商户订单号: `%s`\n\n恢复处理时订单已存在，行预留冻结未释放，请人工核对。",
+					batch.BatchNo, item.LineNo, item.TranFlow), true)
+			return ordermodel.PayoutBatchItemManual
+		}
+		return fail(item.FreezeAmount, "order already exists")
+	}
+
+	// 2.1 累计限额，后续失败回滚
+	reservation, err := s.payoutSvc.limitSvc.Reserve(2, merchant.MerchantID, uint64(products[0].SysChannelID), batch.Currency, row.AccNo, amount)
+	if err != nil {
+		return fail(item.FreezeAmount, err.Error())
+	}
+
+	// 3 结算 & 冻结多退少补
+	settle, err := s.payoutSvc.calculateSettlement(merchant, products[0], amount, row.PayMethod)
+	if err != nil {
+		s.payoutSvc.limitSvc.Release(reservation)
+		return fail(item.FreezeAmount, err.Error())
+	}
+	need := amount.Add(settle.MerchantTotalFee).Add(settle.AgentTotalFee)
+	orderNo := strconv.FormatUint(oid, 10)
+	if diff := need.Sub(item.FreezeAmount); diff.GreaterThan(decimal.Zero) {
+		if fErr := s.mainDao.FreezeAdditionalAmount(merchant.MerchantID, batch.Currency, orderNo, 0, diff, merchant.NickName, item.TranFlow); fErr != nil {
+			s.payoutSvc.limitSvc.Release(reservation)
+			return fail(item.FreezeAmount, fmt.Sprintf("freeze additional amount failed: %v", fErr))
+		}
+	} else if diff.LessThan(decimal.Zero) {
+		if rErr := s.mainDao.ReleaseExcessFreeze(merchant.MerchantID, batch.Currency, orderNo, 0, diff.Neg(), merchant.NickName, item.TranFlow); rErr != nil {
+			log.Printf("[PAYOUT-BATCH] 释放多余冻结失败 batch=%d line=%d err=%v", batch.BatchID, item.LineNo, rErr)
+			need = item.FreezeAmount
+		}
+	}
+
+	// 4 创建订单（整批已冻结，不再逐笔冻结）
+	now := time.Now()
+	order, tx, err := s.payoutSvc.createOrderAndTransaction(merchant, req, products[0], amount, oid, now, settle, false)
+	if err != nil {
+		s.payoutSvc.limitSvc.Release(reservation)
+		return fail(need, err.Error())
+	}
+	s.payoutSvc.limitSvc.Bind(reservation, 2, order.OrderID)
+	riskResult.Commit()
+	metrics.OrderCreated("payout", req.PayType, batch.Currency, req.MerchantNo)
+	if !order.FreezeAmount.Equal(need) {
+		// 多余冻结释放失败时，以实际冻结金额为准
+		_ = s.payoutSvc.orderDao.UpdateByWhere(shard.OutOrderShard.GetTable(oid, now), map[string]interface{}{"order_id": oid}, map[string]interface{}{"freeze_amount": need})
+		order.FreezeAmount = need
+	}
+	lifecycle.Go("payout-cache-request", func() { cachePayoutRequest(oid, req) })
+
+	// 5 提交上游（需复核的订单待复核通过后再提交）
+	status = ordermodel.PayoutBatchItemSubmitted
+	msg := ""
+	approvalSvc := newPayoutApprovalService(s.payoutSvc)
+	required, reason := approvalSvc.Check(merchant, batch.Currency, amount, req.AccNo)
+	if !required && req.RiskReview != "" && approvalSvc.Enabled() {
+		required, reason = true, "风控复核: "+req.RiskReview
+	}
+	if required {
+		if hErr := approvalSvc.Hold(order, req, reason, now); hErr != nil {
+			status = ordermodel.PayoutBatchItemManual
+			msg = truncateReason(fmt.Sprintf("进入复核失败: %v", hErr))
+			_ = s.payoutSvc.orderDao.UpdateByWhere(shard.OutOrderShard.GetTable(oid, now), map[string]interface{}{"order_id": oid}, map[string]interface{}{
+				"status":      6, // 人工处理
+				"remark":      msg,
+				"update_time": time.Now(),
+			})
+		} else {
+			msg = truncateReason("待复核: " + reason)
+		}
+	} else if lastErr := s.payoutSvc.submitToUpstreams(merchant, &req, products, order, tx, amount, now); lastErr != nil {
+		status = ordermodel.PayoutBatchItemManual
+		if order.Status == payoutStatusFail {
+			// 上游明确拒绝，订单已失败退款
+			status = ordermodel.PayoutBatchItemFailed
+		}
+		msg = truncateReason(lastErr.Error())
+	}
+	if uErr := s.batchDao.UpdateItem(item.ID, oid, status, msg); uErr != nil {
+		log.Printf("[PAYOUT-BATCH] 更新批次行失败 batch=%d line=%d err=%v", batch.BatchID, item.LineNo, uErr)
+	}
+	return status
+}
+
+// evaluateBatchItemRisk 批次行风控评估（引擎未初始化时放行）
+func evaluateBatchItemRisk(batch *ordermodel.PayoutBatchM, merchant *mainmodel.Merchant, req dto.CreatePayoutOrderReq, amount decimal.Decimal) risk.Result {
+	engine := risk.Default()
+	if engine == nil {
+		return risk.Result{Action: risk.ActionAllow}
+	}
+	return engine.Evaluate(risk.Input{
+		OrderType:   2,
+		MerchantID:  merchant.MerchantID,
+		MerchantNo:  req.MerchantNo,
+		TranFlow:    req.TranFlow,
+		PayType:     req.PayType,
+		Currency:    batch.Currency,
+		Amount:      amount,
+		IP:          batch.ClientIP,
+		AccNo:       req.AccNo,
+		Email:       req.PayEmail,
+		Phone:       req.PayPhone,
+		IdentityNum: req.IdentityNum,
+	})
+}
+
+// acquireBatchLease 获取批次处理租约，处理期间定时续期；进程退出后租约过期，由恢复扫描接管
+func acquireBatchLease(batchID uint64) (func(), bool) {
+	key := payoutBatchLeasePrefix + strconv.FormatUint(batchID, 10)
+	ok, err := dal.RedisClient.SetNX(dal.RedisCtx, key, 1, payoutBatchLeaseTTL).Result()
+	if err != nil || !ok {
+		return nil, false
+	}
+	done := make(chan struct{})
+	lifecycle.Go("payout-batch-lease", func() {
+		ticker := time.NewTicker(payoutBatchLeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				dal.RedisClient.Expire(dal.RedisCtx, key, payoutBatchLeaseTTL)
+			}
+		}
+	})
+	return func() {
+		close(done)
+		dal.RedisClient.Del(dal.RedisCtx, key)
+	}, true
+}
+
+// RunResume 启动时及定时恢复处理中但无人处理的批次（进程重启/崩溃后剩余待处理行继续分发）
+func (s *PayoutBatchService) RunResume() {
+	ticker := time.NewTicker(config.C.Batch.ResumeInterval)
+	defer ticker.Stop()
+	log.Printf("[PAYOUT-BATCH] 批次恢复扫描已启动 间隔=%v", config.C.Batch.ResumeInterval)
+	s.resumeOnce()
+	for {
+		select {
+		case <-lifecycle.Stopping():
+			log.Printf("[PAYOUT-BATCH] 批次恢复扫描已停止")
+			return
+		case <-ticker.C:
+			s.resumeOnce()
+		}
+	}
+}
+
+func (s *PayoutBatchService) resumeOnce() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] payout batch resume panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	ok, err := dal.RedisClient.SetNX(dal.RedisCtx, payoutBatchResumeKey, 1, config.C.Batch.ResumeInterval).Result()
+	if err != nil || !ok {
+		return
+	}
+	list, err := s.batchDao.ListProcessingBatches(time.Now().Add(-payoutBatchResumeAfter), 100)
+	if err != nil {
+		log.Printf("[PAYOUT-BATCH] 查询处理中批次失败: %v", err)
+		return
+	}
+	for i := range list {
+		batch := list[i]
+		leased, _ := dal.RedisClient.Exists(dal.RedisCtx, payoutBatchLeasePrefix+strconv.FormatUint(batch.BatchID, 10)).Result()
+		if leased > 0 {
+			continue
+		}
+		merchant, err := s.mainDao.GetMerchantId(strconv.FormatUint(batch.MID, 10))
+		if err != nil || merchant == nil {
+			log.Printf("[PAYOUT-BATCH] 恢复批次查询商户失败 batch=%d err=%v", batch.BatchID, err)
+			continue
+		}
+		items, err := s.batchDao.ListItems(batch.BatchID)
+		if err != nil {
+			log.Printf("[PAYOUT-BATCH] 恢复批次查询行失败 batch=%d err=%v", batch.BatchID, err)
+			continue
+		}
+		log.Printf("[PAYOUT-BATCH] ♻️ 恢复处理中批次 批次号=%s 平台批次=%d", batch.BatchNo, batch.BatchID)
+		lifecycle.Go("payout-batch-process", func() { s.process(&batch, merchant, batch.PayType, items, true) })
+	}
+}
+
+// notifyMerchant 批次完成回调商户
+func (s *PayoutBatchService) notifyMerchant(batch *ordermodel.PayoutBatchM, merchant *mainmodel.Merchant) {
+	if batch.NotifyURL == "" {
+		return
+	}
+	payload := dto.PayoutBatchNotifyPayload{
+		MerchantNo:     merchant.AppId,
+		BatchNo:        batch.BatchNo,
+		BatchSerial:    strconv.FormatUint(batch.BatchID, 10),
+		Status:         "COMPLETED",
+		TotalCount:     batch.TotalCount,
+		SubmittedCount: batch.SubmittedCount,
+		FailCount:      batch.FailCount,
+		TotalAmount:    batch.TotalAmount.String(),
+	}
+	payload.Sign = utils.GenerateSign(map[string]string{
+		"merchant_no":     payload.MerchantNo,
+		"batch_no":        payload.BatchNo,
+		"batch_serial":    payload.BatchSerial,
+		"status":          payload.Status,
+		"total_count":     strconv.Itoa(payload.TotalCount),
+		"submitted_count": strconv.Itoa(payload.SubmittedCount),
+		"fail_count":      strconv.Itoa(payload.FailCount),
+		"total_amount":    payload.TotalAmount,
+	}, merchant.ApiKey)
+
+	var lastErr error
+	for i := 1; i <= payoutBatchNotifyMaxRetry; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+		var respStr string
+		respStr, lastErr = utils.HttpPostJsonWithContext(ctx, batch.NotifyURL, payload)
+		cancel()
+		if lastErr == nil {
+			respStr = strings.ToLower(strings.TrimSpace(respStr))
+			if respStr == "ok" || respStr == "success" {
+				metrics.MerchantNotified("payout_batch", nil)
+				_ = s.batchDao.UpdateBatch(batch.BatchID, map[string]interface{}{"notify_status": 1})
+				log.Printf("[PAYOUT-BATCH] ✅ 批次回调商户成功 批次号=%s 通知次数=%d", batch.BatchNo, i)
+				return
+			}
+			lastErr = fmt.Errorf("invalid merchant response: %s", respStr)
+		}
+		metrics.MerchantNotified("payout_batch", lastErr)
+		log.Printf("[PAYOUT-BATCH] 批次回调商户失败 批次号=%s (通知次数: %d/%d) err=%v", batch.BatchNo, i, payoutBatchNotifyMaxRetry, lastErr)
+		time.Sleep(time.Duration(i*2) * time.Second)
+	}
+
+	_ = s.batchDao.UpdateBatch(batch.BatchID, map[string]interface{}{"notify_status": 2})
+	notify.Notify(system.BotChatID, "warn", "批量代付回调",
+		fmt.Sprintf("[批量代付回调]失败通知商户\n商户号: %v\n商户名称: %v\n批次号: %v\n平台批次: %d\n错误: %v",
+			merchant.AppId, merchant.NickName, batch.BatchNo, batch.BatchID, lastErr), true)
+}
+
+// Get 查询批次状态与单行结果
+func (s *PayoutBatchService) Get(req dto.QueryPayoutBatchReq) (dto.QueryPayoutBatchResp, error) {
+	merchant, err := s.payoutSvc.getMerchantWithCache(req.MerchantNo)
+	if err != nil || merchant == nil {
+		return dto.QueryPayoutBatchResp{}, fmt.Errorf("merchant invalid: %w", err)
+	}
+	batch, err := s.batchDao.GetByBatchNo(merchant.MerchantID, req.BatchNo)
+	if err != nil {
+		return dto.QueryPayoutBatchResp{}, err
+	}
+	if batch == nil {
+		return dto.QueryPayoutBatchResp{}, &BatchError{Code: constant.CodeBatchNotFound, Msg: "batch not found"}
+	}
+	items, err := s.batchDao.ListItems(batch.BatchID)
+	if err != nil {
+		return dto.QueryPayoutBatchResp{}, err
+	}
+
+	resp := dto.QueryPayoutBatchResp{
+		Code:           "0",
+		Msg:            "success",
+		BatchNo:        batch.BatchNo,
+		BatchSerial:    strconv.FormatUint(batch.BatchID, 10),
+		Status:         "PROCESSING",
+		TotalCount:     batch.TotalCount,
+		SubmittedCount: batch.SubmittedCount,
+		FailCount:      batch.FailCount,
+		TotalAmount:    batch.TotalAmount.String(),
+		Items:          make([]dto.PayoutBatchItemResp, 0, len(items)),
+	}
+	if batch.Status == ordermodel.PayoutBatchStatusCompleted {
+		resp.Status = "COMPLETED"
+	}
+
+	for _, it := range items {
+		r := dto.PayoutBatchItemResp{
+			LineNo:   it.LineNo,
+			TranFlow: it.TranFlow,
+			Amount:   it.Amount.String(),
+			Status:   "0001",
+			Msg:      it.ErrorMsg,
+		}
+		switch it.Status {
+		case ordermodel.PayoutBatchItemFailed:
+			r.Status = "0005"
+		case ordermodel.PayoutBatchItemSubmitted, ordermodel.PayoutBatchItemManual:
+			r.PaySerialNo = strconv.FormatUint(it.OrderID, 10)
+			orderTable := shard.OutOrderShard.GetTable(it.OrderID, batch.CreateTime)
+			if order, oErr := s.payoutSvc.orderDao.GetByOrderId(orderTable, it.OrderID); oErr == nil && order != nil {
+				if st := utils.ConvertOrderStatus(order.Status); st != "" {
+					r.Status = st
+				}
+			}
+		}
+		resp.Items = append(resp.Items, r)
+	}
+	return resp, nil
+}
+
+// ParsePayoutBatchFile 解析批量文件（csv 首行为表头；json 为数组）
+func ParsePayoutBatchFile(fileType string, content []byte) ([]dto.PayoutBatchRow, error) {
+	switch strings.ToLower(fileType) {
+	case "json":
+		var rows []dto.PayoutBatchRow
+		if err := json.Unmarshal(content, &rows); err != nil {
+			return nil, fmt.Errorf("invalid json batch file: %w", err)
+		}
+		for i := range rows {
+			rows[i].LineNo = i + 1
+		}
+		return rows, nil
+	case "csv":
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+		reader.TrimLeadingSpace = true
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv header: %w", err)
+		}
+		index := make(map[string]int, len(header))
+		for i, h := range header {
+			index[strings.ToLower(strings.TrimSpace(h))] = i
+		}
+		for _, col := range []string{"tran_flow", "amount", "acc_no", "acc_name", "pay_method"} {
+			if _, ok := index[col]; !ok {
+				return nil, fmt.Errorf("csv header missing column: %s", col)
+			}
+		}
+		get := func(rec []string, col string) string {
+			if i, ok := index[col]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		var rows []dto.PayoutBatchRow
+		for line := 1; ; line++ {
+			rec, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid csv line %d: %w", line, err)
+			}
+			rows = append(rows, dto.PayoutBatchRow{
+				LineNo:       line,
+				TranFlow:     get(rec, "tran_flow"),
+				Amount:       get(rec, "amount"),
+				AccNo:        get(rec, "acc_no"),
+				AccName:      get(rec, "acc_name"),
+				PayMethod:    get(rec, "pay_method"),
+				BankCode:     get(rec, "bank_code"),
+				BankName:     get(rec, "bank_name"),
+				BranchBank:   get(rec, "branch_bank"),
+				PayEmail:     get(rec, "pay_email"),
+				PayPhone:     get(rec, "pay_phone"),
+				IdentityType: get(rec, "identity_type"),
+				IdentityNum:  get(rec, "identity_num"),
+				AccountType:  get(rec, "account_type"),
+				CciNo:        get(rec, "cci_no"),
+				Address:      get(rec, "address"),
+				Network:      get(rec, "network"),
+			})
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("unsupported file_type: %s", fileType)
+	}
+}
@@ -0,0 +1,452 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
+	"wht-order-api/internal/lifecycle"
+	"wht-order-api/internal/metrics"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/system"
+	"wht-order-api/internal/utils"
+)
+
+const (
+	payoutHoldQueuesKey    = "payout_hold_queues"      // 所有排队队列（SET）
+	payoutHoldQueuePrefix  = "payout_hold:"            // 排队队列 payout_hold:{通道编码}:{币种}（ZSET，score=入队时间）
+	payoutHoldDataPrefix   = "payout_hold_data:"       // 排队订单数据 payout_hold_data:{订单号}
+	payoutHoldDrainLockKey = "payout_hold_drain_lock:" // 队列出队锁
+	payoutHoldInflightKey  = "payout_hold_inflight:"   // 处理中订单 payout_hold_inflight:{排队队列}（ZSET，score=出队时间）
+	payoutHoldVisibility   = 10 * time.Minute          // 出队后超过该时长未处理完（进程崩溃）重新放回队列
+	payoutHoldNotifyMax    = 3
+	// 订单状态：失败
+	payoutStatusFail int8 = 3
+)
+
+// payoutHoldEntry 排队订单
+type payoutHoldEntry struct {
+	OrderID   uint64                   `json:"orderId"`
+	MID       uint64                   `json:"mId"`
+	PayType   string                   `json:"payType"`
+	Currency  string                   `json:"currency"`
+	Amount    string                   `json:"amount"`
+	OrderTime time.Time                `json:"orderTime"` // 订单创建时间（用于定位分表）
+	EnqueueAt time.Time                `json:"enqueueAt"` // 首次入队时间（重新入队不变）
+	Request   dto.CreatePayoutOrderReq `json:"request"`
+}
+
+func payoutHoldQueueKey(payType, currency string) string {
+	return payoutHoldQueuePrefix + payType + ":" + strings.ToUpper(currency)
+}
+
+// payoutHoldClaimScript 原子出队：从排队队列移入处理中集合，返回 1 表示抢占成功
+var payoutHoldClaimScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then
+	redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// parkPayout 所有上游均余额不足时订单进入排队，资金保持冻结
+func parkPayout(orderDao dao.PayoutOrderRepository, req *dto.CreatePayoutOrderReq, order *ordermodel.MerchantPayOutOrderM, orderTime time.Time) error {
+	oid := strconv.FormatUint(order.OrderID, 10)
+	dataKey := payoutHoldDataPrefix + oid
+	queueKey := payoutHoldQueueKey(req.PayType, order.Currency)
+
+	entry := payoutHoldEntry{
+		OrderID:   order.OrderID,
+		MID:       order.MID,
+		PayType:   req.PayType,
+		Currency:  order.Currency,
+		Amount:    req.Amount,
+		OrderTime: orderTime,
+		EnqueueAt: time.Now(),
+		Request:   *req,
+	}
+	// 出队后重新入队保持原入队时间，保证先进先出与最长等待时间
+	requeue := false
+	if cached, err := dal.RedisClient.Get(dal.RedisCtx, dataKey).Result(); err == nil && cached != "" {
+		var old payoutHoldEntry
+		if json.Unmarshal([]byte(cached), &old) == nil && !old.EnqueueAt.IsZero() {
+			entry.EnqueueAt = old.EnqueueAt
+			requeue = true
+		}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal hold entry failed: %w", err)
+	}
+
+	ttl := config.C.Holding.MaxWait + time.Hour
+	pipe := dal.RedisClient.TxPipeline()
+	pipe.Set(dal.RedisCtx, dataKey, data, ttl)
+	pipe.ZAdd(dal.RedisCtx, queueKey, &redis.Z{Score: float64(entry.EnqueueAt.Unix()), Member: oid})
+	pipe.SAdd(dal.RedisCtx, payoutHoldQueuesKey, queueKey)
+	if _, err := pipe.Exec(dal.RedisCtx); err != nil {
+		return fmt.Errorf("enqueue hold payout failed: %w", err)
+	}
+
+	orderTable := shard.OutOrderShard.GetTable(order.OrderID, orderTime)
+	if err := orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+		"remark":      "上游余额不足, 排队等待上游补款",
+		"update_time": time.Now(),
+	}); err != nil {
+		log.Printf("[WARN] 更新订单备注失败 order=%d err=%v", order.OrderID, err)
+	}
+
+	log.Printf("[PAYOUT-HOLD] ⏳ 上游余额不足，订单进入排队 order=%d 队列=%s 入队时间=%s",
+		order.OrderID, queueKey, entry.EnqueueAt.Format(time.DateTime))
+	if !requeue {
+		notify.Notify(system.BotChatID, "warn", "代付上游余额不足排队",
+			fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n通道编码: `%s`\n金额: `%s %s`\n最长等待: `%v`\n\n资金已冻结，上游补款后自动提交。",
+				order.OrderID, req.TranFlow, req.PayType, req.Amount, order.Currency, config.C.Holding.MaxWait), true)
+	}
+	return nil
+}
+
+// PayoutHoldingService 上游余额不足代付排队
+// 定时轮询（或上游补款通知触发）按队列先进先出重新提交上游，超过最长等待时间则失败解冻。
+type PayoutHoldingService struct {
+	payoutSvc *PayoutOrderService
+	mainDao   dao.MainRepository
+	orderDao  dao.PayoutOrderRepository
+}
+
+func NewPayoutHoldingService(pub event.Publisher) *PayoutHoldingService {
+	return newPayoutHoldingService(NewPayoutOrderService(pub))
+}
+
+// NewPayoutHoldingServiceWithRepos 注入仓储（单元测试使用内存实现）
+func NewPayoutHoldingServiceWithRepos(pub event.Publisher, mainRepo dao.MainRepository, orderRepo dao.PayoutOrderRepository, indexRepo dao.IndexTableRepository) *PayoutHoldingService {
+	return newPayoutHoldingService(NewPayoutOrderServiceWithRepos(pub, mainRepo, orderRepo, indexRepo))
+}
+
+func newPayoutHoldingService(payoutSvc *PayoutOrderService) *PayoutHoldingService {
+	return &PayoutHoldingService{
+		payoutSvc: payoutSvc,
+		mainDao:   payoutSvc.mainDao,
+		orderDao:  payoutSvc.orderDao,
+	}
+}
+
+// Run 定时出队
+func (s *PayoutHoldingService) Run() {
+	if !config.C.Holding.Enabled {
+		return
+	}
+	ticker := time.NewTicker(config.C.Holding.PollInterval)
+	defer ticker.Stop()
+	log.Printf("[PAYOUT-HOLD] 排队出队已启动 间隔=%v 最长等待=%v", config.C.Holding.PollInterval, config.C.Holding.MaxWait)
+	for {
+		select {
+		case <-lifecycle.Stopping():
+			log.Printf("[PAYOUT-HOLD] 排队出队已停止")
+			return
+		case <-ticker.C:
+			s.Drain("", "")
+		}
+	}
+}
+
+// Drain 出队指定通道/币种（为空表示全部），返回各队列剩余数量
+func (s *PayoutHoldingService) Drain(payType, currency string) map[string]int64 {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] payout hold drain panic: %v\n%s", r, debug.Stack())
+			notify.Notify(system.BotChatID, "error", "代付排队出队Panic", fmt.Sprintf("panic: %v", r), true)
+		}
+	}()
+
+	queues, err := dal.RedisClient.SMembers(dal.RedisCtx, payoutHoldQueuesKey).Result()
+	if err != nil {
+		log.Printf("[PAYOUT-HOLD] 读取排队队列失败: %v", err)
+		return nil
+	}
+	remain := make(map[string]int64, len(queues))
+	for _, q := range queues {
+		if payType != "" || currency != "" {
+			parts := strings.SplitN(strings.TrimPrefix(q, payoutHoldQueuePrefix), ":", 2)
+			if len(parts) != 2 ||
+				(payType != "" && parts[0] != payType) ||
+				(currency != "" && !strings.EqualFold(parts[1], currency)) {
+				continue
+			}
+		}
+		s.drainQueue(q)
+		n, _ := dal.RedisClient.ZCard(dal.RedisCtx, q).Result()
+		remain[strings.TrimPrefix(q, payoutHoldQueuePrefix)] = n
+	}
+	return remain
+}
+
+// drainQueue 按先进先出出队，遇到仍余额不足的订单即停止（队头阻塞，避免重复查询余额）
+func (s *PayoutHoldingService) drainQueue(queueKey string) {
+	lockKey := payoutHoldDrainLockKey + queueKey
+	ok, err := dal.RedisClient.SetNX(dal.RedisCtx, lockKey, 1, config.C.Holding.PollInterval).Result()
+	if err != nil || !ok {
+		return
+	}
+	defer dal.RedisClient.Del(dal.RedisCtx, lockKey)
+
+	s.restoreInflight(queueKey)
+
+	members, err := dal.RedisClient.ZRangeWithScores(dal.RedisCtx, queueKey, 0, int64(config.C.Holding.DrainBatch-1)).Result()
+	if err != nil {
+		log.Printf("[PAYOUT-HOLD] 读取队列失败 queue=%s err=%v", queueKey, err)
+		return
+	}
+	if len(members) == 0 {
+		if n, _ := dal.RedisClient.ZCard(dal.RedisCtx, payoutHoldInflightKey+queueKey).Result(); n == 0 {
+			dal.RedisClient.SRem(dal.RedisCtx, payoutHoldQueuesKey, queueKey)
+		}
+		return
+	}
+
+	inflightKey := payoutHoldInflightKey + queueKey
+	for _, m := range members {
+		oid, _ := m.Member.(string)
+		// 出队抢占：原子移入处理中集合，处理完成（提交、重新入队或移出）后才删除，进程中断不丢单
+		claimed, err := payoutHoldClaimScript.Run(dal.RedisCtx, dal.RedisClient,
+			[]string{queueKey, inflightKey}, oid, time.Now().Unix()).Int()
+		if err != nil {
+			log.Printf("[PAYOUT-HOLD] 出队失败 queue=%s order=%s err=%v", queueKey, oid, err)
+			return
+		}
+		if claimed != 1 {
+			continue
+		}
+		stillLow := s.process(queueKey, oid)
+		dal.RedisClient.ZRem(dal.RedisCtx, inflightKey, oid)
+		if stillLow {
+			break
+		}
+	}
+}
+
+// restoreInflight 处理中超时（出队进程已退出）的订单按原入队时间放回队列
+func (s *PayoutHoldingService) restoreInflight(queueKey string) {
+	inflightKey := payoutHoldInflightKey + queueKey
+	stale, err := dal.RedisClient.ZRangeByScore(dal.RedisCtx, inflightKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Add(-payoutHoldVisibility).Unix(), 10),
+	}).Result()
+	if err != nil {
+		log.Printf("[PAYOUT-HOLD] 读取处理中订单失败 queue=%s err=%v", queueKey, err)
+		return
+	}
+	for _, oid := range stale {
+		score := float64(time.Now().Unix())
+		if cached, err := dal.RedisClient.Get(dal.RedisCtx, payoutHoldDataPrefix+oid).Result(); err == nil {
+			var entry payoutHoldEntry
+			if json.Unmarshal([]byte(cached), &entry) == nil && !entry.EnqueueAt.IsZero() {
+				score = float64(entry.EnqueueAt.Unix())
+			}
+		}
+		pipe := dal.RedisClient.TxPipeline()
+		pipe.ZAdd(dal.RedisCtx, queueKey, &redis.Z{Score: score, Member: oid})
+		pipe.ZRem(dal.RedisCtx, inflightKey, oid)
+		if _, err := pipe.Exec(dal.RedisCtx); err != nil {
+			log.Printf("[PAYOUT-HOLD] 处理中订单放回队列失败 order=%s err=%v", oid, err)
+			continue
+		}
+		log.Printf("[PAYOUT-HOLD] ♻️ 处理中超时订单已放回队列 order=%s queue=%s", oid, queueKey)
+	}
+}
+
+// process 处理单笔出队订单，返回上游是否仍余额不足（已重新入队）
+func (s *PayoutHoldingService) process(queueKey, oid string) (stillLow bool) {
+	dataKey := payoutHoldDataPrefix + oid
+	cached, err := dal.RedisClient.Get(dal.RedisCtx, dataKey).Result()
+	if err != nil || cached == "" {
+		log.Printf("[PAYOUT-HOLD] 排队订单数据缺失 order=%s err=%v", oid, err)
+		notify.Notify(system.BotChatID, "error", "代付排队数据缺失",
+			fmt.Sprintf("平台订单号: `%s`\n队列: `%s`\n\n资金仍处于冻结状态，请人工处理。", oid, queueKey), true)
+		return false
+	}
+	var entry payoutHoldEntry
+	if err := json.Unmarshal([]byte(cached), &entry); err != nil {
+		log.Printf("[PAYOUT-HOLD] 排队订单数据解析失败 order=%s err=%v", oid, err)
+		return false
+	}
+
+	orderTable := shard.OutOrderShard.GetTable(entry.OrderID, entry.OrderTime)
+	order, err := s.orderDao.GetByOrderId(orderTable, entry.OrderID)
+	if err != nil {
+		log.Printf("[PAYOUT-HOLD] 查询排队订单失败，继续排队 order=%s err=%v", oid, err)
+		s.requeue(queueKey, oid, entry)
+		return true
+	}
+	if order == nil || order.Status != 1 {
+		// 订单已被人工处理或状态变化，移出队列
+		log.Printf("[PAYOUT-HOLD] 排队订单状态已变化，移出队列 order=%s", oid)
+		dal.RedisClient.Del(dal.RedisCtx, dataKey)
+		return false
+	}
+	merchant, err := s.mainDao.GetMerchantId(strconv.FormatUint(order.MID, 10))
+	if err != nil || merchant == nil {
+		s.requeue(queueKey, oid, entry)
+		return true
+	}
+
+	// 超过最长等待时间：失败解冻
+	if time.Since(entry.EnqueueAt) > config.C.Holding.MaxWait {
+		s.expire(entry, order, merchant, orderTable)
+		return false
+	}
+
+	products, pErr := s.payoutSvc.selectPayoutProducts(merchant, entry.PayType, entry.Currency, order.Amount)
+	if pErr != nil {
+		log.Printf("[PAYOUT-HOLD] 暂无可用通道，继续排队 order=%s err=%v", oid, pErr)
+		s.requeue(queueKey, oid, entry)
+		return true
+	}
+	if order.UpOrderID == nil {
+		log.Printf("[PAYOUT-HOLD] 订单缺少上游交易，移出队列 order=%s", oid)
+		dal.RedisClient.Del(dal.RedisCtx, dataKey)
+		return false
+	}
+	txTable := shard.UpOutOrderShard.GetTable(*order.UpOrderID, entry.OrderTime)
+	tx, tErr := s.orderDao.GetTxByUpOrderId(txTable, *order.UpOrderID)
+	if tErr != nil || tx == nil {
+		log.Printf("[PAYOUT-HOLD] 上游交易不存在，继续排队 order=%s err=%v", oid, tErr)
+		s.requeue(queueKey, oid, entry)
+		return true
+	}
+
+	req := entry.Request
+	parked, sErr := s.payoutSvc.submitToUpstreamsWithHold(merchant, &req, products, order, tx, order.Amount, entry.OrderTime)
+	if parked {
+		return true
+	}
+	// 提交成功或已转人工，清理排队数据
+	dal.RedisClient.Del(dal.RedisCtx, dataKey)
+	if sErr == nil {
+		log.Printf("[PAYOUT-HOLD] ✅ 排队订单已提交上游 order=%s 排队时长=%v", oid, time.Since(entry.EnqueueAt).Truncate(time.Second))
+	}
+	return false
+}
+
+func (s *PayoutHoldingService) requeue(queueKey, oid string, entry payoutHoldEntry) {
+	if err := dal.RedisClient.ZAdd(dal.RedisCtx, queueKey, &redis.Z{Score: float64(entry.EnqueueAt.Unix()), Member: oid}).Err(); err != nil {
+		log.Printf("[PAYOUT-HOLD] 重新入队失败 order=%s err=%v", oid, err)
+	}
+}
+
+// expire 排队超时：失败解冻并通知商户
+func (s *PayoutHoldingService) expire(entry payoutHoldEntry, order *ordermodel.MerchantPayOutOrderM, merchant *mainmodel.Merchant, orderTable string) {
+	oid := strconv.FormatUint(order.OrderID, 10)
+	settle := order.SettleSnapshot
+	if err := s.mainDao.HandlePayoutCallback(
+		merchant.MerchantID,
+		order.Currency,
+		oid,
+		order.MOrderID,
+		settle.MerchantTotalFee,
+		settle.AgentTotalFee,
+		false,
+		order.Amount,
+		merchant.NickName,
+	); err != nil {
+		// 解冻失败转人工，避免重复解冻
+		_ = s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+			"status":      6, // 人工处理
+			"remark":      truncateReason(fmt.Sprintf("排队超时解冻失败, 等待人工介入: %v", err)),
+			"update_time": time.Now(),
+		})
+		dal.RedisClient.Del(dal.RedisCtx, payoutHoldDataPrefix+oid)
+		notify.Notify(system.BotChatID, "error", "代付排队超时解冻失败",
+			fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n金额: `%s`\n错误: `%v`\n\n当前资金已冻结，请人工处理。",
+				order.OrderID, order.MOrderID, order.Amount.String(), err), true)
+		return
+	}
+
+	now := time.Now()
+	if err := s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+		"status":      payoutStatusFail,
+		"remark":      fmt.Sprintf("上游余额不足, 排队超时(%v)自动失败", config.C.Holding.MaxWait),
+		"finish_time": now,
+		"update_time": now,
+	}); err != nil {
+		log.Printf("[WARN] 更新订单状态失败 order=%d err=%v", order.OrderID, err)
+	}
+	dal.RedisClient.Del(dal.RedisCtx, payoutHoldDataPrefix+oid)
+	s.payoutSvc.limitSvc.ReleaseOrder(order.OrderID)
+
+	log.Printf("[PAYOUT-HOLD] ❌ 排队超时自动失败 order=%d 排队时长=%v", order.OrderID, time.Since(entry.EnqueueAt).Truncate(time.Second))
+	notify.Notify(system.BotChatID, "warn", "代付排队超时",
+		fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n通道编码: `%s`\n金额: `%s %s`\n\n上游余额长时间不足，订单已失败，冻结资金已退回商户余额。",
+			order.OrderID, order.MOrderID, entry.PayType, order.Amount.String(), order.Currency), true)
+
+	lifecycle.Go("payout-holding-notify", func() {
+		notifyPayoutMerchantFinal(s.orderDao, merchant, order, orderTable, payoutStatusFail, "Refunded 失败(并退款)")
+	})
+}
+
+// notifyPayoutMerchantFinal 平台侧终态（驳回/排队超时等）通知商户
+func notifyPayoutMerchantFinal(orderDao dao.PayoutOrderRepository, merchant *mainmodel.Merchant, order *ordermodel.MerchantPayOutOrderM, orderTable string, status int8, msg string) {
+	if order.NotifyURL == "" {
+		return
+	}
+	payload := dto.PayoutNotifyMerchantPayload{
+		TranFlow:    order.MOrderID,
+		PaySerialNo: strconv.FormatUint(order.OrderID, 10),
+		Status:      utils.ConvertOrderStatus(status),
+		Msg:         msg,
+		MerchantNo:  merchant.AppId,
+		Amount:      order.Amount.String(),
+	}
+	payload.Sign = utils.GenerateSign(map[string]string{
+		"status":        payload.Status,
+		"msg":           payload.Msg,
+		"tran_flow":     payload.TranFlow,
+		"pay_serial_no": payload.PaySerialNo,
+		"amount":        payload.Amount,
+		"merchant_no":   payload.MerchantNo,
+	}, merchant.ApiKey)
+
+	var lastErr error
+	for i := 1; i <= payoutHoldNotifyMax; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+		var respStr string
+		respStr, lastErr = utils.HttpPostJsonWithContext(ctx, order.NotifyURL, payload)
+		cancel()
+		if lastErr == nil {
+			respStr = strings.ToLower(strings.TrimSpace(respStr))
+			if respStr == "ok" || respStr == "success" {
+				metrics.MerchantNotified("payout", nil)
+				_ = orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+					"notify_status": 1,
+					"notify_time":   time.Now(),
+				})
+				return
+			}
+			lastErr = fmt.Errorf("invalid merchant response: %s", respStr)
+		}
+		metrics.MerchantNotified("payout", lastErr)
+		log.Printf("[代付通知] 通知商户失败 order=%d status=%s (通知次数: %d/%d) err=%v", order.OrderID, payload.Status, i, payoutHoldNotifyMax, lastErr)
+		time.Sleep(time.Duration(i*2) * time.Second)
+	}
+	_ = orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+		"notify_status": 2,
+		"notify_time":   time.Now(),
+	})
+	notify.Notify(system.BotChatID, "warn", "代付回调",
+		fmt.Sprintf("[代付通知]失败通知商户信息\n商户号: %v\n商户名称: %v\n平台订单号: %v\n商户订单号: %v\n状态: %v\n错误: %v",
+			merchant.AppId, merchant.NickName, order.OrderID, order.MOrderID, payload.Status, lastErr), true)
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/idgen"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/utils"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/shopspring/decimal"
+)
+
+const holdingInterfaceCode = "test_holding_flow"
+
+// holdingConnector 上游余额可调的代付连接器，记录提交顺序
+type holdingConnector struct {
+	fakePayoutConnector
+	mu        sync.Mutex
+	balance   decimal.Decimal
+	submitted []string
+}
+
+func (c *holdingConnector) Balance(ctx context.Context, req dto.UpstreamRequest) (decimal.Decimal, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.balance, nil
+}
+
+func (c *holdingConnector) CreatePayout(ctx context.Context, req dto.UpstreamRequest) (*connector.OrderResult, error) {
+	c.mu.Lock()
+	c.submitted = append(c.submitted, req.MchOrderId)
+	c.mu.Unlock()
+	return c.fakePayoutConnector.CreatePayout(ctx, req)
+}
+
+func (c *holdingConnector) topUp(v int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.balance = decimal.NewFromInt(v)
+}
+
+type holdingFixture struct {
+	srv      *fakeredis.Server
+	main     *memdao.MainStore
+	orders   *memdao.PayoutOrderStore
+	conn     *holdingConnector
+	svc      *PayoutHoldingService
+	released []string // 累计限额回滚的预占金额
+	mu       sync.Mutex
+}
+
+// newHoldingFixture 商户 1001（无代理）开通 BR_PIX_OUT（费率 2%），余额 5000 BRL；上游余额为 0
+func newHoldingFixture(t *testing.T) *holdingFixture {
+	t.Helper()
+	f := &holdingFixture{
+		srv:    fakeredis.Use(t),
+		main:   memdao.NewMainStore(),
+		orders: memdao.NewPayoutOrderStore(),
+		conn:   &holdingConnector{},
+	}
+	shard.InitShardEngines()
+	if err := idgen.InitNode("default", 1); err != nil {
+		t.Fatalf("init idgen: %v", err)
+	}
+	prevTimeout, prevHolding, prevReassign, prevApproval := config.C.Upstream.Timeout.Payout, config.C.Holding, config.C.Reassign.Enabled, config.C.Approval.Enabled
+	config.C.Upstream.Timeout.Payout = 5 * time.Second
+	config.C.Holding = config.HoldingCfg{Enabled: true, MaxWait: 30 * time.Minute, PollInterval: time.Minute, DrainBatch: 10}
+	config.C.Reassign.Enabled, config.C.Approval.Enabled = false, false
+	t.Cleanup(func() {
+		config.C.Upstream.Timeout.Payout, config.C.Holding, config.C.Reassign.Enabled, config.C.Approval.Enabled = prevTimeout, prevHolding, prevReassign, prevApproval
+	})
+
+	// 出队抢占与限额回滚脚本的 Go 替身（与 Lua 语义一致）
+	f.srv.HandleScript(payoutHoldClaimScript.Hash(), func(call func(args ...string) interface{}, keys, argv []string) interface{} {
+		if n, _ := call("ZREM", keys[0], argv[0]).(int64); n == 1 {
+			call("ZADD", keys[1], argv[1], argv[0])
+			return int64(1)
+		}
+		return int64(0)
+	})
+	f.srv.HandleScript(limitReleaseScript.Hash(), func(call func(args ...string) interface{}, keys, argv []string) interface{} {
+		f.mu.Lock()
+		f.released = append(f.released, argv[0])
+		f.mu.Unlock()
+		return int64(0)
+	})
+
+	connector.Register(holdingInterfaceCode, f.conn)
+	f.main.AddMerchant(mainmodel.Merchant{MerchantID: testMerchantID, NickName: "m1", AppId: "APP1001", ApiKey: "secret", Status: 1, Currency: "BRL"})
+	f.main.AddCountry(dto.CurrencyCodeResponse{ID: 76, Code: "BRL", Country: "Brazil"})
+	f.main.AddSysChannel(dto.PayWayVo{Id: 8, Title: "PIX 代付", Currency: "BRL", Coding: reassignChannelCode, Type: 2, Status: 1})
+	f.main.AddMerchantChannel(dto.MerchantChannelDTO{
+		MId: testMerchantID, SysChannelId: 8, Status: 1, Type: 2, DispatchMode: 1, Currency: "BRL",
+		SysChannelCode: reassignChannelCode, DefaultRate: decimal.NewFromInt(2),
+	})
+	minAmount, maxAmount := decimal.NewFromInt(10), decimal.NewFromInt(5000)
+	f.main.AddPayProduct(testMerchantID, dto.PayProductVo{
+		ID: 61, Currency: "BRL", Type: 2, Status: 1,
+		UpstreamId: 701, UpstreamCode: "UP_701", UpstreamTitle: "up", UpstreamWeight: 10, InterfaceCode: holdingInterfaceCode,
+		SysChannelID: 8, SysChannelCode: reassignChannelCode, SysChannelTitle: "PIX 代付",
+		MDefaultRate: decimal.NewFromInt(2), CostRate: decimal.NewFromInt(1),
+		MinAmount: &minAmount, MaxAmount: &maxAmount,
+	})
+	f.main.SetAccount(testMerchantID, "BRL", decimal.NewFromInt(5000), decimal.Zero)
+
+	f.svc = NewPayoutHoldingServiceWithRepos(nopPublisher{}, f.main, f.orders, f.orders.Index)
+	t.Cleanup(f.svc.payoutSvc.Shutdown)
+	return f
+}
+
+// park 上游余额不足下单，订单进入排队并返回订单号
+func (f *holdingFixture) park(t *testing.T, tranFlow string) uint64 {
+	t.Helper()
+	resp, err := f.svc.payoutSvc.Create(dto.CreatePayoutOrderReq{
+		MerchantNo: "APP1001", TranFlow: tranFlow, Amount: "1000", PayType: reassignChannelCode,
+		AccNo: "12345678", AccName: "Joao", PayMethod: "PIX",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	waitLifecycle(t)
+	orderID, _ := strconv.ParseUint(resp.PaySerialNo, 10, 64)
+	if _, ok := f.queueScore(orderID); !ok {
+		t.Fatalf("order %d not parked", orderID)
+	}
+	return orderID
+}
+
+func (f *holdingFixture) queueKey() string {
+	return payoutHoldQueueKey(reassignChannelCode, "BRL")
+}
+
+func (f *holdingFixture) queueScore(orderID uint64) (float64, bool) {
+	members, _ := dal.RedisClient.ZRangeWithScores(dal.RedisCtx, f.queueKey(), 0, -1).Result()
+	for _, m := range members {
+		if m.Member == strconv.FormatUint(orderID, 10) {
+			return m.Score, true
+		}
+	}
+	return 0, false
+}
+
+func (f *holdingFixture) entry(t *testing.T, orderID uint64) payoutHoldEntry {
+	t.Helper()
+	cached, ok := f.srv.Get(payoutHoldDataPrefix + strconv.FormatUint(orderID, 10))
+	if !ok {
+		t.Fatalf("hold data of %d missing", orderID)
+	}
+	var e payoutHoldEntry
+	if err := json.Unmarshal([]byte(cached), &e); err != nil {
+		t.Fatalf("decode hold data: %v", err)
+	}
+	return e
+}
+
+// backdate 模拟订单已排队 d，返回改写后的入队时间
+func (f *holdingFixture) backdate(t *testing.T, orderID uint64, d time.Duration) time.Time {
+	t.Helper()
+	e := f.entry(t, orderID)
+	e.EnqueueAt = time.Now().Add(-d).Truncate(time.Second)
+	oid := strconv.FormatUint(orderID, 10)
+	dal.RedisClient.Set(dal.RedisCtx, payoutHoldDataPrefix+oid, utils.MapToJSON(e), time.Hour)
+	dal.RedisClient.ZAdd(dal.RedisCtx, f.queueKey(), &redis.Z{Score: float64(e.EnqueueAt.Unix()), Member: oid})
+	return e.EnqueueAt
+}
+
+func (f *holdingFixture) order(t *testing.T, orderID uint64) *ordermodel.MerchantPayOutOrderM {
+	t.Helper()
+	o, err := f.orders.GetByOrderId(shard.OutOrderShard.GetTable(orderID, time.Now()), orderID)
+	if err != nil || o == nil {
+		t.Fatalf("load order: %v", err)
+	}
+	return o
+}
+
+func (f *holdingFixture) balance(t *testing.T) (money, freeze decimal.Decimal) {
+	t.Helper()
+	acc, ok := f.main.Account(testMerchantID, "BRL")
+	if !ok {
+		t.Fatal("account not found")
+	}
+	return acc.Money, acc.FreezeMoney
+}
+
+func TestHoldingParksAndRequeueKeepsEnqueueTime(t *testing.T) {
+	f := newHoldingFixture(t)
+	orderID := f.park(t, "P-H001")
+
+	// 资金保持冻结，订单仍为处理中
+	if o := f.order(t, orderID); o.Status != 1 {
+		t.Errorf("order status = %d, want 1", o.Status)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(3980)) || !freeze.Equal(decimal.NewFromInt(1020)) {
+		t.Errorf("balance = %s/%s, want 3980/1020", money, freeze)
+	}
+	if len(f.conn.submitted) != 0 {
+		t.Errorf("submitted = %v, want none", f.conn.submitted)
+	}
+
+	// 上游仍余额不足：出队后重新入队，入队时间不变
+	enqueueAt := f.backdate(t, orderID, 10*time.Minute)
+	remain := f.svc.Drain("", "")
+	waitLifecycle(t)
+	if remain[reassignChannelCode+":BRL"] != 1 {
+		t.Errorf("remain = %v, want 1", remain)
+	}
+	if score, ok := f.queueScore(orderID); !ok || int64(score) != enqueueAt.Unix() {
+		t.Errorf("queue score = %v (%v), want %d", score, ok, enqueueAt.Unix())
+	}
+	if e := f.entry(t, orderID); !e.EnqueueAt.Equal(enqueueAt) {
+		t.Errorf("enqueueAt = %s, want %s", e.EnqueueAt, enqueueAt)
+	}
+	if n, _ := dal.RedisClient.ZCard(dal.RedisCtx, payoutHoldInflightKey+f.queueKey()).Result(); n != 0 {
+		t.Errorf("inflight = %d, want 0", n)
+	}
+	if len(f.conn.submitted) != 0 {
+		t.Errorf("submitted while balance low: %v", f.conn.submitted)
+	}
+}
+
+func TestHoldingDrainSubmitsInOrderAfterTopUp(t *testing.T) {
+	f := newHoldingFixture(t)
+	first := f.park(t, "P-H002")
+	second := f.park(t, "P-H003")
+	f.backdate(t, second, 5*time.Minute)
+	f.backdate(t, first, 8*time.Minute)
+
+	f.conn.topUp(100000)
+	remain := f.svc.Drain(reassignChannelCode, "brl")
+	waitLifecycle(t)
+
+	if remain[reassignChannelCode+":BRL"] != 0 {
+		t.Errorf("remain = %v, want 0", remain)
+	}
+	if len(f.conn.submitted) != 2 {
+		t.Fatalf("submitted = %v, want 2 orders", f.conn.submitted)
+	}
+	// 先入队先提交
+	if o := f.order(t, first); o.UpOrderID == nil || f.conn.submitted[0] != strconv.FormatUint(*o.UpOrderID, 10) {
+		t.Errorf("submitted = %v, first order should go first", f.conn.submitted)
+	}
+	for _, id := range []uint64{first, second} {
+		if f.srv.Exists(payoutHoldDataPrefix + strconv.FormatUint(id, 10)) {
+			t.Errorf("hold data of %d not cleaned", id)
+		}
+	}
+	// 队列已空，下一轮移出队列集合
+	f.svc.Drain("", "")
+	if ok, _ := dal.RedisClient.SIsMember(dal.RedisCtx, payoutHoldQueuesKey, f.queueKey()).Result(); ok {
+		t.Error("empty queue still registered")
+	}
+}
+
+func TestHoldingExpiryUnfreezesAndReleasesLimit(t *testing.T) {
+	f := newHoldingFixture(t)
+	orderID := f.park(t, "P-H004")
+	oid := strconv.FormatUint(orderID, 10)
+	dal.RedisClient.Set(dal.RedisCtx, limitHoldPrefix+oid, utils.MapToJSON(limitHold{Keys: []string{"txn_limit:d:1001"}, Amount: "1000"}), time.Hour)
+	f.backdate(t, orderID, 31*time.Minute)
+
+	f.svc.Drain("", "")
+	waitLifecycle(t)
+
+	if o := f.order(t, orderID); o.Status != payoutStatusFail || o.FinishTime == nil {
+		t.Errorf("order = status %d, finishTime %v", o.Status, o.FinishTime)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(5000)) || !freeze.IsZero() {
+		t.Errorf("balance = %s/%s, want 5000/0", money, freeze)
+	}
+	if len(f.released) != 1 || f.released[0] != "1000" || f.srv.Exists(limitHoldPrefix+oid) {
+		t.Errorf("limit released = %v, hold kept = %v", f.released, f.srv.Exists(limitHoldPrefix+oid))
+	}
+	if _, ok := f.queueScore(orderID); ok || f.srv.Exists(payoutHoldDataPrefix+oid) {
+		t.Error("expired order still queued")
+	}
+	if len(f.conn.submitted) != 0 {
+		t.Errorf("submitted = %v, want none", f.conn.submitted)
+	}
+
+	// 再次出队不会重复解冻
+	f.svc.Drain("", "")
+	if money, _ := f.balance(t); !money.Equal(decimal.NewFromInt(5000)) {
+		t.Errorf("balance after second drain = %s", money)
+	}
+}
+
+func TestHoldingRestoresStaleInflight(t *testing.T) {
+	f := newHoldingFixture(t)
+	orderID := f.park(t, "P-H005")
+	oid := strconv.FormatUint(orderID, 10)
+	enqueueAt := f.backdate(t, orderID, 20*time.Minute)
+
+	// 模拟出队进程崩溃：订单停留在处理中集合且已超过可见时间
+	inflightKey := payoutHoldInflightKey + f.queueKey()
+	dal.RedisClient.ZRem(dal.RedisCtx, f.queueKey(), oid)
+	dal.RedisClient.ZAdd(dal.RedisCtx, inflightKey, &redis.Z{Score: float64(time.Now().Add(-payoutHoldVisibility - time.Minute).Unix()), Member: oid})
+
+	f.svc.Drain("", "")
+	waitLifecycle(t)
+	if score, ok := f.queueScore(orderID); !ok || int64(score) != enqueueAt.Unix() {
+		t.Errorf("queue score = %v (%v), want original enqueue %d", score, ok, enqueueAt.Unix())
+	}
+	if n, _ := dal.RedisClient.ZCard(dal.RedisCtx, inflightKey).Result(); n != 0 {
+		t.Errorf("inflight = %d, want 0", n)
+	}
+
+	// 未超时的处理中订单不放回（仍在被其他实例处理）
+	dal.RedisClient.ZRem(dal.RedisCtx, f.queueKey(), oid)
+	dal.RedisClient.ZAdd(dal.RedisCtx, inflightKey, &redis.Z{Score: float64(time.Now().Unix()), Member: oid})
+	f.svc.Drain("", "")
+	if _, ok := f.queueScore(orderID); ok {
+		t.Error("fresh inflight order was put back")
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/jinzhu/copier"
 	"github.com/shopspring/decimal"
 	"golang.org/x/sync/singleflight"
-	"gorm.io/gorm"
 	"log"
 	"math"
 	"runtime/debug"
@@ -18,12 +17,16 @@ import (
 	"time"
 	"wht-order-api/internal/channel/health"
 	"wht-order-api/internal/event"
+	"wht-order-api/internal/lifecycle"
+	"wht-order-api/internal/metrics"
 	mainmodel "wht-order-api/internal/model/main"
 	"wht-order-api/internal/notify"
+	"wht-order-api/internal/outbox"
 	"wht-order-api/internal/shard"
 	"wht-order-api/internal/system"
 	"wht-order-api/internal/utils"
 
+	"wht-order-api/internal/config"
 	"wht-order-api/internal/dal"
 	"wht-order-api/internal/dao"
 	"wht-order-api/internal/dto"
@@ -35,9 +38,10 @@ import (
 const payoutUpstreamFailKey = "payout_up_fail:"
 
 type PayoutOrderService struct {
-	mainDao         *dao.MainDao
-	orderDao        *dao.PayoutOrderDao
-	indexTableDao   *dao.IndexTableDao
+	mainDao         dao.MainRepository        // 主数据库
+	orderDao        dao.PayoutOrderRepository // 代付订单库
+	indexTableDao   dao.IndexTableRepository
+	commonSvc       *CommonService
 	merchantGroup   singleflight.Group
 	channelGroup    singleflight.Group
 	upstreamGroup   singleflight.Group
@@ -47,14 +51,28 @@ type PayoutOrderService struct {
 	lastHealthCheck time.Time
 	isHealthy       bool
 	pub             event.Publisher
+	feeSvc          *FeeScheduleService          // 阶梯费率方案
+	limitSvc        *LimitService                // 累计限额
+	approvalDao     dao.PayoutApprovalRepository // 代付复核（开启复核时使用）
 }
 
 func NewPayoutOrderService(pub event.Publisher) *PayoutOrderService {
+	// 默认全局 DB
+	service := NewPayoutOrderServiceWithRepos(pub, dao.NewMainDao(), dao.NewPayoutOrderDao(), dao.NewIndexTableDao())
+	service.approvalDao = dao.NewPayoutApprovalDao()
+	return service
+}
+
+// NewPayoutOrderServiceWithRepos 注入仓储（单元测试使用内存实现）
+func NewPayoutOrderServiceWithRepos(pub event.Publisher, mainRepo dao.MainRepository, orderRepo dao.PayoutOrderRepository, indexRepo dao.IndexTableRepository) *PayoutOrderService {
 	ctx, cancel := context.WithCancel(context.Background())
 	service := &PayoutOrderService{
-		mainDao:       dao.NewMainDao(),        // 使用工厂方法
-		orderDao:      dao.NewPayoutOrderDao(), // 使用工厂方法
-		indexTableDao: dao.NewIndexTableDao(),  // 使用工厂方法
+		mainDao:       mainRepo,
+		orderDao:      orderRepo,
+		indexTableDao: indexRepo,
+		commonSvc:     NewCommonServiceWithRepos(mainRepo, nil, indexRepo), // 代付不访问代收订单库
+		feeSvc:        NewFeeScheduleService(mainRepo),
+		limitSvc:      NewLimitService(mainRepo),
 		ctx:           ctx,
 		cancel:        cancel,
 		isHealthy:     false,
@@ -83,6 +101,7 @@ func (s *PayoutOrderService) recordUpstreamFail(upstreamID uint64, upstreamName,
 	if cnt == 1 {
 		dal.RedisClient.Expire(dal.RedisCtx, key, 5*time.Minute)
 	}
+	metrics.SetUpstreamFailCount("payout", upstreamCode, sysChannelCode, cnt)
 
 	// 警告通知
 	if cnt == 3 {
@@ -111,6 +130,7 @@ func (s *PayoutOrderService) recordUpstreamFail(upstreamID uint64, upstreamName,
 func (s *PayoutOrderService) clearUpstreamFail(upstreamID uint64, upstreamCode, sysChannelCode string) {
 	key := fmt.Sprintf("%s%d:%s:%s", payoutUpstreamFailKey, upstreamID, upstreamCode, sysChannelCode)
 	dal.RedisClient.Del(dal.RedisCtx, key)
+	metrics.SetUpstreamFailCount("payout", upstreamCode, sysChannelCode, 0)
 }
 
 // 获取失败次数
@@ -190,7 +210,7 @@ func (s *PayoutOrderService) Create(req dto.CreatePayoutOrderReq) (resp dto.Crea
 	}
 
 	// 5 商户通道
-	merchantChannelInfo, err := NewCommonService().GetMerchantChannelInfo(merchant.MerchantID, req.PayType)
+	merchantChannelInfo, err := s.commonSvc.GetMerchantChannelInfo(merchant.MerchantID, req.PayType)
 	if err != nil || merchantChannelInfo == nil {
 		return resp, errors.New(fmt.Sprintf("merchant channel invalid,payType: %s", req.PayType))
 	}
@@ -243,31 +263,136 @@ func (s *PayoutOrderService) Create(req dto.CreatePayoutOrderReq) (resp dto.Crea
 	}
 
 	// 8 计算结算
-	settle, err := s.calculateSettlement(merchant, products[0], amount)
+	settle, err := s.calculateSettlement(merchant, products[0], amount, req.PayMethod)
 	if err != nil {
 		return resp, err
 	}
 
 	// 9 商户余额
-	merchantMoney, mmErr := s.mainDao.GetMerchantAccount(strconv.FormatUint(merchant.MerchantID, 10))
+	merchantMoney, mmErr := s.mainDao.GetMerchantAccount(strconv.FormatUint(merchant.MerchantID, 10), channelDetail.Currency)
 	if mmErr != nil || merchantMoney.Money.LessThan(amount.Add(settle.AgentTotalFee).Add(settle.MerchantTotalFee)) {
 		return resp, errors.New("merchant insufficient balance [商户余额不足]")
 	}
+
+	// 9.1 累计限额（商户日/月、通道日、收款账户日），下单失败回滚
+	reservation, err := s.limitSvc.Reserve(2, merchant.MerchantID, channelDetail.Id, channelDetail.Currency, req.AccNo, amount)
+	if err != nil {
+		return resp, err
+	}
+
 	// 10 创建订单
 	now := time.Now()
-	order, tx, err := s.createOrderAndTransaction(merchant, req, products[0], amount, oid, now, settle)
+	order, tx, err := s.createOrderAndTransaction(merchant, req, products[0], amount, oid, now, settle, true)
 	if err != nil {
+		s.limitSvc.Release(reservation)
 		return resp, err
 	}
+	// 预占绑定订单：出款成功保留，驳回/失败退款时回滚
+	s.limitSvc.Bind(reservation, 2, order.OrderID)
+	metrics.OrderCreated("payout", req.PayType, channelDetail.Currency, req.MerchantNo)
+	// 缓存原始请求，供上游失败后自动改派使用
+	lifecycle.Go("payout-cache-request", func() { cachePayoutRequest(oid, req) })
+
+	// 10.1 大额/首次出款复核：资金已冻结，待复核通过后再提交上游（未开启复核时风控规则仅发布风控事件）
+	if config.C.Approval.Enabled {
+		approvalSvc := newPayoutApprovalService(s)
+		required, reason := approvalSvc.Check(merchant, channelDetail.Currency, amount, req.AccNo)
+		if !required && req.RiskReview != "" {
+			// 风控规则判定需复核
+			required, reason = true, "风控复核: "+req.RiskReview
+		}
+		if required {
+			if hErr := approvalSvc.Hold(order, req, reason, now); hErr != nil {
+				// 进入复核失败时不提交上游，转人工处理
+				log.Printf("[WARN] 代付进入复核失败 order=%d err=%v", oid, hErr)
+				_ = s.orderDao.UpdateByWhere(shard.OutOrderShard.GetTable(oid, now), map[string]interface{}{"order_id": oid}, map[string]interface{}{
+					"status":      6, // 人工处理
+					"remark":      truncateReason(fmt.Sprintf("进入复核失败, 等待人工介入: %v", hErr)),
+					"update_time": time.Now(),
+				})
+				notify.Notify(system.BotChatID, "error", "代付进入复核失败",
+					fmt.Sprintf("平台订单号: `%d`\n商户订单号: `%s`\n金额: `%s`\n原因: `%s`\n错误: `%v`\n\n当前资金已冻结，请人工处理。",
+						oid, req.TranFlow, req.Amount, reason, hErr), true)
+			}
+			resp = dto.CreatePayoutOrderResp{
+				PaySerialNo: strconv.FormatUint(oid, 10),
+				TranFlow:    req.TranFlow,
+				SysTime:     strconv.FormatInt(utils.GetTimestampMs(), 10),
+				Amount:      req.Amount, Code: "0", Status: "0001",
+			}
+			return resp, nil
+		}
+	}
 
 	// 11 调用上游（失败降级 + 成功后更新绑定信息 + settle_snapshot）
+	if lastErr := s.submitToUpstreams(merchant, &req, products, order, tx, amount, now); lastErr != nil {
+		resp = dto.CreatePayoutOrderResp{
+			PaySerialNo: strconv.FormatUint(oid, 10),
+			TranFlow:    req.TranFlow,
+			SysTime:     time.Now().Format(time.RFC3339),
+			Amount:      req.Amount,
+			Code:        "0", Status: "0001",
+		}
+		if isUnknownOutcomeErr(lastErr) {
+			// 上游可能已出款，按处理中返回，避免商户换单号重复提交
+			return resp, nil
+		}
+		if order.Status == payoutStatusFail {
+			// 上游明确拒绝，订单已失败退款
+			resp.Status = utils.ConvertOrderStatus(payoutStatusFail)
+		}
+		return resp, lastErr
+	}
+
+	// 12 构建响应
+	resp = dto.CreatePayoutOrderResp{
+		PaySerialNo: strconv.FormatUint(oid, 10),
+		TranFlow:    req.TranFlow,
+		SysTime:     strconv.FormatInt(utils.GetTimestampMs(), 10),
+		Amount:      req.Amount, Code: "0", Status: "0001",
+	}
+
+	// 13 异步事件
+	lifecycle.Go("payout-post-create", func() {
+		s.asyncPostOrderCreation(oid, order, merchant.MerchantID, req.TranFlow, req.Amount, now)
+	})
+
+	return resp, nil
+}
+
+// submitToUpstreams 按通道顺序提交上游（失败降级），全部失败时返回最后一个错误：
+// 上游明确拒绝则订单失败并退款，结果未知则转人工；若全部因上游余额不足失败，则进入排队等待上游补款（返回 nil）
+func (s *PayoutOrderService) submitToUpstreams(
+	merchant *mainmodel.Merchant,
+	req *dto.CreatePayoutOrderReq,
+	products []dto.PayProductVo,
+	order *ordermodel.MerchantPayOutOrderM,
+	tx *ordermodel.PayoutUpstreamTxM,
+	amount decimal.Decimal,
+	now time.Time,
+) error {
+	_, err := s.submitToUpstreamsWithHold(merchant, req, products, order, tx, amount, now)
+	return err
+}
+
+// submitToUpstreamsWithHold 同 submitToUpstreams，额外返回订单是否已进入余额不足排队
+func (s *PayoutOrderService) submitToUpstreamsWithHold(
+	merchant *mainmodel.Merchant,
+	req *dto.CreatePayoutOrderReq,
+	products []dto.PayProductVo,
+	order *ordermodel.MerchantPayOutOrderM,
+	tx *ordermodel.PayoutUpstreamTxM,
+	amount decimal.Decimal,
+	now time.Time,
+) (bool, error) {
 	var lastErr error
-	for _, product := range products {
+	allBalanceLow := len(products) > 0
+	for i, product := range products {
 		log.Printf("[代付上游调用尝试] 商户号=%s, 通道=%s/%s, 上游ID=%d",
 			req.MerchantNo, product.SysChannelCode, product.UpstreamCode, product.UpstreamId)
 
 		// 调用上游接口
-		_, err = s.callUpstreamService(merchant, &req, &product, tx.UpOrderId, order)
+		_, err := s.callUpstreamService(merchant, req, &product, tx.UpOrderId, order)
 		if err == nil {
 			// ✅ 调用成功逻辑
 			s.clearUpstreamFail(
@@ -278,21 +403,23 @@ func (s *PayoutOrderService) Create(req dto.CreatePayoutOrderReq) (resp dto.Crea
 			lastErr = nil
 
 			// ✅ 异步更新绑定（通道、费率、settle_snapshot）
-			go func(p dto.PayProductVo) {
+			p := product
+			lifecycle.Go("payout-order-bind", func() {
 				if e := s.updatePayoutOrderBindOnSuccess(order, tx, merchant, p, amount, now); e != nil {
 					log.Printf("[WARN] 代付订单绑定更新失败 order=%d err=%v", order.OrderID, e)
 					notify.Notify(system.BotChatID, "warn", "代付订单通道绑定更新失败",
 						fmt.Sprintf("订单号: %d\n通道: %s/%s\n错误: %v",
 							order.OrderID, p.SysChannelCode, p.UpstreamCode, e), true)
 				}
-			}(product)
+			})
 
 			// ✅ 更新通道成功率
-			go func(pid int64) {
+			pid := product.ID
+			lifecycle.Go("success-rate", func() {
 				if e := s.mainDao.UpdateSuccessRate(pid, true); e != nil {
 					log.Printf("update channel success rate failed: %v", e)
 				}
-			}(product.ID)
+			})
 
 			log.Printf("[代付上游调用成功] 商户号=%s, 通道=%s/%s, 上游ID=%d, 订单ID=%d",
 				req.MerchantNo, product.SysChannelCode, product.UpstreamCode, product.UpstreamId, order.OrderID)
@@ -301,15 +428,19 @@ func (s *PayoutOrderService) Create(req dto.CreatePayoutOrderReq) (resp dto.Crea
 
 		// ❌ 调用失败逻辑
 		lastErr = err
+		if !isUpstreamBalanceLow(err) {
+			allBalanceLow = false
+		}
 		log.Printf("[代付上游调用失败] 商户号=%s, 通道=%s/%s, 上游ID=%d, 错误=%v",
 			req.MerchantNo, product.SysChannelCode, product.UpstreamCode, product.UpstreamId, err)
 
 		// 更新通道成功率（异步标记失败）
-		go func(pid int64) {
+		pid := product.ID
+		lifecycle.Go("success-rate", func() {
 			if e := s.mainDao.UpdateSuccessRate(pid, false); e != nil {
 				log.Printf("update channel fail rate failed: %v", e)
 			}
-		}(product.ID)
+		})
 
 		// 记录失败计数(多维度)
 		s.recordUpstreamFail(
@@ -330,8 +461,18 @@ func (s *PayoutOrderService) Create(req dto.CreatePayoutOrderReq) (resp dto.Crea
 				product.UpstreamTitle,
 				req.TranFlow,
 				err,
-				utils.MapToJSON(req),
+				utils.MapToJSON(*req),
 			), true)
+
+		// 不可重试的上游错误（账户无效、订单重复、结果未知等）不再切换通道，避免重复出款
+		if !isRetryableUpstreamErr(err) {
+			allBalanceLow = false
+			log.Printf("[代付上游调用失败] 错误不可重试，停止切换通道: 订单ID=%d, 错误=%v", order.OrderID, err)
+			break
+		}
+		if i < len(products)-1 {
+			metrics.UpstreamFailover("payout", req.PayType)
+		}
 	}
 
 	//// ❌ 所有上游均失败
@@ -346,22 +487,44 @@ func (s *PayoutOrderService) Create(req dto.CreatePayoutOrderReq) (resp dto.Crea
 	//	return resp, lastErr
 	//}
 
-	// ❌ 所有上游均失败
+	// ⏳ 所有上游均余额不足：资金保持冻结，进入排队等待上游补款
+	if lastErr != nil && allBalanceLow && config.C.Holding.Enabled {
+		if hErr := parkPayout(s.orderDao, req, order, now); hErr == nil {
+			return true, nil
+		} else {
+			log.Printf("[WARN] 代付进入余额排队失败 order=%d err=%v", order.OrderID, hErr)
+		}
+	}
+
+	// ❌ 上游明确拒绝：订单失败并退回冻结资金
+	if lastErr != nil && !isUnknownOutcomeErr(lastErr) && s.failRejectedPayout(merchant, req, order, lastErr, now) {
+		return false, lastErr
+	}
+
+	// ❌ 结果未知（或失败退款异常）：转人工
 	if lastErr != nil {
 		orderTable := shard.OutOrderShard.GetTable(order.OrderID, now)
+		remark := fmt.Sprintf("所有上游均失败, 等待人工介入: %v", lastErr)
+		title := "⚠️ 代付所有上游均失败"
+		if isUnknownOutcomeErr(lastErr) {
+			// 上游可能已出款：不切换通道，等待上游回调或人工向上游查单确认
+			remark = fmt.Sprintf("上游结果未知, 等待查单/人工确认: %v", lastErr)
+			title = "⚠️ 代付上游结果未知"
+		}
+		order.Status = 6
 		update := map[string]interface{}{
 			"status":      6, // 人工处理
-			"remark":      fmt.Sprintf("所有上游均失败, 等待人工介入: %v", lastErr),
+			"remark":      truncateReason(remark),
 			"update_time": time.Now(),
 		}
-		if err := dal.OrderDB.Table(orderTable).Where("order_id = ?", order.OrderID).Updates(update).Error; err != nil {
+		if err := s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, update); err != nil {
 			log.Printf("[WARN] 更新订单状态失败 order=%d err=%v", order.OrderID, err)
 		}
 
 		// ✅ Telegram 告警推送
-		notify.Notify(system.BotChatID, "error", "⚠️ 代付所有上游均失败",
+		notify.Notify(system.BotChatID, "error", title,
 			fmt.Sprintf(
-				"💀 代付订单所有上游调用失败\n平台单号: `%d`\n商户单号: `%v`\n商户号: `%s`\n金额: `%s`\n系统通道: `%s`\n错误: `%v`\n\n当前资金已冻结，请人工处理。",
+				"💀 代付订单上游调用失败\n平台单号: `%d`\n商户单号: `%v`\n商户号: `%s`\n金额: `%s`\n系统通道: `%s`\n错误: `%v`\n\n当前资金已冻结，请向上游查单确认后人工处理。",
 				order.OrderID,
 				req.TranFlow,
 				req.MerchantNo,
@@ -371,85 +534,73 @@ func (s *PayoutOrderService) Create(req dto.CreatePayoutOrderReq) (resp dto.Crea
 			),
 			true,
 		)
-
-		resp = dto.CreatePayoutOrderResp{
-			PaySerialNo: strconv.FormatUint(oid, 10),
-			TranFlow:    req.TranFlow,
-			SysTime:     time.Now().Format(time.RFC3339),
-			Amount:      req.Amount,
-			Code:        "0", Status: "0001",
-		}
-		return resp, nil
 	}
 
-	// 12 构建响应
-	resp = dto.CreatePayoutOrderResp{
-		PaySerialNo: strconv.FormatUint(oid, 10),
-		TranFlow:    req.TranFlow,
-		SysTime:     strconv.FormatInt(utils.GetTimestampMs(), 10),
-		Amount:      req.Amount, Code: "0", Status: "0001",
-	}
+	return false, lastErr
+}
 
-	// 13 异步事件
-	go s.asyncPostOrderCreation(oid, order, merchant.MerchantID, req.TranFlow, req.Amount, now)
-
-	// 14) 异步处理统计数据
-	go func(ord *ordermodel.MerchantPayOutOrderM) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("[Panic-Recovered] 异步统计 goroutine panic: %v\n%s", r, debug.Stack())
-				notify.Notify(system.BotChatID, "error", "异步统计 Panic",
-					fmt.Sprintf("🚨 异步统计 goroutine panic: %v", r), true)
-			}
-		}()
-
-		// 1️⃣ 获取国家信息
-		country, cErr := s.mainDao.GetCountry(ord.Currency)
-		if cErr != nil {
-			log.Printf("[order_stat] 获取国家信息异常: %v, country=%v", cErr, country)
-			notify.Notify(system.BotChatID, "warn", "代付下单",
-				fmt.Sprintf("⚠️ 获取国家信息异常: %v", cErr), true)
-			// 直接返回，不继续往下执行
-			return
-		}
+// failRejectedPayout 上游明确拒绝（未出款）时订单置为失败、退回冻结资金并释放累计限额；
+// 退款失败返回 false，由调用方转人工
+func (s *PayoutOrderService) failRejectedPayout(merchant *mainmodel.Merchant, req *dto.CreatePayoutOrderReq, order *ordermodel.MerchantPayOutOrderM, lastErr error, now time.Time) bool {
+	oid := strconv.FormatUint(order.OrderID, 10)
+	settle := order.SettleSnapshot
+	if err := s.mainDao.HandlePayoutCallback(
+		merchant.MerchantID,
+		order.Currency,
+		oid,
+		order.MOrderID,
+		settle.MerchantTotalFee,
+		settle.AgentTotalFee,
+		false,
+		order.Amount,
+		merchant.NickName,
+	); err != nil {
+		log.Printf("[WARN] 代付上游拒绝后解冻失败 order=%d err=%v", order.OrderID, err)
+		return false
+	}
 
-		// 2️⃣ 检查 Publisher 是否存在
-		if s.pub == nil {
-			log.Printf("[order_stat] Publisher 未初始化，跳过发布。OrderID=%v", ord.OrderID)
-			notify.Notify(system.BotChatID, "warn", "代付下单",
-				fmt.Sprintf("⚠️ Publisher 未初始化，跳过发布 OrderID=%v", ord.OrderID), true)
-			return
-		}
+	orderTable := shard.OutOrderShard.GetTable(order.OrderID, now)
+	finish := time.Now()
+	order.Status = payoutStatusFail
+	if err := s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+		"status":      payoutStatusFail,
+		"remark":      truncateReason(fmt.Sprintf("上游拒绝, 订单失败并退款: %v", lastErr)),
+		"finish_time": finish,
+		"update_time": finish,
+	}); err != nil {
+		log.Printf("[WARN] 更新订单状态失败 order=%d err=%v", order.OrderID, err)
+	}
+	s.limitSvc.ReleaseOrder(order.OrderID)
+
+	log.Printf("[代付上游拒绝] ❌ 订单失败并退款 order=%d 错误=%v", order.OrderID, lastErr)
+	notify.Notify(system.BotChatID, "warn", "代付上游拒绝",
+		fmt.Sprintf("平台单号: `%d`\n商户单号: `%s`\n商户号: `%s`\n金额: `%s`\n系统通道: `%s`\n错误: `%v`\n\n订单已失败，冻结资金已退回商户余额。",
+			order.OrderID, req.TranFlow, req.MerchantNo, req.Amount, req.PayType, lastErr), true)
+
+	lifecycle.Go("payout-reject-notify", func() {
+		notifyPayoutMerchantFinal(s.orderDao, merchant, order, orderTable, payoutStatusFail, "Refunded 失败(并退款)")
+	})
+	return true
+}
 
-		// 3️⃣ 构造消息并发布
-		msg := &dto.OrderMessageMQ{
-			OrderID:       strconv.FormatUint(ord.OrderID, 10),
-			MerchantID:    ord.MID,
-			CountryID:     country.ID,
-			ChannelID:     ord.ChannelID,
-			SupplierID:    ord.SupplierID,
-			Amount:        ord.Amount,
-			SuccessAmount: decimal.Zero,
-			Profit:        decimal.Zero,
-			Cost:          decimal.Zero,
-			Fee:           decimal.Zero,
-			Status:        1,
-			OrderType:     "payout",
-			Currency:      ord.Currency,
-			CreateTime:    time.Now(),
+// selectPayoutProducts 按商户通道调度模式选择上游通道（批量代付/复核通过后提交使用）
+func (s *PayoutOrderService) selectPayoutProducts(merchant *mainmodel.Merchant, payType, currency string, amount decimal.Decimal) ([]dto.PayProductVo, error) {
+	merchantChannelInfo, err := s.commonSvc.GetMerchantChannelInfo(merchant.MerchantID, payType)
+	if err != nil || merchantChannelInfo == nil {
+		return nil, fmt.Errorf("merchant channel invalid,payType: %s", payType)
+	}
+	if merchantChannelInfo.DispatchMode == 2 {
+		single, err := s.SelectSingleChannel(uint(merchant.MerchantID), payType, 2, currency)
+		if err != nil {
+			return nil, errors.New("no single channel available")
 		}
-
-		if err := s.pub.Publish("order_stat", msg); err != nil {
-			log.Printf("[order_stat] 发布订单统计失败 OrderID=%v: %v", ord.OrderID, err)
-			notify.Notify(system.BotChatID, "warn", "代付下单",
-				fmt.Sprintf("⚠️ 发布订单统计失败 OrderID=%v: %v", ord.OrderID, err), true)
-			return
+		orderRange := fmt.Sprintf("%v-%v", single.MinAmount, single.MaxAmount)
+		if !utils.MatchOrderRange(amount, orderRange) {
+			return nil, fmt.Errorf("the amount does not meet the risk control requirements.order amount:%v,limit amount:%s", amount, orderRange)
 		}
-
-		log.Printf("[order_stat] ✅ 订单统计入列成功 OrderID=%v", ord.OrderID)
-	}(order)
-
-	return resp, nil
+		return []dto.PayProductVo{single}, nil
+	}
+	return s.selectWeightedPollingChannels(uint(merchant.MerchantID), payType, 2, currency, amount)
 }
 
 // ================== 轮询通道选择 ==================
@@ -539,7 +690,7 @@ func (s *PayoutOrderService) updatePayoutOrderBindOnSuccess(
 ) error {
 
 	// 1️⃣ 重新计算结算信息
-	settle, err := s.calculateSettlement(merchant, product, amount)
+	settle, err := s.calculateSettlement(merchant, product, amount, order.PayMethod)
 	if err != nil {
 		return fmt.Errorf("recalculate payout settlement failed: %w", err)
 	}
@@ -578,7 +729,7 @@ func (s *PayoutOrderService) updatePayoutOrderBindOnSuccess(
 			log.Printf("[PAYOUT-FREEZE-ADJUST][FAIL] %v", msg)
 			notify.Notify(system.BotChatID, "warn", "代付补冻结失败", msg, true)
 		} else {
-			log.Printf("[PAYOUT-FREEZE-ADJUST] ✅ 成功补冻结 %.4f 元", diff)
+			log.Printf("[PAYOUT-FREEZE-ADJUST] ✅ 成功补冻结 %s 元", diff.StringFixed(4))
 			notify.Notify(system.BotChatID, "info", "代付补冻结成功",
 				fmt.Sprintf("订单号: `%d`\n补冻结金额: `%s`\n通道: `%s/%s`",
 					order.OrderID, diff.StringFixed(4), product.SysChannelCode, product.UpstreamCode), false)
@@ -619,9 +770,7 @@ func (s *PayoutOrderService) updatePayoutOrderBindOnSuccess(
 		"remark":           fmt.Sprintf("通道成功切换为: %s/%s", product.SysChannelCode, product.UpstreamCode),
 	}
 
-	if err := dal.OrderDB.Table(orderTable).
-		Where("order_id = ?", order.OrderID).
-		Updates(updateOrder).Error; err != nil {
+	if err := s.orderDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, updateOrder); err != nil {
 		return fmt.Errorf("update payout order bind failed: %w", err)
 	}
 
@@ -636,9 +785,7 @@ func (s *PayoutOrderService) updatePayoutOrderBindOnSuccess(
 			"currency":    product.Currency,
 			"update_time": now,
 		}
-		if err := dal.OrderDB.Table(txTable).
-			Where("order_id = ? AND up_order_id = ?", upTx.OrderID, upTx.UpOrderId).
-			Updates(updateTx).Error; err != nil {
+		if err := s.orderDao.UpdateByWhere(txTable, map[string]interface{}{"order_id": upTx.OrderID, "up_order_id": upTx.UpOrderId}, updateTx); err != nil {
 			log.Printf("[WARN] update payout upstream tx failed: %v", err)
 		} else {
 			log.Printf("[PAYOUT-TX-UPDATE] ✅ 上游交易同步完成 order=%d supplier=%d", upTx.OrderID, product.UpstreamId)
@@ -712,9 +859,9 @@ func (s *PayoutOrderService) callUpstreamServiceInternal(
 
 	if req.BankCode != "" {
 		// 根据接平台银行编码查询平台银行信息
-		platformBank, pbErr := s.mainDao.QueryPlatformBankInfo(req.BankCode, merchant.Currency)
+		platformBank, pbErr := s.mainDao.QueryPlatformBankInfo(req.BankCode, payChannelProduct.Currency)
 		if pbErr != nil {
-			return "", fmt.Errorf(fmt.Sprintf("platform Bank code does not exist,%s", req.BankCode))
+			return "", fmt.Errorf("platform Bank code does not exist,%s", req.BankCode)
 		}
 
 		// 根据接口ID+平台银行编码+国家货币查询对应上游银行编码+银行名称
@@ -722,7 +869,7 @@ func (s *PayoutOrderService) callUpstreamServiceInternal(
 
 			upstreamBank, ubErr := s.mainDao.QueryUpstreamBankInfo(payChannelProduct.InterfaceID, req.BankCode, payChannelProduct.Currency)
 			if ubErr != nil {
-				return "", fmt.Errorf(fmt.Sprintf("upstream Bank code does not exist,%s", req.BankCode))
+				return "", fmt.Errorf("upstream Bank code does not exist,%s", req.BankCode)
 			} else {
 				bankCode = upstreamBank.UpstreamBankCode
 				bankName = upstreamBank.UpstreamBankName
@@ -803,14 +950,14 @@ func (s *PayoutOrderService) createOrderAndTransaction(
 	oid uint64,
 	now time.Time,
 	settle dto.SettlementResult,
+	freeze bool,
 ) (*ordermodel.MerchantPayOutOrderM, *ordermodel.PayoutUpstreamTxM, error) {
 	var order *ordermodel.MerchantPayOutOrderM
 	var tx *ordermodel.PayoutUpstreamTxM
 
-	err := dal.OrderDB.Transaction(func(txDB *gorm.DB) error {
-		// 事务内的 dao
-		orderDao := dao.NewPayoutOrderDaoWithDB(txDB)
+	statEvt := s.payoutCreateStatEvent(merchant.MerchantID, payChannelProduct, amount, oid)
 
+	err := s.orderDao.Transaction(func(orderDao dao.PayoutOrderRepository) error {
 		// 创建订单
 		if err := s.createOrder(merchant, req, payChannelProduct, amount, oid, now, settle, orderDao); err != nil {
 			return fmt.Errorf("create order failed: %w", err)
@@ -827,7 +974,16 @@ func (s *PayoutOrderService) createOrderAndTransaction(
 		if err := s.createOrderIndex(merchant, req, oid, now, orderDao); err != nil {
 			return fmt.Errorf("create order index failed: %w", err)
 		}
-		// 冻结商户资金
+		// 订单统计事件同事务写入发件箱
+		if statEvt != nil {
+			if err := orderDao.InsertOutbox(statEvt); err != nil {
+				return fmt.Errorf("insert order_stat outbox failed: %w", err)
+			}
+		}
+		// 冻结商户资金（批量代付已整批冻结，无需逐笔冻结）
+		if !freeze {
+			return nil
+		}
 		needFreezeAmount := amount.Add(settle.AgentTotalFee).Add(settle.MerchantTotalFee)
 		freezeErr := s.freezePayout(merchant.MerchantID, payChannelProduct.Currency, strconv.FormatUint(oid, 10), req.TranFlow, needFreezeAmount, merchant.NickName)
 		if freezeErr != nil {
@@ -861,6 +1017,38 @@ func (s *PayoutOrderService) createOrderAndTransaction(
 	return order, tx, nil
 }
 
+// payoutCreateStatEvent 构造代付下单统计事件，国家信息获取失败时告警并跳过统计
+func (s *PayoutOrderService) payoutCreateStatEvent(mid uint64, product dto.PayProductVo, amount decimal.Decimal, oid uint64) *ordermodel.OutboxEventM {
+	country, cErr := s.mainDao.GetCountry(product.Currency)
+	if cErr != nil {
+		log.Printf("[order_stat] 获取国家信息异常: %v, country=%v", cErr, country)
+		notify.Notify(system.BotChatID, "warn", "代付下单",
+			fmt.Sprintf("⚠️ 获取国家信息异常: %v", cErr), true)
+		return nil
+	}
+	evt, err := outbox.NewOrderStat(&dto.OrderMessageMQ{
+		OrderID:       strconv.FormatUint(oid, 10),
+		MerchantID:    mid,
+		CountryID:     country.ID,
+		ChannelID:     product.SysChannelID,
+		SupplierID:    product.UpstreamId,
+		Amount:        amount,
+		SuccessAmount: decimal.Zero,
+		Profit:        decimal.Zero,
+		Cost:          decimal.Zero,
+		Fee:           decimal.Zero,
+		Status:        1,
+		OrderType:     "payout",
+		Currency:      product.Currency,
+		CreateTime:    time.Now(),
+	})
+	if err != nil {
+		log.Printf("[order_stat] 构造订单统计事件失败 OrderID=%v: %v", oid, err)
+		return nil
+	}
+	return evt
+}
+
 // 冻结资金
 func (s *PayoutOrderService) freezePayout(uid uint64, currency string, orderNo string, mOrderNo string, amount decimal.Decimal, operator string) error {
 
@@ -880,7 +1068,7 @@ func (s *PayoutOrderService) createOrder(
 	oid uint64,
 	now time.Time,
 	settle dto.SettlementResult,
-	orderDao *dao.PayoutOrderDao,
+	orderDao dao.PayoutOrderRepository,
 ) error {
 	var orderSettle dto.SettlementResult
 	if err := copier.Copy(&orderSettle, &settle); err != nil {
@@ -946,7 +1134,7 @@ func (s *PayoutOrderService) createOrder(
 }
 
 // calculateSettlement 计算结算费用
-func (s *PayoutOrderService) calculateSettlement(merchant *mainmodel.Merchant, payChannelProduct dto.PayProductVo, amount decimal.Decimal) (dto.SettlementResult, error) {
+func (s *PayoutOrderService) calculateSettlement(merchant *mainmodel.Merchant, payChannelProduct dto.PayProductVo, amount decimal.Decimal, payMethod string) (dto.SettlementResult, error) {
 	// 验证输入参数
 	if merchant == nil {
 		return dto.SettlementResult{}, errors.New("merchant cannot be nil")
@@ -955,45 +1143,18 @@ func (s *PayoutOrderService) calculateSettlement(merchant *mainmodel.Merchant, p
 		return dto.SettlementResult{}, errors.New("amount must be positive")
 	}
 
-	var agentPct, agentFixed = decimal.Zero, decimal.Zero
-
-	// 如果有代理商户，获取代理信息
-	if merchant.PId > 0 {
-		agentMerchant := dto.QueryAgentMerchant{
-			AId:          int64(merchant.PId),
-			MId:          int64(merchant.MerchantID),
-			SysChannelID: payChannelProduct.SysChannelID,
-			Currency:     payChannelProduct.Currency,
-		}
-
-		agentInfo, err := s.mainDao.GetAgentMerchant(agentMerchant)
-		if err != nil {
-			log.Printf("get agent merchant failed[获取代理商户失败]: %v", err)
-			// 不返回错误，继续使用零值
-		} else if agentInfo != nil && agentInfo.Status == 1 {
-			agentPct = agentInfo.DefaultRate
-			agentFixed = agentInfo.SingleFee
-		}
-	}
-
 	// 验证费率有效性
 	if payChannelProduct.MDefaultRate.IsNegative() || payChannelProduct.CostRate.IsNegative() {
 		return dto.SettlementResult{}, errors.New("invalid rate value[无效的费率值]")
 	}
 
-	// 计算结算费用
-	settle := utils.Calculate(
+	// 计算结算费用（多级代理分润，命中阶梯/交易量费率方案时按方案计价）
+	settle := s.feeSvc.Settle(
+		merchant,
+		payChannelProduct,
 		amount,
-		payChannelProduct.MDefaultRate,
-		payChannelProduct.MSingleFee,
-		agentPct,
-		agentFixed,
-		payChannelProduct.CostRate,
-		payChannelProduct.CostFee,
+		payMethod,
 		"agent_from_platform",
-		payChannelProduct.Currency,
-		payChannelProduct.MinFee,
-		payChannelProduct.HasMinFee,
 	)
 
 	return settle, nil
@@ -1006,7 +1167,7 @@ func (s *PayoutOrderService) createUpstreamTx(
 	payChannelProduct dto.PayProductVo,
 	oid uint64,
 	now time.Time,
-	orderDao *dao.PayoutOrderDao,
+	orderDao dao.PayoutOrderRepository,
 ) (*ordermodel.PayoutUpstreamTxM, error) {
 	txId := idgen.New()
 	txTable := shard.UpOutOrderShard.GetTable(txId, now)
@@ -1047,7 +1208,7 @@ func (s *PayoutOrderService) createOrderIndex(
 	req dto.CreatePayoutOrderReq,
 	oid uint64,
 	now time.Time,
-	orderDao *dao.PayoutOrderDao,
+	orderDao dao.PayoutOrderRepository,
 ) error {
 	receiveIndexTable := utils.GetOrderIndexTable("p_out_order_index", now)
 	orderLogIndexTable := shard.OutOrderLogShard.GetTable(oid, now)
@@ -1407,7 +1568,7 @@ func (s *PayoutOrderService) HealthCheck() bool {
 	ctx, cancel := context.WithTimeout(s.ctx, 3*time.Second)
 	defer cancel()
 
-	if err := dal.OrderDB.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+	if err := s.orderDao.Ping(ctx); err != nil {
 		log.Printf("数据库健康检查失败: %v", err)
 		s.isHealthy = false
 		return false
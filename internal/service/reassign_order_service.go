@@ -16,6 +16,8 @@ import (
 	"sync"
 	"time"
 	"wht-order-api/internal/channel/health"
+	"wht-order-api/internal/lifecycle"
+	"wht-order-api/internal/metrics"
 	mainmodel "wht-order-api/internal/model/main"
 	"wht-order-api/internal/notify"
 	"wht-order-api/internal/shard"
@@ -43,6 +45,7 @@ type ReassignOrderService struct {
 	healthCheckLock sync.RWMutex
 	lastHealthCheck time.Time
 	isHealthy       bool
+	feeSvc          *FeeScheduleService // 阶梯费率方案
 }
 
 func NewReassignOrderService() *ReassignOrderService {
@@ -51,6 +54,7 @@ func NewReassignOrderService() *ReassignOrderService {
 		mainDao:       dao.NewMainDao(),        // 使用工厂方法
 		orderDao:      dao.NewPayoutOrderDao(), // 使用工厂方法
 		indexTableDao: dao.NewIndexTableDao(),  // 使用工厂方法
+		feeSvc:        NewFeeScheduleService(dao.NewMainDao()),
 		ctx:           ctx,
 		cancel:        cancel,
 		isHealthy:     false,
@@ -78,6 +82,7 @@ func (s *ReassignOrderService) recordUpstreamFail(upstreamID uint64, upstreamNam
 	if cnt == 1 {
 		dal.RedisClient.Expire(dal.RedisCtx, key, 5*time.Minute)
 	}
+	metrics.SetUpstreamFailCount("reassign", upstreamCode, sysChannelCode, cnt)
 
 	// ⚠️ 第3次警告
 	if cnt == 3 {
@@ -102,6 +107,7 @@ func (s *ReassignOrderService) recordUpstreamFail(upstreamID uint64, upstreamNam
 func (s *ReassignOrderService) clearUpstreamFail(upstreamID uint64, upstreamCode, sysChannelCode string) {
 	key := fmt.Sprintf("%s%d:%s:%s", reassignUpstreamFailKey, upstreamID, upstreamCode, sysChannelCode)
 	dal.RedisClient.Del(dal.RedisCtx, key)
+	metrics.SetUpstreamFailCount("reassign", upstreamCode, sysChannelCode, 0)
 }
 
 // 获取失败次数
@@ -227,13 +233,13 @@ func (s *ReassignOrderService) Create(req dto.CreateReassignOrderReq) (resp dto.
 	}
 
 	// 8 计算结算
-	settle, err := s.calculateSettlement(merchant, single, amount)
+	settle, err := s.calculateSettlement(merchant, single, amount, req.PayMethod)
 	if err != nil {
 		return resp, err
 	}
 
 	// 9 商户余额
-	merchantMoney, mmErr := s.mainDao.GetMerchantAccount(strconv.FormatUint(merchant.MerchantID, 10))
+	merchantMoney, mmErr := s.mainDao.GetMerchantAccount(strconv.FormatUint(merchant.MerchantID, 10), channelDetail.Currency)
 	if mmErr != nil || merchantMoney.Money.LessThan(amount.Add(settle.AgentTotalFee).Add(settle.MerchantTotalFee)) {
 		return resp, errors.New("merchant insufficient balance")
 	}
@@ -244,6 +250,7 @@ func (s *ReassignOrderService) Create(req dto.CreateReassignOrderReq) (resp dto.
 	if err != nil {
 		return resp, err
 	}
+	metrics.OrderCreated("reassign", req.PayType, channelDetail.Currency, req.MerchantNo)
 
 	// 11 调用上游（仅一次）
 	singleProduct := single
@@ -258,11 +265,12 @@ func (s *ReassignOrderService) Create(req dto.CreateReassignOrderReq) (resp dto.
 			singleProduct.UpstreamCode,
 			singleProduct.SysChannelCode,
 		)
-		go func(pid int64) {
+		pid := singleProduct.ID
+		lifecycle.Go("success-rate", func() {
 			if e := s.mainDao.UpdateSuccessRate(pid, false); e != nil {
 				log.Printf("update channel success rate failed: %v", e)
 			}
-		}(singleProduct.ID)
+		})
 
 		notify.Notify(system.BotChatID, "warn", "改派代付上游调用失败",
 			fmt.Sprintf(
@@ -287,11 +295,12 @@ func (s *ReassignOrderService) Create(req dto.CreateReassignOrderReq) (resp dto.
 			singleProduct.SysChannelCode,
 		)
 		lastErr = nil
-		go func(pid int64) {
+		pid := singleProduct.ID
+		lifecycle.Go("success-rate", func() {
 			if e := s.mainDao.UpdateSuccessRate(pid, true); e != nil {
 				log.Printf("update channel success rate failed: %v", e)
 			}
-		}(singleProduct.ID)
+		})
 
 		// ✅ 改派成功后修正订单表信息
 		if uErr := s.updateReassignOrderInfo(order, merchant, singleProduct, settle, amount); uErr != nil {
@@ -323,7 +332,9 @@ func (s *ReassignOrderService) Create(req dto.CreateReassignOrderReq) (resp dto.
 	}
 
 	// 13 异步缓存
-	go s.asyncPostOrderCreation(orderId, order, merchant.MerchantID, req.TranFlow, req.Amount, now)
+	lifecycle.Go("reassign-post-create", func() {
+		s.asyncPostOrderCreation(orderId, order, merchant.MerchantID, req.TranFlow, req.Amount, now)
+	})
 	return resp, nil
 }
 
@@ -377,7 +388,7 @@ func (s *ReassignOrderService) updateReassignOrderInfo(
 			log.Printf("[REASSIGN-FREEZE-ADJUST][FAIL] %v", msg)
 			notify.Notify(system.BotChatID, "warn", "改派补冻结失败", msg, true)
 		} else {
-			log.Printf("[REASSIGN-FREEZE-ADJUST] ✅ 成功补冻结 %.4f 元", diff)
+			log.Printf("[REASSIGN-FREEZE-ADJUST] ✅ 成功补冻结 %s 元", diff.StringFixed(4))
 			notify.Notify(system.BotChatID, "info", "改派补冻结成功",
 				fmt.Sprintf("订单号: `%d`\n补冻结金额: `%s`\n通道: `%s/%s`",
 					order.OrderID, diff.StringFixed(4), product.SysChannelCode, product.UpstreamCode), false)
@@ -501,9 +512,9 @@ func (s *ReassignOrderService) callUpstreamServiceInternal(
 
 	if req.BankCode != "" {
 		// 根据接平台银行编码查询平台银行信息
-		platformBank, pbErr := s.mainDao.QueryPlatformBankInfo(req.BankCode, merchant.Currency)
+		platformBank, pbErr := s.mainDao.QueryPlatformBankInfo(req.BankCode, payChannelProduct.Currency)
 		if pbErr != nil {
-			return "", fmt.Errorf(fmt.Sprintf("platform Bank code does not exist,%s", req.BankCode))
+			return "", fmt.Errorf("platform Bank code does not exist,%s", req.BankCode)
 		}
 
 		// 根据接口ID+平台银行编码+国家货币查询对应上游银行编码+银行名称
@@ -511,7 +522,7 @@ func (s *ReassignOrderService) callUpstreamServiceInternal(
 
 			upstreamBank, ubErr := s.mainDao.QueryUpstreamBankInfo(payChannelProduct.InterfaceID, req.BankCode, payChannelProduct.Currency)
 			if ubErr != nil {
-				return "", fmt.Errorf(fmt.Sprintf("upstream Bank code does not exist,%s", req.BankCode))
+				return "", fmt.Errorf("upstream Bank code does not exist,%s", req.BankCode)
 			} else {
 				bankCode = upstreamBank.UpstreamBankCode
 				bankName = upstreamBank.UpstreamBankName
@@ -750,7 +761,7 @@ func (s *ReassignOrderService) createOrder(
 }
 
 // calculateSettlement 计算结算费用
-func (s *ReassignOrderService) calculateSettlement(merchant *mainmodel.Merchant, payChannelProduct dto.PayProductVo, amount decimal.Decimal) (dto.SettlementResult, error) {
+func (s *ReassignOrderService) calculateSettlement(merchant *mainmodel.Merchant, payChannelProduct dto.PayProductVo, amount decimal.Decimal, payMethod string) (dto.SettlementResult, error) {
 	// 验证输入参数
 	if merchant == nil {
 		return dto.SettlementResult{}, errors.New("merchant cannot be nil")
@@ -759,45 +770,18 @@ func (s *ReassignOrderService) calculateSettlement(merchant *mainmodel.Merchant,
 		return dto.SettlementResult{}, errors.New("amount must be positive")
 	}
 
-	var agentPct, agentFixed = decimal.Zero, decimal.Zero
-
-	// 如果有代理商户，获取代理信息
-	if merchant.PId > 0 {
-		agentMerchant := dto.QueryAgentMerchant{
-			AId:          int64(merchant.PId),
-			MId:          int64(merchant.MerchantID),
-			SysChannelID: payChannelProduct.SysChannelID,
-			Currency:     payChannelProduct.Currency,
-		}
-
-		agentInfo, err := s.mainDao.GetAgentMerchant(agentMerchant)
-		if err != nil {
-			log.Printf("get agent merchant failed: %v", err)
-			// 不返回错误，继续使用零值
-		} else if agentInfo != nil && agentInfo.Status == 1 {
-			agentPct = agentInfo.DefaultRate
-			agentFixed = agentInfo.SingleFee
-		}
-	}
-
 	// 验证费率有效性
 	if payChannelProduct.MDefaultRate.IsNegative() || payChannelProduct.CostRate.IsNegative() {
 		return dto.SettlementResult{}, errors.New("invalid rate value")
 	}
 
-	// 计算结算费用
-	settle := utils.Calculate(
+	// 计算结算费用（多级代理分润，命中阶梯/交易量费率方案时按方案计价）
+	settle := s.feeSvc.Settle(
+		merchant,
+		payChannelProduct,
 		amount,
-		payChannelProduct.MDefaultRate,
-		payChannelProduct.MSingleFee,
-		agentPct,
-		agentFixed,
-		payChannelProduct.CostRate,
-		payChannelProduct.CostFee,
+		payMethod,
 		"agent_from_platform",
-		payChannelProduct.Currency,
-		payChannelProduct.MinFee,
-		payChannelProduct.HasMinFee,
 	)
 
 	return settle, nil
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jinzhu/copier"
-	"gorm.io/gorm"
 	"log"
 	"math"
 	"runtime/debug"
@@ -14,6 +13,8 @@ import (
 	"sync"
 	"time"
 	"wht-order-api/internal/event"
+	"wht-order-api/internal/lifecycle"
+	"wht-order-api/internal/metrics"
 	"wht-order-api/internal/notify"
 	"wht-order-api/internal/shard"
 	"wht-order-api/internal/system"
@@ -30,28 +31,41 @@ import (
 	"wht-order-api/internal/dto"
 	"wht-order-api/internal/idgen"
 	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/outbox"
 )
 
 // ================== Redis 失败计数 ==================
 const upstreamFailKey = "pay_up_fail:"
 
 type ReceiveOrderService struct {
-	mainDao       *dao.MainDao  // 主数据库
-	orderDao      *dao.OrderDao //订单数据库
-	indexTableDao *dao.IndexTableDao
+	mainDao       dao.MainRepository  // 主数据库
+	orderDao      dao.OrderRepository //订单数据库
+	indexTableDao dao.IndexTableRepository
+	commonSvc     *CommonService
 	merchantGroup singleflight.Group
 	channelGroup  singleflight.Group
 	ctx           context.Context
 	cancel        context.CancelFunc
 	pub           event.Publisher
+	feeSvc        *FeeScheduleService // 阶梯费率方案
+	limitSvc      *LimitService       // 累计限额
 }
 
 func NewReceiveOrderService(pub event.Publisher) *ReceiveOrderService {
+	// 默认全局 DB
+	return NewReceiveOrderServiceWithRepos(pub, dao.NewMainDao(), dao.NewOrderDao(), dao.NewIndexTableDao())
+}
+
+// NewReceiveOrderServiceWithRepos 注入仓储（单元测试使用内存实现）
+func NewReceiveOrderServiceWithRepos(pub event.Publisher, mainRepo dao.MainRepository, orderRepo dao.OrderRepository, indexRepo dao.IndexTableRepository) *ReceiveOrderService {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &ReceiveOrderService{
-		mainDao:       dao.NewMainDao(),
-		orderDao:      dao.NewOrderDao(), // 默认全局 DB
-		indexTableDao: dao.NewIndexTableDao(),
+		mainDao:       mainRepo,
+		orderDao:      orderRepo,
+		indexTableDao: indexRepo,
+		commonSvc:     NewCommonServiceWithRepos(mainRepo, orderRepo, indexRepo),
+		feeSvc:        NewFeeScheduleService(mainRepo),
+		limitSvc:      NewLimitService(mainRepo),
 		ctx:           ctx,
 		cancel:        cancel,
 		pub:           pub, // 注入
@@ -71,6 +85,7 @@ func (s *ReceiveOrderService) recordUpstreamFail(upstreamID uint64, upstreamName
 	if cnt == 1 {
 		dal.RedisClient.Expire(dal.RedisCtx, key, 5*time.Minute)
 	}
+	metrics.SetUpstreamFailCount("receive", upstreamCode, sysChannelCode, cnt)
 
 	// 通知逻辑
 	if cnt == 3 {
@@ -90,6 +105,7 @@ func (s *ReceiveOrderService) recordUpstreamFail(upstreamID uint64, upstreamName
 func (s *ReceiveOrderService) clearUpstreamFail(upstreamID uint64, upstreamCode, sysChannelCode string) {
 	key := fmt.Sprintf("%s%d:%s:%s", upstreamFailKey, upstreamID, upstreamCode, sysChannelCode)
 	dal.RedisClient.Del(dal.RedisCtx, key)
+	metrics.SetUpstreamFailCount("receive", upstreamCode, sysChannelCode, 0)
 }
 
 // 获取失败次数
@@ -134,6 +150,16 @@ func (s *ReceiveOrderService) Create(req dto.CreateOrderReq) (resp dto.CreateOrd
 		return resp, errors.New("amount format error")
 	}
 
+	// 虚拟币代收：pay_method 须与商户币种一致，金额精度不超过代币精度
+	if utils.IsCryptoCurrency(merchant.Currency) {
+		if err := utils.CheckCryptoPayMethod(merchant.Currency, req.PayMethod); err != nil {
+			return resp, err
+		}
+		if err := utils.CheckCryptoAmount(req.PayMethod, amount); err != nil {
+			return resp, err
+		}
+	}
+
 	// 通道信息
 	channelDetail, err := s.getSysChannelWithCache(req.PayType)
 	if err != nil || channelDetail == nil {
@@ -141,7 +167,7 @@ func (s *ReceiveOrderService) Create(req dto.CreateOrderReq) (resp dto.CreateOrd
 	}
 
 	// 商户通道信息
-	merchantChannelInfo, err := NewCommonService().GetMerchantChannelInfo(merchant.MerchantID, req.PayType)
+	merchantChannelInfo, err := s.commonSvc.GetMerchantChannelInfo(merchant.MerchantID, req.PayType)
 	if err != nil || merchantChannelInfo == nil {
 		return resp, errors.New("merchant channel invalid")
 	}
@@ -188,7 +214,13 @@ func (s *ReceiveOrderService) Create(req dto.CreateOrderReq) (resp dto.CreateOrd
 	}
 
 	// 结算计算
-	settle, err := s.calculateSettlement(merchant, products[0], amount)
+	settle, err := s.calculateSettlement(merchant, products[0], amount, req.PayMethod)
+	if err != nil {
+		return resp, err
+	}
+
+	// 累计限额（商户日/月、通道日），下单失败回滚
+	reservation, err := s.limitSvc.Reserve(1, merchant.MerchantID, channelDetail.Id, channelDetail.Currency, "", amount)
 	if err != nil {
 		return resp, err
 	}
@@ -197,13 +229,17 @@ func (s *ReceiveOrderService) Create(req dto.CreateOrderReq) (resp dto.CreateOrd
 	now := time.Now()
 	order, tx, err := s.createOrderAndTransaction(merchant, req, products[0], amount, oid, now, settle)
 	if err != nil {
+		s.limitSvc.Release(reservation)
 		return resp, err
 	}
+	// 预占绑定订单：支付成功保留，失败或超时未支付回滚
+	s.limitSvc.Bind(reservation, 1, order.OrderID)
+	metrics.OrderCreated("receive", req.PayType, channelDetail.Currency, req.MerchantNo)
 
 	// ================== 调用上游通道 ==================
 	var payUrl string
 	var lastErr error
-	for _, product := range products {
+	for i, product := range products {
 		payUrl, err = s.callUpstreamService(merchant, &req, &product, tx.UpOrderId)
 		if err == nil {
 			// 成功后清理
@@ -214,20 +250,22 @@ func (s *ReceiveOrderService) Create(req dto.CreateOrderReq) (resp dto.CreateOrd
 			)
 
 			// 异步更新订单绑定
-			go func(p dto.PayProductVo) {
-				if err := s.updateOrderBindOnSuccess(order, tx, merchant, p, amount, now); err != nil {
+			p := product
+			lifecycle.Go("receive-order-bind", func() {
+				if err := s.updateOrderBindOnSuccess(order, tx, merchant, p, amount, req.PayMethod, now); err != nil {
 					log.Printf("[ORDER-BIND-UPDATE] ❌ 更新订单绑定失败: orderID=%d, upstream=%s, err=%v", order.OrderID, p.UpstreamCode, err)
 					notify.Notify(system.BotChatID, "warn", "订单绑定更新失败",
 						fmt.Sprintf("⚠️ OrderID: %d\n上游: %s\n错误: %v", order.OrderID, p.UpstreamCode, err), true)
 				}
-			}(product)
+			})
 
 			// 异步更新通道成功率
-			go func(pid int64) {
+			pid := product.ID
+			lifecycle.Go("success-rate", func() {
 				if err := s.mainDao.UpdateSuccessRate(pid, true); err != nil {
 					log.Printf("[SUCCESS-RATE] ❌ 更新通道成功率失败: productID=%d, err=%v", pid, err)
 				}
-			}(product.ID)
+			})
 			break
 		}
 		// 当前上游失败
@@ -237,21 +275,31 @@ func (s *ReceiveOrderService) Create(req dto.CreateOrderReq) (resp dto.CreateOrd
 			product.UpstreamCode,
 			product.SysChannelCode, // ✅ 系统通道编码
 		)
-		go func(pid int64) {
+		pid := product.ID
+		lifecycle.Go("success-rate", func() {
 			if err := s.mainDao.UpdateSuccessRate(pid, false); err != nil {
 				log.Printf("[SUCCESS-RATE] ❌ 更新通道成功率失败: productID=%d, err=%v", pid, err)
 			}
-		}(product.ID)
+		})
 		lastErr = err
+
+		// 不可重试的上游错误（如收款信息被拒）切换通道也无意义
+		if !isRetryableUpstreamErr(err) {
+			log.Printf("[Upstream-Receive] 上游错误不可重试，停止切换通道: order=%d, upstream=%s, err=%v", order.OrderID, product.UpstreamCode, err)
+			break
+		}
+		if i < len(products)-1 {
+			metrics.UpstreamFailover("receive", req.PayType)
+		}
 	}
 
 	// 所有上游都失败
 	if payUrl == "" && lastErr != nil {
-		go func() {
+		s.limitSvc.Release(reservation)
+		lifecycle.Go("receive-order-fail", func() {
 			table := shard.OrderShard.GetTable(order.OrderID, now)
-			dal.OrderDB.Table(table).Where("order_id = ?", order.OrderID).
-				Updates(map[string]interface{}{"status": 5, "update_time": time.Now()})
-		}()
+			_ = s.orderDao.UpdateOrderFields(table, order.OrderID, map[string]interface{}{"status": 5, "update_time": time.Now()})
+		})
 		resp = dto.CreateOrderResp{
 			TranFlow: req.TranFlow, PaySerialNo: strconv.FormatUint(oid, 10),
 			Amount: req.Amount, Code: "001", SysTime: strconv.FormatInt(utils.GetTimestampMs(), 10),
@@ -259,11 +307,8 @@ func (s *ReceiveOrderService) Create(req dto.CreateOrderReq) (resp dto.CreateOrd
 		return resp, lastErr
 	}
 
-	go func() {
-		table := shard.OrderShard.GetTable(order.OrderID, now)
-		dal.OrderDB.Table(table).Where("order_id = ?", order.OrderID).
-			Updates(map[string]interface{}{"pay_address": payUrl, "update_time": time.Now()})
-	}()
+	// 异步回写支付地址，订单统计事件同事务写入发件箱
+	lifecycle.Go("receive-pay-address", func() { s.savePayAddressWithStat(order, payUrl, now) })
 	// 成功返回
 	resp = dto.CreateOrderResp{
 		TranFlow: req.TranFlow, PaySerialNo: strconv.FormatUint(oid, 10),
@@ -271,10 +316,10 @@ func (s *ReceiveOrderService) Create(req dto.CreateOrderReq) (resp dto.CreateOrd
 		SysTime: strconv.FormatInt(utils.GetTimestampMs(), 10), Yul1: payUrl,
 	}
 
-	// 异步缓存订单 & 发布统计消息
-	go s.asyncPostOrderCreation(oid, order, merchant.MerchantID, req.TranFlow, req.Amount, now)
-	// 异步发布订单统计事件
-	go s.publishOrderStat(order)
+	// 异步缓存订单
+	lifecycle.Go("receive-post-create", func() {
+		s.asyncPostOrderCreation(oid, order, merchant.MerchantID, req.TranFlow, req.Amount, now)
+	})
 
 	return resp, nil
 }
@@ -351,30 +396,23 @@ func (s *ReceiveOrderService) selectWeightedPollingChannel(
 	return filtered, nil
 }
 
-// publishOrderStat 异步发布订单统计事件
-func (s *ReceiveOrderService) publishOrderStat(ord *ordermodel.MerchantOrder) {
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("[order_stat panic recovered] %v\n%s", r, debug.Stack())
-			}
-		}()
-
-		// 查询国家信息
-		country, cErr := s.mainDao.GetCountry(ord.Currency)
-		if cErr != nil {
-			notify.Notify(system.BotChatID, "warn", "代收下单统计",
-				fmt.Sprintf("⚠️ order %v, 获取国家信息异常: err=%v currency=%v", ord.OrderID, cErr, ord.Currency), true)
-			return
-		}
-
-		if s.pub == nil {
-			log.Printf("[order_stat] publisher is nil, skip publish. order=%v", ord.OrderID)
-			return
+// savePayAddressWithStat 回写支付地址，订单统计事件在同一事务写入发件箱（由 outbox.Relay 投递）
+func (s *ReceiveOrderService) savePayAddressWithStat(ord *ordermodel.MerchantOrder, payUrl string, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[order_stat panic recovered] %v\n%s", r, debug.Stack())
 		}
+	}()
 
-		// 组装 MQ 消息体
-		msg := &dto.OrderMessageMQ{
+	// 查询国家信息（失败时仅回写支付地址，不写统计事件）
+	var evt *ordermodel.OutboxEventM
+	country, cErr := s.mainDao.GetCountry(ord.Currency)
+	if cErr != nil {
+		notify.Notify(system.BotChatID, "warn", "代收下单统计",
+			fmt.Sprintf("⚠️ order %v, 获取国家信息异常: err=%v currency=%v", ord.OrderID, cErr, ord.Currency), true)
+	} else {
+		var err error
+		evt, err = outbox.NewOrderStat(&dto.OrderMessageMQ{
 			OrderID:       strconv.FormatUint(ord.OrderID, 10),
 			MerchantID:    ord.MID,
 			CountryID:     country.ID,
@@ -389,17 +427,30 @@ func (s *ReceiveOrderService) publishOrderStat(ord *ordermodel.MerchantOrder) {
 			OrderType:     "collect",
 			Currency:      ord.Currency,
 			CreateTime:    time.Now(),
+		})
+		if err != nil {
+			log.Printf("[order_stat] 构造统计事件失败, order=%v: %v", ord.OrderID, err)
 		}
+	}
 
-		// 入列统计队列
-		if err := s.pub.Publish("order_stat", msg); err != nil {
-			notify.Notify(system.BotChatID, "warn", "代收下单统计",
-				fmt.Sprintf("⚠️ order %v, 统计数据入列失败: %v", ord.OrderID, err), true)
-			return
+	table := shard.OrderShard.GetTable(ord.OrderID, now)
+	err := s.orderDao.Transaction(func(orderDao dao.OrderRepository) error {
+		if err := orderDao.UpdateOrderFields(table, ord.OrderID, map[string]interface{}{"pay_address": payUrl, "update_time": time.Now()}); err != nil {
+			return err
 		}
-
-		log.Printf("[order_stat] 入列成功, order=%v, merchant=%v, channel=%v", ord.OrderID, ord.MID, ord.ChannelID)
-	}()
+		if evt == nil {
+			return nil
+		}
+		return orderDao.InsertOutbox(evt)
+	})
+	if err != nil {
+		notify.Notify(system.BotChatID, "warn", "代收下单统计",
+			fmt.Sprintf("⚠️ order %v, 回写支付地址/统计事件失败: %v", ord.OrderID, err), true)
+		return
+	}
+	if evt != nil {
+		log.Printf("[order_stat] 已写入发件箱, order=%v, event=%v, merchant=%v, channel=%v", ord.OrderID, evt.EventID, ord.MID, ord.ChannelID)
+	}
 }
 
 // updateOrderBindOnSuccess 成功后将订单与实际成功的通道产品进行绑定，并重新计算费用/利润/快照
@@ -409,10 +460,11 @@ func (s *ReceiveOrderService) updateOrderBindOnSuccess(
 	merchant *mainmodel.Merchant,
 	product dto.PayProductVo,
 	amount decimal.Decimal,
+	payMethod string,
 	now time.Time,
 ) error {
 	// 重新计算结算（包含代理信息、商户费率、成本费率等）
-	settle, err := s.calculateSettlement(merchant, product, amount)
+	settle, err := s.calculateSettlement(merchant, product, amount, payMethod)
 	if err != nil {
 		return fmt.Errorf("recalculate settlement failed: %w", err)
 	}
@@ -451,9 +503,7 @@ func (s *ReceiveOrderService) updateOrderBindOnSuccess(
 		"update_time":      now,
 	}
 
-	if err := dal.OrderDB.Table(orderTable).
-		Where("order_id = ?", order.OrderID).
-		Updates(updateOrder).Error; err != nil {
+	if err := s.orderDao.UpdateOrderFields(orderTable, order.OrderID, updateOrder); err != nil {
 		return fmt.Errorf("update order binding failed: %w", err)
 	}
 
@@ -465,9 +515,7 @@ func (s *ReceiveOrderService) updateOrderBindOnSuccess(
 			"currency":    product.Currency,
 			"update_time": now,
 		}
-		if err := dal.OrderDB.Table(txTable).
-			Where("order_id = ? AND up_order_id = ?", upTx.OrderID, upTx.UpOrderId).
-			Updates(updateTx).Error; err != nil {
+		if err := s.orderDao.UpdateTxFields(txTable, upTx.UpOrderId, updateTx); err != nil {
 			return fmt.Errorf("update upstream tx failed: %w", err)
 		}
 	}
@@ -654,38 +702,14 @@ func (s *ReceiveOrderService) checkIdempotency(merchantID uint64, tranFlow strin
 }
 
 // calculateSettlement 计算结算费用
-func (s *ReceiveOrderService) calculateSettlement(merchant *mainmodel.Merchant, payChannelProduct dto.PayProductVo, amount decimal.Decimal) (dto.SettlementResult, error) {
-	var agentPct, agentFixed = decimal.Zero, decimal.Zero
-
-	// 如果有代理商户，获取代理信息
-	if merchant.PId > 0 {
-		agentMerchant := dto.QueryAgentMerchant{
-			AId:          int64(merchant.PId),
-			MId:          int64(merchant.MerchantID),
-			SysChannelID: payChannelProduct.SysChannelID,
-			Currency:     payChannelProduct.Currency,
-		}
-
-		agentInfo, err := s.mainDao.GetAgentMerchant(agentMerchant)
-		if err == nil && agentInfo != nil && agentInfo.Status == 1 {
-			agentPct = agentInfo.DefaultRate
-			agentFixed = agentInfo.SingleFee
-		}
-	}
-
-	// 计算结算费用
-	settle := utils.Calculate(
+func (s *ReceiveOrderService) calculateSettlement(merchant *mainmodel.Merchant, payChannelProduct dto.PayProductVo, amount decimal.Decimal, payMethod string) (dto.SettlementResult, error) {
+	// 计算结算费用（多级代理分润，命中阶梯/交易量费率方案时按方案计价）
+	settle := s.feeSvc.Settle(
+		merchant,
+		payChannelProduct,
 		amount,
-		payChannelProduct.MDefaultRate,
-		payChannelProduct.MSingleFee,
-		agentPct,
-		agentFixed,
-		payChannelProduct.CostRate,
-		payChannelProduct.CostFee,
+		payMethod,
 		"agent_from_platform",
-		payChannelProduct.Currency,
-		payChannelProduct.MinFee,
-		payChannelProduct.HasMinFee,
 	)
 
 	return settle, nil
@@ -704,10 +728,8 @@ func (s *ReceiveOrderService) createOrderAndTransaction(
 	var order *ordermodel.MerchantOrder
 	var tx *ordermodel.UpstreamTx
 
-	err := dal.OrderDB.Transaction(func(txDB *gorm.DB) error {
-		// 事务内的 dao
-		orderDao := dao.NewOrderDaoWithDB(txDB)
-
+	err := s.orderDao.Transaction(func(orderDao dao.OrderRepository) error {
+		// orderDao 为事务内的 dao
 		// 创建订单
 		if err := s.createOrder(merchant, req, payChannelProduct, amount, oid, now, settle, orderDao); err != nil {
 			return err
@@ -756,7 +778,7 @@ func (s *ReceiveOrderService) createOrder(
 	oid uint64,
 	now time.Time,
 	settle dto.SettlementResult,
-	orderDao *dao.OrderDao, // 使用事务 Dao
+	orderDao dao.OrderRepository, // 使用事务 Dao
 ) error {
 	var orderSettle dto.SettlementResult
 	if err := copier.Copy(&orderSettle, &settle); err != nil {
@@ -818,7 +840,7 @@ func (s *ReceiveOrderService) createUpstreamTx(
 	payChannelProduct dto.PayProductVo,
 	oid uint64,
 	now time.Time,
-	orderDao *dao.OrderDao,
+	orderDao dao.OrderRepository,
 ) (*ordermodel.UpstreamTx, error) {
 	txId := idgen.New()
 	txTable := shard.UpOrderShard.GetTable(txId, now)
@@ -860,7 +882,7 @@ func (s *ReceiveOrderService) createOrderIndex(
 	req dto.CreateOrderReq,
 	oid uint64,
 	now time.Time,
-	orderDao *dao.OrderDao,
+	orderDao dao.OrderRepository,
 ) error {
 	receiveIndexTable := utils.GetOrderIndexTable("p_order_index", now)
 	orderLogIndexTable := shard.OrderLogShard.GetTable(oid, now)
@@ -901,13 +923,13 @@ func (s *ReceiveOrderService) callUpstreamService(
 		// 根据接平台银行编码查询平台银行信息
 		platformBank, pbErr := s.mainDao.QueryPlatformBankInfo(req.BankCode, merchant.Currency)
 		if pbErr != nil {
-			return "", fmt.Errorf(fmt.Sprintf("receive platform Bank code does not exist,%s", req.BankCode))
+			return "", fmt.Errorf("receive platform Bank code does not exist,%s", req.BankCode)
 		}
 		// 根据接口ID+平台银行编码+国家货币查询对应上游银行编码+银行名称
 		upstreamBank, ubErr := s.mainDao.QueryUpstreamBankInfo(payChannelProduct.InterfaceID, req.BankCode, payChannelProduct.Currency)
 		if ubErr != nil {
 			if payChannelProduct.InterfacePayVerifyBank > 0 {
-				return "", fmt.Errorf(fmt.Sprintf("receive upstream Bank code does not exist,%s", req.BankCode))
+				return "", fmt.Errorf("receive upstream Bank code does not exist,%s", req.BankCode)
 			} else {
 				bankCode = platformBank.Code
 				bankName = platformBank.Name
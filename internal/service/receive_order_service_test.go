@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/idgen"
+	mainmodel "wht-order-api/internal/model/main"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	testMerchantID  = uint64(1001)
+	testProductID   = int64(11)
+	testChannelCode = "BR_PIX"
+)
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(topic string, msg any) error { return nil }
+
+// fakeConnector 原生连接器替身，只实现代收下单
+type fakeConnector struct {
+	connector.Connector
+	createReceive func(req dto.UpstreamRequest) (*connector.OrderResult, error)
+}
+
+func (c *fakeConnector) CreateReceive(ctx context.Context, req dto.UpstreamRequest) (*connector.OrderResult, error) {
+	return c.createReceive(req)
+}
+
+// newReceiveService 商户 1001 开通 BR_PIX（轮询），挂一个 10-1000 BRL 的上游产品，接口编码对应 fake 连接器
+func newReceiveService(t *testing.T, interfaceCode string, conn connector.Connector) (*ReceiveOrderService, *memdao.MainStore, *memdao.OrderStore) {
+	t.Helper()
+	fakeredis.Use(t)
+	shard.InitShardEngines()
+	if err := idgen.InitNode("default", 1); err != nil {
+		t.Fatalf("init idgen: %v", err)
+	}
+	prevTimeout := config.C.Upstream.Timeout.Receive
+	config.C.Upstream.Timeout.Receive = 5 * time.Second
+	t.Cleanup(func() { config.C.Upstream.Timeout.Receive = prevTimeout })
+	connector.Register(interfaceCode, conn)
+
+	main := memdao.NewMainStore()
+	main.AddMerchant(mainmodel.Merchant{MerchantID: testMerchantID, NickName: "m1", AppId: "APP1001", ApiKey: "secret", Status: 1, Currency: "BRL"})
+	main.AddCountry(dto.CurrencyCodeResponse{ID: 76, Code: "BRL", Country: "Brazil"})
+	main.AddSysChannel(dto.PayWayVo{Id: 7, Title: "PIX", Currency: "BRL", Coding: testChannelCode, Type: 1, Status: 1})
+	main.AddMerchantChannel(dto.MerchantChannelDTO{
+		MId: testMerchantID, SysChannelId: 7, Status: 1, Type: 1, DispatchMode: 1, Currency: "BRL",
+		SysChannelCode: testChannelCode, DefaultRate: decimal.NewFromInt(3),
+	})
+	minAmount, maxAmount := decimal.NewFromInt(10), decimal.NewFromInt(1000)
+	main.AddPayProduct(testMerchantID, dto.PayProductVo{
+		ID: testProductID, Currency: "BRL", Type: 1, Status: 1,
+		UpstreamId: 301, UpstreamCode: "UP_PIX", UpstreamTitle: "up1", UpstreamWeight: 10, InterfaceCode: interfaceCode,
+		SysChannelID: 7, SysChannelCode: testChannelCode, SysChannelTitle: "PIX",
+		MDefaultRate: decimal.NewFromInt(3), CostRate: decimal.NewFromInt(1),
+		MinAmount: &minAmount, MaxAmount: &maxAmount,
+	})
+
+	orders := memdao.NewOrderStore()
+	svc := NewReceiveOrderServiceWithRepos(nopPublisher{}, main, orders, orders.Index)
+	t.Cleanup(svc.Shutdown)
+	return svc, main, orders
+}
+
+func createReq(tranFlow, amount string) dto.CreateOrderReq {
+	return dto.CreateOrderReq{
+		MerchantNo: "APP1001",
+		TranFlow:   tranFlow,
+		Amount:     amount,
+		PayType:    testChannelCode,
+		NotifyUrl:  "http://127.0.0.1/notify",
+	}
+}
+
+// waitFor 等待异步 goroutine 落库
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReceiveCreateSuccess(t *testing.T) {
+	var gotReq dto.UpstreamRequest
+	svc, main, orders := newReceiveService(t, "test_receive_ok", &fakeConnector{
+		createReceive: func(req dto.UpstreamRequest) (*connector.OrderResult, error) {
+			gotReq = req
+			return &connector.OrderResult{UpOrderNo: "UP-1", PayUrl: "https://pay.example/1"}, nil
+		},
+	})
+
+	resp, err := svc.Create(createReq("M-1", "100"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if resp.Code != "0" || resp.Status != "0001" || resp.Yul1 != "https://pay.example/1" {
+		t.Fatalf("resp = %+v", resp)
+	}
+
+	all := orders.Orders()
+	if len(all) != 1 {
+		t.Fatalf("orders = %d, want 1", len(all))
+	}
+	o := all[0]
+	if strconv.FormatUint(o.OrderID, 10) != resp.PaySerialNo || o.MOrderID != "M-1" || o.Status != 1 {
+		t.Errorf("order = %+v", o)
+	}
+	// 商户费率 3%：手续费 3，到账 97；上游成本 1%，利润 2
+	if !o.Fees.Equal(decimal.NewFromInt(3)) || !o.Profit.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("fees/profit = %s/%s, want 3/2", o.Fees, o.Profit)
+	}
+	if !o.SettleSnapshot.MerchantRecv.Equal(decimal.NewFromInt(97)) {
+		t.Errorf("settle snapshot merchantRecv = %s, want 97", o.SettleSnapshot.MerchantRecv)
+	}
+
+	txs := orders.Txs()
+	if len(txs) != 1 {
+		t.Fatalf("txs = %d, want 1", len(txs))
+	}
+	tx := txs[0]
+	if tx.OrderID != o.OrderID || tx.UpOrderNo != "UP-1" || o.UpOrderID == nil || *o.UpOrderID != tx.UpOrderId {
+		t.Errorf("tx = %+v, order.upOrderId = %v", tx, o.UpOrderID)
+	}
+	if gotReq.MchOrderId != strconv.FormatUint(tx.UpOrderId, 10) || gotReq.Mode != "receive" || gotReq.DownstreamOrderNo != "M-1" {
+		t.Errorf("upstream request = %+v", gotReq)
+	}
+
+	idx, err := orders.Index.GetByIndexTable(utils.GetOrderIndexTable("p_order_index", time.Now()), "M-1", testMerchantID)
+	if err != nil || idx == nil || idx.OrderID != o.OrderID {
+		t.Errorf("receive index = %+v, err %v", idx, err)
+	}
+
+	waitFor(t, "success rate", func() bool {
+		ok, _ := main.SuccessRate(testProductID)
+		return ok == 1
+	})
+	// 收银台地址与下单统计事件同事务落库
+	waitFor(t, "order_stat outbox", func() bool { return len(orders.Outbox.Events()) == 1 })
+	if evt := orders.Outbox.Events()[0]; evt.Topic != "order_stat" || evt.AggregateID != o.OrderID || evt.EventID == "" {
+		t.Errorf("outbox event = %+v", evt)
+	}
+}
+
+func TestReceiveCreateUpstreamFail(t *testing.T) {
+	svc, main, orders := newReceiveService(t, "test_receive_fail", &fakeConnector{
+		createReceive: func(req dto.UpstreamRequest) (*connector.OrderResult, error) {
+			return nil, errors.New("connection reset by peer")
+		},
+	})
+
+	resp, err := svc.Create(createReq("M-2", "100"))
+	if err == nil {
+		t.Fatal("create: want upstream error")
+	}
+	var ue *UpstreamError
+	if !errors.As(err, &ue) || !ue.Retryable {
+		t.Errorf("err = %v, want retryable *UpstreamError", err)
+	}
+	if resp.Code != "001" || resp.PaySerialNo == "" {
+		t.Errorf("resp = %+v", resp)
+	}
+
+	// 订单已落库，上游全部失败后异步标记为下单失败（status=5）
+	waitFor(t, "order status 5", func() bool {
+		all := orders.Orders()
+		return len(all) == 1 && all[0].Status == 5
+	})
+	waitFor(t, "fail rate", func() bool {
+		_, fail := main.SuccessRate(testProductID)
+		return fail == 1
+	})
+}
+
+func TestReceiveCreateRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		req  dto.CreateOrderReq
+	}{
+		{"缺少商户订单号", createReq("", "100")},
+		{"商户不存在", func() dto.CreateOrderReq { r := createReq("M-3", "100"); r.MerchantNo = "APP404"; return r }()},
+		{"通道未开通", func() dto.CreateOrderReq { r := createReq("M-3", "100"); r.PayType = "BR_BOLETO"; return r }()},
+		{"金额超出通道范围", createReq("M-3", "5000")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			svc, _, orders := newReceiveService(t, "test_receive_rejected", &fakeConnector{
+				createReceive: func(req dto.UpstreamRequest) (*connector.OrderResult, error) {
+					called = true
+					return &connector.OrderResult{PayUrl: "https://pay.example/x"}, nil
+				},
+			})
+
+			if _, err := svc.Create(tt.req); err == nil {
+				t.Fatal("create: want error")
+			}
+			if called {
+				t.Error("upstream must not be called")
+			}
+			if n := len(orders.Orders()); n != 0 {
+				t.Errorf("orders = %d, want 0", n)
+			}
+		})
+	}
+}
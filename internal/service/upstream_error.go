@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/metrics"
+	mainmodel "wht-order-api/internal/model/main"
+	"wht-order-api/internal/utils"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const upstreamErrMapCachePrefix = "upstream_err_map:" // upstream_err_map:{接口编码}
+
+// UpstreamError 上游调用失败（已映射为平台错误码，对商户返回 Code）
+type UpstreamError struct {
+	Code         int    // 平台错误码（3xxx/4xxx）
+	Retryable    bool   // 是否可重试/切换下一个通道
+	Unknown      bool   // 请求可能已被上游受理、结果未知（代付需查单或人工确认，不得切换通道）
+	UpstreamCode string // 上游原始返回码
+	UpstreamMsg  string // 上游原始返回信息
+	Err          error  // 底层错误
+}
+
+func (e *UpstreamError) Error() string {
+	msg := fmt.Sprintf("上游错误[%d]", e.Code)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.UpstreamCode != "" || e.UpstreamMsg != "" {
+		msg += fmt.Sprintf(" (上游code=%s, msg=%s)", e.UpstreamCode, e.UpstreamMsg)
+	}
+	return msg
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// isRetryableUpstreamErr 是否继续切换下一个通道；非 UpstreamError（如本地银行编码映射缺失）视为可切换
+func isRetryableUpstreamErr(err error) bool {
+	var ue *UpstreamError
+	if errors.As(err, &ue) {
+		return ue.Retryable
+	}
+	return true
+}
+
+// isUpstreamBalanceLow 上游余额不足（下单前余额检查，或上游返回码经映射为 CodeUpstreamBalanceInsufficient）
+func isUpstreamBalanceLow(err error) bool {
+	var ue *UpstreamError
+	return errors.As(err, &ue) && ue.Code == constant.CodeUpstreamBalanceInsufficient
+}
+
+// observeUpstreamCall 上游下单耗时与结果指标（结果为 success 或映射后的平台错误码）
+func observeUpstreamCall(mode string, req dto.UpstreamRequest, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+		var ue *UpstreamError
+		if errors.As(err, &ue) {
+			result = strconv.Itoa(ue.Code)
+		}
+	}
+	metrics.ObserveUpstream(mode, req.ProviderKey, req.UpstreamCode, result, time.Since(start))
+}
+
+// classifyTransportError 请求上游失败（无业务响应）时的错误分类
+func classifyTransportError(err error) *UpstreamError {
+	ue := &UpstreamError{Code: constant.CodeUpstreamNetworkError, Retryable: true, Err: fmt.Errorf("请求上游失败: %w", err)}
+
+	var netErr net.Error
+	var statusErr *utils.HTTPStatusError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		ue.Code = constant.CodeUpstreamTimeout
+	case errors.As(err, &statusErr):
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			ue.Code = constant.CodeUpstreamRateLimit
+		case statusErr.StatusCode == http.StatusServiceUnavailable:
+			ue.Code = constant.CodeUpstreamMaintenance
+		case statusErr.StatusCode >= 400 && statusErr.StatusCode < 500:
+			// 请求本身不被接受，换通道也一样
+			ue.Code, ue.Retryable = constant.CodeUpstreamDataFormatError, false
+		default:
+			ue.Code = constant.CodeUpstreamError
+		}
+	}
+	return ue
+}
+
+// classifyPayoutTransportError 代付请求上游失败时的错误分类：
+// 请求可能已送达上游（超时、连接中断、5xx）时结果未知，重试或切换通道都可能重复出款
+func classifyPayoutTransportError(err error) *UpstreamError {
+	ue := classifyTransportError(err)
+	if !ue.Retryable || requestNotDelivered(err) {
+		return ue
+	}
+	switch ue.Code {
+	case constant.CodeUpstreamRateLimit, constant.CodeUpstreamMaintenance:
+		// 429/503 上游明确未受理
+		return ue
+	}
+	ue.Retryable, ue.Unknown = false, true
+	return ue
+}
+
+// requestNotDelivered 请求确定未送达上游（建连失败、域名解析失败）
+func requestNotDelivered(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// isUnknownOutcomeErr 上游结果未知（代付已可能出款）
+func isUnknownOutcomeErr(err error) bool {
+	var ue *UpstreamError
+	return errors.As(err, &ue) && ue.Unknown
+}
+
+// retryableTransport DoWithRetry 内使用：不可重试的 HTTP 错误直接返回
+func retryableTransport(err error) error {
+	if err == nil || classifyTransportError(err).Retryable {
+		return err
+	}
+	return utils.Permanent(err)
+}
+
+// retryablePayoutTransport 代付 DoWithRetry 内使用：结果未知的请求不再重发
+func retryablePayoutTransport(err error) error {
+	if err == nil || classifyPayoutTransportError(err).Retryable {
+		return err
+	}
+	return utils.Permanent(err)
+}
+
+// upstreamErrorMapper 按接口编码将上游返回码/信息映射为平台错误码（w_upstream_error_mapping，缓存 5 分钟）
+type upstreamErrorMapper struct {
+	once    sync.Once
+	mainDao dao.MainRepository
+	group   singleflight.Group
+}
+
+var upstreamErrMapper = &upstreamErrorMapper{}
+
+func (m *upstreamErrorMapper) mappings(interfaceCode string) ([]mainmodel.UpstreamErrorMapping, error) {
+	cacheKey := upstreamErrMapCachePrefix + interfaceCode
+	result, err, _ := m.group.Do(cacheKey, func() (interface{}, error) {
+		cached, err := dal.RedisClient.Get(dal.RedisCtx, cacheKey).Result()
+		if err == nil && cached != "" {
+			var list []mainmodel.UpstreamErrorMapping
+			if err := utils.JSONToMap(cached, &list); err == nil {
+				return list, nil
+			}
+		}
+
+		m.once.Do(func() { m.mainDao = dao.NewMainDao() })
+		list, err := m.mainDao.ListUpstreamErrorMappings(interfaceCode)
+		if err != nil {
+			return nil, err
+		}
+		if listJSON := utils.MapToJSON(list); listJSON != "" {
+			dal.RedisClient.Set(dal.RedisCtx, cacheKey, listJSON, 5*time.Minute)
+		}
+		return list, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]mainmodel.UpstreamErrorMapping), nil
+}
+
+// matchUpstreamErrorMapping 按顺序返回第一条命中的映射（返回码、关键字均为空的规则忽略）
+func matchUpstreamErrorMapping(list []mainmodel.UpstreamErrorMapping, upstreamCode, upstreamMsg string) *mainmodel.UpstreamErrorMapping {
+	msg := strings.ToLower(upstreamMsg)
+	for i := range list {
+		rule := &list[i]
+		if rule.UpstreamCode == "" && rule.MsgKeyword == "" {
+			continue
+		}
+		if rule.UpstreamCode != "" && rule.UpstreamCode != upstreamCode {
+			continue
+		}
+		if rule.MsgKeyword != "" && !strings.Contains(msg, strings.ToLower(rule.MsgKeyword)) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// mapUpstreamError 将上游业务失败映射为 UpstreamError；未配置映射时按上游拒绝处理并允许切换通道
+func mapUpstreamError(interfaceCode, upstreamCode, upstreamMsg string, err error) *UpstreamError {
+	ue := &UpstreamError{
+		Code:         constant.CodeUpstreamRejected,
+		Retryable:    true,
+		UpstreamCode: upstreamCode,
+		UpstreamMsg:  upstreamMsg,
+		Err:          err,
+	}
+
+	list, lErr := upstreamErrMapper.mappings(interfaceCode)
+	if lErr != nil {
+		log.Printf("[Upstream-Error-Map] ⚠️ 加载错误码映射失败, interface=%s, err=%v", interfaceCode, lErr)
+		return ue
+	}
+	if rule := matchUpstreamErrorMapping(list, upstreamCode, upstreamMsg); rule != nil {
+		ue.Code = rule.Code
+		ue.Retryable = rule.Retryable == 1
+	}
+	return ue
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/utils"
+)
+
+func TestClassifyPayoutTransportError(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+
+	cases := []struct {
+		name      string
+		err       error
+		code      int
+		retryable bool
+		unknown   bool
+	}{
+		{"timeout", fmt.Errorf("HTTP请求失败: %w", context.DeadlineExceeded), constant.CodeUpstreamTimeout, false, true},
+		{"connection reset", fmt.Errorf("HTTP请求失败: %w", readErr), constant.CodeUpstreamNetworkError, false, true},
+		{"5xx", &utils.HTTPStatusError{StatusCode: 502}, constant.CodeUpstreamError, false, true},
+		{"dial refused", fmt.Errorf("HTTP请求失败: %w", dialErr), constant.CodeUpstreamNetworkError, true, false},
+		{"dns", &net.DNSError{Err: "no such host", Name: "pay.example"}, constant.CodeUpstreamNetworkError, true, false},
+		{"rate limit", &utils.HTTPStatusError{StatusCode: 429}, constant.CodeUpstreamRateLimit, true, false},
+		{"maintenance", &utils.HTTPStatusError{StatusCode: 503}, constant.CodeUpstreamMaintenance, true, false},
+		{"4xx", &utils.HTTPStatusError{StatusCode: 400}, constant.CodeUpstreamDataFormatError, false, false},
+	}
+	for _, c := range cases {
+		ue := classifyPayoutTransportError(c.err)
+		if ue.Code != c.code || ue.Retryable != c.retryable || ue.Unknown != c.unknown {
+			t.Errorf("%s: got code=%d retryable=%v unknown=%v, want %d/%v/%v",
+				c.name, ue.Code, ue.Retryable, ue.Unknown, c.code, c.retryable, c.unknown)
+		}
+	}
+
+	// 代收不受影响：超时仍可切换通道
+	if ue := classifyTransportError(context.DeadlineExceeded); !ue.Retryable || ue.Unknown {
+		t.Fatalf("receive timeout: retryable=%v unknown=%v", ue.Retryable, ue.Unknown)
+	}
+	if !isUnknownOutcomeErr(fmt.Errorf("wrap: %w", classifyPayoutTransportError(context.DeadlineExceeded))) {
+		t.Fatal("wrapped unknown outcome not detected")
+	}
+	if isUnknownOutcomeErr(errors.New("plain")) {
+		t.Fatal("plain error treated as unknown outcome")
+	}
+}
+
+func TestIsUpstreamBalanceLow(t *testing.T) {
+	// 上游返回码映射为余额不足时不包含哨兵错误，按错误码判断
+	mapped := &UpstreamError{Code: constant.CodeUpstreamBalanceInsufficient, Retryable: true, UpstreamCode: "E102", Err: errors.New("交易失败")}
+	if !isUpstreamBalanceLow(fmt.Errorf("wrap: %w", mapped)) {
+		t.Fatal("mapped balance error not detected")
+	}
+	if !isUpstreamBalanceLow(&UpstreamError{Code: constant.CodeUpstreamBalanceInsufficient, Err: ErrUpstreamBalanceInsufficient}) {
+		t.Fatal("pre-check balance error not detected")
+	}
+	if isUpstreamBalanceLow(&UpstreamError{Code: constant.CodeUpstreamRejected}) || isUpstreamBalanceLow(ErrUpstreamBalanceInsufficient) {
+		t.Fatal("non-UpstreamError or other code treated as balance low")
+	}
+}
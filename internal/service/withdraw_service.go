@@ -0,0 +1,630 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"wht-order-api/internal/beneficiary"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/event"
+	"wht-order-api/internal/idgen"
+	"wht-order-api/internal/lifecycle"
+	"wht-order-api/internal/metrics"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/notify"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/system"
+	"wht-order-api/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+const withdrawNotifyMax = 3
+
+// WithdrawError 提现业务错误（携带错误码）
+type WithdrawError struct {
+	Code int
+	Msg  string
+}
+
+func (e *WithdrawError) Error() string {
+	return e.Msg
+}
+
+func withdrawErr(code int, format string, args ...interface{}) *WithdrawError {
+	return &WithdrawError{Code: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+// WithdrawService 商户提现服务：资金冻结走 FreezePayout，出款复用代付上游通道
+type WithdrawService struct {
+	payoutSvc   *PayoutOrderService
+	mainDao     dao.MainRepository
+	withdrawDao dao.WithdrawRepository
+}
+
+func NewWithdrawService(pub event.Publisher) *WithdrawService {
+	payoutSvc := NewPayoutOrderService(pub)
+	return &WithdrawService{
+		payoutSvc:   payoutSvc,
+		mainDao:     payoutSvc.mainDao,
+		withdrawDao: dao.NewWithdrawDao(),
+	}
+}
+
+// NewWithdrawServiceWithRepos 注入仓储（单元测试使用内存实现）
+func NewWithdrawServiceWithRepos(pub event.Publisher, mainRepo dao.MainRepository, payoutRepo dao.PayoutOrderRepository, indexRepo dao.IndexTableRepository, withdrawRepo dao.WithdrawRepository) *WithdrawService {
+	return &WithdrawService{
+		payoutSvc:   NewPayoutOrderServiceWithRepos(pub, mainRepo, payoutRepo, indexRepo),
+		mainDao:     mainRepo,
+		withdrawDao: withdrawRepo,
+	}
+}
+
+// calcWithdrawFee 提现手续费 = 金额×费率% + 固定费用，并限制在 [min_fee, max_fee] 之间
+func calcWithdrawFee(rule *mainmodel.MerchantWithdrawFee, amount decimal.Decimal) decimal.Decimal {
+	fee := amount.Mul(rule.FeeRate).Div(decimal.NewFromInt(100)).Add(rule.FixedFee)
+	if fee.LessThan(rule.MinFee) {
+		fee = rule.MinFee
+	}
+	if rule.MaxFee.GreaterThan(decimal.Zero) && fee.GreaterThan(rule.MaxFee) {
+		fee = rule.MaxFee
+	}
+	return fee.Round(2)
+}
+
+func toWithdrawDestinationVo(d mainmodel.MerchantWithdrawDestination) dto.WithdrawDestinationVo {
+	return dto.WithdrawDestinationVo{
+		DestinationId: strconv.FormatUint(d.ID, 10),
+		Currency:      d.Currency,
+		Label:         d.Label,
+		AccNo:         d.AccountNo,
+		AccName:       d.AccountName,
+		BankCode:      d.BankCode,
+		BankName:      d.BankName,
+		PayMethod:     d.PayMethod,
+		Status:        strconv.Itoa(int(d.Status)),
+		ActiveAt:      d.ActiveAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// SaveDestination 新增/修改提现账户；账户信息变更或重新启用后进入冷却期
+func (s *WithdrawService) SaveDestination(req dto.WithdrawDestinationSaveReq) (dto.WithdrawDestinationVo, error) {
+	var resp dto.WithdrawDestinationVo
+	merchant, err := s.mainDao.GetMerchant(req.MerchantNo)
+	if err != nil || merchant == nil || merchant.Status != 1 {
+		return resp, withdrawErr(constant.CodeMerchantAbnormal, "merchant invalid")
+	}
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	if bErr := beneficiary.Check(s.mainDao, beneficiary.Account{
+		Currency:    currency,
+		PayMethod:   req.PayMethod,
+		AccNo:       req.AccNo,
+		AccName:     req.AccName,
+		BankCode:    req.BankCode,
+		CciNo:       req.CciNo,
+		AccountType: req.AccountType,
+		IdentityNum: req.IdentityNum,
+	}); bErr != nil {
+		return resp, withdrawErr(constant.CodeCardInvalid, "%v", bErr)
+	}
+	status := int8(1)
+	if req.Status == "0" {
+		status = 0
+	}
+
+	now := time.Now()
+	dest := &mainmodel.MerchantWithdrawDestination{MID: merchant.MerchantID, CreateTime: now}
+	if req.DestinationId != "" {
+		id, pErr := strconv.ParseUint(req.DestinationId, 10, 64)
+		if pErr != nil {
+			return resp, withdrawErr(constant.CodeWithdrawDestinationNotFound, "destination not found")
+		}
+		if dest, err = s.mainDao.GetWithdrawDestination(merchant.MerchantID, id); err != nil {
+			return resp, err
+		}
+		if dest == nil {
+			return resp, withdrawErr(constant.CodeWithdrawDestinationNotFound, "destination not found")
+		}
+	}
+
+	changed := dest.ID == 0 ||
+		dest.Currency != currency ||
+		dest.AccountNo != req.AccNo ||
+		dest.AccountName != req.AccName ||
+		dest.BankCode != req.BankCode ||
+		dest.PayMethod != req.PayMethod ||
+		dest.CciNo != req.CciNo ||
+		(dest.Status == 0 && status == 1)
+
+	dest.Currency = currency
+	dest.Label = req.Label
+	dest.AccountNo = req.AccNo
+	dest.AccountName = req.AccName
+	dest.BankCode = req.BankCode
+	dest.BankName = req.BankName
+	dest.PayMethod = req.PayMethod
+	dest.AccountType = req.AccountType
+	dest.CciNo = req.CciNo
+	dest.IdentityNum = req.IdentityNum
+	dest.Status = status
+	dest.UpdateTime = now
+	if changed {
+		dest.ActiveAt = now.Add(config.C.Withdraw.CoolDown)
+	}
+	if err := s.mainDao.SaveWithdrawDestination(dest); err != nil {
+		return resp, fmt.Errorf("save withdraw destination failed: %w", err)
+	}
+
+	if changed {
+		log.Printf("[WITHDRAW] 提现账户变更 商户=%s 账户ID=%d 冷却至=%s", req.MerchantNo, dest.ID, dest.ActiveAt.Format(time.RFC3339))
+		notify.Notify(system.BotChatID, "warn", "商户提现账户变更",
+			fmt.Sprintf("商户号: `%s`\n商户名称: `%s`\n账户ID: `%d`\n币种: `%s`\n收款账号: `%s`\n收款人: `%s`\n冷却至: `%s`",
+				req.MerchantNo, merchant.NickName, dest.ID, currency, req.AccNo, req.AccName, dest.ActiveAt.Format("2006-01-02 15:04:05")), true)
+	}
+	return toWithdrawDestinationVo(*dest), nil
+}
+
+// ListDestinations 商户提现账户列表
+func (s *WithdrawService) ListDestinations(req dto.WithdrawDestinationListReq) ([]dto.WithdrawDestinationVo, error) {
+	merchant, err := s.mainDao.GetMerchant(req.MerchantNo)
+	if err != nil || merchant == nil || merchant.Status != 1 {
+		return nil, withdrawErr(constant.CodeMerchantAbnormal, "merchant invalid")
+	}
+	list, err := s.mainDao.ListWithdrawDestinations(merchant.MerchantID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]dto.WithdrawDestinationVo, 0, len(list))
+	for _, d := range list {
+		resp = append(resp, toWithdrawDestinationVo(d))
+	}
+	return resp, nil
+}
+
+// Create 商户提现下单：校验账户与冷却期 → 计算手续费 → 冻结资金 → 提交代付上游
+func (s *WithdrawService) Create(req dto.CreateWithdrawReq) (dto.CreateWithdrawResp, error) {
+	var resp dto.CreateWithdrawResp
+	if !config.C.Withdraw.Enabled {
+		return resp, withdrawErr(constant.CodeWithdrawDisabled, "withdraw disabled")
+	}
+
+	// 1 商户
+	merchant, err := s.payoutSvc.getMerchantWithCache(req.MerchantNo)
+	if err != nil || merchant == nil || merchant.Status != 1 {
+		return resp, withdrawErr(constant.CodeMerchantAbnormal, "merchant invalid")
+	}
+
+	// 2 金额
+	amount, err := decimal.NewFromString(strings.TrimSpace(req.Amount))
+	if err != nil || amount.LessThanOrEqual(decimal.Zero) {
+		return resp, withdrawErr(constant.CodeWithdrawAmountInvalid, "amount invalid")
+	}
+
+	// 3 提现账户 + 冷却期
+	destId, err := strconv.ParseUint(req.DestinationId, 10, 64)
+	if err != nil {
+		return resp, withdrawErr(constant.CodeWithdrawDestinationNotFound, "destination not found")
+	}
+	dest, err := s.mainDao.GetWithdrawDestination(merchant.MerchantID, destId)
+	if err != nil {
+		return resp, err
+	}
+	if dest == nil || dest.Status != 1 {
+		return resp, withdrawErr(constant.CodeWithdrawDestinationNotFound, "destination not found")
+	}
+	now := time.Now()
+	if now.Before(dest.ActiveAt) {
+		return resp, withdrawErr(constant.CodeWithdrawDestinationCooling, "destination in cool-down until %s", dest.ActiveAt.Format("2006-01-02 15:04:05"))
+	}
+
+	// 4 通道币种必须与提现账户一致
+	channelDetail, err := s.payoutSvc.getSysChannelWithCache(req.PayType)
+	if err != nil || channelDetail == nil {
+		return resp, withdrawErr(constant.CodeChannelNotFound, "channel invalid")
+	}
+	currency := channelDetail.Currency
+	if !strings.EqualFold(currency, dest.Currency) {
+		return resp, withdrawErr(constant.CodeWithdrawCurrencyMismatch, "destination currency %s does not match channel currency %s", dest.Currency, currency)
+	}
+
+	// 5 手续费规则
+	rule, err := s.mainDao.GetWithdrawFeeRule(merchant.MerchantID, currency)
+	if err != nil {
+		return resp, err
+	}
+	if rule == nil {
+		return resp, withdrawErr(constant.CodeWithdrawFeeRuleMissing, "withdraw fee rule not configured for %s", currency)
+	}
+	if amount.LessThan(rule.MinAmount) || (rule.MaxAmount.GreaterThan(decimal.Zero) && amount.GreaterThan(rule.MaxAmount)) {
+		return resp, withdrawErr(constant.CodeWithdrawAmountInvalid, "amount must be between %s and %s", rule.MinAmount.StringFixed(2), rule.MaxAmount.StringFixed(2))
+	}
+	fee := calcWithdrawFee(rule, amount)
+
+	// 6 幂等
+	if idx, iErr := s.withdrawDao.GetIndex(merchant.MerchantID, req.WithdrawNo); iErr != nil {
+		return resp, iErr
+	} else if idx != nil {
+		return resp, withdrawErr(constant.CodeWithdrawAlreadyExist, "withdraw_no already exists")
+	}
+
+	// 7 选择上游通道（与代付一致的调度模式）
+	products, err := s.payoutSvc.selectPayoutProducts(merchant, req.PayType, currency, amount)
+	if err != nil {
+		return resp, withdrawErr(constant.CodeChannelUnavailable, "%v", err)
+	}
+
+	// 8 创建提现订单 + 上游交易 + 索引，并冻结资金
+	order, upTx, err := s.createWithdrawOrder(merchant, req, dest, products[0], amount, fee, now)
+	if err != nil {
+		return resp, err
+	}
+
+	// 9 提交上游（失败降级）
+	s.submitToUpstreams(merchant, req, dest, products, order, upTx)
+
+	resp = dto.CreateWithdrawResp{
+		Code:             "0",
+		Msg:              "成功",
+		Status:           utils.ConvertOrderStatus(ordermodel.WithdrawStatusProcessing),
+		WithdrawNo:       req.WithdrawNo,
+		WithdrawSerialNo: strconv.FormatUint(order.OrderID, 10),
+		Currency:         currency,
+		Amount:           amount.StringFixed(2),
+		Fee:              fee.StringFixed(2),
+		SysTime:          strconv.FormatInt(utils.GetTimestampMs(), 10),
+	}
+	return resp, nil
+}
+
+// withdrawCost 上游出款成本
+func withdrawCost(product dto.PayProductVo, amount decimal.Decimal) decimal.Decimal {
+	return amount.Mul(product.CostRate).Div(decimal.NewFromInt(100)).Add(product.CostFee)
+}
+
+// createWithdrawOrder 事务内创建提现订单、上游交易、索引并冻结资金（冻结失败整体回滚）
+func (s *WithdrawService) createWithdrawOrder(
+	merchant *mainmodel.Merchant,
+	req dto.CreateWithdrawReq,
+	dest *mainmodel.MerchantWithdrawDestination,
+	product dto.PayProductVo,
+	amount, fee decimal.Decimal,
+	now time.Time,
+) (*ordermodel.WithdrawOrderM, *ordermodel.PayoutUpstreamTxM, error) {
+	oid := idgen.New()
+	txId := idgen.New()
+	orderTable := shard.WithdrawShard.GetTable(oid, now)
+	cost := withdrawCost(product, amount)
+
+	order := &ordermodel.WithdrawOrderM{
+		OrderID:       oid,
+		MID:           merchant.MerchantID,
+		WithdrawNo:    req.WithdrawNo,
+		DestinationID: dest.ID,
+		Currency:      product.Currency,
+		Amount:        amount,
+		Fee:           fee,
+		FreezeAmount:  amount.Add(fee),
+		Cost:          cost,
+		Profit:        fee.Sub(cost),
+		PayType:       req.PayType,
+		AccountNo:     dest.AccountNo,
+		AccountName:   dest.AccountName,
+		BankCode:      dest.BankCode,
+		PayMethod:     dest.PayMethod,
+		SupplierID:    product.UpstreamId,
+		UpChannelID:   product.ID,
+		UpOrderID:     &txId,
+		Status:        ordermodel.WithdrawStatusProcessing,
+		NotifyURL:     req.NotifyUrl,
+		ClientIP:      req.ClientId,
+		CreateTime:    now,
+		UpdateTime:    &now,
+	}
+	upTx := &ordermodel.PayoutUpstreamTxM{
+		UpOrderId:  txId,
+		OrderID:    oid,
+		MerchantID: strconv.FormatUint(merchant.MerchantID, 10),
+		SupplierId: uint64(product.UpstreamId),
+		Amount:     amount,
+		Currency:   product.Currency,
+		Status:     0,
+		CreateTime: &now,
+	}
+
+	err := s.withdrawDao.Transaction(func(repo dao.WithdrawRepository) error {
+		if err := repo.Insert(orderTable, order); err != nil {
+			return fmt.Errorf("insert withdraw order failed[创建提现订单失败]: %w", err)
+		}
+		if err := repo.InsertTx(shard.UpOutOrderShard.GetTable(txId, now), upTx); err != nil {
+			return fmt.Errorf("insert transaction failed[创建交易订单失败]: %w", err)
+		}
+		if err := repo.InsertIndex(&ordermodel.WithdrawOrderIndexM{
+			MID:            merchant.MerchantID,
+			WithdrawNo:     req.WithdrawNo,
+			OrderID:        oid,
+			OrderTableName: orderTable,
+			CreateTime:     now,
+		}); err != nil {
+			return withdrawErr(constant.CodeWithdrawAlreadyExist, "withdraw_no already exists")
+		}
+		// 与代付同一冻结路径：到账金额 + 手续费
+		if err := s.mainDao.FreezePayout(merchant.MerchantID, product.Currency, strconv.FormatUint(oid, 10), req.WithdrawNo, order.FreezeAmount, merchant.NickName); err != nil {
+			return withdrawErr(constant.CodeMerchantBalanceLow, "freeze merchant money failed[冻结商户金额失败]: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, upTx, nil
+}
+
+// submitToUpstreams 按通道顺序提交上游（不可重试错误停止切换），全部失败时转人工（资金保持冻结，避免上游实际出款后重复出款）
+func (s *WithdrawService) submitToUpstreams(
+	merchant *mainmodel.Merchant,
+	req dto.CreateWithdrawReq,
+	dest *mainmodel.MerchantWithdrawDestination,
+	products []dto.PayProductVo,
+	order *ordermodel.WithdrawOrderM,
+	upTx *ordermodel.PayoutUpstreamTxM,
+) {
+	payoutReq := dto.CreatePayoutOrderReq{
+		MerchantNo:  req.MerchantNo,
+		TranFlow:    req.WithdrawNo,
+		Amount:      order.Amount.String(),
+		PayType:     req.PayType,
+		NotifyUrl:   req.NotifyUrl,
+		AccNo:       dest.AccountNo,
+		AccName:     dest.AccountName,
+		PayMethod:   dest.PayMethod,
+		BankCode:    dest.BankCode,
+		BankName:    dest.BankName,
+		IdentityNum: dest.IdentityNum,
+		AccountType: dest.AccountType,
+		CciNo:       dest.CciNo,
+		ClientId:    req.ClientId,
+	}
+	orderTable := shard.WithdrawShard.GetTable(order.OrderID, order.CreateTime)
+
+	var lastErr error
+	for i, product := range products {
+		_, err := s.payoutSvc.callUpstreamService(merchant, &payoutReq, &product, upTx.UpOrderId, nil)
+		if err == nil {
+			s.payoutSvc.clearUpstreamFail(uint64(product.UpstreamId), product.UpstreamCode, product.SysChannelCode)
+			if i > 0 {
+				// 降级到后续通道：同步供应商与成本
+				cost := withdrawCost(product, order.Amount)
+				if _, uErr := s.withdrawDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+					"supplier_id":   product.UpstreamId,
+					"up_channel_id": product.ID,
+					"cost":          cost,
+					"profit":        order.Fee.Sub(cost),
+					"update_time":   time.Now(),
+				}); uErr != nil {
+					log.Printf("[WITHDRAW] 更新提现通道绑定失败 order=%d err=%v", order.OrderID, uErr)
+				}
+				_ = s.payoutSvc.orderDao.UpdateByWhere(shard.UpOutOrderShard.GetTable(upTx.UpOrderId, order.CreateTime),
+					map[string]interface{}{"up_order_id": upTx.UpOrderId},
+					map[string]interface{}{"supplier_id": product.UpstreamId, "update_time": time.Now()})
+			}
+			log.Printf("[WITHDRAW] ✅ 提现提交上游成功 商户号=%s 提现单号=%s 通道=%s/%s",
+				req.MerchantNo, req.WithdrawNo, product.SysChannelCode, product.UpstreamCode)
+			return
+		}
+
+		lastErr = err
+		s.payoutSvc.recordUpstreamFail(uint64(product.UpstreamId), product.UpstreamTitle, product.UpstreamCode, product.SysChannelCode)
+		log.Printf("[WITHDRAW] 提现提交上游失败 商户号=%s 提现单号=%s 通道=%s/%s 错误=%v",
+			req.MerchantNo, req.WithdrawNo, product.SysChannelCode, product.UpstreamCode, err)
+
+		// 不可重试的上游错误（账户无效、订单重复、结果未知等）不再切换通道，避免重复出款
+		if !isRetryableUpstreamErr(err) {
+			log.Printf("[WITHDRAW] 错误不可重试，停止切换通道 order=%d err=%v", order.OrderID, err)
+			break
+		}
+		if i < len(products)-1 {
+			metrics.UpstreamFailover("withdraw", req.PayType)
+		}
+	}
+
+	remark := fmt.Sprintf("所有上游均失败, 等待人工介入: %v", lastErr)
+	title := "⚠️ 商户提现所有上游均失败"
+	if isUnknownOutcomeErr(lastErr) {
+		// 上游可能已出款：等待上游回调或人工向上游查单确认
+		remark = fmt.Sprintf("上游结果未知, 等待查单/人工确认: %v", lastErr)
+		title = "⚠️ 商户提现上游结果未知"
+	}
+	if _, err := s.withdrawDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+		"status":      ordermodel.WithdrawStatusManual,
+		"remark":      truncateReason(remark),
+		"update_time": time.Now(),
+	}); err != nil {
+		log.Printf("[WITHDRAW] 更新提现订单状态失败 order=%d err=%v", order.OrderID, err)
+	}
+	notify.Notify(system.BotChatID, "error", title,
+		fmt.Sprintf("平台提现单号: `%d`\n商户提现单号: `%s`\n商户号: `%s`\n金额: `%s %s`\n系统通道: `%s`\n错误: `%v`\n\n当前资金已冻结，请向上游查单确认后人工处理。",
+			order.OrderID, req.WithdrawNo, req.MerchantNo, order.Amount.StringFixed(2), order.Currency, req.PayType, lastErr), true)
+}
+
+// Get 提现查询
+func (s *WithdrawService) Get(req dto.QueryWithdrawReq) (dto.CreateWithdrawResp, error) {
+	var resp dto.CreateWithdrawResp
+	merchant, err := s.mainDao.GetMerchant(req.MerchantNo)
+	if err != nil || merchant == nil || merchant.Status != 1 {
+		return resp, withdrawErr(constant.CodeMerchantAbnormal, "merchant invalid")
+	}
+	idx, err := s.withdrawDao.GetIndex(merchant.MerchantID, req.WithdrawNo)
+	if err != nil {
+		return resp, err
+	}
+	if idx == nil {
+		return resp, withdrawErr(constant.CodeWithdrawNotFound, "withdraw not found")
+	}
+	order, err := s.withdrawDao.GetByOrderId(idx.OrderTableName, idx.OrderID)
+	if err != nil {
+		return resp, err
+	}
+	if order == nil {
+		return resp, withdrawErr(constant.CodeWithdrawNotFound, "withdraw not found")
+	}
+	resp = dto.CreateWithdrawResp{
+		Code:             "0",
+		Msg:              "成功",
+		Status:           withdrawStatusCode(order.Status),
+		WithdrawNo:       order.WithdrawNo,
+		WithdrawSerialNo: strconv.FormatUint(order.OrderID, 10),
+		Currency:         order.Currency,
+		Amount:           order.Amount.StringFixed(2),
+		Fee:              order.Fee.StringFixed(2),
+		SysTime:          strconv.FormatInt(utils.GetTimestampMs(), 10),
+	}
+	return resp, nil
+}
+
+// withdrawStatusCode 对外状态：人工处理中仍视为处理中
+func withdrawStatusCode(status int8) string {
+	if status == ordermodel.WithdrawStatusManual {
+		return utils.ConvertOrderStatus(ordermodel.WithdrawStatusProcessing)
+	}
+	return utils.ConvertOrderStatus(status)
+}
+
+// HandleUpstreamCallback 处理提现上游回调；订单不是提现单时返回 handled=false
+func (s *WithdrawService) HandleUpstreamCallback(orderId, upOrderId uint64, statusText, upOrderNo string) (bool, error) {
+	orderTable := shard.WithdrawShard.GetTable(orderId, time.Now())
+	order, err := s.withdrawDao.GetByOrderId(orderTable, orderId)
+	if err != nil {
+		return true, fmt.Errorf("query withdraw order failed: %w", err)
+	}
+	if order == nil {
+		return false, nil
+	}
+	if order.UpOrderID != nil && *order.UpOrderID != upOrderId {
+		log.Printf("[WITHDRAW-CALLBACK] 过期交易回调，忽略 提现单号=%d 交易订单号=%d", orderId, upOrderId)
+		return true, nil
+	}
+	if statusText != "SUCCESS" && statusText != "FAIL" {
+		return true, nil
+	}
+	if order.Status != ordermodel.WithdrawStatusProcessing && order.Status != ordermodel.WithdrawStatusManual {
+		log.Printf("[WITHDRAW-CALLBACK] 提现单已终态，忽略重复回调 提现单号=%d 状态=%d", orderId, order.Status)
+		return true, nil
+	}
+
+	merchant, err := s.mainDao.GetMerchantId(strconv.FormatUint(order.MID, 10))
+	if err != nil || merchant == nil {
+		return true, fmt.Errorf("withdraw merchant not found: %v", err)
+	}
+
+	// 先按原状态条件更新（CAS），只有抢到状态变更的回调才结算，避免重复回调重复结算
+	success := statusText == "SUCCESS"
+	newStatus := ordermodel.WithdrawStatusFailed
+	if success {
+		newStatus = ordermodel.WithdrawStatusSuccess
+	}
+	now := time.Now()
+	rows, err := s.withdrawDao.UpdateByWhere(orderTable,
+		map[string]interface{}{"order_id": order.OrderID, "status": order.Status},
+		map[string]interface{}{
+			"status":      newStatus,
+			"remark":      truncateReason(fmt.Sprintf("上游回调 %s, 上游流水号: %s", statusText, upOrderNo)),
+			"finish_time": now,
+			"update_time": now,
+		})
+	if err != nil {
+		return true, fmt.Errorf("update withdraw order failed: %w", err)
+	}
+	if rows != 1 {
+		log.Printf("[WITHDRAW-CALLBACK] 提现单状态已被其他回调更新，跳过结算 提现单号=%d", orderId)
+		return true, nil
+	}
+
+	// 资金结算：成功扣减冻结，失败解冻退回余额
+	if err := s.mainDao.HandlePayoutCallback(order.MID, order.Currency, strconv.FormatUint(order.OrderID, 10), order.WithdrawNo,
+		order.Fee, decimal.Zero, success, order.Amount, merchant.NickName); err != nil {
+		// 结算失败回退状态，让回调重试时重新结算
+		if _, rbErr := s.withdrawDao.UpdateByWhere(orderTable,
+			map[string]interface{}{"order_id": order.OrderID, "status": newStatus},
+			map[string]interface{}{"status": order.Status, "finish_time": nil, "update_time": time.Now()}); rbErr != nil {
+			log.Printf("[WITHDRAW-CALLBACK] ❌ 结算失败后回退状态失败 提现单号=%d err=%v", orderId, rbErr)
+			err = fmt.Errorf("%v; 回退状态失败: %v", err, rbErr)
+		}
+		msg := fmt.Sprintf("提现结算失败\n平台提现单号: %d\n商户提现单号: %s\n状态: %s\n错误: %v", order.OrderID, order.WithdrawNo, statusText, err)
+		notify.Notify(system.BotChatID, "warn", "提现回调", msg, true)
+		return true, errors.New(msg)
+	}
+	order.Status = newStatus
+
+	log.Printf("[WITHDRAW-CALLBACK] ✅ 提现完成 提现单号=%d 商户提现单号=%s 状态=%s", order.OrderID, order.WithdrawNo, statusText)
+	lifecycle.Go("withdraw-notify", func() { s.notifyMerchant(merchant, order, orderTable) })
+	return true, nil
+}
+
+// notifyMerchant 通知商户提现结果
+func (s *WithdrawService) notifyMerchant(merchant *mainmodel.Merchant, order *ordermodel.WithdrawOrderM, orderTable string) {
+	if order.NotifyURL == "" {
+		return
+	}
+	payload := dto.WithdrawNotifyMerchantPayload{
+		WithdrawNo:       order.WithdrawNo,
+		WithdrawSerialNo: strconv.FormatUint(order.OrderID, 10),
+		Status:           withdrawStatusCode(order.Status),
+		MerchantNo:       merchant.AppId,
+		Currency:         order.Currency,
+		Amount:           order.Amount.StringFixed(2),
+		Fee:              order.Fee.StringFixed(2),
+	}
+	if order.Status == ordermodel.WithdrawStatusSuccess {
+		payload.Msg = "提现成功"
+	} else {
+		payload.Msg = "提现失败，资金已退回"
+	}
+	payload.Sign = utils.GenerateSign(map[string]string{
+		"withdraw_no":        payload.WithdrawNo,
+		"withdraw_serial_no": payload.WithdrawSerialNo,
+		"status":             payload.Status,
+		"msg":                payload.Msg,
+		"merchant_no":        payload.MerchantNo,
+		"currency":           payload.Currency,
+		"amount":             payload.Amount,
+		"fee":                payload.Fee,
+	}, merchant.ApiKey)
+
+	var lastErr error
+	for i := 1; i <= withdrawNotifyMax; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+		var respStr string
+		respStr, lastErr = utils.HttpPostJsonWithContext(ctx, order.NotifyURL, payload)
+		cancel()
+		if lastErr == nil {
+			respStr = strings.ToLower(strings.TrimSpace(respStr))
+			if respStr == "ok" || respStr == "success" {
+				metrics.MerchantNotified("withdraw", nil)
+				_, _ = s.withdrawDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+					"notify_status": 1,
+					"notify_time":   time.Now(),
+				})
+				return
+			}
+			lastErr = fmt.Errorf("invalid merchant response: %s", respStr)
+		}
+		metrics.MerchantNotified("withdraw", lastErr)
+		log.Printf("[提现通知] 通知商户失败 order=%d status=%s (通知次数: %d/%d) err=%v", order.OrderID, payload.Status, i, withdrawNotifyMax, lastErr)
+		time.Sleep(time.Duration(i*2) * time.Second)
+	}
+	_, _ = s.withdrawDao.UpdateByWhere(orderTable, map[string]interface{}{"order_id": order.OrderID}, map[string]interface{}{
+		"notify_status": 2,
+		"notify_time":   time.Now(),
+	})
+	notify.Notify(system.BotChatID, "warn", "提现回调",
+		fmt.Sprintf("[提现通知]失败通知商户信息\n商户号: %v\n商户名称: %v\n平台提现单号: %v\n商户提现单号: %v\n状态: %v\n错误: %v",
+			merchant.AppId, merchant.NickName, order.OrderID, order.WithdrawNo, payload.Status, lastErr), true)
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+	"wht-order-api/internal/config"
+	"wht-order-api/internal/connector"
+	"wht-order-api/internal/constant"
+	"wht-order-api/internal/dal"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/dto"
+	"wht-order-api/internal/idgen"
+	mainmodel "wht-order-api/internal/model/main"
+	ordermodel "wht-order-api/internal/model/order"
+	"wht-order-api/internal/shard"
+	"wht-order-api/internal/utils"
+
+	"github.com/shopspring/decimal"
+)
+
+const withdrawChannelCode = "BR_PIX_OUT"
+
+type withdrawFixture struct {
+	main      *memdao.MainStore
+	payouts   *memdao.PayoutOrderStore
+	withdraws *memdao.WithdrawStore
+	primary   *fakePayoutConnector // 上游 501，权重 20，轮询首选
+	backup    *fakePayoutConnector // 上游 502，权重 10
+	svc       *WithdrawService
+	destId    uint64
+}
+
+// newWithdrawFixture 商户 1001 余额 1000 BRL；手续费 1% + 2，最低 5、最高 50；已过冷却期的 PIX 提现账户一个
+func newWithdrawFixture(t *testing.T) *withdrawFixture {
+	t.Helper()
+	fakeredis.Use(t)
+	shard.InitShardEngines()
+	if err := idgen.InitNode("default", 1); err != nil {
+		t.Fatalf("init idgen: %v", err)
+	}
+	prevTimeout, prevWithdraw := config.C.Upstream.Timeout.Payout, config.C.Withdraw
+	config.C.Upstream.Timeout.Payout = 5 * time.Second
+	config.C.Withdraw.Enabled, config.C.Withdraw.CoolDown = true, 24*time.Hour
+	t.Cleanup(func() { config.C.Upstream.Timeout.Payout, config.C.Withdraw = prevTimeout, prevWithdraw })
+	// 错误码映射走缓存：未配置映射的上游失败可切换通道
+	seedErrorMappings(nil)
+
+	f := &withdrawFixture{
+		main:    memdao.NewMainStore(),
+		payouts: memdao.NewPayoutOrderStore(),
+		primary: &fakePayoutConnector{},
+		backup:  &fakePayoutConnector{},
+	}
+	f.withdraws = memdao.NewWithdrawStore(f.payouts)
+	connector.Register("test_withdraw_a", f.primary)
+	connector.Register("test_withdraw_b", f.backup)
+	f.main.AddMerchant(mainmodel.Merchant{MerchantID: testMerchantID, NickName: "m1", AppId: "APP1001", ApiKey: "secret", Status: 1, Currency: "BRL"})
+	f.main.AddCountry(dto.CurrencyCodeResponse{ID: 76, Code: "BRL", Country: "Brazil"})
+	f.main.AddSysChannel(dto.PayWayVo{Id: 8, Title: "PIX 代付", Currency: "BRL", Coding: withdrawChannelCode, Type: 2, Status: 1})
+	f.main.AddMerchantChannel(dto.MerchantChannelDTO{
+		MId: testMerchantID, SysChannelId: 8, Status: 1, Type: 2, DispatchMode: 1, Currency: "BRL",
+		SysChannelCode: withdrawChannelCode, DefaultRate: decimal.NewFromInt(2),
+	})
+	f.addProduct(41, 501, 20, "test_withdraw_a", 1)
+	f.addProduct(42, 502, 10, "test_withdraw_b", 2)
+	f.main.AddWithdrawFeeRule(mainmodel.MerchantWithdrawFee{
+		MID: 0, Currency: "BRL", FeeRate: decimal.NewFromInt(1), FixedFee: decimal.NewFromInt(2),
+		MinFee: decimal.NewFromInt(5), MaxFee: decimal.NewFromInt(50),
+		MinAmount: decimal.NewFromInt(10), MaxAmount: decimal.NewFromInt(20000), Status: 1,
+	})
+	f.destId = f.main.AddWithdrawDestination(mainmodel.MerchantWithdrawDestination{
+		MID: testMerchantID, Currency: "BRL", AccountNo: "joao@example.com", AccountName: "Joao", PayMethod: "PIX",
+		Status: 1, ActiveAt: time.Now().Add(-time.Hour),
+	})
+	f.main.SetAccount(testMerchantID, "BRL", decimal.NewFromInt(1000), decimal.Zero)
+
+	f.svc = NewWithdrawServiceWithRepos(nopPublisher{}, f.main, f.payouts, f.payouts.Index, f.withdraws)
+	t.Cleanup(f.svc.payoutSvc.Shutdown)
+	return f
+}
+
+// addProduct 挂一个上游产品，成本费率 costRate%
+func (f *withdrawFixture) addProduct(id, upstreamId int64, weight int, interfaceCode string, costRate int64) {
+	minAmount, maxAmount := decimal.NewFromInt(10), decimal.NewFromInt(20000)
+	f.main.AddPayProduct(testMerchantID, dto.PayProductVo{
+		ID: id, Currency: "BRL", Type: 2, Status: 1,
+		UpstreamId: upstreamId, UpstreamCode: "UP_" + strconv.FormatInt(upstreamId, 10), UpstreamTitle: "up", UpstreamWeight: weight,
+		InterfaceCode: interfaceCode,
+		SysChannelID:  8, SysChannelCode: withdrawChannelCode, SysChannelTitle: "PIX 代付",
+		MDefaultRate: decimal.NewFromInt(2), CostRate: decimal.NewFromInt(costRate),
+		MinAmount: &minAmount, MaxAmount: &maxAmount,
+	})
+}
+
+// seedErrorMappings 预置上游错误码映射缓存（mapUpstreamError 不访问主库）
+func seedErrorMappings(list []mainmodel.UpstreamErrorMapping) {
+	if list == nil {
+		list = []mainmodel.UpstreamErrorMapping{}
+	}
+	for _, code := range []string{"test_withdraw_a", "test_withdraw_b"} {
+		dal.RedisClient.Set(dal.RedisCtx, upstreamErrMapCachePrefix+code, utils.MapToJSON(list), time.Minute)
+	}
+}
+
+func (f *withdrawFixture) create(withdrawNo, destId, amount string) (dto.CreateWithdrawResp, error) {
+	return f.svc.Create(dto.CreateWithdrawReq{
+		MerchantNo: "APP1001", WithdrawNo: withdrawNo, DestinationId: destId, PayType: withdrawChannelCode, Amount: amount,
+	})
+}
+
+// mustCreate 下单成功并返回落库的提现订单
+func (f *withdrawFixture) mustCreate(t *testing.T, withdrawNo, amount string) *ordermodel.WithdrawOrderM {
+	t.Helper()
+	resp, err := f.create(withdrawNo, strconv.FormatUint(f.destId, 10), amount)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	waitLifecycle(t)
+	all := f.withdraws.Orders()
+	if len(all) != 1 || resp.WithdrawSerialNo != strconv.FormatUint(all[0].OrderID, 10) {
+		t.Fatalf("orders = %+v, resp = %+v", all, resp)
+	}
+	return f.order(t, all[0].OrderID)
+}
+
+func (f *withdrawFixture) order(t *testing.T, orderID uint64) *ordermodel.WithdrawOrderM {
+	t.Helper()
+	o, err := f.withdraws.GetByOrderId(shard.WithdrawShard.GetTable(orderID, time.Now()), orderID)
+	if err != nil || o == nil {
+		t.Fatalf("load withdraw order: %v", err)
+	}
+	return o
+}
+
+func (f *withdrawFixture) balance(t *testing.T) (money, freeze decimal.Decimal) {
+	t.Helper()
+	acc, ok := f.main.Account(testMerchantID, "BRL")
+	if !ok {
+		t.Fatal("account not found")
+	}
+	return acc.Money, acc.FreezeMoney
+}
+
+func withdrawErrCode(err error) int {
+	var we *WithdrawError
+	if errors.As(err, &we) {
+		return we.Code
+	}
+	return 0
+}
+
+func TestCalcWithdrawFee(t *testing.T) {
+	rule := &mainmodel.MerchantWithdrawFee{
+		FeeRate: decimal.NewFromInt(1), FixedFee: decimal.NewFromInt(2),
+		MinFee: decimal.NewFromInt(5), MaxFee: decimal.NewFromInt(50),
+	}
+	cases := []struct {
+		amount, want string
+	}{
+		{"100", "5"},         // 1 + 2 低于最低手续费
+		{"1000", "12"},       // 10 + 2
+		{"1234.56", "14.35"}, // 12.3456 + 2 保留两位
+		{"10000", "50"},      // 102 超过最高手续费
+	}
+	for _, c := range cases {
+		if got := calcWithdrawFee(rule, decimal.RequireFromString(c.amount)); !got.Equal(decimal.RequireFromString(c.want)) {
+			t.Errorf("fee(%s) = %s, want %s", c.amount, got, c.want)
+		}
+	}
+	// 最高手续费为 0 不封顶
+	rule.MaxFee = decimal.Zero
+	if got := calcWithdrawFee(rule, decimal.NewFromInt(10000)); !got.Equal(decimal.NewFromInt(102)) {
+		t.Errorf("uncapped fee = %s, want 102", got)
+	}
+}
+
+func TestWithdrawCreateFreezesAmountPlusFee(t *testing.T) {
+	f := newWithdrawFixture(t)
+
+	order := f.mustCreate(t, "W-1001", "500")
+	// 手续费 500×1% + 2 = 7，冻结 507；成本按首选上游 1%
+	if !order.Fee.Equal(decimal.NewFromInt(7)) || !order.FreezeAmount.Equal(decimal.NewFromInt(507)) ||
+		!order.Cost.Equal(decimal.NewFromInt(5)) || order.Status != ordermodel.WithdrawStatusProcessing || order.SupplierID != 501 {
+		t.Fatalf("order = %+v", order)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(493)) || !freeze.Equal(decimal.NewFromInt(507)) {
+		t.Errorf("balance = %s/%s, want 493/507", money, freeze)
+	}
+	txs := f.payouts.Txs()
+	if len(txs) != 1 || txs[0].OrderID != order.OrderID || txs[0].UpOrderId != *order.UpOrderID || f.primary.calls != 1 {
+		t.Errorf("txs = %+v, upstream calls %d", txs, f.primary.calls)
+	}
+
+	// 同一商户提现单号重复提交
+	if _, err := f.create("W-1001", strconv.FormatUint(f.destId, 10), "500"); withdrawErrCode(err) != constant.CodeWithdrawAlreadyExist {
+		t.Errorf("duplicate err = %v", err)
+	}
+}
+
+func TestWithdrawDestinationCoolDown(t *testing.T) {
+	f := newWithdrawFixture(t)
+
+	// 新增账户进入冷却期，期内不可提现，资金不冻结
+	dest, err := f.svc.SaveDestination(dto.WithdrawDestinationSaveReq{
+		MerchantNo: "APP1001", Currency: "brl", AccNo: "maria@example.com", AccName: "Maria", PayMethod: "PIX",
+	})
+	if err != nil {
+		t.Fatalf("save destination: %v", err)
+	}
+	if _, err := f.create("W-2001", dest.DestinationId, "500"); withdrawErrCode(err) != constant.CodeWithdrawDestinationCooling {
+		t.Fatalf("create err = %v, want cool-down", err)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(1000)) || !freeze.IsZero() || len(f.withdraws.Orders()) != 0 {
+		t.Errorf("balance = %s/%s, orders = %d", money, freeze, len(f.withdraws.Orders()))
+	}
+
+	// 只改别名不重置冷却期；修改收款账号重新进入冷却期
+	destId := strconv.FormatUint(f.destId, 10)
+	if _, err := f.svc.SaveDestination(dto.WithdrawDestinationSaveReq{
+		MerchantNo: "APP1001", DestinationId: destId, Currency: "BRL", Label: "主账户",
+		AccNo: "joao@example.com", AccName: "Joao", PayMethod: "PIX",
+	}); err != nil {
+		t.Fatalf("save label: %v", err)
+	}
+	if d, _ := f.main.GetWithdrawDestination(testMerchantID, f.destId); d == nil || d.ActiveAt.After(time.Now()) {
+		t.Fatalf("label change reset cool-down: %+v", d)
+	}
+	if _, err := f.svc.SaveDestination(dto.WithdrawDestinationSaveReq{
+		MerchantNo: "APP1001", DestinationId: destId, Currency: "BRL", Label: "主账户",
+		AccNo: "joao.new@example.com", AccName: "Joao", PayMethod: "PIX",
+	}); err != nil {
+		t.Fatalf("save account: %v", err)
+	}
+	if _, err := f.create("W-2002", destId, "500"); withdrawErrCode(err) != constant.CodeWithdrawDestinationCooling {
+		t.Errorf("create after account change err = %v, want cool-down", err)
+	}
+}
+
+func TestWithdrawCurrencyMismatch(t *testing.T) {
+	f := newWithdrawFixture(t)
+	mxn := f.main.AddWithdrawDestination(mainmodel.MerchantWithdrawDestination{
+		MID: testMerchantID, Currency: "MXN", AccountNo: "012345678901234567", AccountName: "Juan",
+		Status: 1, ActiveAt: time.Now().Add(-time.Hour),
+	})
+
+	if _, err := f.create("W-3001", strconv.FormatUint(mxn, 10), "500"); withdrawErrCode(err) != constant.CodeWithdrawCurrencyMismatch {
+		t.Fatalf("create err = %v, want currency mismatch", err)
+	}
+	if f.primary.calls != 0 || len(f.withdraws.Orders()) != 0 {
+		t.Errorf("upstream calls = %d, orders = %d, want 0/0", f.primary.calls, len(f.withdraws.Orders()))
+	}
+}
+
+func TestWithdrawCallbackSettlesOnce(t *testing.T) {
+	f := newWithdrawFixture(t)
+	order := f.mustCreate(t, "W-4001", "500")
+
+	// 非当前上游交易的回调忽略
+	if handled, err := f.svc.HandleUpstreamCallback(order.OrderID, *order.UpOrderID+1, "SUCCESS", "UP-STALE"); !handled || err != nil {
+		t.Fatalf("stale callback = %v/%v", handled, err)
+	}
+	if o := f.order(t, order.OrderID); o.Status != ordermodel.WithdrawStatusProcessing {
+		t.Fatalf("status after stale callback = %d", o.Status)
+	}
+
+	if handled, err := f.svc.HandleUpstreamCallback(order.OrderID, *order.UpOrderID, "SUCCESS", "UP-1"); !handled || err != nil {
+		t.Fatalf("success callback = %v/%v", handled, err)
+	}
+	waitLifecycle(t)
+	// 重复成功回调、成功后的失败回调都不再结算
+	for _, status := range []string{"SUCCESS", "FAIL"} {
+		if _, err := f.svc.HandleUpstreamCallback(order.OrderID, *order.UpOrderID, status, "UP-1"); err != nil {
+			t.Fatalf("repeat %s callback: %v", status, err)
+		}
+	}
+	if o := f.order(t, order.OrderID); o.Status != ordermodel.WithdrawStatusSuccess || o.FinishTime == nil {
+		t.Errorf("order = status %d, finishTime %v", o.Status, o.FinishTime)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(493)) || !freeze.IsZero() {
+		t.Errorf("balance = %s/%s, want 493/0", money, freeze)
+	}
+	if n := len(f.main.MoneyLogs(testMerchantID)); n != 2 {
+		t.Errorf("money logs = %d, want freeze + payout", n)
+	}
+
+	// 非提现单交由代付回调处理
+	if handled, _ := f.svc.HandleUpstreamCallback(order.OrderID+1, *order.UpOrderID, "SUCCESS", "UP-1"); handled {
+		t.Error("unknown order handled as withdraw")
+	}
+}
+
+func TestWithdrawCallbackRollsBackStatusWhenSettleFails(t *testing.T) {
+	f := newWithdrawFixture(t)
+	order := f.mustCreate(t, "W-5001", "500")
+
+	// 冻结资金不足导致结算失败：状态回退为处理中，等待回调重试
+	f.main.SetAccount(testMerchantID, "BRL", decimal.NewFromInt(493), decimal.Zero)
+	if handled, err := f.svc.HandleUpstreamCallback(order.OrderID, *order.UpOrderID, "FAIL", "UP-1"); !handled || err == nil {
+		t.Fatalf("fail callback = %v/%v, want settle error", handled, err)
+	}
+	if o := f.order(t, order.OrderID); o.Status != ordermodel.WithdrawStatusProcessing || o.FinishTime != nil {
+		t.Fatalf("order after rollback = status %d, finishTime %v", o.Status, o.FinishTime)
+	}
+
+	// 重试回调重新结算，冻结退回余额
+	f.main.SetAccount(testMerchantID, "BRL", decimal.NewFromInt(493), decimal.NewFromInt(507))
+	if _, err := f.svc.HandleUpstreamCallback(order.OrderID, *order.UpOrderID, "FAIL", "UP-1"); err != nil {
+		t.Fatalf("retry callback: %v", err)
+	}
+	waitLifecycle(t)
+	if o := f.order(t, order.OrderID); o.Status != ordermodel.WithdrawStatusFailed {
+		t.Errorf("status = %d, want failed", o.Status)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(1000)) || !freeze.IsZero() {
+		t.Errorf("balance = %s/%s, want 1000/0", money, freeze)
+	}
+}
+
+func TestWithdrawFailoverToBackupUpstream(t *testing.T) {
+	f := newWithdrawFixture(t)
+	f.primary.reject = true
+
+	// 首选上游拒绝（未配置映射，可切换）：降级到备用上游并同步供应商与成本
+	order := f.mustCreate(t, "W-6001", "500")
+	if f.primary.calls != 1 || f.backup.calls != 1 {
+		t.Fatalf("upstream calls = %d/%d, want 1/1", f.primary.calls, f.backup.calls)
+	}
+	if order.Status != ordermodel.WithdrawStatusProcessing || order.SupplierID != 502 || order.UpChannelID != 42 ||
+		!order.Cost.Equal(decimal.NewFromInt(10)) || !order.Profit.Equal(decimal.NewFromInt(-3)) {
+		t.Errorf("order = %+v", order)
+	}
+	if txs := f.payouts.Txs(); len(txs) != 1 || txs[0].SupplierId != 502 {
+		t.Errorf("txs = %+v", txs)
+	}
+}
+
+func TestWithdrawStopsFailoverOnNonRetryableError(t *testing.T) {
+	f := newWithdrawFixture(t)
+	f.primary.reject = true
+	seedErrorMappings([]mainmodel.UpstreamErrorMapping{{UpstreamCode: "ACCOUNT_INVALID", Code: constant.CodeUpstreamInvalidAccount, Status: 1}})
+
+	// 账户无效不可切换：不再提交备用上游，转人工且资金保持冻结
+	order := f.mustCreate(t, "W-7001", "500")
+	if f.primary.calls != 1 || f.backup.calls != 0 {
+		t.Fatalf("upstream calls = %d/%d, want 1/0", f.primary.calls, f.backup.calls)
+	}
+	if order.Status != ordermodel.WithdrawStatusManual || order.SupplierID != 501 {
+		t.Errorf("order = status %d, supplier %d", order.Status, order.SupplierID)
+	}
+	if money, freeze := f.balance(t); !money.Equal(decimal.NewFromInt(493)) || !freeze.Equal(decimal.NewFromInt(507)) {
+		t.Errorf("balance = %s/%s, want 493/507", money, freeze)
+	}
+}
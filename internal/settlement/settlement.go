@@ -7,11 +7,12 @@ import (
 	"strconv"
 	"wht-order-api/internal/dao"
 	"wht-order-api/internal/dto"
+	mainmodel "wht-order-api/internal/model/main"
 )
 
 type Settlement struct {
-	mainDao  *dao.MainDao
-	orderDao *dao.OrderDao
+	mainDao  dao.MainRepository
+	orderDao dao.OrderRepository
 }
 
 func NewSettlement() *Settlement {
@@ -21,6 +22,14 @@ func NewSettlement() *Settlement {
 	}
 }
 
+// NewSettlementWithRepos 注入仓储（单元测试使用内存实现）
+func NewSettlementWithRepos(mainRepo dao.MainRepository, orderRepo dao.OrderRepository) *Settlement {
+	return &Settlement{
+		mainDao:  mainRepo,
+		orderDao: orderRepo,
+	}
+}
+
 // DoPaySettlement 处理代收订单结算逻辑
 func (s *Settlement) DoPaySettlement(req dto.SettlementResult, mId string, orderId uint64, mOrderId string) error {
 	orderNo := strconv.FormatUint(orderId, 10)
@@ -53,22 +62,12 @@ func (s *Settlement) DoPaySettlement(req dto.SettlementResult, mId string, order
 		return fmt.Errorf("[SETTLEMENT] 商户资金结算失败, merchantID=%v, orderNo=%v, err=%w", merchant.MerchantID, orderNo, err)
 	}
 
-	// 3) 代理收益 & 账户更新
-	agentMoney := dto.AgentMoney{
-		AID:        merchant.PId,
-		MID:        merchant.MerchantID,
-		OrderNo:    orderNo,
-		MOrderNo:   mOrderId,
-		OrderMoney: req.OrderAmount,
-		Money:      req.AgentIncome,
-		Currency:   req.Currency,
-		Type:       dto.MoneyLogTypeDeposit, // ✅ 常量定义
-		Remark:     "商户代收收益",
+	// 3) 代理收益 & 账户更新（多级代理逐级入账）
+	if err := s.postAgentCommission(req, merchant, orderNo, mOrderId, dto.MoneyLogTypeDeposit, dto.MoneyLogTypeDepositComm, "商户代收收益", "代理代收佣金"); err != nil {
+		return err
 	}
 
-	if err := s.mainDao.CreateAgentMoneyLog(agentMoney, dto.MoneyLogTypeDepositComm, "代理代收佣金"); err != nil {
-		return fmt.Errorf("[SETTLEMENT] 代理资金结算失败, agentID=%v, orderNo=%v, err=%w", merchant.PId, orderNo, err)
-	}
+	RecordVolume(merchant.MerchantID, req.Currency, orderNo, req.OrderAmount)
 
 	log.Printf("[SETTLEMENT] 结算完成: 商户=%v, 代理=%v, 订单号=%v", merchant.MerchantID, merchant.PId, orderNo)
 	return nil
@@ -107,29 +106,49 @@ func (s *Settlement) DoPayoutSettlement(req dto.SettlementResult, mId string, or
 			merchant.MerchantID, orderNo, req.MerchantTotalFee, req.AgentTotalFee, handleErr)
 	}
 
-	// 3) 成功时才处理代理收益
-	if status && req.AgentIncome.GreaterThan(decimal.Zero) {
+	// 3) 成功时才处理代理收益（多级代理逐级入账）
+	if status {
+		if err := s.postAgentCommission(req, merchant, orderNo, mOrderNo, 0, dto.MoneyLogTypePayoutComm, "商户代付收益", "代理代付佣金"); err != nil {
+			return err
+		}
+		RecordVolume(merchant.MerchantID, req.Currency, orderNo, orderAmount)
+	}
+
+	log.Printf("[SETTLEMENT] 代付结算完成: 商户=%v(金额=%v %s), 代理=%v(收益=%v %s), 订单号=%v, 状态=%v",
+		merchant.MerchantID, req.MerchantRecv, req.Currency,
+		merchant.PId, req.AgentIncome, req.Currency,
+		orderNo, status)
+
+	return nil
+}
+
+// postAgentCommission 代理分润入账：按结算快照中的各级分润逐级入账（按 代理+订单+类型 幂等，重复结算不会重复入账）
+// 旧快照没有分润明细时，按商户直属代理单笔入账
+func (s *Settlement) postAgentCommission(req dto.SettlementResult, merchant *mainmodel.Merchant, orderNo, mOrderNo string, agentType, logType int8, remark, description string) error {
+	legs := req.AgentLegs
+	if len(legs) == 0 {
+		legs = []dto.AgentLeg{{AgentID: merchant.PId, Level: 1, Commission: req.AgentIncome}}
+	}
+
+	for _, leg := range legs {
+		if leg.AgentID == 0 || leg.Commission.LessThanOrEqual(decimal.Zero) {
+			continue
+		}
 		agentMoney := dto.AgentMoney{
-			AID:        merchant.PId,
+			AID:        leg.AgentID,
 			MID:        merchant.MerchantID,
 			OrderNo:    orderNo,
 			MOrderNo:   mOrderNo,
 			OrderMoney: req.OrderAmount,
-			Money:      req.AgentIncome,
+			Money:      leg.Commission,
 			Currency:   req.Currency,
-			Remark:     "商户代付收益",
+			Type:       agentType,
+			Remark:     remark,
 		}
-
-		if err := s.mainDao.CreateAgentMoneyLog(agentMoney, dto.MoneyLogTypePayoutComm, "代理代付佣金"); err != nil {
-			return fmt.Errorf("[SETTLEMENT] 代理资金结算失败, agentID=%v, orderNo=%v, 金额=%v, err=%w",
-				merchant.PId, orderNo, req.AgentIncome, err)
+		if err := s.mainDao.CreateAgentMoneyLog(agentMoney, logType, description); err != nil {
+			return fmt.Errorf("[SETTLEMENT] 代理资金结算失败, agentID=%v, level=%d, orderNo=%v, 金额=%v, err=%w",
+				leg.AgentID, leg.Level, orderNo, leg.Commission, err)
 		}
 	}
-
-	log.Printf("[SETTLEMENT] 代付结算完成: 商户=%v(金额=%v %s), 代理=%v(收益=%v %s), 订单号=%v, 状态=%v",
-		merchant.MerchantID, req.MerchantRecv, req.Currency,
-		merchant.PId, req.AgentIncome, req.Currency,
-		orderNo, status)
-
 	return nil
 }
@@ -0,0 +1,114 @@
+package settlement
+
+import (
+	"testing"
+	"time"
+	"wht-order-api/internal/dal/fakeredis"
+	"wht-order-api/internal/dao/memdao"
+	"wht-order-api/internal/dto"
+	mainmodel "wht-order-api/internal/model/main"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	merchantID = uint64(1001)
+	agentL1    = uint64(2001)
+	agentL2    = uint64(2002)
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func newStore() *memdao.MainStore {
+	store := memdao.NewMainStore()
+	store.AddMerchant(mainmodel.Merchant{MerchantID: merchantID, NickName: "m1", AppId: "APP1001", Status: 1, PId: agentL1, Currency: "BRL"})
+	return store
+}
+
+func balance(t *testing.T, store *memdao.MainStore, uid uint64) (money, freeze decimal.Decimal) {
+	t.Helper()
+	acc, ok := store.Account(uid, "BRL")
+	if !ok {
+		t.Fatalf("account %d not found", uid)
+	}
+	return acc.Money, acc.FreezeMoney
+}
+
+func TestDoPaySettlement(t *testing.T) {
+	srv := fakeredis.Use(t)
+	store := newStore()
+	s := NewSettlementWithRepos(store, memdao.NewOrderStore())
+
+	req := dto.SettlementResult{
+		OrderAmount:  d("1000"),
+		Currency:     "BRL",
+		MerchantRecv: d("970"),
+		AgentIncome:  d("8"),
+		AgentLegs: []dto.AgentLeg{
+			{AgentID: agentL1, Level: 1, Commission: d("5")},
+			{AgentID: agentL2, Level: 2, Commission: d("3")},
+		},
+	}
+	if err := s.DoPaySettlement(req, "1001", 9001, "M-9001"); err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+	// 重复结算（回调重放）不重复入账
+	if err := s.DoPaySettlement(req, "1001", 9001, "M-9001"); err != nil {
+		t.Fatalf("settle again: %v", err)
+	}
+
+	if money, _ := balance(t, store, merchantID); !money.Equal(d("970")) {
+		t.Errorf("merchant balance = %s, want 970", money)
+	}
+	if money, _ := balance(t, store, agentL1); !money.Equal(d("5")) {
+		t.Errorf("agent L1 balance = %s, want 5", money)
+	}
+	if money, _ := balance(t, store, agentL2); !money.Equal(d("3")) {
+		t.Errorf("agent L2 balance = %s, want 3", money)
+	}
+	if n := len(store.AgentMoneyLogs()); n != 2 {
+		t.Errorf("agent money logs = %d, want 2", n)
+	}
+	if logs := store.MoneyLogs(merchantID); len(logs) != 1 || logs[0].Type != dto.MoneyLogTypeDeposit {
+		t.Errorf("merchant money logs = %+v, want one deposit", logs)
+	}
+
+	// 当月交易量按订单号幂等累计
+	if v, _ := srv.Get(feeVolumeKey(merchantID, "BRL", time.Now())); v != "1000" {
+		t.Errorf("monthly volume = %q, want 1000", v)
+	}
+}
+
+func TestDoPaySettlementLegacySnapshot(t *testing.T) {
+	fakeredis.Use(t)
+	store := newStore()
+	s := NewSettlementWithRepos(store, memdao.NewOrderStore())
+
+	// 旧快照没有分润明细：按商户直属代理单笔入账
+	req := dto.SettlementResult{OrderAmount: d("100"), Currency: "BRL", MerchantRecv: d("97"), AgentIncome: d("1.2")}
+	if err := s.DoPaySettlement(req, "1001", 9002, "M-9002"); err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+	if money, _ := balance(t, store, agentL1); !money.Equal(d("1.2")) {
+		t.Errorf("agent balance = %s, want 1.2", money)
+	}
+}
+
+func TestDoPaySettlementInvalidMerchant(t *testing.T) {
+	fakeredis.Use(t)
+	store := newStore()
+	store.AddMerchant(mainmodel.Merchant{MerchantID: 1002, Status: 0})
+	s := NewSettlementWithRepos(store, memdao.NewOrderStore())
+
+	req := dto.SettlementResult{OrderAmount: d("100"), Currency: "BRL", MerchantRecv: d("97")}
+	for _, mId := range []string{"1002", "404"} {
+		if err := s.DoPaySettlement(req, mId, 9003, "M-9003"); err == nil {
+			t.Errorf("merchant %s: want error", mId)
+		}
+	}
+	if _, ok := store.Account(1002, "BRL"); ok {
+		t.Error("disabled merchant must not be credited")
+	}
+}
@@ -0,0 +1,55 @@
+package settlement
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"wht-order-api/internal/dal"
+
+	"github.com/shopspring/decimal"
+)
+
+// 商户当月累计交易量（用于交易量阶梯费率），仅统计结算成功的订单
+const (
+	feeVolumePrefix     = "fee_volume:"      // fee_volume:{商户ID}:{币种}:{yyyyMM}
+	feeVolumeSeenPrefix = "fee_volume_seen:" // fee_volume_seen:{订单号}，防止重复结算重复累计
+	feeVolumeTTL        = 40 * 24 * time.Hour
+)
+
+func feeVolumeKey(mId uint64, currency string, t time.Time) string {
+	return fmt.Sprintf("%s%d:%s:%s", feeVolumePrefix, mId, currency, t.Format("200601"))
+}
+
+// MonthlyVolume 商户当月累计交易量，读取失败按 0 处理（回退到最低档）
+func MonthlyVolume(mId uint64, currency string) decimal.Decimal {
+	if dal.RedisClient == nil {
+		return decimal.Zero
+	}
+	val, err := dal.RedisClient.Get(dal.RedisCtx, feeVolumeKey(mId, currency, time.Now())).Result()
+	if err != nil || val == "" {
+		return decimal.Zero
+	}
+	v, err := decimal.NewFromString(val)
+	if err != nil {
+		return decimal.Zero
+	}
+	return v
+}
+
+// RecordVolume 累计商户当月交易量（按订单号幂等）
+func RecordVolume(mId uint64, currency, orderNo string, amount decimal.Decimal) {
+	if dal.RedisClient == nil || amount.LessThanOrEqual(decimal.Zero) {
+		return
+	}
+	ok, err := dal.RedisClient.SetNX(dal.RedisCtx, feeVolumeSeenPrefix+orderNo, 1, feeVolumeTTL).Result()
+	if err != nil || !ok {
+		return
+	}
+	key := feeVolumeKey(mId, currency, time.Now())
+	pipe := dal.RedisClient.TxPipeline()
+	pipe.IncrByFloat(dal.RedisCtx, key, amount.InexactFloat64())
+	pipe.Expire(dal.RedisCtx, key, feeVolumeTTL)
+	if _, err := pipe.Exec(dal.RedisCtx); err != nil {
+		log.Printf("[SETTLEMENT] 累计商户月交易量失败 商户=%d 订单号=%s err=%v", mId, orderNo, err)
+	}
+}
@@ -7,6 +7,7 @@ var (
 	UpOutOrderShard  *ShardEngine
 	OrderLogShard    *ShardEngine
 	OutOrderLogShard *ShardEngine
+	WithdrawShard    *ShardEngine
 )
 
 // InitShardEngines 初始化所有分片引擎
@@ -17,4 +18,5 @@ func InitShardEngines() {
 	UpOutOrderShard = NewShardEngine("p_up_out_order", 4)
 	OrderLogShard = NewShardEngine("p_order_log", 4)
 	OutOrderLogShard = NewShardEngine("p_out_order_log", 4)
+	WithdrawShard = NewShardEngine("p_withdraw_order", 4)
 }
@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -10,6 +11,7 @@ import (
 	"reflect"
 	"strings"
 	"time"
+	"wht-order-api/internal/i18n"
 )
 
 const ShardCount = 4
@@ -115,6 +117,8 @@ func ConvertOrderStatus(status int8) string {
 		statusStr = "0006"
 	case 5: //下单失败
 		statusStr = "0003"
+	case 7: //待复核
+		statusStr = "0001"
 	}
 
 	return statusStr
@@ -125,6 +129,15 @@ func PtrTime(t time.Time) *time.Time {
 	return &t
 }
 
+// TruncateRunes 按字符截断（避免截断多字节中文导致非法 UTF-8）
+func TruncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
 // GenerateTraceID 生成链路追踪字段,全链路唯一标识，用于串联请求、日志、MQ、回调等所有环节
 func GenerateTraceID() string {
 	return uuid.New().String()
@@ -217,16 +230,17 @@ func checkNilRecursive(val reflect.Value, path string) error {
 	return nil
 }
 
-// 错误信息映射函数（可扩展）
-func ValidationMsg(fe validator.FieldError) string {
+// ValidationMsg 字段校验错误信息（按请求语言从文案目录渲染，可扩展）
+func ValidationMsg(c *gin.Context, fe validator.FieldError) string {
+	lang := i18n.FromContext(c)
 	switch fe.Tag() {
 	case "required":
-		return "字段不能为空"
+		return i18n.Text(lang, i18n.TextFieldRequired)
 	case "url":
-		return "必须是合法的 URL 地址"
+		return i18n.Text(lang, i18n.TextFieldURL)
 	case "email":
-		return "必须是合法的邮箱格式"
+		return i18n.Text(lang, i18n.TextFieldEmail)
 	default:
-		return "参数格式错误"
+		return i18n.Text(lang, i18n.TextFieldInvalid)
 	}
 }
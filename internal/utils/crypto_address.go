@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/crypto/sha3"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Index = func() [256]int {
+	var idx [256]int
+	for i := range idx {
+		idx[i] = -1
+	}
+	for i := 0; i < len(base58Alphabet); i++ {
+		idx[base58Alphabet[i]] = i
+	}
+	return idx
+}()
+
+// Base58Decode Base58（比特币字母表）解码，保留前导零字节
+func Base58Decode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty base58 string")
+	}
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		v := base58Index[s[i]]
+		if v < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", s[i])
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(v)))
+	}
+	leading := 0
+	for leading < len(s) && s[leading] == '1' {
+		leading++
+	}
+	return append(make([]byte, leading), n.Bytes()...), nil
+}
+
+// ValidTronAddress TRON 地址：Base58Check，21 字节负载（0x41 前缀）+ 4 字节双 SHA256 校验
+func ValidTronAddress(addr string) bool {
+	if len(addr) != 34 || addr[0] != 'T' {
+		return false
+	}
+	raw, err := Base58Decode(addr)
+	if err != nil || len(raw) != 25 || raw[0] != 0x41 {
+		return false
+	}
+	first := sha256.Sum256(raw[:21])
+	second := sha256.Sum256(first[:])
+	return bytes.Equal(second[:4], raw[21:])
+}
+
+// ValidEVMAddress EVM 地址（ETH/BSC/Polygon）：0x + 40 位十六进制；大小写混合时必须符合 EIP-55 校验
+func ValidEVMAddress(addr string) bool {
+	if len(addr) != 42 || !strings.HasPrefix(addr, "0x") {
+		return false
+	}
+	body := addr[2:]
+	if _, err := hex.DecodeString(body); err != nil {
+		return false
+	}
+	if body == strings.ToLower(body) || body == strings.ToUpper(body) {
+		return true
+	}
+	return addr == ToChecksumAddress(addr)
+}
+
+// ToChecksumAddress 按 EIP-55 生成校验大小写地址
+func ToChecksumAddress(addr string) string {
+	lower := strings.ToLower(strings.TrimPrefix(addr, "0x"))
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(lower))
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && c <= 'f' && hash[i] >= '8' {
+			out[i] = c - 32
+		}
+	}
+	return "0x" + string(out)
+}
+
+// ed25519 曲线参数：p = 2^255 - 19，d = -121665/121666 mod p
+var (
+	ed25519P = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	ed25519D = func() *big.Int {
+		num := new(big.Int).Neg(big.NewInt(121665))
+		den := new(big.Int).ModInverse(big.NewInt(121666), ed25519P)
+		d := num.Mul(num, den)
+		return d.Mod(d, ed25519P)
+	}()
+)
+
+// ValidSolanaAddress Solana 钱包地址：Base58 解码为 32 字节且为 ed25519 曲线上的点
+func ValidSolanaAddress(addr string) bool {
+	if len(addr) < 32 || len(addr) > 44 {
+		return false
+	}
+	raw, err := Base58Decode(addr)
+	if err != nil || len(raw) != 32 {
+		return false
+	}
+	return isEd25519Point(raw)
+}
+
+// isEd25519Point 判断压缩点是否在曲线上：x² = (y² - 1) / (d·y² + 1) 需为模 p 的二次剩余
+func isEd25519Point(b []byte) bool {
+	le := make([]byte, 32)
+	copy(le, b)
+	sign := le[31] >> 7
+	le[31] &= 0x7f
+	// 小端转大端
+	for i, j := 0, 31; i < j; i, j = i+1, j-1 {
+		le[i], le[j] = le[j], le[i]
+	}
+	y := new(big.Int).SetBytes(le)
+	if y.Cmp(ed25519P) >= 0 {
+		return false
+	}
+
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, ed25519P)
+	u := new(big.Int).Sub(y2, big.NewInt(1))
+	u.Mod(u, ed25519P)
+	v := new(big.Int).Mul(ed25519D, y2)
+	v.Add(v, big.NewInt(1))
+	v.Mod(v, ed25519P)
+
+	vInv := new(big.Int).ModInverse(v, ed25519P)
+	if vInv == nil {
+		return false
+	}
+	x2 := u.Mul(u, vInv)
+	x2.Mod(x2, ed25519P)
+	if x2.Sign() == 0 {
+		// x = 0 时符号位必须为 0
+		return sign == 0
+	}
+	// 欧拉判别：x2^((p-1)/2) == 1
+	exp := new(big.Int).Rsh(new(big.Int).Sub(ed25519P, big.NewInt(1)), 1)
+	return new(big.Int).Exp(x2, exp, ed25519P).Cmp(big.NewInt(1)) == 0
+}
+
+// ValidCryptoAddress 按 pay_method 所在链校验收款地址
+func ValidCryptoAddress(payMethod, addr string) error {
+	info, err := ParsePayMethod(strings.ToUpper(payMethod))
+	if err != nil {
+		return err
+	}
+	addr = strings.TrimSpace(addr)
+	var ok bool
+	switch info.Chain {
+	case "TRON":
+		ok = ValidTronAddress(addr)
+	case "ETH", "BSC", "POLYGON":
+		ok = ValidEVMAddress(addr)
+	case "SOLANA":
+		ok = ValidSolanaAddress(addr)
+	default:
+		return fmt.Errorf("unsupported chain: %s", info.Chain)
+	}
+	if !ok {
+		return fmt.Errorf("address %s is not a valid %s address", addr, info.Chain)
+	}
+	return nil
+}
+
+// CheckCryptoNetwork 校验 network 与 pay_method 所在链/协议一致（network 为空不校验）
+func CheckCryptoNetwork(payMethod, network string) error {
+	network = strings.TrimSpace(network)
+	if network == "" {
+		return nil
+	}
+	info, err := ParsePayMethod(strings.ToUpper(payMethod))
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(network, info.Chain) && !strings.EqualFold(network, info.Protocol) {
+		return fmt.Errorf("network %s does not match pay_method %s (%s/%s)", network, payMethod, info.Chain, info.Protocol)
+	}
+	return nil
+}
+
+// CheckCryptoAmount 校验金额精度不超过代币精度
+func CheckCryptoAmount(payMethod string, amount decimal.Decimal) error {
+	info, err := ParsePayMethod(strings.ToUpper(payMethod))
+	if err != nil {
+		return err
+	}
+	if !amount.Equal(amount.Truncate(info.Decimals)) {
+		return fmt.Errorf("amount %s exceeds %s precision of %d decimals", amount, payMethod, info.Decimals)
+	}
+	return nil
+}
+
+// CheckCryptoPayMethod 校验虚拟币 pay_method 与币种一致
+func CheckCryptoPayMethod(currency, payMethod string) error {
+	if payMethod == "" {
+		return errors.New("payMethod不能为空")
+	}
+	supported, ok := CryptoPayMethods[strings.ToUpper(currency)]
+	if !ok {
+		return errors.New("不支持的虚拟币币种")
+	}
+	if !InArray(payMethod, supported) {
+		return fmt.Errorf("虚拟币支付方式不支持，允许：%v", strings.Join(supported, ","))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"math/big"
+	"testing"
+)
+
+// base58Encode 测试用 Base58 编码
+func base58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append([]byte{base58Alphabet[mod.Int64()]}, out...)
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append([]byte{'1'}, out...)
+	}
+	return string(out)
+}
+
+func TestValidCryptoAddress(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solKey := base58Encode(pub)
+
+	tests := []struct {
+		name      string
+		payMethod string
+		addr      string
+		wantOK    bool
+	}{
+		{"TRON-有效", "USDT_TRC20", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", true},
+		{"TRON-校验和错误", "USDT_TRC20", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u", false},
+		{"TRON-EVM地址", "USDC_TRC20", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false},
+		{"EVM-EIP55", "USDT_ERC20", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", true},
+		{"EVM-全小写", "USDT_BEP20", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{"EVM-大小写校验错误", "USDC_POLYGON", "0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false},
+		{"EVM-长度错误", "USDT_ERC20", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA", false},
+		{"SOL-生成公钥", "USDT_SPL", solKey, true},
+		{"SOL-系统程序", "USDC_SPL", "11111111111111111111111111111111", true},
+		{"SOL-非法字符", "USDT_SPL", "0OIl" + solKey[4:], false},
+		{"SOL-长度错误", "USDT_SPL", "3QJmV3qfvL9SuYo34YihAf3sRCW3qSinyC", false},
+		{"未知支付方式", "BTC", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidCryptoAddress(tt.payMethod, tt.addr)
+			if (err == nil) != tt.wantOK {
+				t.Errorf("ValidCryptoAddress(%s, %s) err = %v, wantOK %v", tt.payMethod, tt.addr, err, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestToChecksumAddress(t *testing.T) {
+	for _, addr := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		if got := ToChecksumAddress(addr); got != addr {
+			t.Errorf("ToChecksumAddress(%s) = %s", addr, got)
+		}
+	}
+}
+
+func TestCheckCryptoNetworkAndAmount(t *testing.T) {
+	if err := CheckCryptoNetwork("USDT_TRC20", "tron"); err != nil {
+		t.Errorf("network TRON: %v", err)
+	}
+	if err := CheckCryptoNetwork("USDT_TRC20", "TRC20"); err != nil {
+		t.Errorf("network TRC20: %v", err)
+	}
+	if err := CheckCryptoNetwork("USDT_TRC20", "ERC20"); err == nil {
+		t.Error("network ERC20 for TRC20 should fail")
+	}
+	if err := CheckCryptoNetwork("USDT_SPL", ""); err != nil {
+		t.Errorf("empty network: %v", err)
+	}
+
+	if err := CheckCryptoAmount("USDT_TRC20", d("10.123456")); err != nil {
+		t.Errorf("6 decimals: %v", err)
+	}
+	if err := CheckCryptoAmount("USDT_TRC20", d("10.1234567")); err == nil {
+		t.Error("7 decimals on TRC20 should fail")
+	}
+	if err := CheckCryptoAmount("USDT_BEP20", d("10.1234567")); err != nil {
+		t.Errorf("BEP20 18 decimals: %v", err)
+	}
+}
+
+func TestCurrencyDecimals(t *testing.T) {
+	// USDT 在 BEP20 上 18 位，其余链 6 位，取最小值
+	if got, ok := CurrencyDecimals("usdt"); !ok || got != 6 {
+		t.Errorf("USDT decimals = %d/%v, want 6", got, ok)
+	}
+	if _, ok := CurrencyDecimals("BTC"); ok {
+		t.Error("BTC has no configured pay_method")
+	}
+	if _, ok := CurrencyDecimals("BRL"); ok {
+		t.Error("fiat currency should not resolve")
+	}
+}
@@ -10,30 +10,31 @@ type PayMethodInfo struct {
 	Currency  string
 	Chain     string
 	Protocol  string
+	Decimals  int32 // 代币精度（链上最小单位位数）
 }
 
 // 全量映射表（最终版）
 var PayMethodMap = map[string]PayMethodInfo{
 	// TRON
-	"USDT_TRC20": {"USDT_TRC20", "USDT", "TRON", "TRC20"},
-	"USDC_TRC20": {"USDC_TRC20", "USDC", "TRON", "TRC20"},
+	"USDT_TRC20": {"USDT_TRC20", "USDT", "TRON", "TRC20", 6},
+	"USDC_TRC20": {"USDC_TRC20", "USDC", "TRON", "TRC20", 6},
 
 	// ETH
-	"USDT_ERC20": {"USDT_ERC20", "USDT", "ETH", "ERC20"},
-	"USDC_ERC20": {"USDC_ERC20", "USDC", "ETH", "ERC20"},
+	"USDT_ERC20": {"USDT_ERC20", "USDT", "ETH", "ERC20", 6},
+	"USDC_ERC20": {"USDC_ERC20", "USDC", "ETH", "ERC20", 6},
 
 	// BSC
-	"USDT_BEP20": {"USDT_BEP20", "USDT", "BSC", "BEP20"},
-	"USDC_BEP20": {"USDC_BEP20", "USDC", "BSC", "BEP20"},
+	"USDT_BEP20": {"USDT_BEP20", "USDT", "BSC", "BEP20", 18},
+	"USDC_BEP20": {"USDC_BEP20", "USDC", "BSC", "BEP20", 18},
 
 	// Polygon
-	"USDT_POLYGON":        {"USDT_POLYGON", "USDT", "POLYGON", "ERC20"},
-	"USDC_POLYGON":        {"USDC_POLYGON", "USDC", "POLYGON", "ERC20"},
-	"USDC_POLYGON_NATIVE": {"USDC_POLYGON_NATIVE", "USDC", "POLYGON", "NATIVE"},
+	"USDT_POLYGON":        {"USDT_POLYGON", "USDT", "POLYGON", "ERC20", 6},
+	"USDC_POLYGON":        {"USDC_POLYGON", "USDC", "POLYGON", "ERC20", 6},
+	"USDC_POLYGON_NATIVE": {"USDC_POLYGON_NATIVE", "USDC", "POLYGON", "NATIVE", 6},
 
 	// Solana
-	"USDT_SPL": {"USDT_SPL", "USDT", "SOLANA", "SPL"},
-	"USDC_SPL": {"USDC_SPL", "USDC", "SOLANA", "SPL"},
+	"USDT_SPL": {"USDT_SPL", "USDT", "SOLANA", "SPL", 6},
+	"USDC_SPL": {"USDC_SPL", "USDC", "SOLANA", "SPL", 6},
 }
 
 // 根据 pay_method 获取三要素：币种、链、协议
@@ -66,6 +67,20 @@ func IsNative(payMethod string) bool {
 	return ok && info.Protocol == "NATIVE"
 }
 
+// CurrencyDecimals 虚拟币币种精度：同一币种多条链取最小代币精度，保证金额在任意链上都可出款（未配置链时 ok=false）
+func CurrencyDecimals(currency string) (decimals int32, ok bool) {
+	c := strings.ToUpper(currency)
+	for _, info := range PayMethodMap {
+		if info.Currency != c {
+			continue
+		}
+		if !ok || info.Decimals < decimals {
+			decimals, ok = info.Decimals, true
+		}
+	}
+	return decimals, ok
+}
+
 func IsCryptoCurrency(currency string) bool {
 	c := strings.ToUpper(currency)
 	return c == "USDT" || c == "USDC" || c == "BTC" || c == "SOL" || c == "MATIC" || c == "TRX" || c == "ETH" || c == "BNB" // 你可扩展
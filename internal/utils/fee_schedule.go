@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"wht-order-api/internal/dto"
+
+	"github.com/shopspring/decimal"
+)
+
+// FlatFeeQuote 平铺费率（单一比例 + 固定费用）
+func FlatFeeQuote(rate, fixed decimal.Decimal) dto.FeeQuote {
+	return dto.FeeQuote{Rate: rate, Fixed: fixed}
+}
+
+// EvaluateFeeSchedule 按费率方案选出适用档位
+//
+//	金额档：以单笔订单金额为计价基数
+//	交易量档：以商户当月累计交易量为计价基数（不含本单）
+//
+// 取 From <= 计价基数 的最高档；基数低于最低档时返回 false，由调用方回退平铺费率
+func EvaluateFeeSchedule(schedule dto.FeeSchedule, amount, monthlyVolume decimal.Decimal) (dto.FeeQuote, bool) {
+	if len(schedule.Tiers) == 0 {
+		return dto.FeeQuote{}, false
+	}
+
+	basis, modeName := amount, "amount"
+	if schedule.TierMode == dto.FeeTierModeVolume {
+		basis, modeName = monthlyVolume, "volume"
+	}
+
+	tiers := make([]dto.FeeTier, len(schedule.Tiers))
+	copy(tiers, schedule.Tiers)
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].From.LessThan(tiers[j].From) })
+
+	idx := -1
+	for i, t := range tiers {
+		if basis.GreaterThanOrEqual(t.From) {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return dto.FeeQuote{}, false
+	}
+
+	tier := tiers[idx]
+	return dto.FeeQuote{
+		Rate:   tier.Rate,
+		Fixed:  tier.Fixed,
+		MinFee: schedule.MinFee,
+		MaxFee: schedule.MaxFee,
+		Tier:   fmt.Sprintf("schedule#%d:%s>=%s", schedule.ID, modeName, tier.From.String()),
+	}, true
+}
+
+// MatchFeeSchedule 从候选方案中选出最匹配的一条：
+// 指定归属方优先于通用（owner_id=0），同级下指定支付方式优先于通用（pay_method 为空）
+func MatchFeeSchedule(schedules []dto.FeeSchedule, party int8, ownerID uint64, payMethod string) (dto.FeeSchedule, bool) {
+	best, bestScore := dto.FeeSchedule{}, -1
+	for _, s := range schedules {
+		if s.Party != party {
+			continue
+		}
+		score := 0
+		switch {
+		case s.OwnerID == ownerID && ownerID > 0:
+			score += 2
+		case s.OwnerID != 0:
+			continue
+		}
+		switch {
+		case s.PayMethod != "" && strings.EqualFold(s.PayMethod, payMethod):
+			score++
+		case s.PayMethod != "":
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	return best, bestScore >= 0
+}
@@ -13,6 +13,16 @@ import (
 	"time"
 )
 
+// HTTPStatusError 非 200 的 HTTP 响应
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return "HTTP错误: " + e.Status
+}
+
 // HttpPostJsonWithContext 发送带上下文的HTTP POST JSON请求
 func HttpPostJsonWithContext(ctx context.Context, url string, data interface{}) (string, error) {
 	// 创建HTTP客户端（带超时设置）
@@ -52,7 +62,7 @@ func HttpPostJsonWithContext(ctx context.Context, url string, data interface{})
 
 	// 检查响应状态码
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP错误: %s", resp.Status)
+		return "", &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	// 读取响应体
@@ -2,12 +2,30 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 )
 
-// DoWithRetry 执行带重试逻辑的函数
+// permanentError 不可重试的错误，DoWithRetry 遇到后立即返回
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记错误不可重试（如上游明确拒绝的 4xx）
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// DoWithRetry 执行带重试逻辑的函数（fn 返回 Permanent 包装的错误时不再重试）
 func DoWithRetry(ctx context.Context, maxRetries int, interval time.Duration, fn func() error) error {
 	var err error
 	for attempt := 1; attempt <= maxRetries; attempt++ {
@@ -16,6 +34,12 @@ func DoWithRetry(ctx context.Context, maxRetries int, interval time.Duration, fn
 			return nil
 		}
 
+		var pe *permanentError
+		if errors.As(err, &pe) {
+			log.Printf("[RETRY] 第 %d/%d 次失败(不可重试): %v", attempt, maxRetries, pe.err)
+			return pe.err
+		}
+
 		// 若超时或临时网络错误，进行重试
 		log.Printf("[RETRY] 第 %d/%d 次失败: %v", attempt, maxRetries, err)
 
@@ -1,86 +1,72 @@
 package utils
 
-import "wht-order-api/internal/constant"
+import (
+	"wht-order-api/internal/i18n"
 
-// 统一响应格式（支持中英文提示）
+	"github.com/gin-gonic/gin"
+)
+
+// 统一响应格式（msg 按请求语言渲染：Accept-Language / 商户偏好，默认中文）
 type Response struct {
 	Code    int         `json:"code"`
-	Msg     string      `json:"msg"`              // 中文描述
+	Msg     string      `json:"msg"`              // 请求语言描述
 	MsgEN   string      `json:"msg_en,omitempty"` // 英文描述
+	Detail  string      `json:"detail,omitempty"` // 错误详情（自定义错误信息）
 	Data    interface{} `json:"data,omitempty"`
 	TraceID string      `json:"trace_id,omitempty"`
 }
 
-// 成功响应
-func Success(data interface{}) Response {
-	return Response{
-		Code:  constant.CodeSuccess,
-		Msg:   "成功",
-		MsgEN: "Success",
-		Data:  data,
+// ResponseCodeKey 本次请求返回的业务码（请求指标按业务码统计）
+const ResponseCodeKey = "response_code"
+
+// newResponse 按错误码从文案目录渲染 msg / msg_en
+func newResponse(c *gin.Context, code int) Response {
+	if c != nil {
+		c.Set(ResponseCodeKey, code)
 	}
+	msg, _ := i18n.Message(i18n.FromContext(c), code)
+	msgEN, _ := i18n.Message(i18n.LangEN, code)
+	return Response{Code: code, Msg: msg, MsgEN: msgEN}
 }
 
-// 错误响应（自动从 constant 中获取中英文描述）
-func Error(code int) Response {
-	if info, exists := constant.GetErrorInfo(code); exists {
-		return Response{
-			Code:  code,
-			Msg:   info.CN,
-			MsgEN: info.EN,
-		}
-	}
-	return Response{
-		Code:  code,
-		Msg:   "未知错误",
-		MsgEN: "Unknown error",
-	}
+// 成功响应
+func Success(c *gin.Context, data interface{}) Response {
+	resp := newResponse(c, 0)
+	resp.Msg = i18n.Text(i18n.FromContext(c), i18n.TextSuccess)
+	resp.MsgEN = i18n.Text(i18n.LangEN, i18n.TextSuccess)
+	resp.Data = data
+	return resp
+}
+
+// 错误响应
+func Error(c *gin.Context, code int) Response {
+	return newResponse(c, code)
 }
 
 // 带数据的错误响应
-func ErrorWithData(code int, data interface{}) Response {
-	if info, exists := constant.GetErrorInfo(code); exists {
-		return Response{
-			Code:  code,
-			Msg:   info.CN,
-			MsgEN: info.EN,
-			Data:  data,
-		}
-	}
-	return Response{
-		Code:  code,
-		Msg:   "未知错误",
-		MsgEN: "Unknown error",
-		Data:  data,
-	}
+func ErrorWithData(c *gin.Context, code int, data interface{}) Response {
+	resp := newResponse(c, code)
+	resp.Data = data
+	return resp
 }
 
 // 错误响应（带 TraceID）
-func ErrorWithTrace(code int, traceID string) Response {
-	info, _ := constant.GetErrorInfo(code)
-	return Response{
-		Code:    code,
-		Msg:     info.CN,
-		MsgEN:   info.EN,
-		TraceID: traceID,
-	}
+func ErrorWithTrace(c *gin.Context, code int, traceID string) Response {
+	resp := newResponse(c, code)
+	resp.TraceID = traceID
+	return resp
 }
 
-// 自定义错误响应（仅中文）
-func CustomError(code int, message string) Response {
-	return Response{
-		Code:  code,
-		Msg:   message,
-		MsgEN: "Custom error",
-	}
+// 自定义错误响应（msg 仍为错误码文案，自定义信息放在 detail）
+func CustomError(c *gin.Context, code int, message string) Response {
+	resp := newResponse(c, code)
+	resp.Detail = message
+	return resp
 }
 
 // 自定义错误响应（带 TraceID）
-func CustomErrorWithTrace(code int, message string, traceID string) Response {
-	return Response{
-		Code:    code,
-		Msg:     message,
-		MsgEN:   "Custom error",
-		TraceID: traceID,
-	}
+func CustomErrorWithTrace(c *gin.Context, code int, message string, traceID string) Response {
+	resp := CustomError(c, code, message)
+	resp.TraceID = traceID
+	return resp
 }
@@ -26,31 +26,43 @@ func Calculate(
 	minFee decimal.Decimal,
 	hasMinFee int8, // ← int8 类型
 ) dto.SettlementResult {
-
-	// ======= 费率计算 =======
-	merchantPctAmt := orderAmount.Mul(merchantPct).Div(decimal.NewFromFloat(100.0))
-	agentPctAmt := orderAmount.Mul(agentPct).Div(decimal.NewFromFloat(100.0))
-	upPctAmt := orderAmount.Mul(upPct).Div(decimal.NewFromFloat(100.0))
-
-	// ======= 合计 =======
-	merchantTotal := merchantPctAmt.Add(merchantFixed)
-	agentTotal := agentPctAmt.Add(agentFixed)
-	upTotal := upPctAmt.Add(upFixed)
-
-	// ======= 核心新增：保底手续费逻辑 =======
+	merchantQuote := FlatFeeQuote(merchantPct, merchantFixed)
 	// hasMinFee == 1 → 启用；其它情况都不启用
 	if hasMinFee == 1 {
-		if merchantTotal.LessThan(minFee) {
-			merchantTotal = minFee
-		}
+		merchantQuote.MinFee = minFee
 	}
+	return CalculateByQuotes(
+		orderAmount,
+		merchantQuote,
+		[]dto.AgentQuote{{Level: 1, Quote: FlatFeeQuote(agentPct, agentFixed)}},
+		FlatFeeQuote(upPct, upFixed),
+		mode,
+		currency,
+	)
+}
+
+// CalculateByQuotes 按商户/各级代理/上游各自适用的费率（可来自阶梯费率方案）计算结算
+// agentQuotes 自下而上排列，代理佣金合计为各级分润之和
+func CalculateByQuotes(
+	orderAmount decimal.Decimal,
+	merchantQuote dto.FeeQuote,
+	agentQuotes []dto.AgentQuote,
+	upQuote dto.FeeQuote,
+	mode string,
+	currency string,
+) dto.SettlementResult {
+
+	// ======= 费率计算（含保底 / 封顶） =======
+	merchantPctAmt, merchantTotal := applyFeeQuote(orderAmount, merchantQuote)
+	agentLegs, agentPctAmt, agentFixed, agentTotal := calcAgentLegs(orderAmount, agentQuotes)
+	upPctAmt, upTotal := applyFeeQuote(orderAmount, upQuote)
 
 	// ======= 构建基础数据 =======
 	res := dto.SettlementResult{
 		OrderAmount: orderAmount,
 
 		MerchantFee:      merchantPctAmt,
-		MerchantFixed:    merchantFixed,
+		MerchantFixed:    merchantQuote.Fixed,
 		MerchantTotalFee: merchantTotal,
 
 		AgentFee:      agentPctAmt,
@@ -58,11 +70,19 @@ func Calculate(
 		AgentTotalFee: agentTotal,
 
 		UpFeePct:     upPctAmt,
-		UpFixed:      upFixed,
+		UpFixed:      upQuote.Fixed,
 		UpTotalFee:   upTotal,
 		UpstreamCost: orderAmount.Sub(upTotal),
 
 		Currency: currency,
+
+		MerchantFeeTier: merchantQuote.Tier,
+		UpFeeTier:       upQuote.Tier,
+
+		AgentLegs: agentLegs,
+	}
+	if len(agentQuotes) > 0 {
+		res.AgentFeeTier = agentQuotes[0].Quote.Tier
 	}
 
 	// ======= 结算模式 =======
@@ -97,6 +117,49 @@ func Calculate(
 	return res
 }
 
+// calcAgentLegs 逐级计算代理分润：
+// 每级按自身费率算出本级费用，分润 = 本级费用 - 下级已分配费用（不足时该级分润为 0），
+// 因此各级分润合计 = 最高的一级费用。返回 (分润明细, 合计对应的比例费用, 固定费用, 合计)
+// AgentID 为 0（旧入口 Calculate）时不生成明细，由结算按商户直属代理单笔入账
+func calcAgentLegs(orderAmount decimal.Decimal, quotes []dto.AgentQuote) ([]dto.AgentLeg, decimal.Decimal, decimal.Decimal, decimal.Decimal) {
+	var legs []dto.AgentLeg
+	pct, fixed, covered := decimal.Zero, decimal.Zero, decimal.Zero
+	for _, q := range quotes {
+		levelPct, levelFee := applyFeeQuote(orderAmount, q.Quote)
+		commission := MaxDecimal(levelFee.Sub(covered), decimal.Zero)
+		if levelFee.GreaterThan(covered) {
+			covered = levelFee
+			pct, fixed = levelPct, q.Quote.Fixed
+		}
+		if q.AgentID == 0 {
+			continue
+		}
+		legs = append(legs, dto.AgentLeg{
+			AgentID:    q.AgentID,
+			Level:      q.Level,
+			Rate:       q.Quote.Rate,
+			Fixed:      q.Quote.Fixed,
+			Fee:        levelFee,
+			Commission: commission,
+			Tier:       q.Quote.Tier,
+		})
+	}
+	return legs, pct, fixed, covered
+}
+
+// applyFeeQuote 返回 (比例费用, 合计费用)；合计 = 比例费用 + 固定费用，再按保底/封顶修正
+func applyFeeQuote(orderAmount decimal.Decimal, q dto.FeeQuote) (decimal.Decimal, decimal.Decimal) {
+	pctAmt := orderAmount.Mul(q.Rate).Div(decimal.NewFromFloat(100.0))
+	total := pctAmt.Add(q.Fixed)
+	if q.MinFee.GreaterThan(decimal.Zero) && total.LessThan(q.MinFee) {
+		total = q.MinFee
+	}
+	if q.MaxFee.GreaterThan(decimal.Zero) && total.GreaterThan(q.MaxFee) {
+		total = q.MaxFee
+	}
+	return pctAmt, total
+}
+
 // MaxDecimal 返回最大值（防止负数）
 func MaxDecimal(a, b decimal.Decimal) decimal.Decimal {
 	if a.Cmp(b) >= 0 {
@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"testing"
+	"wht-order-api/internal/dto"
+
+	"github.com/shopspring/decimal"
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func TestEvaluateFeeSchedule(t *testing.T) {
+	amountBands := dto.FeeSchedule{
+		ID:       1,
+		TierMode: dto.FeeTierModeAmount,
+		Tiers: []dto.FeeTier{
+			{From: d("5000"), Rate: d("1.5"), Fixed: d("0")},
+			{From: d("0"), Rate: d("3"), Fixed: d("2")},
+			{From: d("1000"), Rate: d("2"), Fixed: d("1")},
+		},
+		MaxFee: d("80"),
+	}
+	volumeTiers := dto.FeeSchedule{
+		ID:       2,
+		TierMode: dto.FeeTierModeVolume,
+		Tiers: []dto.FeeTier{
+			{From: d("100000"), Rate: d("2.5")},
+			{From: d("1000000"), Rate: d("2")},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		schedule dto.FeeSchedule
+		amount   string
+		volume   string
+		wantHit  bool
+		wantRate string
+		wantTier string
+	}{
+		{"金额档-最低档", amountBands, "500", "0", true, "3", "schedule#1:amount>=0"},
+		{"金额档-边界含起点", amountBands, "1000", "0", true, "2", "schedule#1:amount>=1000"},
+		{"金额档-最高档", amountBands, "8000", "0", true, "1.5", "schedule#1:amount>=5000"},
+		{"交易量档-未达门槛", volumeTiers, "500", "99999.99", false, "0", ""},
+		{"交易量档-第一档", volumeTiers, "500", "100000", true, "2.5", "schedule#2:volume>=100000"},
+		{"交易量档-忽略单笔金额", volumeTiers, "9999999", "2500000", true, "2", "schedule#2:volume>=1000000"},
+		{"无档位", dto.FeeSchedule{ID: 3}, "500", "0", false, "0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, hit := EvaluateFeeSchedule(tt.schedule, d(tt.amount), d(tt.volume))
+			if hit != tt.wantHit {
+				t.Fatalf("hit = %v, want %v", hit, tt.wantHit)
+			}
+			if !q.Rate.Equal(d(tt.wantRate)) {
+				t.Errorf("rate = %s, want %s", q.Rate, tt.wantRate)
+			}
+			if q.Tier != tt.wantTier {
+				t.Errorf("tier = %q, want %q", q.Tier, tt.wantTier)
+			}
+			if hit && !q.MaxFee.Equal(tt.schedule.MaxFee) {
+				t.Errorf("maxFee = %s, want %s", q.MaxFee, tt.schedule.MaxFee)
+			}
+		})
+	}
+}
+
+func TestMatchFeeSchedule(t *testing.T) {
+	list := []dto.FeeSchedule{
+		{ID: 1, Party: dto.FeePartyMerchant},
+		{ID: 2, Party: dto.FeePartyMerchant, PayMethod: "PIX"},
+		{ID: 3, Party: dto.FeePartyMerchant, OwnerID: 88},
+		{ID: 4, Party: dto.FeePartyMerchant, OwnerID: 88, PayMethod: "PIX"},
+		{ID: 5, Party: dto.FeePartyMerchant, OwnerID: 99},
+		{ID: 6, Party: dto.FeePartyAgent, OwnerID: 7},
+	}
+
+	tests := []struct {
+		name      string
+		party     int8
+		ownerID   uint64
+		payMethod string
+		wantHit   bool
+		wantID    uint64
+	}{
+		{"商户+支付方式", dto.FeePartyMerchant, 88, "pix", true, 4},
+		{"商户通用支付方式", dto.FeePartyMerchant, 88, "SPEI", true, 3},
+		{"通用+支付方式", dto.FeePartyMerchant, 10, "PIX", true, 2},
+		{"全通用", dto.FeePartyMerchant, 10, "", true, 1},
+		{"代理指定", dto.FeePartyAgent, 7, "PIX", true, 6},
+		{"代理未配置", dto.FeePartyAgent, 8, "", false, 0},
+		{"上游未配置", dto.FeePartyUpstream, 1, "", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hit := MatchFeeSchedule(list, tt.party, tt.ownerID, tt.payMethod)
+			if hit != tt.wantHit {
+				t.Fatalf("hit = %v, want %v", hit, tt.wantHit)
+			}
+			if s.ID != tt.wantID {
+				t.Errorf("schedule id = %d, want %d", s.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func level1(q dto.FeeQuote) []dto.AgentQuote {
+	return []dto.AgentQuote{{AgentID: 1, Level: 1, Quote: q}}
+}
+
+func TestCalculateByQuotes(t *testing.T) {
+	tiered := dto.FeeQuote{Rate: d("2"), Fixed: d("1"), MaxFee: d("15"), Tier: "schedule#1:amount>=1000"}
+
+	tests := []struct {
+		name           string
+		amount         string
+		merchant       dto.FeeQuote
+		agent          []dto.AgentQuote
+		up             dto.FeeQuote
+		mode           string
+		wantMerchant   string
+		wantRecv       string
+		wantAgent      string
+		wantProfit     string
+		wantMerchantTr string
+	}{
+		{
+			name:     "平台付佣-平铺费率",
+			amount:   "1000",
+			merchant: FlatFeeQuote(d("3"), d("2")), agent: level1(FlatFeeQuote(d("0.5"), d("0"))), up: FlatFeeQuote(d("1"), d("1")),
+			mode:         "agent_from_platform",
+			wantMerchant: "32", wantRecv: "968", wantAgent: "5", wantProfit: "16",
+		},
+		{
+			name:     "商户付佣-平铺费率",
+			amount:   "1000",
+			merchant: FlatFeeQuote(d("3"), d("2")), agent: level1(FlatFeeQuote(d("0.5"), d("0"))), up: FlatFeeQuote(d("1"), d("1")),
+			mode:         "agent_from_merchant",
+			wantMerchant: "32", wantRecv: "963", wantAgent: "5", wantProfit: "21",
+		},
+		{
+			name:     "平台付佣-阶梯未触顶",
+			amount:   "500",
+			merchant: tiered, agent: level1(FlatFeeQuote(d("0.2"), d("0"))), up: FlatFeeQuote(d("1"), d("0")),
+			mode:         "agent_from_platform",
+			wantMerchant: "11", wantRecv: "489", wantAgent: "1", wantProfit: "5", wantMerchantTr: "schedule#1:amount>=1000",
+		},
+		{
+			name:     "平台付佣-封顶",
+			amount:   "2000",
+			merchant: tiered, agent: level1(FlatFeeQuote(d("0.2"), d("0"))), up: FlatFeeQuote(d("0.5"), d("0")),
+			mode:         "agent_from_platform",
+			wantMerchant: "15", wantRecv: "1985", wantAgent: "4", wantProfit: "1", wantMerchantTr: "schedule#1:amount>=1000",
+		},
+		{
+			name:     "商户付佣-封顶",
+			amount:   "2000",
+			merchant: tiered, agent: level1(FlatFeeQuote(d("0.2"), d("0"))), up: FlatFeeQuote(d("0.5"), d("0")),
+			mode:         "agent_from_merchant",
+			wantMerchant: "15", wantRecv: "1981", wantAgent: "4", wantProfit: "5", wantMerchantTr: "schedule#1:amount>=1000",
+		},
+		{
+			name:     "平台付佣-保底",
+			amount:   "100",
+			merchant: dto.FeeQuote{Rate: d("1"), MinFee: d("5")}, agent: level1(FlatFeeQuote(d("0"), d("1"))), up: FlatFeeQuote(d("1"), d("0")),
+			mode:         "agent_from_platform",
+			wantMerchant: "5", wantRecv: "95", wantAgent: "1", wantProfit: "3",
+		},
+		{
+			name:     "商户付佣-保底",
+			amount:   "100",
+			merchant: dto.FeeQuote{Rate: d("1"), MinFee: d("5")}, agent: level1(FlatFeeQuote(d("0"), d("1"))), up: FlatFeeQuote(d("1"), d("0")),
+			mode:         "agent_from_merchant",
+			wantMerchant: "5", wantRecv: "94", wantAgent: "1", wantProfit: "4",
+		},
+		{
+			name:     "平台付佣-利润不为负",
+			amount:   "100",
+			merchant: FlatFeeQuote(d("1"), d("0")), agent: level1(FlatFeeQuote(d("1"), d("0"))), up: FlatFeeQuote(d("2"), d("0")),
+			mode:         "agent_from_platform",
+			wantMerchant: "1", wantRecv: "99", wantAgent: "1", wantProfit: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := CalculateByQuotes(d(tt.amount), tt.merchant, tt.agent, tt.up, tt.mode, "BRL")
+			if !res.MerchantTotalFee.Equal(d(tt.wantMerchant)) {
+				t.Errorf("merchantTotalFee = %s, want %s", res.MerchantTotalFee, tt.wantMerchant)
+			}
+			if !res.MerchantRecv.Equal(d(tt.wantRecv)) {
+				t.Errorf("merchantRecv = %s, want %s", res.MerchantRecv, tt.wantRecv)
+			}
+			if !res.AgentIncome.Equal(d(tt.wantAgent)) {
+				t.Errorf("agentIncome = %s, want %s", res.AgentIncome, tt.wantAgent)
+			}
+			if !res.PlatformProfit.Equal(d(tt.wantProfit)) {
+				t.Errorf("platformProfit = %s, want %s", res.PlatformProfit, tt.wantProfit)
+			}
+			if res.MerchantFeeTier != tt.wantMerchantTr {
+				t.Errorf("merchantFeeTier = %q, want %q", res.MerchantFeeTier, tt.wantMerchantTr)
+			}
+		})
+	}
+}
+
+// 旧入口 Calculate 与平铺费率的 CalculateByQuotes 结果一致（hasMinFee 仅开关商户保底）
+func TestCalculateCompatible(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		hasMinFee int8
+		want      string
+	}{
+		{"平台付佣-保底关闭", "agent_from_platform", 0, "1.5"},
+		{"平台付佣-保底开启", "agent_from_platform", 1, "3"},
+		{"商户付佣-保底关闭", "agent_from_merchant", 0, "1.5"},
+		{"商户付佣-保底开启", "agent_from_merchant", 1, "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Calculate(d("50"), d("2"), d("0.5"), d("0.2"), d("0"), d("1"), d("0"), tt.mode, "MXN", d("3"), tt.hasMinFee)
+			if !res.MerchantTotalFee.Equal(d(tt.want)) {
+				t.Errorf("merchantTotalFee = %s, want %s", res.MerchantTotalFee, tt.want)
+			}
+			if res.MerchantFeeTier != "" {
+				t.Errorf("flat rate should not record a tier, got %q", res.MerchantFeeTier)
+			}
+		})
+	}
+}
+
+func TestCalculateAgentLegs(t *testing.T) {
+	merchant := FlatFeeQuote(d("5"), d("0"))
+	up := FlatFeeQuote(d("1"), d("0"))
+	chain := func(rates ...string) []dto.AgentQuote {
+		quotes := make([]dto.AgentQuote, 0, len(rates))
+		for i, r := range rates {
+			quotes = append(quotes, dto.AgentQuote{AgentID: uint64(100 + i), Level: i + 1, Quote: FlatFeeQuote(d(r), d("0"))})
+		}
+		return quotes
+	}
+
+	tests := []struct {
+		name       string
+		agents     []dto.AgentQuote
+		mode       string
+		wantLegs   []string // 各级分润
+		wantAgent  string
+		wantRecv   string
+		wantProfit string
+	}{
+		{"平台付佣-无代理", nil, "agent_from_platform", nil, "0", "950", "40"},
+		{"平台付佣-单级", chain("1"), "agent_from_platform", []string{"10"}, "10", "950", "30"},
+		{"平台付佣-三级差额", chain("1", "1.5", "2.2"), "agent_from_platform", []string{"10", "5", "7"}, "22", "950", "18"},
+		{"平台付佣-上级费率低于下级", chain("2", "1.5", "3"), "agent_from_platform", []string{"20", "0", "10"}, "30", "950", "10"},
+		{"商户付佣-单级", chain("1"), "agent_from_merchant", []string{"10"}, "10", "940", "40"},
+		{"商户付佣-三级差额", chain("1", "1.5", "2.2"), "agent_from_merchant", []string{"10", "5", "7"}, "22", "928", "40"},
+		{"商户付佣-上级费率低于下级", chain("2", "1.5", "3"), "agent_from_merchant", []string{"20", "0", "10"}, "30", "920", "40"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := CalculateByQuotes(d("1000"), merchant, tt.agents, up, tt.mode, "PEN")
+			if len(res.AgentLegs) != len(tt.wantLegs) {
+				t.Fatalf("legs = %d, want %d", len(res.AgentLegs), len(tt.wantLegs))
+			}
+			sum := decimal.Zero
+			for i, leg := range res.AgentLegs {
+				if leg.Level != i+1 || leg.AgentID != uint64(100+i) {
+					t.Errorf("leg[%d] = agent %d level %d", i, leg.AgentID, leg.Level)
+				}
+				if !leg.Commission.Equal(d(tt.wantLegs[i])) {
+					t.Errorf("leg[%d] commission = %s, want %s", i, leg.Commission, tt.wantLegs[i])
+				}
+				sum = sum.Add(leg.Commission)
+			}
+			if !res.AgentIncome.Equal(d(tt.wantAgent)) || !res.AgentTotalFee.Equal(d(tt.wantAgent)) {
+				t.Errorf("agentIncome = %s, agentTotalFee = %s, want %s", res.AgentIncome, res.AgentTotalFee, tt.wantAgent)
+			}
+			if !sum.Equal(res.AgentIncome) {
+				t.Errorf("legs sum = %s, agentIncome = %s", sum, res.AgentIncome)
+			}
+			if !res.MerchantRecv.Equal(d(tt.wantRecv)) {
+				t.Errorf("merchantRecv = %s, want %s", res.MerchantRecv, tt.wantRecv)
+			}
+			if !res.PlatformProfit.Equal(d(tt.wantProfit)) {
+				t.Errorf("platformProfit = %s, want %s", res.PlatformProfit, tt.wantProfit)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+-- 商户级代付复核规则（主库）
+CREATE TABLE IF NOT EXISTS `w_merchant_payout_approval` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `m_id` bigint unsigned NOT NULL COMMENT '商户ID',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `threshold` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '复核阈值，0表示不按金额复核',
+  `first_time_beneficiary` tinyint NOT NULL DEFAULT '0' COMMENT '首次出款账户是否复核 0否 1是',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '状态 0停用 1启用',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_m_currency` (`m_id`, `currency`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商户代付复核规则';
+
+-- 资金流水查询 / 对账单导出（按商户+币种+时间区间扫描）
+ALTER TABLE `w_money_log` ADD INDEX `idx_uid_currency_time` (`uid`, `currency`, `create_time`);
+
+-- 资金流水幂等唯一约束见 money_log_idempotency.sql（需先清理历史重复流水）
+
+-- 商户钱包：每个商户每个币种一条
+ALTER TABLE `w_merchant_money` ADD UNIQUE KEY `uniq_uid_currency` (`uid`, `currency`);
+
+-- 商户钱包换汇记录
+CREATE TABLE IF NOT EXISTS `w_merchant_fx_conversion` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `conversion_no` varchar(64) NOT NULL COMMENT '换汇单号（幂等键）',
+  `uid` bigint unsigned NOT NULL COMMENT '商户ID',
+  `from_currency` varchar(10) NOT NULL COMMENT '源币种',
+  `to_currency` varchar(10) NOT NULL COMMENT '目标币种',
+  `from_amount` decimal(18,4) NOT NULL COMMENT '转出金额',
+  `to_amount` decimal(18,4) NOT NULL COMMENT '转入金额',
+  `rate` decimal(20,8) NOT NULL COMMENT '汇率（1 源币种 = rate 目标币种）',
+  `operator` varchar(30) NOT NULL COMMENT '操作人',
+  `remark` varchar(255) DEFAULT NULL COMMENT '备注',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_conversion_no` (`conversion_no`),
+  KEY `idx_uid_time` (`uid`, `create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商户钱包换汇记录';
+
+-- 商户预登记提现账户（变更后进入冷却期）
+CREATE TABLE IF NOT EXISTS `w_merchant_withdraw_destination` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `m_id` bigint unsigned NOT NULL COMMENT '商户ID',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `label` varchar(50) DEFAULT NULL COMMENT '账户别名',
+  `account_no` varchar(128) NOT NULL COMMENT '收款账号/虚拟币地址',
+  `account_name` varchar(64) NOT NULL COMMENT '收款人',
+  `bank_code` varchar(30) DEFAULT NULL COMMENT '平台银行编码',
+  `bank_name` varchar(64) DEFAULT NULL COMMENT '银行名称',
+  `pay_method` varchar(30) DEFAULT NULL COMMENT '支付方式（虚拟币为链）',
+  `account_type` varchar(30) DEFAULT NULL COMMENT '账户类型',
+  `cci_no` varchar(64) DEFAULT NULL COMMENT '银行间账户',
+  `identity_num` varchar(30) DEFAULT NULL COMMENT '证件号码',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '0停用 1启用',
+  `active_at` datetime NOT NULL COMMENT '冷却期结束时间',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  `update_time` datetime NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_m_id` (`m_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商户提现账户';
+
+-- 提现手续费规则（m_id=0 为币种默认规则）
+CREATE TABLE IF NOT EXISTS `w_merchant_withdraw_fee` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `m_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '商户ID，0 为默认',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `fee_rate` decimal(10,4) NOT NULL DEFAULT '0.0000' COMMENT '费率（%）',
+  `fixed_fee` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '单笔固定费用',
+  `min_fee` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '最低手续费',
+  `max_fee` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '最高手续费，0 不限',
+  `min_amount` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '单笔最低提现',
+  `max_amount` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '单笔最高提现，0 不限',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '0停用 1启用',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_m_currency` (`m_id`, `currency`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='提现手续费规则';
+
+-- 阶梯/交易量费率方案（同一系统通道+币种下按 适用方 → 归属ID → 支付方式 匹配，未命中使用通道平铺费率）
+CREATE TABLE IF NOT EXISTS `w_fee_schedule` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `party` tinyint NOT NULL COMMENT '适用方 1商户 2代理 3上游',
+  `owner_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '商户ID/代理ID/上游通道产品ID，0 为通用',
+  `sys_channel_id` bigint NOT NULL COMMENT '系统通道ID',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `pay_method` varchar(30) NOT NULL DEFAULT '' COMMENT '支付方式，空为通用',
+  `tier_mode` tinyint NOT NULL DEFAULT '1' COMMENT '1按单笔金额分档 2按商户当月交易量分档',
+  `tiers` json NOT NULL COMMENT '阶梯档位 [{"from":"0","rate":"3","fixed":"1"}]',
+  `min_fee` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '保底手续费，0 不限',
+  `max_fee` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '封顶手续费，0 不限',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '0停用 1启用',
+  `remark` varchar(255) DEFAULT NULL COMMENT '备注',
+  `update_time` datetime DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_schedule` (`sys_channel_id`, `currency`, `party`, `owner_id`, `pay_method`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='阶梯/交易量费率方案';
+
+-- 多级代理：w_agent_merchant 中 m_id 也可以是下级代理ID，(a_id=上级代理, m_id=下级代理) 表示上级在该通道的费率，
+-- 结算时自商户直属代理起逐级向上查找，每级分得本级费率与下级费率之间的差额
+ALTER TABLE `w_agent_merchant` ADD INDEX `idx_m_channel` (`m_id`, `sys_channel_id`);
+
+-- 商户累计限额（m_id=0 为币种默认规则，商户专属规则优先；金额/笔数为 0 表示不限）
+CREATE TABLE IF NOT EXISTS `w_merchant_limit` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `m_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '商户ID，0 为默认',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `order_type` tinyint NOT NULL COMMENT '订单类型 1代收 2代付',
+  `daily_amount` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '单日累计金额上限',
+  `daily_count` bigint NOT NULL DEFAULT '0' COMMENT '单日累计笔数上限',
+  `monthly_amount` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '单月累计金额上限',
+  `monthly_count` bigint NOT NULL DEFAULT '0' COMMENT '单月累计笔数上限',
+  `payer_daily_amount` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '单个收款账户单日金额上限（仅代付）',
+  `payer_daily_count` bigint NOT NULL DEFAULT '0' COMMENT '单个收款账户单日笔数上限（仅代付）',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '0停用 1启用',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_m_currency_type` (`m_id`, `currency`, `order_type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商户累计限额';
+
+-- 系统通道单日累计限额（金额/笔数为 0 表示不限）
+CREATE TABLE IF NOT EXISTS `w_channel_limit` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `sys_channel_id` bigint NOT NULL COMMENT '系统通道ID',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `daily_amount` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '单日累计金额上限',
+  `daily_count` bigint NOT NULL DEFAULT '0' COMMENT '单日累计笔数上限',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '0停用 1启用',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_channel_currency` (`sys_channel_id`, `currency`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='系统通道单日累计限额';
+
+-- 风控规则（规则引擎定时热加载；m_id=0、order_type=0 为通用）
+-- params 示例：
+--   velocity:       {"dimension":"ip","window":"10m","max":5}            dimension: ip/acc_no/email/phone/identity_num/merchant
+--   amount_anomaly: {"minSamples":20,"multiple":"5","stdDev":"3"}        对比商户历史均值/标准差
+--   round_amount:   {"unit":"1000","minAmount":"10000"}                 整数金额
+--   structuring:    {"threshold":"10000","margin":"0.1","window":"24h","max":3,"dimension":"acc_no"}  略低于阈值的拆单
+--   blacklist:      {"fields":["ip","acc_no"]}                           为空则校验全部字段
+CREATE TABLE IF NOT EXISTS `w_risk_rule` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `name` varchar(64) NOT NULL COMMENT '规则名称',
+  `rule_type` varchar(32) NOT NULL COMMENT '规则类型 blacklist/velocity/amount_anomaly/round_amount/structuring',
+  `order_type` tinyint NOT NULL DEFAULT '0' COMMENT '订单类型 0全部 1代收 2代付',
+  `m_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '商户ID，0 为全部商户',
+  `params` json DEFAULT NULL COMMENT '规则参数',
+  `action` tinyint NOT NULL DEFAULT '2' COMMENT '命中动作 1放行(仅计分) 2复核 3拒绝',
+  `score` int NOT NULL DEFAULT '0' COMMENT '命中分值',
+  `priority` int NOT NULL DEFAULT '0' COMMENT '优先级，越小越先执行',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '0停用 1启用',
+  `update_time` datetime DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='风控规则';
+
+-- 风控黑名单（m_id=0 为全局）
+CREATE TABLE IF NOT EXISTS `w_risk_blacklist` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `field` varchar(32) NOT NULL COMMENT '字段 ip/acc_no/email/phone/identity_num',
+  `value` varchar(128) NOT NULL COMMENT '值',
+  `m_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '商户ID，0 为全局',
+  `remark` varchar(255) DEFAULT NULL COMMENT '备注',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '0停用 1启用',
+  `update_time` datetime DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_field_value` (`field`, `value`, `m_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='风控黑名单';
+
+-- 上游错误码映射（interface_code='*' 为通用规则，接口专属规则优先）
+CREATE TABLE IF NOT EXISTS `w_upstream_error_mapping` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `interface_code` varchar(64) NOT NULL DEFAULT '*' COMMENT '上游接口编码，* 为通用',
+  `upstream_code` varchar(64) NOT NULL DEFAULT '' COMMENT '上游返回码，空为不限',
+  `msg_keyword` varchar(128) NOT NULL DEFAULT '' COMMENT '上游返回信息关键字，空为不限',
+  `code` int NOT NULL COMMENT '映射后的平台错误码',
+  `retryable` tinyint NOT NULL DEFAULT '0' COMMENT '是否可重试/切换通道 0否 1是',
+  `priority` int NOT NULL DEFAULT '0' COMMENT '优先级，越小越先匹配',
+  `remark` varchar(255) DEFAULT NULL COMMENT '备注',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '0停用 1启用',
+  PRIMARY KEY (`id`),
+  KEY `idx_interface_code` (`interface_code`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='上游错误码映射';
+
+-- 示例：
+-- INSERT INTO `w_upstream_error_mapping` (`interface_code`, `upstream_code`, `msg_keyword`, `code`, `retryable`, `remark`) VALUES
+--   ('*', '', 'insufficient', 3003, 1, '上游余额不足，切换通道'),
+--   ('*', '', 'invalid account', 3004, 0, '收款账户无效，不再切换'),
+--   ('*', '', 'too many requests', 3008, 1, '上游限频'),
+--   ('*', '', 'maintenance', 3009, 1, '上游维护'),
+--   ('*', '', 'duplicate', 3014, 0, '订单重复');
+
+-- 商户响应语言偏好（zh/en/es/pt，为空按 Accept-Language，默认中文）
+ALTER TABLE `w_merchant` ADD COLUMN `language` varchar(8) NOT NULL DEFAULT '' COMMENT '响应语言偏好 zh/en/es/pt';
+
+-- 上游回调原始报文归档（直连回调入口，争议处理用）
+CREATE TABLE IF NOT EXISTS `w_upstream_callback_archive` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+  `interface_code` varchar(64) NOT NULL COMMENT '上游接口编码',
+  `mode` varchar(16) NOT NULL COMMENT 'receive/payout',
+  `m_order_id` varchar(64) DEFAULT NULL COMMENT '平台交易订单号',
+  `up_order_id` varchar(64) DEFAULT NULL COMMENT '上游流水号',
+  `up_status` varchar(16) DEFAULT NULL COMMENT '回调状态',
+  `remote_ip` varchar(64) NOT NULL COMMENT '实际来源IP',
+  `headers` text COMMENT '原始请求头(JSON)',
+  `body` mediumtext COMMENT '原始请求体',
+  `result` tinyint NOT NULL COMMENT '1已入队 2拒绝 3入队失败',
+  `remark` varchar(255) DEFAULT NULL COMMENT '拒绝/失败原因',
+  `create_time` datetime NOT NULL COMMENT '接收时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_m_order_id` (`m_order_id`),
+  KEY `idx_up_order_id` (`up_order_id`),
+  KEY `idx_create_time` (`create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='上游回调原始报文归档';
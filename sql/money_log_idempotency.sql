@@ -0,0 +1,14 @@
+-- 资金流水幂等（主库）：同一商户同一单号同一类型同一改派次数只记一次（资金变动前先插流水，冲突即视为已处理）
+-- 需在上线自动改派、提现等依赖流水去重的功能之前执行
+
+-- 1 预检查：历史重复流水会导致唯一约束创建失败，结果非空时先人工核对合并后再继续
+SELECT `uid`, `order_no`, `type`, COUNT(*) AS `cnt`, MIN(`id`) AS `first_id`, MAX(`id`) AS `last_id`
+FROM `w_money_log`
+GROUP BY `uid`, `order_no`, `type`
+HAVING COUNT(*) > 1;
+
+-- 2 改派次数：order_no 始终为平台订单号，同一订单多次改派调整冻结时按 attempt 区分
+ALTER TABLE `w_money_log` ADD COLUMN `attempt` int NOT NULL DEFAULT '0' COMMENT '改派次数，默认0' AFTER `type`;
+
+-- 3 唯一约束
+ALTER TABLE `w_money_log` ADD UNIQUE KEY `uniq_uid_order_type_attempt` (`uid`, `order_no`, `type`, `attempt`);
@@ -0,0 +1,180 @@
+-- 代付订单改派链路（订单库，不分表）
+CREATE TABLE IF NOT EXISTS `p_out_order_reassign` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `order_id` bigint unsigned NOT NULL COMMENT '平台订单ID',
+  `attempt` int NOT NULL DEFAULT '0' COMMENT '尝试序号，0为首次下单',
+  `up_order_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '上游交易订单ID',
+  `supplier_id` bigint NOT NULL DEFAULT '0' COMMENT '上游供应商ID',
+  `up_channel_id` bigint NOT NULL DEFAULT '0' COMMENT '上游通道ID',
+  `up_channel_code` varchar(30) DEFAULT NULL COMMENT '上游通道编码',
+  `result` tinyint NOT NULL DEFAULT '0' COMMENT '结果 0处理中 1成功 2失败 3提交失败',
+  `reason` varchar(255) DEFAULT NULL COMMENT '失败原因',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `update_time` datetime DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_order_attempt` (`order_id`, `attempt`),
+  KEY `idx_up_order_id` (`up_order_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='代付订单改派链路';
+
+-- 批量代付批次（订单库，不分表）
+CREATE TABLE IF NOT EXISTS `p_out_batch` (
+  `batch_id` bigint unsigned NOT NULL COMMENT '平台批次ID',
+  `m_id` bigint unsigned NOT NULL COMMENT '商户ID',
+  `batch_no` varchar(64) NOT NULL COMMENT '商户批次号',
+  `pay_type` varchar(30) NOT NULL COMMENT '通道编码',
+  `currency` char(3) NOT NULL COMMENT '货币代码',
+  `file_type` varchar(10) NOT NULL COMMENT '文件类型 csv/json',
+  `file_hash` varchar(32) NOT NULL COMMENT '文件MD5',
+  `total_count` int NOT NULL DEFAULT '0' COMMENT '总笔数',
+  `total_amount` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '总金额',
+  `freeze_amount` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '整批冻结金额',
+  `submitted_count` int NOT NULL DEFAULT '0' COMMENT '已提交笔数',
+  `fail_count` int NOT NULL DEFAULT '0' COMMENT '失败笔数',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '批次状态 0处理中 1已完成',
+  `notify_url` varchar(255) DEFAULT NULL COMMENT '批次完成回调地址',
+  `order_notify_url` varchar(255) DEFAULT NULL COMMENT '单笔订单回调地址',
+  `notify_status` tinyint NOT NULL DEFAULT '0' COMMENT '批次回调状态 0未通知 1成功 2失败',
+  `client_ip` varchar(32) DEFAULT NULL COMMENT '提交IP',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  `finish_time` datetime DEFAULT NULL COMMENT '完成时间',
+  PRIMARY KEY (`batch_id`),
+  UNIQUE KEY `uniq_merchant_batch` (`m_id`, `batch_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='批量代付批次';
+
+-- 批量代付批次行
+CREATE TABLE IF NOT EXISTS `p_out_batch_item` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `batch_id` bigint unsigned NOT NULL COMMENT '平台批次ID',
+  `line_no` int NOT NULL COMMENT '行号',
+  `tran_flow` varchar(64) NOT NULL COMMENT '商户订单号',
+  `amount` decimal(18,4) NOT NULL COMMENT '订单金额',
+  `freeze_amount` decimal(18,4) NOT NULL COMMENT '预留冻结金额',
+  `payload` text NOT NULL COMMENT '行原始数据JSON',
+  `order_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '平台订单ID',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '行状态 0待处理 1已提交 2失败 3转人工',
+  `error_msg` varchar(255) DEFAULT NULL COMMENT '失败原因',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  `update_time` datetime DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_batch_line` (`batch_id`, `line_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='批量代付批次行';
+
+-- 大额代付复核（订单库，不分表）
+CREATE TABLE IF NOT EXISTS `p_out_approval` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `order_id` bigint unsigned NOT NULL COMMENT '平台订单ID',
+  `m_id` bigint unsigned NOT NULL COMMENT '商户ID',
+  `m_order_id` varchar(50) NOT NULL COMMENT '商户订单号',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `amount` decimal(18,4) NOT NULL COMMENT '订单金额',
+  `freeze_amount` decimal(18,4) NOT NULL COMMENT '冻结金额',
+  `reason` varchar(255) DEFAULT NULL COMMENT '触发复核原因',
+  `payload` json DEFAULT NULL COMMENT '商户原始请求',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '0待复核 1通过 2驳回 3超时',
+  `order_create_time` datetime NOT NULL COMMENT '订单创建时间',
+  `expire_at` datetime NOT NULL COMMENT '超时时间',
+  `operator` varchar(50) DEFAULT NULL COMMENT '复核人',
+  `operator_ip` varchar(64) DEFAULT NULL COMMENT '复核IP',
+  `remark` varchar(255) DEFAULT NULL COMMENT '复核备注',
+  `decide_time` datetime DEFAULT NULL COMMENT '复核时间',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `update_time` datetime DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_order_id` (`order_id`),
+  KEY `idx_status_expire` (`status`, `expire_at`),
+  KEY `idx_m_id` (`m_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='大额代付复核';
+
+-- 商户已成功出款的收款账户（订单库，不分表）
+CREATE TABLE IF NOT EXISTS `p_out_beneficiary` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `m_id` bigint unsigned NOT NULL COMMENT '商户ID',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `acc_no` varchar(64) NOT NULL COMMENT '收款账号',
+  `first_order_id` bigint unsigned NOT NULL COMMENT '首笔成功订单',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_m_cur_acc` (`m_id`, `currency`, `acc_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商户出款收款账户';
+
+-- 商户提现订单模板（按月分表，实际表名 p_withdraw_order_YYYYMM_p0..p3，由建表任务按模板创建）
+CREATE TABLE IF NOT EXISTS `p_withdraw_order` (
+  `order_id` bigint unsigned NOT NULL COMMENT '平台提现单号',
+  `m_id` bigint unsigned NOT NULL COMMENT '商户ID',
+  `withdraw_no` varchar(64) NOT NULL COMMENT '商户提现单号',
+  `destination_id` bigint unsigned NOT NULL COMMENT '提现账户ID',
+  `currency` varchar(10) NOT NULL COMMENT '币种',
+  `amount` decimal(18,4) NOT NULL COMMENT '到账金额',
+  `fee` decimal(18,4) NOT NULL COMMENT '提现手续费',
+  `freeze_amount` decimal(18,4) NOT NULL COMMENT '冻结金额（到账金额+手续费）',
+  `cost` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '上游成本',
+  `profit` decimal(18,4) NOT NULL DEFAULT '0.0000' COMMENT '平台利润',
+  `pay_type` varchar(30) NOT NULL COMMENT '系统通道编码',
+  `account_no` varchar(128) NOT NULL COMMENT '收款账号/地址',
+  `account_name` varchar(64) NOT NULL COMMENT '收款人',
+  `bank_code` varchar(30) DEFAULT NULL COMMENT '银行编码',
+  `pay_method` varchar(30) DEFAULT NULL COMMENT '支付方式/链',
+  `supplier_id` bigint NOT NULL DEFAULT '0' COMMENT '上游供应商ID',
+  `up_channel_id` bigint NOT NULL DEFAULT '0' COMMENT '上游通道ID',
+  `up_order_id` bigint unsigned DEFAULT NULL COMMENT '上游交易订单ID',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '1处理中 2成功 3失败 6人工处理',
+  `notify_url` varchar(255) DEFAULT NULL COMMENT '商户回调地址',
+  `notify_status` tinyint NOT NULL DEFAULT '0' COMMENT '0未通知 1成功 2失败',
+  `remark` varchar(255) DEFAULT NULL COMMENT '备注',
+  `client_ip` varchar(64) DEFAULT NULL COMMENT '下单IP',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  `update_time` datetime DEFAULT NULL COMMENT '更新时间',
+  `finish_time` datetime DEFAULT NULL COMMENT '完成时间',
+  `notify_time` datetime DEFAULT NULL COMMENT '通知时间',
+  PRIMARY KEY (`order_id`),
+  KEY `idx_m_id_withdraw_no` (`m_id`, `withdraw_no`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商户提现订单';
+
+-- 商户提现单号索引（订单库，不分表）
+CREATE TABLE IF NOT EXISTS `p_withdraw_order_index` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `m_id` bigint unsigned NOT NULL COMMENT '商户ID',
+  `withdraw_no` varchar(64) NOT NULL COMMENT '商户提现单号',
+  `order_id` bigint unsigned NOT NULL COMMENT '平台提现单号',
+  `order_table_name` varchar(50) NOT NULL COMMENT '提现订单分表',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_m_withdraw_no` (`m_id`, `withdraw_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商户提现单号索引';
+
+-- 事务发件箱（订单库，不分表）：与订单/状态变更同事务写入，由 Relay 投递到 MQ
+CREATE TABLE IF NOT EXISTS `p_outbox` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `event_id` varchar(64) NOT NULL COMMENT '事件ID（消费端去重）',
+  `topic` varchar(64) NOT NULL COMMENT '生产者配置名',
+  `aggregate_id` bigint unsigned NOT NULL COMMENT '关联平台订单号',
+  `payload` json NOT NULL COMMENT '消息体',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '0待投递 1已投递 2死信',
+  `attempts` int NOT NULL DEFAULT '0' COMMENT '投递失败次数',
+  `next_retry_at` datetime NOT NULL COMMENT '下次投递时间',
+  `last_error` varchar(255) DEFAULT NULL COMMENT '最近一次投递错误',
+  `sent_time` datetime DEFAULT NULL COMMENT '投递成功时间',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  `update_time` datetime DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_event_id` (`event_id`),
+  KEY `idx_status_next_retry` (`status`, `next_retry_at`),
+  KEY `idx_aggregate_id` (`aggregate_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='事务发件箱';
+
+-- 上游回调去重（订单库，不分表）：消费端处理前原子认领，重复消息直接确认，终态冲突转人工复核
+CREATE TABLE IF NOT EXISTS `p_callback_dedup` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `mode` varchar(16) NOT NULL COMMENT 'receive/payout',
+  `up_order_id` bigint unsigned NOT NULL COMMENT '交易订单号',
+  `status` varchar(8) NOT NULL COMMENT '上游回调状态',
+  `msg_id` varchar(128) NOT NULL COMMENT '上游消息ID（上游流水号）',
+  `terminal_up_order_id` bigint unsigned DEFAULT NULL COMMENT '终态回调占位，非终态为NULL',
+  `state` tinyint NOT NULL DEFAULT '0' COMMENT '0处理中 1已处理',
+  `create_time` datetime NOT NULL COMMENT '创建时间',
+  `update_time` datetime NOT NULL COMMENT '最近认领时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_callback_msg` (`mode`, `up_order_id`, `status`, `msg_id`),
+  UNIQUE KEY `uniq_callback_terminal` (`mode`, `terminal_up_order_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='上游回调去重';